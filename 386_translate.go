@@ -0,0 +1,542 @@
+package plan9asm
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// The 386 backend reuses the amd64 lowerings on an amd64Ctx in i386 mode:
+// general-purpose registers live in i32 slots (AX..DI, no R8-R15), ABI0
+// passes arguments and results in 4-byte aligned FP slots, and SP points at
+// a local frame from which CALL reads the callee's outgoing arguments.
+
+func translateFunc386(b *strings.Builder, fn Func, sig FuncSig, resolve func(string) string, sigs map[string]FuncSig, cfg lowerConfig) error {
+	for _, ins := range fn.Instrs {
+		if err := i386CheckInstr(ins); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintf(b, "define %s %s(", sig.Ret, llvmGlobal(sig.Name))
+	for i, t := range sig.Args {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		fmt.Fprintf(b, "%s %%arg%d", t, i)
+	}
+	b.WriteString(")")
+	if sig.Attrs != "" {
+		b.WriteString(" " + sig.Attrs)
+	}
+	b.WriteString(" {\n")
+
+	c := newAMD64Ctx(b, fn, sig, resolve, sigs, cfg)
+	c.i386 = true
+	c.frameSize = textFrameSize(fn)
+	if err := c.emitEntryAllocas(); err != nil {
+		return err
+	}
+	if err := c.lowerBlocks(); err != nil {
+		return err
+	}
+
+	b.WriteString("}\n")
+	return nil
+}
+
+// i386CheckInstr rejects instructions that only exist in 64-bit mode: the
+// R8-R15 registers, 64-bit general-purpose operations and SYSCALL.
+func i386CheckInstr(ins Instr) error {
+	op := strings.ToUpper(string(ins.Op))
+	if op == "SYSCALL" {
+		return fmt.Errorf("386: SYSCALL is 64-bit only, use INT $0x80: %q", ins.Raw)
+	}
+	var regs []Reg
+	for _, a := range ins.Args {
+		switch a.Kind {
+		case OpReg:
+			regs = append(regs, a.Reg)
+		case OpMem:
+			regs = append(regs, a.Mem.Base, a.Mem.Index)
+		}
+	}
+	for _, r := range regs {
+		if n, err := strconv.Atoi(strings.TrimPrefix(string(r), "R")); err == nil && strings.HasPrefix(string(r), "R") && n >= 8 {
+			return fmt.Errorf("386: no register %s: %q", r, ins.Raw)
+		}
+	}
+	// Sign and zero extensions to a quadword (MOVLQZX) and conversions
+	// from or to one (CVTSQ2SD) need a 64-bit register.
+	if (len(op) == 7 && strings.HasPrefix(op, "MOV") && op[4] == 'Q' && (strings.HasSuffix(op, "SX") || strings.HasSuffix(op, "ZX"))) ||
+		(strings.HasPrefix(op, "CVT") && strings.Contains(op, "SQ")) {
+		return fmt.Errorf("386: %s has no 32-bit form: %q", op, ins.Raw)
+	}
+	if !strings.HasSuffix(op, "Q") || op == "CDQ" {
+		return nil
+	}
+	// Condition codes such as EQ end in Q too.
+	if _, ok := amd64JccCond(op); ok {
+		return nil
+	}
+	if _, ok := amd64SetccCond(op); ok {
+		return nil
+	}
+	if ty, _, ok := amd64CmovCond(op); ok {
+		if ty == I64 {
+			return fmt.Errorf("386: %s has no 32-bit form: %q", op, ins.Raw)
+		}
+		return nil
+	}
+	// Quadword SSE/AVX/mask operations and MOVQ to or from an XMM or MMX
+	// register exist in 32-bit mode as long as no operand is a
+	// general-purpose register.
+	switch op {
+	case "PINSRQ", "PEXTRQ", "VPINSRQ", "VPEXTRQ":
+		return fmt.Errorf("386: %s has no 32-bit form: %q", op, ins.Raw)
+	}
+	vec, gpr := false, false
+	for _, a := range ins.Args {
+		if a.Kind != OpReg {
+			continue
+		}
+		if amd64IsVecOrMaskReg(a.Reg) || i386IsMMXReg(a.Reg) {
+			vec = true
+		} else {
+			gpr = true
+		}
+	}
+	simd := op == "MOVQ" || strings.HasPrefix(op, "V") || strings.HasPrefix(op, "K") ||
+		(strings.HasPrefix(op, "P") && !strings.HasPrefix(op, "PUSH") && !strings.HasPrefix(op, "POP"))
+	if !simd || gpr || !vec {
+		return fmt.Errorf("386: %s has no 32-bit form: %q", op, ins.Raw)
+	}
+	return nil
+}
+
+// i386IsMMXReg reports whether r is one of the MMX registers M0-M7, which
+// 386 code uses for 64-bit atomic loads and stores. They keep i64 slots.
+func i386IsMMXReg(r Reg) bool {
+	return len(r) == 2 && r[0] == 'M' && r[1] >= '0' && r[1] <= '7'
+}
+
+// lower386 handles the instructions that only exist in 32-bit mode, plus the
+// Linux INT $0x80 system call.
+func (c *amd64Ctx) lower386(op Op, ins Instr) (ok bool, terminated bool, err error) {
+	repn := c.repn
+	c.repn = false
+	switch op {
+	case "REPN":
+		c.repn = true
+		return true, false, nil
+	case "SCASB":
+		if !repn {
+			return true, false, fmt.Errorf("386 SCASB needs a REPN prefix: %q", ins.Raw)
+		}
+		return true, false, c.repnScasb()
+	case "PUSHL":
+		if len(ins.Args) != 1 {
+			return true, false, fmt.Errorf("386 PUSHL expects src: %q", ins.Raw)
+		}
+		v, err := c.evalI64(ins.Args[0])
+		if err != nil {
+			return true, false, err
+		}
+		c.pushI64(v)
+		return true, false, nil
+	case "POPL":
+		if len(ins.Args) != 1 || ins.Args[0].Kind != OpReg {
+			return true, false, fmt.Errorf("386 POPL expects dstReg: %q", ins.Raw)
+		}
+		return true, false, c.storeReg(ins.Args[0].Reg, c.popI64())
+	case "EMMS":
+		return true, false, nil
+	case "PUSHFL":
+		c.pushI64(c.flagsWord())
+		return true, false, nil
+	case "POPFL":
+		c.setFlagsFromWord(c.popI64(), 32)
+		return true, false, nil
+	case "PUSHAL":
+		for _, r := range []Reg{AX, CX, DX, BX, SP, BP, SI, DI} {
+			v, err := c.loadReg(r)
+			if err != nil {
+				return true, false, err
+			}
+			c.pushI64(v)
+		}
+		return true, false, nil
+	case "POPAL":
+		for _, r := range []Reg{DI, SI, BP, SP, BX, DX, CX, AX} {
+			v := c.popI64()
+			if r == SP {
+				// The saved ESP is discarded.
+				continue
+			}
+			if err := c.storeReg(r, v); err != nil {
+				return true, false, err
+			}
+		}
+		return true, false, nil
+	case "INT":
+		if len(ins.Args) != 1 || ins.Args[0].Kind != OpImm || ins.Args[0].Imm != 0x80 {
+			// Other traps (INT $3) keep the amd64 lowering.
+			return false, false, nil
+		}
+		switch c.cfg.goos {
+		case "", "linux", "android":
+		default:
+			return true, false, fmt.Errorf("386 INT $0x80 system calls on %s pass arguments on the stack: %q", c.cfg.goos, ins.Raw)
+		}
+		s := c.cfg.syscallSite(Arch386, c.b, c.newTmp)
+		if s.Num, err = c.loadReg(AX); err != nil {
+			return true, false, err
+		}
+		for _, r := range []Reg{BX, CX, DX, SI, DI, BP} {
+			v, err := c.loadReg(r)
+			if err != nil {
+				return true, false, err
+			}
+			s.Args = append(s.Args, v)
+		}
+		res, err := c.cfg.syscallStrategy().Lower(s)
+		if err != nil {
+			return true, false, err
+		}
+		if err := c.storeReg(AX, res.ret(s)); err != nil {
+			return true, false, err
+		}
+		return true, false, c.storeReg(DX, res.R2)
+	}
+	return false, false, nil
+}
+
+// repnScasb lowers REPN; SCASB: scan CX bytes at DI for AL, leaving DI
+// one past the match and ZF set when it is found.
+func (c *amd64Ctx) repnScasb() error {
+	loop := "repn_" + c.newTmp()
+	body, done := loop+"_body", loop+"_done"
+	fmt.Fprintf(c.b, "  br label %%%s\n\n%s:\n", loop, loop)
+	cx, err := c.loadReg(CX)
+	if err != nil {
+		return err
+	}
+	end := c.newTmp()
+	fmt.Fprintf(c.b, "  %%%s = icmp eq i64 %s, 0\n", end, cx)
+	fmt.Fprintf(c.b, "  br i1 %%%s, label %%%s, label %%%s\n\n%s:\n", end, done, body, body)
+	di, err := c.loadReg(DI)
+	if err != nil {
+		return err
+	}
+	ax, err := c.loadReg(AX)
+	if err != nil {
+		return err
+	}
+	b := c.newTmp()
+	fmt.Fprintf(c.b, "  %%%s = load i8, ptr %s, align 1\n", b, c.ptrFromAddrI64(di))
+	al := c.newTmp()
+	fmt.Fprintf(c.b, "  %%%s = trunc i64 %s to i8\n", al, ax)
+	c.setCmpFlagsSized(I8, "%"+al, "%"+b)
+	ndi := c.newTmp()
+	fmt.Fprintf(c.b, "  %%%s = add i64 %s, 1\n", ndi, di)
+	if err := c.storeReg(DI, "%"+ndi); err != nil {
+		return err
+	}
+	ncx := c.newTmp()
+	fmt.Fprintf(c.b, "  %%%s = sub i64 %s, 1\n", ncx, cx)
+	if err := c.storeReg(CX, "%"+ncx); err != nil {
+		return err
+	}
+	found := c.newTmp()
+	fmt.Fprintf(c.b, "  %%%s = icmp eq i8 %%%s, %%%s\n", found, al, b)
+	fmt.Fprintf(c.b, "  br i1 %%%s, label %%%s, label %%%s\n\n%s:\n", found, done, loop, done)
+	return nil
+}
+
+// i386ArgSlots returns the FP slots of csig's argument i: one slot with
+// Field < 0 for a scalar, or one per field of an aggregate.
+func i386ArgSlots(csig FuncSig, i int) []FrameSlot {
+	var out []FrameSlot
+	for _, s := range csig.Frame.Params {
+		if s.Index == i {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// i386BuildArg assembles argument i of csig, of type ty, from the values
+// load returns for its FP slots.
+func (c *amd64Ctx) i386BuildArg(csig FuncSig, i int, ty LLVMType, load func(FrameSlot) (string, error)) (string, error) {
+	slots := i386ArgSlots(csig, i)
+	if len(slots) == 0 {
+		return "", fmt.Errorf("386 call %q: no frame slot for arg %d", csig.Name, i)
+	}
+	if len(slots) == 1 && slots[0].Field < 0 {
+		return load(slots[0])
+	}
+	agg := "undef"
+	for _, s := range slots {
+		v, err := load(s)
+		if err != nil {
+			return "", err
+		}
+		t := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = insertvalue %s %s, %s %s, %d\n", t, ty, agg, s.Type, v, s.Field)
+		agg = "%" + t
+	}
+	return agg, nil
+}
+
+// i386FromI64 converts a register model value to ty.
+func (c *amd64Ctx) i386FromI64(v string, ty LLVMType) (string, error) {
+	switch ty {
+	case I64:
+		return v, nil
+	case I1, I8, I16, I32:
+		t := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = trunc i64 %s to %s\n", t, v, ty)
+		return "%" + t, nil
+	case Ptr:
+		return c.i64ToPtr(v), nil
+	case LLVMType("double"):
+		t := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = bitcast i64 %s to double\n", t, v)
+		return "%" + t, nil
+	case LLVMType("float"):
+		t32 := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = trunc i64 %s to i32\n", t32, v)
+		t := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = bitcast i32 %%%s to float\n", t, t32)
+		return "%" + t, nil
+	}
+	return "", fmt.Errorf("386: unsupported value type %s", ty)
+}
+
+// stackPtr returns a pointer to off(SP).
+func (c *amd64Ctx) stackPtr(off int64) (string, error) {
+	sp, err := c.loadReg(SP)
+	if err != nil {
+		return "", err
+	}
+	t := c.newTmp()
+	fmt.Fprintf(c.b, "  %%%s = add i64 %s, %d\n", t, sp, off)
+	return c.i64ToPtr("%" + t), nil
+}
+
+// pushedWordPtr returns a pointer to the virtual stack entry holding the
+// word at 4*word(SP), counting down from the last PUSHL.
+func (c *amd64Ctx) pushedWordPtr(word int64) string {
+	sp := c.newTmp()
+	fmt.Fprintf(c.b, "  %%%s = load i64, ptr %s\n", sp, c.vspSlot)
+	idx := c.newTmp()
+	fmt.Fprintf(c.b, "  %%%s = sub i64 %%%s, %d\n", idx, sp, word+1)
+	neg := c.newTmp()
+	fmt.Fprintf(c.b, "  %%%s = icmp slt i64 %%%s, 0\n", neg, idx)
+	clamped := c.newTmp()
+	fmt.Fprintf(c.b, "  %%%s = select i1 %%%s, i64 0, i64 %%%s\n", clamped, neg, idx)
+	ptr := c.newTmp()
+	fmt.Fprintf(c.b, "  %%%s = getelementptr inbounds [64 x i64], ptr %s, i32 0, i64 %%%s\n", ptr, c.vstackSlot, clamped)
+	return "%" + ptr
+}
+
+// loadOutArg386 loads an outgoing ABI0 slot at s.Offset(SP). Functions with
+// a frame keep the outgoing area in it; frameless ones PUSHL their
+// arguments, which live on the virtual stack.
+func (c *amd64Ctx) loadOutArg386(s FrameSlot) (string, error) {
+	if c.frameSize > 0 {
+		p, err := c.stackPtr(s.Offset)
+		if err != nil {
+			return "", err
+		}
+		t := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = load %s, ptr %s, align 4\n", t, s.Type, p)
+		return "%" + t, nil
+	}
+	lo := c.newTmp()
+	fmt.Fprintf(c.b, "  %%%s = load i64, ptr %s\n", lo, c.pushedWordPtr(s.Offset/4))
+	v := "%" + lo
+	if s.Type == I64 || s.Type == LLVMType("double") {
+		hi := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = load i64, ptr %s\n", hi, c.pushedWordPtr(s.Offset/4+1))
+		lo32 := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = and i64 %s, 4294967295\n", lo32, v)
+		sh := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = shl i64 %%%s, 32\n", sh, hi)
+		w := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = or i64 %%%s, %%%s\n", w, lo32, sh)
+		v = "%" + w
+	}
+	return c.i386FromI64(v, s.Type)
+}
+
+// storeOutArg386 stores v, of type s.Type, to the outgoing slot at
+// s.Offset(SP); see loadOutArg386.
+func (c *amd64Ctx) storeOutArg386(s FrameSlot, v string) error {
+	if c.frameSize > 0 {
+		p, err := c.stackPtr(s.Offset)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(c.b, "  store %s %s, ptr %s, align 4\n", s.Type, v, p)
+		return nil
+	}
+	w, ok, err := amd64ValueAsI64(c, s.Type, v)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("386: unsupported pushed slot type %s", s.Type)
+	}
+	fmt.Fprintf(c.b, "  store i64 %s, ptr %s\n", w, c.pushedWordPtr(s.Offset/4))
+	if s.Type == I64 || s.Type == LLVMType("double") {
+		hi := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = lshr i64 %s, 32\n", hi, w)
+		fmt.Fprintf(c.b, "  store i64 %%%s, ptr %s\n", hi, c.pushedWordPtr(s.Offset/4+1))
+	}
+	return nil
+}
+
+// callSym386 performs an ABI0 call: the arguments are read from the
+// outgoing area at 0(SP) following the callee's frame layout, and the
+// results are stored back after them.
+func (c *amd64Ctx) callSym386(callee string, csig FuncSig) error {
+	csig.Name = callee
+	args := make([]string, 0, len(csig.Args))
+	for i, ty := range csig.Args {
+		v, err := c.i386BuildArg(csig, i, ty, c.loadOutArg386)
+		if err != nil {
+			return err
+		}
+		args = append(args, fmt.Sprintf("%s %s", ty, v))
+	}
+	if csig.Ret == Void {
+		fmt.Fprintf(c.b, "  call void %s(%s)\n", llvmGlobal(callee), strings.Join(args, ", "))
+		return nil
+	}
+	ret := c.newTmp()
+	fmt.Fprintf(c.b, "  %%%s = call %s %s(%s)\n", ret, csig.Ret, llvmGlobal(callee), strings.Join(args, ", "))
+	if len(csig.Frame.Results) == 0 {
+		// Without a frame layout the result is taken to come back in EAX.
+		switch csig.Ret {
+		case I64:
+			return c.storeReg(AX, "%"+ret)
+		case I1, I8, I16, I32:
+			z := c.newTmp()
+			fmt.Fprintf(c.b, "  %%%s = zext %s %%%s to i64\n", z, csig.Ret, ret)
+			return c.storeReg(AX, "%"+z)
+		case Ptr:
+			return c.storeReg(AX, c.ptrToI64("%"+ret))
+		}
+		return fmt.Errorf("386 call %q: no frame slot for its %s result", callee, csig.Ret)
+	}
+	multi := len(csig.Frame.Results) > 1 && csig.Frame.Results[len(csig.Frame.Results)-1].Index > 0
+	for _, s := range csig.Frame.Results {
+		v, ty := "%"+ret, csig.Ret
+		if multi {
+			fields, ok := parseLiteralStructFields(ty)
+			if !ok || s.Index >= len(fields) {
+				return fmt.Errorf("386 call %q: result %d of %s", callee, s.Index, ty)
+			}
+			t := c.newTmp()
+			fmt.Fprintf(c.b, "  %%%s = extractvalue %s %s, %d\n", t, ty, v, s.Index)
+			v, ty = "%"+t, fields[s.Index]
+		}
+		if s.Field >= 0 {
+			t := c.newTmp()
+			fmt.Fprintf(c.b, "  %%%s = extractvalue %s %s, %d\n", t, ty, v, s.Field)
+			v = "%" + t
+		}
+		if err := c.storeOutArg386(s, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tailArg386 returns argument i of an ABI0 tail call, which takes over the
+// caller's own argument frame.
+func (c *amd64Ctx) tailArg386(csig FuncSig, i int) (string, error) {
+	return c.i386BuildArg(csig, i, csig.Args[i], func(s FrameSlot) (string, error) {
+		v, err := c.evalFPToI64(s.Offset)
+		if err != nil {
+			return "", err
+		}
+		return c.i386FromI64(v, s.Type)
+	})
+}
+
+// callIndirect386 calls a code pointer whose signature is unknown. Its ABI0
+// arguments stay in the outgoing frame; only the EAX result is modeled.
+func (c *amd64Ctx) callIndirect386(addr string) error {
+	fptr := c.i64ToPtr(addr)
+	ret := c.newTmp()
+	fmt.Fprintf(c.b, "  %%%s = call i32 %s()\n", ret, fptr)
+	z := c.newTmp()
+	fmt.Fprintf(c.b, "  %%%s = zext i32 %%%s to i64\n", z, ret)
+	return c.storeReg(AX, "%"+z)
+}
+
+// fpResultSlot386 returns the FP result slot at off.
+func (c *amd64Ctx) fpResultSlot386(off int64) (FrameSlot, bool) {
+	for _, r := range c.fpResults {
+		if r.Offset == off {
+			return r, true
+		}
+	}
+	return FrameSlot{}, false
+}
+
+// evalFPHigh386 reads the high word of a 64-bit argument or result whose
+// slot starts at off-4, as in MOVL x_hi+4(FP), DX.
+func (c *amd64Ctx) evalFPHigh386(off int64) (string, bool, error) {
+	if s, ok := c.fpParam(off - 4); ok && (s.Type == I64 || s.Type == LLVMType("double")) {
+		v, err := c.evalFPToI64(off - 4)
+		if err != nil {
+			return "", true, err
+		}
+		t := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = lshr i64 %s, 32\n", t, v)
+		return "%" + t, true, nil
+	}
+	if s, ok := c.fpResultSlot386(off - 4); ok && s.Type == I64 {
+		alloca, _, _ := c.fpResultAlloca(off - 4)
+		ld := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = load i64, ptr %s\n", ld, alloca)
+		t := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = lshr i64 %%%s, 32\n", t, ld)
+		return "%" + t, true, nil
+	}
+	return "", false, nil
+}
+
+// storeFPWord386 stores the low 32 bits of v into one half of a 64-bit
+// result: the low word when its slot starts at off, the high word when it
+// starts at off-4. It reports false for any other slot.
+func (c *amd64Ctx) storeFPWord386(off int64, v string) (bool, error) {
+	slotOff, shift, keep := off, 0, "-4294967296"
+	if s, ok := c.fpResultSlot386(off); !ok || s.Type != I64 {
+		if s, ok := c.fpResultSlot386(off - 4); !ok || s.Type != I64 {
+			return false, nil
+		}
+		slotOff, shift, keep = off-4, 32, "4294967295"
+	}
+	alloca, _, _ := c.fpResultAlloca(slotOff)
+	lo := c.newTmp()
+	fmt.Fprintf(c.b, "  %%%s = and i64 %s, 4294967295\n", lo, v)
+	word := "%" + lo
+	if shift != 0 {
+		t := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = shl i64 %s, %d\n", t, word, shift)
+		word = "%" + t
+	}
+	old := c.newTmp()
+	fmt.Fprintf(c.b, "  %%%s = load i64, ptr %s\n", old, alloca)
+	kept := c.newTmp()
+	fmt.Fprintf(c.b, "  %%%s = and i64 %%%s, %s\n", kept, old, keep)
+	merged := c.newTmp()
+	fmt.Fprintf(c.b, "  %%%s = or i64 %%%s, %s\n", merged, kept, word)
+	fmt.Fprintf(c.b, "  store i64 %%%s, ptr %s\n", merged, alloca)
+	c.markFPResultWritten(slotOff)
+	return true, nil
+}
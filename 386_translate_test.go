@@ -0,0 +1,250 @@
+//go:build !llgo
+// +build !llgo
+
+package plan9asm
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func sig386(name string, args []LLVMType, ret LLVMType) FuncSig {
+	return sigWithClassicFrame32(name, args, ret)
+}
+
+func translate386(t *testing.T, src string, sigs map[string]FuncSig) string {
+	t.Helper()
+	file, err := Parse(Arch386, src)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	ll, err := translateIRText(file, Options{
+		TargetTriple: "i386-unknown-linux-gnu",
+		Goarch:       "386",
+		ResolveSym:   testResolveSym("example"),
+		Sigs:         sigs,
+	})
+	if err != nil {
+		t.Fatalf("translateIRText: %v", err)
+	}
+	return ll
+}
+
+func TestTranslate386Registers(t *testing.T) {
+	ll := translate386(t, `TEXT ·add(SB),NOSPLIT,$0-12
+	MOVL a+0(FP), EAX
+	MOVL b+4(FP), BX
+	ADDL BX, AX
+	ADCL $0, AX
+	IMULL BX, AX
+	CDQ
+	PUSHL AX
+	POPL CX
+	MOVL CX, ret+8(FP)
+	RET
+`, map[string]FuncSig{
+		"example.add": sig386("example.add", []LLVMType{I32, I32}, I32),
+	})
+	for _, want := range []string{
+		`define i32 @"example.add"(i32 %arg0, i32 %arg1)`,
+		"%reg_AX = alloca i32",
+		"%reg_CX = alloca i32",
+		"add i32",
+		"sext i32",
+		"ashr i32",
+		"store i32 %",
+		"ret i32",
+	} {
+		if !strings.Contains(ll, want) {
+			t.Fatalf("missing %q in IR:\n%s", want, ll)
+		}
+	}
+	if strings.Contains(ll, "alloca i64\n  store i64 0, ptr %reg_") {
+		t.Fatalf("unexpected 64-bit register slot:\n%s", ll)
+	}
+}
+
+func TestTranslate386StackCall(t *testing.T) {
+	ll := translate386(t, `TEXT ·outer(SB),NOSPLIT,$12-8
+	MOVL x+0(FP), AX
+	MOVL AX, 0(SP)
+	MOVL $7, 4(SP)
+	CALL ·inner(SB)
+	MOVL 8(SP), AX
+	MOVL AX, ret+4(FP)
+	RET
+
+TEXT ·pushed(SB),NOSPLIT,$0-4
+	PUSHL $7
+	PUSHL $1
+	CALL ·inner(SB)
+	POPL AX
+	POPL AX
+	MOVL $0, ret+0(FP)
+	RET
+`, map[string]FuncSig{
+		"example.outer":  sig386("example.outer", []LLVMType{I32}, I32),
+		"example.pushed": sig386("example.pushed", nil, I32),
+		"example.inner":  sig386("example.inner", []LLVMType{I32, I32}, I32),
+	})
+	for _, want := range []string{
+		"%frame = alloca [12 x i8], align 4",
+		"ptrtoint ptr %frame to i32",
+		`call i32 @"example.inner"(i32 %`,
+		"add i64 %", // 8(SP)
+		"load i64, ptr %virt_sp",
+	} {
+		if !strings.Contains(ll, want) {
+			t.Fatalf("missing %q in IR:\n%s", want, ll)
+		}
+	}
+}
+
+func TestTranslate386TailCallNarrowsResult(t *testing.T) {
+	// A helper without a Go declaration returns a whole word, which a
+	// bool-returning caller truncates.
+	ll := translate386(t, `TEXT ·equal(SB),NOSPLIT,$0-9
+	JMP ·body(SB)
+`, map[string]FuncSig{
+		"example.equal": sig386("example.equal", []LLVMType{I32, I32}, I1),
+		"example.body":  {Name: "example.body", Ret: I32},
+	})
+	if !strings.Contains(ll, "trunc i32 %") || !strings.Contains(ll, "ret i1 %") {
+		t.Fatalf("missing result truncation in IR:\n%s", ll)
+	}
+}
+
+func TestTranslate386Int64Words(t *testing.T) {
+	ll := translate386(t, `TEXT ·swap(SB),NOSPLIT,$0-16
+	MOVL v_lo+0(FP), AX
+	MOVL v_hi+4(FP), DX
+	MOVL DX, ret_lo+8(FP)
+	MOVL AX, ret_hi+12(FP)
+	RET
+
+TEXT ·load64(SB),NOSPLIT,$0-12
+	MOVL p+0(FP), AX
+	MOVQ (AX), M0
+	MOVQ M0, ret+4(FP)
+	EMMS
+	RET
+
+TEXT ·cas64(SB),NOSPLIT,$0-21
+	MOVL p+0(FP), BP
+	MOVL old_lo+4(FP), AX
+	MOVL old_hi+8(FP), DX
+	MOVL new_lo+12(FP), BX
+	MOVL new_hi+16(FP), CX
+	LOCK
+	CMPXCHG8B 0(BP)
+	SETEQ ret+20(FP)
+	RET
+`, map[string]FuncSig{
+		"example.swap":   sig386("example.swap", []LLVMType{I64}, I64),
+		"example.load64": sig386("example.load64", []LLVMType{Ptr}, I64),
+		"example.cas64":  sig386("example.cas64", []LLVMType{Ptr, I64, I64}, I1),
+	})
+	for _, want := range []string{
+		"lshr i64 %arg0, 32",
+		"and i64 %", // merging a word into the i64 result
+		"%reg_M0 = alloca i64",
+		"cmpxchg ptr",
+		"i64 %",
+	} {
+		if !strings.Contains(ll, want) {
+			t.Fatalf("missing %q in IR:\n%s", want, ll)
+		}
+	}
+}
+
+func TestTranslate386RepnScasb(t *testing.T) {
+	ll := translate386(t, `TEXT ·index(SB),NOSPLIT,$0-16
+	MOVL b_base+0(FP), SI
+	MOVL b_len+4(FP), CX
+	MOVB c+12(FP), AL
+	MOVL SI, DI
+	CLD; REPN; SCASB
+	JZ 3(PC)
+	MOVL $-1, ret+16(FP)
+	RET
+	SUBL SI, DI
+	SUBL $1, DI
+	MOVL DI, ret+16(FP)
+	RET
+`, map[string]FuncSig{
+		"example.index": sig386("example.index", []LLVMType{"{ ptr, i32, i32 }", I8}, I32),
+	})
+	for _, want := range []string{"_body:", "_done:", "load i8, ptr", "icmp eq i8"} {
+		if !strings.Contains(ll, want) {
+			t.Fatalf("missing %q in IR:\n%s", want, ll)
+		}
+	}
+}
+
+func TestTranslate386Rejects64BitForms(t *testing.T) {
+	for _, tc := range []struct{ insn, want string }{
+		{"MOVQ AX, BX", "MOVQ has no 32-bit form"},
+		{"ADDQ $1, AX", "ADDQ has no 32-bit form"},
+		{"MOVLQZX AX, BX", "MOVLQZX has no 32-bit form"},
+		{"MOVL R8, AX", "no register R8"},
+		{"SYSCALL", "use INT $0x80"},
+	} {
+		file, err := Parse(Arch386, "TEXT ·f(SB),NOSPLIT,$0-0\n\t"+tc.insn+"\n\tRET\n")
+		if err != nil {
+			t.Fatalf("%s: Parse: %v", tc.insn, err)
+		}
+		_, err = translateIRText(file, Options{
+			Goarch:     "386",
+			ResolveSym: testResolveSym("example"),
+			Sigs:       map[string]FuncSig{"example.f": {Name: "example.f", Ret: Void}},
+		})
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: err = %v, want %q", tc.insn, err, tc.want)
+		}
+	}
+	// Quadword moves through XMM registers are fine.
+	translate386(t, "TEXT ·g(SB),NOSPLIT,$0-8\n\tMOVL p+0(FP), AX\n\tMOVQ (AX), X0\n\tMOVQ X0, (AX)\n\tRET\n", map[string]FuncSig{
+		"example.g": sig386("example.g", []LLVMType{Ptr}, Void),
+	})
+}
+
+func TestTranslate386Compile(t *testing.T) {
+	llc, _, ok := findLlcAndClang(t)
+	if !ok {
+		t.Skip("llc not found")
+	}
+	ll := translate386(t, `TEXT ·sum(SB),NOSPLIT,$8-16
+	MOVL p+0(FP), SI
+	MOVL n+4(FP), CX
+	XORL AX, AX
+	XORL DX, DX
+loop:
+	CMPL CX, $0
+	JEQ done
+	ADDL (SI), AX
+	ADCL $0, DX
+	ADDL $4, SI
+	DECL CX
+	JMP loop
+done:
+	MOVL AX, ret_lo+8(FP)
+	MOVL DX, ret_hi+12(FP)
+	RET
+`, map[string]FuncSig{
+		"example.sum": sig386("example.sum", []LLVMType{Ptr, I32}, I64),
+	})
+	dir := t.TempDir()
+	llPath := filepath.Join(dir, "sum.ll")
+	if err := os.WriteFile(llPath, []byte(ll), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, triple := range []string{"i386-unknown-linux-gnu", "i686-pc-windows-msvc"} {
+		out, err := exec.Command(llc, "-mtriple="+triple, "-mcpu=pentium4", "-filetype=obj", llPath, "-o", filepath.Join(dir, "sum.o")).CombinedOutput()
+		if err != nil {
+			t.Fatalf("llc %s: %v\n%s", triple, err, out)
+		}
+	}
+}
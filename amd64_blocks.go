@@ -16,14 +16,7 @@ func amd64SplitBlocks(fn Func) []amd64Block {
 	anon := 0
 
 	isPCRelTarget := func(ins Instr) (off int64, ok bool) {
-		op := strings.ToUpper(string(ins.Op))
-		switch Op(op) {
-		case "JMP",
-			"JE", "JEQ", "JZ", "JNE", "JNZ",
-			"JL", "JLT", "JLE", "JG", "JGT", "JGE", "JS", "JNS",
-			"JB", "JLO", "JBE", "JA", "JHI", "JAE", "JHS", "JLS", "JNA",
-			"JNC", "JC", "JCC":
-		default:
+		if !amd64IsJump(string(ins.Op)) {
 			return 0, false
 		}
 		if len(ins.Args) != 1 || ins.Args[0].Kind != OpMem {
@@ -45,17 +38,7 @@ func amd64SplitBlocks(fn Func) []amd64Block {
 		if ins.Op == OpRET {
 			return true
 		}
-		op := strings.ToUpper(string(ins.Op))
-		switch Op(op) {
-		case "JMP",
-			"JE", "JEQ", "JZ", "JNE", "JNZ",
-			"JL", "JLT", "JLE", "JG", "JGT", "JGE", "JS", "JNS",
-			"JB", "JLO", "JBE", "JA", "JHI", "JAE", "JHS", "JLS", "JNA",
-			"JNC", "JC", "JCC":
-			return true
-		default:
-			return false
-		}
+		return amd64IsJump(string(ins.Op))
 	}
 
 	linear := make([]Instr, 0, len(fn.Instrs))
@@ -11,9 +11,9 @@ type amd64Ctx struct {
 	b   *strings.Builder
 	sig FuncSig
 
-	resolve  func(string) string
-	sigs     map[string]FuncSig
-	annotate bool
+	resolve func(string) string
+	sigs    map[string]FuncSig
+	cfg     lowerConfig
 
 	tmp int
 
@@ -38,13 +38,17 @@ type amd64Ctx struct {
 	usedKRegs map[int]bool
 	kRegSlot  map[int]string // avx512 mask reg index -> alloca name (i64)
 
+	// EFLAGS status bits, one i1 alloca each (see amd64_flags.go).
 	flagsZSlot   string
-	flagsSltSlot string // signed negative-style bit for J{L,LE,G,GE}-like checks
-	flagsCFSlot  string // carry/borrow style bit for J{B,BE,A,AE,NC,C}-like checks
-	flagsOFSlot  string // overflow-style bit used by ADOX carry chain modeling
+	flagsSFSlot  string
+	flagsCFSlot  string
+	flagsOFSlot  string
+	flagsPFSlot  string
+	flagsAFSlot  string
 	flagsWritten bool
-	vstackSlot   string // [64 x i64] virtual stack for PUSHQ/POPQ
-	vspSlot      string // i64 virtual stack pointer (next free slot)
+	flagsDead    flagMask // flags no later instruction reads (see flaglive.go)
+	vstackSlot   string   // [64 x i64] virtual stack for PUSHQ/POPQ
+	vspSlot      string   // i64 virtual stack pointer (next free slot)
 
 	fpParams       map[int64]FrameSlot // off(FP) -> slot
 	fpResults      []FrameSlot
@@ -52,15 +56,22 @@ type amd64Ctx struct {
 	fpResAllocaIdx map[int]string   // result index -> alloca
 	fpResWritten   map[int]bool     // result index -> whether written via +off(FP)
 	fpResAddrTaken map[int]bool     // result index -> address of fp_ret_* escaped
+
+	// i386 selects the 386 model (see 386_translate.go): i32 register slots,
+	// every argument on the stack, and SP addressing a local frame of
+	// frameSize bytes. Values still flow through the lowerings as i64.
+	i386      bool
+	frameSize int64
+	repn      bool // a REPN prefix applies to the next instruction
 }
 
-func newAMD64Ctx(b *strings.Builder, fn Func, sig FuncSig, resolve func(string) string, sigs map[string]FuncSig, annotate bool) *amd64Ctx {
+func newAMD64Ctx(b *strings.Builder, fn Func, sig FuncSig, resolve func(string) string, sigs map[string]FuncSig, cfg lowerConfig) *amd64Ctx {
 	c := &amd64Ctx{
 		b:              b,
 		sig:            sig,
 		resolve:        resolve,
 		sigs:           sigs,
-		annotate:       annotate,
+		cfg:            cfg,
 		blocks:         amd64SplitBlocks(fn),
 		usedRegs:       map[Reg]bool{},
 		regSlot:        map[Reg]string{},
@@ -93,7 +104,7 @@ func newAMD64Ctx(b *strings.Builder, fn Func, sig FuncSig, resolve func(string)
 }
 
 func (c *amd64Ctx) emitSourceComment(ins Instr) {
-	if !c.annotate {
+	if !c.cfg.annotateSource {
 		return
 	}
 	emitIRSourceComment(c.b, ins.Raw)
@@ -160,6 +171,22 @@ func amd64ParseKReg(r Reg) (idx int, ok bool) {
 	return n, true
 }
 
+// amd64IsVecOrMaskReg reports whether r names an X/Y/Z vector or K mask
+// register, none of which can stand in for a general-purpose operand.
+func amd64IsVecOrMaskReg(r Reg) bool {
+	if _, ok := amd64ParseXReg(r); ok {
+		return true
+	}
+	if _, ok := amd64ParseYReg(r); ok {
+		return true
+	}
+	if _, ok := amd64ParseZReg(r); ok {
+		return true
+	}
+	_, ok := amd64ParseKReg(r)
+	return ok
+}
+
 func (c *amd64Ctx) scanUsedRegs() {
 	markReg := func(r Reg) {
 		if r == "" {
@@ -199,16 +226,34 @@ func (c *amd64Ctx) scanUsedRegs() {
 		}
 	}
 
+	markContract := func(regs []Reg) {
+		for _, r := range regs {
+			if r != FLAGS {
+				markReg(r)
+			}
+		}
+	}
 	for _, blk := range c.blocks {
 		for _, ins := range blk.instrs {
 			for _, op := range ins.Args {
 				markOp(op)
 			}
+			// A register contract passes registers the caller may not name.
+			if base, _, ok := goReferencedFunc(Instr{Op: Op(strings.ToUpper(string(ins.Op))), Args: ins.Args}); ok {
+				if csig, ok := c.sigs[c.resolve(base)]; ok && regContractSig(csig, c.contractWordType()) {
+					markContract(csig.ArgRegs)
+					markContract(csig.RetRegs)
+				}
+			}
 		}
 	}
+	markContract(c.sig.RetRegs)
 
 	// Ensure a few common regs exist even if only used implicitly by helpers.
 	markReg(AX)
+	if c.i386 && c.frameSize > 0 {
+		markReg(SP)
+	}
 
 	// Ensure arg regs exist for ABIInternal-style stdlib asm. This matters for:
 	//   - functions like runtime·cmpstring<ABIInternal> that tail-call helpers
@@ -216,9 +261,11 @@ func (c *amd64Ctx) scanUsedRegs() {
 	//   - helpers that expect words of an aggregate arg (slice/string) in
 	//     consecutive registers.
 	if len(c.sig.ArgRegs) > 0 {
-		for i := 0; i < len(c.sig.Args) && i < len(c.sig.ArgRegs); i++ {
-			markReg(c.sig.ArgRegs[i])
-		}
+		markContract(c.sig.ArgRegs)
+		return
+	}
+	if c.i386 {
+		// ABI0 passes every argument on the stack.
 		return
 	}
 	goABI := []Reg{AX, BX, CX, DI, SI, Reg("R8"), Reg("R9"), Reg("R10"), Reg("R11")}
@@ -244,13 +291,29 @@ func (c *amd64Ctx) emitEntryAllocas() error {
 	}
 	sort.Strings(regs)
 
+	gpr := I64
+	if c.i386 {
+		gpr = I32
+	}
 	c.b.WriteString(amd64LLVMBlockName("entry") + ":\n")
 	for _, rs := range regs {
 		r := Reg(rs)
 		name := c.slotName(r)
 		c.regSlot[r] = name
-		fmt.Fprintf(c.b, "  %s = alloca i64\n", name)
-		fmt.Fprintf(c.b, "  store i64 0, ptr %s\n", name)
+		ty := gpr
+		if i386IsMMXReg(r) {
+			ty = I64
+		}
+		fmt.Fprintf(c.b, "  %s = alloca %s\n", name, ty)
+		fmt.Fprintf(c.b, "  store %s 0, ptr %s\n", ty, name)
+	}
+	if c.i386 && c.frameSize > 0 {
+		// SP addresses the local frame, whose bottom holds the outgoing
+		// ABI0 arguments of CALLs.
+		fmt.Fprintf(c.b, "  %%frame = alloca [%d x i8], align 4\n", c.frameSize)
+		if err := c.storeReg(SP, c.ptrToI64("%frame")); err != nil {
+			return err
+		}
 	}
 
 	xIdx := make([]int, 0, len(c.usedXRegs))
@@ -302,17 +365,15 @@ func (c *amd64Ctx) emitEntryAllocas() error {
 	}
 
 	c.flagsZSlot = "%flags_z"
-	c.flagsSltSlot = "%flags_slt"
+	c.flagsSFSlot = "%flags_sf"
 	c.flagsCFSlot = "%flags_cf"
 	c.flagsOFSlot = "%flags_of"
-	fmt.Fprintf(c.b, "  %s = alloca i1\n", c.flagsZSlot)
-	fmt.Fprintf(c.b, "  store i1 false, ptr %s\n", c.flagsZSlot)
-	fmt.Fprintf(c.b, "  %s = alloca i1\n", c.flagsSltSlot)
-	fmt.Fprintf(c.b, "  store i1 false, ptr %s\n", c.flagsSltSlot)
-	fmt.Fprintf(c.b, "  %s = alloca i1\n", c.flagsCFSlot)
-	fmt.Fprintf(c.b, "  store i1 false, ptr %s\n", c.flagsCFSlot)
-	fmt.Fprintf(c.b, "  %s = alloca i1\n", c.flagsOFSlot)
-	fmt.Fprintf(c.b, "  store i1 false, ptr %s\n", c.flagsOFSlot)
+	c.flagsPFSlot = "%flags_pf"
+	c.flagsAFSlot = "%flags_af"
+	for _, slot := range []string{c.flagsZSlot, c.flagsSFSlot, c.flagsCFSlot, c.flagsOFSlot, c.flagsPFSlot, c.flagsAFSlot} {
+		fmt.Fprintf(c.b, "  %s = alloca i1\n", slot)
+		fmt.Fprintf(c.b, "  store i1 false, ptr %s\n", slot)
+	}
 
 	// Virtual stack for stack-manipulation instructions used by some stdlib asm
 	// stubs (e.g. syscall rawVfork paths using POPQ/PUSHQ around SYSCALL).
@@ -340,8 +401,7 @@ func (c *amd64Ctx) emitEntryAllocas() error {
 	if len(c.sig.ArgRegs) > 0 {
 		for i := 0; i < len(c.sig.Args) && i < len(c.sig.ArgRegs); i++ {
 			r := c.sig.ArgRegs[i]
-			slot, ok := c.regSlot[r]
-			if !ok {
+			if _, ok := c.regSlot[r]; !ok && r != FLAGS {
 				continue
 			}
 			arg := fmt.Sprintf("%%arg%d", i)
@@ -352,10 +412,15 @@ func (c *amd64Ctx) emitEntryAllocas() error {
 			if !ok {
 				continue
 			}
-			fmt.Fprintf(c.b, "  store i64 %s, ptr %s\n", v, slot)
+			if err := c.storeContractReg(r, v); err != nil {
+				return err
+			}
 		}
 		return nil
 	}
+	if c.i386 {
+		return nil
+	}
 
 	// Default Go internal ABI integer argument registers (ssa/opGen.go).
 	goABI := []Reg{AX, BX, CX, DI, SI, Reg("R8"), Reg("R9"), Reg("R10"), Reg("R11")}
@@ -443,9 +508,7 @@ func (c *amd64Ctx) popI64() string {
 func amd64ValueAsI64(c *amd64Ctx, ty LLVMType, v string) (out string, ok bool, err error) {
 	switch ty {
 	case Ptr:
-		t := c.newTmp()
-		fmt.Fprintf(c.b, "  %%%s = ptrtoint ptr %s to i64\n", t, v)
-		return "%" + t, true, nil
+		return c.ptrToI64(v), true, nil
 	case I1:
 		t := c.newTmp()
 		fmt.Fprintf(c.b, "  %%%s = zext i1 %s to i64\n", t, v)
@@ -542,11 +605,20 @@ func (c *amd64Ctx) loadReg(r Reg) (string, error) {
 		fmt.Fprintf(c.b, "  %%%s = and i64 %s, 255\n", t, v)
 		return "%" + t, nil
 	}
+	if amd64IsVecOrMaskReg(r) {
+		return "", fmt.Errorf("amd64: %s is not a general-purpose register", r)
+	}
 	slot, ok := c.regSlot[r]
 	if !ok {
 		return "0", nil
 	}
 	t := c.newTmp()
+	if c.i386 && !i386IsMMXReg(r) {
+		fmt.Fprintf(c.b, "  %%%s = load i32, ptr %s\n", t, slot)
+		z := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = zext i32 %%%s to i64\n", z, t)
+		return "%" + z, nil
+	}
 	fmt.Fprintf(c.b, "  %%%s = load i64, ptr %s\n", t, slot)
 	return "%" + t, nil
 }
@@ -574,10 +646,19 @@ func (c *amd64Ctx) storeReg(r Reg, v string) error {
 		r = base
 		v = "%" + merged
 	}
+	if amd64IsVecOrMaskReg(r) {
+		return fmt.Errorf("amd64: %s is not a general-purpose register", r)
+	}
 	slot, ok := c.regSlot[r]
 	if !ok {
 		return nil
 	}
+	if c.i386 && !i386IsMMXReg(r) {
+		t := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = trunc i64 %s to i32\n", t, v)
+		fmt.Fprintf(c.b, "  store i32 %%%s, ptr %s\n", t, slot)
+		return nil
+	}
 	fmt.Fprintf(c.b, "  store i64 %s, ptr %s\n", v, slot)
 	return nil
 }
@@ -750,50 +831,6 @@ func (c *amd64Ctx) storeK(r Reg, v string) error {
 	return nil
 }
 
-func (c *amd64Ctx) setZFlagFromI64(v string) {
-	t := c.newTmp()
-	fmt.Fprintf(c.b, "  %%%s = icmp eq i64 %s, 0\n", t, v)
-	fmt.Fprintf(c.b, "  store i1 %%%s, ptr %s\n", t, c.flagsZSlot)
-}
-
-func (c *amd64Ctx) setZSFlagsFromI64(v string) {
-	z := c.newTmp()
-	fmt.Fprintf(c.b, "  %%%s = icmp eq i64 %s, 0\n", z, v)
-	fmt.Fprintf(c.b, "  store i1 %%%s, ptr %s\n", z, c.flagsZSlot)
-	slt := c.newTmp()
-	fmt.Fprintf(c.b, "  %%%s = icmp slt i64 %s, 0\n", slt, v)
-	fmt.Fprintf(c.b, "  store i1 %%%s, ptr %s\n", slt, c.flagsSltSlot)
-}
-
-func (c *amd64Ctx) setZSFlagsFromI32(v string) {
-	z := c.newTmp()
-	fmt.Fprintf(c.b, "  %%%s = icmp eq i32 %s, 0\n", z, v)
-	fmt.Fprintf(c.b, "  store i1 %%%s, ptr %s\n", z, c.flagsZSlot)
-	slt := c.newTmp()
-	fmt.Fprintf(c.b, "  %%%s = icmp slt i32 %s, 0\n", slt, v)
-	fmt.Fprintf(c.b, "  store i1 %%%s, ptr %s\n", slt, c.flagsSltSlot)
-}
-
-func (c *amd64Ctx) setCmpFlags(a, b string) {
-	// Plan 9 CMPQ uses source-destination order for flag interpretation here:
-	// treat CMPQ a,b as deriving less-than from a<b.
-	zt := c.newTmp()
-	fmt.Fprintf(c.b, "  %%%s = icmp eq i64 %s, %s\n", zt, a, b)
-	fmt.Fprintf(c.b, "  store i1 %%%s, ptr %s\n", zt, c.flagsZSlot)
-	slt := c.newTmp()
-	fmt.Fprintf(c.b, "  %%%s = icmp slt i64 %s, %s\n", slt, a, b)
-	fmt.Fprintf(c.b, "  store i1 %%%s, ptr %s\n", slt, c.flagsSltSlot)
-	ult := c.newTmp()
-	fmt.Fprintf(c.b, "  %%%s = icmp ult i64 %s, %s\n", ult, a, b)
-	fmt.Fprintf(c.b, "  store i1 %%%s, ptr %s\n", ult, c.flagsCFSlot)
-}
-
-func (c *amd64Ctx) loadFlag(slot string) string {
-	t := c.newTmp()
-	fmt.Fprintf(c.b, "  %%%s = load i1, ptr %s\n", t, slot)
-	return "%" + t
-}
-
 func (c *amd64Ctx) fpParam(off int64) (slot FrameSlot, ok bool) {
 	s, ok := c.fpParams[off]
 	if !ok {
@@ -839,6 +876,11 @@ func (c *amd64Ctx) markFPResultWritten(off int64) {
 func (c *amd64Ctx) evalFPToI64(off int64) (string, error) {
 	slot, ok := c.fpParam(off)
 	if !ok {
+		if c.i386 {
+			if v, ok, err := c.evalFPHigh386(off); ok {
+				return v, err
+			}
+		}
 		if alloca, ty, rok := c.fpResultAlloca(off); rok && ty != "" {
 			ld := c.newTmp()
 			fmt.Fprintf(c.b, "  %%%s = load %s, ptr %s\n", ld, ty, alloca)
@@ -863,9 +905,7 @@ func (c *amd64Ctx) evalFPToI64(off int64) (string, error) {
 				fmt.Fprintf(c.b, "  %%%s = zext i32 %s to i64\n", z, v)
 				return "%" + z, nil
 			case Ptr:
-				p := c.newTmp()
-				fmt.Fprintf(c.b, "  %%%s = ptrtoint ptr %s to i64\n", p, v)
-				return "%" + p, nil
+				return c.ptrToI64(v), nil
 			}
 		}
 		// Keep translating when FP offsets can't be recovered from signature
@@ -889,9 +929,7 @@ func (c *amd64Ctx) evalFPToI64(off int64) (string, error) {
 	}
 	switch ty {
 	case Ptr:
-		t := c.newTmp()
-		fmt.Fprintf(c.b, "  %%%s = ptrtoint ptr %s to i64\n", t, arg)
-		return "%" + t, nil
+		return c.ptrToI64(arg), nil
 	case I1:
 		t := c.newTmp()
 		fmt.Fprintf(c.b, "  %%%s = zext i1 %s to i64\n", t, arg)
@@ -987,15 +1025,11 @@ func (c *amd64Ctx) storeFPResult(off int64, ty LLVMType, v string) error {
 			c.markFPResultWritten(off)
 			return nil
 		case ty == I64 && slotTy == Ptr:
-			t := c.newTmp()
-			fmt.Fprintf(c.b, "  %%%s = inttoptr i64 %s to ptr\n", t, v)
-			fmt.Fprintf(c.b, "  store ptr %%%s, ptr %s\n", t, alloca)
+			fmt.Fprintf(c.b, "  store ptr %s, ptr %s\n", c.i64ToPtr(v), alloca)
 			c.markFPResultWritten(off)
 			return nil
 		case ty == Ptr && slotTy == I64:
-			t := c.newTmp()
-			fmt.Fprintf(c.b, "  %%%s = ptrtoint ptr %s to i64\n", t, v)
-			fmt.Fprintf(c.b, "  store i64 %%%s, ptr %s\n", t, alloca)
+			fmt.Fprintf(c.b, "  store i64 %s, ptr %s\n", c.ptrToI64(v), alloca)
 			c.markFPResultWritten(off)
 			return nil
 		case ty == I64 && slotTy == I64:
@@ -1024,6 +1058,10 @@ func isAMD64FloatRetTy(ty LLVMType) bool {
 }
 
 func (c *amd64Ctx) retIntRegByOrd(i int) (Reg, bool) {
+	if c.i386 {
+		// ABI0 returns every result on the stack.
+		return "", false
+	}
 	// Go internal ABI integer return registers on amd64.
 	retRegs := []Reg{AX, BX, CX, DI, SI, Reg("R8"), Reg("R9"), Reg("R10"), Reg("R11")}
 	if i < 0 || i >= len(retRegs) {
@@ -1061,9 +1099,7 @@ func (c *amd64Ctx) loadRetIntRegTyped(ord int, ty LLVMType) (string, error) {
 		fmt.Fprintf(c.b, "  %%%s = trunc i64 %s to i1\n", t, v)
 		return "%" + t, nil
 	case Ptr:
-		t := c.newTmp()
-		fmt.Fprintf(c.b, "  %%%s = inttoptr i64 %s to ptr\n", t, v)
-		return "%" + t, nil
+		return c.i64ToPtr(v), nil
 	case LLVMType("double"):
 		t := c.newTmp()
 		fmt.Fprintf(c.b, "  %%%s = bitcast i64 %s to double\n", t, v)
@@ -1188,9 +1224,34 @@ func (c *amd64Ctx) addrFromMem(mem MemRef) (addrI64 string, err error) {
 }
 
 func (c *amd64Ctx) ptrFromAddrI64(addrI64 string) string {
+	return c.i64ToPtr(addrI64)
+}
+
+// ptrToI64 converts pointer p into the i64 register value model. 386
+// pointers are 32 bits wide and zero-extended.
+func (c *amd64Ctx) ptrToI64(p string) string {
 	t := c.newTmp()
-	fmt.Fprintf(c.b, "  %%%s = inttoptr i64 %s to ptr\n", t, addrI64)
-	return "%" + t
+	if !c.i386 {
+		fmt.Fprintf(c.b, "  %%%s = ptrtoint ptr %s to i64\n", t, p)
+		return "%" + t
+	}
+	fmt.Fprintf(c.b, "  %%%s = ptrtoint ptr %s to i32\n", t, p)
+	z := c.newTmp()
+	fmt.Fprintf(c.b, "  %%%s = zext i32 %%%s to i64\n", z, t)
+	return "%" + z
+}
+
+// i64ToPtr is the inverse of ptrToI64.
+func (c *amd64Ctx) i64ToPtr(v string) string {
+	t := c.newTmp()
+	if !c.i386 {
+		fmt.Fprintf(c.b, "  %%%s = inttoptr i64 %s to ptr\n", t, v)
+		return "%" + t
+	}
+	fmt.Fprintf(c.b, "  %%%s = trunc i64 %s to i32\n", t, v)
+	p := c.newTmp()
+	fmt.Fprintf(c.b, "  %%%s = inttoptr i32 %%%s to ptr\n", p, t)
+	return "%" + p
 }
 
 func (c *amd64Ctx) ptrFromSB(sym string) (ptr string, err error) {
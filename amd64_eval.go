@@ -39,9 +39,7 @@ func (c *amd64Ctx) evalI64(op Operand) (string, error) {
 			return "", err
 		}
 		if addrOnly {
-			t := c.newTmp()
-			fmt.Fprintf(c.b, "  %%%s = ptrtoint ptr %s to i64\n", t, p)
-			return "%" + t, nil
+			return c.ptrToI64(p), nil
 		}
 		t := c.newTmp()
 		fmt.Fprintf(c.b, "  %%%s = load i64, ptr %s, align 1\n", t, p)
@@ -0,0 +1,174 @@
+package plan9asm
+
+import "strings"
+
+// amd64FlagEffect returns how the lowering of ins reads and writes the
+// modeled flags. def only lists flags written on every path; ops missing
+// here are treated as not touching flags, so every flag reader must be
+// listed.
+func amd64FlagEffect(ins Instr) flagEffect {
+	op := strings.ToUpper(string(ins.Op))
+	if cc, ok := amd64JccCond(op); ok {
+		return flagEffect{use: amd64CondFlags[cc]}
+	}
+	if cc, ok := amd64SetccCond(op); ok {
+		return flagEffect{use: amd64CondFlags[cc]}
+	}
+	if _, cc, ok := amd64CmovCond(op); ok {
+		return flagEffect{use: amd64CondFlags[cc]}
+	}
+
+	const (
+		arith = amd64FlagsAll
+		logic = amd64FlagsAll &^ amd64FlagAF
+		shift = amd64FlagCF | amd64FlagOF | amd64FlagZF | amd64FlagSF | amd64FlagPF
+	)
+	switch op {
+	case "CMPB", "CMPW", "CMPL", "CMPQ",
+		"ADDB", "ADDL", "ADDQ", "SUBL", "SUBQ", "NEGL", "NEGQ",
+		"XADDL", "XADDQ", "CMPXCHGL", "CMPXCHGQ",
+		"COMISD", "UCOMISD", "VPTEST", "POPCNTL", "POPCNTQ", "POPFL", "POPFQ":
+		return flagEffect{def: arith}
+	case "TESTB", "TESTW", "TESTL", "TESTQ",
+		"ANDB", "ANDL", "ANDQ", "ORB", "ORL", "ORQ", "XORB", "XORL", "XORQ",
+		"ANDNL", "ANDNQ", "BZHIQ":
+		return flagEffect{def: logic}
+	case "INCL", "INCQ", "DECL", "DECQ":
+		return flagEffect{def: arith &^ amd64FlagCF}
+	case "ADCB", "ADCL", "ADCQ", "SBBL", "SBBQ":
+		return flagEffect{use: amd64FlagCF, def: arith}
+	case "ADCXQ":
+		return flagEffect{use: amd64FlagCF, def: amd64FlagCF}
+	case "ADOXQ":
+		return flagEffect{use: amd64FlagOF, def: amd64FlagOF}
+	case "RCRQ":
+		return flagEffect{use: amd64FlagCF, def: amd64FlagCF | amd64FlagOF}
+	case "MULL", "MULQ", "IMULL", "IMUL3L", "IMULQ", "IMUL3Q":
+		return flagEffect{def: amd64FlagCF | amd64FlagOF}
+	case "BEXTRQ":
+		return flagEffect{def: amd64FlagCF | amd64FlagOF | amd64FlagZF}
+	case "TZCNTQ":
+		return flagEffect{def: amd64FlagCF | amd64FlagZF}
+	case "BSFQ", "BSFL", "BSRQ", "BSRL", "CMPXCHG8B":
+		return flagEffect{def: amd64FlagZF}
+	case "BTQ", "BTSQ":
+		return flagEffect{def: amd64FlagCF}
+	case "SAHF":
+		return flagEffect{def: arith &^ amd64FlagOF}
+	case "SCASB":
+		// REPN SCASB leaves the flags alone when CX starts at zero.
+		return flagEffect{use: arith, def: arith}
+	case "PUSHFL", "PUSHFQ", "LAHF":
+		return flagEffect{use: arith}
+	case "SHLQ", "SHRQ", "SARQ", "SALQ", "SHLL", "SHRL", "SARL", "SALL":
+		return amd64CountFlagEffect(op, ins, shift)
+	case "SHLB":
+		return flagEffect{use: shift, def: shift}
+	case "ROLL", "ROLQ", "RORL", "RORQ":
+		return amd64CountFlagEffect(op, ins, amd64FlagCF|amd64FlagOF)
+	}
+	if def, ok := amd64InlineAsmFlags[Op(op)]; ok {
+		return flagEffect{def: def}
+	}
+	return flagEffect{}
+}
+
+// amd64CountFlagEffect is the flag effect of a shift or rotate that writes
+// flags unless its count is zero: an immediate count that masks to zero
+// leaves them alone, and a register count may be zero, so the previous
+// flags are read.
+func amd64CountFlagEffect(op string, ins Instr, flags flagMask) flagEffect {
+	if len(ins.Args) > 0 && ins.Args[0].Kind == OpImm {
+		mask := int64(63)
+		if op[len(op)-1] == 'L' {
+			mask = 31
+		}
+		if ins.Args[0].Imm&mask == 0 {
+			return flagEffect{}
+		}
+		return flagEffect{def: flags}
+	}
+	return flagEffect{use: flags, def: flags}
+}
+
+// flagLiveness computes the flags live after each instruction of c.blocks.
+func (c *amd64Ctx) flagLiveness() [][]flagMask {
+	effects := make([][]flagEffect, len(c.blocks))
+	succs := make([]flagSuccs, len(c.blocks))
+	for bi, blk := range c.blocks {
+		effects[bi] = make([]flagEffect, len(blk.instrs))
+		for ii, ins := range blk.instrs {
+			effects[bi][ii] = regContractFlagEffect(ins, amd64FlagEffect(ins), c.sig, c.resolve, c.sigs, amd64FlagsAll)
+		}
+		succs[bi] = c.flagSuccs(bi)
+	}
+	if c.cfg.keepDeadFlags {
+		return flagAllLive(effects, amd64FlagsAll)
+	}
+	return flagLiveOut(effects, succs, amd64FlagsAll)
+}
+
+// flagSuccs mirrors how lowerBranch resolves the jump ending block bi.
+func (c *amd64Ctx) flagSuccs(bi int) flagSuccs {
+	var fall flagSuccs
+	if bi+1 < len(c.blocks) {
+		fall.blocks = []int{bi + 1}
+	}
+	instrs := c.blocks[bi].instrs
+	if len(instrs) == 0 {
+		return fall
+	}
+	ii := len(instrs) - 1
+	last := instrs[ii]
+	op := strings.ToUpper(string(last.Op))
+	if last.Op == OpRET {
+		return flagSuccs{}
+	}
+	if !amd64IsJump(op) {
+		return fall
+	}
+	if len(last.Args) != 1 {
+		return flagSuccs{unknown: true}
+	}
+	target := -1
+	byName := func(name string) int {
+		for i, blk := range c.blocks {
+			if blk.name == name {
+				return i
+			}
+		}
+		return -1
+	}
+	arg := last.Args[0]
+	switch arg.Kind {
+	case OpIdent:
+		target = byName(arg.Ident)
+	case OpReg:
+		target = byName(string(arg.Reg))
+		if target < 0 && op == "JMP" {
+			// Indirect tail jump.
+			return flagSuccs{}
+		}
+	case OpSym:
+		s := strings.TrimSpace(arg.Sym)
+		if op == "JMP" && strings.HasSuffix(s, "(SB)") {
+			return flagSuccs{}
+		}
+		target = byName(strings.TrimSuffix(strings.TrimSuffix(s, "(SB)"), "<>"))
+	case OpMem:
+		if strings.EqualFold(string(arg.Mem.Base), "PC") {
+			if tbi, ok := c.blockByIdx[c.blockBase[bi]+ii+int(arg.Mem.Off)]; ok {
+				target = tbi
+			}
+		} else if op == "JMP" {
+			return flagSuccs{}
+		}
+	}
+	if target < 0 {
+		return flagSuccs{unknown: true}
+	}
+	if op == "JMP" {
+		return flagSuccs{blocks: []int{target}}
+	}
+	return flagSuccs{blocks: append([]int{target}, fall.blocks...)}
+}
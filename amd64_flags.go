@@ -0,0 +1,550 @@
+package plan9asm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// EFLAGS is modeled as one i1 alloca per status flag. Lowerings define every
+// flag the instruction defines on hardware; flags the SDM leaves undefined
+// keep their previous value.
+
+// Modeled flags as a flagMask, for liveness (see flaglive.go).
+const (
+	amd64FlagCF flagMask = 1 << iota
+	amd64FlagPF
+	amd64FlagAF
+	amd64FlagZF
+	amd64FlagSF
+	amd64FlagOF
+
+	amd64FlagsAll = amd64FlagCF | amd64FlagPF | amd64FlagAF | amd64FlagZF | amd64FlagSF | amd64FlagOF
+)
+
+// amd64FlagBits gives the EFLAGS bit position of each modeled flag, used to
+// compose and decompose the flags word for PUSHFQ/POPFQ and LAHF/SAHF.
+var amd64FlagBits = []struct {
+	bit  uint
+	mask flagMask
+	slot func(c *amd64Ctx) string
+}{
+	{0, amd64FlagCF, func(c *amd64Ctx) string { return c.flagsCFSlot }},
+	{2, amd64FlagPF, func(c *amd64Ctx) string { return c.flagsPFSlot }},
+	{4, amd64FlagAF, func(c *amd64Ctx) string { return c.flagsAFSlot }},
+	{6, amd64FlagZF, func(c *amd64Ctx) string { return c.flagsZSlot }},
+	{7, amd64FlagSF, func(c *amd64Ctx) string { return c.flagsSFSlot }},
+	{11, amd64FlagOF, func(c *amd64Ctx) string { return c.flagsOFSlot }},
+}
+
+// amd64FlagsFixed holds the EFLAGS bits that always read as set in user mode
+// (reserved bit 1 and IF).
+const amd64FlagsFixed = 0x202
+
+// amd64CondCodes maps condition suffixes, in both Plan 9 and Intel spelling,
+// to the Plan 9 name evaluated by condValue.
+var amd64CondCodes = map[string]string{
+	"EQ": "EQ", "E": "EQ", "Z": "EQ",
+	"NE": "NE", "NZ": "NE",
+	"CS": "CS", "LO": "CS", "B": "CS", "C": "CS", "NAE": "CS",
+	"CC": "CC", "HS": "CC", "AE": "CC", "NC": "CC", "NB": "CC",
+	"HI": "HI", "A": "HI", "NBE": "HI",
+	"LS": "LS", "BE": "LS", "NA": "LS",
+	"LT": "LT", "L": "LT", "NGE": "LT",
+	"GE": "GE", "NL": "GE",
+	"GT": "GT", "G": "GT", "NLE": "GT",
+	"LE": "LE", "NG": "LE",
+	"MI": "MI", "S": "MI",
+	"PL": "PL", "NS": "PL",
+	"OS": "OS", "O": "OS",
+	"OC": "OC", "NO": "OC",
+	"PS": "PS", "PE": "PS", "P": "PS",
+	"PC": "PC", "PO": "PC", "NP": "PC",
+}
+
+// amd64CondFlags lists the flags each canonical condition reads.
+var amd64CondFlags = map[string]flagMask{
+	"EQ": amd64FlagZF, "NE": amd64FlagZF,
+	"CS": amd64FlagCF, "CC": amd64FlagCF,
+	"HI": amd64FlagCF | amd64FlagZF, "LS": amd64FlagCF | amd64FlagZF,
+	"LT": amd64FlagSF | amd64FlagOF, "GE": amd64FlagSF | amd64FlagOF,
+	"GT": amd64FlagZF | amd64FlagSF | amd64FlagOF, "LE": amd64FlagZF | amd64FlagSF | amd64FlagOF,
+	"MI": amd64FlagSF, "PL": amd64FlagSF,
+	"OS": amd64FlagOF, "OC": amd64FlagOF,
+	"PS": amd64FlagPF, "PC": amd64FlagPF,
+}
+
+// amd64JccCond returns the condition of a conditional jump such as JLT or JPE.
+func amd64JccCond(op string) (string, bool) {
+	op = strings.ToUpper(op)
+	if !strings.HasPrefix(op, "J") {
+		return "", false
+	}
+	cc, ok := amd64CondCodes[op[1:]]
+	return cc, ok
+}
+
+// amd64IsJump reports whether op is JMP or a conditional jump.
+func amd64IsJump(op string) bool {
+	if strings.EqualFold(op, "JMP") {
+		return true
+	}
+	_, ok := amd64JccCond(op)
+	return ok
+}
+
+// amd64SetccCond returns the condition of SETcc.
+func amd64SetccCond(op string) (string, bool) {
+	op = strings.ToUpper(op)
+	if !strings.HasPrefix(op, "SET") {
+		return "", false
+	}
+	cc, ok := amd64CondCodes[op[3:]]
+	return cc, ok
+}
+
+// amd64CmovCond splits CMOV{W,L,Q}cc into operand width and condition.
+func amd64CmovCond(op string) (LLVMType, string, bool) {
+	op = strings.ToUpper(op)
+	if !strings.HasPrefix(op, "CMOV") || len(op) < 6 {
+		return "", "", false
+	}
+	var ty LLVMType
+	switch op[4] {
+	case 'W':
+		ty = I16
+	case 'L':
+		ty = I32
+	case 'Q':
+		ty = I64
+	default:
+		return "", "", false
+	}
+	cc, ok := amd64CondCodes[op[5:]]
+	return ty, cc, ok
+}
+
+// condValue evaluates condition cc (any spelling in amd64CondCodes) against
+// the modeled flags.
+func (c *amd64Ctx) condValue(cc string) (string, error) {
+	canon, ok := amd64CondCodes[strings.ToUpper(cc)]
+	if !ok {
+		return "", fmt.Errorf("amd64: unsupported condition %q", cc)
+	}
+	not := func(x string) string {
+		t := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = xor i1 %s, true\n", t, x)
+		return "%" + t
+	}
+	or := func(a, b string) string {
+		t := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = or i1 %s, %s\n", t, a, b)
+		return "%" + t
+	}
+	// less is SF != OF, the signed less-than after CMP.
+	less := func() string {
+		sf := c.loadFlag(c.flagsSFSlot)
+		of := c.loadFlag(c.flagsOFSlot)
+		t := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = xor i1 %s, %s\n", t, sf, of)
+		return "%" + t
+	}
+
+	switch canon {
+	case "EQ":
+		return c.loadFlag(c.flagsZSlot), nil
+	case "NE":
+		return not(c.loadFlag(c.flagsZSlot)), nil
+	case "CS":
+		return c.loadFlag(c.flagsCFSlot), nil
+	case "CC":
+		return not(c.loadFlag(c.flagsCFSlot)), nil
+	case "LS":
+		return or(c.loadFlag(c.flagsCFSlot), c.loadFlag(c.flagsZSlot)), nil
+	case "HI":
+		return not(or(c.loadFlag(c.flagsCFSlot), c.loadFlag(c.flagsZSlot))), nil
+	case "LT":
+		return less(), nil
+	case "GE":
+		return not(less()), nil
+	case "LE":
+		return or(less(), c.loadFlag(c.flagsZSlot)), nil
+	case "GT":
+		return not(or(less(), c.loadFlag(c.flagsZSlot))), nil
+	case "MI":
+		return c.loadFlag(c.flagsSFSlot), nil
+	case "PL":
+		return not(c.loadFlag(c.flagsSFSlot)), nil
+	case "OS":
+		return c.loadFlag(c.flagsOFSlot), nil
+	case "OC":
+		return not(c.loadFlag(c.flagsOFSlot)), nil
+	case "PS":
+		return c.loadFlag(c.flagsPFSlot), nil
+	case "PC":
+		return not(c.loadFlag(c.flagsPFSlot)), nil
+	}
+	return "", fmt.Errorf("amd64: unsupported condition %q", cc)
+}
+
+func (c *amd64Ctx) loadFlag(slot string) string {
+	t := c.newTmp()
+	fmt.Fprintf(c.b, "  %%%s = load i1, ptr %s\n", t, slot)
+	return "%" + t
+}
+
+// storeFlag writes v to the flag in slot unless no later instruction reads
+// that flag.
+func (c *amd64Ctx) storeFlag(slot string, v string) {
+	for _, f := range amd64FlagBits {
+		if f.slot(c) == slot && c.flagsDead&f.mask != 0 {
+			return
+		}
+	}
+	fmt.Fprintf(c.b, "  store i1 %s, ptr %s\n", v, slot)
+}
+
+// flagLive reports whether any flag in m may be read after the current
+// instruction. Helpers use it to skip computing dead flags.
+func (c *amd64Ctx) flagLive(m flagMask) bool {
+	return c.flagsDead&m != m
+}
+
+// parityFlag returns PF for res: set when the low byte has an even number
+// of one bits.
+func (c *amd64Ctx) parityFlag(ty LLVMType, res string) string {
+	x := res
+	if ty != I8 {
+		t := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = trunc %s %s to i8\n", t, ty, res)
+		x = "%" + t
+	}
+	for _, sh := range []int{4, 2, 1} {
+		s := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = lshr i8 %s, %d\n", s, x, sh)
+		f := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = xor i8 %s, %%%s\n", f, x, s)
+		x = "%" + f
+	}
+	lsb := c.newTmp()
+	fmt.Fprintf(c.b, "  %%%s = and i8 %s, 1\n", lsb, x)
+	pf := c.newTmp()
+	fmt.Fprintf(c.b, "  %%%s = icmp eq i8 %%%s, 0\n", pf, lsb)
+	return "%" + pf
+}
+
+// setResultFlags sets ZF, SF and PF from a result of type ty.
+func (c *amd64Ctx) setResultFlags(ty LLVMType, res string) {
+	if c.flagLive(amd64FlagZF) {
+		z := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = icmp eq %s %s, 0\n", z, ty, res)
+		c.storeFlag(c.flagsZSlot, "%"+z)
+	}
+	if c.flagLive(amd64FlagSF) {
+		sf := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = icmp slt %s %s, 0\n", sf, ty, res)
+		c.storeFlag(c.flagsSFSlot, "%"+sf)
+	}
+	if c.flagLive(amd64FlagPF) {
+		c.storeFlag(c.flagsPFSlot, c.parityFlag(ty, res))
+	}
+}
+
+// setLogicFlags models AND/OR/XOR/TEST: ZF, SF and PF from the result, CF and
+// OF cleared. AF is undefined.
+func (c *amd64Ctx) setLogicFlags(ty LLVMType, res string) {
+	c.setResultFlags(ty, res)
+	c.storeFlag(c.flagsCFSlot, "false")
+	c.storeFlag(c.flagsOFSlot, "false")
+}
+
+// auxCarry returns AF, the carry or borrow out of bit 3, which for both
+// addition and subtraction is bit 4 of a^b^res.
+func (c *amd64Ctx) auxCarry(ty LLVMType, a, b, res string) string {
+	x1 := c.newTmp()
+	fmt.Fprintf(c.b, "  %%%s = xor %s %s, %s\n", x1, ty, a, b)
+	x2 := c.newTmp()
+	fmt.Fprintf(c.b, "  %%%s = xor %s %%%s, %s\n", x2, ty, x1, res)
+	bit := c.newTmp()
+	fmt.Fprintf(c.b, "  %%%s = and %s %%%s, 16\n", bit, ty, x2)
+	af := c.newTmp()
+	fmt.Fprintf(c.b, "  %%%s = icmp ne %s %%%s, 0\n", af, ty, bit)
+	return "%" + af
+}
+
+// setAddFlags models res = a + b + carryIn. carryIn is an i1 value, or ""
+// for plain ADD. With writeCF false CF is preserved, as INC does.
+func (c *amd64Ctx) setAddFlags(ty LLVMType, a, b, carryIn, res string, writeCF bool) {
+	c.setResultFlags(ty, res)
+	if writeCF && c.flagLive(amd64FlagCF) {
+		// Carry out: res < a, or res == a when a carry came in (b + 1 wrapped).
+		cf := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = icmp ult %s %s, %s\n", cf, ty, res, a)
+		out := "%" + cf
+		if carryIn != "" {
+			eq := c.newTmp()
+			fmt.Fprintf(c.b, "  %%%s = icmp eq %s %s, %s\n", eq, ty, res, a)
+			wrap := c.newTmp()
+			fmt.Fprintf(c.b, "  %%%s = and i1 %s, %%%s\n", wrap, carryIn, eq)
+			t := c.newTmp()
+			fmt.Fprintf(c.b, "  %%%s = or i1 %s, %%%s\n", t, out, wrap)
+			out = "%" + t
+		}
+		c.storeFlag(c.flagsCFSlot, out)
+	}
+	if c.flagLive(amd64FlagOF) {
+		// Signed overflow: both inputs have the same sign and res differs.
+		x1 := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = xor %s %s, %s\n", x1, ty, a, res)
+		x2 := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = xor %s %s, %s\n", x2, ty, b, res)
+		x3 := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = and %s %%%s, %%%s\n", x3, ty, x1, x2)
+		of := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = icmp slt %s %%%s, 0\n", of, ty, x3)
+		c.storeFlag(c.flagsOFSlot, "%"+of)
+	}
+	if c.flagLive(amd64FlagAF) {
+		c.storeFlag(c.flagsAFSlot, c.auxCarry(ty, a, b, res))
+	}
+}
+
+// setSubFlags models res = a - b - borrowIn. borrowIn is an i1 value, or ""
+// for plain SUB. With writeCF false CF is preserved, as DEC does.
+func (c *amd64Ctx) setSubFlags(ty LLVMType, a, b, borrowIn, res string, writeCF bool) {
+	c.setResultFlags(ty, res)
+	if writeCF && c.flagLive(amd64FlagCF) {
+		cf := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = icmp ult %s %s, %s\n", cf, ty, a, b)
+		out := "%" + cf
+		if borrowIn != "" {
+			eq := c.newTmp()
+			fmt.Fprintf(c.b, "  %%%s = icmp eq %s %s, %s\n", eq, ty, a, b)
+			wrap := c.newTmp()
+			fmt.Fprintf(c.b, "  %%%s = and i1 %s, %%%s\n", wrap, borrowIn, eq)
+			t := c.newTmp()
+			fmt.Fprintf(c.b, "  %%%s = or i1 %s, %%%s\n", t, out, wrap)
+			out = "%" + t
+		}
+		c.storeFlag(c.flagsCFSlot, out)
+	}
+	if c.flagLive(amd64FlagOF) {
+		// Signed overflow: inputs have different signs and res differs from a.
+		x1 := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = xor %s %s, %s\n", x1, ty, a, b)
+		x2 := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = xor %s %s, %s\n", x2, ty, a, res)
+		x3 := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = and %s %%%s, %%%s\n", x3, ty, x1, x2)
+		of := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = icmp slt %s %%%s, 0\n", of, ty, x3)
+		c.storeFlag(c.flagsOFSlot, "%"+of)
+	}
+	if c.flagLive(amd64FlagAF) {
+		c.storeFlag(c.flagsAFSlot, c.auxCarry(ty, a, b, res))
+	}
+}
+
+// setMulFlags models MUL/IMUL: CF and OF both report that the full product
+// did not fit the destination. SF, ZF, AF and PF are undefined.
+func (c *amd64Ctx) setMulFlags(overflow string) {
+	c.storeFlag(c.flagsCFSlot, overflow)
+	c.storeFlag(c.flagsOFSlot, overflow)
+}
+
+// setIMulFlags sets CF and OF when the i128 product does not equal the
+// sign extension of its truncated i64 result lo.
+func (c *amd64Ctx) setIMulFlags(lo, product string) {
+	if !c.flagLive(amd64FlagCF | amd64FlagOF) {
+		return
+	}
+	ext := c.newTmp()
+	fmt.Fprintf(c.b, "  %%%s = sext i64 %s to i128\n", ext, lo)
+	ov := c.newTmp()
+	fmt.Fprintf(c.b, "  %%%s = icmp ne i128 %%%s, %s\n", ov, ext, product)
+	c.setMulFlags("%" + ov)
+}
+
+// setShiftFlags models SHL/SHR/SAR of v by amt (already masked, type ty)
+// producing res. kind is 'l', 'r' or 'a' for SHL, SHR and SAR. A zero count
+// leaves all flags unchanged, so a register count selects between the new
+// and the previous flags. OF is only architecturally defined for a count of
+// one; other counts store the same formula.
+func (c *amd64Ctx) setShiftFlags(ty LLVMType, kind byte, v, amt, res string) {
+	if amt == "0" || !c.flagLive(amd64FlagCF|amd64FlagOF|amd64FlagZF|amd64FlagSF|amd64FlagPF) {
+		return
+	}
+	width, _ := llvmIntBits(ty)
+	variable := strings.HasPrefix(amt, "%")
+	nz, safe := "", amt
+	if variable {
+		nz = c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = icmp ne %s %s, 0\n", nz, ty, amt)
+		t := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = select i1 %%%s, %s %s, %s 1\n", t, nz, ty, amt, ty)
+		safe = "%" + t
+	}
+	set := func(slot, v string) {
+		if !variable {
+			c.storeFlag(slot, v)
+			return
+		}
+		old := c.loadFlag(slot)
+		t := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = select i1 %%%s, i1 %s, i1 %s\n", t, nz, v, old)
+		c.storeFlag(slot, "%"+t)
+	}
+
+	cf := ""
+	if c.flagLive(amd64FlagCF) || (kind == 'l' && c.flagLive(amd64FlagOF)) {
+		// CF is the last bit shifted out.
+		bitPos := c.newTmp()
+		outBit := c.newTmp()
+		if kind == 'l' {
+			fmt.Fprintf(c.b, "  %%%s = sub %s %d, %s\n", bitPos, ty, width, safe)
+		} else {
+			fmt.Fprintf(c.b, "  %%%s = sub %s %s, 1\n", bitPos, ty, safe)
+		}
+		fmt.Fprintf(c.b, "  %%%s = lshr %s %s, %%%s\n", outBit, ty, v, bitPos)
+		lsb := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = trunc %s %%%s to i1\n", lsb, ty, outBit)
+		cf = "%" + lsb
+		if c.flagLive(amd64FlagCF) {
+			set(c.flagsCFSlot, cf)
+		}
+	}
+	if c.flagLive(amd64FlagOF) {
+		of := "false"
+		switch kind {
+		case 'l':
+			// OF = MSB(res) ^ CF.
+			msb := c.newTmp()
+			fmt.Fprintf(c.b, "  %%%s = icmp slt %s %s, 0\n", msb, ty, res)
+			t := c.newTmp()
+			fmt.Fprintf(c.b, "  %%%s = xor i1 %%%s, %s\n", t, msb, cf)
+			of = "%" + t
+		case 'r':
+			// OF = MSB of the original operand.
+			msb := c.newTmp()
+			fmt.Fprintf(c.b, "  %%%s = icmp slt %s %s, 0\n", msb, ty, v)
+			of = "%" + msb
+		}
+		set(c.flagsOFSlot, of)
+	}
+	if c.flagLive(amd64FlagZF) {
+		z := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = icmp eq %s %s, 0\n", z, ty, res)
+		set(c.flagsZSlot, "%"+z)
+	}
+	if c.flagLive(amd64FlagSF) {
+		sf := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = icmp slt %s %s, 0\n", sf, ty, res)
+		set(c.flagsSFSlot, "%"+sf)
+	}
+	if c.flagLive(amd64FlagPF) {
+		set(c.flagsPFSlot, c.parityFlag(ty, res))
+	}
+}
+
+// setRotateFlags models ROL/ROR: CF receives the bit rotated into the far
+// end and OF (count of one) the XOR of the top result bits. SF, ZF, PF and
+// AF are unaffected. A zero count leaves CF and OF unchanged.
+func (c *amd64Ctx) setRotateFlags(ty LLVMType, left bool, amt, res string) {
+	if amt == "0" || !c.flagLive(amd64FlagCF|amd64FlagOF) {
+		return
+	}
+	width, _ := llvmIntBits(ty)
+	msb := c.newTmp()
+	fmt.Fprintf(c.b, "  %%%s = icmp slt %s %s, 0\n", msb, ty, res)
+	var cf, of string
+	if left {
+		lsb := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = trunc %s %s to i1\n", lsb, ty, res)
+		cf = "%" + lsb
+		t := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = xor i1 %%%s, %s\n", t, msb, cf)
+		of = "%" + t
+	} else {
+		cf = "%" + msb
+		next := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = lshr %s %s, %d\n", next, ty, res, width-2)
+		b := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = trunc %s %%%s to i1\n", b, ty, next)
+		t := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = xor i1 %%%s, %%%s\n", t, msb, b)
+		of = "%" + t
+	}
+	if strings.HasPrefix(amt, "%") {
+		nz := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = icmp ne %s %s, 0\n", nz, ty, amt)
+		for _, f := range []struct {
+			mask    flagMask
+			slot, v string
+		}{{amd64FlagCF, c.flagsCFSlot, cf}, {amd64FlagOF, c.flagsOFSlot, of}} {
+			if !c.flagLive(f.mask) {
+				continue
+			}
+			old := c.loadFlag(f.slot)
+			t := c.newTmp()
+			fmt.Fprintf(c.b, "  %%%s = select i1 %%%s, i1 %s, i1 %s\n", t, nz, f.v, old)
+			c.storeFlag(f.slot, "%"+t)
+		}
+		return
+	}
+	c.storeFlag(c.flagsCFSlot, cf)
+	c.storeFlag(c.flagsOFSlot, of)
+}
+
+func (c *amd64Ctx) setCmpFlags(a, b string) {
+	c.setCmpFlagsSized(I64, a, b)
+}
+
+// setCmpFlagsSized models CMP* a, b. Plan 9 lists the operands in
+// source-destination order but CMP compares them left to right, so the flags
+// are those of a - b: JLT after CMPQ a, b jumps when a < b.
+func (c *amd64Ctx) setCmpFlagsSized(ty LLVMType, a, b string) {
+	res := c.newTmp()
+	fmt.Fprintf(c.b, "  %%%s = sub %s %s, %s\n", res, ty, a, b)
+	c.setSubFlags(ty, a, b, "", "%"+res, true)
+}
+
+// setTestFlagsSized models TEST* a, b: the logic flags of a & b.
+func (c *amd64Ctx) setTestFlagsSized(ty LLVMType, a, b string) {
+	and := c.newTmp()
+	fmt.Fprintf(c.b, "  %%%s = and %s %s, %s\n", and, ty, a, b)
+	c.setLogicFlags(ty, "%"+and)
+}
+
+// flagsWord composes the low 16 bits of RFLAGS from the modeled flags.
+func (c *amd64Ctx) flagsWord() string {
+	acc := fmt.Sprintf("%d", amd64FlagsFixed)
+	for _, f := range amd64FlagBits {
+		v := c.loadFlag(f.slot(c))
+		z := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = zext i1 %s to i64\n", z, v)
+		sh := "%" + z
+		if f.bit != 0 {
+			t := c.newTmp()
+			fmt.Fprintf(c.b, "  %%%s = shl i64 %%%s, %d\n", t, z, f.bit)
+			sh = "%" + t
+		}
+		o := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = or i64 %s, %s\n", o, acc, sh)
+		acc = "%" + o
+	}
+	return acc
+}
+
+// setFlagsFromWord loads the modeled flags from an RFLAGS value. Only flags
+// at bit positions below maxBit are written (8 for SAHF).
+func (c *amd64Ctx) setFlagsFromWord(w string, maxBit uint) {
+	for _, f := range amd64FlagBits {
+		if f.bit >= maxBit || !c.flagLive(f.mask) {
+			continue
+		}
+		sh := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = lshr i64 %s, %d\n", sh, w, f.bit)
+		b := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = trunc i64 %%%s to i1\n", b, sh)
+		c.storeFlag(f.slot(c), "%"+b)
+	}
+}
@@ -0,0 +1,288 @@
+//go:build !llgo
+// +build !llgo
+
+package plan9asm
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestAMD64CondSpellings(t *testing.T) {
+	for _, op := range []string{"JO", "JNO", "JOS", "JPE", "JPO", "JPS", "JPC", "JNP", "JA", "JNBE", "JG", "JNLE"} {
+		if _, ok := amd64JccCond(op); !ok {
+			t.Fatalf("amd64JccCond(%q) not recognized", op)
+		}
+	}
+	if _, ok := amd64JccCond("JMP"); ok {
+		t.Fatalf("JMP must not be a conditional jump")
+	}
+	if cc, _ := amd64SetccCond("SETPE"); cc != "PS" {
+		t.Fatalf("SETPE -> %q, want PS", cc)
+	}
+	if ty, cc, _ := amd64CmovCond("CMOVLPC"); ty != I32 || cc != "PC" {
+		t.Fatalf("CMOVLPC -> %s %q, want i32 PC", ty, cc)
+	}
+	if _, _, ok := amd64CmovCond("CMOVBQEQ"); ok {
+		t.Fatalf("CMOVBQEQ must not be recognized")
+	}
+}
+
+// amd64FlagsTestSig is the (a, b int64) int64 signature shared by the
+// execution tests below.
+func amd64FlagsTestSig(name string) FuncSig {
+	return FuncSig{
+		Name: name,
+		Args: []LLVMType{I64, I64},
+		Ret:  I64,
+		Frame: FrameLayout{
+			Params: []FrameSlot{
+				{Offset: 0, Type: I64, Index: 0, Field: -1},
+				{Offset: 8, Type: I64, Index: 1, Field: -1},
+			},
+			Results: []FrameSlot{
+				{Offset: 16, Type: I64, Index: 0, Field: -1},
+			},
+		},
+	}
+}
+
+// TestRuntimeExecAMD64Flags compares the modeled RFLAGS word after each ALU
+// op with what the host CPU produces for the same operands.
+func TestRuntimeExecAMD64Flags(t *testing.T) {
+	if runtime.GOARCH != "amd64" {
+		t.Skip("runtime execution test only runs on amd64 host")
+	}
+
+	llc, clang, ok := findLlcAndClang(t)
+	if !ok {
+		t.Skip("llc/clang not found")
+	}
+
+	ops := []struct {
+		name, plan9, gnu string
+	}{
+		{"addq", "ADDQ BX, AX", "addq %2, %1"},
+		{"subq", "SUBQ BX, AX", "subq %2, %1"},
+		{"addl", "ADDL BX, AX", "addl %k2, %k1"},
+		{"subl", "SUBL BX, AX", "subl %k2, %k1"},
+		{"negq", "NEGQ AX", "negq %1"},
+		{"incq", "INCQ AX", "incq %1"},
+		{"andq", "ANDQ BX, AX", "andq %2, %1"},
+		{"shlq", "SHLQ $1, AX", "shlq $1, %1"},
+		{"imulq", "IMULQ BX, AX", "imulq %2, %1"},
+	}
+	var src strings.Builder
+	sigs := map[string]FuncSig{}
+	for _, op := range ops {
+		src.WriteString("TEXT " + op.name + "(SB),NOSPLIT,$0-24\n" +
+			"\tMOVQ a+0(FP), AX\n" +
+			"\tMOVQ b+8(FP), BX\n" +
+			"\t" + op.plan9 + "\n" +
+			"\tPUSHFQ\n" +
+			"\tPOPQ AX\n" +
+			"\tMOVQ AX, ret+16(FP)\n" +
+			"\tRET\n")
+		sigs[op.name] = amd64FlagsTestSig(op.name)
+	}
+	src.WriteString(`TEXT ucomisd(SB),NOSPLIT,$0-24
+	MOVQ a+0(FP), X0
+	MOVQ b+8(FP), X1
+	UCOMISD X1, X0
+	PUSHFQ
+	POPQ AX
+	MOVQ AX, ret+16(FP)
+	RET
+`)
+	sigs["ucomisd"] = amd64FlagsTestSig("ucomisd")
+
+	file, err := Parse(ArchAMD64, src.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ll, err := Translate(file, Options{
+		TargetTriple: testTargetTriple(runtime.GOOS, runtime.GOARCH),
+		Sigs:         sigs,
+		Goarch:       "amd64",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var mainC strings.Builder
+	mainC.WriteString(`
+#include <stdint.h>
+#include <string.h>
+#define CF 0x1
+#define PF 0x4
+#define AF 0x10
+#define ZF 0x40
+#define SF 0x80
+#define OF 0x800
+#define ALL (CF|PF|AF|ZF|SF|OF)
+#define HW(name, insn) \
+	static uint64_t hw_##name(uint64_t a, uint64_t b) { \
+		uint64_t f; \
+		__asm__ volatile(insn "\n\tpushfq\n\tpopq %0" : "=&r"(f), "+r"(a) : "r"(b) : "cc"); \
+		return f; \
+	}
+`)
+	for _, op := range ops {
+		mainC.WriteString("HW(" + op.name + ", \"" + op.gnu + "\")\n")
+		mainC.WriteString("extern uint64_t " + op.name + "(uint64_t, uint64_t);\n")
+	}
+	mainC.WriteString(`extern uint64_t ucomisd(uint64_t, uint64_t);
+static uint64_t hw_ucomisd(uint64_t a, uint64_t b) {
+	double x, y;
+	uint64_t f;
+	memcpy(&x, &a, 8);
+	memcpy(&y, &b, 8);
+	__asm__ volatile("ucomisd %2, %1\n\tpushfq\n\tpopq %0" : "=&r"(f) : "x"(x), "x"(y) : "cc");
+	return f;
+}
+static const uint64_t vals[] = {
+	0, 1, 3, 0xf, 0x10, 0x7f, 0x80, 0xff,
+	0x7fffffff, 0x80000000, 0xffffffff, 0x100000000,
+	0x7fffffffffffffff, 0x8000000000000000, 0xffffffffffffffff, 0x123456789abcdef0,
+};
+static const uint64_t fvals[] = {
+	0, 0x3ff0000000000000, 0xbff0000000000000, 0x7ff8000000000000, 0x7ff0000000000000,
+};
+#define N(a) (sizeof(a) / sizeof((a)[0]))
+#define CHECK(code, name, mask, vs) \
+	for (unsigned i = 0; i < N(vs); i++) \
+		for (unsigned j = 0; j < N(vs); j++) \
+			if ((name(vs[i], vs[j]) & (mask)) != (hw_##name(vs[i], vs[j]) & (mask))) return code;
+int main(void) {
+	CHECK(1, addq, ALL, vals)
+	CHECK(2, subq, ALL, vals)
+	CHECK(3, addl, ALL, vals)
+	CHECK(4, subl, ALL, vals)
+	CHECK(5, negq, ALL, vals)
+	CHECK(6, incq, ALL & ~CF, vals)
+	CHECK(7, andq, ALL & ~AF, vals)
+	CHECK(8, shlq, ALL & ~AF, vals)
+	CHECK(9, imulq, CF | OF, vals)
+	CHECK(10, ucomisd, ALL, fvals)
+	return 0;
+}
+`)
+	compileAndRunRuntimeTest(t, llc, clang, "amd64_flags", ll, mainC.String())
+}
+
+// TestRuntimeExecAMD64Conditions evaluates all sixteen conditions through
+// SETcc after CMPQ and checks them against the host CPU.
+func TestRuntimeExecAMD64Conditions(t *testing.T) {
+	if runtime.GOARCH != "amd64" {
+		t.Skip("runtime execution test only runs on amd64 host")
+	}
+
+	llc, clang, ok := findLlcAndClang(t)
+	if !ok {
+		t.Skip("llc/clang not found")
+	}
+
+	// Each function packs eight SETcc bytes into AX, BX, CX and DX and folds
+	// them into one word, bit 8*i holding condition i.
+	conds := [][]string{
+		{"EQ", "NE", "CS", "CC", "HI", "LS", "LT", "GE"},
+		{"GT", "LE", "MI", "PL", "OS", "OC", "PS", "PC"},
+	}
+	bytes := []Reg{AL, AH, BL, BH, CL, CH, DL, DH}
+	var src strings.Builder
+	sigs := map[string]FuncSig{}
+	for i, set := range conds {
+		name := "cond" + string(rune('0'+i))
+		src.WriteString("TEXT " + name + "(SB),NOSPLIT,$0-24\n" +
+			"\tMOVQ a+0(FP), AX\n" +
+			"\tMOVQ b+8(FP), BX\n" +
+			"\tXORQ CX, CX\n" +
+			"\tXORQ DX, DX\n" +
+			"\tCMPQ AX, BX\n")
+		for j, cc := range set {
+			src.WriteString("\tSET" + cc + " " + string(bytes[j]) + "\n")
+		}
+		src.WriteString(`	ANDQ $0xffff, AX
+	ANDQ $0xffff, BX
+	SHLQ $16, BX
+	ORQ BX, AX
+	SHLQ $32, CX
+	ORQ CX, AX
+	SHLQ $48, DX
+	ORQ DX, AX
+	MOVQ AX, ret+16(FP)
+	RET
+`)
+		sigs[name] = amd64FlagsTestSig(name)
+	}
+	src.WriteString(`TEXT cmovjo(SB),NOSPLIT,$0-24
+	MOVQ a+0(FP), AX
+	MOVQ b+8(FP), BX
+	MOVQ $0, CX
+	MOVQ $2, DX
+	ADDQ BX, AX
+	CMOVQPS DX, CX
+	JOS overflow
+	MOVQ CX, ret+16(FP)
+	RET
+overflow:
+	ORQ $1, CX
+	MOVQ CX, ret+16(FP)
+	RET
+`)
+	sigs["cmovjo"] = amd64FlagsTestSig("cmovjo")
+
+	file, err := Parse(ArchAMD64, src.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ll, err := Translate(file, Options{
+		TargetTriple: testTargetTriple(runtime.GOOS, runtime.GOARCH),
+		Sigs:         sigs,
+		Goarch:       "amd64",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mainC := `
+#include <stdint.h>
+extern uint64_t cond0(uint64_t, uint64_t);
+extern uint64_t cond1(uint64_t, uint64_t);
+extern uint64_t cmovjo(uint64_t, uint64_t);
+static uint64_t hwflags(uint64_t a, uint64_t b) {
+	uint64_t f;
+	__asm__ volatile("cmpq %2, %1\n\tpushfq\n\tpopq %0" : "=&r"(f) : "r"(a), "r"(b) : "cc");
+	return f;
+}
+static uint64_t pack(const int *c) {
+	uint64_t w = 0;
+	for (int i = 0; i < 8; i++) w |= (uint64_t)(c[i] != 0) << (8 * i);
+	return w;
+}
+static const uint64_t vals[] = {
+	0, 1, 2, 0x7f, 0x80, 0xff, 0x7fffffff, 0x80000000,
+	0x7fffffffffffffff, 0x8000000000000000, 0xfffffffffffffffe, 0xffffffffffffffff,
+};
+#define N(a) (sizeof(a) / sizeof((a)[0]))
+int main(void) {
+	for (unsigned i = 0; i < N(vals); i++) {
+		for (unsigned j = 0; j < N(vals); j++) {
+			uint64_t a = vals[i], b = vals[j], f = hwflags(a, b);
+			int cf = f & 0x1, pf = (f >> 2) & 1, zf = (f >> 6) & 1, sf = (f >> 7) & 1, of = (f >> 11) & 1;
+			int c0[8] = {zf, !zf, cf, !cf, !cf && !zf, cf || zf, sf != of, sf == of};
+			int c1[8] = {!zf && sf == of, zf || sf != of, sf, !sf, of, !of, pf, !pf};
+			if (cond0(a, b) != pack(c0)) return 1;
+			if (cond1(a, b) != pack(c1)) return 2;
+			uint64_t sum = a + b;
+			int ovf = ((int64_t)(a ^ sum) & (int64_t)(b ^ sum)) < 0;
+			int par = !__builtin_parity((unsigned)(sum & 0xff));
+			if (cmovjo(a, b) != (uint64_t)(ovf | (par << 1))) return 3;
+		}
+	}
+	return 0;
+}
+`
+	compileAndRunRuntimeTest(t, llc, clang, "amd64_conditions", ll, mainC)
+}
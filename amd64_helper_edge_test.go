@@ -14,7 +14,7 @@ func newAMD64CtxWithFuncForTest(t *testing.T, fn Func, sig FuncSig, sigs map[str
 		sigs = map[string]FuncSig{}
 	}
 	var b strings.Builder
-	c := newAMD64Ctx(&b, fn, sig, testResolveSym("example"), sigs, false)
+	c := newAMD64Ctx(&b, fn, sig, testResolveSym("example"), sigs, lowerConfig{})
 	if err := c.emitEntryAllocas(); err != nil {
 		t.Fatalf("emitEntryAllocas() error = %v", err)
 	}
@@ -126,9 +126,9 @@ func TestAMD64CtxHelperEdges(t *testing.T) {
 	})
 
 	t.Run("FlagsAndFP", func(t *testing.T) {
-		c.setZFlagFromI64("1")
-		c.setZSFlagsFromI64("2")
-		c.setZSFlagsFromI32("3")
+		c.setResultFlags(I64, "1")
+		c.setLogicFlags(I32, "3")
+		c.setAddFlags(I8, "1", "2", "true", "3", true)
 		c.setCmpFlags("4", "5")
 		if got := c.loadFlag(c.flagsZSlot); got == "" {
 			t.Fatalf("loadFlag() returned empty value")
@@ -705,7 +705,7 @@ func TestAMD64SetGEUsesSignedFlag(t *testing.T) {
 	if err := c.storeReg(AX, "4660"); err != nil {
 		t.Fatalf("storeReg(AX) error = %v", err)
 	}
-	b.WriteString("  store i1 false, ptr " + c.flagsSltSlot + "\n")
+	b.WriteString("  store i1 false, ptr " + c.flagsSFSlot + "\n")
 
 	ins := Instr{Raw: "SETGE AH", Args: []Operand{{Kind: OpReg, Reg: AH}}}
 	if ok, term, err := c.lowerArith("SETGE", ins); !ok || term || err != nil {
@@ -714,8 +714,8 @@ func TestAMD64SetGEUsesSignedFlag(t *testing.T) {
 
 	out := b.String()
 	for _, want := range []string{
-		"store i1 false, ptr %flags_slt",
-		"load i1, ptr %flags_slt",
+		"store i1 false, ptr %flags_sf",
+		"load i1, ptr %flags_sf",
 		"xor i1 %",
 		"shl i64",
 		"or i64",
@@ -957,7 +957,7 @@ func TestAMD64TranslateReturnCoverage(t *testing.T) {
 		},
 	}
 	var translated strings.Builder
-	if err := translateFuncAMD64(&translated, fn, FuncSig{Name: "example.edge", Ret: I32}, testResolveSym("example"), nil, true); err != nil {
+	if err := translateFuncAMD64(&translated, fn, FuncSig{Name: "example.edge", Ret: I32}, testResolveSym("example"), nil, lowerConfig{annotateSource: true}); err != nil {
 		t.Fatalf("translateFuncAMD64() error = %v", err)
 	}
 	if !strings.Contains(translated.String(), "ret i32 0") || !strings.Contains(translated.String(), "; s: NOP") {
@@ -1336,7 +1336,7 @@ func TestAMD64MovSyscallAndCRC32Coverage(t *testing.T) {
 	checkMov("MOVLQSX", Instr{Raw: "MOVLQSX 8(BX), SI", Args: []Operand{{Kind: OpMem, Mem: MemRef{Base: BX, Off: 8}}, {Kind: OpReg, Reg: SI}}})
 	checkMov("MOVLQSX", Instr{Raw: "MOVLQSX example.global(SB), DI", Args: []Operand{{Kind: OpSym, Sym: "example.global(SB)"}, {Kind: OpReg, Reg: DI}}})
 	checkMov("MOVWQSX", Instr{Raw: "MOVWQSX 8(BX), SI", Args: []Operand{{Kind: OpMem, Mem: MemRef{Base: BX, Off: 8}}, {Kind: OpReg, Reg: SI}}})
-	b.WriteString("  store i1 true, ptr " + c.flagsSltSlot + "\n")
+	b.WriteString("  store i1 true, ptr " + c.flagsSFSlot + "\n")
 	checkMov("CMOVQLT", Instr{Raw: "CMOVQLT AX, BX", Args: []Operand{{Kind: OpReg, Reg: AX}, {Kind: OpReg, Reg: BX}}})
 	checkMov("MOVB", Instr{Raw: "MOVB $1, AX", Args: []Operand{{Kind: OpImm, Imm: 1}, {Kind: OpReg, Reg: AX}}})
 	checkMov("MOVBLZX", Instr{Raw: "MOVBLZX 8(BX), AX", Args: []Operand{{Kind: OpMem, Mem: MemRef{Base: BX, Off: 8}}, {Kind: OpReg, Reg: AX}}})
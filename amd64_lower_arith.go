@@ -2,6 +2,13 @@ package plan9asm
 
 import "fmt"
 
+var amd64ArithFeatures = []featureRule{
+	{ops: []string{"POPCNTL", "POPCNTQ"}, features: []string{"+popcnt"}},
+	{ops: []string{"TZCNTQ", "ANDNL", "ANDNQ", "BEXTRQ"}, features: []string{"+bmi"}},
+	{ops: []string{"BZHIQ", "SHLXQ", "SHRXQ", "RORXL", "RORXQ", "MULXQ"}, features: []string{"+bmi2"}},
+	{ops: []string{"ADCXQ", "ADOXQ"}, features: []string{"+adx"}},
+}
+
 func (c *amd64Ctx) lowerArith(op Op, ins Instr) (ok bool, terminated bool, err error) {
 	switch op {
 	case "PUSHQ":
@@ -26,11 +33,21 @@ func (c *amd64Ctx) lowerArith(op Op, ins Instr) (ok bool, terminated bool, err e
 		}
 		return true, false, nil
 	case "PUSHFQ":
-		// Flag register modeling is minimal; preserve stack shape only.
-		c.pushI64("0")
+		c.pushI64(c.flagsWord())
 		return true, false, nil
 	case "POPFQ":
-		_ = c.popI64()
+		c.setFlagsFromWord(c.popI64(), 64)
+		return true, false, nil
+	case "LAHF":
+		// AH = SF:ZF:0:AF:0:PF:1:CF.
+		w := c.flagsWord()
+		return true, false, c.storeReg(AH, w)
+	case "SAHF":
+		ah, err := c.loadReg(AH)
+		if err != nil {
+			return true, false, err
+		}
+		c.setFlagsFromWord(ah, 8)
 		return true, false, nil
 	case "LFENCE", "MFENCE", "SFENCE", "PAUSE", "PREFETCHNTA":
 		// Ordering/prefetch hints do not change SSA-visible values here.
@@ -140,7 +157,11 @@ func (c *amd64Ctx) lowerArith(op Op, ins Instr) (ok bool, terminated bool, err e
 			return true, false, err
 		}
 		return true, false, nil
-	case "MOVSQ":
+	case "MOVSL", "MOVSQ":
+		ty, n := I32, 4
+		if op == "MOVSQ" {
+			ty, n = I64, 8
+		}
 		si, err := c.loadReg(SI)
 		if err != nil {
 			return true, false, err
@@ -152,12 +173,12 @@ func (c *amd64Ctx) lowerArith(op Op, ins Instr) (ok bool, terminated bool, err e
 		ps := c.ptrFromAddrI64(si)
 		pd := c.ptrFromAddrI64(di)
 		v := c.newTmp()
-		fmt.Fprintf(c.b, "  %%%s = load i64, ptr %s, align 1\n", v, ps)
-		fmt.Fprintf(c.b, "  store i64 %%%s, ptr %s, align 1\n", v, pd)
+		fmt.Fprintf(c.b, "  %%%s = load %s, ptr %s, align 1\n", v, ty, ps)
+		fmt.Fprintf(c.b, "  store %s %%%s, ptr %s, align 1\n", ty, v, pd)
 		ns := c.newTmp()
-		fmt.Fprintf(c.b, "  %%%s = add i64 %s, 8\n", ns, si)
+		fmt.Fprintf(c.b, "  %%%s = add i64 %s, %d\n", ns, si, n)
 		nd := c.newTmp()
-		fmt.Fprintf(c.b, "  %%%s = add i64 %s, 8\n", nd, di)
+		fmt.Fprintf(c.b, "  %%%s = add i64 %s, %d\n", nd, di, n)
 		if err := c.storeReg(SI, "%"+ns); err != nil {
 			return true, false, err
 		}
@@ -165,7 +186,7 @@ func (c *amd64Ctx) lowerArith(op Op, ins Instr) (ok bool, terminated bool, err e
 			return true, false, err
 		}
 		return true, false, nil
-	case "STOSQ":
+	case "STOSL", "STOSQ":
 		di, err := c.loadReg(DI)
 		if err != nil {
 			return true, false, err
@@ -175,9 +196,17 @@ func (c *amd64Ctx) lowerArith(op Op, ins Instr) (ok bool, terminated bool, err e
 			return true, false, err
 		}
 		pd := c.ptrFromAddrI64(di)
-		fmt.Fprintf(c.b, "  store i64 %s, ptr %s, align 1\n", ax, pd)
+		n := 8
+		if op == "STOSL" {
+			n = 4
+			t := c.newTmp()
+			fmt.Fprintf(c.b, "  %%%s = trunc i64 %s to i32\n", t, ax)
+			fmt.Fprintf(c.b, "  store i32 %%%s, ptr %s, align 1\n", t, pd)
+		} else {
+			fmt.Fprintf(c.b, "  store i64 %s, ptr %s, align 1\n", ax, pd)
+		}
 		nd := c.newTmp()
-		fmt.Fprintf(c.b, "  %%%s = add i64 %s, 8\n", nd, di)
+		fmt.Fprintf(c.b, "  %%%s = add i64 %s, %d\n", nd, di, n)
 		if err := c.storeReg(DI, "%"+nd); err != nil {
 			return true, false, err
 		}
@@ -202,7 +231,7 @@ func (c *amd64Ctx) lowerArith(op Op, ins Instr) (ok bool, terminated bool, err e
 			if err := c.storeReg(ins.Args[0].Reg, "%"+z); err != nil {
 				return true, false, err
 			}
-			c.setZSFlagsFromI32("%" + neg)
+			c.setSubFlags(I32, "0", "%"+t32, "", "%"+neg, true)
 			return true, false, nil
 		case OpMem:
 			addr, err := c.addrFromMem(ins.Args[0].Mem)
@@ -215,13 +244,14 @@ func (c *amd64Ctx) lowerArith(op Op, ins Instr) (ok bool, terminated bool, err e
 			neg := c.newTmp()
 			fmt.Fprintf(c.b, "  %%%s = sub i32 0, %%%s\n", neg, ld)
 			fmt.Fprintf(c.b, "  store i32 %%%s, ptr %s, align 1\n", neg, p)
-			c.setZSFlagsFromI32("%" + neg)
+			c.setSubFlags(I32, "0", "%"+ld, "", "%"+neg, true)
 			return true, false, nil
 		default:
 			return true, false, fmt.Errorf("amd64 NEGL expects reg/mem dst: %q", ins.Raw)
 		}
 	case "RCRQ":
 		// Rotate through carry right (count=1) used by runtime time division path.
+		// Only CF and OF change.
 		if len(ins.Args) != 2 || ins.Args[0].Kind != OpImm {
 			return true, false, fmt.Errorf("amd64 RCRQ expects $count, dstReg: %q", ins.Raw)
 		}
@@ -237,7 +267,7 @@ func (c *amd64Ctx) lowerArith(op Op, ins Instr) (ok bool, terminated bool, err e
 		fmt.Fprintf(c.b, "  %%%s = and i64 %s, 1\n", lsb, dv)
 		newCF := c.newTmp()
 		fmt.Fprintf(c.b, "  %%%s = icmp ne i64 %%%s, 0\n", newCF, lsb)
-		fmt.Fprintf(c.b, "  store i1 %%%s, ptr %s\n", newCF, c.flagsCFSlot)
+		c.storeFlag(c.flagsCFSlot, "%"+newCF)
 		shr := c.newTmp()
 		fmt.Fprintf(c.b, "  %%%s = lshr i64 %s, 1\n", shr, dv)
 		cf64 := c.newTmp()
@@ -249,7 +279,12 @@ func (c *amd64Ctx) lowerArith(op Op, ins Instr) (ok bool, terminated bool, err e
 		if err := c.storeReg(ins.Args[1].Reg, "%"+out); err != nil {
 			return true, false, err
 		}
-		c.setZSFlagsFromI64("%" + out)
+		// OF = MSB(res) ^ MSB-1(res) = oldCF ^ MSB(src).
+		srcMSB := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = icmp slt i64 %s, 0\n", srcMSB, dv)
+		of := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = xor i1 %s, %%%s\n", of, oldCF, srcMSB)
+		c.storeFlag(c.flagsOFSlot, "%"+of)
 		return true, false, nil
 
 	case "ADDQ", "SUBQ", "XORQ", "ANDQ", "ORQ":
@@ -308,18 +343,12 @@ func (c *amd64Ctx) lowerArith(op Op, ins Instr) (ok bool, terminated bool, err e
 		}
 		switch op {
 		case "ADDQ":
-			cf := c.newTmp()
-			fmt.Fprintf(c.b, "  %%%s = icmp ult i64 %s, %s\n", cf, r, dv)
-			fmt.Fprintf(c.b, "  store i1 %%%s, ptr %s\n", cf, c.flagsCFSlot)
+			c.setAddFlags(I64, dv, src, "", r, true)
 		case "SUBQ":
-			cf := c.newTmp()
-			fmt.Fprintf(c.b, "  %%%s = icmp ult i64 %s, %s\n", cf, dv, src)
-			fmt.Fprintf(c.b, "  store i1 %%%s, ptr %s\n", cf, c.flagsCFSlot)
+			c.setSubFlags(I64, dv, src, "", r, true)
 		default:
-			fmt.Fprintf(c.b, "  store i1 false, ptr %s\n", c.flagsCFSlot)
+			c.setLogicFlags(I64, r)
 		}
-		fmt.Fprintf(c.b, "  store i1 false, ptr %s\n", c.flagsOFSlot)
-		c.setZSFlagsFromI64(r)
 		return true, false, nil
 
 	case "ADCQ", "SBBQ":
@@ -364,9 +393,8 @@ func (c *amd64Ctx) lowerArith(op Op, ins Instr) (ok bool, terminated bool, err e
 			fmt.Fprintf(c.b, "  %%%s = add i128 %%%s, %%%s\n", total2, total1, cf128)
 			cf := c.newTmp()
 			fmt.Fprintf(c.b, "  %%%s = icmp ugt i128 %%%s, 18446744073709551615\n", cf, total2)
-			fmt.Fprintf(c.b, "  store i1 %%%s, ptr %s\n", cf, c.flagsCFSlot)
-			fmt.Fprintf(c.b, "  store i1 false, ptr %s\n", c.flagsOFSlot)
-			c.setZSFlagsFromI64(out)
+			c.setAddFlags(I64, dv, src, cfIn, out, false)
+			c.storeFlag(c.flagsCFSlot, "%"+cf)
 			return true, false, nil
 		}
 
@@ -382,9 +410,66 @@ func (c *amd64Ctx) lowerArith(op Op, ins Instr) (ok bool, terminated bool, err e
 		if err := c.storeReg(dst, out); err != nil {
 			return true, false, err
 		}
-		fmt.Fprintf(c.b, "  store i1 %%%s, ptr %s\n", borrow, c.flagsCFSlot)
-		fmt.Fprintf(c.b, "  store i1 false, ptr %s\n", c.flagsOFSlot)
-		c.setZSFlagsFromI64(out)
+		c.setSubFlags(I64, dv, src, cfIn, out, false)
+		c.storeFlag(c.flagsCFSlot, "%"+borrow)
+		return true, false, nil
+
+	case "ADCL", "SBBL":
+		// 32-bit add/subtract with carry/borrow: src, dstReg. The carry out is
+		// bit 32 of the i64 sum of the zero-extended operands.
+		if len(ins.Args) != 2 || ins.Args[1].Kind != OpReg {
+			return true, false, fmt.Errorf("amd64 %s expects src, dstReg: %q", op, ins.Raw)
+		}
+		src64, err := c.evalI64(ins.Args[0])
+		if err != nil {
+			return true, false, err
+		}
+		dst := ins.Args[1].Reg
+		dv64, err := c.loadReg(dst)
+		if err != nil {
+			return true, false, err
+		}
+		s32 := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = trunc i64 %s to i32\n", s32, src64)
+		d32 := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = trunc i64 %s to i32\n", d32, dv64)
+		cfIn := c.loadFlag(c.flagsCFSlot)
+		cf32 := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = zext i1 %s to i32\n", cf32, cfIn)
+		wide := func(v string) string {
+			t := c.newTmp()
+			fmt.Fprintf(c.b, "  %%%s = zext i32 %%%s to i64\n", t, v)
+			return "%" + t
+		}
+		d64, s64, c64 := wide(d32), wide(s32), wide(cf32)
+		ir := "add"
+		if op == "SBBL" {
+			ir = "sub"
+		}
+		r1 := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = %s i32 %%%s, %%%s\n", r1, ir, d32, s32)
+		r2 := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = %s i32 %%%s, %%%s\n", r2, ir, r1, cf32)
+		if err := c.storeReg(dst, wide(r2)); err != nil {
+			return true, false, err
+		}
+		var cf string
+		if op == "ADCL" {
+			sum := c.newTmp()
+			fmt.Fprintf(c.b, "  %%%s = add i64 %s, %s\n", sum, d64, s64)
+			total := c.newTmp()
+			fmt.Fprintf(c.b, "  %%%s = add i64 %%%s, %s\n", total, sum, c64)
+			cf = c.newTmp()
+			fmt.Fprintf(c.b, "  %%%s = icmp ugt i64 %%%s, 4294967295\n", cf, total)
+			c.setAddFlags(I32, "%"+d32, "%"+s32, cfIn, "%"+r2, false)
+		} else {
+			subtr := c.newTmp()
+			fmt.Fprintf(c.b, "  %%%s = add i64 %s, %s\n", subtr, s64, c64)
+			cf = c.newTmp()
+			fmt.Fprintf(c.b, "  %%%s = icmp ult i64 %s, %%%s\n", cf, d64, subtr)
+			c.setSubFlags(I32, "%"+d32, "%"+s32, cfIn, "%"+r2, false)
+		}
+		c.storeFlag(c.flagsCFSlot, "%"+cf)
 		return true, false, nil
 
 	case "ADCB":
@@ -454,14 +539,8 @@ func (c *amd64Ctx) lowerArith(op Op, ins Instr) (ok bool, terminated bool, err e
 		fmt.Fprintf(c.b, "  %%%s = add i16 %%%s, %%%s\n", total2, total1, cf16)
 		cf := c.newTmp()
 		fmt.Fprintf(c.b, "  %%%s = icmp ugt i16 %%%s, 255\n", cf, total2)
-		fmt.Fprintf(c.b, "  store i1 %%%s, ptr %s\n", cf, c.flagsCFSlot)
-		fmt.Fprintf(c.b, "  store i1 false, ptr %s\n", c.flagsOFSlot)
-		zf := c.newTmp()
-		fmt.Fprintf(c.b, "  %%%s = icmp eq i8 %%%s, 0\n", zf, res)
-		fmt.Fprintf(c.b, "  store i1 %%%s, ptr %s\n", zf, c.flagsZSlot)
-		sf := c.newTmp()
-		fmt.Fprintf(c.b, "  %%%s = icmp slt i8 %%%s, 0\n", sf, res)
-		fmt.Fprintf(c.b, "  store i1 %%%s, ptr %s\n", sf, c.flagsSltSlot)
+		c.setAddFlags(I8, d8, s8, cfIn, out8, false)
+		c.storeFlag(c.flagsCFSlot, "%"+cf)
 		return true, false, nil
 
 	case "ADCXQ", "ADOXQ":
@@ -478,12 +557,11 @@ func (c *amd64Ctx) lowerArith(op Op, ins Instr) (ok bool, terminated bool, err e
 		if err != nil {
 			return true, false, err
 		}
-		carryIn := c.loadFlag(c.flagsCFSlot)
 		flagOut := c.flagsCFSlot
 		if op == "ADOXQ" {
-			carryIn = c.loadFlag(c.flagsOFSlot)
 			flagOut = c.flagsOFSlot
 		}
+		carryIn := c.loadFlag(flagOut)
 		cf64t := c.newTmp()
 		fmt.Fprintf(c.b, "  %%%s = zext i1 %s to i64\n", cf64t, carryIn)
 		cf64 := "%" + cf64t
@@ -507,7 +585,7 @@ func (c *amd64Ctx) lowerArith(op Op, ins Instr) (ok bool, terminated bool, err e
 		fmt.Fprintf(c.b, "  %%%s = add i128 %%%s, %%%s\n", total2, total1, cf128)
 		carry := c.newTmp()
 		fmt.Fprintf(c.b, "  %%%s = icmp ugt i128 %%%s, 18446744073709551615\n", carry, total2)
-		fmt.Fprintf(c.b, "  store i1 %%%s, ptr %s\n", carry, flagOut)
+		c.storeFlag(flagOut, "%"+carry)
 		// ADCX/ADOX do not define ZF/SF in the same way as ADD; keep current bits.
 		return true, false, nil
 
@@ -583,18 +661,12 @@ func (c *amd64Ctx) lowerArith(op Op, ins Instr) (ok bool, terminated bool, err e
 		}
 		switch op {
 		case "ADDL":
-			cf := c.newTmp()
-			fmt.Fprintf(c.b, "  %%%s = icmp ult i32 %%%s, %s\n", cf, x, dtr)
-			fmt.Fprintf(c.b, "  store i1 %%%s, ptr %s\n", cf, c.flagsCFSlot)
+			c.setAddFlags(I32, dtr, s32, "", "%"+x, true)
 		case "SUBL":
-			cf := c.newTmp()
-			fmt.Fprintf(c.b, "  %%%s = icmp ult i32 %s, %s\n", cf, dtr, s32)
-			fmt.Fprintf(c.b, "  store i1 %%%s, ptr %s\n", cf, c.flagsCFSlot)
+			c.setSubFlags(I32, dtr, s32, "", "%"+x, true)
 		default:
-			fmt.Fprintf(c.b, "  store i1 false, ptr %s\n", c.flagsCFSlot)
+			c.setLogicFlags(I32, "%"+x)
 		}
-		fmt.Fprintf(c.b, "  store i1 false, ptr %s\n", c.flagsOFSlot)
-		c.setZSFlagsFromI32("%" + x)
 		return true, false, nil
 
 	case "ADDB", "XORB", "ANDB", "ORB":
@@ -639,25 +711,10 @@ func (c *amd64Ctx) lowerArith(op Op, ins Instr) (ok bool, terminated bool, err e
 			return true, false, err
 		}
 		if op == "ADDB" {
-			d16 := c.newTmp()
-			fmt.Fprintf(c.b, "  %%%s = zext i8 %%%s to i16\n", d16, d8)
-			s16 := c.newTmp()
-			fmt.Fprintf(c.b, "  %%%s = zext i8 %s to i16\n", s16, s8)
-			total := c.newTmp()
-			fmt.Fprintf(c.b, "  %%%s = add i16 %%%s, %%%s\n", total, d16, s16)
-			cf := c.newTmp()
-			fmt.Fprintf(c.b, "  %%%s = icmp ugt i16 %%%s, 255\n", cf, total)
-			fmt.Fprintf(c.b, "  store i1 %%%s, ptr %s\n", cf, c.flagsCFSlot)
+			c.setAddFlags(I8, "%"+d8, s8, "", "%"+x, true)
 		} else {
-			fmt.Fprintf(c.b, "  store i1 false, ptr %s\n", c.flagsCFSlot)
+			c.setLogicFlags(I8, "%"+x)
 		}
-		fmt.Fprintf(c.b, "  store i1 false, ptr %s\n", c.flagsOFSlot)
-		zf := c.newTmp()
-		fmt.Fprintf(c.b, "  %%%s = icmp eq i8 %%%s, 0\n", zf, x)
-		fmt.Fprintf(c.b, "  store i1 %%%s, ptr %s\n", zf, c.flagsZSlot)
-		sf := c.newTmp()
-		fmt.Fprintf(c.b, "  %%%s = icmp slt i8 %%%s, 0\n", sf, x)
-		fmt.Fprintf(c.b, "  store i1 %%%s, ptr %s\n", sf, c.flagsSltSlot)
 		return true, false, nil
 
 	case "INCQ", "DECQ":
@@ -701,7 +758,12 @@ func (c *amd64Ctx) lowerArith(op Op, ins Instr) (ok bool, terminated bool, err e
 		if err := storeDst(out); err != nil {
 			return true, false, err
 		}
-		c.setZSFlagsFromI64(out)
+		// INC/DEC leave CF untouched.
+		if op == "INCQ" {
+			c.setAddFlags(I64, v, "1", "", out, false)
+		} else {
+			c.setSubFlags(I64, v, "1", "", out, false)
+		}
 		return true, false, nil
 
 	case "INCL", "DECL":
@@ -754,7 +816,11 @@ func (c *amd64Ctx) lowerArith(op Op, ins Instr) (ok bool, terminated bool, err e
 		if err := storeDst("%" + x); err != nil {
 			return true, false, err
 		}
-		c.setZSFlagsFromI32("%" + x)
+		if op == "INCL" {
+			c.setAddFlags(I32, "%"+tr, "1", "", "%"+x, false)
+		} else {
+			c.setSubFlags(I32, "%"+tr, "1", "", "%"+x, false)
+		}
 		return true, false, nil
 
 	case "LEAQ", "LEAL":
@@ -785,9 +851,7 @@ func (c *amd64Ctx) lowerArith(op Op, ins Instr) (ok bool, terminated bool, err e
 			alloca, _, ok := c.fpResultAlloca(ins.Args[0].FPOffset)
 			if ok {
 				c.markFPResultAddrTaken(ins.Args[0].FPOffset)
-				t := c.newTmp()
-				fmt.Fprintf(c.b, "  %%%s = ptrtoint ptr %s to i64\n", t, alloca)
-				return true, false, storeLEA("%" + t)
+				return true, false, storeLEA(c.ptrToI64(alloca))
 			}
 			// Fallback: treat FP slot value as pointer-like integer address.
 			v, err := c.evalFPToI64(ins.Args[0].FPOffset)
@@ -800,9 +864,7 @@ func (c *amd64Ctx) lowerArith(op Op, ins Instr) (ok bool, terminated bool, err e
 			alloca, _, ok := c.fpResultAlloca(ins.Args[0].FPOffset)
 			if ok {
 				c.markFPResultAddrTaken(ins.Args[0].FPOffset)
-				t := c.newTmp()
-				fmt.Fprintf(c.b, "  %%%s = ptrtoint ptr %s to i64\n", t, alloca)
-				return true, false, storeLEA("%" + t)
+				return true, false, storeLEA(c.ptrToI64(alloca))
 			}
 			v, err := c.evalFPToI64(ins.Args[0].FPOffset)
 			if err != nil {
@@ -814,9 +876,7 @@ func (c *amd64Ctx) lowerArith(op Op, ins Instr) (ok bool, terminated bool, err e
 			if err != nil {
 				return true, false, err
 			}
-			t := c.newTmp()
-			fmt.Fprintf(c.b, "  %%%s = ptrtoint ptr %s to i64\n", t, p)
-			return true, false, storeLEA("%" + t)
+			return true, false, storeLEA(c.ptrToI64(p))
 		default:
 			return true, false, fmt.Errorf("amd64 %s unsupported src: %q", op, ins.Raw)
 		}
@@ -831,15 +891,25 @@ func (c *amd64Ctx) lowerArith(op Op, ins Instr) (ok bool, terminated bool, err e
 			return true, false, err
 		}
 		dst := ins.Args[1].Reg
+		// ZF reports a zero source; OF, SF, AF, CF and PF are cleared.
+		for _, slot := range []string{c.flagsOFSlot, c.flagsSFSlot, c.flagsAFSlot, c.flagsCFSlot, c.flagsPFSlot} {
+			c.storeFlag(slot, "false")
+		}
 		if op == "POPCNTL" {
 			tr := c.newTmp()
 			fmt.Fprintf(c.b, "  %%%s = trunc i64 %s to i32\n", tr, srcv)
+			zf := c.newTmp()
+			fmt.Fprintf(c.b, "  %%%s = icmp eq i32 %%%s, 0\n", zf, tr)
+			c.storeFlag(c.flagsZSlot, "%"+zf)
 			call := c.newTmp()
 			fmt.Fprintf(c.b, "  %%%s = call i32 @llvm.ctpop.i32(i32 %%%s)\n", call, tr)
 			z := c.newTmp()
 			fmt.Fprintf(c.b, "  %%%s = zext i32 %%%s to i64\n", z, call)
 			return true, false, c.storeReg(dst, "%"+z)
 		}
+		zf := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = icmp eq i64 %s, 0\n", zf, srcv)
+		c.storeFlag(c.flagsZSlot, "%"+zf)
 		call := c.newTmp()
 		fmt.Fprintf(c.b, "  %%%s = call i64 @llvm.ctpop.i64(i64 %s)\n", call, srcv)
 		return true, false, c.storeReg(dst, "%"+call)
@@ -858,10 +928,13 @@ func (c *amd64Ctx) lowerArith(op Op, ins Instr) (ok bool, terminated bool, err e
 		if err := c.storeReg(ins.Args[1].Reg, "%"+call); err != nil {
 			return true, false, err
 		}
+		// CF reports a zero source, ZF a zero result; the rest are undefined.
 		cf := c.newTmp()
 		fmt.Fprintf(c.b, "  %%%s = icmp eq i64 %s, 0\n", cf, srcv)
-		fmt.Fprintf(c.b, "  store i1 %%%s, ptr %s\n", cf, c.flagsCFSlot)
-		c.setZSFlagsFromI64("%" + call)
+		c.storeFlag(c.flagsCFSlot, "%"+cf)
+		zf := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = icmp eq i64 %%%s, 0\n", zf, call)
+		c.storeFlag(c.flagsZSlot, "%"+zf)
 		return true, false, nil
 
 	case "BSFQ", "BSRQ", "BSWAPQ", "BSFL", "BSRL":
@@ -893,7 +966,7 @@ func (c *amd64Ctx) lowerArith(op Op, ins Instr) (ok bool, terminated bool, err e
 			// ZF is set when src == 0.
 			zf := c.newTmp()
 			fmt.Fprintf(c.b, "  %%%s = icmp eq i64 %s, 0\n", zf, sv)
-			fmt.Fprintf(c.b, "  store i1 %%%s, ptr %s\n", zf, c.flagsZSlot)
+			c.storeFlag(c.flagsZSlot, "%"+zf)
 			// dst = cttz(src). Use non-poison form for src==0.
 			call := c.newTmp()
 			fmt.Fprintf(c.b, "  %%%s = call i64 @llvm.cttz.i64(i64 %s, i1 false)\n", call, sv)
@@ -902,7 +975,7 @@ func (c *amd64Ctx) lowerArith(op Op, ins Instr) (ok bool, terminated bool, err e
 			// ZF is set when src == 0.
 			zf := c.newTmp()
 			fmt.Fprintf(c.b, "  %%%s = icmp eq i64 %s, 0\n", zf, sv)
-			fmt.Fprintf(c.b, "  store i1 %%%s, ptr %s\n", zf, c.flagsZSlot)
+			c.storeFlag(c.flagsZSlot, "%"+zf)
 			// dst = 63 - ctlz(src). Use non-poison form for src==0.
 			clz := c.newTmp()
 			fmt.Fprintf(c.b, "  %%%s = call i64 @llvm.ctlz.i64(i64 %s, i1 false)\n", clz, sv)
@@ -919,7 +992,7 @@ func (c *amd64Ctx) lowerArith(op Op, ins Instr) (ok bool, terminated bool, err e
 			fmt.Fprintf(c.b, "  %%%s = trunc i64 %s to i32\n", tr, sv)
 			zf := c.newTmp()
 			fmt.Fprintf(c.b, "  %%%s = icmp eq i32 %%%s, 0\n", zf, tr)
-			fmt.Fprintf(c.b, "  store i1 %%%s, ptr %s\n", zf, c.flagsZSlot)
+			c.storeFlag(c.flagsZSlot, "%"+zf)
 			// dst = zext(cttz(trunc32(src))). Use non-poison form for src==0.
 			call := c.newTmp()
 			fmt.Fprintf(c.b, "  %%%s = call i32 @llvm.cttz.i32(i32 %%%s, i1 false)\n", call, tr)
@@ -932,7 +1005,7 @@ func (c *amd64Ctx) lowerArith(op Op, ins Instr) (ok bool, terminated bool, err e
 			fmt.Fprintf(c.b, "  %%%s = trunc i64 %s to i32\n", tr, sv)
 			zf := c.newTmp()
 			fmt.Fprintf(c.b, "  %%%s = icmp eq i32 %%%s, 0\n", zf, tr)
-			fmt.Fprintf(c.b, "  store i1 %%%s, ptr %s\n", zf, c.flagsZSlot)
+			c.storeFlag(c.flagsZSlot, "%"+zf)
 			// dst = zext(31 - ctlz(trunc32(src))). Use non-poison form for src==0.
 			clz := c.newTmp()
 			fmt.Fprintf(c.b, "  %%%s = call i32 @llvm.ctlz.i32(i32 %%%s, i1 false)\n", clz, tr)
@@ -944,96 +1017,6 @@ func (c *amd64Ctx) lowerArith(op Op, ins Instr) (ok bool, terminated bool, err e
 		}
 		return true, false, fmt.Errorf("amd64: unsupported bit op %s", op)
 
-	case "SETEQ", "SETGT", "SETGE", "SETHI", "SETCS":
-		// SETcc dst: set byte based on flags.
-		// We support register destinations and FP result slots.
-		if len(ins.Args) != 1 {
-			return true, false, fmt.Errorf("amd64 %s expects one destination: %q", op, ins.Raw)
-		}
-		cond := ""
-		switch op {
-		case "SETEQ":
-			cond = c.loadFlag(c.flagsZSlot)
-		case "SETGT":
-			// signed >
-			slt := c.loadFlag(c.flagsSltSlot)
-			z := c.loadFlag(c.flagsZSlot)
-			t1 := c.newTmp()
-			fmt.Fprintf(c.b, "  %%%s = or i1 %s, %s\n", t1, slt, z)
-			t2 := c.newTmp()
-			fmt.Fprintf(c.b, "  %%%s = xor i1 %%%s, true\n", t2, t1)
-			cond = "%" + t2
-		case "SETGE":
-			slt := c.loadFlag(c.flagsSltSlot)
-			t := c.newTmp()
-			fmt.Fprintf(c.b, "  %%%s = xor i1 %s, true\n", t, slt)
-			cond = "%" + t
-		case "SETHI":
-			// unsigned >
-			cf := c.loadFlag(c.flagsCFSlot)
-			z := c.loadFlag(c.flagsZSlot)
-			t1 := c.newTmp()
-			fmt.Fprintf(c.b, "  %%%s = or i1 %s, %s\n", t1, cf, z)
-			t2 := c.newTmp()
-			fmt.Fprintf(c.b, "  %%%s = xor i1 %%%s, true\n", t2, t1)
-			cond = "%" + t2
-		case "SETCS":
-			cond = c.loadFlag(c.flagsCFSlot)
-		}
-		switch ins.Args[0].Kind {
-		case OpReg:
-			sel := c.newTmp()
-			fmt.Fprintf(c.b, "  %%%s = select i1 %s, i8 1, i8 0\n", sel, cond)
-			return true, false, c.storeRegSized(ins.Args[0].Reg, I8, "%"+sel)
-		case OpFP:
-			return true, false, c.storeFPResult(ins.Args[0].FPOffset, I1, cond)
-		default:
-			return true, false, fmt.Errorf("amd64 %s expects reg or fp destination: %q", op, ins.Raw)
-		}
-
-	case "CMOVQEQ", "CMOVQNE", "CMOVQCS", "CMOVQCC", "CMOVQGT":
-		// Conditional move: src, dstReg
-		if len(ins.Args) != 2 || ins.Args[1].Kind != OpReg {
-			return true, false, fmt.Errorf("amd64 %s expects src, dstReg: %q", op, ins.Raw)
-		}
-		src, err := c.evalI64(ins.Args[0])
-		if err != nil {
-			return true, false, err
-		}
-		dst := ins.Args[1].Reg
-		cur, err := c.loadReg(dst)
-		if err != nil {
-			return true, false, err
-		}
-		cond := ""
-		switch op {
-		case "CMOVQEQ":
-			cond = c.loadFlag(c.flagsZSlot)
-		case "CMOVQNE":
-			z := c.loadFlag(c.flagsZSlot)
-			nz := c.newTmp()
-			fmt.Fprintf(c.b, "  %%%s = xor i1 %s, true\n", nz, z)
-			cond = "%" + nz
-		case "CMOVQCS":
-			cond = c.loadFlag(c.flagsCFSlot)
-		case "CMOVQCC":
-			cf := c.loadFlag(c.flagsCFSlot)
-			nc := c.newTmp()
-			fmt.Fprintf(c.b, "  %%%s = xor i1 %s, true\n", nc, cf)
-			cond = "%" + nc
-		case "CMOVQGT":
-			slt := c.loadFlag(c.flagsSltSlot)
-			z := c.loadFlag(c.flagsZSlot)
-			t1 := c.newTmp()
-			fmt.Fprintf(c.b, "  %%%s = or i1 %s, %s\n", t1, slt, z)
-			t2 := c.newTmp()
-			fmt.Fprintf(c.b, "  %%%s = xor i1 %%%s, true\n", t2, t1)
-			cond = "%" + t2
-		}
-		sel := c.newTmp()
-		fmt.Fprintf(c.b, "  %%%s = select i1 %s, i64 %s, i64 %s\n", sel, cond, src, cur)
-		return true, false, c.storeReg(dst, "%"+sel)
-
 	case "ANDNL", "ANDNQ":
 		// BMI1 ANDN: dst = ~src2 & src1
 		if len(ins.Args) != 3 || ins.Args[2].Kind != OpReg {
@@ -1053,6 +1036,7 @@ func (c *amd64Ctx) lowerArith(op Op, ins Instr) (ok bool, terminated bool, err e
 			fmt.Fprintf(c.b, "  %%%s = xor i64 %s, -1\n", n, src2)
 			a := c.newTmp()
 			fmt.Fprintf(c.b, "  %%%s = and i64 %%%s, %s\n", a, n, src1)
+			c.setLogicFlags(I64, "%"+a)
 			return true, false, c.storeReg(dst, "%"+a)
 		}
 		s1 := c.newTmp()
@@ -1063,6 +1047,7 @@ func (c *amd64Ctx) lowerArith(op Op, ins Instr) (ok bool, terminated bool, err e
 		fmt.Fprintf(c.b, "  %%%s = xor i32 %%%s, -1\n", n, s2)
 		a := c.newTmp()
 		fmt.Fprintf(c.b, "  %%%s = and i32 %%%s, %%%s\n", a, n, s1)
+		c.setLogicFlags(I32, "%"+a)
 		z := c.newTmp()
 		fmt.Fprintf(c.b, "  %%%s = zext i32 %%%s to i64\n", z, a)
 		return true, false, c.storeReg(dst, "%"+z)
@@ -1112,6 +1097,12 @@ func (c *amd64Ctx) lowerArith(op Op, ins Instr) (ok bool, terminated bool, err e
 		fmt.Fprintf(c.b, "  %%%s = select i1 %%%s, i64 -1, i64 %%%s\n", mask, isFull, maskTmp)
 		out := c.newTmp()
 		fmt.Fprintf(c.b, "  %%%s = and i64 %%%s, %%%s\n", out, shifted, mask)
+		// ZF from the result; CF and OF cleared.
+		zf := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = icmp eq i64 %%%s, 0\n", zf, out)
+		c.storeFlag(c.flagsZSlot, "%"+zf)
+		c.storeFlag(c.flagsCFSlot, "false")
+		c.storeFlag(c.flagsOFSlot, "false")
 		return true, false, c.storeReg(ins.Args[2].Reg, "%"+out)
 
 	case "BZHIQ":
@@ -1143,6 +1134,11 @@ func (c *amd64Ctx) lowerArith(op Op, ins Instr) (ok bool, terminated bool, err e
 		fmt.Fprintf(c.b, "  %%%s = select i1 %%%s, i64 %%%s, i64 -1\n", mask, valid, maskOrZero)
 		out := c.newTmp()
 		fmt.Fprintf(c.b, "  %%%s = and i64 %s, %%%s\n", out, src, mask)
+		// ZF and SF from the result, CF set for an out-of-range index.
+		c.setLogicFlags(I64, "%"+out)
+		cf := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = xor i1 %%%s, true\n", cf, valid)
+		c.storeFlag(c.flagsCFSlot, "%"+cf)
 		return true, false, c.storeReg(ins.Args[2].Reg, "%"+out)
 
 	case "SHRQ", "SHLQ", "SARQ", "SHLL", "SHRL", "SARL", "SALQ", "SALL":
@@ -1189,6 +1185,13 @@ func (c *amd64Ctx) lowerArith(op Op, ins Instr) (ok bool, terminated bool, err e
 			return true, false, fmt.Errorf("amd64 %s unsupported shift amt: %q", op, ins.Raw)
 		}
 
+		kind := byte('r')
+		switch op {
+		case "SHLQ", "SALQ", "SHLL", "SALL":
+			kind = 'l'
+		case "SARQ", "SARL":
+			kind = 'a'
+		}
 		if valTy == I64 {
 			t := c.newTmp()
 			switch op {
@@ -1199,22 +1202,28 @@ func (c *amd64Ctx) lowerArith(op Op, ins Instr) (ok bool, terminated bool, err e
 			case "SARQ":
 				fmt.Fprintf(c.b, "  %%%s = ashr i64 %s, %s\n", t, dv, amtI64)
 			}
+			c.setShiftFlags(I64, kind, dv, amtI64, "%"+t)
 			return true, false, c.storeReg(dst, "%"+t)
 		}
 
 		// 32-bit shifts: operate on low 32, zero-extend to 64.
 		tr := c.newTmp()
 		fmt.Fprintf(c.b, "  %%%s = trunc i64 %s to i32\n", tr, dv)
-		amt32 := c.newTmp()
-		fmt.Fprintf(c.b, "  %%%s = trunc i64 %s to i32\n", amt32, amtI64)
+		amt32 := amtI64
+		if ins.Args[0].Kind != OpImm {
+			t := c.newTmp()
+			fmt.Fprintf(c.b, "  %%%s = trunc i64 %s to i32\n", t, amtI64)
+			amt32 = "%" + t
+		}
 		sh := c.newTmp()
 		if op == "SHLL" || op == "SALL" {
-			fmt.Fprintf(c.b, "  %%%s = shl i32 %%%s, %%%s\n", sh, tr, amt32)
+			fmt.Fprintf(c.b, "  %%%s = shl i32 %%%s, %s\n", sh, tr, amt32)
 		} else if op == "SARL" {
-			fmt.Fprintf(c.b, "  %%%s = ashr i32 %%%s, %%%s\n", sh, tr, amt32)
+			fmt.Fprintf(c.b, "  %%%s = ashr i32 %%%s, %s\n", sh, tr, amt32)
 		} else {
-			fmt.Fprintf(c.b, "  %%%s = lshr i32 %%%s, %%%s\n", sh, tr, amt32)
+			fmt.Fprintf(c.b, "  %%%s = lshr i32 %%%s, %s\n", sh, tr, amt32)
 		}
+		c.setShiftFlags(I32, kind, "%"+tr, amt32, "%"+sh)
 		z := c.newTmp()
 		fmt.Fprintf(c.b, "  %%%s = zext i32 %%%s to i64\n", z, sh)
 		return true, false, c.storeReg(dst, "%"+z)
@@ -1256,6 +1265,8 @@ func (c *amd64Ctx) lowerArith(op Op, ins Instr) (ok bool, terminated bool, err e
 		fmt.Fprintf(c.b, "  %%%s = shl i8 %%%s, %%%s\n", sh, d8, amt8)
 		out := c.newTmp()
 		fmt.Fprintf(c.b, "  %%%s = select i1 %%%s, i8 %%%s, i8 0\n", out, inRange, sh)
+		// CF and OF are undefined for counts of 8 or more.
+		c.setShiftFlags(I8, 'l', "%"+d8, "%"+amt8, "%"+out)
 		return true, false, c.storeRegSized(dst, I8, "%"+out)
 
 	case "SHLXQ", "SHRXQ":
@@ -1306,7 +1317,7 @@ func (c *amd64Ctx) lowerArith(op Op, ins Instr) (ok bool, terminated bool, err e
 		var cnt32 string
 		switch ins.Args[0].Kind {
 		case OpImm:
-			cnt32 = fmt.Sprintf("%d", uint32(ins.Args[0].Imm))
+			cnt32 = fmt.Sprintf("%d", uint32(ins.Args[0].Imm)&31)
 		case OpReg:
 			cv64, err := c.loadReg(ins.Args[0].Reg)
 			if err != nil {
@@ -1314,23 +1325,24 @@ func (c *amd64Ctx) lowerArith(op Op, ins Instr) (ok bool, terminated bool, err e
 			}
 			tr := c.newTmp()
 			fmt.Fprintf(c.b, "  %%%s = trunc i64 %s to i32\n", tr, cv64)
-			cnt32 = "%" + tr
+			cm := c.newTmp()
+			fmt.Fprintf(c.b, "  %%%s = and i32 %%%s, 31\n", cm, tr)
+			cnt32 = "%" + cm
 		default:
 			return true, false, fmt.Errorf("amd64 ROLL unsupported count: %q", ins.Raw)
 		}
 
-		cm := c.newTmp()
-		fmt.Fprintf(c.b, "  %%%s = and i32 %s, 31\n", cm, cnt32)
 		neg := c.newTmp()
-		fmt.Fprintf(c.b, "  %%%s = sub i32 32, %%%s\n", neg, cm)
+		fmt.Fprintf(c.b, "  %%%s = sub i32 32, %s\n", neg, cnt32)
 		nm := c.newTmp()
 		fmt.Fprintf(c.b, "  %%%s = and i32 %%%s, 31\n", nm, neg)
 		lhs := c.newTmp()
-		fmt.Fprintf(c.b, "  %%%s = shl i32 %%%s, %%%s\n", lhs, dv32, cm)
+		fmt.Fprintf(c.b, "  %%%s = shl i32 %%%s, %s\n", lhs, dv32, cnt32)
 		rhs := c.newTmp()
 		fmt.Fprintf(c.b, "  %%%s = lshr i32 %%%s, %%%s\n", rhs, dv32, nm)
 		rot := c.newTmp()
 		fmt.Fprintf(c.b, "  %%%s = or i32 %%%s, %%%s\n", rot, lhs, rhs)
+		c.setRotateFlags(I32, true, cnt32, "%"+rot)
 		z := c.newTmp()
 		fmt.Fprintf(c.b, "  %%%s = zext i32 %%%s to i64\n", z, rot)
 		return true, false, c.storeReg(dst, "%"+z)
@@ -1370,6 +1382,7 @@ func (c *amd64Ctx) lowerArith(op Op, ins Instr) (ok bool, terminated bool, err e
 		fmt.Fprintf(c.b, "  %%%s = lshr i64 %s, %%%s\n", rhs, dv, nm)
 		rot := c.newTmp()
 		fmt.Fprintf(c.b, "  %%%s = or i64 %%%s, %%%s\n", rot, lhs, rhs)
+		c.setRotateFlags(I64, true, cnt, "%"+rot)
 		return true, false, c.storeReg(dst, "%"+rot)
 
 	case "RORQ":
@@ -1407,6 +1420,7 @@ func (c *amd64Ctx) lowerArith(op Op, ins Instr) (ok bool, terminated bool, err e
 		fmt.Fprintf(c.b, "  %%%s = shl i64 %s, %%%s\n", rhs, dv, nm)
 		rot := c.newTmp()
 		fmt.Fprintf(c.b, "  %%%s = or i64 %%%s, %%%s\n", rot, lhs, rhs)
+		c.setRotateFlags(I64, false, cnt, "%"+rot)
 		return true, false, c.storeReg(dst, "%"+rot)
 
 	case "RORL":
@@ -1448,6 +1462,7 @@ func (c *amd64Ctx) lowerArith(op Op, ins Instr) (ok bool, terminated bool, err e
 		fmt.Fprintf(c.b, "  %%%s = shl i32 %%%s, %%%s\n", rhs, dv32, nm)
 		rot := c.newTmp()
 		fmt.Fprintf(c.b, "  %%%s = or i32 %%%s, %%%s\n", rot, lhs, rhs)
+		c.setRotateFlags(I32, false, cnt32, "%"+rot)
 		z := c.newTmp()
 		fmt.Fprintf(c.b, "  %%%s = zext i32 %%%s to i64\n", z, rot)
 		return true, false, c.storeReg(dst, "%"+z)
@@ -1594,7 +1609,7 @@ func (c *amd64Ctx) lowerArith(op Op, ins Instr) (ok bool, terminated bool, err e
 		}
 		cf := c.newTmp()
 		fmt.Fprintf(c.b, "  %%%s = icmp ne i64 %%%s, 0\n", cf, hi)
-		fmt.Fprintf(c.b, "  store i1 %%%s, ptr %s\n", cf, c.flagsCFSlot)
+		c.setMulFlags("%" + cf)
 		return true, false, nil
 
 	case "MULXQ":
@@ -1669,6 +1684,9 @@ func (c *amd64Ctx) lowerArith(op Op, ins Instr) (ok bool, terminated bool, err e
 		if err := c.storeReg(DX, "%"+hi64); err != nil {
 			return true, false, err
 		}
+		cf := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = icmp ne i32 %%%s, 0\n", cf, hi32)
+		c.setMulFlags("%" + cf)
 		return true, false, nil
 
 	case "DIVL":
@@ -1724,6 +1742,95 @@ func (c *amd64Ctx) lowerArith(op Op, ins Instr) (ok bool, terminated bool, err e
 		}
 		return true, false, nil
 
+	case "CDQ":
+		// EDX = sign of EAX.
+		ax, err := c.loadReg(AX)
+		if err != nil {
+			return true, false, err
+		}
+		a32 := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = trunc i64 %s to i32\n", a32, ax)
+		sign := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = ashr i32 %%%s, 31\n", sign, a32)
+		z := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = zext i32 %%%s to i64\n", z, sign)
+		return true, false, c.storeReg(DX, "%"+z)
+
+	case "IMULL", "IMUL3L":
+		// IMULL src          -> EDX:EAX = signed EAX*src
+		// IMULL src, dst     -> dst = signed(dst*src)
+		// IMUL3L imm,src,dst -> dst = signed(src*imm)
+		// The i64 product of the sign-extended operands overflows 32 bits
+		// when it differs from the sign extension of its low half.
+		mul := func(a64, b64 string) (lo, prod string) {
+			ext := func(v string) string {
+				t32 := c.newTmp()
+				fmt.Fprintf(c.b, "  %%%s = trunc i64 %s to i32\n", t32, v)
+				t := c.newTmp()
+				fmt.Fprintf(c.b, "  %%%s = sext i32 %%%s to i64\n", t, t32)
+				return "%" + t
+			}
+			a, b := ext(a64), ext(b64)
+			p := c.newTmp()
+			fmt.Fprintf(c.b, "  %%%s = mul i64 %s, %s\n", p, a, b)
+			l := c.newTmp()
+			fmt.Fprintf(c.b, "  %%%s = trunc i64 %%%s to i32\n", l, p)
+			if c.flagLive(amd64FlagCF | amd64FlagOF) {
+				back := c.newTmp()
+				fmt.Fprintf(c.b, "  %%%s = sext i32 %%%s to i64\n", back, l)
+				ov := c.newTmp()
+				fmt.Fprintf(c.b, "  %%%s = icmp ne i64 %%%s, %%%s\n", ov, back, p)
+				c.setMulFlags("%" + ov)
+			}
+			z := c.newTmp()
+			fmt.Fprintf(c.b, "  %%%s = zext i32 %%%s to i64\n", z, l)
+			return "%" + z, "%" + p
+		}
+		switch {
+		case len(ins.Args) == 1:
+			src, err := c.evalI64(ins.Args[0])
+			if err != nil {
+				return true, false, err
+			}
+			ax, err := c.loadReg(AX)
+			if err != nil {
+				return true, false, err
+			}
+			lo, p := mul(ax, src)
+			hi := c.newTmp()
+			fmt.Fprintf(c.b, "  %%%s = lshr i64 %s, 32\n", hi, p)
+			hi32 := c.newTmp()
+			fmt.Fprintf(c.b, "  %%%s = and i64 %%%s, 4294967295\n", hi32, hi)
+			if err := c.storeReg(AX, lo); err != nil {
+				return true, false, err
+			}
+			return true, false, c.storeReg(DX, "%"+hi32)
+		case len(ins.Args) == 2 && ins.Args[1].Kind == OpReg:
+			src, err := c.evalI64(ins.Args[0])
+			if err != nil {
+				return true, false, err
+			}
+			dv, err := c.loadReg(ins.Args[1].Reg)
+			if err != nil {
+				return true, false, err
+			}
+			lo, _ := mul(dv, src)
+			return true, false, c.storeReg(ins.Args[1].Reg, lo)
+		case len(ins.Args) == 3 && op == "IMUL3L" && ins.Args[2].Kind == OpReg:
+			imm, err := c.evalI64(ins.Args[0])
+			if err != nil {
+				return true, false, err
+			}
+			src, err := c.evalI64(ins.Args[1])
+			if err != nil {
+				return true, false, err
+			}
+			lo, _ := mul(src, imm)
+			return true, false, c.storeReg(ins.Args[2].Reg, lo)
+		default:
+			return true, false, fmt.Errorf("amd64 %s expects src[, dstReg] or imm, src, dstReg: %q", op, ins.Raw)
+		}
+
 	case "IMULQ", "IMUL3Q":
 		// IMULQ src         -> RDX:RAX = signed RAX*src
 		// IMULQ src, dst    -> dst = signed(dst*src)
@@ -1756,6 +1863,7 @@ func (c *amd64Ctx) lowerArith(op Op, ins Instr) (ok bool, terminated bool, err e
 			if err := c.storeReg(DX, "%"+hi); err != nil {
 				return true, false, err
 			}
+			c.setIMulFlags("%"+lo, "%"+p)
 			return true, false, nil
 		case 2:
 			if ins.Args[1].Kind != OpReg {
@@ -1778,6 +1886,7 @@ func (c *amd64Ctx) lowerArith(op Op, ins Instr) (ok bool, terminated bool, err e
 			fmt.Fprintf(c.b, "  %%%s = mul i128 %%%s, %%%s\n", p, a128, b128)
 			lo := c.newTmp()
 			fmt.Fprintf(c.b, "  %%%s = trunc i128 %%%s to i64\n", lo, p)
+			c.setIMulFlags("%"+lo, "%"+p)
 			return true, false, c.storeReg(dst, "%"+lo)
 		case 3:
 			if op != "IMUL3Q" || ins.Args[2].Kind != OpReg {
@@ -1799,6 +1908,7 @@ func (c *amd64Ctx) lowerArith(op Op, ins Instr) (ok bool, terminated bool, err e
 			fmt.Fprintf(c.b, "  %%%s = mul i128 %%%s, %%%s\n", p, a128, b128)
 			lo := c.newTmp()
 			fmt.Fprintf(c.b, "  %%%s = trunc i128 %%%s to i64\n", lo, p)
+			c.setIMulFlags("%"+lo, "%"+p)
 			return true, false, c.storeReg(ins.Args[2].Reg, "%"+lo)
 		default:
 			return true, false, fmt.Errorf("amd64 %s expects 1/2/3 operands: %q", op, ins.Raw)
@@ -1820,11 +1930,8 @@ func (c *amd64Ctx) lowerArith(op Op, ins Instr) (ok bool, terminated bool, err e
 		if err := c.storeReg(r, out); err != nil {
 			return true, false, err
 		}
-		cf := c.newTmp()
-		fmt.Fprintf(c.b, "  %%%s = icmp ne i64 %s, 0\n", cf, v)
-		fmt.Fprintf(c.b, "  store i1 %%%s, ptr %s\n", cf, c.flagsCFSlot)
-		c.setZSFlagsFromI64(out)
+		c.setSubFlags(I64, "0", v, "", out, true)
 		return true, false, nil
 	}
-	return false, false, nil
+	return c.lowerCond(op, ins)
 }
@@ -43,9 +43,7 @@ func (c *amd64Ctx) lowerAtomic(op Op, ins Instr) (ok bool, terminated bool, err
 		cx := c.newTmp()
 		fmt.Fprintf(c.b, "  %%%s = cmpxchg ptr %s, %s %s, %s %s seq_cst seq_cst, align %d\n", cx, ptr, ty, exp, ty, newv, align)
 		old := c.newTmp()
-		okv := c.newTmp()
 		fmt.Fprintf(c.b, "  %%%s = extractvalue {%s, i1} %%%s, 0\n", old, ty, cx)
-		fmt.Fprintf(c.b, "  %%%s = extractvalue {%s, i1} %%%s, 1\n", okv, ty, cx)
 		old64, err := c.amd64AtomicExtendToI64("%"+old, ty)
 		if err != nil {
 			return true, false, err
@@ -53,7 +51,64 @@ func (c *amd64Ctx) lowerAtomic(op Op, ins Instr) (ok bool, terminated bool, err
 		if err := c.storeReg(AX, old64); err != nil {
 			return true, false, err
 		}
-		fmt.Fprintf(c.b, "  store i1 %%%s, ptr %s\n", okv, c.flagsZSlot)
+		// Flags are those of CMP between the accumulator and the old value,
+		// so ZF reports success.
+		c.setCmpFlagsSized(ty, exp, "%"+old)
+		return true, false, nil
+
+	case "CMPXCHG8B":
+		// Compares EDX:EAX with the 64-bit memory operand and stores ECX:EBX
+		// there on a match; either way EDX:EAX ends up holding the old value
+		// and ZF reports success.
+		if len(ins.Args) != 1 || ins.Args[0].Kind != OpMem {
+			return true, false, fmt.Errorf("amd64 CMPXCHG8B expects mem: %q", ins.Raw)
+		}
+		pair := func(hi, lo Reg) (string, error) {
+			h, err := c.loadReg(hi)
+			if err != nil {
+				return "", err
+			}
+			l, err := c.loadReg(lo)
+			if err != nil {
+				return "", err
+			}
+			sh := c.newTmp()
+			fmt.Fprintf(c.b, "  %%%s = shl i64 %s, 32\n", sh, h)
+			lm := c.newTmp()
+			fmt.Fprintf(c.b, "  %%%s = and i64 %s, 4294967295\n", lm, l)
+			v := c.newTmp()
+			fmt.Fprintf(c.b, "  %%%s = or i64 %%%s, %%%s\n", v, sh, lm)
+			return "%" + v, nil
+		}
+		exp, err := pair(DX, AX)
+		if err != nil {
+			return true, false, err
+		}
+		newv, err := pair(CX, BX)
+		if err != nil {
+			return true, false, err
+		}
+		ptr, err := c.amd64AtomicPtrFromMem(ins.Args[0].Mem)
+		if err != nil {
+			return true, false, err
+		}
+		cx := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = cmpxchg ptr %s, i64 %s, i64 %s seq_cst seq_cst, align 8\n", cx, ptr, exp, newv)
+		old := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = extractvalue {i64, i1} %%%s, 0\n", old, cx)
+		okv := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = extractvalue {i64, i1} %%%s, 1\n", okv, cx)
+		lo := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = and i64 %%%s, 4294967295\n", lo, old)
+		hi := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = lshr i64 %%%s, 32\n", hi, old)
+		if err := c.storeReg(AX, "%"+lo); err != nil {
+			return true, false, err
+		}
+		if err := c.storeReg(DX, "%"+hi); err != nil {
+			return true, false, err
+		}
+		c.storeFlag(c.flagsZSlot, "%"+okv)
 		return true, false, nil
 
 	case "XADDL", "XADDQ":
@@ -82,6 +137,9 @@ func (c *amd64Ctx) lowerAtomic(op Op, ins Instr) (ok bool, terminated bool, err
 		}
 		old := c.newTmp()
 		fmt.Fprintf(c.b, "  %%%s = atomicrmw add ptr %s, %s %s seq_cst\n", old, ptr, ty, src)
+		sum := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = add %s %%%s, %s\n", sum, ty, old, src)
+		c.setAddFlags(ty, "%"+old, src, "", "%"+sum, true)
 		old64, err := c.amd64AtomicExtendToI64("%"+old, ty)
 		if err != nil {
 			return true, false, err
@@ -203,6 +261,9 @@ func (c *amd64Ctx) lowerAtomic(op Op, ins Instr) (ok bool, terminated bool, err
 		}
 		tmp := c.newTmp()
 		fmt.Fprintf(c.b, "  %%%s = atomicrmw %s ptr %s, %s %s seq_cst\n", tmp, rmw, ptr, ty, src)
+		res := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = %s %s %%%s, %s\n", res, rmw, ty, tmp, src)
+		c.setLogicFlags(ty, "%"+res)
 		return true, false, nil
 	}
 	return false, false, nil
@@ -42,14 +42,10 @@ func (c *amd64Ctx) lowerBranch(bi int, ii int, op Op, ins Instr, emitBr amd64Emi
 			return true, false, fmt.Errorf("amd64 CALL expects reg or symbol(SB) target: %q", ins.Raw)
 		}
 
-	case "JMP",
-		"JE", "JEQ", "JZ", "JNE", "JNZ",
-		"JL", "JLT", "JLE", "JG", "JGT", "JGE", "JS", "JNS",
-		"JA", "JHI", "JAE", "JHS", "JB", "JLO", "JBE", "JLS", "JNA",
-		"JC", "JNC", "JCC":
-		// ok
 	default:
-		return false, false, nil
+		if !amd64IsJump(string(op)) {
+			return false, false, nil
+		}
 	}
 	if len(ins.Args) != 1 {
 		return true, false, fmt.Errorf("amd64 %s expects 1 operand: %q", op, ins.Raw)
@@ -148,71 +144,10 @@ func (c *amd64Ctx) lowerBranch(bi int, ii int, op Op, ins Instr, emitBr amd64Emi
 		return true, false, fmt.Errorf("amd64 %s has no fallthrough block: %q", op, ins.Raw)
 	}
 	fall := c.blocks[bi+1].name
-	cond := ""
-	switch op {
-	case "JE", "JEQ", "JZ":
-		cond = c.loadFlag(c.flagsZSlot)
-	case "JNE", "JNZ":
-		z := c.loadFlag(c.flagsZSlot)
-		t := c.newTmp()
-		fmt.Fprintf(c.b, "  %%%s = xor i1 %s, true\n", t, z)
-		cond = "%" + t
-	case "JL", "JLT":
-		cond = c.loadFlag(c.flagsSltSlot)
-	case "JGE":
-		slt := c.loadFlag(c.flagsSltSlot)
-		t := c.newTmp()
-		fmt.Fprintf(c.b, "  %%%s = xor i1 %s, true\n", t, slt)
-		cond = "%" + t
-	case "JS":
-		cond = c.loadFlag(c.flagsSltSlot)
-	case "JNS":
-		slt := c.loadFlag(c.flagsSltSlot)
-		t := c.newTmp()
-		fmt.Fprintf(c.b, "  %%%s = xor i1 %s, true\n", t, slt)
-		cond = "%" + t
-	case "JLE":
-		slt := c.loadFlag(c.flagsSltSlot)
-		z := c.loadFlag(c.flagsZSlot)
-		t := c.newTmp()
-		fmt.Fprintf(c.b, "  %%%s = or i1 %s, %s\n", t, slt, z)
-		cond = "%" + t
-	case "JG", "JGT":
-		slt := c.loadFlag(c.flagsSltSlot)
-		z := c.loadFlag(c.flagsZSlot)
-		t1 := c.newTmp()
-		fmt.Fprintf(c.b, "  %%%s = or i1 %s, %s\n", t1, slt, z)
-		t2 := c.newTmp()
-		fmt.Fprintf(c.b, "  %%%s = xor i1 %%%s, true\n", t2, t1)
-		cond = "%" + t2
-	case "JB", "JLO", "JC":
-		cond = c.loadFlag(c.flagsCFSlot)
-	case "JNC", "JCC":
-		cf := c.loadFlag(c.flagsCFSlot)
-		t := c.newTmp()
-		fmt.Fprintf(c.b, "  %%%s = xor i1 %s, true\n", t, cf)
-		cond = "%" + t
-	case "JAE", "JHS":
-		cf := c.loadFlag(c.flagsCFSlot)
-		t := c.newTmp()
-		fmt.Fprintf(c.b, "  %%%s = xor i1 %s, true\n", t, cf)
-		cond = "%" + t
-	case "JBE", "JLS", "JNA":
-		cf := c.loadFlag(c.flagsCFSlot)
-		z := c.loadFlag(c.flagsZSlot)
-		t := c.newTmp()
-		fmt.Fprintf(c.b, "  %%%s = or i1 %s, %s\n", t, cf, z)
-		cond = "%" + t
-	case "JA", "JHI":
-		cf := c.loadFlag(c.flagsCFSlot)
-		z := c.loadFlag(c.flagsZSlot)
-		t1 := c.newTmp()
-		fmt.Fprintf(c.b, "  %%%s = or i1 %s, %s\n", t1, cf, z)
-		t2 := c.newTmp()
-		fmt.Fprintf(c.b, "  %%%s = xor i1 %%%s, true\n", t2, t1)
-		cond = "%" + t2
-	default:
-		return true, false, fmt.Errorf("amd64: unsupported branch %s", op)
+	cc, _ := amd64JccCond(string(op))
+	cond, err := c.condValue(cc)
+	if err != nil {
+		return true, false, err
 	}
 
 	if err := emitCondBr(cond, target, fall); err != nil {
@@ -222,6 +157,9 @@ func (c *amd64Ctx) lowerBranch(bi int, ii int, op Op, ins Instr, emitBr amd64Emi
 }
 
 func (c *amd64Ctx) callIndirectAddr(addr string) error {
+	if c.i386 {
+		return c.callIndirect386(addr)
+	}
 	fptr := c.newTmp()
 	fmt.Fprintf(c.b, "  %%%s = inttoptr i64 %s to ptr\n", fptr, addr)
 	di, _ := c.loadReg(DI)
@@ -265,6 +203,12 @@ func (c *amd64Ctx) callSym(symOp Operand) error {
 		// a known no-op runtime scheduler hook above.
 		return fmt.Errorf("amd64 call missing signature for %q", callee)
 	}
+	if regContractSig(csig, c.contractWordType()) {
+		return c.callContract(callee, csig, false)
+	}
+	if c.i386 && len(csig.ArgRegs) == 0 {
+		return c.callSym386(callee, csig)
+	}
 
 	args := make([]string, 0, len(csig.Args))
 	for i := 0; i < len(csig.Args); i++ {
@@ -302,9 +246,7 @@ func (c *amd64Ctx) callSym(symOp Operand) error {
 			fmt.Fprintf(c.b, "  %%%s = trunc i64 %s to i32\n", t, v)
 			args = append(args, "i32 %"+t)
 		case Ptr:
-			t := c.newTmp()
-			fmt.Fprintf(c.b, "  %%%s = inttoptr i64 %s to ptr\n", t, v)
-			args = append(args, "ptr %"+t)
+			args = append(args, "ptr "+c.i64ToPtr(v))
 		default:
 			return fmt.Errorf("amd64 call unsupported arg type %q", csig.Args[i])
 		}
@@ -325,9 +267,7 @@ func (c *amd64Ctx) callSym(symOp Operand) error {
 		fmt.Fprintf(c.b, "  %%%s = zext %s %%%s to i64\n", z, csig.Ret, t)
 		return c.storeReg(AX, "%"+z)
 	case Ptr:
-		p := c.newTmp()
-		fmt.Fprintf(c.b, "  %%%s = ptrtoint ptr %%%s to i64\n", p, t)
-		return c.storeReg(AX, "%"+p)
+		return c.storeReg(AX, c.ptrToI64("%"+t))
 	default:
 		return fmt.Errorf("amd64 call %q unsupported return type %s", callee, csig.Ret)
 	}
@@ -344,6 +284,9 @@ func (c *amd64Ctx) tailCallAndRet(symOp Operand) error {
 	s = strings.TrimSuffix(s, "(SB)")
 	callee := c.resolve(s)
 	csig, ok := c.sigs[callee]
+	if ok && regContractSig(csig, c.contractWordType()) {
+		return c.callContract(callee, csig, true)
+	}
 	if !ok {
 		// Cross-package trampoline (e.g. sync/atomic -> internal/runtime/atomic).
 		// If we don't have an explicit signature, fall back to caller signature.
@@ -401,6 +344,15 @@ func (c *amd64Ctx) tailCallAndRet(symOp Operand) error {
 			}
 			continue
 		}
+		if c.i386 && len(csig.ArgRegs) == 0 {
+			// ABI0 tail calls reuse the caller's argument frame.
+			v, err := c.tailArg386(csig, i)
+			if err != nil {
+				return err
+			}
+			args = append(args, fmt.Sprintf("%s %s", csig.Args[i], v))
+			continue
+		}
 
 		r := Reg("")
 		if i < len(csig.ArgRegs) {
@@ -437,9 +389,7 @@ func (c *amd64Ctx) tailCallAndRet(symOp Operand) error {
 			fmt.Fprintf(c.b, "  %%%s = trunc i64 %s to i32\n", t, v)
 			args = append(args, "i32 %"+t)
 		case Ptr:
-			t := c.newTmp()
-			fmt.Fprintf(c.b, "  %%%s = inttoptr i64 %s to ptr\n", t, v)
-			args = append(args, "ptr %"+t)
+			args = append(args, "ptr "+c.i64ToPtr(v))
 		default:
 			return fmt.Errorf("amd64 tailcall unsupported arg type %q", csig.Args[i])
 		}
@@ -471,6 +421,10 @@ func (c *amd64Ctx) tailCallAndRet(symOp Operand) error {
 			fmt.Fprintf(c.b, "  %%%s = trunc i64 %%%s to %s\n", conv, t, c.sig.Ret)
 			fmt.Fprintf(c.b, "  ret %s %%%s\n", c.sig.Ret, conv)
 			return nil
+		case csig.Ret == I32 && (c.sig.Ret == I1 || c.sig.Ret == I8 || c.sig.Ret == I16):
+			fmt.Fprintf(c.b, "  %%%s = trunc i32 %%%s to %s\n", conv, t, c.sig.Ret)
+			fmt.Fprintf(c.b, "  ret %s %%%s\n", c.sig.Ret, conv)
+			return nil
 		case (csig.Ret == I1 || csig.Ret == I8 || csig.Ret == I16 || csig.Ret == I32) && c.sig.Ret == I64:
 			fmt.Fprintf(c.b, "  %%%s = zext %s %%%s to i64\n", conv, csig.Ret, t)
 			fmt.Fprintf(c.b, "  ret i64 %%%s\n", conv)
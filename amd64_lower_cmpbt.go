@@ -76,7 +76,7 @@ func (c *amd64Ctx) lowerCmpBt(op Op, ins Instr) (ok bool, terminated bool, err e
 		fmt.Fprintf(c.b, "  %%%s = and i64 %%%s, 1\n", and, sh)
 		cf := c.newTmp()
 		fmt.Fprintf(c.b, "  %%%s = icmp ne i64 %%%s, 0\n", cf, and)
-		fmt.Fprintf(c.b, "  store i1 %%%s, ptr %s\n", cf, c.flagsCFSlot)
+		c.storeFlag(c.flagsCFSlot, "%"+cf)
 		return true, false, nil
 
 	case "BTSQ":
@@ -133,7 +133,7 @@ func (c *amd64Ctx) lowerCmpBt(op Op, ins Instr) (ok bool, terminated bool, err e
 		fmt.Fprintf(c.b, "  %%%s = and i64 %%%s, 1\n", and, sh)
 		cf := c.newTmp()
 		fmt.Fprintf(c.b, "  %%%s = icmp ne i64 %%%s, 0\n", cf, and)
-		fmt.Fprintf(c.b, "  store i1 %%%s, ptr %s\n", cf, c.flagsCFSlot)
+		c.storeFlag(c.flagsCFSlot, "%"+cf)
 		one := c.newTmp()
 		fmt.Fprintf(c.b, "  %%%s = shl i64 1, %s\n", one, amt)
 		out := c.newTmp()
@@ -146,32 +146,6 @@ func (c *amd64Ctx) lowerCmpBt(op Op, ins Instr) (ok bool, terminated bool, err e
 	return false, false, nil
 }
 
-func (c *amd64Ctx) setCmpFlagsSized(ty LLVMType, a, b string) {
-	// Store Z (eq), signed-lt, and CF (unsigned-lt) derived from CMP* a,b.
-	zt := c.newTmp()
-	fmt.Fprintf(c.b, "  %%%s = icmp eq %s %s, %s\n", zt, ty, a, b)
-	fmt.Fprintf(c.b, "  store i1 %%%s, ptr %s\n", zt, c.flagsZSlot)
-	slt := c.newTmp()
-	fmt.Fprintf(c.b, "  %%%s = icmp slt %s %s, %s\n", slt, ty, a, b)
-	fmt.Fprintf(c.b, "  store i1 %%%s, ptr %s\n", slt, c.flagsSltSlot)
-	ult := c.newTmp()
-	fmt.Fprintf(c.b, "  %%%s = icmp ult %s %s, %s\n", ult, ty, a, b)
-	fmt.Fprintf(c.b, "  store i1 %%%s, ptr %s\n", ult, c.flagsCFSlot)
-}
-
-func (c *amd64Ctx) setTestFlagsSized(ty LLVMType, a, b string) {
-	// Z from (a&b)==0, signed-lt from (a&b)<0, CF cleared.
-	and := c.newTmp()
-	fmt.Fprintf(c.b, "  %%%s = and %s %s, %s\n", and, ty, a, b)
-	zt := c.newTmp()
-	fmt.Fprintf(c.b, "  %%%s = icmp eq %s %%%s, 0\n", zt, ty, and)
-	fmt.Fprintf(c.b, "  store i1 %%%s, ptr %s\n", zt, c.flagsZSlot)
-	slt := c.newTmp()
-	fmt.Fprintf(c.b, "  %%%s = icmp slt %s %%%s, 0\n", slt, ty, and)
-	fmt.Fprintf(c.b, "  store i1 %%%s, ptr %s\n", slt, c.flagsSltSlot)
-	fmt.Fprintf(c.b, "  store i1 false, ptr %s\n", c.flagsCFSlot)
-}
-
 func (c *amd64Ctx) evalIntSized(op Operand, ty LLVMType) (string, error) {
 	switch op.Kind {
 	case OpImm:
@@ -0,0 +1,74 @@
+package plan9asm
+
+import "fmt"
+
+// lowerCond lowers the flag consumers other than Jcc: SETcc and CMOV{W,L,Q}cc
+// for every condition in amd64CondCodes. lowerMov hands it CMOVcc and
+// lowerArith falls back to it.
+func (c *amd64Ctx) lowerCond(op Op, ins Instr) (ok bool, terminated bool, err error) {
+	if cc, ok := amd64SetccCond(string(op)); ok {
+		// SETcc dst: set byte based on flags.
+		if len(ins.Args) != 1 {
+			return true, false, fmt.Errorf("amd64 %s expects one destination: %q", op, ins.Raw)
+		}
+		cond, err := c.condValue(cc)
+		if err != nil {
+			return true, false, err
+		}
+		switch ins.Args[0].Kind {
+		case OpReg:
+			sel := c.newTmp()
+			fmt.Fprintf(c.b, "  %%%s = select i1 %s, i8 1, i8 0\n", sel, cond)
+			return true, false, c.storeRegSized(ins.Args[0].Reg, I8, "%"+sel)
+		case OpMem:
+			addr, err := c.addrFromMem(ins.Args[0].Mem)
+			if err != nil {
+				return true, false, err
+			}
+			p := c.ptrFromAddrI64(addr)
+			z := c.newTmp()
+			fmt.Fprintf(c.b, "  %%%s = zext i1 %s to i8\n", z, cond)
+			fmt.Fprintf(c.b, "  store i8 %%%s, ptr %s, align 1\n", z, p)
+			return true, false, nil
+		case OpFP:
+			return true, false, c.storeFPResult(ins.Args[0].FPOffset, I1, cond)
+		default:
+			return true, false, fmt.Errorf("amd64 %s expects reg, mem or fp destination: %q", op, ins.Raw)
+		}
+	}
+
+	ty, cc, ok := amd64CmovCond(string(op))
+	if !ok {
+		return false, false, nil
+	}
+	// Conditional move: src, dstReg.
+	if len(ins.Args) != 2 || ins.Args[1].Kind != OpReg {
+		return true, false, fmt.Errorf("amd64 %s expects src, dstReg: %q", op, ins.Raw)
+	}
+	src, err := c.evalIntSized(ins.Args[0], ty)
+	if err != nil {
+		return true, false, err
+	}
+	dst := ins.Args[1].Reg
+	cur, err := c.evalIntSized(ins.Args[1], ty)
+	if err != nil {
+		return true, false, err
+	}
+	cond, err := c.condValue(cc)
+	if err != nil {
+		return true, false, err
+	}
+	sel := c.newTmp()
+	fmt.Fprintf(c.b, "  %%%s = select i1 %s, %s %s, %s %s\n", sel, cond, ty, src, ty, cur)
+	switch ty {
+	case I16:
+		return true, false, c.storeRegSized(dst, I16, "%"+sel)
+	case I32:
+		// 32-bit CMOV zero-extends the destination even when the condition
+		// is false.
+		z := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = zext i32 %%%s to i64\n", z, sel)
+		return true, false, c.storeReg(dst, "%"+z)
+	}
+	return true, false, c.storeReg(dst, "%"+sel)
+}
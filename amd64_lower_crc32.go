@@ -2,6 +2,10 @@ package plan9asm
 
 import "fmt"
 
+var amd64CRC32Features = []featureRule{
+	{ops: []string{"CRC32*"}, required: []string{"+crc32", "+sse4.2"}},
+}
+
 func (c *amd64Ctx) lowerCrc32(op Op, ins Instr) (ok bool, terminated bool, err error) {
 	switch op {
 	case "CRC32B", "CRC32W", "CRC32L", "CRC32Q":
@@ -6,11 +6,16 @@ import (
 	"strings"
 )
 
+var amd64FPFeatures = []featureRule{
+	{ops: []string{"VADDSD"}, features: []string{"+avx"}},
+	{ops: []string{"VFMADD213SD", "VFNMADD231SD"}, features: []string{"+fma"}},
+}
+
 func (c *amd64Ctx) lowerFP(op Op, ins Instr) (ok bool, terminated bool, err error) {
 	switch op {
 	case "MOVSD", "MOVAPD", "ANDPD", "ANDNPD", "ORPD", "XORPS",
 		"ADDSD", "SUBSD", "MULSD", "DIVSD", "MAXSD", "MINSD", "SQRTSD",
-		"COMISD", "CMPSD", "VADDSD", "VFMADD213SD", "VFNMADD231SD",
+		"COMISD", "UCOMISD", "CMPSD", "VADDSD", "VFMADD213SD", "VFNMADD231SD",
 		"CVTSD2SL", "CVTSL2SD", "CVTSQ2SD", "CVTTSD2SQ":
 		// handled below
 	default:
@@ -138,9 +143,11 @@ func (c *amd64Ctx) lowerFP(op Op, ins Instr) (ok bool, terminated bool, err erro
 		fmt.Fprintf(c.b, "  %%%s = call double @llvm.sqrt.f64(double %s)\n", t, src)
 		return true, false, c.storeXLowF64(ins.Args[1].Reg, "%"+t)
 
-	case "COMISD":
+	case "COMISD", "UCOMISD":
+		// ZF:PF:CF = 111 unordered, 100 equal, 001 less, 000 greater;
+		// OF, SF and AF are cleared.
 		if len(ins.Args) != 2 {
-			return true, false, fmt.Errorf("amd64 COMISD expects src, dst: %q", ins.Raw)
+			return true, false, fmt.Errorf("amd64 %s expects src, dst: %q", op, ins.Raw)
 		}
 		src, err := c.evalF64(ins.Args[0])
 		if err != nil {
@@ -152,13 +159,16 @@ func (c *amd64Ctx) lowerFP(op Op, ins Instr) (ok bool, terminated bool, err erro
 		}
 		z := c.newTmp()
 		fmt.Fprintf(c.b, "  %%%s = fcmp ueq double %s, %s\n", z, dst, src)
-		fmt.Fprintf(c.b, "  store i1 %%%s, ptr %s\n", z, c.flagsZSlot)
+		c.storeFlag(c.flagsZSlot, "%"+z)
 		cf := c.newTmp()
 		fmt.Fprintf(c.b, "  %%%s = fcmp ult double %s, %s\n", cf, dst, src)
-		fmt.Fprintf(c.b, "  store i1 %%%s, ptr %s\n", cf, c.flagsCFSlot)
-		lt := c.newTmp()
-		fmt.Fprintf(c.b, "  %%%s = fcmp olt double %s, %s\n", lt, dst, src)
-		fmt.Fprintf(c.b, "  store i1 %%%s, ptr %s\n", lt, c.flagsSltSlot)
+		c.storeFlag(c.flagsCFSlot, "%"+cf)
+		pf := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = fcmp uno double %s, %s\n", pf, dst, src)
+		c.storeFlag(c.flagsPFSlot, "%"+pf)
+		for _, slot := range []string{c.flagsOFSlot, c.flagsSFSlot, c.flagsAFSlot} {
+			c.storeFlag(slot, "false")
+		}
 		return true, false, nil
 
 	case "CMPSD":
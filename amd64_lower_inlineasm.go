@@ -0,0 +1,153 @@
+package plan9asm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// amd64InlineAsmOps lists the opcodes passed through by lowerInlineAsm.
+// Plan 9 and AT&T syntax share the source-first operand order. Each entry
+// assembles with llvm-mc to the encoding the Go assembler produces.
+var amd64InlineAsmOps = map[Op]inlineAsmOp{
+	"PDEPQ":      {"pdepq", "", inlineAsmSame3},
+	"PDEPL":      {"pdepl", "k", inlineAsmSame3},
+	"PEXTQ":      {"pextq", "", inlineAsmSame3},
+	"PEXTL":      {"pextl", "k", inlineAsmSame3},
+	"SARXQ":      {"sarxq", "", inlineAsmSame3},
+	"SARXL":      {"sarxl", "k", inlineAsmSame3},
+	"BLSIQ":      {"blsiq", "", inlineAsmSame2},
+	"BLSMSKQ":    {"blsmskq", "", inlineAsmSame2},
+	"BLSRQ":      {"blsrq", "", inlineAsmSame2},
+	"LZCNTQ":     {"lzcntq", "", inlineAsmSame2},
+	"LZCNTL":     {"lzcntl", "k", inlineAsmSame2},
+	"TZCNTL":     {"tzcntl", "k", inlineAsmSame2},
+	"MOVBEQ":     {"movbeq", "", inlineAsmSame2},
+	"PMADDUBSW":  {"pmaddubsw", "", inlineAsmSame2},
+	"PMULLD":     {"pmulld", "", inlineAsmSame2},
+	"PSIGNB":     {"psignb", "", inlineAsmSame2},
+	"PREFETCHT0": {"prefetcht0", "", inlineAsmSame1},
+	"VAESENC":    {"vaesenc", "", inlineAsmSame3},
+	"VPTERNLOGD": {"vpternlogd", "", inlineAsmSame4},
+}
+
+// amd64InlineAsmFlags lists the flags the passthrough opcodes that write
+// flags define; the others leave them alone. The asm returns each live one
+// through a flag output, so a following branch sees the instruction's flags.
+var amd64InlineAsmFlags = map[Op]flagMask{
+	"BLSIQ":   amd64FlagCF | amd64FlagZF | amd64FlagSF | amd64FlagOF,
+	"BLSMSKQ": amd64FlagCF | amd64FlagZF | amd64FlagSF | amd64FlagOF,
+	"BLSRQ":   amd64FlagCF | amd64FlagZF | amd64FlagSF | amd64FlagOF,
+	"LZCNTQ":  amd64FlagCF | amd64FlagZF,
+	"LZCNTL":  amd64FlagCF | amd64FlagZF,
+	"TZCNTL":  amd64FlagCF | amd64FlagZF,
+}
+
+// amd64FlagOutputs names the flag output constraint of each modeled flag.
+var amd64FlagOutputs = map[flagMask]string{
+	amd64FlagCF: "{@ccc}",
+	amd64FlagPF: "{@ccp}",
+	amd64FlagZF: "{@ccz}",
+	amd64FlagSF: "{@ccs}",
+	amd64FlagOF: "{@cco}",
+}
+
+// lowerInlineAsm passes an instruction without an IR lowering through to the
+// assembler (Options.InlineAsm) when amd64InlineAsmOps has its spelling.
+func (c *amd64Ctx) lowerInlineAsm(ins Instr) error {
+	op := Op(strings.ToUpper(string(ins.Op)))
+	spec, ok := amd64InlineAsmOps[op]
+	if !ok {
+		return fmt.Errorf("amd64: unsupported instruction %s (no verified inline asm spelling)", ins.Op)
+	}
+	args, err := spec.operands(ins, false)
+	if err != nil {
+		return fmt.Errorf("amd64: %v", err)
+	}
+	a := newInlineAsmCall("~{memory},~{dirflag},~{fpsr},~{flags}")
+	a.text(spec.mnemonic)
+
+	for i, arg := range args {
+		if i == 0 {
+			a.text(" ")
+		} else {
+			a.text(", ")
+		}
+		switch arg.Kind {
+		case OpImm:
+			a.text(fmt.Sprintf("$%d", arg.Imm))
+		case OpReg:
+			r := arg.Reg
+			switch {
+			case amd64IsXReg(r):
+				err = a.reg(r, "<16 x i8>", "x", "", func() (string, error) { return c.loadX(r) })
+			case amd64IsYReg(r):
+				err = a.reg(r, "<32 x i8>", "x", "", func() (string, error) { return c.loadY(r) })
+			case amd64IsZReg(r):
+				err = a.reg(r, "<64 x i8>", "v", "", func() (string, error) { return c.loadZ(r) })
+			case amd64IsVecOrMaskReg(r):
+				return fmt.Errorf("amd64: inline asm: unsupported register %s in %q", r, ins.Raw)
+			default:
+				err = a.reg(r, "i64", "r", spec.gpr, func() (string, error) { return c.loadReg(r) })
+			}
+			if err != nil {
+				return err
+			}
+		case OpMem:
+			addr, err := c.addrFromMem(arg.Mem)
+			if err != nil {
+				return err
+			}
+			a.text("(")
+			a.input("i64", "r", addr)
+			a.text(")")
+		case OpSym:
+			if strings.HasPrefix(arg.Sym, "$") {
+				return fmt.Errorf("amd64: inline asm: unsupported operand %q in %q", arg.String(), ins.Raw)
+			}
+			p, err := c.ptrFromSB(arg.Sym)
+			if err != nil {
+				return err
+			}
+			a.text("(")
+			a.input("ptr", "r", p)
+			a.text(")")
+		default:
+			return fmt.Errorf("amd64: inline asm: unsupported operand %q in %q", arg.String(), ins.Raw)
+		}
+	}
+
+	for _, f := range amd64FlagBits {
+		if amd64InlineAsmFlags[op]&f.mask == 0 || !c.flagLive(f.mask) {
+			continue
+		}
+		slot := f.slot(c)
+		a.flag(amd64FlagOutputs[f.mask], func(v string) { c.storeFlag(slot, v) })
+	}
+
+	return a.emit(c.b, c.newTmp, func(r Reg, v string) error {
+		switch {
+		case amd64IsXReg(r):
+			return c.storeX(r, v)
+		case amd64IsYReg(r):
+			return c.storeY(r, v)
+		case amd64IsZReg(r):
+			return c.storeZ(r, v)
+		}
+		return c.storeReg(r, v)
+	})
+}
+
+func amd64IsXReg(r Reg) bool {
+	_, ok := amd64ParseXReg(r)
+	return ok
+}
+
+func amd64IsYReg(r Reg) bool {
+	_, ok := amd64ParseYReg(r)
+	return ok
+}
+
+func amd64IsZReg(r Reg) bool {
+	_, ok := amd64ParseZReg(r)
+	return ok
+}
@@ -7,9 +7,12 @@ import (
 
 func (c *amd64Ctx) lowerMov(op Op, ins Instr) (ok bool, terminated bool, err error) {
 	switch op {
-	case "MOVQ", "MOVD", "MOVL", "MOVLQZX", "MOVLQSX", "MOVBQZX", "MOVBLZX", "MOVB", "MOVW", "MOVWLZX", "MOVWQZX", "MOVWQSX", "CMOVQLT":
+	case "MOVQ", "MOVD", "MOVL", "MOVLQZX", "MOVLQSX", "MOVBQZX", "MOVBLZX", "MOVB", "MOVW", "MOVWLZX", "MOVWQZX", "MOVWQSX":
 		// ok
 	default:
+		if _, _, ok := amd64CmovCond(string(op)); ok {
+			return c.lowerCond(op, ins)
+		}
 		return false, false, nil
 	}
 	if len(ins.Args) != 2 {
@@ -101,24 +104,6 @@ func (c *amd64Ctx) lowerMov(op Op, ins Instr) (ok bool, terminated bool, err err
 	}
 
 	switch op {
-	case "CMOVQLT":
-		// CMOVQLT srcReg, dstReg
-		if src.Kind != OpReg || dst.Kind != OpReg {
-			return true, false, fmt.Errorf("amd64 CMOVQLT expects reg, reg: %q", ins.Raw)
-		}
-		cond := c.loadFlag(c.flagsSltSlot)
-		sv, err := c.loadReg(src.Reg)
-		if err != nil {
-			return true, false, err
-		}
-		dv, err := c.loadReg(dst.Reg)
-		if err != nil {
-			return true, false, err
-		}
-		t := c.newTmp()
-		fmt.Fprintf(c.b, "  %%%s = select i1 %s, i64 %s, i64 %s\n", t, cond, sv, dv)
-		return true, false, c.storeReg(dst.Reg, "%"+t)
-
 	case "MOVB", "MOVW":
 		// MOVB/MOVW src, dst
 		widthTy := I8
@@ -307,20 +292,25 @@ func (c *amd64Ctx) lowerMov(op Op, ins Instr) (ok bool, terminated bool, err err
 
 		case OpFP:
 			// Store low 32 bits (common for ret slots).
-			if src.Kind != OpReg {
-				return true, false, fmt.Errorf("amd64 MOVL expects reg, fp for stores: %q", ins.Raw)
+			if src.Kind != OpReg && src.Kind != OpImm {
+				return true, false, fmt.Errorf("amd64 MOVL expects reg/imm, fp for stores: %q", ins.Raw)
 			}
-			v64, err := c.loadReg(src.Reg)
+			v64, err := c.evalI64(src)
 			if err != nil {
 				return true, false, err
 			}
+			if c.i386 {
+				if ok, err := c.storeFPWord386(dst.FPOffset, v64); ok {
+					return true, false, err
+				}
+			}
 			return true, false, c.storeFPResult(dst.FPOffset, I64, v64)
 		case OpMem:
 			var i32v string
 			switch src.Kind {
 			case OpImm:
 				i32v = fmt.Sprintf("%d", int32(src.Imm))
-			case OpReg, OpFP:
+			case OpReg, OpFP, OpSym:
 				v64, err := c.evalI64(src)
 				if err != nil {
 					return true, false, err
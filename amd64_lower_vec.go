@@ -5,6 +5,96 @@ import (
 	"strings"
 )
 
+var amd64VecFeatures = []featureRule{
+	{ops: []string{"PSHUFB"}, required: []string{"+ssse3"}},
+	{ops: []string{"PALIGNR"}, features: []string{"+ssse3"}},
+	{ops: []string{"PINSRB", "PINSRD", "PINSRQ", "PEXTRB", "PEXTRD", "PBLENDW"}, features: []string{"+sse4.1"}},
+	{ops: []string{"PCMPESTRI"}, features: []string{"+sse4.2"}},
+	{ops: []string{"PCLMULQDQ"}, features: []string{"+sse4.1"}, required: []string{"+pclmul"}},
+	{ops: []string{"AESENC", "AESENCLAST", "AESDEC", "AESDECLAST", "AESIMC", "AESKEYGENASSIST"}, required: []string{"+aes"}},
+	{ops: []string{"SHA1*", "SHA256*"}, features: []string{"+sha"}},
+
+	// VEX-encoded forms need AVX; the 256-bit integer forms need AVX2 and
+	// the 512-bit ones AVX-512.
+	{ops: []string{"VMOVDQU", "VMOVDQA", "VMOVNTDQ", "VMOVAPS", "VMOVAPD", "VZEROUPPER", "VZEROALL", "VPTEST", "VPERM2F128"},
+		features: []string{"+avx"}, operands: amd64AVXFloatWidth},
+	{ops: []string{"VPAND", "VPXOR", "VPOR", "VPADDD", "VPADDQ", "VPSHUFD", "VPSLLD", "VPSRLD", "VPSLLQ", "VPSRLQ"},
+		features: []string{"+avx"}, operands: amd64AVXIntWidth},
+	{ops: []string{"VPCMPEQB", "VPMOVMSKB", "VPALIGNR", "VPSRLDQ", "VPSLLDQ"},
+		features: []string{"+avx"}, operands: amd64AVXByteWidth},
+	{ops: []string{"VPSHUFB"}, features: []string{"+avx"}, required: []string{"+ssse3"}, operands: amd64AVXByteWidth},
+	{ops: []string{"VPERM2I128", "VINSERTI128", "VPBLENDD"}, features: []string{"+avx2"}},
+	{ops: []string{"VPBROADCASTB"}, features: []string{"+avx2"}, operands: amd64AVXByteWidth},
+	{ops: []string{"VPCLMULQDQ"}, features: []string{"+avx", "+vpclmulqdq"}, required: []string{"+pclmul"}, operands: amd64AVXFloatWidth},
+	{ops: []string{"VGF2P8AFFINEQB"}, features: []string{"+avx", "+gfni"}, operands: amd64AVXByteWidth},
+
+	// EVEX-only forms. 128- and 256-bit operands also need AVX512VL.
+	{ops: []string{"VMOVDQU64", "VMOVDQA64", "VPANDQ", "VPXORQ", "VPORQ", "VPTERNLOGD", "VPCOMPRESSQ", "VPCMPUQ", "VEXTRACTF32X4"},
+		features: []string{"+avx512f"}, operands: amd64EVEXWidth},
+	{ops: []string{"VPERMB", "VPERMI2B"}, features: []string{"+avx512f", "+avx512vbmi"}, operands: amd64EVEXWidth},
+	{ops: []string{"VPOPCNTB"}, features: []string{"+avx512f", "+avx512bitalg"}, operands: amd64EVEXWidth},
+	{ops: []string{"KMOVW"}, features: []string{"+avx512f"}},
+	{ops: []string{"KMOVB"}, features: []string{"+avx512dq"}},
+	{ops: []string{"KMOVQ", "KXORQ"}, features: []string{"+avx512bw"}},
+}
+
+// amd64WidestVecReg returns the widest vector register class among ins's
+// operands, or "" when it has none.
+func amd64WidestVecReg(ins Instr) OperandClass {
+	widest := OperandClass("")
+	for _, a := range ins.Args {
+		if a.Kind != OpReg {
+			continue
+		}
+		switch c := classifyReg(ArchAMD64, a.Reg); c {
+		case ClassZReg:
+			return c
+		case ClassYReg:
+			widest = c
+		case ClassXReg:
+			if widest == "" {
+				widest = c
+			}
+		}
+	}
+	return widest
+}
+
+func amd64AVXFloatWidth(ins Instr) []string {
+	if amd64WidestVecReg(ins) == ClassZReg {
+		return []string{"+avx512f"}
+	}
+	return nil
+}
+
+func amd64AVXIntWidth(ins Instr) []string {
+	switch amd64WidestVecReg(ins) {
+	case ClassYReg:
+		return []string{"+avx2"}
+	case ClassZReg:
+		return []string{"+avx512f"}
+	}
+	return nil
+}
+
+func amd64AVXByteWidth(ins Instr) []string {
+	switch amd64WidestVecReg(ins) {
+	case ClassYReg:
+		return []string{"+avx2"}
+	case ClassZReg:
+		return []string{"+avx512f", "+avx512bw"}
+	}
+	return nil
+}
+
+func amd64EVEXWidth(ins Instr) []string {
+	switch amd64WidestVecReg(ins) {
+	case ClassXReg, ClassYReg:
+		return []string{"+avx512vl"}
+	}
+	return nil
+}
+
 func (c *amd64Ctx) lowerVec(op Op, ins Instr) (ok bool, terminated bool, err error) {
 	if raw := string(op); strings.Contains(raw, ".") {
 		if i := strings.IndexByte(raw, '.'); i >= 0 {
@@ -190,12 +280,46 @@ func (c *amd64Ctx) lowerVec(op Op, ins Instr) (ok bool, terminated bool, err err
 				p := c.ptrFromAddrI64(addr)
 				fmt.Fprintf(c.b, "  store i64 %%%s, ptr %s, align 1\n", lo, p)
 				return true, false, nil
+			case OpFP:
+				return true, false, c.storeFPResult(ins.Args[1].FPOffset, I64, "%"+lo)
 			default:
 				return true, false, fmt.Errorf("amd64 MOVQ from X reg unsupported dst: %q", ins.Raw)
 			}
 		}
 	}
 
+	// MOVL Xn, dst (extract low 32 bits from Xn).
+	if op == "MOVL" && len(ins.Args) == 2 && ins.Args[0].Kind == OpReg {
+		if _, ok := amd64ParseXReg(ins.Args[0].Reg); ok {
+			xv, err := c.loadX(ins.Args[0].Reg)
+			if err != nil {
+				return true, false, err
+			}
+			bc := c.newTmp()
+			fmt.Fprintf(c.b, "  %%%s = bitcast <16 x i8> %s to <4 x i32>\n", bc, xv)
+			lo := c.newTmp()
+			fmt.Fprintf(c.b, "  %%%s = extractelement <4 x i32> %%%s, i32 0\n", lo, bc)
+			switch ins.Args[1].Kind {
+			case OpReg:
+				z := c.newTmp()
+				fmt.Fprintf(c.b, "  %%%s = zext i32 %%%s to i64\n", z, lo)
+				return true, false, c.storeReg(ins.Args[1].Reg, "%"+z)
+			case OpMem:
+				addr, err := c.addrFromMem(ins.Args[1].Mem)
+				if err != nil {
+					return true, false, err
+				}
+				p := c.ptrFromAddrI64(addr)
+				fmt.Fprintf(c.b, "  store i32 %%%s, ptr %s, align 1\n", lo, p)
+				return true, false, nil
+			case OpFP:
+				return true, false, c.storeFPResult(ins.Args[1].FPOffset, I32, "%"+lo)
+			default:
+				return true, false, fmt.Errorf("amd64 MOVL from X reg unsupported dst: %q", ins.Raw)
+			}
+		}
+	}
+
 	switch op {
 	case "KXORQ":
 		if len(ins.Args) != 3 || ins.Args[0].Kind != OpReg || ins.Args[1].Kind != OpReg || ins.Args[2].Kind != OpReg {
@@ -1024,9 +1148,21 @@ func (c *amd64Ctx) lowerVec(op Op, ins Instr) (ok bool, terminated bool, err err
 		fmt.Fprintf(c.b, "  %%%s = or i64 %%%s, %%%s\n", o, o01, o23)
 		z := c.newTmp()
 		fmt.Fprintf(c.b, "  %%%s = icmp eq i64 %%%s, 0\n", z, o)
-		fmt.Fprintf(c.b, "  store i1 %%%s, ptr %s\n", z, c.flagsZSlot)
-		// CF/other flags are not used by stdlib here; clear CF to avoid stale reads.
-		fmt.Fprintf(c.b, "  store i1 false, ptr %s\n", c.flagsCFSlot)
+		c.storeFlag(c.flagsZSlot, "%"+z)
+		// CF=1 iff (a&^b)==0 (Intel operand order is reversed); OF, SF, AF
+		// and PF are cleared.
+		nb := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = xor <32 x i8> %s, %s\n", nb, b, llvmAllOnesI8Vec(32))
+		andn := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = and <32 x i8> %s, %%%s\n", andn, a, nb)
+		wide := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = bitcast <32 x i8> %%%s to i256\n", wide, andn)
+		cf := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = icmp eq i256 %%%s, 0\n", cf, wide)
+		c.storeFlag(c.flagsCFSlot, "%"+cf)
+		for _, slot := range []string{c.flagsOFSlot, c.flagsSFSlot, c.flagsAFSlot, c.flagsPFSlot} {
+			c.storeFlag(slot, "false")
+		}
 		return true, false, nil
 
 	case "PCMPESTRI":
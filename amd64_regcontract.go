@@ -0,0 +1,340 @@
+package plan9asm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Register contracts on amd64 and 386 (see regcontract.go).
+
+func amd64RegContractArch(i386 bool) *regContractArch {
+	a := &regContractArch{
+		word:       I64,
+		regs:       []Reg{AX, BX, CX, DX, SI, DI, BP, "R8", "R9", "R10", "R11", "R12", "R13", "R14", "R15", FLAGS},
+		retRegs:    []Reg{AX, BX, CX, DI, SI, "R8", "R9", "R10", "R11"},
+		callRegs:   []Reg{DI, SI, DX, CX, "R8", "R9"},
+		effect:     amd64RegEffect,
+		flagEffect: amd64FlagEffect,
+		// Logic ops leave AF undefined, and only PUSHF and LAHF read it.
+		flagsKill: amd64FlagsAll &^ amd64FlagAF,
+		cfg:       amd64RegContractCFG,
+	}
+	if i386 {
+		// ABI0 passes arguments and results on the stack; a RET without
+		// result slots returns AX.
+		a.word = I32
+		a.regs = []Reg{AX, BX, CX, DX, SI, DI, BP, FLAGS}
+		a.retRegs = []Reg{AX}
+		a.callRegs = nil
+	}
+	tracked := map[Reg]bool{}
+	for _, r := range a.regs {
+		tracked[r] = true
+	}
+	a.canon = func(r Reg) (Reg, bool, bool) {
+		if base, _, ok := amd64ByteAlias(r); ok {
+			r = base
+		}
+		if tracked[r] {
+			return r, false, true
+		}
+		// X, Y and Z name the same vector register.
+		for _, parse := range []func(Reg) (int, bool){amd64ParseXReg, amd64ParseYReg, amd64ParseZReg} {
+			if n, ok := parse(r); ok {
+				return Reg(fmt.Sprintf("X%d", n)), true, true
+			}
+		}
+		if _, ok := amd64ParseKReg(r); ok || i386IsMMXReg(r) {
+			return r, true, true
+		}
+		return "", false, false
+	}
+	return a
+}
+
+func amd64RegContractCFG(fn Func) ([][]Instr, []flagSuccs) {
+	c := newAMD64Ctx(nil, fn, FuncSig{}, nil, nil, lowerConfig{})
+	blocks := make([][]Instr, len(c.blocks))
+	succs := make([]flagSuccs, len(c.blocks))
+	for bi, blk := range c.blocks {
+		blocks[bi] = blk.instrs
+		succs[bi] = c.flagSuccs(bi)
+	}
+	return blocks, succs
+}
+
+// amd64KillDst lists two-operand instructions that overwrite all of a
+// register destination without reading it.
+var amd64KillDst = map[string]bool{
+	"MOVQ": true, "MOVL": true, "MOVD": true, "LEAQ": true, "LEAL": true,
+	"MOVBQZX": true, "MOVWQZX": true, "MOVBLZX": true, "MOVWLZX": true, "MOVLQZX": true,
+	"MOVBQSX": true, "MOVWQSX": true, "MOVBLSX": true, "MOVWLSX": true, "MOVLQSX": true,
+	"POPQ": true, "POPL": true,
+	"POPCNTQ": true, "POPCNTL": true, "LZCNTQ": true, "LZCNTL": true, "TZCNTQ": true, "TZCNTL": true,
+	"PMOVMSKB": true, "VPMOVMSKB": true, "MOVMSKPS": true, "MOVMSKPD": true,
+	"MOVOU": true, "MOVOA": true, "MOVUPS": true, "MOVAPS": true, "MOVUPD": true, "MOVAPD": true,
+	"VMOVDQU": true, "VMOVDQA": true, "KMOVQ": true, "KMOVD": true,
+	"VPBROADCASTB": true, "VPBROADCASTW": true, "VPBROADCASTD": true, "VPBROADCASTQ": true,
+}
+
+// amd64KillDst3 lists non-destructive three-operand forms outside VEX.
+var amd64KillDst3 = map[string]bool{
+	"IMUL3Q": true, "IMUL3L": true, "ANDNQ": true, "ANDNL": true,
+	"SHLXQ": true, "SHLXL": true, "SHRXQ": true, "SHRXL": true, "SARXQ": true, "SARXL": true,
+	"RORXQ": true, "RORXL": true, "BEXTRQ": true, "BEXTRL": true, "BZHIQ": true, "BZHIL": true,
+	"PDEPQ": true, "PDEPL": true, "PEXTQ": true, "PEXTL": true,
+	"PEXTRQ": true, "PEXTRD": true, "PEXTRW": true, "PEXTRB": true,
+	"PSHUFD": true, "PSHUFL": true, "PSHUFHW": true, "PSHUFLW": true,
+}
+
+// amd64ZeroIdiom lists instructions that clear a register when both
+// operands name it.
+var amd64ZeroIdiom = map[string]bool{
+	"XORQ": true, "XORL": true, "SUBQ": true, "SUBL": true,
+	"PXOR": true, "VPXOR": true, "XORPS": true, "XORPD": true,
+}
+
+// amd64RegEffect models the registers ins reads and writes for the
+// contract analysis. Every named register is read unless ins overwrites
+// it whole; the destination may be written unless ins only compares.
+func amd64RegEffect(ins Instr) regEffect {
+	op := strings.ToUpper(string(ins.Op))
+	n := len(ins.Args)
+	var dst Reg
+	if n > 0 && ins.Args[n-1].Kind == OpReg {
+		dst = ins.Args[n-1].Reg
+	}
+	same := n >= 2 && dst != ""
+	for _, a := range ins.Args {
+		same = same && a.Kind == OpReg && a.Reg == dst
+	}
+	if same && amd64ZeroIdiom[op] {
+		return regEffect{def: []Reg{dst}, kill: []Reg{dst}}
+	}
+
+	kill := dst != "" && (amd64KillDst[op] && n <= 2 || amd64KillDst3[op] && n >= 3 || amd64VEXKillsDst(op, ins.Args))
+	var e regEffect
+	for i, a := range ins.Args {
+		if kill && i == n-1 {
+			continue
+		}
+		e.use = append(e.use, regOperands(a)...)
+	}
+	if kill {
+		e.kill = []Reg{dst}
+	}
+	if dst != "" && !amd64OnlyReads(op) {
+		e.def = append(e.def, dst)
+	}
+
+	switch op {
+	case "MULQ", "MULL", "IMULQ", "IMULL":
+		if n == 1 {
+			e.use = append(e.use, AX)
+			e.def = append(e.def, AX, DX)
+			e.kill = append(e.kill, AX, DX)
+		}
+	case "MULXQ", "MULXL":
+		e.use = append(e.use, DX)
+		if n == 3 && ins.Args[1].Kind == OpReg {
+			e.def = append(e.def, ins.Args[1].Reg)
+		}
+	case "DIVQ", "DIVL", "IDIVQ", "IDIVL":
+		e.use = append(e.use, AX, DX)
+		e.def = append(e.def, AX, DX)
+		e.kill = append(e.kill, AX, DX)
+	case "CQO", "CDQ", "CWD":
+		e.use = append(e.use, AX)
+		e.def = append(e.def, DX)
+		e.kill = append(e.kill, DX)
+	case "CPUID":
+		e.use = append(e.use, AX, CX)
+		e.def = append(e.def, AX, BX, CX, DX)
+		e.kill = append(e.kill, AX, BX, CX, DX)
+	case "XGETBV":
+		e.use = append(e.use, CX)
+		e.def = append(e.def, AX, DX)
+		e.kill = append(e.kill, AX, DX)
+	case "RDTSC", "RDTSCP":
+		e.def = append(e.def, AX, CX, DX)
+	case "CMPXCHGQ", "CMPXCHGL", "CMPXCHGW", "CMPXCHGB":
+		e.use = append(e.use, AX)
+		e.def = append(e.def, AX)
+	case "XCHGQ", "XCHGL", "XADDQ", "XADDL":
+		for _, a := range ins.Args {
+			if a.Kind == OpReg {
+				e.def = append(e.def, a.Reg)
+			}
+		}
+	case "SYSCALL":
+		e.use = append(e.use, AX, DI, SI, DX, "R10", "R8", "R9")
+		e.def = append(e.def, AX, CX, DX, "R11")
+	case "INT":
+		e.use = append(e.use, AX, BX, CX, DX, SI, DI, BP)
+		e.def = append(e.def, AX)
+	case "MOVSB", "MOVSW", "MOVSL", "MOVSQ", "STOSB", "STOSW", "STOSL", "STOSQ",
+		"CMPSB", "CMPSW", "CMPSL", "CMPSQ", "SCASB", "SCASW", "SCASL", "SCASQ",
+		"LODSB", "LODSW", "LODSL", "LODSQ":
+		if n == 0 {
+			e.use = append(e.use, AX, CX, SI, DI)
+			e.def = append(e.def, AX, CX, SI, DI)
+		}
+	}
+	return e
+}
+
+// amd64VEXKillsDst reports whether op is a VEX or EVEX form that writes its
+// last operand without reading it: three or more operands, no merge
+// masking, and not a fused multiply-add or other accumulating form.
+func amd64VEXKillsDst(op string, args []Operand) bool {
+	if !strings.HasPrefix(op, "V") || len(args) < 3 {
+		return false
+	}
+	for _, p := range []string{"VFMADD", "VFMSUB", "VFNMADD", "VFNMSUB", "VPDP", "VPTERNLOG", "VPERMT2", "VPERMI2"} {
+		if strings.HasPrefix(op, p) {
+			return false
+		}
+	}
+	for _, a := range args {
+		if a.Kind == OpReg {
+			if _, ok := amd64ParseKReg(a.Reg); ok {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// amd64OnlyReads reports whether op reads its operands without writing any
+// of them.
+func amd64OnlyReads(op string) bool {
+	switch op {
+	case "BTQ", "BTL", "BTW", "PUSHQ", "PUSHL", "PTEST", "VPTEST", "KORTESTQ", "KORTESTD", "KTESTQ",
+		"COMISS", "COMISD", "UCOMISS", "UCOMISD", "JMP", "CALL":
+		return true
+	}
+	if strings.HasPrefix(op, "TEST") {
+		return true
+	}
+	if strings.HasPrefix(op, "CMP") && !strings.HasPrefix(op, "CMPXCHG") && !strings.HasPrefix(op, "CMPS") {
+		return true
+	}
+	_, jcc := amd64JccCond(op)
+	return jcc
+}
+
+// loadContractReg reads r for a contract argument: a general-purpose
+// register as i64, or FLAGS as the RFLAGS word.
+func (c *amd64Ctx) loadContractReg(r Reg) (string, error) {
+	if r == FLAGS {
+		return c.flagsWord(), nil
+	}
+	return c.loadReg(r)
+}
+
+// storeContractReg writes the i64 v, received through a contract, to r.
+func (c *amd64Ctx) storeContractReg(r Reg, v string) error {
+	if r == FLAGS {
+		c.setFlagsFromWord(v, 16)
+		return nil
+	}
+	return c.storeReg(r, v)
+}
+
+// contractWordType returns the type of a register word in a contract.
+func (c *amd64Ctx) contractWordType() LLVMType {
+	if c.i386 {
+		return I32
+	}
+	return I64
+}
+
+// contractWord returns the i64 register value v as the contract word.
+func (c *amd64Ctx) contractWord(v string) string {
+	if !c.i386 {
+		return v
+	}
+	t := c.newTmp()
+	fmt.Fprintf(c.b, "  %%%s = trunc i64 %s to i32\n", t, v)
+	return "%" + t
+}
+
+// contractReg returns the contract word v as an i64 register value.
+func (c *amd64Ctx) contractReg(v string) string {
+	if !c.i386 {
+		return v
+	}
+	t := c.newTmp()
+	fmt.Fprintf(c.b, "  %%%s = zext i32 %s to i64\n", t, v)
+	return "%" + t
+}
+
+// callContract calls callee, whose signature is a register contract,
+// passing its ArgRegs and storing its RetRegs back. A tail call then
+// returns as the caller's RET would.
+func (c *amd64Ctx) callContract(callee string, csig FuncSig, tail bool) error {
+	args := make([]string, 0, len(csig.ArgRegs))
+	for i, r := range csig.ArgRegs {
+		if i >= len(csig.Args) {
+			return fmt.Errorf("amd64 call %q: no argument type for %s", callee, r)
+		}
+		v, err := c.loadContractReg(r)
+		if err != nil {
+			return err
+		}
+		args = append(args, fmt.Sprintf("%s %s", csig.Args[i], c.contractWord(v)))
+	}
+	if csig.Ret == Void {
+		fmt.Fprintf(c.b, "  call void %s(%s)\n", llvmGlobal(callee), strings.Join(args, ", "))
+	} else {
+		if tail {
+			// The caller's RET reads the results after the jump.
+			c.flagsDead = 0
+		}
+		r := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = call %s %s(%s)\n", r, csig.Ret, llvmGlobal(callee), strings.Join(args, ", "))
+		for i, reg := range csig.RetRegs {
+			v := "%" + r
+			if len(csig.RetRegs) > 1 {
+				t := c.newTmp()
+				fmt.Fprintf(c.b, "  %%%s = extractvalue %s %%%s, %d\n", t, csig.Ret, r, i)
+				v = "%" + t
+			}
+			if err := c.storeContractReg(reg, c.contractReg(v)); err != nil {
+				return err
+			}
+		}
+	}
+	if tail {
+		return c.lowerRET()
+	}
+	return nil
+}
+
+// lowerRetRegs returns the registers in c.sig.RetRegs.
+func (c *amd64Ctx) lowerRetRegs() error {
+	vals := make([]string, len(c.sig.RetRegs))
+	for i, r := range c.sig.RetRegs {
+		v, err := c.loadContractReg(r)
+		if err != nil {
+			return err
+		}
+		vals[i] = c.contractWord(v)
+	}
+	if len(vals) == 1 {
+		fmt.Fprintf(c.b, "  ret %s %s\n", c.sig.Ret, vals[0])
+		return nil
+	}
+	fields, ok := parseLiteralStructFields(c.sig.Ret)
+	if !ok || len(fields) != len(vals) {
+		return fmt.Errorf("amd64: RetRegs %v do not match return type %s", c.sig.RetRegs, c.sig.Ret)
+	}
+	cur := "undef"
+	for i, v := range vals {
+		t := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = insertvalue %s %s, %s %s, %d\n", t, c.sig.Ret, cur, fields[i], v, i)
+		cur = "%" + t
+	}
+	fmt.Fprintf(c.b, "  ret %s %s\n", c.sig.Ret, cur)
+	return nil
+}
@@ -9,8 +9,6 @@ type amd64EmitBr func(target string)
 type amd64EmitCondBr func(cond string, target string, fall string) error
 
 func emitAMD64Prelude(b *strings.Builder) {
-	b.WriteString("declare i64 @syscall(i64, i64, i64, i64, i64, i64, i64)\n")
-	b.WriteString("declare i32 @cliteErrno()\n")
 	// Generic LLVM intrinsics used by amd64 lowering.
 	b.WriteString("declare i64 @llvm.cttz.i64(i64, i1)\n")
 	b.WriteString("declare i32 @llvm.cttz.i32(i32, i1)\n")
@@ -42,7 +40,7 @@ func emitAMD64Prelude(b *strings.Builder) {
 	b.WriteString("\n")
 }
 
-func translateFuncAMD64(b *strings.Builder, fn Func, sig FuncSig, resolve func(string) string, sigs map[string]FuncSig, annotateSource bool) error {
+func translateFuncAMD64(b *strings.Builder, fn Func, sig FuncSig, resolve func(string) string, sigs map[string]FuncSig, cfg lowerConfig) error {
 	fmt.Fprintf(b, "define %s %s(", sig.Ret, llvmGlobal(sig.Name))
 	for i, t := range sig.Args {
 		if i > 0 {
@@ -56,7 +54,7 @@ func translateFuncAMD64(b *strings.Builder, fn Func, sig FuncSig, resolve func(s
 	}
 	b.WriteString(" {\n")
 
-	c := newAMD64Ctx(b, fn, sig, resolve, sigs, annotateSource)
+	c := newAMD64Ctx(b, fn, sig, resolve, sigs, cfg)
 	if err := c.emitEntryAllocas(); err != nil {
 		return err
 	}
@@ -77,6 +75,7 @@ func (c *amd64Ctx) lowerBlocks() error {
 		return nil
 	}
 
+	liveOut := c.flagLiveness()
 	for bi := 0; bi < len(c.blocks); bi++ {
 		blk := c.blocks[bi]
 		if bi != 0 {
@@ -86,6 +85,7 @@ func (c *amd64Ctx) lowerBlocks() error {
 		terminated := false
 		for ii, ins := range blk.instrs {
 			c.emitSourceComment(ins)
+			c.flagsDead = amd64FlagsAll &^ liveOut[bi][ii]
 			term, err := c.lowerInstr(bi, ii, ins, emitBr, emitCondBr)
 			if err != nil {
 				return err
@@ -125,6 +125,11 @@ func (c *amd64Ctx) lowerInstr(bi int, ii int, ins Instr, emitBr amd64EmitBr, emi
 		return false, nil
 	}
 
+	if c.i386 {
+		if ok, term, err := c.lower386(Op(op), ins); ok {
+			return term, err
+		}
+	}
 	if ok, term, err := c.lowerBranch(bi, ii, Op(op), ins, emitBr, emitCondBr); ok {
 		return term, err
 	}
@@ -152,10 +157,16 @@ func (c *amd64Ctx) lowerInstr(bi int, ii int, ins Instr, emitBr amd64EmitBr, emi
 	if ok, term, err := c.lowerArith(Op(op), ins); ok {
 		return term, err
 	}
+	if c.cfg.inlineAsm {
+		return false, c.lowerInlineAsm(ins)
+	}
 	return false, fmt.Errorf("amd64: unsupported instruction %s", ins.Op)
 }
 
 func (c *amd64Ctx) lowerRET() error {
+	if len(c.sig.RetRegs) > 0 {
+		return c.lowerRetRegs()
+	}
 	// Prefer classic Go asm return slots if present.
 	if len(c.fpResults) == 0 {
 		rax, err := c.loadReg(AX)
@@ -232,6 +243,6 @@ func (c *amd64Ctx) lowerRetZero() {
 	case I32:
 		c.b.WriteString("  ret i32 0\n")
 	default:
-		fmt.Fprintf(c.b, "  ret %s 0\n", c.sig.Ret)
+		fmt.Fprintf(c.b, "  ret %s %s\n", c.sig.Ret, llvmZeroValue(c.sig.Ret))
 	}
 }
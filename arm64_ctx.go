@@ -8,11 +8,11 @@ import (
 )
 
 type arm64Ctx struct {
-	b        *strings.Builder
-	sig      FuncSig
-	resolve  func(string) string
-	sigs     map[string]FuncSig
-	annotate bool
+	b       *strings.Builder
+	sig     FuncSig
+	resolve func(string) string
+	sigs    map[string]FuncSig
+	cfg     lowerConfig
 
 	tmp int
 
@@ -29,6 +29,7 @@ type arm64Ctx struct {
 	flagsCSlot   string
 	flagsVSlot   string
 	flagsWritten bool
+	flagsDead    flagMask // flags no later instruction reads (see flaglive.go)
 
 	exclusiveValidSlot string
 	exclusivePtrSlot   string
@@ -43,13 +44,13 @@ type arm64Ctx struct {
 	fpResAddrTaken map[int]bool        // result index -> fp result slot address escaped
 }
 
-func newARM64Ctx(b *strings.Builder, fn Func, sig FuncSig, resolve func(string) string, sigs map[string]FuncSig, annotate bool) *arm64Ctx {
+func newARM64Ctx(b *strings.Builder, fn Func, sig FuncSig, resolve func(string) string, sigs map[string]FuncSig, cfg lowerConfig) *arm64Ctx {
 	c := &arm64Ctx{
 		b:              b,
 		sig:            sig,
 		resolve:        resolve,
 		sigs:           sigs,
-		annotate:       annotate,
+		cfg:            cfg,
 		blocks:         arm64SplitBlocks(fn),
 		usedRegs:       map[Reg]bool{},
 		regSlot:        map[Reg]string{},
@@ -69,7 +70,7 @@ func newARM64Ctx(b *strings.Builder, fn Func, sig FuncSig, resolve func(string)
 }
 
 func (c *arm64Ctx) emitSourceComment(ins Instr) {
-	if !c.annotate {
+	if !c.cfg.annotateSource {
 		return
 	}
 	emitIRSourceComment(c.b, ins.Raw)
@@ -142,7 +143,9 @@ func (c *arm64Ctx) scanUsedRegs() {
 	// Ensure arg regs exist.
 	if len(c.sig.ArgRegs) > 0 {
 		for i := 0; i < len(c.sig.Args) && i < len(c.sig.ArgRegs); i++ {
-			markReg(c.sig.ArgRegs[i])
+			if c.sig.ArgRegs[i] != FLAGS {
+				markReg(c.sig.ArgRegs[i])
+			}
 		}
 	} else {
 		// Go internal ABI on arm64 uses up to 16 integer argument registers.
@@ -246,8 +249,7 @@ func (c *arm64Ctx) emitEntryAllocasAndArgInit() error {
 		// Custom arg->reg assignment (used by helper<> bodies).
 		for i := 0; i < len(c.sig.Args) && i < len(c.sig.ArgRegs); i++ {
 			r := c.sig.ArgRegs[i]
-			slot, ok := c.regSlot[r]
-			if !ok {
+			if _, ok := c.regSlot[r]; !ok && r != FLAGS {
 				continue
 			}
 			arg := fmt.Sprintf("%%arg%d", i)
@@ -259,7 +261,9 @@ func (c *arm64Ctx) emitEntryAllocasAndArgInit() error {
 			if !ok {
 				continue
 			}
-			fmt.Fprintf(c.b, "  store i64 %s, ptr %s\n", v, slot)
+			if err := c.storeContractReg(r, v); err != nil {
+				return err
+			}
 		}
 		return nil
 	}
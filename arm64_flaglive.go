@@ -0,0 +1,118 @@
+package plan9asm
+
+import "strings"
+
+// arm64FlagEffect returns how the lowering of ins reads and writes NZCV.
+// def only lists flags written on every path; ops missing here are treated
+// as not touching flags, so every flag reader must be listed.
+func arm64FlagEffect(ins Instr) flagEffect {
+	op := strings.ToUpper(string(ins.Op))
+	if dot := strings.IndexByte(op, '.'); dot >= 0 {
+		op = op[:dot]
+	}
+	cond := func(cc string) flagEffect {
+		if m, ok := arm64CondFlags[strings.ToUpper(cc)]; ok {
+			return flagEffect{use: m}
+		}
+		return flagEffect{use: arm64FlagsAll}
+	}
+	switch op {
+	case "BEQ", "BNE", "BLO", "BLT", "BHI", "BHS", "BLS", "BGE", "BGT", "BLE", "BCC", "BCS":
+		return cond(op[1:])
+	case "CSEL", "CSELW", "CSET", "CNEG", "CINC", "FCSELD":
+		if len(ins.Args) == 0 || ins.Args[0].Kind != OpIdent {
+			return flagEffect{use: arm64FlagsAll}
+		}
+		return cond(ins.Args[0].Ident)
+	case "ADC", "SBC":
+		return flagEffect{use: arm64FlagC}
+	case "ADCS", "SBCS":
+		return flagEffect{use: arm64FlagC, def: arm64FlagsAll}
+	case "ADDS", "SUBS", "ANDS", "ANDSW", "CMP", "CMPW", "CMN", "FCMPD", "SVC":
+		return flagEffect{def: arm64FlagsAll}
+	}
+	return flagEffect{}
+}
+
+// arm64IsBranch reports whether op (suffix stripped) may transfer control
+// within the function.
+func arm64IsBranch(op string) bool {
+	switch op {
+	case "B", "JMP", "BEQ", "BNE", "BLO", "BLT", "BHI", "BHS", "BLS", "BGE", "BGT", "BLE", "BCC", "BCS",
+		"CBZ", "CBNZ", "CBZW", "CBNZW", "TBZ", "TBNZ":
+		return true
+	}
+	return false
+}
+
+// flagLiveness computes the flags live after each instruction of c.blocks.
+func (c *arm64Ctx) flagLiveness() [][]flagMask {
+	effects := make([][]flagEffect, len(c.blocks))
+	succs := make([]flagSuccs, len(c.blocks))
+	for bi, blk := range c.blocks {
+		effects[bi] = make([]flagEffect, len(blk.instrs))
+		for ii, ins := range blk.instrs {
+			effects[bi][ii] = regContractFlagEffect(ins, arm64FlagEffect(ins), c.sig, c.resolve, c.sigs, arm64FlagsAll)
+		}
+		succs[bi] = c.flagSuccs(bi)
+	}
+	if c.cfg.keepDeadFlags {
+		return flagAllLive(effects, arm64FlagsAll)
+	}
+	return flagLiveOut(effects, succs, arm64FlagsAll)
+}
+
+// flagSuccs mirrors how lowerBranch resolves the branch ending block bi.
+func (c *arm64Ctx) flagSuccs(bi int) flagSuccs {
+	var fall flagSuccs
+	if bi+1 < len(c.blocks) {
+		fall.blocks = []int{bi + 1}
+	}
+	instrs := c.blocks[bi].instrs
+	for ii, ins := range instrs {
+		op := strings.ToUpper(string(ins.Op))
+		if dot := strings.IndexByte(op, '.'); dot >= 0 {
+			op = op[:dot]
+		}
+		if ins.Op == OpRET {
+			return flagSuccs{}
+		}
+		if !arm64IsBranch(op) {
+			continue
+		}
+		if ii != len(instrs)-1 || len(ins.Args) == 0 {
+			// Branches that do not end their block (CBZW) are not modeled.
+			return flagSuccs{unknown: true}
+		}
+		arg := ins.Args[len(ins.Args)-1]
+		if op == "B" || op == "JMP" {
+			switch {
+			case arg.Kind == OpReg || (arg.Kind == OpMem && arg.Mem.Base != PC):
+				// Indirect branch out of the function.
+				return flagSuccs{}
+			case arg.Kind == OpSym && strings.HasSuffix(arg.Sym, "(SB)"):
+				// Tail call.
+				return flagSuccs{}
+			}
+		}
+		name, ok := c.resolveBranchTarget(bi, arg)
+		if !ok {
+			return flagSuccs{unknown: true}
+		}
+		target := -1
+		for i, blk := range c.blocks {
+			if blk.name == name {
+				target = i
+				break
+			}
+		}
+		if target < 0 {
+			return flagSuccs{unknown: true}
+		}
+		if op == "B" || op == "JMP" {
+			return flagSuccs{blocks: []int{target}}
+		}
+		return flagSuccs{blocks: append([]int{target}, fall.blocks...)}
+	}
+	return fall
+}
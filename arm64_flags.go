@@ -5,10 +5,54 @@ import (
 	"strings"
 )
 
+// Modeled NZCV flags as a flagMask, for liveness (see flaglive.go).
+const (
+	arm64FlagN flagMask = 1 << iota
+	arm64FlagZ
+	arm64FlagC
+	arm64FlagV
+
+	arm64FlagsAll = arm64FlagN | arm64FlagZ | arm64FlagC | arm64FlagV
+)
+
+// arm64CondFlags lists the flags each condition reads.
+var arm64CondFlags = map[string]flagMask{
+	"EQ": arm64FlagZ, "NE": arm64FlagZ,
+	"CS": arm64FlagC, "HS": arm64FlagC, "LO": arm64FlagC, "CC": arm64FlagC,
+	"HI": arm64FlagC | arm64FlagZ, "LS": arm64FlagC | arm64FlagZ,
+	"LT": arm64FlagN | arm64FlagV, "GE": arm64FlagN | arm64FlagV,
+	"GT": arm64FlagZ | arm64FlagN | arm64FlagV, "LE": arm64FlagZ | arm64FlagN | arm64FlagV,
+}
+
+func (c *arm64Ctx) flagSlotMask(slot string) flagMask {
+	switch slot {
+	case c.flagsNSlot:
+		return arm64FlagN
+	case c.flagsZSlot:
+		return arm64FlagZ
+	case c.flagsCSlot:
+		return arm64FlagC
+	case c.flagsVSlot:
+		return arm64FlagV
+	}
+	return 0
+}
+
+// storeFlag writes v to the flag in slot unless no later instruction reads
+// that flag.
 func (c *arm64Ctx) storeFlag(slot string, v string) {
+	if c.flagsDead&c.flagSlotMask(slot) != 0 {
+		return
+	}
 	fmt.Fprintf(c.b, "  store i1 %s, ptr %s\n", v, slot)
 }
 
+// flagLive reports whether any flag in m may be read after the current
+// instruction.
+func (c *arm64Ctx) flagLive(m flagMask) bool {
+	return c.flagsDead&m != m
+}
+
 func (c *arm64Ctx) setFlagsSub(dst, src, res string) {
 	// NZCV for subtraction:
 	// Z: res==0
@@ -16,28 +60,26 @@ func (c *arm64Ctx) setFlagsSub(dst, src, res string) {
 	// C: dst >= src (unsigned, no borrow)
 	// V: signed overflow for dst - src
 	c.flagsWritten = true
+	c.setResultFlags(res)
+
+	if c.flagLive(arm64FlagC) {
+		carry := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = icmp uge i64 %s, %s\n", carry, dst, src)
+		c.storeFlag(c.flagsCSlot, "%"+carry)
+	}
 
-	z := c.newTmp()
-	fmt.Fprintf(c.b, "  %%%s = icmp eq i64 %s, 0\n", z, res)
-	n := c.newTmp()
-	fmt.Fprintf(c.b, "  %%%s = icmp slt i64 %s, 0\n", n, res)
-	carry := c.newTmp()
-	fmt.Fprintf(c.b, "  %%%s = icmp uge i64 %s, %s\n", carry, dst, src)
-
-	// overflow = ((dst ^ src) & (dst ^ res)) < 0
-	x1 := c.newTmp()
-	fmt.Fprintf(c.b, "  %%%s = xor i64 %s, %s\n", x1, dst, src)
-	x2 := c.newTmp()
-	fmt.Fprintf(c.b, "  %%%s = xor i64 %s, %s\n", x2, dst, res)
-	x3 := c.newTmp()
-	fmt.Fprintf(c.b, "  %%%s = and i64 %%%s, %%%s\n", x3, x1, x2)
-	ov := c.newTmp()
-	fmt.Fprintf(c.b, "  %%%s = icmp slt i64 %%%s, 0\n", ov, x3)
-
-	c.storeFlag(c.flagsZSlot, "%"+z)
-	c.storeFlag(c.flagsNSlot, "%"+n)
-	c.storeFlag(c.flagsCSlot, "%"+carry)
-	c.storeFlag(c.flagsVSlot, "%"+ov)
+	if c.flagLive(arm64FlagV) {
+		// overflow = ((dst ^ src) & (dst ^ res)) < 0
+		x1 := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = xor i64 %s, %s\n", x1, dst, src)
+		x2 := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = xor i64 %s, %s\n", x2, dst, res)
+		x3 := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = and i64 %%%s, %%%s\n", x3, x1, x2)
+		ov := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = icmp slt i64 %%%s, 0\n", ov, x3)
+		c.storeFlag(c.flagsVSlot, "%"+ov)
+	}
 }
 
 func (c *arm64Ctx) setFlagsAdd(dst, src, res string) {
@@ -47,64 +89,77 @@ func (c *arm64Ctx) setFlagsAdd(dst, src, res string) {
 	// C: carry out (unsigned overflow) => res < dst
 	// V: signed overflow for dst + src
 	c.flagsWritten = true
+	c.setResultFlags(res)
+
+	if c.flagLive(arm64FlagC) {
+		carry := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = icmp ult i64 %s, %s\n", carry, res, dst)
+		c.storeFlag(c.flagsCSlot, "%"+carry)
+	}
 
-	z := c.newTmp()
-	fmt.Fprintf(c.b, "  %%%s = icmp eq i64 %s, 0\n", z, res)
-	n := c.newTmp()
-	fmt.Fprintf(c.b, "  %%%s = icmp slt i64 %s, 0\n", n, res)
-	carry := c.newTmp()
-	fmt.Fprintf(c.b, "  %%%s = icmp ult i64 %s, %s\n", carry, res, dst)
-
-	// overflow = (~(dst ^ src) & (dst ^ res)) < 0
-	x1 := c.newTmp()
-	fmt.Fprintf(c.b, "  %%%s = xor i64 %s, %s\n", x1, dst, src)
-	nx1 := c.newTmp()
-	fmt.Fprintf(c.b, "  %%%s = xor i64 %%%s, -1\n", nx1, x1)
-	x2 := c.newTmp()
-	fmt.Fprintf(c.b, "  %%%s = xor i64 %s, %s\n", x2, dst, res)
-	x3 := c.newTmp()
-	fmt.Fprintf(c.b, "  %%%s = and i64 %%%s, %%%s\n", x3, nx1, x2)
-	ov := c.newTmp()
-	fmt.Fprintf(c.b, "  %%%s = icmp slt i64 %%%s, 0\n", ov, x3)
-
-	c.storeFlag(c.flagsZSlot, "%"+z)
-	c.storeFlag(c.flagsNSlot, "%"+n)
-	c.storeFlag(c.flagsCSlot, "%"+carry)
-	c.storeFlag(c.flagsVSlot, "%"+ov)
+	if c.flagLive(arm64FlagV) {
+		// overflow = (~(dst ^ src) & (dst ^ res)) < 0
+		x1 := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = xor i64 %s, %s\n", x1, dst, src)
+		nx1 := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = xor i64 %%%s, -1\n", nx1, x1)
+		x2 := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = xor i64 %s, %s\n", x2, dst, res)
+		x3 := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = and i64 %%%s, %%%s\n", x3, nx1, x2)
+		ov := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = icmp slt i64 %%%s, 0\n", ov, x3)
+		c.storeFlag(c.flagsVSlot, "%"+ov)
+	}
 }
 
 func (c *arm64Ctx) setFlagsLogic(res string) {
 	// ANDS-like: update N/Z; set C/V to 0 (good enough for current corpus).
 	c.flagsWritten = true
-
-	z := c.newTmp()
-	fmt.Fprintf(c.b, "  %%%s = icmp eq i64 %s, 0\n", z, res)
-	n := c.newTmp()
-	fmt.Fprintf(c.b, "  %%%s = icmp slt i64 %s, 0\n", n, res)
-
-	c.storeFlag(c.flagsZSlot, "%"+z)
-	c.storeFlag(c.flagsNSlot, "%"+n)
+	c.setResultFlags(res)
 	c.storeFlag(c.flagsCSlot, "false")
 	c.storeFlag(c.flagsVSlot, "false")
 }
 
+// setResultFlags sets Z and N from res.
+func (c *arm64Ctx) setResultFlags(res string) {
+	if c.flagLive(arm64FlagZ) {
+		z := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = icmp eq i64 %s, 0\n", z, res)
+		c.storeFlag(c.flagsZSlot, "%"+z)
+	}
+	if c.flagLive(arm64FlagN) {
+		n := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = icmp slt i64 %s, 0\n", n, res)
+		c.storeFlag(c.flagsNSlot, "%"+n)
+	}
+}
+
 func (c *arm64Ctx) condValue(cond string) (string, error) {
 	if !c.flagsWritten {
 		return "", fmt.Errorf("arm64: condition %s without any prior flags write", cond)
 	}
-	ldN := c.newTmp()
-	ldZ := c.newTmp()
-	ldC := c.newTmp()
-	ldV := c.newTmp()
-	fmt.Fprintf(c.b, "  %%%s = load i1, ptr %s\n", ldN, c.flagsNSlot)
-	fmt.Fprintf(c.b, "  %%%s = load i1, ptr %s\n", ldZ, c.flagsZSlot)
-	fmt.Fprintf(c.b, "  %%%s = load i1, ptr %s\n", ldC, c.flagsCSlot)
-	fmt.Fprintf(c.b, "  %%%s = load i1, ptr %s\n", ldV, c.flagsVSlot)
-
-	n := "%" + ldN
-	z := "%" + ldZ
-	carry := "%" + ldC
-	v := "%" + ldV
+	// Load only the flags the condition reads; liveness relies on it.
+	load := func(slot string) string {
+		t := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = load i1, ptr %s\n", t, slot)
+		return "%" + t
+	}
+	cc := strings.ToUpper(cond)
+	mask := arm64CondFlags[cc]
+	var n, z, carry, v string
+	if mask&arm64FlagN != 0 {
+		n = load(c.flagsNSlot)
+	}
+	if mask&arm64FlagZ != 0 {
+		z = load(c.flagsZSlot)
+	}
+	if mask&arm64FlagC != 0 {
+		carry = load(c.flagsCSlot)
+	}
+	if mask&arm64FlagV != 0 {
+		v = load(c.flagsVSlot)
+	}
 
 	not := func(x string) string {
 		t := c.newTmp()
@@ -132,7 +187,7 @@ func (c *arm64Ctx) condValue(cond string) (string, error) {
 		return "%" + t
 	}
 
-	switch strings.ToUpper(cond) {
+	switch cc {
 	case "EQ":
 		return z, nil
 	case "NE":
@@ -15,7 +15,7 @@ func newARM64CtxWithFuncForTest(t *testing.T, fn Func, sig FuncSig, sigs map[str
 		sigs = map[string]FuncSig{}
 	}
 	var b strings.Builder
-	c := newARM64Ctx(&b, fn, sig, testResolveSym("example"), sigs, false)
+	c := newARM64Ctx(&b, fn, sig, testResolveSym("example"), sigs, lowerConfig{})
 	if err := c.emitEntryAllocasAndArgInit(); err != nil {
 		t.Fatalf("emitEntryAllocasAndArgInit() error = %v", err)
 	}
@@ -725,7 +725,7 @@ func TestARM64BranchAndReturnEdgeCoverage(t *testing.T) {
 		},
 	}
 	var translated strings.Builder
-	if err := translateFuncARM64(&translated, fn, FuncSig{Name: "example.edge", Ret: I64}, testResolveSym("example"), nil, true); err != nil {
+	if err := translateFuncARM64(&translated, fn, FuncSig{Name: "example.edge", Ret: I64}, testResolveSym("example"), nil, lowerConfig{annotateSource: true}); err != nil {
 		t.Fatalf("translateFuncARM64() error = %v", err)
 	}
 	if !strings.Contains(translated.String(), "ret i64 0") || !strings.Contains(translated.String(), "; s: NOP") {
@@ -2,6 +2,10 @@ package plan9asm
 
 import "fmt"
 
+var arm64ArithFeatures = []featureRule{
+	{ops: []string{"CRC32*"}, required: []string{"+crc"}},
+}
+
 func (c *arm64Ctx) lowerArith(op Op, ins Instr) (ok bool, terminated bool, err error) {
 	switch op {
 	case "MRS_TPIDR_R0":
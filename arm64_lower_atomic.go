@@ -2,6 +2,10 @@ package plan9asm
 
 import "fmt"
 
+var arm64AtomicFeatures = []featureRule{
+	{ops: []string{"SWPAL*", "LDADDAL*", "LDORAL*", "LDCLRAL*", "CASAL*"}, features: []string{"+lse"}},
+}
+
 func (c *arm64Ctx) lowerAtomic(op Op, ins Instr) (ok bool, terminated bool, err error) {
 	switch op {
 	case "LDARW", "LDARB", "LDAR", "LDAXRW", "LDAXRB", "LDAXR":
@@ -297,6 +297,9 @@ func (c *arm64Ctx) callSym(symOp Operand) error {
 		return nil
 	}
 	csig, ok := c.sigs[callee]
+	if ok && regContractSig(csig, I64) {
+		return c.callContract(callee, csig, false)
+	}
 	if !ok {
 		// Default for external runtime helpers not discovered in this asm file.
 		csig = FuncSig{Name: callee, Ret: Void}
@@ -369,6 +372,9 @@ func (c *arm64Ctx) tailCallAndRet(symOp Operand) error {
 	s = strings.TrimSuffix(s, "(SB)")
 	callee := c.resolve(s)
 	csig, ok := c.sigs[callee]
+	if ok && regContractSig(csig, I64) {
+		return c.callContract(callee, csig, true)
+	}
 	if !ok {
 		// Cross-package trampoline (e.g. sync/atomic -> internal/runtime/atomic).
 		// If we don't have an explicit signature, fall back to caller signature.
@@ -56,10 +56,10 @@ func (c *arm64Ctx) lowerFP(op Op, ins Instr) (ok bool, terminated bool, err erro
 		cf := c.newTmp()
 		fmt.Fprintf(c.b, "  %%%s = or i1 %%%s, %%%s\n", c01, gt, eq)
 		fmt.Fprintf(c.b, "  %%%s = or i1 %%%s, %%%s\n", cf, c01, uno)
-		fmt.Fprintf(c.b, "  store i1 %%%s, ptr %s\n", lt, c.flagsNSlot)
-		fmt.Fprintf(c.b, "  store i1 %%%s, ptr %s\n", eq, c.flagsZSlot)
-		fmt.Fprintf(c.b, "  store i1 %%%s, ptr %s\n", cf, c.flagsCSlot)
-		fmt.Fprintf(c.b, "  store i1 %%%s, ptr %s\n", uno, c.flagsVSlot)
+		c.storeFlag(c.flagsNSlot, "%"+lt)
+		c.storeFlag(c.flagsZSlot, "%"+eq)
+		c.storeFlag(c.flagsCSlot, "%"+cf)
+		c.storeFlag(c.flagsVSlot, "%"+uno)
 		c.flagsWritten = true
 		return true, false, nil
 
@@ -0,0 +1,119 @@
+package plan9asm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// arm64GNUArrangement maps Plan 9 vector arrangement suffixes to GNU syntax.
+var arm64GNUArrangement = map[string]string{
+	"B8":  "8b",
+	"B16": "16b",
+	"H4":  "4h",
+	"H8":  "8h",
+	"S2":  "2s",
+	"S4":  "4s",
+	"D1":  "1d",
+	"D2":  "2d",
+	"Q1":  "1q",
+}
+
+// arm64InlineAsmOps lists the opcodes passed through by lowerInlineAsm.
+// Plan 9 mostly lists the destination last, so most orders are reversed, but
+// the four-operand forms are not simple reversals: MADDW Rm, Ra, Rn, Rd is
+// madd wd, wn, wm, wa. Each entry assembles with llvm-mc to the encoding the
+// Go assembler produces.
+var arm64InlineAsmOps = map[Op]inlineAsmOp{
+	"SDIV":   {"sdiv", "x", inlineAsmReversed3},
+	"SDIVW":  {"sdiv", "w", inlineAsmReversed3},
+	"UDIVW":  {"udiv", "w", inlineAsmReversed3},
+	"SMULH":  {"smulh", "x", inlineAsmReversed3},
+	"MADDW":  {"madd", "w", []int{3, 2, 0, 1}},
+	"MSUBW":  {"msub", "w", []int{3, 2, 0, 1}},
+	"CLZW":   {"clz", "w", inlineAsmReversed2},
+	"RBITW":  {"rbit", "w", inlineAsmReversed2},
+	"REVW":   {"rev", "w", inlineAsmReversed2},
+	"REV16W": {"rev16", "w", inlineAsmReversed2},
+	"VBIC":   {"bic", "", inlineAsmReversed3},
+	"VCNT":   {"cnt", "", inlineAsmReversed2},
+	"VMUL":   {"mul", "", inlineAsmReversed3},
+	"VNOT":   {"not", "", inlineAsmReversed2},
+	"VRBIT":  {"rbit", "", inlineAsmReversed2},
+	"VSUB":   {"sub", "", inlineAsmReversed3},
+	"VUMAX":  {"umax", "", inlineAsmReversed3},
+	"VUMIN":  {"umin", "", inlineAsmReversed3},
+	"VUZP1":  {"uzp1", "", inlineAsmReversed3},
+}
+
+// lowerInlineAsm passes an instruction without an IR lowering through to the
+// assembler (Options.InlineAsm) when arm64InlineAsmOps has its spelling.
+// Vector registers must carry an arrangement, since a bare Vn names a scalar
+// register whose width the operand alone does not give. None of the listed
+// opcodes writes NZCV, so the modeled flags stay valid across them.
+func (c *arm64Ctx) lowerInlineAsm(ins Instr) error {
+	spec, ok := arm64InlineAsmOps[Op(strings.ToUpper(string(ins.Op)))]
+	if !ok {
+		return fmt.Errorf("arm64: unsupported instruction %s (no verified inline asm spelling)", ins.Op)
+	}
+	args, err := spec.operands(ins, true)
+	if err != nil {
+		return fmt.Errorf("arm64: %v", err)
+	}
+	a := newInlineAsmCall("~{memory},~{cc}")
+	a.text(spec.mnemonic)
+
+	for i, arg := range args {
+		if i == 0 {
+			a.text(" ")
+		} else {
+			a.text(", ")
+		}
+		switch arg.Kind {
+		case OpImm:
+			a.text(fmt.Sprintf("#%d", arg.Imm))
+		case OpReg:
+			r := arg.Reg
+			s := strings.ToUpper(string(r))
+			if s == "ZR" {
+				a.text(spec.gpr + "zr")
+				continue
+			}
+			if _, ok := arm64ParseVReg(r); ok {
+				base, suffix, _ := strings.Cut(s, ".")
+				if suffix == "" {
+					return fmt.Errorf("arm64: inline asm: %s needs an arrangement in %q", r, ins.Raw)
+				}
+				if err := a.reg(Reg(base), "<16 x i8>", "w", "", func() (string, error) { return c.loadVReg(r) }); err != nil {
+					return err
+				}
+				gnu, ok := arm64GNUArrangement[suffix]
+				if !ok {
+					// Lane selectors: V1.S[1] -> v1.s[1].
+					gnu = strings.ToLower(suffix)
+				}
+				a.text("." + gnu)
+				continue
+			}
+			if err := a.reg(r, "i64", "r", spec.gpr, func() (string, error) { return c.loadReg(r) }); err != nil {
+				return err
+			}
+		case OpMem:
+			addr, _, _, err := c.addrI64(arg.Mem, false)
+			if err != nil {
+				return err
+			}
+			a.text("[")
+			a.input("i64", "r", addr)
+			a.text("]")
+		default:
+			return fmt.Errorf("arm64: inline asm: unsupported operand %q in %q", arg.String(), ins.Raw)
+		}
+	}
+
+	return a.emit(c.b, c.newTmp, func(r Reg, v string) error {
+		if _, ok := arm64ParseVReg(r); ok {
+			return c.storeVReg(r, v)
+		}
+		return c.storeReg(r, v)
+	})
+}
@@ -6,6 +6,12 @@ import (
 	"strings"
 )
 
+var arm64VecFeatures = []featureRule{
+	{ops: []string{"AESE", "AESD", "AESMC", "AESIMC", "VPMULL", "VPMULL2"}, features: []string{"+aes"}},
+	{ops: []string{"SHA1*", "SHA256*"}, features: []string{"+sha2"}},
+	{ops: []string{"SHA512*", "VEOR3", "VBCAX", "VRAX1", "VXAR"}, features: []string{"+sha3"}},
+}
+
 func arm64ParseVRegLane(r Reg) (kind byte, lane int, ok bool) {
 	s := strings.ToUpper(strings.TrimSpace(string(r)))
 	dot := strings.IndexByte(s, '.')
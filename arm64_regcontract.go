@@ -0,0 +1,288 @@
+package plan9asm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Register contracts on arm64 (see regcontract.go).
+
+func arm64RegContractArch() *regContractArch {
+	a := &regContractArch{
+		word:       I64,
+		effect:     arm64RegEffect,
+		flagEffect: arm64FlagEffect,
+		flagsKill:  arm64FlagsAll,
+		cfg:        arm64RegContractCFG,
+	}
+	// R28 is g; R31 is ZR or RSP.
+	tracked := map[Reg]bool{}
+	for i := 0; i <= 30; i++ {
+		if i == 28 {
+			continue
+		}
+		r := Reg(fmt.Sprintf("R%d", i))
+		a.regs = append(a.regs, r)
+		tracked[r] = true
+		if i < 16 {
+			a.retRegs = append(a.retRegs, r)
+		}
+		if i < 8 {
+			a.callRegs = append(a.callRegs, r)
+		}
+	}
+	a.regs = append(a.regs, FLAGS)
+	tracked[FLAGS] = true
+	a.canon = func(r Reg) (Reg, bool, bool) {
+		if tracked[r] {
+			return r, false, true
+		}
+		if n, ok := arm64ParseVReg(r); ok {
+			return Reg(fmt.Sprintf("V%d", n)), true, true
+		}
+		// F<n> is the low half of V<n>.
+		s := string(r)
+		if len(s) > 1 && s[0] == 'F' {
+			if n, ok := arm64ParseVReg(Reg("V" + s[1:])); ok {
+				return Reg(fmt.Sprintf("V%d", n)), true, true
+			}
+		}
+		return "", false, false
+	}
+	return a
+}
+
+func arm64RegContractCFG(fn Func) ([][]Instr, []flagSuccs) {
+	c := newARM64Ctx(nil, fn, FuncSig{}, nil, nil, lowerConfig{})
+	blocks := make([][]Instr, len(c.blocks))
+	succs := make([]flagSuccs, len(c.blocks))
+	for bi, blk := range c.blocks {
+		blocks[bi] = blk.instrs
+		succs[bi] = c.flagSuccs(bi)
+	}
+	return blocks, succs
+}
+
+// arm64RegEffect models the registers ins reads and writes for the
+// contract analysis. Every named register is read unless ins overwrites
+// it whole; the last register operand may be written unless ins only
+// compares or branches.
+func arm64RegEffect(ins Instr) regEffect {
+	op := strings.ToUpper(string(ins.Op))
+	writeback := strings.HasSuffix(op, ".P") || strings.HasSuffix(op, ".W")
+	if dot := strings.IndexByte(op, '.'); dot >= 0 {
+		op = op[:dot]
+	}
+	n := len(ins.Args)
+	var e regEffect
+	if strings.HasPrefix(op, "CAS") {
+		// CAS compares with and overwrites its first register.
+		for _, a := range ins.Args {
+			regs := regOperands(a)
+			e.use = append(e.use, regs...)
+			if a.Kind == OpReg || a.Kind == OpRegList {
+				e.def = append(e.def, regs...)
+			}
+		}
+		return e
+	}
+
+	var dst Operand
+	if n > 0 {
+		dst = ins.Args[n-1]
+	}
+	lane := dst.Kind == OpReg && strings.Contains(string(dst.Reg), "[")
+	kill := false
+	switch {
+	case dst.Kind == OpRegList:
+		kill = strings.HasPrefix(op, "LD") || strings.HasPrefix(op, "VLD")
+	case dst.Kind != OpReg || lane:
+	case n == 2:
+		kill = arm64KillsDst2(op)
+	case n >= 3:
+		kill = !arm64ReadsDst3(op)
+	}
+	for i, a := range ins.Args {
+		if kill && i == n-1 {
+			continue
+		}
+		e.use = append(e.use, regOperands(a)...)
+	}
+	if kill {
+		e.kill = regOperands(dst)
+	}
+	if (dst.Kind == OpReg || dst.Kind == OpRegList) && !arm64OnlyReads(op) {
+		e.def = append(e.def, regOperands(dst)...)
+	}
+	if writeback {
+		for _, a := range ins.Args {
+			if a.Kind == OpMem {
+				e.def = append(e.def, a.Mem.Base)
+			}
+		}
+	}
+	if op == "SVC" {
+		e.use = append(e.use, "R0", "R1", "R2", "R3", "R4", "R5", "R8", "R16")
+		e.def = append(e.def, "R0", "R1")
+	}
+	return e
+}
+
+// arm64KillsDst2 reports whether the two-operand op overwrites its
+// register destination without reading it.
+func arm64KillsDst2(op string) bool {
+	switch op {
+	case "MOVK", "VMOVQ":
+		return false
+	case "MVN", "MVNW", "NEG", "NEGW", "CLZ", "CLZW", "RBIT", "RBITW", "MRS", "ADR", "ADRP", "VMOV", "VLD1":
+		return true
+	}
+	for _, p := range []string{"MOV", "REV", "SXT", "UXT", "CSET", "FMOV", "LDAR", "LDAXR", "LDXR"} {
+		if strings.HasPrefix(op, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// arm64ReadsDst3 reports whether op, with three or more operands, reads
+// the register it writes.
+func arm64ReadsDst3(op string) bool {
+	switch op {
+	case "MOVK", "BFI", "BFIW", "BFXIL", "BFXILW", "VTBX", "VBIT", "VBIF", "VBSL", "VMLA", "VMLS":
+		return true
+	}
+	return strings.HasPrefix(op, "VFML") || strings.HasPrefix(op, "SHA")
+}
+
+// arm64OnlyReads reports whether op reads its operands without writing a
+// register.
+func arm64OnlyReads(op string) bool {
+	if arm64IsBranch(op) {
+		return true
+	}
+	switch op {
+	case "CMP", "CMPW", "CMN", "CMNW", "TST", "TSTW", "FCMPD", "FCMPS", "CCMP", "CCMPW",
+		"BL", "BLR", "CALL", "PRFM", "STP", "STPW":
+		return true
+	}
+	return false
+}
+
+// nzcvSlots returns the flag slots in NZCV order, N at bit 31.
+func (c *arm64Ctx) nzcvSlots() []string {
+	return []string{c.flagsNSlot, c.flagsZSlot, c.flagsCSlot, c.flagsVSlot}
+}
+
+// nzcvWord composes the NZCV register from the modeled flags.
+func (c *arm64Ctx) nzcvWord() string {
+	acc := "0"
+	for i, slot := range c.nzcvSlots() {
+		v := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = load i1, ptr %s\n", v, slot)
+		z := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = zext i1 %%%s to i64\n", z, v)
+		sh := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = shl i64 %%%s, %d\n", sh, z, 31-i)
+		o := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = or i64 %s, %%%s\n", o, acc, sh)
+		acc = "%" + o
+	}
+	return acc
+}
+
+// setNZCVFromWord loads the modeled flags from an NZCV value.
+func (c *arm64Ctx) setNZCVFromWord(w string) {
+	for i, slot := range c.nzcvSlots() {
+		sh := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = lshr i64 %s, %d\n", sh, w, 31-i)
+		b := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = trunc i64 %%%s to i1\n", b, sh)
+		c.storeFlag(slot, "%"+b)
+	}
+	c.flagsWritten = true
+}
+
+// loadContractReg reads r for a contract argument.
+func (c *arm64Ctx) loadContractReg(r Reg) (string, error) {
+	if r == FLAGS {
+		return c.nzcvWord(), nil
+	}
+	return c.loadReg(r)
+}
+
+// storeContractReg writes v, received through a contract, to r.
+func (c *arm64Ctx) storeContractReg(r Reg, v string) error {
+	if r == FLAGS {
+		c.setNZCVFromWord(v)
+		return nil
+	}
+	return c.storeReg(r, v)
+}
+
+// callContract calls callee, whose signature is a register contract,
+// passing its ArgRegs and storing its RetRegs back. A tail call then
+// returns as the caller's RET would.
+func (c *arm64Ctx) callContract(callee string, csig FuncSig, tail bool) error {
+	args := make([]string, 0, len(csig.ArgRegs))
+	for i, r := range csig.ArgRegs {
+		v, err := c.loadContractReg(r)
+		if err != nil {
+			return err
+		}
+		args = append(args, fmt.Sprintf("%s %s", csig.Args[i], v))
+	}
+	if csig.Ret == Void {
+		fmt.Fprintf(c.b, "  call void %s(%s)\n", llvmGlobal(callee), strings.Join(args, ", "))
+	} else {
+		if tail {
+			// The caller's RET reads the results after the branch.
+			c.flagsDead = 0
+		}
+		r := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = call %s %s(%s)\n", r, csig.Ret, llvmGlobal(callee), strings.Join(args, ", "))
+		for i, reg := range csig.RetRegs {
+			v := "%" + r
+			if len(csig.RetRegs) > 1 {
+				t := c.newTmp()
+				fmt.Fprintf(c.b, "  %%%s = extractvalue %s %%%s, %d\n", t, csig.Ret, r, i)
+				v = "%" + t
+			}
+			if err := c.storeContractReg(reg, v); err != nil {
+				return err
+			}
+		}
+	}
+	if tail {
+		return c.lowerRET()
+	}
+	return nil
+}
+
+// lowerRetRegs returns the registers in c.sig.RetRegs.
+func (c *arm64Ctx) lowerRetRegs() error {
+	vals := make([]string, len(c.sig.RetRegs))
+	for i, r := range c.sig.RetRegs {
+		v, err := c.loadContractReg(r)
+		if err != nil {
+			return err
+		}
+		vals[i] = v
+	}
+	if len(vals) == 1 {
+		fmt.Fprintf(c.b, "  ret %s %s\n", c.sig.Ret, vals[0])
+		return nil
+	}
+	fields, ok := parseLiteralStructFields(c.sig.Ret)
+	if !ok || len(fields) != len(vals) {
+		return fmt.Errorf("arm64: RetRegs %v do not match return type %s", c.sig.RetRegs, c.sig.Ret)
+	}
+	cur := "undef"
+	for i, v := range vals {
+		t := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = insertvalue %s %s, %s %s, %d\n", t, c.sig.Ret, cur, fields[i], v, i)
+		cur = "%" + t
+	}
+	fmt.Fprintf(c.b, "  ret %s %s\n", c.sig.Ret, cur)
+	return nil
+}
@@ -9,8 +9,6 @@ type arm64EmitBr func(target string)
 type arm64EmitCondBr func(cond string, target string, fall string) error
 
 func emitARM64Prelude(b *strings.Builder) {
-	b.WriteString("declare i64 @syscall(i64, i64, i64, i64, i64, i64, i64)\n")
-	b.WriteString("declare i32 @cliteErrno()\n")
 	b.WriteString("declare i64 @llvm.bitreverse.i64(i64)\n")
 	b.WriteString("declare i64 @llvm.ctlz.i64(i64, i1)\n")
 	b.WriteString("declare i64 @llvm.bswap.i64(i64)\n")
@@ -30,7 +28,7 @@ func emitARM64Prelude(b *strings.Builder) {
 	b.WriteString("\n")
 }
 
-func translateFuncARM64(b *strings.Builder, fn Func, sig FuncSig, resolve func(string) string, sigs map[string]FuncSig, annotateSource bool) error {
+func translateFuncARM64(b *strings.Builder, fn Func, sig FuncSig, resolve func(string) string, sigs map[string]FuncSig, cfg lowerConfig) error {
 	fmt.Fprintf(b, "define %s %s(", sig.Ret, llvmGlobal(sig.Name))
 	for i, t := range sig.Args {
 		if i > 0 {
@@ -44,7 +42,7 @@ func translateFuncARM64(b *strings.Builder, fn Func, sig FuncSig, resolve func(s
 	}
 	b.WriteString(" {\n")
 
-	c := newARM64Ctx(b, fn, sig, resolve, sigs, annotateSource)
+	c := newARM64Ctx(b, fn, sig, resolve, sigs, cfg)
 	if err := c.emitEntryAllocasAndArgInit(); err != nil {
 		return err
 	}
@@ -69,6 +67,7 @@ func (c *arm64Ctx) lowerBlocks() error {
 		return nil
 	}
 
+	liveOut := c.flagLiveness()
 	for bi := 0; bi < len(c.blocks); bi++ {
 		blk := c.blocks[bi]
 		if bi != 0 {
@@ -76,8 +75,9 @@ func (c *arm64Ctx) lowerBlocks() error {
 		}
 
 		terminated := false
-		for _, ins := range blk.instrs {
+		for ii, ins := range blk.instrs {
 			c.emitSourceComment(ins)
+			c.flagsDead = arm64FlagsAll &^ liveOut[bi][ii]
 			term, err := c.lowerInstr(bi, ins, emitBr, emitCondBr)
 			if err != nil {
 				return err
@@ -154,10 +154,16 @@ func (c *arm64Ctx) lowerInstr(bi int, ins Instr, emitBr arm64EmitBr, emitCondBr
 	if ok, term, err := c.lowerBranch(bi, op, ins, emitBr, emitCondBr); ok {
 		return term, err
 	}
+	if c.cfg.inlineAsm {
+		return false, c.lowerInlineAsm(ins)
+	}
 	return false, fmt.Errorf("arm64: unsupported instruction %s", ins.Op)
 }
 
 func (c *arm64Ctx) lowerRET() error {
+	if len(c.sig.RetRegs) > 0 {
+		return c.lowerRetRegs()
+	}
 	// Prefer classic Go asm return slots if present; many stdlib asm functions
 	// never materialize the return value in R0 and only store to ret+off(FP).
 	if len(c.fpResults) == 0 {
@@ -238,6 +244,6 @@ func (c *arm64Ctx) lowerRetZero() {
 	case I32:
 		c.b.WriteString("  ret i32 0\n")
 	default:
-		fmt.Fprintf(c.b, "  ret %s 0\n", c.sig.Ret)
+		fmt.Fprintf(c.b, "  ret %s %s\n", c.sig.Ret, llvmZeroValue(c.sig.Ret))
 	}
 }
@@ -7,11 +7,11 @@ import (
 )
 
 type armCtx struct {
-	b        *strings.Builder
-	sig      FuncSig
-	resolve  func(string) string
-	sigs     map[string]FuncSig
-	annotate bool
+	b       *strings.Builder
+	sig     FuncSig
+	resolve func(string) string
+	sigs    map[string]FuncSig
+	cfg     lowerConfig
 
 	tmp int
 
@@ -41,13 +41,13 @@ type armCtx struct {
 	fpResAddrTaken map[int]bool
 }
 
-func newARMCtx(b *strings.Builder, fn Func, sig FuncSig, resolve func(string) string, sigs map[string]FuncSig, annotate bool) *armCtx {
+func newARMCtx(b *strings.Builder, fn Func, sig FuncSig, resolve func(string) string, sigs map[string]FuncSig, cfg lowerConfig) *armCtx {
 	c := &armCtx{
 		b:              b,
 		sig:            sig,
 		resolve:        resolve,
 		sigs:           sigs,
-		annotate:       annotate,
+		cfg:            cfg,
 		blocks:         armSplitBlocks(fn),
 		usedRegs:       map[Reg]bool{},
 		regSlot:        map[Reg]string{},
@@ -67,7 +67,7 @@ func newARMCtx(b *strings.Builder, fn Func, sig FuncSig, resolve func(string) st
 }
 
 func (c *armCtx) emitSourceComment(ins Instr) {
-	if !c.annotate {
+	if !c.cfg.annotateSource {
 		return
 	}
 	emitIRSourceComment(c.b, ins.Raw)
@@ -0,0 +1,62 @@
+package plan9asm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// armKuserGetTLS is the address of __kuser_get_tls, the Linux kernel helper
+// that returns the TLS register in R0 on cores without the CP15 register.
+const armKuserGetTLS = 0xffff0fe0
+
+func (opt Options) validateGoarm(arch Arch) error {
+	switch {
+	case opt.Goarm == 0:
+		return nil
+	case arch != ArchARM:
+		return fmt.Errorf("GOARM set for arch %s", arch)
+	case opt.Goarm < 5 || opt.Goarm > 7:
+		return fmt.Errorf("invalid GOARM %d (want 5, 6 or 7)", opt.Goarm)
+	}
+	return nil
+}
+
+// armFloatFeatures returns the VFP features of opt's GOARM level: VFPv1 for
+// 5 and 6, which LLVM only models as VFPv2, and VFPv3 with the 16 double
+// registers F0-F15 for 7.
+func (opt Options) armFloatFeatures() string {
+	switch {
+	case opt.Goarm == 0 || opt.SoftFloat:
+		return ""
+	case opt.Goarm < 7:
+		return "+vfp2"
+	}
+	return "+vfp3d16"
+}
+
+// armIsTLSRead reports whether MRC reads the user TLS register TPIDRURO
+// (MRC 15, 0, Rd, C13, C0, 3), which only ARMv6K and later implement.
+func armIsTLSRead(ins Instr) bool {
+	if len(ins.Args) != 6 {
+		return false
+	}
+	want := []string{"15", "0", "", "c13", "c0", "3"}
+	for i, op := range ins.Args {
+		if i == 2 {
+			continue
+		}
+		if strings.TrimPrefix(strings.ToLower(op.String()), "$") != want[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// lowerARMTLSFallback reads the TLS register through __kuser_get_tls, as Go's
+// assembler does below GOARM=7 by rewriting the MRC into a call to
+// runtime.read_tls_fallback, which jumps to the helper.
+func (c *armCtx) lowerARMTLSFallback(dst Reg) error {
+	t := c.newTmp()
+	fmt.Fprintf(c.b, "  %%%s = call i32 inttoptr (i32 %d to ptr)()\n", t, int32(armKuserGetTLS-(1<<32)))
+	return c.storeReg(dst, "%"+t)
+}
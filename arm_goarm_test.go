@@ -0,0 +1,118 @@
+//go:build !llgo
+// +build !llgo
+
+package plan9asm
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func translateGOARM(t *testing.T, src string, goarm int, softFloat bool) string {
+	t.Helper()
+	file, err := Parse(ArchARM, src)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	ll, err := translateIRText(file, Options{
+		TargetTriple: "armv6-unknown-linux-gnueabihf",
+		Goarch:       "arm",
+		ResolveSym:   testResolveSym("example"),
+		Sigs:         map[string]FuncSig{"example.f": {Name: "example.f", Ret: Void}},
+		Goarm:        goarm,
+		SoftFloat:    softFloat,
+	})
+	if err != nil {
+		t.Fatalf("translateIRText(GOARM=%d): %v", goarm, err)
+	}
+	return ll
+}
+
+func TestTranslateARMTLSFallback(t *testing.T) {
+	src := `TEXT ·f(SB),NOSPLIT,$0-0
+	MRC 15, 0, R0, C13, C0, 3
+	MOVW R0, R1
+	MRC 15, 0, R2, C1, C0, 0
+	RET
+`
+	for _, goarm := range []int{0, 7} {
+		ll := translateGOARM(t, src, goarm, false)
+		wantIR(t, ll, `asm sideeffect "mrc p15, 0, $0, c13, c0, 3"`)
+		if strings.Contains(ll, "inttoptr (i32 -61472 to ptr)") {
+			t.Fatalf("GOARM=%d calls __kuser_get_tls:\n%s", goarm, ll)
+		}
+	}
+	for _, goarm := range []int{5, 6} {
+		ll := translateGOARM(t, src, goarm, false)
+		wantIR(t, ll, "call i32 inttoptr (i32 -61472 to ptr)()", `asm sideeffect "mrc p15, 0, $0, c1, c0, 0"`)
+		if strings.Contains(ll, "c13, c0, 3") {
+			t.Fatalf("GOARM=%d reads TPIDRURO:\n%s", goarm, ll)
+		}
+	}
+}
+
+func TestTranslateARMFloatFeatures(t *testing.T) {
+	src := "TEXT ·f(SB),NOSPLIT,$0-0\n\tMOVD F0, F1\n\tRET\n"
+	for _, tc := range []struct {
+		goarm int
+		soft  bool
+		want  string
+	}{
+		{5, true, `"target-features"="+soft-float"`},
+		{5, false, `"target-features"="+vfp2"`},
+		{6, false, `"target-features"="+vfp2"`},
+		{7, false, `"target-features"="+vfp3d16"`},
+	} {
+		wantIR(t, translateGOARM(t, src, tc.goarm, tc.soft), tc.want)
+	}
+	if ll := translateGOARM(t, src, 0, false); strings.Contains(ll, "vfp") {
+		t.Fatalf("GOARM=0 set VFP features:\n%s", ll)
+	}
+
+	file, err := Parse(ArchARM, src)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	sigs := map[string]FuncSig{"example.f": {Name: "example.f", Ret: Void}}
+	if _, err := translateIRText(file, Options{ResolveSym: testResolveSym("example"), Sigs: sigs, Goarm: 8}); err == nil || !strings.Contains(err.Error(), "invalid GOARM") {
+		t.Fatalf("GOARM=8: err = %v", err)
+	}
+	file.Arch = ArchARM64
+	if _, err := translateIRText(file, Options{ResolveSym: testResolveSym("example"), Sigs: sigs, Goarm: 7}); err == nil || !strings.Contains(err.Error(), "GOARM set") {
+		t.Fatalf("Goarm on arm64: err = %v", err)
+	}
+}
+
+func TestGoArchDefinesGOARM(t *testing.T) {
+	for _, tc := range []struct {
+		goarm string
+		want  []string
+		level int
+		soft  bool
+	}{
+		{"", []string{"GOARM_5", "GOARM_6", "GOARM_7"}, 7, false},
+		{"7", []string{"GOARM_5", "GOARM_6", "GOARM_7"}, 7, false},
+		{"7,softfloat", []string{"GOARM_5", "GOARM_6", "GOARM_7"}, 7, true},
+		{"6", []string{"GOARM_5", "GOARM_6"}, 6, false},
+		{"5", []string{"GOARM_5"}, 5, true},
+		{"5,hardfloat", []string{"GOARM_5"}, 5, false},
+	} {
+		got, err := goArchDefines(ArchARM, GoModuleOptions{GOARCH: "arm", GOARM: tc.goarm})
+		if err != nil || !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("goArchDefines(GOARM=%q) = %v, %v; want %v", tc.goarm, got, err, tc.want)
+		}
+		level, soft, err := goParseGOARM(tc.goarm)
+		if err != nil || level != tc.level || soft != tc.soft {
+			t.Errorf("goParseGOARM(%q) = %d, %v, %v; want %d, %v", tc.goarm, level, soft, err, tc.level, tc.soft)
+		}
+	}
+	for _, bad := range []string{"8", "7,fast", "v7"} {
+		if _, err := goArchDefines(ArchARM, GoModuleOptions{GOARCH: "arm", GOARM: bad}); err == nil {
+			t.Errorf("goArchDefines accepted GOARM=%q", bad)
+		}
+	}
+	if _, err := goArchDefines(ArchARM64, GoModuleOptions{GOARCH: "arm64", GOARM: "7"}); err == nil {
+		t.Errorf("goArchDefines accepted GOARM for arm64")
+	}
+}
@@ -15,7 +15,7 @@ func newARMCtxWithFuncForTest(t *testing.T, fn Func, sig FuncSig, sigs map[strin
 		sigs = map[string]FuncSig{}
 	}
 	var b strings.Builder
-	c := newARMCtx(&b, fn, sig, testResolveSym("example"), sigs, false)
+	c := newARMCtx(&b, fn, sig, testResolveSym("example"), sigs, lowerConfig{})
 	if err := c.emitEntryAllocasAndArgInit(); err != nil {
 		t.Fatalf("emitEntryAllocasAndArgInit() error = %v", err)
 	}
@@ -509,6 +509,9 @@ func (c *armCtx) lowerARMMRC(ins Instr) error {
 	if len(ins.Args) != 6 || ins.Args[2].Kind != OpReg {
 		return fmt.Errorf("arm MRC expects coproc, opc1, dst, CRn, CRm, opc2: %q", ins.Raw)
 	}
+	if c.cfg.goarm != 0 && c.cfg.goarm < 7 && armIsTLSRead(ins) {
+		return c.lowerARMTLSFallback(ins.Args[2].Reg)
+	}
 	part := func(op Operand) string {
 		s := strings.ToLower(strings.TrimSpace(op.String()))
 		return strings.TrimPrefix(s, "$")
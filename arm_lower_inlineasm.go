@@ -0,0 +1,69 @@
+package plan9asm
+
+import "fmt"
+
+// armInlineAsmOps lists the opcodes passed through by lowerInlineAsm, with
+// their UAL spelling. Each entry assembles with llvm-mc to the encoding the
+// Go assembler produces.
+var armInlineAsmOps = map[Op]inlineAsmOp{
+	"REV":   {"rev", "", inlineAsmReversed2},
+	"REV16": {"rev16", "", inlineAsmReversed2},
+	"REVSH": {"revsh", "", inlineAsmReversed2},
+	"RBIT":  {"rbit", "", inlineAsmReversed2},
+	// MULS Rm, Rn, Ra, Rd is mls rd, rn, rm, ra.
+	"MULS": {"mls", "", []int{3, 1, 0, 2}},
+}
+
+// lowerInlineAsm passes an instruction without an IR lowering through to the
+// assembler (Options.InlineAsm) when armInlineAsmOps has its spelling.
+// Opcode suffixes are rejected: the modeled flags never reach CPSR, so a
+// conditional form would test stale flags. None of the listed opcodes
+// writes the flags.
+func (c *armCtx) lowerInlineAsm(ins Instr) error {
+	base, cond, postInc, setFlags := armDecodeOp(string(ins.Op))
+	if postInc || setFlags || (cond != "" && cond != "AL") {
+		return fmt.Errorf("arm: inline asm: unsupported opcode suffix in %q", ins.Raw)
+	}
+	spec, ok := armInlineAsmOps[Op(base)]
+	if !ok {
+		return fmt.Errorf("arm: unsupported instruction %s (no verified inline asm spelling)", ins.Op)
+	}
+	args, err := spec.operands(ins, true)
+	if err != nil {
+		return fmt.Errorf("arm: %v", err)
+	}
+	a := newInlineAsmCall("~{memory},~{cc}")
+	a.text(spec.mnemonic)
+
+	for i, arg := range args {
+		if i == 0 {
+			a.text(" ")
+		} else {
+			a.text(", ")
+		}
+		switch arg.Kind {
+		case OpImm:
+			a.text(fmt.Sprintf("#%d", arg.Imm))
+		case OpReg:
+			r := arg.Reg
+			if classifyReg(ArchARM, r) != ClassReg {
+				return fmt.Errorf("arm: inline asm: unsupported register %s in %q", r, ins.Raw)
+			}
+			if err := a.reg(r, "i32", "r", "", func() (string, error) { return c.loadReg(r) }); err != nil {
+				return err
+			}
+		case OpMem:
+			addr, _, _, err := c.addrI32(arg.Mem, false)
+			if err != nil {
+				return err
+			}
+			a.text("[")
+			a.input("i32", "r", addr)
+			a.text("]")
+		default:
+			return fmt.Errorf("arm: inline asm: unsupported operand %q in %q", arg.String(), ins.Raw)
+		}
+	}
+
+	return a.emit(c.b, c.newTmp, c.storeReg)
+}
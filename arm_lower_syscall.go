@@ -29,55 +29,33 @@ func (c *armCtx) lowerSyscall(op string, ins Instr) (ok bool, terminated bool, e
 				return true, false, err
 			}
 		}
-		loadArg := func(r Reg) (string, error) {
-			if _, ok := c.regSlot[r]; !ok {
-				return "0", nil
+		s := c.cfg.syscallSite(ArchARM, c.b, c.newTmp)
+		s.Num = c.zextI32ToI64(num32)
+		for _, r := range []Reg{"R0", "R1", "R2", "R3", "R4", "R5", "R6"} {
+			a := "0"
+			if _, ok := c.regSlot[r]; ok {
+				if a, err = c.loadReg(r); err != nil {
+					return true, false, err
+				}
 			}
-			return c.loadReg(r)
+			s.Args = append(s.Args, c.zextI32ToI64(a))
 		}
-		a1, err := loadArg(Reg("R0"))
+		res, err := c.cfg.syscallStrategy().Lower(s)
 		if err != nil {
 			return true, false, err
 		}
-		a2, err := loadArg(Reg("R1"))
-		if err != nil {
-			return true, false, err
-		}
-		a3, err := loadArg(Reg("R2"))
-		if err != nil {
-			return true, false, err
-		}
-		a4, err := loadArg(Reg("R3"))
-		if err != nil {
-			return true, false, err
-		}
-		a5, err := loadArg(Reg("R4"))
-		if err != nil {
-			return true, false, err
-		}
-		a6, err := loadArg(Reg("R5"))
-		if err != nil {
-			return true, false, err
-		}
-		r := c.newTmp()
-		fmt.Fprintf(c.b, "  %%%s = call i64 @syscall(i64 %s, i64 %s, i64 %s, i64 %s, i64 %s, i64 %s, i64 %s)\n",
-			r,
-			c.zextI32ToI64(num32),
-			c.zextI32ToI64(a1),
-			c.zextI32ToI64(a2),
-			c.zextI32ToI64(a3),
-			c.zextI32ToI64(a4),
-			c.zextI32ToI64(a5),
-			c.zextI32ToI64(a6),
-		)
-		if err := c.storeReg(Reg("R0"), c.truncI64ToI32("%"+r)); err != nil {
+		if err := c.storeReg(Reg("R0"), c.truncI64ToI32(res.ret(s))); err != nil {
 			return true, false, err
 		}
 		if _, ok := c.regSlot[Reg("R1")]; ok {
-			if err := c.storeReg(Reg("R1"), "0"); err != nil {
+			if err := c.storeReg(Reg("R1"), c.truncI64ToI32(res.R2)); err != nil {
 				return true, false, err
 			}
 		}
+		if s.conv == syscallConvBSD {
+			c.storeFlag(c.flagsCSlot, res.IsErr)
+			c.flagsWritten = true
+		}
 		return true, false, nil
 	}
 	return false, false, nil
@@ -14,7 +14,7 @@ func newARMCtxForTest(t *testing.T, sig FuncSig, sigs map[string]FuncSig) (*armC
 		sigs = map[string]FuncSig{}
 	}
 	var b strings.Builder
-	c := newARMCtx(&b, Func{}, sig, testResolveSym("example"), sigs, false)
+	c := newARMCtx(&b, Func{}, sig, testResolveSym("example"), sigs, lowerConfig{})
 	if err := c.emitEntryAllocasAndArgInit(); err != nil {
 		t.Fatalf("emitEntryAllocasAndArgInit() error = %v", err)
 	}
@@ -5,7 +5,7 @@ import (
 	"strings"
 )
 
-func translateFuncARM(b *strings.Builder, fn Func, sig FuncSig, resolve func(string) string, sigs map[string]FuncSig, annotateSource bool) error {
+func translateFuncARM(b *strings.Builder, fn Func, sig FuncSig, resolve func(string) string, sigs map[string]FuncSig, cfg lowerConfig) error {
 	fmt.Fprintf(b, "define %s %s(", sig.Ret, llvmGlobal(sig.Name))
 	for i, t := range sig.Args {
 		if i > 0 {
@@ -19,7 +19,7 @@ func translateFuncARM(b *strings.Builder, fn Func, sig FuncSig, resolve func(str
 	}
 	b.WriteString(" {\n")
 
-	c := newARMCtx(b, fn, sig, resolve, sigs, annotateSource)
+	c := newARMCtx(b, fn, sig, resolve, sigs, cfg)
 	if err := c.emitEntryAllocasAndArgInit(); err != nil {
 		return err
 	}
@@ -108,6 +108,9 @@ func (c *armCtx) lowerInstr(bi int, ins Instr, emitBr armEmitBr, emitCondBr armE
 	if ok, term, err := c.lowerBranch(bi, baseOp, cond, ins, emitBr, emitCondBr); ok {
 		return term, err
 	}
+	if c.cfg.inlineAsm {
+		return false, c.lowerInlineAsm(ins)
+	}
 	return false, fmt.Errorf("arm: unsupported instruction %s", ins.Op)
 }
 
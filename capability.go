@@ -0,0 +1,337 @@
+package plan9asm
+
+import (
+	"sort"
+	"strings"
+)
+
+// OperandClass is the coarse operand shape used by the capability registry.
+//
+// Classes are intentionally broader than OperandKind: they distinguish
+// register files (GPR vs. vector vs. mask) but not register numbers, vector
+// arrangements or concrete offsets.
+type OperandClass string
+
+const (
+	ClassImm      OperandClass = "imm"      // $123
+	ClassAddr     OperandClass = "addr"     // $sym(SB)
+	ClassReg      OperandClass = "reg"      // general purpose register
+	ClassXReg     OperandClass = "xreg"     // amd64 X0..X31
+	ClassYReg     OperandClass = "yreg"     // amd64 Y0..Y31
+	ClassZReg     OperandClass = "zreg"     // amd64 Z0..Z31
+	ClassKReg     OperandClass = "kreg"     // amd64 K0..K7
+	ClassVReg     OperandClass = "vreg"     // arm64 V0..V31 (any arrangement), ppc64 V0..V31 and VS0..VS63, s390x V0..V31, mips64 W0..W31
+	ClassFReg     OperandClass = "freg"     // arm/arm64 F0..F31
+	ClassShifted  OperandClass = "shifted"  // R1<<2, R1->R2, ...
+	ClassExtended OperandClass = "extended" // R1.UXTW, ...
+	ClassMem      OperandClass = "mem"      // off(base)(index*scale)
+	ClassRegList  OperandClass = "reglist"  // (R1, R2), [R4-R7]
+	ClassVList    OperandClass = "vlist"    // [V1.B16, V2.B16]
+	ClassFP       OperandClass = "fp"       // name+off(FP) or $name+off(FP)
+	ClassSym      OperandClass = "sym"      // sym(SB)
+	ClassCond     OperandClass = "cond"     // arm64 condition operand (EQ, NE, ...)
+	ClassLabel    OperandClass = "label"    // branch label or other identifier
+)
+
+// OperandForm describes one family of operand shapes accepted by a lowering,
+// in Plan 9 (source-first) operand order. Each position lists the classes
+// accepted there; any combination of them is accepted.
+type OperandForm [][]OperandClass
+
+// String renders the form as "imm|reg, reg".
+func (f OperandForm) String() string {
+	parts := make([]string, len(f))
+	for i, pos := range f {
+		alts := make([]string, len(pos))
+		for j, c := range pos {
+			alts[j] = string(c)
+		}
+		parts[i] = strings.Join(alts, "|")
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Match reports whether args have exactly the form's arity and every operand
+// falls into one of the classes accepted at its position.
+func (f OperandForm) Match(arch Arch, args []Operand) bool {
+	if len(f) != len(args) {
+		return false
+	}
+	for i, a := range args {
+		c := classifyOperand(arch, a)
+		ok := false
+		for _, want := range f[i] {
+			if want == c {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func parseOperandForm(s string) OperandForm {
+	if s == "" {
+		return OperandForm{}
+	}
+	parts := strings.Split(s, ",")
+	f := make(OperandForm, len(parts))
+	for i, p := range parts {
+		for _, alt := range strings.Split(p, "|") {
+			f[i] = append(f[i], OperandClass(strings.TrimSpace(alt)))
+		}
+	}
+	return f
+}
+
+// OpCapability describes one opcode lowered by an architecture backend.
+type OpCapability struct {
+	Op Op
+
+	// Forms lists the operand shapes the lowering accepts. It is empty when
+	// AnyOperands is set.
+	Forms []OperandForm
+
+	// AnyOperands reports that the lowering ignores its operands (directives,
+	// hints and similar no-op instructions).
+	AnyOperands bool
+
+	// Features lists the LLVM target features the lowering requires, in the
+	// same "+feature" spelling used for the "target-features" attribute.
+	Features []string
+}
+
+// Accepts reports whether args match one of the capability's operand forms.
+func (c OpCapability) Accepts(arch Arch, args []Operand) bool {
+	if c.AnyOperands {
+		return true
+	}
+	for _, f := range c.Forms {
+		if f.Match(arch, args) {
+			return true
+		}
+	}
+	return false
+}
+
+// Capabilities returns the registry of opcodes lowered for arch, sorted by
+// opcode name. It returns nil for architectures without a backend.
+func Capabilities(arch Arch) []OpCapability {
+	table := loweredOpForms[capabilityTableArch(arch)]
+	if table == nil {
+		return nil
+	}
+	ops := make([]string, 0, len(table))
+	for op := range table {
+		ops = append(ops, op)
+	}
+	sort.Strings(ops)
+	out := make([]OpCapability, 0, len(ops))
+	for _, op := range ops {
+		out = append(out, newOpCapability(arch, op, table[op]))
+	}
+	return out
+}
+
+// LookupCapability returns the registry entry for op on arch. Opcode suffixes
+// that only select a condition or addressing variant (arm64 "MOVD.P", arm
+// "MOVW.EQ") are stripped before the lookup.
+func LookupCapability(arch Arch, op Op) (OpCapability, bool) {
+	table := loweredOpForms[capabilityTableArch(arch)]
+	if table == nil {
+		return OpCapability{}, false
+	}
+	name := capabilityOpName(arch, op)
+	forms, ok := table[name]
+	if !ok {
+		return OpCapability{}, false
+	}
+	return newOpCapability(arch, name, forms), true
+}
+
+// UnsupportedInstr is an instruction rejected by CheckFile.
+type UnsupportedInstr struct {
+	Func   string // TEXT symbol of the enclosing function
+	Instr  Instr
+	Reason string
+}
+
+// CheckFile reports the instructions in file whose opcode or operand shape
+// falls outside the capability registry of file.Arch.
+//
+// An empty result means every instruction has a lowering for its shape. It
+// does not guarantee that translation succeeds: signatures, unresolved
+// symbolic immediates and value-specific restrictions (e.g. arm64 vector
+// arrangements) are only checked by the translator itself.
+func CheckFile(file *File) []UnsupportedInstr {
+	if file == nil {
+		return nil
+	}
+	var out []UnsupportedInstr
+	for _, fn := range file.Funcs {
+		for _, ins := range fn.Instrs {
+			if ins.Op == OpTEXT || ins.Op == OpLABEL {
+				continue
+			}
+			cp, ok := LookupCapability(file.Arch, ins.Op)
+			if !ok {
+				out = append(out, UnsupportedInstr{Func: fn.Sym, Instr: ins, Reason: "unsupported opcode " + string(ins.Op)})
+				continue
+			}
+			if !cp.Accepts(file.Arch, ins.Args) {
+				out = append(out, UnsupportedInstr{Func: fn.Sym, Instr: ins, Reason: "unsupported operand form " + instrForm(file.Arch, ins).String()})
+			}
+		}
+	}
+	return out
+}
+
+func newOpCapability(arch Arch, op string, forms []string) OpCapability {
+	cp := OpCapability{Op: Op(op)}
+	for _, f := range forms {
+		if f == anyOperandsForm {
+			cp.AnyOperands = true
+			continue
+		}
+		cp.Forms = append(cp.Forms, parseOperandForm(f))
+	}
+	if feats := inferFuncTargetFeatures(arch, Func{Instrs: []Instr{{Op: Op(op)}}}); feats != "" {
+		cp.Features = strings.Split(feats, ",")
+	}
+	return cp
+}
+
+// capabilityTableArch returns the loweredOpForms key of arch. The
+// little-endian MIPS dialects accept the same forms as the big-endian ones.
+func capabilityTableArch(arch Arch) Arch {
+	switch arch {
+	case ArchMIPSLE:
+		return ArchMIPS
+	case ArchMIPS64LE:
+		return ArchMIPS64
+	}
+	return arch
+}
+
+// anyOperandsForm marks operand-agnostic opcodes in loweredOpForms.
+const anyOperandsForm = "*"
+
+func capabilityOpName(arch Arch, op Op) string {
+	name := strings.ToUpper(strings.TrimSpace(string(op)))
+	switch arch {
+	case ArchARM:
+		base, _, _, _ := armDecodeOp(name)
+		return base
+	case ArchARM64, ArchRISCV64:
+		if dot := strings.IndexByte(name, '.'); dot >= 0 {
+			return name[:dot]
+		}
+	}
+	return name
+}
+
+func instrForm(arch Arch, ins Instr) OperandForm {
+	f := make(OperandForm, len(ins.Args))
+	for i, a := range ins.Args {
+		f[i] = []OperandClass{classifyOperand(arch, a)}
+	}
+	return f
+}
+
+func classifyOperand(arch Arch, op Operand) OperandClass {
+	switch op.Kind {
+	case OpImm:
+		return ClassImm
+	case OpReg:
+		return classifyReg(arch, op.Reg)
+	case OpRegShift:
+		return ClassShifted
+	case OpRegExtend:
+		return ClassExtended
+	case OpFP, OpFPAddr:
+		return ClassFP
+	case OpMem:
+		return ClassMem
+	case OpRegList:
+		for _, r := range op.RegList {
+			if classifyReg(arch, r) == ClassVReg {
+				return ClassVList
+			}
+		}
+		return ClassRegList
+	case OpSym:
+		if strings.HasPrefix(strings.TrimSpace(op.Sym), "$") {
+			return ClassAddr
+		}
+		return ClassSym
+	case OpIdent:
+		if arch == ArchARM64 && armCondCodes[strings.ToUpper(op.Ident)] {
+			return ClassCond
+		}
+		return ClassLabel
+	}
+	return ClassLabel
+}
+
+func classifyReg(arch Arch, r Reg) OperandClass {
+	s := strings.ToUpper(string(r))
+	switch arch {
+	case ArchAMD64, Arch386:
+		if _, ok := amd64ParseXReg(r); ok {
+			return ClassXReg
+		}
+		if _, ok := amd64ParseYReg(r); ok {
+			return ClassYReg
+		}
+		if _, ok := amd64ParseZReg(r); ok {
+			return ClassZReg
+		}
+		if _, ok := amd64ParseKReg(r); ok {
+			return ClassKReg
+		}
+	case ArchARM64, ArchARM:
+		if strings.HasPrefix(s, "V") && len(s) > 1 && s[1] >= '0' && s[1] <= '9' {
+			return ClassVReg
+		}
+		if strings.HasPrefix(s, "F") && len(s) > 1 && s[1] >= '0' && s[1] <= '9' {
+			return ClassFReg
+		}
+	case ArchRISCV64:
+		if riscv64IsFReg(r) {
+			return ClassFReg
+		}
+	case ArchLOONG64:
+		if loong64IsFReg(r) {
+			return ClassFReg
+		}
+	case ArchPPC64:
+		if ppc64IsFReg(r) {
+			return ClassFReg
+		}
+		if ppc64IsVReg(r) || ppc64IsVSLow(r) {
+			return ClassVReg
+		}
+	case ArchS390X:
+		if s390xIsFReg(r) {
+			return ClassFReg
+		}
+		if s390xIsVReg(r) {
+			return ClassVReg
+		}
+	case ArchMIPS, ArchMIPSLE, ArchMIPS64, ArchMIPS64LE:
+		if mipsIsFReg(r) {
+			return ClassFReg
+		}
+		if mipsIsWName(s) {
+			return ClassVReg
+		}
+	case ArchWasm:
+		if ty, ok := wasmLocalType(r); ok && ty != "i64" {
+			return ClassFReg
+		}
+	}
+	return ClassReg
+}
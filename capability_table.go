@@ -0,0 +1,2512 @@
+// Code generated by TestCapabilityTableUpToDate -update-capabilities; DO NOT EDIT.
+
+package plan9asm
+
+// loweredOpForms maps arch -> opcode -> accepted operand forms, as found by
+// probing each backend lowering with one synthetic instruction per shape.
+// Forms use the OperandForm.String syntax; "*" accepts any operands.
+var loweredOpForms = map[Arch]map[string][]string{
+	ArchAMD64: {
+		"ADCB":            {"addr|fp|imm|mem|reg|sym, mem|reg"},
+		"ADCL":            {"addr|fp|imm|mem|reg|sym, reg"},
+		"ADCQ":            {"addr|fp|imm|mem|reg|sym, reg"},
+		"ADCXQ":           {"addr|fp|imm|mem|reg|sym, reg"},
+		"ADDB":            {"addr|fp|imm|mem|reg|sym, reg"},
+		"ADDL":            {"addr|fp|imm|mem|reg|sym, mem|reg"},
+		"ADDQ":            {"addr|fp|imm|mem|reg|sym, mem|reg"},
+		"ADDSD":           {"addr|fp|imm|mem|sym|xreg, xreg"},
+		"ADJSP":           {"*"},
+		"ADOXQ":           {"addr|fp|imm|mem|reg|sym, reg"},
+		"AESDEC":          {"addr|mem|sym|xreg, xreg"},
+		"AESDECLAST":      {"addr|mem|sym|xreg, xreg"},
+		"AESENC":          {"addr|mem|sym|xreg, xreg"},
+		"AESENCLAST":      {"addr|mem|sym|xreg, xreg"},
+		"AESIMC":          {"addr|mem|sym|xreg, xreg"},
+		"AESKEYGENASSIST": {"imm, xreg, xreg"},
+		"ANDB":            {"addr|fp|imm|mem|reg|sym, mem|reg"},
+		"ANDL":            {"addr|fp|imm|mem|reg|sym, mem|reg"},
+		"ANDNL":           {"addr|fp|imm|mem|reg|sym, addr|fp|imm|mem|reg|sym, reg"},
+		"ANDNPD":          {"addr|mem|sym|xreg, xreg"},
+		"ANDNQ":           {"addr|fp|imm|mem|reg|sym, addr|fp|imm|mem|reg|sym, reg"},
+		"ANDPD":           {"addr|mem|sym|xreg, xreg"},
+		"ANDQ":            {"addr|fp|imm|mem|reg|sym, mem|reg"},
+		"BEXTRQ":          {"addr|fp|imm|mem|reg|sym, addr|fp|imm|mem|reg|sym, reg"},
+		"BSFL":            {"reg, reg"},
+		"BSFQ":            {"reg, reg"},
+		"BSRL":            {"reg, reg"},
+		"BSRQ":            {"reg, reg"},
+		"BSWAPL":          {"reg"},
+		"BSWAPQ":          {"reg", "reg, reg"},
+		"BTQ":             {"imm, reg"},
+		"BTSQ":            {"imm|reg, mem|reg"},
+		"BYTE":            {"*"},
+		"BZHIQ":           {"addr|fp|imm|mem|reg|sym, addr|fp|imm|mem|reg|sym, reg"},
+		"CALL":            {"mem|reg|sym"},
+		"CDQ":             {"*"},
+		"CLD":             {"*"},
+		"CMPB":            {"addr|fp|imm|mem|reg|sym, addr|fp|imm|mem|reg|sym"},
+		"CMPL":            {"addr|fp|imm|mem|reg|sym, addr|fp|imm|mem|reg|sym"},
+		"CMPQ":            {"addr|fp|imm|mem|reg|sym, addr|fp|imm|mem|reg|sym"},
+		"CMPSD":           {"addr|fp|imm|mem|sym|xreg, xreg, imm"},
+		"CMPW":            {"addr|fp|imm|mem|reg|sym, addr|fp|imm|mem|reg|sym"},
+		"CMPXCHG8B":       {"mem"},
+		"CMPXCHGL":        {"addr|fp|imm|mem|reg|sym, mem"},
+		"CMPXCHGQ":        {"addr|fp|imm|mem|reg|sym, mem"},
+		"COMISD":          {"addr|fp|imm|mem|sym|xreg, addr|imm|mem|sym|xreg"},
+		"CPUID":           {"*"},
+		"CRC32B":          {"mem, reg"},
+		"CRC32L":          {"mem, reg"},
+		"CRC32Q":          {"mem, reg"},
+		"CRC32W":          {"mem, reg"},
+		"CVTSD2SL":        {"addr|fp|imm|mem|sym|xreg, reg"},
+		"CVTSL2SD":        {"addr|fp|imm|mem|reg|sym, xreg"},
+		"CVTSQ2SD":        {"addr|fp|imm|mem|reg|sym, xreg"},
+		"CVTTSD2SQ":       {"addr|fp|imm|mem|sym|xreg, reg"},
+		"DECL":            {"mem|reg"},
+		"DECQ":            {"mem|reg"},
+		"DIVL":            {"addr|fp|imm|mem|reg|sym"},
+		"DIVSD":           {"addr|fp|imm|mem|sym|xreg, xreg"},
+		"FUNCDATA":        {"*"},
+		"IMUL3L":          {"addr|fp|imm|mem|reg|sym", "addr|fp|imm|mem|reg|sym, reg", "addr|fp|imm|mem|reg|sym, addr|fp|imm|mem|reg|sym, reg"},
+		"IMUL3Q":          {"addr|fp|imm|mem|reg|sym", "addr|fp|imm|mem|reg|sym, reg", "addr|fp|imm|mem|reg|sym, addr|fp|imm|mem|reg|sym, reg"},
+		"IMULL":           {"addr|fp|imm|mem|reg|sym", "addr|fp|imm|mem|reg|sym, reg"},
+		"IMULQ":           {"addr|fp|imm|mem|reg|sym", "addr|fp|imm|mem|reg|sym, reg"},
+		"INCL":            {"mem|reg"},
+		"INCQ":            {"mem|reg"},
+		"INT":             {"*"},
+		"JA":              {"addr|label|sym"},
+		"JAE":             {"addr|label|sym"},
+		"JB":              {"addr|label|sym"},
+		"JBE":             {"addr|label|sym"},
+		"JC":              {"addr|label|sym"},
+		"JCC":             {"addr|label|sym"},
+		"JE":              {"addr|label|sym"},
+		"JEQ":             {"addr|label|sym"},
+		"JG":              {"addr|label|sym"},
+		"JGE":             {"addr|label|sym"},
+		"JGT":             {"addr|label|sym"},
+		"JHI":             {"addr|label|sym"},
+		"JHS":             {"addr|label|sym"},
+		"JL":              {"addr|label|sym"},
+		"JLE":             {"addr|label|sym"},
+		"JLO":             {"addr|label|sym"},
+		"JLS":             {"addr|label|sym"},
+		"JLT":             {"addr|label|sym"},
+		"JMP":             {"addr|label|mem|reg|sym"},
+		"JNA":             {"addr|label|sym"},
+		"JNC":             {"addr|label|sym"},
+		"JNE":             {"addr|label|sym"},
+		"JNS":             {"addr|label|sym"},
+		"JNZ":             {"addr|label|sym"},
+		"JS":              {"addr|label|sym"},
+		"JZ":              {"addr|label|sym"},
+		"KMOVB":           {"kreg|mem|reg, kreg|mem|reg"},
+		"KMOVQ":           {"kreg|mem|reg, kreg|mem|reg"},
+		"KMOVW":           {"kreg|mem|reg, kreg|mem|reg"},
+		"KXORQ":           {"kreg, kreg, kreg"},
+		"LAHF":            {"*"},
+		"LEAL":            {"addr|fp|mem|sym, reg"},
+		"LEAQ":            {"addr|fp|mem|sym, reg"},
+		"LFENCE":          {"*"},
+		"LOCK":            {"*"},
+		"MAXSD":           {"addr|fp|imm|mem|sym|xreg, xreg"},
+		"MFENCE":          {"*"},
+		"MINSD":           {"addr|fp|imm|mem|sym|xreg, xreg"},
+		"MOVAPD":          {"addr|mem|sym|xreg, xreg"},
+		"MOVAPS":          {"addr|mem|sym|xreg, xreg", "xreg, addr|mem|sym|xreg"},
+		"MOVB":            {"addr|fp|imm|mem|reg|sym, addr|fp|mem|reg|sym"},
+		"MOVBLZX":         {"addr|fp|imm|mem|reg|sym, addr|fp|mem|reg|sym"},
+		"MOVBQZX":         {"addr|fp|imm|mem|reg|sym, addr|fp|mem|reg|sym"},
+		"MOVD":            {"addr|fp|imm|mem|reg|sym, addr|fp|mem|reg|sym", "reg, addr|fp|mem|reg|sym|xreg"},
+		"MOVL":            {"addr|fp|imm|mem|reg|sym, addr|imm|mem|reg|sym|xreg", "imm|reg|xreg, fp|mem|reg", "addr|fp|imm|kreg|label|mem|reg|sym|zreg, imm"},
+		"MOVLQSX":         {"addr|fp|imm|mem|reg|sym, reg"},
+		"MOVLQZX":         {"addr|fp|imm|mem|reg|sym, addr|imm|mem|reg|sym", "imm|reg, addr|fp|imm|mem|reg|sym", "addr|fp|imm|kreg|label|mem|reg|sym|zreg, imm"},
+		"MOVO":            {"addr|mem|sym|xreg, xreg", "xreg, addr|mem|sym|xreg"},
+		"MOVOA":           {"addr|mem|sym|xreg, xreg", "xreg, addr|mem|sym|xreg"},
+		"MOVOU":           {"addr|mem|sym|xreg, xreg", "xreg, addr|mem|sym|xreg"},
+		"MOVQ":            {"addr|fp|imm|mem|reg|sym, addr|fp|mem|reg|sym|xreg", "addr|fp|imm|mem|reg|sym|xreg, fp|mem|reg"},
+		"MOVSB":           {"*"},
+		"MOVSD":           {"addr|fp|imm|mem|sym|xreg, addr|fp|mem|sym|xreg"},
+		"MOVSL":           {"*"},
+		"MOVSQ":           {"*"},
+		"MOVUPS":          {"addr|mem|sym|xreg, xreg", "xreg, addr|mem|sym|xreg"},
+		"MOVW":            {"addr|fp|imm|mem|reg|sym, addr|fp|mem|reg|sym"},
+		"MOVWLZX":         {"addr|fp|imm|mem|reg|sym, addr|fp|mem|reg|sym"},
+		"MOVWQSX":         {"addr|fp|imm|mem|reg|sym, reg"},
+		"MOVWQZX":         {"addr|fp|imm|mem|reg|sym, addr|fp|mem|reg|sym"},
+		"MULL":            {"addr|fp|imm|mem|reg|sym"},
+		"MULQ":            {"addr|fp|imm|mem|reg|sym"},
+		"MULSD":           {"addr|fp|imm|mem|sym|xreg, xreg"},
+		"MULXQ":           {"addr|fp|imm|mem|reg|sym, reg, reg"},
+		"NEGL":            {"mem|reg"},
+		"NEGQ":            {"reg"},
+		"NOP":             {"*"},
+		"NOTL":            {"reg"},
+		"NOTQ":            {"mem|reg"},
+		"ORB":             {"addr|fp|imm|mem|reg|sym, mem|reg"},
+		"ORL":             {"addr|fp|imm|mem|reg|sym, mem|reg"},
+		"ORPD":            {"addr|mem|sym|xreg, xreg"},
+		"ORQ":             {"addr|fp|imm|mem|reg|sym, mem|reg"},
+		"PADDD":           {"addr|mem|sym|xreg, xreg"},
+		"PADDL":           {"addr|mem|sym|xreg, xreg"},
+		"PADDQ":           {"addr|mem|sym|xreg, xreg"},
+		"PALIGNR":         {"imm, xreg, xreg"},
+		"PAND":            {"addr|mem|sym|xreg, xreg"},
+		"PANDN":           {"addr|mem|sym|xreg, xreg"},
+		"PAUSE":           {"*"},
+		"PBLENDW":         {"imm, addr|mem|sym|xreg, xreg"},
+		"PCALIGN":         {"*"},
+		"PCDATA":          {"*"},
+		"PCLMULQDQ":       {"imm, xreg, xreg"},
+		"PCMPEQB":         {"xreg, xreg"},
+		"PCMPEQL":         {"xreg, xreg"},
+		"PINSRB":          {"imm, addr|fp|imm|mem|reg|sym, xreg"},
+		"PINSRD":          {"imm, addr|fp|imm|mem|reg|sym, xreg"},
+		"PINSRQ":          {"imm, addr|fp|imm|mem|reg|sym, xreg"},
+		"PINSRW":          {"imm, addr|fp|imm|mem|reg|sym, xreg"},
+		"PMOVMSKB":        {"xreg, reg"},
+		"POPCNTL":         {"reg, reg"},
+		"POPCNTQ":         {"reg, reg"},
+		"POPFQ":           {"*"},
+		"POPQ":            {"reg"},
+		"PREFETCHNTA":     {"*"},
+		"PSHUFB":          {"addr|mem|sym|xreg, xreg"},
+		"PSHUFD":          {"imm, xreg, xreg"},
+		"PSHUFHW":         {"imm, xreg, xreg"},
+		"PSHUFL":          {"imm, xreg, xreg"},
+		"PSLLDQ":          {"imm, xreg"},
+		"PSLLL":           {"imm, xreg"},
+		"PSRAL":           {"imm, xreg"},
+		"PSRLDQ":          {"imm, xreg"},
+		"PSRLL":           {"imm, xreg"},
+		"PSRLQ":           {"imm, xreg"},
+		"PSUBL":           {"addr|mem|sym|xreg, xreg"},
+		"PUNPCKLBW":       {"xreg, xreg"},
+		"PUSHFQ":          {"*"},
+		"PUSHQ":           {"addr|fp|imm|mem|reg|sym"},
+		"PXOR":            {"addr|mem|sym|xreg, xreg"},
+		"RCRQ":            {"imm, reg"},
+		"RDTSC":           {"*"},
+		"RDTSCP":          {"*"},
+		"REP":             {"*"},
+		"RET":             {"*"},
+		"ROLL":            {"imm|reg, reg"},
+		"ROLQ":            {"imm|reg, reg"},
+		"RORL":            {"imm|reg, reg"},
+		"RORQ":            {"imm|reg, reg"},
+		"RORXL":           {"imm, reg, reg"},
+		"RORXQ":           {"imm, reg, reg"},
+		"SAHF":            {"*"},
+		"SALL":            {"imm|reg, reg", "imm|reg, reg, reg"},
+		"SALQ":            {"imm|reg, reg", "imm|reg, reg, reg"},
+		"SARL":            {"imm|reg, reg", "imm|reg, reg, reg"},
+		"SARQ":            {"imm|reg, reg", "imm|reg, reg, reg"},
+		"SBBL":            {"addr|fp|imm|mem|reg|sym, reg"},
+		"SBBQ":            {"addr|fp|imm|mem|reg|sym, reg"},
+		"SFENCE":          {"*"},
+		"SHA1MSG1":        {"addr|mem|sym|xreg, xreg"},
+		"SHA1MSG2":        {"addr|mem|sym|xreg, xreg"},
+		"SHA1NEXTE":       {"addr|mem|sym|xreg, xreg"},
+		"SHA1RNDS4":       {"imm, addr|mem|sym|xreg, xreg"},
+		"SHA256MSG1":      {"addr|mem|sym|xreg, xreg"},
+		"SHA256MSG2":      {"addr|mem|sym|xreg, xreg"},
+		"SHA256RNDS2":     {"addr|mem|sym|xreg, addr|mem|sym|xreg, xreg"},
+		"SHLB":            {"imm|reg, reg"},
+		"SHLL":            {"imm|reg, reg", "imm|reg, reg, reg"},
+		"SHLQ":            {"imm|reg, reg", "imm|reg, reg, reg"},
+		"SHLXQ":           {"imm|reg, reg, reg"},
+		"SHRL":            {"imm|reg, reg", "imm|reg, reg, reg"},
+		"SHRQ":            {"imm|reg, reg", "imm|reg, reg, reg"},
+		"SHRXQ":           {"imm|reg, reg, reg"},
+		"SHUFPS":          {"imm, addr|mem|sym|xreg, xreg"},
+		"SQRTSD":          {"addr|fp|imm|mem|sym|xreg, xreg"},
+		"STD":             {"*"},
+		"STOSL":           {"*"},
+		"STOSQ":           {"*"},
+		"SUBL":            {"addr|fp|imm|mem|reg|sym, mem|reg"},
+		"SUBQ":            {"addr|fp|imm|mem|reg|sym, mem|reg"},
+		"SUBSD":           {"addr|fp|imm|mem|sym|xreg, xreg"},
+		"SYSCALL":         {""},
+		"TESTB":           {"addr|fp|imm|mem|reg|sym, addr|fp|imm|mem|reg|sym"},
+		"TESTL":           {"addr|fp|imm|mem|reg|sym, addr|fp|imm|mem|reg|sym"},
+		"TESTQ":           {"addr|fp|imm|mem|reg|sym, addr|fp|imm|mem|reg|sym"},
+		"TESTW":           {"addr|fp|imm|mem|reg|sym, addr|fp|imm|mem|reg|sym"},
+		"TZCNTQ":          {"reg, reg"},
+		"UCOMISD":         {"addr|fp|imm|mem|sym|xreg, addr|imm|mem|sym|xreg"},
+		"UNDEF":           {"*"},
+		"VADDSD":          {"addr|fp|imm|mem|sym|xreg, addr|fp|imm|mem|sym|xreg, xreg"},
+		"VFMADD213SD":     {"addr|fp|imm|mem|sym|xreg, addr|fp|imm|mem|sym|xreg, xreg"},
+		"VFNMADD231SD":    {"addr|fp|imm|mem|sym|xreg, addr|fp|imm|mem|sym|xreg, xreg"},
+		"VGF2P8AFFINEQB":  {"imm, addr|fp|imm|kreg|label|mem|reg|sym|xreg|yreg|zreg, addr|mem|sym|zreg, zreg"},
+		"VMOVAPD":         {"addr|mem|sym|xreg, xreg", "addr|mem|sym|yreg, yreg", "addr|mem|sym|zreg, zreg", "xreg|yreg|zreg, addr|mem|sym"},
+		"VMOVAPS":         {"addr|mem|sym|xreg, xreg", "addr|mem|sym|yreg, yreg", "addr|mem|sym|zreg, zreg", "xreg|yreg|zreg, addr|mem|sym"},
+		"VMOVDQA":         {"addr|mem|sym|xreg, xreg", "addr|mem|sym|yreg, yreg", "xreg|yreg, addr|mem|sym"},
+		"VMOVDQA64":       {"addr|mem|sym|zreg, zreg", "zreg, addr|mem|sym|zreg"},
+		"VMOVDQU":         {"addr|mem|sym|xreg, xreg", "addr|mem|sym|yreg, yreg", "xreg|yreg, addr|mem|sym"},
+		"VMOVDQU64":       {"addr|mem|sym|zreg, zreg", "zreg, addr|mem|sym|zreg"},
+		"VMOVNTDQ":        {"yreg, addr|mem|sym"},
+		"VPADDD":          {"addr|mem|sym|xreg, addr|mem|sym|xreg, xreg", "addr|mem|sym|yreg, addr|mem|sym|yreg, yreg"},
+		"VPADDQ":          {"addr|mem|sym|xreg, addr|mem|sym|xreg, xreg", "addr|mem|sym|yreg, addr|mem|sym|yreg, yreg"},
+		"VPALIGNR":        {"imm, yreg, yreg, yreg"},
+		"VPAND":           {"addr|mem|sym|yreg, addr|mem|sym|yreg, yreg"},
+		"VPANDQ":          {"addr|mem|sym|yreg, addr|mem|sym|yreg, yreg", "addr|mem|sym|zreg, addr|mem|sym|zreg, zreg"},
+		"VPBLENDD":        {"imm, addr|mem|sym|yreg, addr|mem|sym|yreg, yreg"},
+		"VPBROADCASTB":    {"xreg, yreg"},
+		"VPCLMULQDQ":      {"imm, zreg, zreg, zreg"},
+		"VPCMPEQB":        {"yreg, yreg, yreg"},
+		"VPCOMPRESSQ":     {"addr|mem|sym|zreg, kreg, zreg"},
+		"VPERM2F128":      {"imm, addr|mem|sym|yreg, addr|mem|sym|yreg, yreg"},
+		"VPERM2I128":      {"imm, addr|mem|sym|yreg, addr|mem|sym|yreg, yreg"},
+		"VPERMB":          {"addr|fp|imm|kreg|label|mem|reg|sym|xreg|yreg|zreg, addr|mem|sym|zreg, zreg"},
+		"VPERMI2B":        {"addr|fp|imm|kreg|label|mem|reg|sym|xreg|yreg|zreg, addr|mem|sym|zreg, zreg"},
+		"VPMOVMSKB":       {"yreg, reg"},
+		"VPOPCNTB":        {"addr|mem|sym|zreg, zreg"},
+		"VPOR":            {"addr|mem|sym|xreg, addr|mem|sym|xreg, xreg", "addr|mem|sym|yreg, addr|mem|sym|yreg, yreg"},
+		"VPORQ":           {"addr|mem|sym|xreg, addr|mem|sym|xreg, xreg", "addr|mem|sym|yreg, addr|mem|sym|yreg, yreg", "addr|mem|sym|zreg, addr|mem|sym|zreg, zreg"},
+		"VPSHUFB":         {"addr|mem|sym|xreg, addr|mem|sym|xreg, xreg", "addr|mem|sym|yreg, addr|mem|sym|yreg, yreg"},
+		"VPSHUFD":         {"imm, yreg, yreg"},
+		"VPSLLD":          {"imm, yreg, yreg"},
+		"VPSLLDQ":         {"imm, yreg, yreg"},
+		"VPSLLQ":          {"imm, yreg, yreg"},
+		"VPSRLD":          {"imm, yreg, yreg"},
+		"VPSRLDQ":         {"imm, yreg, yreg"},
+		"VPSRLQ":          {"imm, yreg, yreg"},
+		"VPTEST":          {"yreg, yreg"},
+		"VPXOR":           {"addr|mem|sym|xreg, addr|mem|sym|xreg, xreg", "addr|mem|sym|yreg, addr|mem|sym|yreg, yreg"},
+		"VPXORQ":          {"addr|mem|sym|xreg, addr|mem|sym|xreg, xreg", "addr|mem|sym|yreg, addr|mem|sym|yreg, yreg", "addr|mem|sym|zreg, addr|mem|sym|zreg, zreg"},
+		"VZEROALL":        {"*"},
+		"VZEROUPPER":      {"*"},
+		"XADDL":           {"reg, mem"},
+		"XADDQ":           {"reg, mem"},
+		"XCHGB":           {"reg, addr|mem|reg|sym"},
+		"XCHGL":           {"reg, addr|mem|reg|sym"},
+		"XCHGQ":           {"reg, addr|mem|reg|sym"},
+		"XGETBV":          {"*"},
+		"XORB":            {"addr|fp|imm|mem|reg|sym, reg"},
+		"XORL":            {"addr|fp|imm|mem|reg|sym, mem|reg"},
+		"XORPS":           {"addr|mem|sym|xreg, xreg"},
+		"XORQ":            {"addr|fp|imm|mem|reg|sym, mem|reg"},
+	},
+	Arch386: {
+		"ADCB":            {"addr|fp|imm|mem|reg|sym, mem|reg"},
+		"ADCL":            {"addr|fp|imm|mem|reg|sym, reg"},
+		"ADDB":            {"addr|fp|imm|mem|reg|sym, reg"},
+		"ADDL":            {"addr|fp|imm|mem|reg|sym, mem|reg"},
+		"ADDSD":           {"addr|imm|mem|sym|xreg, xreg"},
+		"ADJSP":           {"*"},
+		"AESDEC":          {"addr|mem|sym|xreg, xreg"},
+		"AESDECLAST":      {"addr|mem|sym|xreg, xreg"},
+		"AESENC":          {"addr|mem|sym|xreg, xreg"},
+		"AESENCLAST":      {"addr|mem|sym|xreg, xreg"},
+		"AESIMC":          {"addr|mem|sym|xreg, xreg"},
+		"AESKEYGENASSIST": {"imm, xreg, xreg"},
+		"ANDB":            {"addr|fp|imm|mem|reg|sym, mem|reg"},
+		"ANDL":            {"addr|fp|imm|mem|reg|sym, mem|reg"},
+		"ANDNL":           {"addr|fp|imm|mem|reg|sym, addr|fp|imm|mem|reg|sym, reg"},
+		"ANDNPD":          {"addr|mem|sym|xreg, xreg"},
+		"ANDPD":           {"addr|mem|sym|xreg, xreg"},
+		"BSFL":            {"reg, reg"},
+		"BSRL":            {"reg, reg"},
+		"BSWAPL":          {"reg"},
+		"BYTE":            {"*"},
+		"CALL":            {"mem|reg|sym"},
+		"CDQ":             {"*"},
+		"CLD":             {"*"},
+		"CMPB":            {"addr|fp|imm|mem|reg|sym, addr|fp|imm|mem|reg|sym"},
+		"CMPL":            {"addr|fp|imm|mem|reg|sym, addr|fp|imm|mem|reg|sym"},
+		"CMPSD":           {"addr|imm|mem|sym|xreg, xreg, imm"},
+		"CMPW":            {"addr|fp|imm|mem|reg|sym, addr|fp|imm|mem|reg|sym"},
+		"CMPXCHG8B":       {"mem"},
+		"CMPXCHGL":        {"addr|fp|imm|mem|reg|sym, mem"},
+		"COMISD":          {"addr|imm|mem|sym|xreg, addr|imm|mem|sym|xreg"},
+		"CPUID":           {"*"},
+		"CRC32B":          {"mem, reg"},
+		"CRC32L":          {"mem, reg"},
+		"CRC32W":          {"mem, reg"},
+		"CVTSD2SL":        {"addr|imm|mem|sym|xreg, reg"},
+		"CVTSL2SD":        {"addr|fp|imm|mem|reg|sym, xreg"},
+		"DECL":            {"mem|reg"},
+		"DIVL":            {"addr|fp|imm|mem|reg|sym"},
+		"DIVSD":           {"addr|imm|mem|sym|xreg, xreg"},
+		"EMMS":            {"*"},
+		"FUNCDATA":        {"*"},
+		"IMUL3L":          {"addr|fp|imm|mem|reg|sym", "addr|fp|imm|mem|reg|sym, reg", "addr|fp|imm|mem|reg|sym, addr|fp|imm|mem|reg|sym, reg"},
+		"IMULL":           {"addr|fp|imm|mem|reg|sym", "addr|fp|imm|mem|reg|sym, reg"},
+		"INCL":            {"mem|reg"},
+		"INT":             {"*"},
+		"JA":              {"addr|label|sym"},
+		"JAE":             {"addr|label|sym"},
+		"JB":              {"addr|label|sym"},
+		"JBE":             {"addr|label|sym"},
+		"JC":              {"addr|label|sym"},
+		"JCC":             {"addr|label|sym"},
+		"JE":              {"addr|label|sym"},
+		"JEQ":             {"addr|label|sym"},
+		"JG":              {"addr|label|sym"},
+		"JGE":             {"addr|label|sym"},
+		"JGT":             {"addr|label|sym"},
+		"JHI":             {"addr|label|sym"},
+		"JHS":             {"addr|label|sym"},
+		"JL":              {"addr|label|sym"},
+		"JLE":             {"addr|label|sym"},
+		"JLO":             {"addr|label|sym"},
+		"JLS":             {"addr|label|sym"},
+		"JLT":             {"addr|label|sym"},
+		"JMP":             {"addr|label|mem|reg|sym"},
+		"JNA":             {"addr|label|sym"},
+		"JNC":             {"addr|label|sym"},
+		"JNE":             {"addr|label|sym"},
+		"JNS":             {"addr|label|sym"},
+		"JNZ":             {"addr|label|sym"},
+		"JS":              {"addr|label|sym"},
+		"JZ":              {"addr|label|sym"},
+		"KMOVB":           {"kreg|mem|reg, kreg|mem|reg"},
+		"KMOVQ":           {"kreg|mem, kreg", "kreg, kreg|mem"},
+		"KMOVW":           {"kreg|mem|reg, kreg|mem|reg"},
+		"KXORQ":           {"kreg, kreg, kreg"},
+		"LAHF":            {"*"},
+		"LEAL":            {"addr|fp|mem|sym, reg"},
+		"LFENCE":          {"*"},
+		"LOCK":            {"*"},
+		"MAXSD":           {"addr|imm|mem|sym|xreg, xreg"},
+		"MFENCE":          {"*"},
+		"MINSD":           {"addr|imm|mem|sym|xreg, xreg"},
+		"MOVAPD":          {"addr|mem|sym|xreg, xreg"},
+		"MOVAPS":          {"addr|mem|sym|xreg, xreg", "xreg, addr|mem|sym|xreg"},
+		"MOVB":            {"addr|fp|imm|mem|reg|sym, addr|fp|mem|reg|sym"},
+		"MOVBLZX":         {"addr|fp|imm|mem|reg|sym, addr|fp|mem|reg|sym"},
+		"MOVD":            {"addr|fp|imm|mem|reg|sym, addr|fp|mem|reg|sym", "reg, addr|fp|mem|reg|sym|xreg"},
+		"MOVL":            {"addr|fp|imm|mem|reg|sym, addr|imm|mem|reg|sym|xreg", "imm|reg|xreg, fp|mem|reg", "addr|fp|imm|kreg|label|mem|reg|sym|zreg, imm"},
+		"MOVO":            {"addr|mem|sym|xreg, xreg", "xreg, addr|mem|sym|xreg"},
+		"MOVOA":           {"addr|mem|sym|xreg, xreg", "xreg, addr|mem|sym|xreg"},
+		"MOVOU":           {"addr|mem|sym|xreg, xreg", "xreg, addr|mem|sym|xreg"},
+		"MOVQ":            {"addr|fp|imm|mem|sym, xreg", "xreg, fp|mem"},
+		"MOVSB":           {"*"},
+		"MOVSD":           {"addr|imm|mem|sym|xreg, addr|mem|sym|xreg"},
+		"MOVSL":           {"*"},
+		"MOVUPS":          {"addr|mem|sym|xreg, xreg", "xreg, addr|mem|sym|xreg"},
+		"MOVW":            {"addr|fp|imm|mem|reg|sym, addr|fp|mem|reg|sym"},
+		"MOVWLZX":         {"addr|fp|imm|mem|reg|sym, addr|fp|mem|reg|sym"},
+		"MULL":            {"addr|fp|imm|mem|reg|sym"},
+		"MULSD":           {"addr|imm|mem|sym|xreg, xreg"},
+		"NEGL":            {"mem|reg"},
+		"NOP":             {"*"},
+		"NOTL":            {"reg"},
+		"ORB":             {"addr|fp|imm|mem|reg|sym, mem|reg"},
+		"ORL":             {"addr|fp|imm|mem|reg|sym, mem|reg"},
+		"ORPD":            {"addr|mem|sym|xreg, xreg"},
+		"PADDD":           {"addr|mem|sym|xreg, xreg"},
+		"PADDL":           {"addr|mem|sym|xreg, xreg"},
+		"PADDQ":           {"addr|mem|sym|xreg, xreg"},
+		"PALIGNR":         {"imm, xreg, xreg"},
+		"PAND":            {"addr|mem|sym|xreg, xreg"},
+		"PANDN":           {"addr|mem|sym|xreg, xreg"},
+		"PAUSE":           {"*"},
+		"PBLENDW":         {"imm, addr|mem|sym|xreg, xreg"},
+		"PCALIGN":         {"*"},
+		"PCDATA":          {"*"},
+		"PCLMULQDQ":       {"imm, xreg, xreg"},
+		"PCMPEQB":         {"xreg, xreg"},
+		"PCMPEQL":         {"xreg, xreg"},
+		"PINSRB":          {"imm, addr|fp|imm|mem|reg|sym, xreg"},
+		"PINSRD":          {"imm, addr|fp|imm|mem|reg|sym, xreg"},
+		"PINSRW":          {"imm, addr|fp|imm|mem|reg|sym, xreg"},
+		"PMOVMSKB":        {"xreg, reg"},
+		"POPAL":           {"*"},
+		"POPCNTL":         {"reg, reg"},
+		"POPFL":           {"*"},
+		"POPL":            {"reg"},
+		"PREFETCHNTA":     {"*"},
+		"PSHUFB":          {"addr|mem|sym|xreg, xreg"},
+		"PSHUFD":          {"imm, xreg, xreg"},
+		"PSHUFHW":         {"imm, xreg, xreg"},
+		"PSHUFL":          {"imm, xreg, xreg"},
+		"PSLLDQ":          {"imm, xreg"},
+		"PSLLL":           {"imm, xreg"},
+		"PSRAL":           {"imm, xreg"},
+		"PSRLDQ":          {"imm, xreg"},
+		"PSRLL":           {"imm, xreg"},
+		"PSRLQ":           {"imm, xreg"},
+		"PSUBL":           {"addr|mem|sym|xreg, xreg"},
+		"PUNPCKLBW":       {"xreg, xreg"},
+		"PUSHAL":          {"*"},
+		"PUSHFL":          {"*"},
+		"PUSHL":           {"addr|fp|imm|mem|reg|sym"},
+		"PXOR":            {"addr|mem|sym|xreg, xreg"},
+		"RDTSC":           {"*"},
+		"RDTSCP":          {"*"},
+		"REP":             {"*"},
+		"REPN":            {"*"},
+		"RET":             {"*"},
+		"ROLL":            {"imm|reg, reg"},
+		"RORL":            {"imm|reg, reg"},
+		"RORXL":           {"imm, reg, reg"},
+		"SAHF":            {"*"},
+		"SALL":            {"imm|reg, reg", "imm|reg, reg, reg"},
+		"SARL":            {"imm|reg, reg", "imm|reg, reg, reg"},
+		"SBBL":            {"addr|fp|imm|mem|reg|sym, reg"},
+		"SFENCE":          {"*"},
+		"SHA1MSG1":        {"addr|mem|sym|xreg, xreg"},
+		"SHA1MSG2":        {"addr|mem|sym|xreg, xreg"},
+		"SHA1NEXTE":       {"addr|mem|sym|xreg, xreg"},
+		"SHA1RNDS4":       {"imm, addr|mem|sym|xreg, xreg"},
+		"SHA256MSG1":      {"addr|mem|sym|xreg, xreg"},
+		"SHA256MSG2":      {"addr|mem|sym|xreg, xreg"},
+		"SHA256RNDS2":     {"addr|mem|sym|xreg, addr|mem|sym|xreg, xreg"},
+		"SHLB":            {"imm|reg, reg"},
+		"SHLL":            {"imm|reg, reg", "imm|reg, reg, reg"},
+		"SHRL":            {"imm|reg, reg", "imm|reg, reg, reg"},
+		"SHUFPS":          {"imm, addr|mem|sym|xreg, xreg"},
+		"SQRTSD":          {"addr|imm|mem|sym|xreg, xreg"},
+		"STD":             {"*"},
+		"STOSL":           {"*"},
+		"SUBL":            {"addr|fp|imm|mem|reg|sym, mem|reg"},
+		"SUBSD":           {"addr|imm|mem|sym|xreg, xreg"},
+		"TESTB":           {"addr|fp|imm|mem|reg|sym, addr|fp|imm|mem|reg|sym"},
+		"TESTL":           {"addr|fp|imm|mem|reg|sym, addr|fp|imm|mem|reg|sym"},
+		"TESTW":           {"addr|fp|imm|mem|reg|sym, addr|fp|imm|mem|reg|sym"},
+		"UCOMISD":         {"addr|imm|mem|sym|xreg, addr|imm|mem|sym|xreg"},
+		"UNDEF":           {"*"},
+		"VADDSD":          {"addr|imm|mem|sym|xreg, addr|imm|mem|sym|xreg, xreg"},
+		"VFMADD213SD":     {"addr|imm|mem|sym|xreg, addr|imm|mem|sym|xreg, xreg"},
+		"VFNMADD231SD":    {"addr|imm|mem|sym|xreg, addr|imm|mem|sym|xreg, xreg"},
+		"VGF2P8AFFINEQB":  {"imm, addr|fp|imm|kreg|label|mem|reg|sym|xreg|yreg|zreg, addr|mem|sym|zreg, zreg"},
+		"VMOVAPD":         {"addr|mem|sym|xreg, xreg", "addr|mem|sym|yreg, yreg", "addr|mem|sym|zreg, zreg", "xreg|yreg|zreg, addr|mem|sym"},
+		"VMOVAPS":         {"addr|mem|sym|xreg, xreg", "addr|mem|sym|yreg, yreg", "addr|mem|sym|zreg, zreg", "xreg|yreg|zreg, addr|mem|sym"},
+		"VMOVDQA":         {"addr|mem|sym|xreg, xreg", "addr|mem|sym|yreg, yreg", "xreg|yreg, addr|mem|sym"},
+		"VMOVDQA64":       {"addr|mem|sym|zreg, zreg", "zreg, addr|mem|sym|zreg"},
+		"VMOVDQU":         {"addr|mem|sym|xreg, xreg", "addr|mem|sym|yreg, yreg", "xreg|yreg, addr|mem|sym"},
+		"VMOVDQU64":       {"addr|mem|sym|zreg, zreg", "zreg, addr|mem|sym|zreg"},
+		"VMOVNTDQ":        {"yreg, addr|mem|sym"},
+		"VPADDD":          {"addr|mem|sym|xreg, addr|mem|sym|xreg, xreg", "addr|mem|sym|yreg, addr|mem|sym|yreg, yreg"},
+		"VPADDQ":          {"addr|mem|sym|xreg, addr|mem|sym|xreg, xreg", "addr|mem|sym|yreg, addr|mem|sym|yreg, yreg"},
+		"VPALIGNR":        {"imm, yreg, yreg, yreg"},
+		"VPAND":           {"addr|mem|sym|yreg, addr|mem|sym|yreg, yreg"},
+		"VPANDQ":          {"addr|mem|sym|yreg, addr|mem|sym|yreg, yreg", "addr|mem|sym|zreg, addr|mem|sym|zreg, zreg"},
+		"VPBLENDD":        {"imm, addr|mem|sym|yreg, addr|mem|sym|yreg, yreg"},
+		"VPBROADCASTB":    {"xreg, yreg"},
+		"VPCLMULQDQ":      {"imm, zreg, zreg, zreg"},
+		"VPCMPEQB":        {"yreg, yreg, yreg"},
+		"VPCOMPRESSQ":     {"addr|mem|sym|zreg, kreg, zreg"},
+		"VPERM2F128":      {"imm, addr|mem|sym|yreg, addr|mem|sym|yreg, yreg"},
+		"VPERM2I128":      {"imm, addr|mem|sym|yreg, addr|mem|sym|yreg, yreg"},
+		"VPERMB":          {"addr|fp|imm|kreg|label|mem|reg|sym|xreg|yreg|zreg, addr|mem|sym|zreg, zreg"},
+		"VPERMI2B":        {"addr|fp|imm|kreg|label|mem|reg|sym|xreg|yreg|zreg, addr|mem|sym|zreg, zreg"},
+		"VPMOVMSKB":       {"yreg, reg"},
+		"VPOPCNTB":        {"addr|mem|sym|zreg, zreg"},
+		"VPOR":            {"addr|mem|sym|xreg, addr|mem|sym|xreg, xreg", "addr|mem|sym|yreg, addr|mem|sym|yreg, yreg"},
+		"VPORQ":           {"addr|mem|sym|xreg, addr|mem|sym|xreg, xreg", "addr|mem|sym|yreg, addr|mem|sym|yreg, yreg", "addr|mem|sym|zreg, addr|mem|sym|zreg, zreg"},
+		"VPSHUFB":         {"addr|mem|sym|xreg, addr|mem|sym|xreg, xreg", "addr|mem|sym|yreg, addr|mem|sym|yreg, yreg"},
+		"VPSHUFD":         {"imm, yreg, yreg"},
+		"VPSLLD":          {"imm, yreg, yreg"},
+		"VPSLLDQ":         {"imm, yreg, yreg"},
+		"VPSLLQ":          {"imm, yreg, yreg"},
+		"VPSRLD":          {"imm, yreg, yreg"},
+		"VPSRLDQ":         {"imm, yreg, yreg"},
+		"VPSRLQ":          {"imm, yreg, yreg"},
+		"VPTEST":          {"yreg, yreg"},
+		"VPXOR":           {"addr|mem|sym|xreg, addr|mem|sym|xreg, xreg", "addr|mem|sym|yreg, addr|mem|sym|yreg, yreg"},
+		"VPXORQ":          {"addr|mem|sym|xreg, addr|mem|sym|xreg, xreg", "addr|mem|sym|yreg, addr|mem|sym|yreg, yreg", "addr|mem|sym|zreg, addr|mem|sym|zreg, zreg"},
+		"VZEROALL":        {"*"},
+		"VZEROUPPER":      {"*"},
+		"XADDL":           {"reg, mem"},
+		"XCHGB":           {"reg, addr|mem|reg|sym"},
+		"XCHGL":           {"reg, addr|mem|reg|sym"},
+		"XGETBV":          {"*"},
+		"XORB":            {"addr|fp|imm|mem|reg|sym, reg"},
+		"XORL":            {"addr|fp|imm|mem|reg|sym, mem|reg"},
+		"XORPS":           {"addr|mem|sym|xreg, xreg"},
+	},
+	ArchARM: {
+		"ADC":      {"addr|fp|imm|label|mem|reg|shifted|sym, reg", "addr|fp|imm|label|mem|reg|shifted|sym, addr|fp|imm|label|mem|reg|shifted|sym, reg"},
+		"ADD":      {"addr|fp|imm|label|mem|reg|shifted|sym, reg", "addr|fp|imm|label|mem|reg|shifted|sym, addr|fp|imm|label|mem|reg|shifted|sym, reg"},
+		"AND":      {"addr|fp|imm|label|mem|reg|shifted|sym, reg", "addr|fp|imm|label|mem|reg|shifted|sym, addr|fp|imm|label|mem|reg|shifted|sym, reg"},
+		"B":        {"label|sym"},
+		"BIC":      {"addr|fp|imm|label|mem|reg|shifted|sym, reg", "addr|fp|imm|label|mem|reg|shifted|sym, addr|fp|imm|label|mem|reg|shifted|sym, reg"},
+		"BL":       {"addr|mem|reg|sym"},
+		"BYTE":     {"*"},
+		"CALL":     {"addr|mem|reg|sym"},
+		"CLZ":      {"addr|fp|imm|label|mem|reg|shifted|sym, reg"},
+		"CMN":      {"addr|fp|imm|label|mem|reg|shifted|sym, addr|imm|label|mem|reg|shifted|sym"},
+		"CMP":      {"addr|fp|imm|label|mem|reg|shifted|sym, addr|imm|label|mem|reg|shifted|sym"},
+		"DIVUHW":   {"addr|fp|imm|label|mem|reg|shifted|sym, addr|fp|imm|label|mem|reg|shifted|sym, reg"},
+		"DMB":      {"*"},
+		"EOR":      {"addr|fp|imm|label|mem|reg|shifted|sym, reg", "addr|fp|imm|label|mem|reg|shifted|sym, addr|fp|imm|label|mem|reg|shifted|sym, reg"},
+		"FUNCDATA": {"*"},
+		"JMP":      {"label|sym"},
+		"LDREX":    {"mem, reg"},
+		"LDREXB":   {"mem, reg"},
+		"LDREXD":   {"mem, reg"},
+		"MOVB":     {"addr|fp|imm|label|mem|reg|shifted|sym, addr|fp|mem|reg|sym"},
+		"MOVBU":    {"addr|fp|imm|label|mem|reg|shifted|sym, addr|fp|mem|reg|sym"},
+		"MOVD":     {"freg|mem, freg", "freg, freg|mem"},
+		"MOVM":     {"mem, reglist", "reglist, mem"},
+		"MOVW":     {"addr|fp|imm|label|mem|reg|shifted|sym, addr|fp|mem|reg|sym"},
+		"MUL":      {"addr|fp|imm|label|mem|reg|shifted|sym, reg", "addr|fp|imm|label|mem|reg|shifted|sym, addr|fp|imm|label|mem|reg|shifted|sym, reg"},
+		"MULA":     {"addr|fp|imm|label|mem|reg|shifted|sym, addr|fp|imm|label|mem|reg|shifted|sym, addr|fp|imm|label|mem|reg|shifted|sym, reg"},
+		"MULAL":    {"addr|fp|imm|label|mem|reg|shifted|sym, addr|fp|imm|label|mem|reg|shifted|sym, reglist"},
+		"MULALU":   {"addr|fp|imm|label|mem|reg|shifted|sym, addr|fp|imm|label|mem|reg|shifted|sym, reglist"},
+		"MULAWT":   {"addr|fp|imm|label|mem|reg|shifted|sym, addr|fp|imm|label|mem|reg|shifted|sym, addr|fp|imm|label|mem|reg|shifted|sym, reg"},
+		"MULLU":    {"addr|fp|imm|label|mem|reg|shifted|sym, addr|fp|imm|label|mem|reg|shifted|sym, reglist"},
+		"MULU":     {"addr|fp|imm|label|mem|reg|shifted|sym, reg", "addr|fp|imm|label|mem|reg|shifted|sym, addr|fp|imm|label|mem|reg|shifted|sym, reg"},
+		"MVN":      {"addr|fp|imm|label|mem|reg|shifted|sym, reg", "addr|fp|freg|imm|label|mem|reg|reglist|shifted|sym, addr|fp|imm|label|mem|reg|shifted|sym, reg"},
+		"NOP":      {"*"},
+		"ORR":      {"addr|fp|imm|label|mem|reg|shifted|sym, reg", "addr|fp|imm|label|mem|reg|shifted|sym, addr|fp|imm|label|mem|reg|shifted|sym, reg"},
+		"PCDATA":   {"*"},
+		"RET":      {"*"},
+		"RSB":      {"addr|fp|imm|label|mem|reg|shifted|sym, reg", "addr|fp|imm|label|mem|reg|shifted|sym, addr|fp|imm|label|mem|reg|shifted|sym, reg"},
+		"SBC":      {"addr|fp|imm|label|mem|reg|shifted|sym, reg", "addr|fp|imm|label|mem|reg|shifted|sym, addr|fp|imm|label|mem|reg|shifted|sym, reg"},
+		"STREX":    {"reg, mem, reg"},
+		"STREXB":   {"reg, mem, reg"},
+		"STREXD":   {"reg, mem, reg"},
+		"SUB":      {"addr|fp|imm|label|mem|reg|shifted|sym, reg", "addr|fp|imm|label|mem|reg|shifted|sym, addr|fp|imm|label|mem|reg|shifted|sym, reg"},
+		"SWI":      {"", "imm"},
+		"TEQ":      {"addr|fp|imm|label|mem|reg|shifted|sym, addr|imm|label|mem|reg|shifted|sym"},
+		"TST":      {"addr|fp|imm|label|mem|reg|shifted|sym, addr|imm|label|mem|reg|shifted|sym"},
+		"UNDEF":    {"*"},
+		"WORD":     {"*"},
+	},
+	ArchARM64: {
+		"ADC":              {"addr|cond|extended|fp|freg|imm|label|mem|reg|shifted|sym, freg|reg", "addr|cond|extended|fp|freg|imm|label|mem|reg|shifted|sym, addr|cond|extended|fp|freg|imm|label|mem|reg|shifted|sym, freg|reg"},
+		"ADCS":             {"addr|cond|extended|fp|freg|imm|label|mem|reg|shifted|sym, freg|reg", "addr|cond|extended|fp|freg|imm|label|mem|reg|shifted|sym, addr|cond|extended|fp|freg|imm|label|mem|reg|shifted|sym, freg|reg"},
+		"ADD":              {"addr|cond|extended|fp|freg|imm|label|mem|reg|shifted|sym, freg|reg", "addr|cond|extended|fp|freg|imm|label|mem|reg|shifted|sym, addr|cond|extended|fp|freg|imm|label|mem|reg|shifted|sym, freg|reg"},
+		"ADDS":             {"addr|cond|extended|fp|freg|imm|label|mem|reg|shifted|sym, freg|reg", "addr|cond|extended|fp|freg|imm|label|mem|reg|shifted|sym, addr|cond|extended|fp|freg|imm|label|mem|reg|shifted|sym, freg|reg"},
+		"ADDW":             {"addr|cond|extended|fp|freg|imm|label|mem|reg|shifted|sym, freg|reg", "addr|cond|extended|fp|freg|imm|label|mem|reg|shifted|sym, addr|cond|extended|fp|freg|imm|label|mem|reg|shifted|sym, freg|reg"},
+		"AESD":             {"*"},
+		"AESE":             {"*"},
+		"AESIMC":           {"*"},
+		"AESMC":            {"*"},
+		"AND":              {"addr|cond|extended|fp|freg|imm|label|mem|reg|shifted|sym, freg|reg", "addr|cond|extended|fp|freg|imm|label|mem|reg|shifted|sym, addr|cond|extended|fp|freg|imm|label|mem|reg|shifted|sym, freg|reg"},
+		"ANDS":             {"addr|cond|extended|fp|freg|imm|label|mem|reg|shifted|sym, freg|reg", "addr|cond|extended|fp|freg|imm|label|mem|reg|shifted|sym, addr|cond|extended|fp|freg|imm|label|mem|reg|shifted|sym, freg|reg"},
+		"ANDSW":            {"addr|cond|extended|fp|freg|imm|label|mem|reg|shifted|sym, freg|reg", "addr|cond|extended|fp|freg|imm|label|mem|reg|shifted|sym, addr|cond|extended|fp|freg|imm|label|mem|reg|shifted|sym, freg|reg"},
+		"ANDW":             {"addr|cond|extended|fp|freg|imm|label|mem|reg|shifted|sym, freg|reg", "addr|cond|extended|fp|freg|imm|label|mem|reg|shifted|sym, addr|cond|extended|fp|freg|imm|label|mem|reg|shifted|sym, freg|reg"},
+		"ASR":              {"freg|imm|reg, freg|reg", "freg|imm|reg, freg|reg, freg|reg"},
+		"B":                {"addr|cond|freg|label|mem|reg|sym"},
+		"BIC":              {"addr|cond|extended|fp|freg|imm|label|mem|reg|shifted|sym, freg|reg", "addr|cond|extended|fp|freg|imm|label|mem|reg|shifted|sym, addr|cond|extended|fp|freg|imm|label|mem|reg|shifted|sym, freg|reg"},
+		"BICW":             {"addr|cond|extended|fp|freg|imm|label|mem|reg|shifted|sym, freg|reg", "addr|cond|extended|fp|freg|imm|label|mem|reg|shifted|sym, addr|cond|extended|fp|freg|imm|label|mem|reg|shifted|sym, freg|reg"},
+		"BL":               {"addr|freg|mem|reg|sym"},
+		"BLR":              {"addr|freg|mem|reg|sym"},
+		"BREAK":            {"*"},
+		"BRK":              {"*"},
+		"BYTE":             {"*"},
+		"CALL":             {"addr|freg|mem|reg|sym"},
+		"CASALD":           {"freg|reg, mem, freg|reg"},
+		"CASALW":           {"freg|reg, mem, freg|reg"},
+		"CBNZ":             {"freg|reg, addr|cond|label|sym"},
+		"CBNZW":            {"freg|reg, addr|cond|label|sym"},
+		"CBZ":              {"freg|reg, addr|cond|label|sym"},
+		"CBZW":             {"freg|reg, addr|cond|label|sym"},
+		"CCMP":             {"*"},
+		"CLZ":              {"freg|reg, freg|reg"},
+		"CMN":              {"addr|cond|extended|fp|freg|imm|label|mem|reg|shifted|sym, addr|cond|extended|freg|imm|label|mem|reg|shifted|sym"},
+		"CMP":              {"addr|cond|extended|fp|freg|imm|label|mem|reg|shifted|sym, addr|cond|extended|freg|imm|label|mem|reg|shifted|sym"},
+		"CMPW":             {"addr|cond|extended|fp|freg|imm|label|mem|reg|shifted|sym, addr|cond|extended|freg|imm|label|mem|reg|shifted|sym"},
+		"CRC32B":           {"freg|reg, freg|reg"},
+		"CRC32CB":          {"freg|reg, freg|reg"},
+		"CRC32CH":          {"freg|reg, freg|reg"},
+		"CRC32CW":          {"freg|reg, freg|reg"},
+		"CRC32CX":          {"freg|reg, freg|reg"},
+		"CRC32H":           {"freg|reg, freg|reg"},
+		"CRC32W":           {"freg|reg, freg|reg"},
+		"CRC32X":           {"freg|reg, freg|reg"},
+		"DC":               {"*"},
+		"DMB":              {"*"},
+		"DSB":              {"*"},
+		"EOR":              {"addr|cond|extended|fp|freg|imm|label|mem|reg|shifted|sym, freg|reg", "addr|cond|extended|fp|freg|imm|label|mem|reg|shifted|sym, addr|cond|extended|fp|freg|imm|label|mem|reg|shifted|sym, freg|reg"},
+		"EORW":             {"addr|cond|extended|fp|freg|imm|label|mem|reg|shifted|sym, freg|reg", "addr|cond|extended|fp|freg|imm|label|mem|reg|shifted|sym, addr|cond|extended|fp|freg|imm|label|mem|reg|shifted|sym, freg|reg"},
+		"EXTR":             {"imm, addr|cond|extended|fp|freg|imm|label|mem|reg|shifted|sym, addr|cond|extended|fp|freg|imm|label|mem|reg|shifted|sym, freg|reg"},
+		"FABSD":            {"fp|freg|imm|reg, fp|freg|reg"},
+		"FADDD":            {"fp|freg|imm|reg, freg|reg", "fp|freg|imm|reg, fp|freg|imm|reg, fp|freg|reg"},
+		"FCMPD":            {"fp|freg|imm|reg, freg|imm|reg"},
+		"FCVTZSD":          {"fp|freg|imm|reg, freg|reg"},
+		"FDIVD":            {"fp|freg|imm|reg, freg|reg", "fp|freg|imm|reg, fp|freg|imm|reg, fp|freg|reg"},
+		"FLDPD":            {"*"},
+		"FMADDD":           {"fp|freg|imm|reg, fp|freg|imm|reg, fp|freg|imm|reg, fp|freg|reg"},
+		"FMAXD":            {"fp|freg|imm|reg, freg|reg", "fp|freg|imm|reg, fp|freg|imm|reg, fp|freg|reg"},
+		"FMIND":            {"fp|freg|imm|reg, freg|reg", "fp|freg|imm|reg, fp|freg|imm|reg, fp|freg|reg"},
+		"FMOVD":            {"addr|cond|extended|fp|freg|imm|label|mem|reg|shifted|sym, fp|freg|reg"},
+		"FMOVS":            {"*"},
+		"FMSUBD":           {"fp|freg|imm|reg, fp|freg|imm|reg, fp|freg|imm|reg, fp|freg|reg"},
+		"FMULD":            {"fp|freg|imm|reg, freg|reg", "fp|freg|imm|reg, fp|freg|imm|reg, fp|freg|reg"},
+		"FNMSUBD":          {"fp|freg|imm|reg, fp|freg|imm|reg, fp|freg|imm|reg, fp|freg|reg"},
+		"FNMULD":           {"fp|freg|imm|reg, freg|reg", "fp|freg|imm|reg, fp|freg|imm|reg, fp|freg|reg"},
+		"FRINTMD":          {"fp|freg|imm|reg, fp|freg|reg"},
+		"FRINTPD":          {"fp|freg|imm|reg, fp|freg|reg"},
+		"FRINTZD":          {"fp|freg|imm|reg, fp|freg|reg"},
+		"FSTPD":            {"*"},
+		"FSUBD":            {"fp|freg|imm|reg, freg|reg", "fp|freg|imm|reg, fp|freg|imm|reg, fp|freg|reg"},
+		"FUNCDATA":         {"*"},
+		"ISB":              {"*"},
+		"JMP":              {"addr|cond|freg|label|mem|reg|sym"},
+		"LDADDALD":         {"freg|reg, mem, freg|reg"},
+		"LDADDALW":         {"freg|reg, mem, freg|reg"},
+		"LDAR":             {"mem, freg|reg"},
+		"LDARB":            {"mem, freg|reg"},
+		"LDARW":            {"mem, freg|reg"},
+		"LDAXR":            {"mem, freg|reg"},
+		"LDAXRB":           {"mem, freg|reg"},
+		"LDAXRW":           {"mem, freg|reg"},
+		"LDCLRALB":         {"freg|reg, mem, freg|reg"},
+		"LDCLRALD":         {"freg|reg, mem, freg|reg"},
+		"LDCLRALW":         {"freg|reg, mem, freg|reg"},
+		"LDORALB":          {"freg|reg, mem, freg|reg"},
+		"LDORALD":          {"freg|reg, mem, freg|reg"},
+		"LDORALW":          {"freg|reg, mem, freg|reg"},
+		"LDP":              {"addr|fp|mem|sym, reglist"},
+		"LDPW":             {"mem, reglist"},
+		"LSL":              {"freg|imm|reg, freg|reg", "freg|imm|reg, freg|reg, freg|reg"},
+		"LSLW":             {"freg|imm|reg, freg|reg", "freg|imm|reg, freg|reg, freg|reg"},
+		"LSR":              {"freg|imm|reg, freg|reg", "freg|imm|reg, freg|reg, freg|reg"},
+		"MADD":             {"addr|cond|extended|fp|freg|imm|label|mem|reg|shifted|sym, addr|cond|extended|fp|freg|imm|label|mem|reg|shifted|sym, addr|cond|extended|fp|freg|imm|label|mem|reg|shifted|sym, freg|reg"},
+		"MOV":              {"*"},
+		"MOVB":             {"addr|cond|extended|fp|freg|imm|label|mem|reg|shifted|sym, fp|freg|mem|reg"},
+		"MOVBU":            {"addr|fp|freg|mem|reg|sym, freg|reg", "freg|reg, fp|freg|mem|reg"},
+		"MOVD":             {"addr|cond|extended|fp|freg|imm|label|mem|reg|shifted|sym, addr|cond|extended|fp|freg|imm|label|mem|reg|reglist|shifted|sym|vlist"},
+		"MOVH":             {"addr|cond|extended|fp|freg|imm|label|mem|reg|shifted|sym, addr|cond|extended|fp|freg|imm|label|mem|reg|reglist|shifted|sym|vlist"},
+		"MOVHU":            {"fp|mem, freg|reg", "freg|reg, mem"},
+		"MOVW":             {"addr|cond|extended|fp|freg|imm|label|mem|reg|shifted|sym, addr|cond|extended|fp|freg|imm|label|mem|reg|reglist|shifted|sym|vlist"},
+		"MOVWU":            {"fp|mem, freg|reg", "freg|reg, fp|mem"},
+		"MRS":              {"cond|label, freg|reg"},
+		"MSR":              {"freg|imm|reg, cond|label"},
+		"MSUB":             {"addr|cond|extended|fp|freg|imm|label|mem|reg|shifted|sym, addr|cond|extended|fp|freg|imm|label|mem|reg|shifted|sym, addr|cond|extended|fp|freg|imm|label|mem|reg|shifted|sym, freg|reg"},
+		"MUL":              {"addr|cond|extended|fp|freg|imm|label|mem|reg|shifted|sym, freg|reg", "addr|cond|extended|fp|freg|imm|label|mem|reg|shifted|sym, addr|cond|extended|fp|freg|imm|label|mem|reg|shifted|sym, freg|reg"},
+		"MVN":              {"addr|cond|extended|fp|freg|imm|label|mem|reg|shifted|sym, freg|reg"},
+		"MVNW":             {"addr|cond|extended|fp|freg|imm|label|mem|reg|shifted|sym, freg|reg"},
+		"NEG":              {"addr|cond|extended|fp|freg|imm|label|mem|reg|shifted|sym, freg|reg"},
+		"NOP":              {"*"},
+		"ORR":              {"addr|cond|extended|fp|freg|imm|label|mem|reg|shifted|sym, freg|reg", "addr|cond|extended|fp|freg|imm|label|mem|reg|shifted|sym, addr|cond|extended|fp|freg|imm|label|mem|reg|shifted|sym, freg|reg"},
+		"ORRW":             {"addr|cond|extended|fp|freg|imm|label|mem|reg|shifted|sym, freg|reg", "addr|cond|extended|fp|freg|imm|label|mem|reg|shifted|sym, addr|cond|extended|fp|freg|imm|label|mem|reg|shifted|sym, freg|reg"},
+		"P256ADDINLINE":    {"*"},
+		"P256MULBY2INLINE": {"*"},
+		"PCALIGN":          {"*"},
+		"PCDATA":           {"*"},
+		"PRFM":             {"*"},
+		"RBIT":             {"freg|reg, freg|reg"},
+		"RET":              {"*"},
+		"REV":              {"freg|reg, freg|reg"},
+		"RORW":             {"freg|imm|reg, freg|reg", "freg|imm|reg, freg|reg, freg|reg"},
+		"SBC":              {"addr|cond|extended|fp|freg|imm|label|mem|reg|shifted|sym, freg|reg", "addr|cond|extended|fp|freg|imm|label|mem|reg|shifted|sym, addr|cond|extended|fp|freg|imm|label|mem|reg|shifted|sym, freg|reg"},
+		"SBCS":             {"addr|cond|extended|fp|freg|imm|label|mem|reg|shifted|sym, freg|reg", "addr|cond|extended|fp|freg|imm|label|mem|reg|shifted|sym, addr|cond|extended|fp|freg|imm|label|mem|reg|shifted|sym, freg|reg"},
+		"SCVTFD":           {"freg|reg, fp|freg|reg"},
+		"SHA1C":            {"*"},
+		"SHA1H":            {"*"},
+		"SHA1M":            {"*"},
+		"SHA1P":            {"*"},
+		"SHA1SU0":          {"*"},
+		"SHA1SU1":          {"*"},
+		"SHA256H":          {"*"},
+		"SHA256H2":         {"*"},
+		"SHA256SU0":        {"*"},
+		"SHA256SU1":        {"*"},
+		"SHA512H":          {"*"},
+		"SHA512H2":         {"*"},
+		"SHA512SU0":        {"*"},
+		"SHA512SU1":        {"*"},
+		"STLR":             {"freg|reg, mem"},
+		"STLRB":            {"freg|reg, mem"},
+		"STLRW":            {"freg|reg, mem"},
+		"STLXR":            {"freg|reg, mem, freg|reg"},
+		"STLXRB":           {"freg|reg, mem, freg|reg"},
+		"STLXRW":           {"freg|reg, mem, freg|reg"},
+		"STP":              {"reglist, mem"},
+		"STPW":             {"reglist, mem"},
+		"STY":              {"*"},
+		"SUB":              {"addr|cond|extended|fp|freg|imm|label|mem|reg|shifted|sym, freg|reg", "addr|cond|extended|fp|freg|imm|label|mem|reg|shifted|sym, addr|cond|extended|fp|freg|imm|label|mem|reg|shifted|sym, freg|reg"},
+		"SUBS":             {"addr|cond|extended|fp|freg|imm|label|mem|reg|shifted|sym, freg|reg", "addr|cond|extended|fp|freg|imm|label|mem|reg|shifted|sym, addr|cond|extended|fp|freg|imm|label|mem|reg|shifted|sym, freg|reg"},
+		"SUBW":             {"addr|cond|extended|fp|freg|imm|label|mem|reg|shifted|sym, freg|reg", "addr|cond|extended|fp|freg|imm|label|mem|reg|shifted|sym, addr|cond|extended|fp|freg|imm|label|mem|reg|shifted|sym, freg|reg"},
+		"SVC":              {"", "imm"},
+		"SWPALB":           {"freg|reg, mem, freg|reg"},
+		"SWPALD":           {"freg|reg, mem, freg|reg"},
+		"SWPALW":           {"freg|reg, mem, freg|reg"},
+		"UDIV":             {"addr|cond|extended|fp|freg|imm|label|mem|reg|shifted|sym, freg|reg", "addr|cond|extended|fp|freg|imm|label|mem|reg|shifted|sym, addr|cond|extended|fp|freg|imm|label|mem|reg|shifted|sym, freg|reg"},
+		"UMULH":            {"addr|cond|extended|fp|freg|imm|label|mem|reg|shifted|sym, addr|cond|extended|fp|freg|imm|label|mem|reg|shifted|sym, freg|reg"},
+		"UNDEF":            {"*"},
+		"VADD":             {"vreg, vreg", "vreg, vreg, vreg"},
+		"VADDP":            {"vreg, vreg, vreg"},
+		"VAND":             {"vreg, vreg, vreg"},
+		"VBCAX":            {"*"},
+		"VCMEQ":            {"vreg, vreg, vreg"},
+		"VDUP":             {"*"},
+		"VEOR":             {"vreg, vreg, vreg"},
+		"VEOR3":            {"*"},
+		"VEXT":             {"*"},
+		"VLD1":             {"mem, vlist|vreg"},
+		"VLD1R":            {"*"},
+		"VLD4R":            {"*"},
+		"VMOV":             {"freg|reg|vreg, vreg", "vreg, freg|reg|vreg"},
+		"VORR":             {"vreg, vreg, vreg"},
+		"VPMULL":           {"*"},
+		"VPMULL2":          {"*"},
+		"VRAX1":            {"*"},
+		"VREV32":           {"*"},
+		"VREV64":           {"*"},
+		"VSHL":             {"*"},
+		"VSRI":             {"*"},
+		"VST1":             {"vlist, mem"},
+		"VTBL":             {"*"},
+		"VUADDLV":          {"vreg, vreg"},
+		"VUSHR":            {"*"},
+		"VXAR":             {"*"},
+		"VZIP1":            {"*"},
+		"VZIP2":            {"*"},
+		"WORD":             {"*"},
+		"YIELD":            {"*"},
+	},
+	ArchRISCV64: {
+		"ADD":       {"imm|reg, reg", "imm|reg, reg, reg"},
+		"ADDI":      {"imm, reg", "imm, reg, reg"},
+		"ADDIW":     {"imm, reg", "imm, reg, reg"},
+		"ADDUW":     {"reg, reg", "reg, reg, reg"},
+		"ADDW":      {"imm|reg, reg", "imm|reg, reg, reg"},
+		"AMOADDD":   {"reg, mem, reg"},
+		"AMOADDW":   {"reg, mem, reg"},
+		"AMOANDD":   {"reg, mem, reg"},
+		"AMOANDW":   {"reg, mem, reg"},
+		"AMOMAXD":   {"reg, mem, reg"},
+		"AMOMAXUD":  {"reg, mem, reg"},
+		"AMOMAXUW":  {"reg, mem, reg"},
+		"AMOMAXW":   {"reg, mem, reg"},
+		"AMOMIND":   {"reg, mem, reg"},
+		"AMOMINUD":  {"reg, mem, reg"},
+		"AMOMINUW":  {"reg, mem, reg"},
+		"AMOMINW":   {"reg, mem, reg"},
+		"AMOORD":    {"reg, mem, reg"},
+		"AMOORW":    {"reg, mem, reg"},
+		"AMOSWAPD":  {"reg, mem, reg"},
+		"AMOSWAPW":  {"reg, mem, reg"},
+		"AMOXORD":   {"reg, mem, reg"},
+		"AMOXORW":   {"reg, mem, reg"},
+		"AND":       {"imm|reg, reg", "imm|reg, reg, reg"},
+		"ANDI":      {"imm, reg", "imm, reg, reg"},
+		"ANDN":      {"reg, reg", "reg, reg, reg"},
+		"BCLR":      {"imm|reg, reg", "imm|reg, reg, reg"},
+		"BCLRI":     {"imm, reg", "imm, reg, reg"},
+		"BEQ":       {"reg, reg, label"},
+		"BEQZ":      {"reg, label"},
+		"BEXT":      {"imm|reg, reg", "imm|reg, reg, reg"},
+		"BEXTI":     {"imm, reg", "imm, reg, reg"},
+		"BGE":       {"reg, reg, label"},
+		"BGEU":      {"reg, reg, label"},
+		"BGEZ":      {"reg, label"},
+		"BGT":       {"reg, reg, label"},
+		"BGTU":      {"reg, reg, label"},
+		"BGTZ":      {"reg, label"},
+		"BINV":      {"imm|reg, reg", "imm|reg, reg, reg"},
+		"BINVI":     {"imm, reg", "imm, reg, reg"},
+		"BLE":       {"reg, reg, label"},
+		"BLEU":      {"reg, reg, label"},
+		"BLEZ":      {"reg, label"},
+		"BLT":       {"reg, reg, label"},
+		"BLTU":      {"reg, reg, label"},
+		"BLTZ":      {"reg, label"},
+		"BNE":       {"reg, reg, label"},
+		"BNEZ":      {"reg, label"},
+		"BSET":      {"imm|reg, reg", "imm|reg, reg, reg"},
+		"BSETI":     {"imm, reg", "imm, reg, reg"},
+		"BYTE":      {"*"},
+		"CALL":      {"mem|reg|sym"},
+		"CLZ":       {"reg, reg"},
+		"CLZW":      {"reg, reg"},
+		"CPOP":      {"reg, reg"},
+		"CPOPW":     {"reg, reg"},
+		"CTZ":       {"reg, reg"},
+		"CTZW":      {"reg, reg"},
+		"DIV":       {"reg, reg", "reg, reg, reg"},
+		"DIVU":      {"reg, reg", "reg, reg, reg"},
+		"DIVUW":     {"reg, reg", "reg, reg, reg"},
+		"DIVW":      {"reg, reg", "reg, reg, reg"},
+		"EBREAK":    {"*"},
+		"ECALL":     {"*"},
+		"FABSD":     {"freg, freg"},
+		"FABSS":     {"freg, freg"},
+		"FADDD":     {"freg, freg", "freg, freg, freg"},
+		"FADDS":     {"freg, freg", "freg, freg, freg"},
+		"FCLASSD":   {"freg, reg"},
+		"FCLASSS":   {"freg, reg"},
+		"FCVTDL":    {"reg, freg"},
+		"FCVTDLU":   {"reg, freg"},
+		"FCVTDS":    {"freg, freg"},
+		"FCVTDW":    {"reg, freg"},
+		"FCVTDWU":   {"reg, freg"},
+		"FCVTLD":    {"freg, reg"},
+		"FCVTLS":    {"freg, reg"},
+		"FCVTLUD":   {"freg, reg"},
+		"FCVTLUS":   {"freg, reg"},
+		"FCVTSD":    {"freg, freg"},
+		"FCVTSL":    {"reg, freg"},
+		"FCVTSLU":   {"reg, freg"},
+		"FCVTSW":    {"reg, freg"},
+		"FCVTSWU":   {"reg, freg"},
+		"FCVTWD":    {"freg, reg"},
+		"FCVTWS":    {"freg, reg"},
+		"FCVTWUD":   {"freg, reg"},
+		"FCVTWUS":   {"freg, reg"},
+		"FDIVD":     {"freg, freg", "freg, freg, freg"},
+		"FDIVS":     {"freg, freg", "freg, freg, freg"},
+		"FENCE":     {"*"},
+		"FEQD":      {"freg, freg, reg"},
+		"FEQS":      {"freg, freg, reg"},
+		"FLED":      {"freg, freg, reg"},
+		"FLES":      {"freg, freg, reg"},
+		"FLTD":      {"freg, freg, reg"},
+		"FLTS":      {"freg, freg, reg"},
+		"FMADDD":    {"freg, freg, freg, freg"},
+		"FMADDS":    {"freg, freg, freg, freg"},
+		"FMAXD":     {"freg, freg", "freg, freg, freg"},
+		"FMAXS":     {"freg, freg", "freg, freg, freg"},
+		"FMIND":     {"freg, freg", "freg, freg, freg"},
+		"FMINS":     {"freg, freg", "freg, freg, freg"},
+		"FMSUBD":    {"freg, freg, freg, freg"},
+		"FMSUBS":    {"freg, freg, freg, freg"},
+		"FMULD":     {"freg, freg", "freg, freg, freg"},
+		"FMULS":     {"freg, freg", "freg, freg, freg"},
+		"FMVDX":     {"reg, freg"},
+		"FMVWX":     {"reg, freg"},
+		"FMVXD":     {"freg, reg"},
+		"FMVXW":     {"freg, reg"},
+		"FNED":      {"freg, freg, reg"},
+		"FNEGD":     {"freg, freg"},
+		"FNEGS":     {"freg, freg"},
+		"FNES":      {"freg, freg, reg"},
+		"FNMADDD":   {"freg, freg, freg, freg"},
+		"FNMADDS":   {"freg, freg, freg, freg"},
+		"FNMSUBD":   {"freg, freg, freg, freg"},
+		"FNMSUBS":   {"freg, freg, freg, freg"},
+		"FSGNJD":    {"freg, freg", "freg, freg, freg"},
+		"FSGNJND":   {"freg, freg", "freg, freg, freg"},
+		"FSGNJNS":   {"freg, freg", "freg, freg, freg"},
+		"FSGNJS":    {"freg, freg", "freg, freg, freg"},
+		"FSGNJXD":   {"freg, freg", "freg, freg, freg"},
+		"FSGNJXS":   {"freg, freg", "freg, freg, freg"},
+		"FSQRTD":    {"freg, freg"},
+		"FSQRTS":    {"freg, freg"},
+		"FSUBD":     {"freg, freg", "freg, freg, freg"},
+		"FSUBS":     {"freg, freg", "freg, freg, freg"},
+		"FUNCDATA":  {"*"},
+		"JAL":       {"reg, mem|reg|sym"},
+		"JALR":      {"reg, mem|reg|sym"},
+		"JMP":       {"label|mem|reg|sym"},
+		"LRD":       {"mem, reg"},
+		"LRW":       {"mem, reg"},
+		"LUI":       {"imm, reg"},
+		"MAX":       {"reg, reg", "reg, reg, reg"},
+		"MAXU":      {"reg, reg", "reg, reg, reg"},
+		"MIN":       {"reg, reg", "reg, reg, reg"},
+		"MINU":      {"reg, reg", "reg, reg, reg"},
+		"MOV":       {"addr|fp|imm|mem|reg|sym, reg", "reg, fp|mem|reg|sym"},
+		"MOVB":      {"addr|fp|imm|mem|reg|sym, reg", "reg, fp|mem|reg|sym"},
+		"MOVBU":     {"addr|fp|imm|mem|reg|sym, reg", "reg, fp|mem|reg|sym"},
+		"MOVD":      {"addr|fp|freg|mem|reg|sym, freg", "freg, fp|freg|mem|reg|sym"},
+		"MOVF":      {"addr|fp|freg|mem|reg|sym, freg", "freg, fp|freg|mem|reg|sym"},
+		"MOVH":      {"addr|fp|imm|mem|reg|sym, reg", "reg, fp|mem|reg|sym"},
+		"MOVHU":     {"addr|fp|imm|mem|reg|sym, reg", "reg, fp|mem|reg|sym"},
+		"MOVW":      {"addr|fp|imm|mem|reg|sym, reg", "reg, fp|mem|reg|sym"},
+		"MOVWU":     {"addr|fp|imm|mem|reg|sym, reg", "reg, fp|mem|reg|sym"},
+		"MUL":       {"reg, reg", "reg, reg, reg"},
+		"MULH":      {"reg, reg", "reg, reg, reg"},
+		"MULHSU":    {"reg, reg", "reg, reg, reg"},
+		"MULHU":     {"reg, reg", "reg, reg, reg"},
+		"MULW":      {"reg, reg", "reg, reg, reg"},
+		"NEG":       {"reg", "reg, reg"},
+		"NEGW":      {"reg", "reg, reg"},
+		"NOP":       {"*"},
+		"NOT":       {"reg", "reg, reg"},
+		"OR":        {"imm|reg, reg", "imm|reg, reg, reg"},
+		"ORCB":      {"reg, reg"},
+		"ORI":       {"imm, reg", "imm, reg, reg"},
+		"ORN":       {"reg, reg", "reg, reg, reg"},
+		"PAUSE":     {"*"},
+		"PCALIGN":   {"*"},
+		"PCDATA":    {"*"},
+		"RDCYCLE":   {"reg"},
+		"RDINSTRET": {"reg"},
+		"RDTIME":    {"reg"},
+		"REM":       {"reg, reg", "reg, reg, reg"},
+		"REMU":      {"reg, reg", "reg, reg, reg"},
+		"REMUW":     {"reg, reg", "reg, reg, reg"},
+		"REMW":      {"reg, reg", "reg, reg, reg"},
+		"RET":       {"*"},
+		"REV8":      {"reg, reg"},
+		"ROL":       {"reg, reg", "reg, reg, reg"},
+		"ROLW":      {"reg, reg", "reg, reg, reg"},
+		"ROR":       {"imm|reg, reg", "imm|reg, reg, reg"},
+		"RORI":      {"imm, reg", "imm, reg, reg"},
+		"RORIW":     {"imm, reg", "imm, reg, reg"},
+		"RORW":      {"imm|reg, reg", "imm|reg, reg, reg"},
+		"SCD":       {"reg, mem, reg"},
+		"SCW":       {"reg, mem, reg"},
+		"SEQZ":      {"reg, reg"},
+		"SEXTB":     {"reg, reg"},
+		"SEXTH":     {"reg, reg"},
+		"SH1ADD":    {"reg, reg", "reg, reg, reg"},
+		"SH1ADDUW":  {"reg, reg", "reg, reg, reg"},
+		"SH2ADD":    {"reg, reg", "reg, reg, reg"},
+		"SH2ADDUW":  {"reg, reg", "reg, reg, reg"},
+		"SH3ADD":    {"reg, reg", "reg, reg, reg"},
+		"SH3ADDUW":  {"reg, reg", "reg, reg, reg"},
+		"SLL":       {"imm|reg, reg", "imm|reg, reg, reg"},
+		"SLLI":      {"imm, reg", "imm, reg, reg"},
+		"SLLIUW":    {"imm, reg", "imm, reg, reg"},
+		"SLLIW":     {"imm, reg", "imm, reg, reg"},
+		"SLLW":      {"imm|reg, reg", "imm|reg, reg, reg"},
+		"SLT":       {"imm|reg, reg", "imm|reg, reg, reg"},
+		"SLTI":      {"imm, reg", "imm, reg, reg"},
+		"SLTIU":     {"imm, reg", "imm, reg, reg"},
+		"SLTU":      {"imm|reg, reg", "imm|reg, reg, reg"},
+		"SNEZ":      {"reg, reg"},
+		"SRA":       {"imm|reg, reg", "imm|reg, reg, reg"},
+		"SRAI":      {"imm, reg", "imm, reg, reg"},
+		"SRAIW":     {"imm, reg", "imm, reg, reg"},
+		"SRAW":      {"imm|reg, reg", "imm|reg, reg, reg"},
+		"SRL":       {"imm|reg, reg", "imm|reg, reg, reg"},
+		"SRLI":      {"imm, reg", "imm, reg, reg"},
+		"SRLIW":     {"imm, reg", "imm, reg, reg"},
+		"SRLW":      {"imm|reg, reg", "imm|reg, reg, reg"},
+		"SUB":       {"imm|reg, reg", "imm|reg, reg, reg"},
+		"SUBW":      {"imm|reg, reg", "imm|reg, reg, reg"},
+		"UNDEF":     {"*"},
+		"WORD":      {"*"},
+		"XNOR":      {"reg, reg", "reg, reg, reg"},
+		"XOR":       {"imm|reg, reg", "imm|reg, reg, reg"},
+		"XORI":      {"imm, reg", "imm, reg, reg"},
+		"ZEXTH":     {"reg, reg"},
+	},
+	ArchLOONG64: {
+		"ABSD":       {"freg, freg"},
+		"ABSF":       {"freg, freg"},
+		"ADD":        {"imm|reg, reg", "imm|reg, reg, reg"},
+		"ADDD":       {"freg, freg", "freg, freg, freg"},
+		"ADDF":       {"freg, freg", "freg, freg, freg"},
+		"ADDU":       {"imm|reg, reg", "imm|reg, reg, reg"},
+		"ADDV":       {"imm|reg, reg", "imm|reg, reg, reg"},
+		"ADDVU":      {"imm|reg, reg", "imm|reg, reg, reg"},
+		"ADDW":       {"imm|reg, reg", "imm|reg, reg, reg"},
+		"ALSLV":      {"imm, reg, reg, reg"},
+		"ALSLW":      {"imm, reg, reg, reg"},
+		"ALSLWU":     {"imm, reg, reg, reg"},
+		"AMADDB":     {"reg, mem, reg"},
+		"AMADDDBB":   {"reg, mem, reg"},
+		"AMADDDBH":   {"reg, mem, reg"},
+		"AMADDDBV":   {"reg, mem, reg"},
+		"AMADDDBW":   {"reg, mem, reg"},
+		"AMADDH":     {"reg, mem, reg"},
+		"AMADDV":     {"reg, mem, reg"},
+		"AMADDW":     {"reg, mem, reg"},
+		"AMANDB":     {"reg, mem, reg"},
+		"AMANDDBB":   {"reg, mem, reg"},
+		"AMANDDBH":   {"reg, mem, reg"},
+		"AMANDDBV":   {"reg, mem, reg"},
+		"AMANDDBW":   {"reg, mem, reg"},
+		"AMANDH":     {"reg, mem, reg"},
+		"AMANDV":     {"reg, mem, reg"},
+		"AMANDW":     {"reg, mem, reg"},
+		"AMCASB":     {"reg, mem, reg"},
+		"AMCASDBB":   {"reg, mem, reg"},
+		"AMCASDBH":   {"reg, mem, reg"},
+		"AMCASDBV":   {"reg, mem, reg"},
+		"AMCASDBW":   {"reg, mem, reg"},
+		"AMCASH":     {"reg, mem, reg"},
+		"AMCASV":     {"reg, mem, reg"},
+		"AMCASW":     {"reg, mem, reg"},
+		"AMMAXB":     {"reg, mem, reg"},
+		"AMMAXBU":    {"reg, mem, reg"},
+		"AMMAXDBB":   {"reg, mem, reg"},
+		"AMMAXDBBU":  {"reg, mem, reg"},
+		"AMMAXDBH":   {"reg, mem, reg"},
+		"AMMAXDBHU":  {"reg, mem, reg"},
+		"AMMAXDBV":   {"reg, mem, reg"},
+		"AMMAXDBVU":  {"reg, mem, reg"},
+		"AMMAXDBW":   {"reg, mem, reg"},
+		"AMMAXDBWU":  {"reg, mem, reg"},
+		"AMMAXH":     {"reg, mem, reg"},
+		"AMMAXHU":    {"reg, mem, reg"},
+		"AMMAXV":     {"reg, mem, reg"},
+		"AMMAXVU":    {"reg, mem, reg"},
+		"AMMAXW":     {"reg, mem, reg"},
+		"AMMAXWU":    {"reg, mem, reg"},
+		"AMMINB":     {"reg, mem, reg"},
+		"AMMINBU":    {"reg, mem, reg"},
+		"AMMINDBB":   {"reg, mem, reg"},
+		"AMMINDBBU":  {"reg, mem, reg"},
+		"AMMINDBH":   {"reg, mem, reg"},
+		"AMMINDBHU":  {"reg, mem, reg"},
+		"AMMINDBV":   {"reg, mem, reg"},
+		"AMMINDBVU":  {"reg, mem, reg"},
+		"AMMINDBW":   {"reg, mem, reg"},
+		"AMMINDBWU":  {"reg, mem, reg"},
+		"AMMINH":     {"reg, mem, reg"},
+		"AMMINHU":    {"reg, mem, reg"},
+		"AMMINV":     {"reg, mem, reg"},
+		"AMMINVU":    {"reg, mem, reg"},
+		"AMMINW":     {"reg, mem, reg"},
+		"AMMINWU":    {"reg, mem, reg"},
+		"AMORB":      {"reg, mem, reg"},
+		"AMORDBB":    {"reg, mem, reg"},
+		"AMORDBH":    {"reg, mem, reg"},
+		"AMORDBV":    {"reg, mem, reg"},
+		"AMORDBW":    {"reg, mem, reg"},
+		"AMORH":      {"reg, mem, reg"},
+		"AMORV":      {"reg, mem, reg"},
+		"AMORW":      {"reg, mem, reg"},
+		"AMSWAPB":    {"reg, mem, reg"},
+		"AMSWAPDBB":  {"reg, mem, reg"},
+		"AMSWAPDBH":  {"reg, mem, reg"},
+		"AMSWAPDBV":  {"reg, mem, reg"},
+		"AMSWAPDBW":  {"reg, mem, reg"},
+		"AMSWAPH":    {"reg, mem, reg"},
+		"AMSWAPV":    {"reg, mem, reg"},
+		"AMSWAPW":    {"reg, mem, reg"},
+		"AMXORB":     {"reg, mem, reg"},
+		"AMXORDBB":   {"reg, mem, reg"},
+		"AMXORDBH":   {"reg, mem, reg"},
+		"AMXORDBV":   {"reg, mem, reg"},
+		"AMXORDBW":   {"reg, mem, reg"},
+		"AMXORH":     {"reg, mem, reg"},
+		"AMXORV":     {"reg, mem, reg"},
+		"AMXORW":     {"reg, mem, reg"},
+		"AND":        {"imm|reg, reg", "imm|reg, reg, reg"},
+		"ANDN":       {"imm|reg, reg", "imm|reg, reg, reg"},
+		"BEQ":        {"reg, label", "reg, reg, label"},
+		"BFPF":       {"label", "reg, label"},
+		"BFPT":       {"label", "reg, label"},
+		"BGE":        {"reg, label", "reg, reg, label"},
+		"BGEU":       {"reg, label", "reg, reg, label"},
+		"BGEZ":       {"reg, label"},
+		"BGTZ":       {"reg, label"},
+		"BITREV4B":   {"reg, reg"},
+		"BITREV8B":   {"reg, reg"},
+		"BITREVV":    {"reg, reg"},
+		"BITREVW":    {"reg, reg"},
+		"BLEZ":       {"reg, label"},
+		"BLT":        {"reg, label", "reg, reg, label"},
+		"BLTU":       {"reg, label", "reg, reg, label"},
+		"BLTZ":       {"reg, label"},
+		"BNE":        {"reg, label", "reg, reg, label"},
+		"BREAK":      {"*"},
+		"BYTE":       {"*"},
+		"CALL":       {"mem|reg|sym"},
+		"CLOV":       {"reg, reg"},
+		"CLOW":       {"reg, reg"},
+		"CLZV":       {"reg, reg"},
+		"CLZW":       {"reg, reg"},
+		"CMPEQD":     {"freg, freg, reg"},
+		"CMPEQF":     {"freg, freg, reg"},
+		"CMPGED":     {"freg, freg, reg"},
+		"CMPGEF":     {"freg, freg, reg"},
+		"CMPGTD":     {"freg, freg, reg"},
+		"CMPGTF":     {"freg, freg, reg"},
+		"CPUCFG":     {"addr|fp|freg|imm|label|mem|reg|sym, reg"},
+		"CRCCWBW":    {"reg, reg, reg"},
+		"CRCCWHW":    {"reg, reg, reg"},
+		"CRCCWVW":    {"reg, reg, reg"},
+		"CRCCWWW":    {"reg, reg, reg"},
+		"CRCWBW":     {"reg, reg, reg"},
+		"CRCWHW":     {"reg, reg, reg"},
+		"CRCWVW":     {"reg, reg, reg"},
+		"CRCWWW":     {"reg, reg, reg"},
+		"CTOV":       {"reg, reg"},
+		"CTOW":       {"reg, reg"},
+		"CTZV":       {"reg, reg"},
+		"CTZW":       {"reg, reg"},
+		"DBAR":       {"*"},
+		"DIV":        {"imm|reg, reg", "imm|reg, reg, reg"},
+		"DIVD":       {"freg, freg", "freg, freg, freg"},
+		"DIVF":       {"freg, freg", "freg, freg, freg"},
+		"DIVU":       {"imm|reg, reg", "imm|reg, reg, reg"},
+		"DIVV":       {"imm|reg, reg", "imm|reg, reg, reg"},
+		"DIVVU":      {"imm|reg, reg", "imm|reg, reg, reg"},
+		"DIVW":       {"imm|reg, reg", "imm|reg, reg, reg"},
+		"DIVWU":      {"imm|reg, reg", "imm|reg, reg, reg"},
+		"EXTWB":      {"reg, reg"},
+		"EXTWH":      {"reg, reg"},
+		"FCLASSD":    {"freg, freg"},
+		"FCLASSF":    {"freg, freg"},
+		"FCOPYSGD":   {"freg, freg", "freg, freg, freg"},
+		"FCOPYSGF":   {"freg, freg", "freg, freg, freg"},
+		"FFINTDV":    {"freg, freg"},
+		"FFINTDW":    {"freg, freg"},
+		"FFINTFV":    {"freg, freg"},
+		"FFINTFW":    {"freg, freg"},
+		"FMADDD":     {"freg, freg, freg, freg"},
+		"FMADDF":     {"freg, freg, freg, freg"},
+		"FMAXD":      {"freg, freg", "freg, freg, freg"},
+		"FMAXF":      {"freg, freg", "freg, freg, freg"},
+		"FMIND":      {"freg, freg", "freg, freg, freg"},
+		"FMINF":      {"freg, freg", "freg, freg, freg"},
+		"FMSUBD":     {"freg, freg, freg, freg"},
+		"FMSUBF":     {"freg, freg, freg, freg"},
+		"FNMADDD":    {"freg, freg, freg, freg"},
+		"FNMADDF":    {"freg, freg, freg, freg"},
+		"FNMSUBD":    {"freg, freg, freg, freg"},
+		"FNMSUBF":    {"freg, freg, freg, freg"},
+		"FRINTD":     {"freg, freg"},
+		"FRINTF":     {"freg, freg"},
+		"FTINTRMVD":  {"freg, freg"},
+		"FTINTRMVF":  {"freg, freg"},
+		"FTINTRMWD":  {"freg, freg"},
+		"FTINTRMWF":  {"freg, freg"},
+		"FTINTRNEVD": {"freg, freg"},
+		"FTINTRNEVF": {"freg, freg"},
+		"FTINTRNEWD": {"freg, freg"},
+		"FTINTRNEWF": {"freg, freg"},
+		"FTINTRPVD":  {"freg, freg"},
+		"FTINTRPVF":  {"freg, freg"},
+		"FTINTRPWD":  {"freg, freg"},
+		"FTINTRPWF":  {"freg, freg"},
+		"FTINTRZVD":  {"freg, freg"},
+		"FTINTRZVF":  {"freg, freg"},
+		"FTINTRZWD":  {"freg, freg"},
+		"FTINTRZWF":  {"freg, freg"},
+		"FTINTVD":    {"freg, freg"},
+		"FTINTVF":    {"freg, freg"},
+		"FTINTWD":    {"freg, freg"},
+		"FTINTWF":    {"freg, freg"},
+		"FUNCDATA":   {"*"},
+		"JAL":        {"mem|reg|sym"},
+		"JMP":        {"label|mem|reg|sym"},
+		"LL":         {"mem, reg"},
+		"LLV":        {"mem, reg"},
+		"LLW":        {"mem, reg"},
+		"MASKEQZ":    {"imm|reg, reg", "imm|reg, reg, reg"},
+		"MASKNEZ":    {"imm|reg, reg", "imm|reg, reg, reg"},
+		"MOVB":       {"addr|fp|imm|mem|reg|sym, reg", "reg, fp|mem|reg|sym"},
+		"MOVBU":      {"addr|fp|imm|mem|reg|sym, reg", "reg, fp|mem|reg|sym"},
+		"MOVD":       {"addr|fp|freg|mem|sym, freg", "freg, fp|freg|mem|sym"},
+		"MOVDF":      {"freg, freg"},
+		"MOVDV":      {"freg, freg"},
+		"MOVDW":      {"freg, freg"},
+		"MOVF":       {"addr|fp|freg|mem|sym, freg", "freg, fp|freg|mem|sym"},
+		"MOVFD":      {"freg, freg"},
+		"MOVFV":      {"freg, freg"},
+		"MOVFW":      {"freg, freg"},
+		"MOVH":       {"addr|fp|imm|mem|reg|sym, reg", "reg, fp|mem|reg|sym"},
+		"MOVHU":      {"addr|fp|imm|mem|reg|sym, reg", "reg, fp|mem|reg|sym"},
+		"MOVV":       {"addr|fp|freg|imm|mem|reg|sym, reg", "freg|reg, freg|reg", "reg, fp|freg|mem|reg|sym"},
+		"MOVVD":      {"freg, freg"},
+		"MOVVF":      {"freg, freg"},
+		"MOVVP":      {"addr|fp|imm|mem|reg|sym, reg", "reg, fp|mem|reg|sym"},
+		"MOVW":       {"addr|fp|freg|imm|mem|reg|sym, reg", "freg|reg, freg|reg", "reg, fp|freg|mem|reg|sym"},
+		"MOVWD":      {"freg, freg"},
+		"MOVWF":      {"freg, freg"},
+		"MOVWP":      {"addr|fp|imm|mem|reg|sym, reg", "reg, fp|mem|reg|sym"},
+		"MOVWU":      {"addr|fp|imm|mem|reg|sym, reg", "reg, fp|mem|reg|sym"},
+		"MUL":        {"imm|reg, reg", "imm|reg, reg, reg"},
+		"MULD":       {"freg, freg", "freg, freg, freg"},
+		"MULF":       {"freg, freg", "freg, freg, freg"},
+		"MULH":       {"imm|reg, reg", "imm|reg, reg, reg"},
+		"MULHU":      {"imm|reg, reg", "imm|reg, reg, reg"},
+		"MULHV":      {"imm|reg, reg", "imm|reg, reg, reg"},
+		"MULHVU":     {"imm|reg, reg", "imm|reg, reg, reg"},
+		"MULU":       {"imm|reg, reg", "imm|reg, reg, reg"},
+		"MULV":       {"imm|reg, reg", "imm|reg, reg, reg"},
+		"MULVU":      {"imm|reg, reg", "imm|reg, reg, reg"},
+		"MULW":       {"imm|reg, reg", "imm|reg, reg, reg"},
+		"MULWVW":     {"imm|reg, reg", "imm|reg, reg, reg"},
+		"MULWVWU":    {"imm|reg, reg", "imm|reg, reg, reg"},
+		"NEGD":       {"freg, freg"},
+		"NEGF":       {"freg, freg"},
+		"NEGV":       {"reg", "reg, reg"},
+		"NEGW":       {"reg", "reg, reg"},
+		"NOOP":       {"*"},
+		"NOP":        {"*"},
+		"NOR":        {"imm|reg, reg", "imm|reg, reg, reg"},
+		"OR":         {"imm|reg, reg", "imm|reg, reg, reg"},
+		"ORN":        {"imm|reg, reg", "imm|reg, reg, reg"},
+		"PCALIGN":    {"*"},
+		"PCDATA":     {"*"},
+		"PRELD":      {"*"},
+		"PRELDX":     {"*"},
+		"RDTIMED":    {"reg, reg"},
+		"RDTIMEHW":   {"reg, reg"},
+		"RDTIMELW":   {"reg, reg"},
+		"REM":        {"imm|reg, reg", "imm|reg, reg, reg"},
+		"REMU":       {"imm|reg, reg", "imm|reg, reg, reg"},
+		"REMV":       {"imm|reg, reg", "imm|reg, reg, reg"},
+		"REMVU":      {"imm|reg, reg", "imm|reg, reg, reg"},
+		"REMW":       {"imm|reg, reg", "imm|reg, reg, reg"},
+		"REMWU":      {"imm|reg, reg", "imm|reg, reg, reg"},
+		"RET":        {"*"},
+		"REVB2H":     {"reg, reg"},
+		"REVB2W":     {"reg, reg"},
+		"REVB4H":     {"reg, reg"},
+		"REVBV":      {"reg, reg"},
+		"REVH2W":     {"reg, reg"},
+		"REVHV":      {"reg, reg"},
+		"ROTR":       {"imm|reg, reg", "imm|reg, reg, reg"},
+		"ROTRV":      {"imm|reg, reg", "imm|reg, reg, reg"},
+		"SC":         {"reg, mem"},
+		"SCV":        {"reg, mem"},
+		"SCW":        {"reg, mem"},
+		"SGT":        {"imm|reg, reg", "imm|reg, reg, reg"},
+		"SGTU":       {"imm|reg, reg", "imm|reg, reg, reg"},
+		"SLL":        {"imm|reg, reg", "imm|reg, reg, reg"},
+		"SLLV":       {"imm|reg, reg", "imm|reg, reg, reg"},
+		"SQRTD":      {"freg, freg"},
+		"SQRTF":      {"freg, freg"},
+		"SRA":        {"imm|reg, reg", "imm|reg, reg, reg"},
+		"SRAV":       {"imm|reg, reg", "imm|reg, reg, reg"},
+		"SRL":        {"imm|reg, reg", "imm|reg, reg, reg"},
+		"SRLV":       {"imm|reg, reg", "imm|reg, reg, reg"},
+		"SUB":        {"imm|reg, reg", "imm|reg, reg, reg"},
+		"SUBD":       {"freg, freg", "freg, freg, freg"},
+		"SUBF":       {"freg, freg", "freg, freg, freg"},
+		"SUBU":       {"imm|reg, reg", "imm|reg, reg, reg"},
+		"SUBV":       {"imm|reg, reg", "imm|reg, reg, reg"},
+		"SUBVU":      {"imm|reg, reg", "imm|reg, reg, reg"},
+		"SUBW":       {"imm|reg, reg", "imm|reg, reg, reg"},
+		"SYSCALL":    {"*"},
+		"TRUNCDV":    {"freg, freg"},
+		"TRUNCDW":    {"freg, freg"},
+		"TRUNCFV":    {"freg, freg"},
+		"TRUNCFW":    {"freg, freg"},
+		"UNDEF":      {"*"},
+		"WORD":       {"*"},
+		"XOR":        {"imm|reg, reg", "imm|reg, reg, reg"},
+	},
+	ArchPPC64: {
+		"ADD":        {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"ADDC":       {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"ADDCC":      {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"ADDCCC":     {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"ADDE":       {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"ADDECC":     {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"ADDIS":      {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"ADDISCC":    {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"ADDME":      {"reg", "reg, reg"},
+		"ADDMECC":    {"reg", "reg, reg"},
+		"ADDZE":      {"reg", "reg, reg"},
+		"ADDZECC":    {"reg", "reg, reg"},
+		"AND":        {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"ANDCC":      {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"ANDIS":      {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"ANDISCC":    {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"ANDN":       {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"ANDNCC":     {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"BC":         {"imm, imm, label|reg|vreg"},
+		"BDNZ":       {"label|reg|vreg"},
+		"BDZ":        {"label|reg|vreg"},
+		"BEQ":        {"label|reg|vreg"},
+		"BGE":        {"label|reg|vreg"},
+		"BGT":        {"label|reg|vreg"},
+		"BL":         {"sym"},
+		"BLE":        {"label|reg|vreg"},
+		"BLT":        {"label|reg|vreg"},
+		"BNE":        {"label|reg|vreg"},
+		"BR":         {"label|reg|sym|vreg"},
+		"BRD":        {"reg", "reg, reg"},
+		"BRDCC":      {"reg", "reg, reg"},
+		"BRH":        {"reg", "reg, reg"},
+		"BRHCC":      {"reg", "reg, reg"},
+		"BRW":        {"reg", "reg, reg"},
+		"BRWCC":      {"reg", "reg, reg"},
+		"BVC":        {"label|reg|vreg"},
+		"BVS":        {"label|reg|vreg"},
+		"BYTE":       {"*"},
+		"CALL":       {"sym"},
+		"CMP":        {"imm|reg, reg", "reg, imm|reg"},
+		"CMPB":       {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"CMPBCC":     {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"CMPU":       {"imm|reg, reg", "reg, imm|reg"},
+		"CMPW":       {"imm|reg, reg", "reg, imm|reg"},
+		"CMPWU":      {"imm|reg, reg", "reg, imm|reg"},
+		"CNTLZD":     {"reg", "reg, reg"},
+		"CNTLZDCC":   {"reg", "reg, reg"},
+		"CNTLZW":     {"reg", "reg, reg"},
+		"CNTLZWCC":   {"reg", "reg, reg"},
+		"CNTTZD":     {"reg", "reg, reg"},
+		"CNTTZDCC":   {"reg", "reg, reg"},
+		"CNTTZW":     {"reg", "reg, reg"},
+		"CNTTZWCC":   {"reg", "reg, reg"},
+		"CRAND":      {"imm, imm, imm"},
+		"CRANDN":     {"imm, imm, imm"},
+		"CREQV":      {"imm, imm, imm"},
+		"CRNAND":     {"imm, imm, imm"},
+		"CRNOR":      {"imm, imm, imm"},
+		"CROR":       {"imm, imm, imm"},
+		"CRORN":      {"imm, imm, imm"},
+		"CRXOR":      {"imm, imm, imm"},
+		"DCBF":       {"*"},
+		"DCBST":      {"*"},
+		"DCBT":       {"*"},
+		"DCBTST":     {"*"},
+		"DCBZ":       {"mem"},
+		"DIVD":       {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"DIVDCC":     {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"DIVDU":      {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"DIVDUCC":    {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"DIVW":       {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"DIVWCC":     {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"DIVWU":      {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"DIVWUCC":    {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"EIEIO":      {"*"},
+		"EQV":        {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"EQVCC":      {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"EXTSB":      {"reg", "reg, reg"},
+		"EXTSBCC":    {"reg", "reg, reg"},
+		"EXTSH":      {"reg", "reg, reg"},
+		"EXTSHCC":    {"reg", "reg, reg"},
+		"EXTSW":      {"reg", "reg, reg"},
+		"EXTSWCC":    {"reg", "reg, reg"},
+		"EXTSWSLI":   {"imm, reg, reg"},
+		"EXTSWSLICC": {"imm, reg, reg"},
+		"FABS":       {"freg, freg"},
+		"FADD":       {"freg, freg", "freg, freg, freg"},
+		"FADDS":      {"freg, freg", "freg, freg, freg"},
+		"FCFID":      {"freg, freg"},
+		"FCFIDS":     {"freg, freg"},
+		"FCFIDU":     {"freg, freg"},
+		"FCFIDUS":    {"freg, freg"},
+		"FCMPO":      {"freg, freg"},
+		"FCMPU":      {"freg, freg"},
+		"FCPSGN":     {"freg, freg", "freg, freg, freg"},
+		"FCTID":      {"freg, freg"},
+		"FCTIDUZ":    {"freg, freg"},
+		"FCTIDZ":     {"freg, freg"},
+		"FCTIW":      {"freg, freg"},
+		"FCTIWUZ":    {"freg, freg"},
+		"FCTIWZ":     {"freg, freg"},
+		"FDIV":       {"freg, freg", "freg, freg, freg"},
+		"FDIVS":      {"freg, freg", "freg, freg, freg"},
+		"FMADD":      {"freg, freg, freg, freg"},
+		"FMADDS":     {"freg, freg, freg, freg"},
+		"FMOVD":      {"addr|fp|freg|imm|mem|sym, freg", "freg, fp|freg|mem|sym"},
+		"FMOVDU":     {"freg, mem", "mem, freg"},
+		"FMOVS":      {"addr|fp|freg|imm|mem|sym, freg", "freg, fp|freg|mem|sym"},
+		"FMOVSU":     {"freg, mem", "mem, freg"},
+		"FMOVSX":     {"addr|fp|freg|imm|mem|sym, freg", "freg, fp|freg|mem|sym"},
+		"FMOVSZ":     {"addr|fp|freg|imm|mem|sym, freg", "freg, fp|freg|mem|sym"},
+		"FMSUB":      {"freg, freg, freg, freg"},
+		"FMSUBS":     {"freg, freg, freg, freg"},
+		"FMUL":       {"freg, freg", "freg, freg, freg"},
+		"FMULS":      {"freg, freg", "freg, freg, freg"},
+		"FNABS":      {"freg, freg"},
+		"FNEG":       {"freg, freg"},
+		"FNMADD":     {"freg, freg, freg, freg"},
+		"FNMADDS":    {"freg, freg, freg, freg"},
+		"FNMSUB":     {"freg, freg, freg, freg"},
+		"FNMSUBS":    {"freg, freg, freg, freg"},
+		"FRES":       {"freg, freg"},
+		"FRIM":       {"freg, freg"},
+		"FRIN":       {"freg, freg"},
+		"FRIP":       {"freg, freg"},
+		"FRIZ":       {"freg, freg"},
+		"FRSP":       {"freg, freg"},
+		"FRSQRTE":    {"freg, freg"},
+		"FSEL":       {"freg, freg, freg, freg"},
+		"FSQRT":      {"freg, freg"},
+		"FSQRTS":     {"freg, freg"},
+		"FSUB":       {"freg, freg", "freg, freg, freg"},
+		"FSUBS":      {"freg, freg", "freg, freg, freg"},
+		"FUNCDATA":   {"*"},
+		"HWSYNC":     {"*"},
+		"ICBI":       {"*"},
+		"ISEL":       {"imm, reg, reg, reg"},
+		"ISYNC":      {"*"},
+		"JMP":        {"label|reg|sym|vreg"},
+		"LBAR":       {"mem, reg"},
+		"LDAR":       {"mem, reg"},
+		"LHAR":       {"mem, reg"},
+		"LVSL":       {"mem, freg|vreg"},
+		"LVSR":       {"mem, freg|vreg"},
+		"LWAR":       {"mem, reg"},
+		"LWSYNC":     {"*"},
+		"LXVL":       {"reg, reg, freg|vreg"},
+		"LXVLL":      {"reg, reg, freg|vreg"},
+		"MFFPRD":     {"freg|vreg, reg"},
+		"MFVRD":      {"freg|vreg, reg"},
+		"MFVSRD":     {"freg|vreg, reg"},
+		"MFVSRLD":    {"freg|vreg, reg"},
+		"MFVSRWZ":    {"freg|vreg, reg"},
+		"MODSD":      {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"MODSDCC":    {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"MODSW":      {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"MODSWCC":    {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"MODUD":      {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"MODUDCC":    {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"MODUW":      {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"MODUWCC":    {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"MOVB":       {"addr|fp|imm|mem|reg|sym, reg", "imm|reg, mem|reg", "reg, fp|mem|reg|sym"},
+		"MOVBU":      {"imm|reg, mem", "mem, reg"},
+		"MOVBZ":      {"addr|fp|imm|mem|reg|sym, reg", "imm|reg, mem|reg", "reg, fp|mem|reg|sym"},
+		"MOVBZU":     {"imm|reg, mem", "mem, reg"},
+		"MOVD":       {"addr|fp|imm|mem|reg|sym, reg", "imm|reg, mem|reg", "reg, fp|mem|reg|sym"},
+		"MOVDBR":     {"imm|reg, mem", "mem, reg"},
+		"MOVDU":      {"imm|reg, mem", "mem, reg"},
+		"MOVFL":      {"reg, imm"},
+		"MOVH":       {"addr|fp|imm|mem|reg|sym, reg", "imm|reg, mem|reg", "reg, fp|mem|reg|sym"},
+		"MOVHBR":     {"imm|reg, mem", "mem, reg"},
+		"MOVHU":      {"imm|reg, mem", "mem, reg"},
+		"MOVHZ":      {"addr|fp|imm|mem|reg|sym, reg", "imm|reg, mem|reg", "reg, fp|mem|reg|sym"},
+		"MOVHZU":     {"imm|reg, mem", "mem, reg"},
+		"MOVW":       {"addr|fp|imm|mem|reg|sym, reg", "imm|reg, mem|reg", "reg, fp|mem|reg|sym"},
+		"MOVWBR":     {"imm|reg, mem", "mem, reg"},
+		"MOVWU":      {"imm|reg, mem", "mem, reg"},
+		"MOVWZ":      {"addr|fp|imm|mem|reg|sym, reg", "imm|reg, mem|reg", "reg, fp|mem|reg|sym"},
+		"MOVWZU":     {"imm|reg, mem", "mem, reg"},
+		"MTFPRD":     {"reg, freg|vreg"},
+		"MTVRD":      {"reg, freg|vreg"},
+		"MTVSRD":     {"reg, freg|vreg"},
+		"MTVSRDD":    {"reg, reg, freg|vreg"},
+		"MTVSRWA":    {"reg, freg|vreg"},
+		"MTVSRWS":    {"reg, freg|vreg"},
+		"MTVSRWZ":    {"reg, freg|vreg"},
+		"MULHD":      {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"MULHDCC":    {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"MULHDU":     {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"MULHDUCC":   {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"MULHW":      {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"MULHWCC":    {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"MULHWU":     {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"MULHWUCC":   {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"MULLD":      {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"MULLDCC":    {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"MULLW":      {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"MULLWCC":    {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"NAND":       {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"NANDCC":     {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"NEG":        {"reg", "reg, reg"},
+		"NEGCC":      {"reg", "reg, reg"},
+		"NOOP":       {"*"},
+		"NOP":        {"*"},
+		"NOR":        {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"NORCC":      {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"OR":         {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"ORCC":       {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"ORIS":       {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"ORISCC":     {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"ORN":        {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"ORNCC":      {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"PCALIGN":    {"*"},
+		"PCDATA":     {"*"},
+		"POPCNTB":    {"reg", "reg, reg"},
+		"POPCNTBCC":  {"reg", "reg, reg"},
+		"POPCNTD":    {"reg", "reg, reg"},
+		"POPCNTDCC":  {"reg", "reg, reg"},
+		"POPCNTW":    {"reg", "reg, reg"},
+		"POPCNTWCC":  {"reg", "reg, reg"},
+		"RET":        {"*"},
+		"ROTL":       {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"ROTLCC":     {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"ROTLW":      {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"ROTLWCC":    {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"SLD":        {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"SLDCC":      {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"SLW":        {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"SLWCC":      {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"SRAD":       {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"SRADCC":     {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"SRAW":       {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"SRAWCC":     {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"SRD":        {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"SRDCC":      {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"SRW":        {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"SRWCC":      {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"STBCCC":     {"reg, mem"},
+		"STDCCC":     {"reg, mem"},
+		"STHCCC":     {"reg, mem"},
+		"STWCCC":     {"reg, mem"},
+		"STXVL":      {"freg|vreg, reg, reg"},
+		"STXVLL":     {"freg|vreg, reg, reg"},
+		"SUB":        {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"SUBC":       {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"SUBCC":      {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"SUBCCC":     {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"SUBE":       {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"SUBECC":     {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"SUBME":      {"reg", "reg, reg"},
+		"SUBMECC":    {"reg", "reg, reg"},
+		"SUBZE":      {"reg", "reg, reg"},
+		"SUBZECC":    {"reg", "reg, reg"},
+		"SYNC":       {"*"},
+		"SYSCALL":    {"", "imm|reg"},
+		"TD":         {"imm, reg, imm|reg"},
+		"TW":         {"imm, reg, imm|reg"},
+		"UNDEF":      {"*"},
+		"VADDCUQ":    {"freg|vreg, freg|vreg, freg|vreg"},
+		"VADDECUQ":   {"freg|vreg, freg|vreg, freg|vreg, freg|vreg"},
+		"VADDEUQM":   {"freg|vreg, freg|vreg, freg|vreg, freg|vreg"},
+		"VADDUBM":    {"freg|vreg, freg|vreg, freg|vreg"},
+		"VADDUDM":    {"freg|vreg, freg|vreg, freg|vreg"},
+		"VADDUHM":    {"freg|vreg, freg|vreg, freg|vreg"},
+		"VADDUQM":    {"freg|vreg, freg|vreg, freg|vreg"},
+		"VADDUWM":    {"freg|vreg, freg|vreg, freg|vreg"},
+		"VAND":       {"freg|vreg, freg|vreg, freg|vreg"},
+		"VANDC":      {"freg|vreg, freg|vreg, freg|vreg"},
+		"VBPERMQ":    {"freg|vreg, freg|vreg, freg|vreg"},
+		"VCLZB":      {"freg|vreg, freg|vreg"},
+		"VCLZD":      {"freg|vreg, freg|vreg"},
+		"VCLZH":      {"freg|vreg, freg|vreg"},
+		"VCLZW":      {"freg|vreg, freg|vreg"},
+		"VCMPEQUB":   {"freg|vreg, freg|vreg, freg|vreg"},
+		"VCMPEQUBCC": {"freg|vreg, freg|vreg, freg|vreg"},
+		"VCMPEQUD":   {"freg|vreg, freg|vreg, freg|vreg"},
+		"VCMPEQUDCC": {"freg|vreg, freg|vreg, freg|vreg"},
+		"VCMPEQUH":   {"freg|vreg, freg|vreg, freg|vreg"},
+		"VCMPEQUHCC": {"freg|vreg, freg|vreg, freg|vreg"},
+		"VCMPEQUW":   {"freg|vreg, freg|vreg, freg|vreg"},
+		"VCMPEQUWCC": {"freg|vreg, freg|vreg, freg|vreg"},
+		"VCMPGTSB":   {"freg|vreg, freg|vreg, freg|vreg"},
+		"VCMPGTSBCC": {"freg|vreg, freg|vreg, freg|vreg"},
+		"VCMPGTSD":   {"freg|vreg, freg|vreg, freg|vreg"},
+		"VCMPGTSDCC": {"freg|vreg, freg|vreg, freg|vreg"},
+		"VCMPGTSH":   {"freg|vreg, freg|vreg, freg|vreg"},
+		"VCMPGTSHCC": {"freg|vreg, freg|vreg, freg|vreg"},
+		"VCMPGTSW":   {"freg|vreg, freg|vreg, freg|vreg"},
+		"VCMPGTSWCC": {"freg|vreg, freg|vreg, freg|vreg"},
+		"VCMPGTUB":   {"freg|vreg, freg|vreg, freg|vreg"},
+		"VCMPGTUBCC": {"freg|vreg, freg|vreg, freg|vreg"},
+		"VCMPGTUD":   {"freg|vreg, freg|vreg, freg|vreg"},
+		"VCMPGTUDCC": {"freg|vreg, freg|vreg, freg|vreg"},
+		"VCMPGTUH":   {"freg|vreg, freg|vreg, freg|vreg"},
+		"VCMPGTUHCC": {"freg|vreg, freg|vreg, freg|vreg"},
+		"VCMPGTUW":   {"freg|vreg, freg|vreg, freg|vreg"},
+		"VCMPGTUWCC": {"freg|vreg, freg|vreg, freg|vreg"},
+		"VCTZB":      {"freg|vreg, freg|vreg"},
+		"VCTZD":      {"freg|vreg, freg|vreg"},
+		"VCTZH":      {"freg|vreg, freg|vreg"},
+		"VCTZW":      {"freg|vreg, freg|vreg"},
+		"VEQV":       {"freg|vreg, freg|vreg, freg|vreg"},
+		"VNAND":      {"freg|vreg, freg|vreg, freg|vreg"},
+		"VNOR":       {"freg|vreg, freg|vreg, freg|vreg"},
+		"VOR":        {"freg|vreg, freg|vreg, freg|vreg"},
+		"VORC":       {"freg|vreg, freg|vreg, freg|vreg"},
+		"VPERM":      {"freg|vreg, freg|vreg, freg|vreg, freg|vreg"},
+		"VPOPCNTB":   {"freg|vreg, freg|vreg"},
+		"VPOPCNTD":   {"freg|vreg, freg|vreg"},
+		"VPOPCNTH":   {"freg|vreg, freg|vreg"},
+		"VPOPCNTW":   {"freg|vreg, freg|vreg"},
+		"VSL":        {"freg|vreg, freg|vreg, freg|vreg"},
+		"VSLO":       {"freg|vreg, freg|vreg, freg|vreg"},
+		"VSR":        {"freg|vreg, freg|vreg, freg|vreg"},
+		"VSRO":       {"freg|vreg, freg|vreg, freg|vreg"},
+		"VSUBCUQ":    {"freg|vreg, freg|vreg, freg|vreg"},
+		"VSUBECUQ":   {"freg|vreg, freg|vreg, freg|vreg, freg|vreg"},
+		"VSUBEUQM":   {"freg|vreg, freg|vreg, freg|vreg, freg|vreg"},
+		"VSUBUBM":    {"freg|vreg, freg|vreg, freg|vreg"},
+		"VSUBUDM":    {"freg|vreg, freg|vreg, freg|vreg"},
+		"VSUBUHM":    {"freg|vreg, freg|vreg, freg|vreg"},
+		"VSUBUQM":    {"freg|vreg, freg|vreg, freg|vreg"},
+		"VSUBUWM":    {"freg|vreg, freg|vreg, freg|vreg"},
+		"VXOR":       {"freg|vreg, freg|vreg, freg|vreg"},
+		"WORD":       {"*"},
+		"XOR":        {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"XORCC":      {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"XORIS":      {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"XORISCC":    {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"XXBRD":      {"freg|vreg, freg|vreg"},
+		"XXBRH":      {"freg|vreg, freg|vreg"},
+		"XXBRQ":      {"freg|vreg, freg|vreg"},
+		"XXBRW":      {"freg|vreg, freg|vreg"},
+		"XXLAND":     {"freg|vreg, freg|vreg, freg|vreg"},
+		"XXLANDC":    {"freg|vreg, freg|vreg, freg|vreg"},
+		"XXLEQV":     {"freg|vreg, freg|vreg, freg|vreg"},
+		"XXLNAND":    {"freg|vreg, freg|vreg, freg|vreg"},
+		"XXLNOR":     {"freg|vreg, freg|vreg, freg|vreg"},
+		"XXLOR":      {"freg|vreg, freg|vreg, freg|vreg"},
+		"XXLORC":     {"freg|vreg, freg|vreg, freg|vreg"},
+		"XXLXOR":     {"freg|vreg, freg|vreg, freg|vreg"},
+	},
+	ArchS390X: {
+		"ADD":      {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"ADDC":     {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"ADDE":     {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"ADDW":     {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"AND":      {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"ANDW":     {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"BC":       {"imm, label"},
+		"BEQ":      {"label"},
+		"BGE":      {"label"},
+		"BGT":      {"label"},
+		"BL":       {"mem|reg|sym"},
+		"BLE":      {"label"},
+		"BLEU":     {"label"},
+		"BLT":      {"label"},
+		"BLTU":     {"label"},
+		"BNE":      {"label"},
+		"BR":       {"label|mem|reg|sym"},
+		"BRC":      {"imm, label"},
+		"BRCT":     {"reg, label"},
+		"BRCTG":    {"reg, label"},
+		"BRRK":     {"*"},
+		"BVC":      {"label"},
+		"BVS":      {"label"},
+		"CALL":     {"mem|reg|sym"},
+		"CDFBRA":   {"reg, freg"},
+		"CDGBRA":   {"reg, freg"},
+		"CDLFBR":   {"reg, freg"},
+		"CDLGBR":   {"reg, freg"},
+		"CEBR":     {"freg, freg"},
+		"CEFBRA":   {"reg, freg"},
+		"CEGBRA":   {"reg, freg"},
+		"CELFBR":   {"reg, freg"},
+		"CELGBR":   {"reg, freg"},
+		"CFDBRA":   {"freg, reg"},
+		"CFEBRA":   {"freg, reg"},
+		"CGDBRA":   {"freg, reg"},
+		"CGEBRA":   {"freg, reg"},
+		"CGIJ":     {"imm, addr|freg|imm|reg, addr|freg|imm|reg, label"},
+		"CGRJ":     {"imm, addr|freg|imm|reg, addr|freg|imm|reg, label"},
+		"CIJ":      {"imm, addr|freg|imm|reg, addr|freg|imm|reg, label"},
+		"CLC":      {"imm, mem, mem"},
+		"CLFDBR":   {"freg, reg"},
+		"CLFEBR":   {"freg, reg"},
+		"CLGDBR":   {"freg, reg"},
+		"CLGEBR":   {"freg, reg"},
+		"CLGIJ":    {"imm, addr|freg|imm|reg, addr|freg|imm|reg, label"},
+		"CLGRJ":    {"imm, addr|freg|imm|reg, addr|freg|imm|reg, label"},
+		"CLIJ":     {"imm, addr|freg|imm|reg, addr|freg|imm|reg, label"},
+		"CLRJ":     {"imm, addr|freg|imm|reg, addr|freg|imm|reg, label"},
+		"CMP":      {"reg, addr|freg|imm|reg"},
+		"CMPBEQ":   {"addr|freg|imm|reg, addr|freg|imm|reg, label"},
+		"CMPBGE":   {"addr|freg|imm|reg, addr|freg|imm|reg, label"},
+		"CMPBGT":   {"addr|freg|imm|reg, addr|freg|imm|reg, label"},
+		"CMPBLE":   {"addr|freg|imm|reg, addr|freg|imm|reg, label"},
+		"CMPBLT":   {"addr|freg|imm|reg, addr|freg|imm|reg, label"},
+		"CMPBNE":   {"addr|freg|imm|reg, addr|freg|imm|reg, label"},
+		"CMPU":     {"reg, addr|freg|imm|reg"},
+		"CMPUBEQ":  {"addr|freg|imm|reg, addr|freg|imm|reg, label"},
+		"CMPUBGE":  {"addr|freg|imm|reg, addr|freg|imm|reg, label"},
+		"CMPUBGT":  {"addr|freg|imm|reg, addr|freg|imm|reg, label"},
+		"CMPUBLE":  {"addr|freg|imm|reg, addr|freg|imm|reg, label"},
+		"CMPUBLT":  {"addr|freg|imm|reg, addr|freg|imm|reg, label"},
+		"CMPUBNE":  {"addr|freg|imm|reg, addr|freg|imm|reg, label"},
+		"CMPW":     {"reg, addr|freg|imm|reg"},
+		"CMPWBEQ":  {"addr|freg|imm|reg, addr|freg|imm|reg, label"},
+		"CMPWBGE":  {"addr|freg|imm|reg, addr|freg|imm|reg, label"},
+		"CMPWBGT":  {"addr|freg|imm|reg, addr|freg|imm|reg, label"},
+		"CMPWBLE":  {"addr|freg|imm|reg, addr|freg|imm|reg, label"},
+		"CMPWBLT":  {"addr|freg|imm|reg, addr|freg|imm|reg, label"},
+		"CMPWBNE":  {"addr|freg|imm|reg, addr|freg|imm|reg, label"},
+		"CMPWU":    {"reg, addr|freg|imm|reg"},
+		"CMPWUBEQ": {"addr|freg|imm|reg, addr|freg|imm|reg, label"},
+		"CMPWUBGE": {"addr|freg|imm|reg, addr|freg|imm|reg, label"},
+		"CMPWUBGT": {"addr|freg|imm|reg, addr|freg|imm|reg, label"},
+		"CMPWUBLE": {"addr|freg|imm|reg, addr|freg|imm|reg, label"},
+		"CMPWUBLT": {"addr|freg|imm|reg, addr|freg|imm|reg, label"},
+		"CMPWUBNE": {"addr|freg|imm|reg, addr|freg|imm|reg, label"},
+		"CPSDR":    {"freg, freg, freg"},
+		"CRJ":      {"imm, addr|freg|imm|reg, addr|freg|imm|reg, label"},
+		"CS":       {"reg, reg, mem"},
+		"CSG":      {"reg, reg, mem"},
+		"CSY":      {"reg, reg, mem"},
+		"DIVD":     {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"DIVDU":    {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"DIVW":     {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"DIVWU":    {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"FABS":     {"freg", "freg, freg"},
+		"FADD":     {"freg, freg"},
+		"FADDS":    {"freg, freg"},
+		"FCMPO":    {"freg, freg"},
+		"FCMPU":    {"freg, freg"},
+		"FDIV":     {"freg, freg"},
+		"FDIVS":    {"freg, freg"},
+		"FIDBR":    {"imm, freg, freg"},
+		"FIEBR":    {"imm, freg, freg"},
+		"FLOGR":    {"reg, reg"},
+		"FMADD":    {"freg, freg, freg"},
+		"FMADDS":   {"freg, freg, freg"},
+		"FMOVD":    {"addr|fp|freg|imm|mem|sym, freg", "freg, fp|freg|mem|sym"},
+		"FMOVS":    {"addr|fp|freg|imm|mem|sym, freg", "freg, fp|freg|mem|sym"},
+		"FMSUB":    {"freg, freg, freg"},
+		"FMSUBS":   {"freg, freg, freg"},
+		"FMUL":     {"freg, freg"},
+		"FMULS":    {"freg, freg"},
+		"FNABS":    {"freg", "freg, freg"},
+		"FNEG":     {"freg", "freg, freg"},
+		"FNEGS":    {"freg", "freg, freg"},
+		"FSQRT":    {"freg", "freg, freg"},
+		"FSQRTS":   {"freg", "freg, freg"},
+		"FSUB":     {"freg, freg"},
+		"FSUBS":    {"freg, freg"},
+		"FUNCDATA": {"*"},
+		"IPM":      {"reg"},
+		"JMP":      {"label|mem|reg|sym"},
+		"KEBR":     {"freg, freg"},
+		"LA":       {"mem, reg"},
+		"LAA":      {"reg, reg, mem"},
+		"LAAG":     {"reg, reg, mem"},
+		"LAAL":     {"reg, reg, mem"},
+		"LAALG":    {"reg, reg, mem"},
+		"LAN":      {"reg, reg, mem"},
+		"LANG":     {"reg, reg, mem"},
+		"LAO":      {"reg, reg, mem"},
+		"LAOG":     {"reg, reg, mem"},
+		"LAX":      {"reg, reg, mem"},
+		"LAXG":     {"reg, reg, mem"},
+		"LAY":      {"mem, reg"},
+		"LCDBR":    {"freg", "freg, freg"},
+		"LDEBR":    {"freg, freg"},
+		"LDGR":     {"freg|reg, freg|reg"},
+		"LEDBR":    {"freg, freg"},
+		"LGDR":     {"freg|reg, freg|reg"},
+		"LMG":      {"fp|mem, reg, reg"},
+		"LMY":      {"mem, reg, reg"},
+		"LNDFR":    {"freg", "freg, freg"},
+		"LOCGR":    {"imm, addr|freg|imm|reg, reg"},
+		"LOCR":     {"imm, addr|freg|imm|reg, reg"},
+		"LPDFR":    {"freg", "freg, freg"},
+		"LTDBR":    {"freg", "freg, freg"},
+		"LTEBR":    {"freg", "freg, freg"},
+		"MLGR":     {"reg, reg"},
+		"MODD":     {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"MODDU":    {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"MODW":     {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"MODWU":    {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"MOVB":     {"addr|fp|imm|mem|reg|sym, reg", "imm|reg, fp|mem|reg|sym"},
+		"MOVBZ":    {"addr|fp|imm|mem|reg|sym, reg", "imm|reg, fp|mem|reg|sym"},
+		"MOVD":     {"addr|fp|imm|mem|reg|sym, reg", "imm|reg, fp|mem|reg|sym"},
+		"MOVDBR":   {"addr|fp|imm|mem|reg|sym, reg", "imm|reg, fp|mem|reg|sym"},
+		"MOVDEQ":   {"addr|freg|imm|reg, reg"},
+		"MOVDGE":   {"addr|freg|imm|reg, reg"},
+		"MOVDGT":   {"addr|freg|imm|reg, reg"},
+		"MOVDLE":   {"addr|freg|imm|reg, reg"},
+		"MOVDLT":   {"addr|freg|imm|reg, reg"},
+		"MOVDNE":   {"addr|freg|imm|reg, reg"},
+		"MOVH":     {"addr|fp|imm|mem|reg|sym, reg", "imm|reg, fp|mem|reg|sym"},
+		"MOVHBR":   {"addr|fp|imm|mem|reg|sym, reg", "imm|reg, fp|mem|reg|sym"},
+		"MOVHZ":    {"addr|fp|imm|mem|reg|sym, reg", "imm|reg, fp|mem|reg|sym"},
+		"MOVW":     {"addr|fp|imm|mem|reg|sym, reg", "imm|reg, fp|mem|reg|sym"},
+		"MOVWBR":   {"addr|fp|imm|mem|reg|sym, reg", "imm|reg, fp|mem|reg|sym"},
+		"MOVWZ":    {"addr|fp|imm|mem|reg|sym, reg", "imm|reg, fp|mem|reg|sym"},
+		"MULHD":    {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"MULHDU":   {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"MULLD":    {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"MULLW":    {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"MVC":      {"imm, mem, mem"},
+		"NC":       {"imm, mem, mem"},
+		"NEG":      {"reg", "reg, reg"},
+		"NEGW":     {"reg", "reg, reg"},
+		"NOOP":     {"*"},
+		"NOP":      {"*"},
+		"OC":       {"imm, mem, mem"},
+		"OR":       {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"ORW":      {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"PCALIGN":  {"*"},
+		"PCDATA":   {"*"},
+		"POPCNT":   {"reg, reg"},
+		"RET":      {"*"},
+		"RLL":      {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"RLLG":     {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"SLD":      {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"SLW":      {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"SRAD":     {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"SRAW":     {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"SRD":      {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"SRW":      {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"STMG":     {"reg, reg, fp|mem"},
+		"STMY":     {"reg, reg, mem"},
+		"SUB":      {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"SUBC":     {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"SUBE":     {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"SUBW":     {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"SYNC":     {"*"},
+		"SYSCALL":  {"", "imm"},
+		"TMHH":     {"reg, imm"},
+		"TMHL":     {"reg, imm"},
+		"TMLH":     {"reg, imm"},
+		"TMLL":     {"reg, imm"},
+		"UNDEF":    {"*"},
+		"VAB":      {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VACCB":    {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VACCCQ":   {"freg|vreg, freg|vreg, freg|vreg, freg|vreg"},
+		"VACCF":    {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VACCG":    {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VACCH":    {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VACCQ":    {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VACQ":     {"freg|vreg, freg|vreg, freg|vreg, freg|vreg"},
+		"VAF":      {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VAG":      {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VAH":      {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VAQ":      {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VCEQB":    {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VCEQBS":   {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VCEQF":    {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VCEQFS":   {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VCEQG":    {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VCEQGS":   {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VCEQH":    {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VCEQHS":   {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VCHB":     {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VCHBS":    {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VCHF":     {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VCHFS":    {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VCHG":     {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VCHGS":    {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VCHH":     {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VCHHS":    {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VCHLB":    {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VCHLBS":   {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VCHLF":    {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VCHLFS":   {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VCHLG":    {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VCHLGS":   {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VCHLH":    {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VCHLHS":   {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VCLZB":    {"freg|vreg, freg|vreg"},
+		"VCLZF":    {"freg|vreg, freg|vreg"},
+		"VCLZG":    {"freg|vreg, freg|vreg"},
+		"VCLZH":    {"freg|vreg, freg|vreg"},
+		"VCTZB":    {"freg|vreg, freg|vreg"},
+		"VCTZF":    {"freg|vreg, freg|vreg"},
+		"VCTZG":    {"freg|vreg, freg|vreg"},
+		"VCTZH":    {"freg|vreg, freg|vreg"},
+		"VERIMB":   {"imm, freg|vreg, freg|vreg, freg|vreg"},
+		"VERIMF":   {"imm, freg|vreg, freg|vreg, freg|vreg"},
+		"VERIMG":   {"imm, freg|vreg, freg|vreg, freg|vreg"},
+		"VERIMH":   {"imm, freg|vreg, freg|vreg, freg|vreg"},
+		"VERLLB":   {"imm|reg, freg|vreg", "imm|reg, freg|vreg, freg|vreg"},
+		"VERLLF":   {"imm|reg, freg|vreg", "imm|reg, freg|vreg, freg|vreg"},
+		"VERLLG":   {"imm|reg, freg|vreg", "imm|reg, freg|vreg, freg|vreg"},
+		"VERLLH":   {"imm|reg, freg|vreg", "imm|reg, freg|vreg, freg|vreg"},
+		"VERLLVB":  {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VERLLVF":  {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VERLLVG":  {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VERLLVH":  {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VESLB":    {"imm|reg, freg|vreg", "imm|reg, freg|vreg, freg|vreg"},
+		"VESLF":    {"imm|reg, freg|vreg", "imm|reg, freg|vreg, freg|vreg"},
+		"VESLG":    {"imm|reg, freg|vreg", "imm|reg, freg|vreg, freg|vreg"},
+		"VESLH":    {"imm|reg, freg|vreg", "imm|reg, freg|vreg, freg|vreg"},
+		"VESLVB":   {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VESLVF":   {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VESLVG":   {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VESLVH":   {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VESRAB":   {"imm|reg, freg|vreg", "imm|reg, freg|vreg, freg|vreg"},
+		"VESRAF":   {"imm|reg, freg|vreg", "imm|reg, freg|vreg, freg|vreg"},
+		"VESRAG":   {"imm|reg, freg|vreg", "imm|reg, freg|vreg, freg|vreg"},
+		"VESRAH":   {"imm|reg, freg|vreg", "imm|reg, freg|vreg, freg|vreg"},
+		"VESRAVB":  {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VESRAVF":  {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VESRAVG":  {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VESRAVH":  {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VESRLB":   {"imm|reg, freg|vreg", "imm|reg, freg|vreg, freg|vreg"},
+		"VESRLF":   {"imm|reg, freg|vreg", "imm|reg, freg|vreg, freg|vreg"},
+		"VESRLG":   {"imm|reg, freg|vreg", "imm|reg, freg|vreg, freg|vreg"},
+		"VESRLH":   {"imm|reg, freg|vreg", "imm|reg, freg|vreg, freg|vreg"},
+		"VESRLVB":  {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VESRLVF":  {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VESRLVG":  {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VESRLVH":  {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VFADB":    {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VFCEDB":   {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VFCEDBS":  {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VFCHDB":   {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VFCHDBS":  {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VFCHEDB":  {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VFCHEDBS": {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VFDDB":    {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VFEEB":    {"freg|vreg, freg|vreg, freg|vreg"},
+		"VFEEBS":   {"freg|vreg, freg|vreg, freg|vreg"},
+		"VFEEF":    {"freg|vreg, freg|vreg, freg|vreg"},
+		"VFEEFS":   {"freg|vreg, freg|vreg, freg|vreg"},
+		"VFEEH":    {"freg|vreg, freg|vreg, freg|vreg"},
+		"VFEEHS":   {"freg|vreg, freg|vreg, freg|vreg"},
+		"VFLCDB":   {"freg|vreg, freg|vreg"},
+		"VFLNDB":   {"freg|vreg, freg|vreg"},
+		"VFLPDB":   {"freg|vreg, freg|vreg"},
+		"VFMADB":   {"freg|vreg, freg|vreg, freg|vreg, freg|vreg"},
+		"VFMDB":    {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VFMSDB":   {"freg|vreg, freg|vreg, freg|vreg, freg|vreg"},
+		"VFSDB":    {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VFSQDB":   {"freg|vreg, freg|vreg"},
+		"VGBM":     {"imm, freg|vreg"},
+		"VGMB":     {"imm, imm, freg|vreg"},
+		"VGMF":     {"imm, imm, freg|vreg"},
+		"VGMG":     {"imm, imm, freg|vreg"},
+		"VGMH":     {"imm, imm, freg|vreg"},
+		"VL":       {"mem, freg|vreg"},
+		"VLCB":     {"freg|vreg, freg|vreg"},
+		"VLCF":     {"freg|vreg, freg|vreg"},
+		"VLCG":     {"freg|vreg, freg|vreg"},
+		"VLCH":     {"freg|vreg, freg|vreg"},
+		"VLEIB":    {"imm, imm, freg|vreg"},
+		"VLEIF":    {"imm, imm, freg|vreg"},
+		"VLEIG":    {"imm, imm, freg|vreg"},
+		"VLEIH":    {"imm, imm, freg|vreg"},
+		"VLGVB":    {"imm|reg, freg|vreg, reg"},
+		"VLGVF":    {"imm|reg, freg|vreg, reg"},
+		"VLGVG":    {"imm|reg, freg|vreg, reg"},
+		"VLGVH":    {"imm|reg, freg|vreg, reg"},
+		"VLM":      {"mem, vreg, vreg"},
+		"VLPB":     {"freg|vreg, freg|vreg"},
+		"VLPF":     {"freg|vreg, freg|vreg"},
+		"VLPG":     {"freg|vreg, freg|vreg"},
+		"VLPH":     {"freg|vreg, freg|vreg"},
+		"VLR":      {"freg|vreg, freg|vreg"},
+		"VLREPB":   {"fp|mem, freg|vreg"},
+		"VLREPF":   {"fp|mem, freg|vreg"},
+		"VLREPG":   {"fp|mem, freg|vreg"},
+		"VLREPH":   {"fp|mem, freg|vreg"},
+		"VLVGB":    {"imm|reg, reg, freg|vreg"},
+		"VLVGF":    {"imm|reg, reg, freg|vreg"},
+		"VLVGG":    {"imm|reg, reg, freg|vreg"},
+		"VLVGH":    {"imm|reg, reg, freg|vreg"},
+		"VLVGP":    {"reg, reg, freg|vreg"},
+		"VMAEB":    {"freg|vreg, freg|vreg, freg|vreg, freg|vreg"},
+		"VMAEF":    {"freg|vreg, freg|vreg, freg|vreg, freg|vreg"},
+		"VMAEH":    {"freg|vreg, freg|vreg, freg|vreg, freg|vreg"},
+		"VMAHB":    {"freg|vreg, freg|vreg, freg|vreg, freg|vreg"},
+		"VMAHF":    {"freg|vreg, freg|vreg, freg|vreg, freg|vreg"},
+		"VMAHH":    {"freg|vreg, freg|vreg, freg|vreg, freg|vreg"},
+		"VMALB":    {"freg|vreg, freg|vreg, freg|vreg, freg|vreg"},
+		"VMALEB":   {"freg|vreg, freg|vreg, freg|vreg, freg|vreg"},
+		"VMALEF":   {"freg|vreg, freg|vreg, freg|vreg, freg|vreg"},
+		"VMALEH":   {"freg|vreg, freg|vreg, freg|vreg, freg|vreg"},
+		"VMALF":    {"freg|vreg, freg|vreg, freg|vreg, freg|vreg"},
+		"VMALHB":   {"freg|vreg, freg|vreg, freg|vreg, freg|vreg"},
+		"VMALHF":   {"freg|vreg, freg|vreg, freg|vreg, freg|vreg"},
+		"VMALHH":   {"freg|vreg, freg|vreg, freg|vreg, freg|vreg"},
+		"VMALHW":   {"freg|vreg, freg|vreg, freg|vreg, freg|vreg"},
+		"VMALOB":   {"freg|vreg, freg|vreg, freg|vreg, freg|vreg"},
+		"VMALOF":   {"freg|vreg, freg|vreg, freg|vreg, freg|vreg"},
+		"VMALOH":   {"freg|vreg, freg|vreg, freg|vreg, freg|vreg"},
+		"VMAOB":    {"freg|vreg, freg|vreg, freg|vreg, freg|vreg"},
+		"VMAOF":    {"freg|vreg, freg|vreg, freg|vreg, freg|vreg"},
+		"VMAOH":    {"freg|vreg, freg|vreg, freg|vreg, freg|vreg"},
+		"VMEB":     {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VMEF":     {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VMEH":     {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VMHB":     {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VMHF":     {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VMHH":     {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VMLB":     {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VMLEB":    {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VMLEF":    {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VMLEH":    {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VMLF":     {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VMLHB":    {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VMLHF":    {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VMLHH":    {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VMLHW":    {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VMLOB":    {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VMLOF":    {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VMLOH":    {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VMNB":     {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VMNF":     {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VMNG":     {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VMNH":     {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VMNLB":    {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VMNLF":    {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VMNLG":    {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VMNLH":    {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VMOB":     {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VMOF":     {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VMOH":     {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VMRHB":    {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VMRHF":    {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VMRHG":    {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VMRHH":    {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VMRLB":    {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VMRLF":    {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VMRLG":    {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VMRLH":    {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VMXB":     {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VMXF":     {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VMXG":     {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VMXH":     {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VMXLB":    {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VMXLF":    {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VMXLG":    {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VMXLH":    {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VN":       {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VNC":      {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VNN":      {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VNO":      {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VNOT":     {"freg|vreg, freg|vreg"},
+		"VNX":      {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VO":       {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VOC":      {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VONE":     {"freg|vreg"},
+		"VPDI":     {"imm, freg|vreg, freg|vreg, freg|vreg"},
+		"VPERM":    {"freg|vreg, freg|vreg, freg|vreg, freg|vreg"},
+		"VREPB":    {"imm, freg|vreg, freg|vreg"},
+		"VREPF":    {"imm, freg|vreg, freg|vreg"},
+		"VREPG":    {"imm, freg|vreg, freg|vreg"},
+		"VREPH":    {"imm, freg|vreg, freg|vreg"},
+		"VREPIB":   {"imm, freg|vreg"},
+		"VREPIF":   {"imm, freg|vreg"},
+		"VREPIG":   {"imm, freg|vreg"},
+		"VREPIH":   {"imm, freg|vreg"},
+		"VSB":      {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VSBCBIQ":  {"freg|vreg, freg|vreg, freg|vreg, freg|vreg"},
+		"VSBIQ":    {"freg|vreg, freg|vreg, freg|vreg, freg|vreg"},
+		"VSCBIB":   {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VSCBIF":   {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VSCBIG":   {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VSCBIH":   {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VSCBIQ":   {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VSEL":     {"freg|vreg, freg|vreg, freg|vreg, freg|vreg"},
+		"VSF":      {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VSG":      {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VSH":      {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VSL":      {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VSLB":     {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VSLDB":    {"imm, freg|vreg, freg|vreg, freg|vreg"},
+		"VSQ":      {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VSRA":     {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VSRAB":    {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VSRL":     {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VSRLB":    {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VST":      {"freg|vreg, mem"},
+		"VSTM":     {"vreg, vreg, mem"},
+		"VSUMB":    {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VSUMGF":   {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VSUMGH":   {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VSUMH":    {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VSUMQF":   {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VSUMQG":   {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VUPHB":    {"freg|vreg, freg|vreg"},
+		"VUPHF":    {"freg|vreg, freg|vreg"},
+		"VUPHH":    {"freg|vreg, freg|vreg"},
+		"VUPLB":    {"freg|vreg, freg|vreg"},
+		"VUPLF":    {"freg|vreg, freg|vreg"},
+		"VUPLHB":   {"freg|vreg, freg|vreg"},
+		"VUPLHF":   {"freg|vreg, freg|vreg"},
+		"VUPLHH":   {"freg|vreg, freg|vreg"},
+		"VUPLHW":   {"freg|vreg, freg|vreg"},
+		"VUPLLB":   {"freg|vreg, freg|vreg"},
+		"VUPLLF":   {"freg|vreg, freg|vreg"},
+		"VUPLLH":   {"freg|vreg, freg|vreg"},
+		"VX":       {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"VZERO":    {"freg|vreg"},
+		"WFADB":    {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"WFCEDB":   {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"WFCEDBS":  {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"WFCHDB":   {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"WFCHDBS":  {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"WFCHEDB":  {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"WFCHEDBS": {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"WFDDB":    {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"WFLCDB":   {"freg|vreg, freg|vreg"},
+		"WFLNDB":   {"freg|vreg, freg|vreg"},
+		"WFLPDB":   {"freg|vreg, freg|vreg"},
+		"WFMADB":   {"freg|vreg, freg|vreg, freg|vreg, freg|vreg"},
+		"WFMDB":    {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"WFMSDB":   {"freg|vreg, freg|vreg, freg|vreg, freg|vreg"},
+		"WFSDB":    {"freg|vreg, freg|vreg", "freg|vreg, freg|vreg, freg|vreg"},
+		"WFSQDB":   {"freg|vreg, freg|vreg"},
+		"XC":       {"imm, mem, mem"},
+		"XOR":      {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+		"XORW":     {"imm|reg, reg", "imm|reg, imm|reg, reg"},
+	},
+	ArchMIPS: {
+		"ABSD":     {"freg, freg"},
+		"ABSF":     {"freg, freg"},
+		"ADD":      {"addr|imm|reg, reg", "addr|imm|reg, reg, reg"},
+		"ADDD":     {"freg, freg", "freg, freg, freg"},
+		"ADDF":     {"freg, freg", "freg, freg, freg"},
+		"ADDU":     {"addr|imm|reg, reg", "addr|imm|reg, reg, reg"},
+		"AND":      {"addr|imm|reg, reg", "addr|imm|reg, reg, reg"},
+		"BEQ":      {"reg, label", "reg, reg, label"},
+		"BFPF":     {"label"},
+		"BFPT":     {"label"},
+		"BGEZ":     {"reg, label"},
+		"BGTZ":     {"reg, label"},
+		"BLEZ":     {"reg, label"},
+		"BLTZ":     {"reg, label"},
+		"BNE":      {"reg, label", "reg, reg, label"},
+		"BREAK":    {"*"},
+		"CALL":     {"mem|reg|sym"},
+		"CLO":      {"reg, reg"},
+		"CLZ":      {"reg, reg"},
+		"CMOVF":    {"reg, reg"},
+		"CMOVN":    {"reg, reg, reg"},
+		"CMOVT":    {"reg, reg"},
+		"CMOVZ":    {"reg, reg, reg"},
+		"CMPEQD":   {"freg, freg"},
+		"CMPEQF":   {"freg, freg"},
+		"CMPGED":   {"freg, freg"},
+		"CMPGEF":   {"freg, freg"},
+		"CMPGTD":   {"freg, freg"},
+		"CMPGTF":   {"freg, freg"},
+		"DIV":      {"reg, reg"},
+		"DIVD":     {"freg, freg", "freg, freg, freg"},
+		"DIVF":     {"freg, freg", "freg, freg, freg"},
+		"DIVU":     {"reg, reg"},
+		"FUNCDATA": {"*"},
+		"JAL":      {"mem|reg|sym"},
+		"JMP":      {"label|mem|reg|sym"},
+		"LL":       {"mem, reg"},
+		"MADD":     {"reg, reg"},
+		"MOVB":     {"addr|fp|imm|mem|reg|sym, reg", "reg, fp|mem|reg|sym"},
+		"MOVBU":    {"addr|fp|imm|mem|reg|sym, reg", "reg, fp|mem|reg|sym"},
+		"MOVD":     {"addr|fp|freg|mem|reg|sym, freg", "freg, fp|freg|mem|reg|sym"},
+		"MOVDF":    {"freg, freg"},
+		"MOVDW":    {"freg, freg"},
+		"MOVF":     {"addr|fp|freg|mem|reg|sym, freg", "freg, fp|freg|mem|reg|sym"},
+		"MOVFD":    {"freg, freg"},
+		"MOVFW":    {"freg, freg"},
+		"MOVH":     {"addr|fp|imm|mem|reg|sym, reg", "reg, fp|mem|reg|sym"},
+		"MOVHU":    {"addr|fp|imm|mem|reg|sym, reg", "reg, fp|mem|reg|sym"},
+		"MOVW":     {"addr|fp|freg|imm|mem|reg|sym, reg", "freg|reg, freg|reg", "reg, fp|freg|mem|reg|sym"},
+		"MOVWD":    {"freg, freg"},
+		"MOVWF":    {"freg, freg"},
+		"MOVWL":    {"mem, reg", "reg, mem"},
+		"MOVWR":    {"mem, reg", "reg, mem"},
+		"MSUB":     {"reg, reg"},
+		"MUL":      {"reg, reg", "addr|imm|reg, reg, reg"},
+		"MULD":     {"freg, freg", "freg, freg, freg"},
+		"MULF":     {"freg, freg", "freg, freg, freg"},
+		"MULU":     {"reg, reg", "addr|imm|reg, reg, reg"},
+		"NEGD":     {"freg, freg"},
+		"NEGF":     {"freg, freg"},
+		"NEGW":     {"reg", "reg, reg"},
+		"NOOP":     {"*"},
+		"NOP":      {"*"},
+		"NOR":      {"addr|imm|reg, reg", "addr|imm|reg, reg, reg"},
+		"OR":       {"addr|imm|reg, reg", "addr|imm|reg, reg, reg"},
+		"PCALIGN":  {"*"},
+		"PCDATA":   {"*"},
+		"REM":      {"reg, reg"},
+		"REMU":     {"reg, reg"},
+		"RET":      {"*"},
+		"ROTR":     {"addr|imm|reg, reg", "addr|imm|reg, reg, reg"},
+		"SC":       {"reg, mem"},
+		"SEB":      {"reg, reg"},
+		"SEH":      {"reg, reg"},
+		"SGT":      {"addr|imm|reg, reg", "addr|imm|reg, reg, reg"},
+		"SGTU":     {"addr|imm|reg, reg", "addr|imm|reg, reg, reg"},
+		"SLL":      {"addr|imm|reg, reg", "addr|imm|reg, reg, reg"},
+		"SQRTD":    {"freg, freg"},
+		"SQRTF":    {"freg, freg"},
+		"SRA":      {"addr|imm|reg, reg", "addr|imm|reg, reg, reg"},
+		"SRL":      {"addr|imm|reg, reg", "addr|imm|reg, reg, reg"},
+		"SUB":      {"addr|imm|reg, reg", "addr|imm|reg, reg, reg"},
+		"SUBD":     {"freg, freg", "freg, freg, freg"},
+		"SUBF":     {"freg, freg", "freg, freg, freg"},
+		"SUBU":     {"addr|imm|reg, reg", "addr|imm|reg, reg, reg"},
+		"SYNC":     {"*"},
+		"SYSCALL":  {"*"},
+		"TEQ":      {"imm, reg", "imm, reg, reg"},
+		"TNE":      {"imm, reg", "imm, reg, reg"},
+		"TRUNCDW":  {"freg, freg"},
+		"TRUNCFW":  {"freg, freg"},
+		"UNDEF":    {"*"},
+		"WSBH":     {"reg, reg"},
+		"XOR":      {"addr|imm|reg, reg", "addr|imm|reg, reg, reg"},
+	},
+	ArchMIPS64: {
+		"ABSD":     {"freg, freg"},
+		"ABSF":     {"freg, freg"},
+		"ADD":      {"addr|imm|reg, reg", "addr|imm|reg, reg, reg"},
+		"ADDD":     {"freg, freg", "freg, freg, freg"},
+		"ADDF":     {"freg, freg", "freg, freg, freg"},
+		"ADDU":     {"addr|imm|reg, reg", "addr|imm|reg, reg, reg"},
+		"ADDV":     {"addr|imm|reg, reg", "addr|imm|reg, reg, reg"},
+		"ADDVU":    {"addr|imm|reg, reg", "addr|imm|reg, reg, reg"},
+		"AND":      {"addr|imm|reg, reg", "addr|imm|reg, reg, reg"},
+		"BEQ":      {"reg, label", "reg, reg, label"},
+		"BFPF":     {"label"},
+		"BFPT":     {"label"},
+		"BGEZ":     {"reg, label"},
+		"BGTZ":     {"reg, label"},
+		"BLEZ":     {"reg, label"},
+		"BLTZ":     {"reg, label"},
+		"BNE":      {"reg, label", "reg, reg, label"},
+		"BREAK":    {"*"},
+		"CALL":     {"mem|reg|sym"},
+		"CLO":      {"reg, reg"},
+		"CLZ":      {"reg, reg"},
+		"CMOVF":    {"reg, reg"},
+		"CMOVN":    {"reg, reg, reg"},
+		"CMOVT":    {"reg, reg"},
+		"CMOVZ":    {"reg, reg, reg"},
+		"CMPEQD":   {"freg, freg"},
+		"CMPEQF":   {"freg, freg"},
+		"CMPGED":   {"freg, freg"},
+		"CMPGEF":   {"freg, freg"},
+		"CMPGTD":   {"freg, freg"},
+		"CMPGTF":   {"freg, freg"},
+		"DCLO":     {"reg, reg"},
+		"DCLZ":     {"reg, reg"},
+		"DIV":      {"reg, reg"},
+		"DIVD":     {"freg, freg", "freg, freg, freg"},
+		"DIVF":     {"freg, freg", "freg, freg, freg"},
+		"DIVU":     {"reg, reg"},
+		"DIVV":     {"reg, reg"},
+		"DIVVU":    {"reg, reg"},
+		"DSBH":     {"reg, reg"},
+		"DSHD":     {"reg, reg"},
+		"FUNCDATA": {"*"},
+		"JAL":      {"mem|reg|sym"},
+		"JMP":      {"label|mem|reg|sym"},
+		"LL":       {"mem, reg"},
+		"LLV":      {"mem, reg"},
+		"MADD":     {"reg, reg"},
+		"MOVB":     {"addr|fp|imm|mem|reg|sym, reg", "reg, fp|mem|reg|sym"},
+		"MOVBU":    {"addr|fp|imm|mem|reg|sym, reg", "reg, fp|mem|reg|sym"},
+		"MOVD":     {"addr|fp|freg|mem|reg|sym, freg", "freg, fp|freg|mem|reg|sym"},
+		"MOVDF":    {"freg, freg"},
+		"MOVDV":    {"freg, freg"},
+		"MOVDW":    {"freg, freg"},
+		"MOVF":     {"addr|fp|freg|mem|reg|sym, freg", "freg, fp|freg|mem|reg|sym"},
+		"MOVFD":    {"freg, freg"},
+		"MOVFV":    {"freg, freg"},
+		"MOVFW":    {"freg, freg"},
+		"MOVH":     {"addr|fp|imm|mem|reg|sym, reg", "reg, fp|mem|reg|sym"},
+		"MOVHU":    {"addr|fp|imm|mem|reg|sym, reg", "reg, fp|mem|reg|sym"},
+		"MOVV":     {"addr|fp|freg|imm|mem|reg|sym, reg", "freg|reg, freg|reg", "reg, fp|freg|mem|reg|sym"},
+		"MOVVD":    {"freg, freg"},
+		"MOVVF":    {"freg, freg"},
+		"MOVVL":    {"mem, reg", "reg, mem"},
+		"MOVVR":    {"mem, reg", "reg, mem"},
+		"MOVW":     {"addr|fp|freg|imm|mem|reg|sym, reg", "freg|reg, freg|reg", "reg, fp|freg|mem|reg|sym"},
+		"MOVWD":    {"freg, freg"},
+		"MOVWF":    {"freg, freg"},
+		"MOVWL":    {"mem, reg", "reg, mem"},
+		"MOVWR":    {"mem, reg", "reg, mem"},
+		"MOVWU":    {"addr|fp|imm|mem|reg|sym, reg", "reg, fp|mem|reg|sym"},
+		"MSUB":     {"reg, reg"},
+		"MUL":      {"reg, reg", "addr|imm|reg, reg, reg"},
+		"MULD":     {"freg, freg", "freg, freg, freg"},
+		"MULF":     {"freg, freg", "freg, freg, freg"},
+		"MULU":     {"reg, reg", "addr|imm|reg, reg, reg"},
+		"MULV":     {"reg, reg"},
+		"MULVU":    {"reg, reg"},
+		"NEGD":     {"freg, freg"},
+		"NEGF":     {"freg, freg"},
+		"NEGV":     {"reg", "reg, reg"},
+		"NEGW":     {"reg", "reg, reg"},
+		"NOOP":     {"*"},
+		"NOP":      {"*"},
+		"NOR":      {"addr|imm|reg, reg", "addr|imm|reg, reg, reg"},
+		"OR":       {"addr|imm|reg, reg", "addr|imm|reg, reg, reg"},
+		"PCALIGN":  {"*"},
+		"PCDATA":   {"*"},
+		"REM":      {"reg, reg"},
+		"REMU":     {"reg, reg"},
+		"REMV":     {"reg, reg"},
+		"REMVU":    {"reg, reg"},
+		"RET":      {"*"},
+		"ROTR":     {"addr|imm|reg, reg", "addr|imm|reg, reg, reg"},
+		"ROTRV":    {"addr|imm|reg, reg", "addr|imm|reg, reg, reg"},
+		"SC":       {"reg, mem"},
+		"SCV":      {"reg, mem"},
+		"SEB":      {"reg, reg"},
+		"SEH":      {"reg, reg"},
+		"SGT":      {"addr|imm|reg, reg", "addr|imm|reg, reg, reg"},
+		"SGTU":     {"addr|imm|reg, reg", "addr|imm|reg, reg, reg"},
+		"SLL":      {"addr|imm|reg, reg", "addr|imm|reg, reg, reg"},
+		"SLLV":     {"addr|imm|reg, reg", "addr|imm|reg, reg, reg"},
+		"SQRTD":    {"freg, freg"},
+		"SQRTF":    {"freg, freg"},
+		"SRA":      {"addr|imm|reg, reg", "addr|imm|reg, reg, reg"},
+		"SRAV":     {"addr|imm|reg, reg", "addr|imm|reg, reg, reg"},
+		"SRL":      {"addr|imm|reg, reg", "addr|imm|reg, reg, reg"},
+		"SRLV":     {"addr|imm|reg, reg", "addr|imm|reg, reg, reg"},
+		"SUB":      {"addr|imm|reg, reg", "addr|imm|reg, reg, reg"},
+		"SUBD":     {"freg, freg", "freg, freg, freg"},
+		"SUBF":     {"freg, freg", "freg, freg, freg"},
+		"SUBU":     {"addr|imm|reg, reg", "addr|imm|reg, reg, reg"},
+		"SUBV":     {"addr|imm|reg, reg", "addr|imm|reg, reg, reg"},
+		"SUBVU":    {"addr|imm|reg, reg", "addr|imm|reg, reg, reg"},
+		"SYNC":     {"*"},
+		"SYSCALL":  {"*"},
+		"TEQ":      {"imm, reg", "imm, reg, reg"},
+		"TNE":      {"imm, reg", "imm, reg, reg"},
+		"TRUNCDV":  {"freg, freg"},
+		"TRUNCDW":  {"freg, freg"},
+		"TRUNCFV":  {"freg, freg"},
+		"TRUNCFW":  {"freg, freg"},
+		"UNDEF":    {"*"},
+		"VMOVB":    {"imm|mem, vreg", "vreg, mem"},
+		"VMOVD":    {"imm|mem, vreg", "vreg, mem"},
+		"VMOVH":    {"imm|mem, vreg", "vreg, mem"},
+		"VMOVW":    {"imm|mem, vreg", "vreg, mem"},
+		"WSBH":     {"reg, reg"},
+		"XOR":      {"addr|imm|reg, reg", "addr|imm|reg, reg, reg"},
+	},
+	ArchWasm: {
+		"BLOCK":             {"", "imm"},
+		"BR":                {"imm|label"},
+		"BRIF":              {"imm|label"},
+		"CALL":              {"sym"},
+		"CALLNORESUME":      {"sym"},
+		"CURRENTMEMORY":     {"*"},
+		"DROP":              {"*"},
+		"ELSE":              {"*"},
+		"END":               {"*"},
+		"F32ABS":            {"*"},
+		"F32ADD":            {"*"},
+		"F32CEIL":           {"*"},
+		"F32CONST":          {"imm"},
+		"F32CONVERTI32S":    {"*"},
+		"F32CONVERTI32U":    {"*"},
+		"F32CONVERTI64S":    {"*"},
+		"F32CONVERTI64U":    {"*"},
+		"F32COPYSIGN":       {"*"},
+		"F32DEMOTEF64":      {"*"},
+		"F32DIV":            {"*"},
+		"F32EQ":             {"*"},
+		"F32FLOOR":          {"*"},
+		"F32GE":             {"*"},
+		"F32GT":             {"*"},
+		"F32LE":             {"*"},
+		"F32LOAD":           {"fp|imm|mem"},
+		"F32LT":             {"*"},
+		"F32MAX":            {"*"},
+		"F32MIN":            {"*"},
+		"F32MUL":            {"*"},
+		"F32NE":             {"*"},
+		"F32NEAREST":        {"*"},
+		"F32NEG":            {"*"},
+		"F32REINTERPRETI32": {"*"},
+		"F32SQRT":           {"*"},
+		"F32STORE":          {"fp|imm"},
+		"F32SUB":            {"*"},
+		"F32TRUNC":          {"*"},
+		"F64ABS":            {"*"},
+		"F64ADD":            {"*"},
+		"F64CEIL":           {"*"},
+		"F64CONST":          {"imm"},
+		"F64CONVERTI32S":    {"*"},
+		"F64CONVERTI32U":    {"*"},
+		"F64CONVERTI64S":    {"*"},
+		"F64CONVERTI64U":    {"*"},
+		"F64COPYSIGN":       {"*"},
+		"F64DIV":            {"*"},
+		"F64EQ":             {"*"},
+		"F64FLOOR":          {"*"},
+		"F64GE":             {"*"},
+		"F64GT":             {"*"},
+		"F64LE":             {"*"},
+		"F64LOAD":           {"fp|imm|mem"},
+		"F64LT":             {"*"},
+		"F64MAX":            {"*"},
+		"F64MIN":            {"*"},
+		"F64MUL":            {"*"},
+		"F64NE":             {"*"},
+		"F64NEAREST":        {"*"},
+		"F64NEG":            {"*"},
+		"F64PROMOTEF32":     {"*"},
+		"F64REINTERPRETI64": {"*"},
+		"F64SQRT":           {"*"},
+		"F64STORE":          {"fp|imm"},
+		"F64SUB":            {"*"},
+		"F64TRUNC":          {"*"},
+		"FUNCDATA":          {"*"},
+		"GET":               {"freg|reg"},
+		"GROWMEMORY":        {"*"},
+		"I32ADD":            {"*"},
+		"I32AND":            {"*"},
+		"I32CLZ":            {"*"},
+		"I32CONST":          {"addr|imm"},
+		"I32CTZ":            {"*"},
+		"I32DIVS":           {"*"},
+		"I32DIVU":           {"*"},
+		"I32EQ":             {"*"},
+		"I32EQZ":            {"*"},
+		"I32EXTEND16S":      {"*"},
+		"I32EXTEND8S":       {"*"},
+		"I32GES":            {"*"},
+		"I32GEU":            {"*"},
+		"I32GTS":            {"*"},
+		"I32GTU":            {"*"},
+		"I32LES":            {"*"},
+		"I32LEU":            {"*"},
+		"I32LOAD":           {"fp|imm|mem"},
+		"I32LOAD16S":        {"fp|imm|mem"},
+		"I32LOAD16U":        {"fp|imm|mem"},
+		"I32LOAD8S":         {"fp|imm|mem"},
+		"I32LOAD8U":         {"fp|imm|mem"},
+		"I32LTS":            {"*"},
+		"I32LTU":            {"*"},
+		"I32MUL":            {"*"},
+		"I32NE":             {"*"},
+		"I32OR":             {"*"},
+		"I32POPCNT":         {"*"},
+		"I32REINTERPRETF32": {"*"},
+		"I32REMS":           {"*"},
+		"I32REMU":           {"*"},
+		"I32ROTL":           {"*"},
+		"I32ROTR":           {"*"},
+		"I32SHL":            {"*"},
+		"I32SHRS":           {"*"},
+		"I32SHRU":           {"*"},
+		"I32STORE":          {"fp|imm"},
+		"I32STORE16":        {"fp|imm"},
+		"I32STORE8":         {"fp|imm"},
+		"I32SUB":            {"*"},
+		"I32TRUNCF32S":      {"*"},
+		"I32TRUNCF32U":      {"*"},
+		"I32TRUNCF64S":      {"*"},
+		"I32TRUNCF64U":      {"*"},
+		"I32TRUNCSATF32S":   {"*"},
+		"I32TRUNCSATF32U":   {"*"},
+		"I32TRUNCSATF64S":   {"*"},
+		"I32TRUNCSATF64U":   {"*"},
+		"I32WRAPI64":        {"*"},
+		"I32XOR":            {"*"},
+		"I64ADD":            {"*"},
+		"I64AND":            {"*"},
+		"I64CLZ":            {"*"},
+		"I64CONST":          {"addr|imm"},
+		"I64CTZ":            {"*"},
+		"I64DIVS":           {"*"},
+		"I64DIVU":           {"*"},
+		"I64EQ":             {"*"},
+		"I64EQZ":            {"*"},
+		"I64EXTEND16S":      {"*"},
+		"I64EXTEND32S":      {"*"},
+		"I64EXTEND8S":       {"*"},
+		"I64EXTENDI32S":     {"*"},
+		"I64EXTENDI32U":     {"*"},
+		"I64GES":            {"*"},
+		"I64GEU":            {"*"},
+		"I64GTS":            {"*"},
+		"I64GTU":            {"*"},
+		"I64LES":            {"*"},
+		"I64LEU":            {"*"},
+		"I64LOAD":           {"fp|imm|mem"},
+		"I64LOAD16S":        {"fp|imm|mem"},
+		"I64LOAD16U":        {"fp|imm|mem"},
+		"I64LOAD32S":        {"fp|imm|mem"},
+		"I64LOAD32U":        {"fp|imm|mem"},
+		"I64LOAD8S":         {"fp|imm|mem"},
+		"I64LOAD8U":         {"fp|imm|mem"},
+		"I64LTS":            {"*"},
+		"I64LTU":            {"*"},
+		"I64MUL":            {"*"},
+		"I64NE":             {"*"},
+		"I64OR":             {"*"},
+		"I64POPCNT":         {"*"},
+		"I64REINTERPRETF64": {"*"},
+		"I64REMS":           {"*"},
+		"I64REMU":           {"*"},
+		"I64ROTL":           {"*"},
+		"I64ROTR":           {"*"},
+		"I64SHL":            {"*"},
+		"I64SHRS":           {"*"},
+		"I64SHRU":           {"*"},
+		"I64STORE":          {"fp|imm"},
+		"I64STORE16":        {"fp|imm"},
+		"I64STORE32":        {"fp|imm"},
+		"I64STORE8":         {"fp|imm"},
+		"I64SUB":            {"*"},
+		"I64TRUNCF32S":      {"*"},
+		"I64TRUNCF32U":      {"*"},
+		"I64TRUNCF64S":      {"*"},
+		"I64TRUNCF64U":      {"*"},
+		"I64TRUNCSATF32S":   {"*"},
+		"I64TRUNCSATF32U":   {"*"},
+		"I64TRUNCSATF64S":   {"*"},
+		"I64TRUNCSATF64U":   {"*"},
+		"I64XOR":            {"*"},
+		"IF":                {"", "imm"},
+		"JMP":               {"sym"},
+		"LOOP":              {"", "imm"},
+		"MEMORYCOPY":        {"*"},
+		"MEMORYFILL":        {"*"},
+		"MOVB":              {"addr|fp|imm|mem|reg, fp|mem|reg"},
+		"MOVD":              {"addr|fp|imm|mem|reg, fp|mem|reg"},
+		"MOVH":              {"addr|fp|imm|mem|reg, fp|mem|reg"},
+		"MOVW":              {"addr|fp|imm|mem|reg, fp|mem|reg"},
+		"NOP":               {"*"},
+		"PCDATA":            {"*"},
+		"RET":               {"*"},
+		"SELECT":            {"*"},
+		"SET":               {"freg|reg"},
+		"TEE":               {"freg|reg"},
+		"UNDEF":             {"*"},
+		"UNREACHABLE":       {"*"},
+	},
+}
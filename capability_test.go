@@ -0,0 +1,1066 @@
+//go:build !llgo
+// +build !llgo
+
+package plan9asm
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+)
+
+var updateCapabilityTable = flag.Bool("update-capabilities", false, "regenerate capability_table.go by probing the lowerers")
+
+const capabilityTableFile = "capability_table.go"
+
+// capabilitySamples lists concrete operand spellings for each class. When a
+// class has several samples they are tried in order until one is accepted,
+// so arrangement- or addressing-specific lowerings are still characterized.
+var capabilitySamples = map[Arch]map[OperandClass][]string{
+	ArchAMD64: {
+		ClassImm:   {"$1"},
+		ClassAddr:  {"$·probe_sym(SB)"},
+		ClassReg:   {"BX"},
+		ClassXReg:  {"X1"},
+		ClassYReg:  {"Y1"},
+		ClassZReg:  {"Z1"},
+		ClassKReg:  {"K1"},
+		ClassMem:   {"8(SI)", "8(SI)(CX*8)"},
+		ClassFP:    {"a+0(FP)"},
+		ClassSym:   {"·probe_sym(SB)"},
+		ClassLabel: {"probe_target"},
+	},
+	Arch386: {
+		ClassImm:   {"$1"},
+		ClassAddr:  {"$·probe_sym(SB)"},
+		ClassReg:   {"BX"},
+		ClassXReg:  {"X1"},
+		ClassYReg:  {"Y1"},
+		ClassZReg:  {"Z1"},
+		ClassKReg:  {"K1"},
+		ClassMem:   {"8(SI)", "8(SI)(CX*4)"},
+		ClassFP:    {"a+0(FP)"},
+		ClassSym:   {"·probe_sym(SB)"},
+		ClassLabel: {"probe_target"},
+	},
+	ArchARM64: {
+		ClassImm:      {"$1"},
+		ClassAddr:     {"$·probe_sym(SB)"},
+		ClassReg:      {"R1"},
+		ClassVReg:     {"V1.B16", "V1.D2", "V1.S4", "V1.H8", "V1.D[1]", "V1.S[1]", "V1.B[1]", "V1.B8", "V1.Q1"},
+		ClassFReg:     {"F1"},
+		ClassShifted:  {"R1<<2"},
+		ClassExtended: {"R1.UXTW"},
+		ClassMem:      {"8(R2)", "(R2)", "(R2)(R3)"},
+		ClassRegList:  {"(R4, R5)"},
+		ClassVList:    {"[V1.B16, V2.B16]", "[V1.D2, V2.D2]", "[V1.S4, V2.S4]", "[V1.B16]"},
+		ClassFP:       {"a+0(FP)"},
+		ClassSym:      {"·probe_sym(SB)"},
+		ClassCond:     {"EQ"},
+		ClassLabel:    {"probe_target"},
+	},
+	ArchARM: {
+		ClassImm:     {"$1"},
+		ClassAddr:    {"$·probe_sym(SB)"},
+		ClassReg:     {"R1"},
+		ClassFReg:    {"F1"},
+		ClassShifted: {"R1<<2"},
+		ClassMem:     {"8(R2)", "(R2)"},
+		ClassRegList: {"(R4, R5)", "[R4-R7]"},
+		ClassFP:      {"a+0(FP)"},
+		ClassSym:     {"·probe_sym(SB)"},
+		ClassLabel:   {"probe_target"},
+	},
+	ArchRISCV64: {
+		ClassImm:   {"$1"},
+		ClassAddr:  {"$·probe_sym(SB)"},
+		ClassReg:   {"X6"},
+		ClassFReg:  {"F1"},
+		ClassMem:   {"8(X7)", "(X7)"},
+		ClassFP:    {"a+0(FP)"},
+		ClassSym:   {"·probe_sym(SB)"},
+		ClassLabel: {"probe_target"},
+	},
+	ArchLOONG64: {
+		ClassImm:   {"$1"},
+		ClassAddr:  {"$·probe_sym(SB)"},
+		ClassReg:   {"R6", "FCC1"},
+		ClassFReg:  {"F1"},
+		ClassMem:   {"8(R7)", "(R7)"},
+		ClassFP:    {"a+0(FP)"},
+		ClassSym:   {"·probe_sym(SB)"},
+		ClassLabel: {"probe_target"},
+	},
+	ArchPPC64: {
+		ClassImm:   {"$1"},
+		ClassAddr:  {"$·probe_sym(SB)"},
+		ClassReg:   {"R6", "CR1", "CTR"},
+		ClassFReg:  {"F1"},
+		ClassVReg:  {"V1", "VS33"},
+		ClassMem:   {"8(R7)", "(R7)", "(R7)(R8)"},
+		ClassFP:    {"a+0(FP)"},
+		ClassSym:   {"·probe_sym(SB)"},
+		ClassLabel: {"probe_target"},
+	},
+	ArchMIPS: {
+		ClassImm:   {"$1"},
+		ClassAddr:  {"$·probe_sym(SB)"},
+		ClassReg:   {"R6", "HI", "FCC0"},
+		ClassFReg:  {"F2"},
+		ClassMem:   {"8(R7)", "(R7)"},
+		ClassFP:    {"a+0(FP)"},
+		ClassSym:   {"·probe_sym(SB)"},
+		ClassLabel: {"probe_target"},
+	},
+	ArchMIPS64: {
+		ClassImm:   {"$1"},
+		ClassAddr:  {"$·probe_sym(SB)"},
+		ClassReg:   {"R6", "HI", "FCC0"},
+		ClassFReg:  {"F2"},
+		ClassVReg:  {"W1"},
+		ClassMem:   {"8(R7)", "(R7)"},
+		ClassFP:    {"a+0(FP)"},
+		ClassSym:   {"·probe_sym(SB)"},
+		ClassLabel: {"probe_target"},
+	},
+	ArchS390X: {
+		ClassImm:   {"$1"},
+		ClassAddr:  {"$·probe_sym(SB)"},
+		ClassReg:   {"R6"},
+		ClassFReg:  {"F1"},
+		ClassVReg:  {"V1"},
+		ClassMem:   {"8(R7)", "(R7)", "8(R7)(R8*1)"},
+		ClassFP:    {"a+0(FP)"},
+		ClassSym:   {"·probe_sym(SB)"},
+		ClassLabel: {"probe_target"},
+	},
+	ArchWasm: {
+		ClassImm:   {"$1"},
+		ClassAddr:  {"$·probe_sym(SB)"},
+		ClassReg:   {"R6", "SP"},
+		ClassFReg:  {"F1", "F16"},
+		ClassMem:   {"8(R7)", "(R7)"},
+		ClassFP:    {"a+0(FP)"},
+		ClassSym:   {"·probe_sym(SB)"},
+		ClassLabel: {"probe_target"},
+	},
+}
+
+// capabilityResultFP is used instead of the parameter slot when an FP operand
+// is the destination (last operand), since only result slots are writable.
+var capabilityResultFP = map[Arch]string{
+	ArchAMD64:   "ret+8(FP)",
+	Arch386:     "ret+4(FP)",
+	ArchARM64:   "ret+8(FP)",
+	ArchARM:     "ret+4(FP)",
+	ArchRISCV64: "ret+8(FP)",
+	ArchLOONG64: "ret+8(FP)",
+	ArchPPC64:   "ret+8(FP)",
+	ArchMIPS:    "ret+4(FP)",
+	ArchMIPS64:  "ret+8(FP)",
+	ArchS390X:   "ret+8(FP)",
+	ArchWasm:    "ret+8(FP)",
+}
+
+// capabilityArchs are the backends covered by capability_table.go.
+var capabilityArchs = []Arch{ArchAMD64, Arch386, ArchARM, ArchARM64, ArchRISCV64, ArchLOONG64, ArchPPC64, ArchS390X, ArchMIPS, ArchMIPS64, ArchWasm}
+
+func TestCapabilityTableUpToDate(t *testing.T) {
+	if *updateCapabilityTable {
+		if err := os.WriteFile(capabilityTableFile, generateCapabilityTable(t), 0644); err != nil {
+			t.Fatalf("write %s: %v", capabilityTableFile, err)
+		}
+		return
+	}
+	// Probing every form takes over a minute; spot-check instead. Each table
+	// entry must still lower its first form, and each opcode missing from the
+	// table must still be rejected in its most common shapes.
+	const hint = "; run: go test -run TestCapabilityTableUpToDate -update-capabilities"
+	for _, arch := range capabilityArchs {
+		candidates := map[string]bool{}
+		for _, op := range capabilityCandidateOps(t, arch) {
+			candidates[op] = true
+			if _, ok := loweredOpForms[arch][op]; ok {
+				continue
+			}
+			for _, tuple := range capabilityCommonTuples(arch) {
+				if capabilityProbe(arch, op, tuple) {
+					t.Fatalf("%s: %s now lowers %v but is not in %s%s", arch, op, tuple, capabilityTableFile, hint)
+				}
+			}
+		}
+		for op, forms := range loweredOpForms[arch] {
+			if !candidates[op] {
+				t.Fatalf("%s: %s in %s no longer appears in the lowerers%s", arch, op, capabilityTableFile, hint)
+			}
+			var tuple []OperandClass
+			if forms[0] != anyOperandsForm {
+				for _, pos := range parseOperandForm(forms[0]) {
+					tuple = append(tuple, pos[0])
+				}
+			}
+			if !capabilityProbe(arch, op, tuple) {
+				t.Fatalf("%s: %s no longer lowers %v%s", arch, op, tuple, hint)
+			}
+		}
+	}
+}
+
+func TestLookupCapability(t *testing.T) {
+	cp, ok := LookupCapability(ArchAMD64, "addq")
+	if !ok {
+		t.Fatalf("LookupCapability(amd64, ADDQ) not found")
+	}
+	if !cp.Accepts(ArchAMD64, []Operand{{Kind: OpImm, Imm: 1}, {Kind: OpReg, Reg: AX}}) {
+		t.Fatalf("ADDQ should accept imm, reg: %v", cp.Forms)
+	}
+	if cp.Accepts(ArchAMD64, []Operand{{Kind: OpReg, Reg: "X1"}, {Kind: OpReg, Reg: "Y2"}}) {
+		t.Fatalf("ADDQ should reject xreg, yreg")
+	}
+
+	cp, ok = LookupCapability(ArchAMD64, "CRC32Q")
+	if !ok {
+		t.Fatalf("LookupCapability(amd64, CRC32Q) not found")
+	}
+	if got := strings.Join(cp.Features, ","); got != "+crc32,+sse4.2" {
+		t.Fatalf("CRC32Q features = %q", got)
+	}
+
+	if _, ok := LookupCapability(ArchARM64, "MOVD.P"); !ok {
+		t.Fatalf("LookupCapability(arm64, MOVD.P) should strip the suffix")
+	}
+	if _, ok := LookupCapability(ArchARM, "MOVW.EQ"); !ok {
+		t.Fatalf("LookupCapability(arm, MOVW.EQ) should strip the condition")
+	}
+	if _, ok := LookupCapability(ArchAMD64, "NOSUCHOP"); ok {
+		t.Fatalf("LookupCapability(amd64, NOSUCHOP) unexpectedly found")
+	}
+	if _, ok := LookupCapability(Arch("sparc64"), "ADD"); ok {
+		t.Fatalf("LookupCapability(sparc64) unexpectedly found")
+	}
+	if _, ok := LookupCapability(ArchMIPS64LE, "LLV"); !ok {
+		t.Fatalf("LookupCapability(mips64le, LLV) should use the mips64 table")
+	}
+	if _, ok := LookupCapability(ArchMIPSLE, "LLV"); ok {
+		t.Fatalf("LookupCapability(mipsle, LLV) unexpectedly found")
+	}
+
+	caps := Capabilities(ArchARM64)
+	if len(caps) == 0 {
+		t.Fatalf("Capabilities(arm64) is empty")
+	}
+	if !sort.SliceIsSorted(caps, func(i, j int) bool { return caps[i].Op < caps[j].Op }) {
+		t.Fatalf("Capabilities(arm64) not sorted")
+	}
+}
+
+func TestCheckFile(t *testing.T) {
+	src := `TEXT ·f(SB),0,$0-16
+	MOVQ a+0(FP), AX
+	ADDQ $1, AX
+	FROBQ AX, BX
+	MOVQ AX, ret+8(FP)
+	RET
+`
+	file, err := Parse(ArchAMD64, src)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	bad := CheckFile(file)
+	if len(bad) != 1 {
+		t.Fatalf("CheckFile = %+v, want one unsupported instr", bad)
+	}
+	if bad[0].Instr.Op != "FROBQ" || bad[0].Func != "·f" || !strings.Contains(bad[0].Reason, "unsupported opcode") {
+		t.Fatalf("CheckFile()[0] = %+v", bad[0])
+	}
+
+	file.Funcs[0].Instrs[3] = Instr{Op: "ADDQ", Args: []Operand{{Kind: OpReg, Reg: "X1"}, {Kind: OpReg, Reg: "Y1"}}, Raw: "ADDQ X1, Y1"}
+	bad = CheckFile(file)
+	if len(bad) != 1 || !strings.Contains(bad[0].Reason, "xreg, yreg") {
+		t.Fatalf("CheckFile = %+v, want operand form rejection", bad)
+	}
+}
+
+func generateCapabilityTable(t *testing.T) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	b.WriteString("// Code generated by TestCapabilityTableUpToDate -update-capabilities; DO NOT EDIT.\n\n")
+	b.WriteString("package plan9asm\n\n")
+	b.WriteString("// loweredOpForms maps arch -> opcode -> accepted operand forms, as found by\n")
+	b.WriteString("// probing each backend lowering with one synthetic instruction per shape.\n")
+	b.WriteString("// Forms use the OperandForm.String syntax; \"*\" accepts any operands.\n")
+	b.WriteString("var loweredOpForms = map[Arch]map[string][]string{\n")
+	for _, arch := range capabilityArchs {
+		fmt.Fprintf(&b, "\t%s: {\n", capabilityArchConst(arch))
+		for _, op := range capabilityCandidateOps(t, arch) {
+			tuples, anyOperands := probeCapabilityTuples(arch, op)
+			var forms []string
+			if anyOperands {
+				forms = []string{anyOperandsForm}
+			} else {
+				forms = factorCapabilityTuples(capabilityClasses(arch), tuples)
+			}
+			if len(forms) == 0 {
+				continue
+			}
+			fmt.Fprintf(&b, "\t\t%q: {", op)
+			for i, f := range forms {
+				if i > 0 {
+					b.WriteString(", ")
+				}
+				fmt.Fprintf(&b, "%q", f)
+			}
+			b.WriteString("},\n")
+		}
+		b.WriteString("\t},\n")
+	}
+	b.WriteString("}\n")
+	out, err := format.Source(b.Bytes())
+	if err != nil {
+		t.Fatalf("format generated table: %v", err)
+	}
+	return out
+}
+
+func capabilityArchConst(arch Arch) string {
+	switch arch {
+	case ArchAMD64:
+		return "ArchAMD64"
+	case Arch386:
+		return "Arch386"
+	case ArchARM:
+		return "ArchARM"
+	case ArchARM64:
+		return "ArchARM64"
+	case ArchRISCV64:
+		return "ArchRISCV64"
+	case ArchLOONG64:
+		return "ArchLOONG64"
+	case ArchPPC64:
+		return "ArchPPC64"
+	case ArchS390X:
+		return "ArchS390X"
+	case ArchMIPS:
+		return "ArchMIPS"
+	case ArchMIPS64:
+		return "ArchMIPS64"
+	case ArchWasm:
+		return "ArchWasm"
+	}
+	return fmt.Sprintf("Arch(%q)", arch)
+}
+
+var (
+	capabilityCaseRe = regexp.MustCompile(`(?s)case\s+([^:]+?):`)
+	capabilityOpRe   = regexp.MustCompile(`^[A-Z][A-Z0-9]*$`)
+)
+
+// capabilityCandidateOps collects every opcode-looking string that appears in
+// a case clause of the arch lowering sources. Probing filters out the ones
+// that are not instructions (register names, condition codes, ...).
+func capabilityCandidateOps(t *testing.T, arch Arch) []string {
+	t.Helper()
+	prefix := string(arch)
+	if archIsMIPS(arch) {
+		// All four MIPS dialects share the mips_*.go lowerings.
+		prefix = "mips"
+	}
+	files, err := filepath.Glob(prefix + "_*.go")
+	if err != nil {
+		t.Fatalf("glob: %v", err)
+	}
+	if arch == Arch386 {
+		// The 386 backend runs the amd64 lowerings in 32-bit mode.
+		amd64Files, err := filepath.Glob(string(ArchAMD64) + "_*.go")
+		if err != nil {
+			t.Fatalf("glob: %v", err)
+		}
+		files = append(files, amd64Files...)
+	}
+	seen := map[string]bool{}
+	switch arch {
+	case ArchRISCV64:
+		for _, op := range riscv64TableOps() {
+			seen[op] = true
+		}
+	case ArchLOONG64:
+		for _, op := range loong64TableOps() {
+			seen[op] = true
+		}
+	case ArchPPC64:
+		for _, op := range ppc64TableOps() {
+			seen[op] = true
+		}
+	case ArchS390X:
+		for _, op := range s390xTableOps() {
+			seen[op] = true
+		}
+	case ArchMIPS, ArchMIPS64:
+		for _, op := range mipsTableOps() {
+			seen[op] = true
+		}
+	case ArchWasm:
+		for _, op := range wasmTableOps() {
+			seen[op] = true
+		}
+	}
+	for _, f := range files {
+		if strings.HasSuffix(f, "_test.go") {
+			continue
+		}
+		src, err := os.ReadFile(f)
+		if err != nil {
+			t.Fatalf("read %s: %v", f, err)
+		}
+		for _, m := range capabilityCaseRe.FindAllSubmatch(src, -1) {
+			for _, item := range strings.Split(string(m[1]), ",") {
+				item = strings.TrimSpace(item)
+				switch {
+				case len(item) >= 2 && strings.HasPrefix(item, `"`) && strings.HasSuffix(item, `"`):
+					item = strings.Trim(item, `"`)
+				case strings.HasPrefix(item, "Op"):
+					item = strings.TrimPrefix(item, "Op")
+				default:
+					continue
+				}
+				item = capabilityOpName(arch, Op(item))
+				if capabilityOpRe.MatchString(item) && Op(item) != OpTEXT && Op(item) != OpLABEL {
+					seen[item] = true
+				}
+			}
+		}
+	}
+	ops := make([]string, 0, len(seen))
+	for op := range seen {
+		ops = append(ops, op)
+	}
+	sort.Strings(ops)
+	return ops
+}
+
+// riscv64TableOps lists the riscv64 opcodes that are looked up in tables or
+// built from a base name and a width or precision suffix, which never
+// appear in case clauses.
+func riscv64TableOps() []string {
+	var ops []string
+	for op := range riscv64ALUOps {
+		ops = append(ops, op)
+	}
+	for op := range riscv64UnaryOps {
+		ops = append(ops, op)
+	}
+	for op := range riscv64MovWidth {
+		ops = append(ops, op)
+	}
+	for op := range riscv64BranchConds {
+		ops = append(ops, op)
+	}
+	for _, w := range []string{"W", "D"} {
+		ops = append(ops, "LR"+w, "SC"+w)
+		for op := range riscv64AMOOps {
+			ops = append(ops, op+w)
+		}
+	}
+	for _, prec := range []string{"S", "D"} {
+		for _, base := range []string{"FADD", "FSUB", "FMUL", "FDIV", "FMIN", "FMAX", "FSGNJ", "FSGNJN", "FSGNJX",
+			"FSQRT", "FABS", "FNEG", "FMADD", "FMSUB", "FNMADD", "FNMSUB", "FEQ", "FLT", "FLE", "FNE", "FCLASS"} {
+			ops = append(ops, base+prec)
+		}
+	}
+	kinds := []string{"L", "LU", "W", "WU", "S", "D"}
+	for _, to := range kinds {
+		for _, from := range kinds {
+			ops = append(ops, "FCVT"+to+from)
+		}
+	}
+	return ops
+}
+
+// loong64TableOps is the loong64 counterpart of riscv64TableOps.
+func loong64TableOps() []string {
+	var ops []string
+	for op := range loong64ALUOps {
+		ops = append(ops, op)
+	}
+	for op := range loong64UnaryOps {
+		ops = append(ops, op)
+	}
+	for op := range loong64MovWidth {
+		ops = append(ops, op)
+	}
+	for op := range loong64BranchConds {
+		ops = append(ops, op)
+	}
+	for _, w := range []string{"B", "H", "W", "V"} {
+		for _, db := range []string{"", "DB"} {
+			ops = append(ops, "AMCAS"+db+w)
+			for op := range loong64AMOps {
+				if base := strings.TrimSuffix(op, "U"); base != op {
+					ops = append(ops, "AM"+base+db+w+"U")
+				} else {
+					ops = append(ops, "AM"+op+db+w)
+				}
+			}
+		}
+	}
+	for _, prec := range []string{"F", "D"} {
+		for _, base := range []string{"ADD", "SUB", "MUL", "DIV", "FMIN", "FMAX", "FCOPYSG", "ABS", "NEG", "SQRT", "FRINT",
+			"FMADD", "FMSUB", "FNMADD", "FNMSUB", "CMPEQ", "CMPGT", "CMPGE", "FCLASS"} {
+			ops = append(ops, base+prec)
+		}
+	}
+	kinds := []string{"F", "D", "W", "V"}
+	for _, to := range kinds {
+		for _, from := range kinds {
+			ops = append(ops, "MOV"+from+to, "FFINT"+to+from, "TRUNC"+from+to)
+			for round := range loong64RoundFns {
+				ops = append(ops, "FTINT"+round+to+from)
+			}
+		}
+	}
+	return ops
+}
+
+// mipsTableOps is the counterpart of riscv64TableOps for the MIPS dialects.
+// The mips64-only forms are listed for mips too, where probing drops them.
+func mipsTableOps() []string {
+	var ops []string
+	for op := range mipsALUOps {
+		ops = append(ops, op)
+	}
+	for op := range mipsHILOOps {
+		ops = append(ops, op)
+	}
+	for op := range mipsUnaryOps {
+		ops = append(ops, op)
+	}
+	for op := range mipsMovWidth {
+		ops = append(ops, op)
+	}
+	for op := range mipsBranchConds {
+		ops = append(ops, op)
+	}
+	for op := range mipsVecLanes {
+		ops = append(ops, op)
+	}
+	for _, prec := range []string{"F", "D"} {
+		for _, base := range []string{"ADD", "SUB", "MUL", "DIV", "ABS", "NEG", "SQRT", "CMPEQ", "CMPGT", "CMPGE"} {
+			ops = append(ops, base+prec)
+		}
+	}
+	kinds := []string{"F", "D", "W", "V"}
+	for _, to := range kinds {
+		for _, from := range kinds {
+			ops = append(ops, "MOV"+from+to, "TRUNC"+from+to)
+		}
+	}
+	return ops
+}
+
+// wasmTableOps is the wasm counterpart of riscv64TableOps: the numeric
+// opcodes are spelled from their value type and the table entry.
+func wasmTableOps() []string {
+	var ops []string
+	for op := range wasmMemOps {
+		ops = append(ops, op)
+	}
+	for op := range wasmMovBits {
+		ops = append(ops, op)
+	}
+	intOps := []string{"CONST", "DIVS", "DIVU", "REMS", "REMU", "EQZ", "CLZ", "CTZ", "POPCNT",
+		"EXTEND8S", "EXTEND16S", "EXTEND32S", "WRAPI64", "EXTENDI32S", "EXTENDI32U",
+		"REINTERPRETF32", "REINTERPRETF64"}
+	for _, tables := range []map[string]string{wasmIntBinOps, wasmIntShifts, wasmIntCompares} {
+		for name := range tables {
+			intOps = append(intOps, name)
+		}
+	}
+	floatOps := []string{"CONST", "NEG", "DEMOTEF64", "PROMOTEF32", "REINTERPRETI32", "REINTERPRETI64",
+		"CONVERTI32S", "CONVERTI32U", "CONVERTI64S", "CONVERTI64U"}
+	for _, tables := range []map[string]string{wasmFloatBinOps, wasmFloatCompares, wasmFloatUnary} {
+		for name := range tables {
+			floatOps = append(floatOps, name)
+		}
+	}
+	for _, from := range []string{"F32", "F64"} {
+		for _, sat := range []string{"TRUNC", "TRUNCSAT"} {
+			intOps = append(intOps, sat+from+"S", sat+from+"U")
+		}
+	}
+	for _, ty := range []string{"I32", "I64"} {
+		for _, name := range intOps {
+			ops = append(ops, ty+name)
+		}
+	}
+	for _, ty := range []string{"F32", "F64"} {
+		for _, name := range floatOps {
+			ops = append(ops, ty+name)
+		}
+	}
+	return ops
+}
+
+// ppc64TableOps is the ppc64 counterpart of riscv64TableOps, including the
+// CC forms of the arithmetic that also set CR0.
+func ppc64TableOps() []string {
+	var ops []string
+	arith := []string{"RLDICL", "RLDICR", "RLDIC", "RLDIMI", "RLDCL", "RLDCR", "CLRLSLDI",
+		"RLWNM", "RLWMI", "CLRLSLWI", "EXTSWSLI"}
+	for op := range ppc64ALUOps {
+		arith = append(arith, op)
+	}
+	for op := range ppc64CarryOps {
+		arith = append(arith, op)
+	}
+	for op := range ppc64UnaryOps {
+		arith = append(arith, op)
+	}
+	for _, op := range arith {
+		ops = append(ops, op, op+"CC")
+	}
+	for op := range ppc64MovForms {
+		ops = append(ops, op)
+	}
+	for op := range ppc64BranchConds {
+		ops = append(ops, op)
+	}
+	for op := range ppc64CRLogic {
+		ops = append(ops, op)
+	}
+	for op := range ppc64ReserveBits {
+		ops = append(ops, op)
+	}
+	for op := range ppc64FPUnary {
+		ops = append(ops, op)
+	}
+	for op := range ppc64FPToInt {
+		ops = append(ops, op)
+	}
+	for op := range ppc64VecLogic {
+		ops = append(ops, op)
+	}
+	for op := range ppc64QuadOps {
+		ops = append(ops, op)
+	}
+	for _, w := range []string{"B", "H", "W", "D"} {
+		ops = append(ops, "VADDU"+w+"M", "VSUBU"+w+"M", "VPOPCNT"+w, "VCLZ"+w, "VCTZ"+w)
+		for _, cmp := range []string{"VCMPEQU", "VCMPGTU", "VCMPGTS"} {
+			ops = append(ops, cmp+w, cmp+w+"CC")
+		}
+		if w != "B" {
+			ops = append(ops, "XXBR"+w)
+		}
+	}
+	return append(ops, "VADDUQM", "VSUBUQM", "XXBRQ")
+}
+
+// s390xTableOps is the s390x counterpart of riscv64TableOps. The vector
+// floating-point operations are keyed without their V/W prefix.
+func s390xTableOps() []string {
+	var ops []string
+	for op := range s390xALUOps {
+		ops = append(ops, op)
+	}
+	for op := range s390xCarryOps {
+		ops = append(ops, op)
+	}
+	for op := range s390xCompares {
+		ops = append(ops, op)
+	}
+	for op := range s390xTestMasks {
+		ops = append(ops, op)
+	}
+	for op := range s390xMovForms {
+		ops = append(ops, op)
+	}
+	for op := range s390xCondMoves {
+		ops = append(ops, op)
+	}
+	for op := range s390xStorageOps {
+		ops = append(ops, op)
+	}
+	for op := range s390xBranchMasks {
+		ops = append(ops, op)
+	}
+	for _, prefix := range []string{"CMPB", "CMPUB", "CMPWB", "CMPWUB"} {
+		for suffix := range s390xCmpBranchSuffixes {
+			ops = append(ops, prefix+suffix)
+		}
+	}
+	for op := range s390xCmpJumps {
+		ops = append(ops, op)
+	}
+	for op := range s390xInterlocked {
+		ops = append(ops, op)
+	}
+	for op := range s390xFPUnary {
+		ops = append(ops, op)
+	}
+	for op := range s390xFPConvs {
+		ops = append(ops, op)
+	}
+	for op := range s390xVecOps {
+		ops = append(ops, op)
+	}
+	for op := range s390xVecLogic {
+		ops = append(ops, op)
+	}
+	for _, op := range []string{"FADB", "FSDB", "FMDB", "FDDB", "FCEDB", "FCHDB", "FCHEDB",
+		"FCEDBS", "FCHDBS", "FCHEDBS", "FMADB", "FMSDB", "FLCDB", "FLPDB", "FLNDB", "FSQDB"} {
+		ops = append(ops, "V"+op, "W"+op)
+	}
+	return append(ops, "BRC", "BC", "BRCT", "BRCTG")
+}
+
+// probeCapabilityTuples returns every accepted operand tuple of op, or
+// anyOperands=true when every probed tuple of arity <= 2 lowers.
+func probeCapabilityTuples(arch Arch, op string) (tuples [][]OperandClass, anyOperands bool) {
+	classes := capabilityClasses(arch)
+	tried := map[string]bool{}
+	probe := func(tuple []OperandClass) bool {
+		key := fmt.Sprint(tuple)
+		if ok, seen := tried[key]; seen {
+			return ok
+		}
+		ok := capabilityProbe(arch, op, tuple)
+		tried[key] = ok
+		if ok {
+			tuples = append(tuples, tuple)
+		}
+		return ok
+	}
+
+	anyOperands = true
+	short := [][]OperandClass{{}}
+	for _, a := range classes {
+		short = append(short, []OperandClass{a})
+	}
+	for _, a := range classes {
+		for _, b := range classes {
+			short = append(short, []OperandClass{a, b})
+		}
+	}
+	for _, tuple := range short {
+		if !probe(tuple) {
+			anyOperands = false
+		}
+	}
+	if anyOperands {
+		return nil, true
+	}
+
+	// Arity 3 and 4: probe seeds (one varying position next to a homogeneous
+	// run, or a homogeneous run around one varying middle position, as in
+	// "AMOADDW reg, mem, reg") and grow the accepted set by single-position
+	// substitutions.
+	for _, n := range []int{3, 4} {
+		var queue [][]OperandClass
+		for _, a := range classes {
+			for _, b := range classes {
+				head := []OperandClass{a}
+				tail := []OperandClass{}
+				for i := 1; i < n; i++ {
+					head = append(head, b)
+					tail = append(tail, b)
+				}
+				tail = append(tail, a)
+				mid := []OperandClass{a, b, a}
+				if n == 4 {
+					mid = []OperandClass{a, b, b, a}
+				}
+				for _, tuple := range [][]OperandClass{head, tail, mid} {
+					if probe(tuple) {
+						queue = append(queue, tuple)
+					}
+				}
+			}
+		}
+		for len(queue) > 0 {
+			tuple := queue[0]
+			queue = queue[1:]
+			for i := range tuple {
+				for _, c := range classes {
+					next := append([]OperandClass(nil), tuple...)
+					next[i] = c
+					if _, seen := tried[fmt.Sprint(next)]; seen {
+						continue
+					}
+					if probe(next) {
+						queue = append(queue, next)
+					}
+				}
+			}
+		}
+	}
+	return tuples, false
+}
+
+// factorCapabilityTuples compresses accepted tuples into OperandForm strings.
+// Each form is grown greedily from an uncovered tuple by adding classes to a
+// position while the whole cartesian product stays accepted.
+func factorCapabilityTuples(classes []OperandClass, tuples [][]OperandClass) []string {
+	accepted := map[string]bool{}
+	for _, tuple := range tuples {
+		accepted[fmt.Sprint(tuple)] = true
+	}
+	sort.Slice(tuples, func(i, j int) bool {
+		if len(tuples[i]) != len(tuples[j]) {
+			return len(tuples[i]) < len(tuples[j])
+		}
+		return fmt.Sprint(tuples[i]) < fmt.Sprint(tuples[j])
+	})
+	covered := map[string]bool{}
+	var out []string
+	for _, tuple := range tuples {
+		if covered[fmt.Sprint(tuple)] {
+			continue
+		}
+		form := make(OperandForm, len(tuple))
+		for i, c := range tuple {
+			form[i] = []OperandClass{c}
+		}
+		for changed := true; changed; {
+			changed = false
+			for i := range form {
+				for _, c := range classes {
+					if capabilityClassIn(form[i], c) {
+						continue
+					}
+					grown := append(OperandForm(nil), form...)
+					grown[i] = append(append([]OperandClass(nil), form[i]...), c)
+					if capabilityFormAccepted(grown, accepted) {
+						sort.Slice(grown[i], func(a, b int) bool { return grown[i][a] < grown[i][b] })
+						form = grown
+						changed = true
+					}
+				}
+			}
+		}
+		capabilityFormTuples(form, func(tuple []OperandClass) bool {
+			covered[fmt.Sprint(tuple)] = true
+			return true
+		})
+		out = append(out, form.String())
+	}
+	return out
+}
+
+func capabilityClassIn(set []OperandClass, c OperandClass) bool {
+	for _, x := range set {
+		if x == c {
+			return true
+		}
+	}
+	return false
+}
+
+func capabilityFormAccepted(form OperandForm, accepted map[string]bool) bool {
+	return capabilityFormTuples(form, func(tuple []OperandClass) bool {
+		return accepted[fmt.Sprint(tuple)]
+	})
+}
+
+// capabilityFormTuples calls fn for every tuple in the cartesian product of
+// form, stopping early (and returning false) when fn returns false.
+func capabilityFormTuples(form OperandForm, fn func([]OperandClass) bool) bool {
+	tuple := make([]OperandClass, len(form))
+	var walk func(i int) bool
+	walk = func(i int) bool {
+		if i == len(form) {
+			return fn(append([]OperandClass(nil), tuple...))
+		}
+		for _, c := range form[i] {
+			tuple[i] = c
+			if !walk(i + 1) {
+				return false
+			}
+		}
+		return true
+	}
+	return walk(0)
+}
+
+// capabilityCommonTuples lists the operand shapes most lowerings use. An
+// opcode that accepts none of them is treated as still unsupported by the
+// spot-check in TestCapabilityTableUpToDate.
+func capabilityCommonTuples(arch Arch) [][]OperandClass {
+	out := [][]OperandClass{{}, {ClassReg}, {ClassImm, ClassReg}, {ClassReg, ClassReg}, {ClassMem, ClassReg}, {ClassReg, ClassReg, ClassReg}}
+	switch arch {
+	case ArchAMD64, Arch386:
+		out = append(out, []OperandClass{ClassXReg, ClassXReg}, []OperandClass{ClassYReg, ClassYReg, ClassYReg})
+	case ArchARM64:
+		out = append(out, []OperandClass{ClassVReg, ClassVReg}, []OperandClass{ClassVReg, ClassVReg, ClassVReg})
+	}
+	return out
+}
+
+func capabilityClasses(arch Arch) []OperandClass {
+	classes := make([]OperandClass, 0, len(capabilitySamples[arch]))
+	for c := range capabilitySamples[arch] {
+		classes = append(classes, c)
+	}
+	sort.Slice(classes, func(i, j int) bool { return classes[i] < classes[j] })
+	return classes
+}
+
+// capabilityProbe lowers "op form" inside a minimal function through the CFG
+// translator of arch and reports whether it succeeds.
+func capabilityProbe(arch Arch, op string, form []OperandClass) bool {
+	variants := capabilitySampleVariants(arch, form)
+	for v := 0; v < variants; v++ {
+		if capabilityLowers(arch, capabilitySampleInstr(arch, op, form, v)) {
+			return true
+		}
+	}
+	return false
+}
+
+// capabilitySampleVariants is the number of sample spellings worth trying
+// for form.
+func capabilitySampleVariants(arch Arch, form []OperandClass) int {
+	variants := 1
+	for _, c := range form {
+		if n := len(capabilitySamples[arch][c]); n > variants {
+			variants = n
+		}
+	}
+	return variants
+}
+
+// capabilitySampleInstr builds "op form" from the v-th sample of each class.
+func capabilitySampleInstr(arch Arch, op string, form []OperandClass, v int) Instr {
+	args := make([]Operand, 0, len(form))
+	raw := make([]string, 0, len(form))
+	for i, c := range form {
+		samples := capabilitySamples[arch][c]
+		s := samples[minInt(v, len(samples)-1)]
+		// A wasm store names its destination slot as its only operand.
+		if c == ClassFP && i == len(form)-1 && (len(form) > 1 || arch == ArchWasm && strings.Contains(op, "STORE")) {
+			s = capabilityResultFP[arch]
+		}
+		var a Operand
+		var err error
+		if archIsMIPS(arch) {
+			// W, HI and LO are only registers to the MIPS operand parser.
+			var ops []Operand
+			if ops, err = mipsParseOperands(s); err == nil {
+				a = ops[0]
+			}
+		} else if arch == ArchWasm {
+			// SP is a global rather than a register to parseOperand.
+			var ops []Operand
+			if ops, err = wasmParseOperands(s); err == nil {
+				a = ops[0]
+			}
+		} else {
+			a, err = parseOperand(s)
+		}
+		if err != nil {
+			panic(fmt.Sprintf("bad capability sample %q: %v", s, err))
+		}
+		args = append(args, a)
+		raw = append(raw, s)
+	}
+	return Instr{Op: Op(op), Args: args, Raw: op + " " + strings.Join(raw, ", ")}
+}
+
+// wasmCapabilityProbe places ins in unreachable code, where the value
+// stack yields operands of any type, and wraps the structured
+// instructions in their construct; branches target a Block labelled
+// probe_target.
+func wasmCapabilityProbe(instrs []Instr, ins Instr) []Instr {
+	mk := func(op string) Instr { return Instr{Op: Op(op), Raw: op} }
+	out := []Instr{instrs[0], mk("UNREACHABLE")}
+	switch ins.Op {
+	case "ELSE", "END":
+		out = append(out, mk("IF"))
+	case "BR", "BRIF":
+		out = append(out, instrs[2], mk("BLOCK"))
+	}
+	out = append(out, ins)
+	switch ins.Op {
+	case "BLOCK", "LOOP", "IF", "ELSE", "BR", "BRIF":
+		out = append(out, mk("END"))
+	}
+	return append(out, instrs[2:]...)
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func capabilityLowers(arch Arch, ins Instr) (ok bool) {
+	defer func() {
+		if recover() != nil {
+			ok = false
+		}
+	}()
+	word := I64
+	if arch == ArchARM || arch == Arch386 || arch == ArchMIPS {
+		word = I32
+	}
+	fn := Func{Sym: "·probe", Instrs: []Instr{
+		{Op: OpTEXT, Raw: "TEXT ·probe(SB)"},
+		ins,
+		{Op: OpLABEL, Args: []Operand{{Kind: OpLabel, Sym: "probe_target"}}, Raw: "probe_target:"},
+		{Op: OpRET, Raw: "RET"},
+	}}
+	resultOff := int64(8)
+	if arch == ArchARM || arch == Arch386 || arch == ArchMIPS {
+		resultOff = 4
+	}
+	sig := FuncSig{
+		Name: "·probe",
+		Args: []LLVMType{word},
+		Ret:  word,
+		Frame: FrameLayout{
+			Params:  []FrameSlot{{Offset: 0, Type: word, Index: 0, Field: -1}},
+			Results: []FrameSlot{{Offset: resultOff, Type: word, Index: 0, Field: -1}},
+		},
+	}
+	sigs := map[string]FuncSig{
+		"·probe":     sig,
+		"·probe_sym": {Name: "·probe_sym", Ret: Void},
+	}
+	if arch == ArchWasm {
+		fn.Instrs = wasmCapabilityProbe(fn.Instrs, ins)
+	}
+	resolve := func(s string) string { return s }
+	var b strings.Builder
+	var err error
+	switch arch {
+	case ArchAMD64:
+		err = translateFuncAMD64(&b, fn, sig, resolve, sigs, lowerConfig{})
+	case Arch386:
+		err = translateFunc386(&b, fn, sig, resolve, sigs, lowerConfig{})
+	case ArchARM64:
+		err = translateFuncARM64(&b, fn, sig, resolve, sigs, lowerConfig{})
+	case ArchARM:
+		err = translateFuncARM(&b, fn, sig, resolve, sigs, lowerConfig{})
+	case ArchRISCV64:
+		err = translateFuncRISCV64(&b, fn, sig, resolve, sigs, lowerConfig{})
+	case ArchLOONG64:
+		err = translateFuncLOONG64(&b, fn, sig, resolve, sigs, lowerConfig{})
+	case ArchPPC64:
+		err = translateFuncPPC64(&b, fn, sig, resolve, sigs, lowerConfig{})
+	case ArchS390X:
+		err = translateFuncS390X(&b, fn, sig, resolve, sigs, lowerConfig{})
+	case ArchMIPS, ArchMIPS64:
+		err = translateFuncMIPS(&b, arch, fn, sig, resolve, sigs, lowerConfig{})
+	case ArchWasm:
+		err = translateFuncWasm(&b, fn, sig, resolve, sigs, lowerConfig{})
+	default:
+		return false
+	}
+	return err == nil
+}
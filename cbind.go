@@ -0,0 +1,336 @@
+package plan9asm
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"sort"
+	"strings"
+)
+
+// CBindingOptions configures GenerateCBindings.
+type CBindingOptions struct {
+	// Goarch is the architecture the bindings are compiled for. It decides
+	// which aggregate results C can receive; empty means amd64.
+	Goarch string
+
+	// Header is the file name of the header, used for its include guard
+	// and by the cgo file to include it. Empty means "plan9asm.h".
+	Header string
+
+	// CgoPackage, when set, also generates a Go file in that package with
+	// one cgo wrapper per function. The translated object is linked by
+	// placing it in the package directory as a .syso file.
+	CgoPackage string
+}
+
+// CBindings holds the files generated by GenerateCBindings.
+type CBindings struct {
+	Header []byte
+	// Cgo is nil unless CBindingOptions.CgoPackage is set.
+	Cgo []byte
+	// Skipped maps the symbols that have no C prototype to the reason.
+	Skipped map[string]string
+}
+
+// GenerateCBindings renders a C header declaring the functions in sigs,
+// keyed by resolved symbol name as in GoModuleTranslation.Signatures or
+// Options.Sigs, and optionally cgo wrappers for them.
+//
+// Each function gets a C identifier made from its symbol, with the symbol
+// itself bound through an asm("...") label. Aggregate arguments are
+// flattened into one parameter per scalar, as LLVM passes them. An
+// aggregate result becomes a struct only where the C ABI returns it in the
+// same registers as LLVM: two fields in separate words on amd64 and arm64
+// (of one float type, or both integers, on arm64). Other functions are
+// listed in Skipped and noted in the header.
+func GenerateCBindings(sigs map[string]FuncSig, opt CBindingOptions) (*CBindings, error) {
+	goarch := opt.Goarch
+	if goarch == "" {
+		goarch = "amd64"
+	}
+	header := opt.Header
+	if header == "" {
+		header = "plan9asm.h"
+	}
+
+	names := make([]string, 0, len(sigs))
+	for name := range sigs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var fns []cFunc
+	skipped := map[string]string{}
+	used := map[string]bool{}
+	for _, name := range names {
+		fn, err := newCFunc(name, sigs[name], goarch)
+		if err != nil {
+			skipped[name] = err.Error()
+			continue
+		}
+		base := fn.ident
+		for i := 2; used[fn.ident] || used[fn.ident+"_ret"]; i++ {
+			fn.ident = fmt.Sprintf("%s_%d", base, i)
+		}
+		used[fn.ident], used[fn.ident+"_ret"] = true, true
+		fns = append(fns, fn)
+	}
+
+	out := &CBindings{Header: renderCHeader(header, fns, names, skipped), Skipped: skipped}
+	if opt.CgoPackage != "" {
+		src, err := format.Source(renderCgo(opt.CgoPackage, header, fns))
+		if err != nil {
+			return nil, fmt.Errorf("format cgo bindings: %v", err)
+		}
+		out.Cgo = src
+	}
+	return out, nil
+}
+
+// cFunc is a function as C sees it.
+type cFunc struct {
+	sym    string
+	ident  string
+	params []LLVMType // flattened scalars
+	ret    []LLVMType // nil for void; two fields return a struct
+}
+
+func newCFunc(sym string, sig FuncSig, goarch string) (cFunc, error) {
+	fn := cFunc{sym: sym, ident: cIdent(sym)}
+	for i, t := range sig.Args {
+		scalars, err := cFlatten(t)
+		if err != nil {
+			return cFunc{}, fmt.Errorf("argument %d: %v", i, err)
+		}
+		fn.params = append(fn.params, scalars...)
+	}
+	if sig.Ret == Void || sig.Ret == "" {
+		return fn, nil
+	}
+	ret, err := cFlatten(sig.Ret)
+	if err != nil {
+		return cFunc{}, fmt.Errorf("result: %v", err)
+	}
+	if len(ret) > 1 && !cStructReturnMatches(ret, goarch) {
+		return cFunc{}, fmt.Errorf("result %s is not returned as a C struct on %s", sig.Ret, goarch)
+	}
+	fn.ret = ret
+	return fn, nil
+}
+
+// cFlatten splits an LLVM type into the scalars it is passed as.
+func cFlatten(t LLVMType) ([]LLVMType, error) {
+	s := strings.TrimSpace(string(t))
+	switch {
+	case strings.HasPrefix(s, "{") && strings.HasSuffix(s, "}"):
+		var out []LLVMType
+		for _, f := range splitTopLevel(s[1 : len(s)-1]) {
+			scalars, err := cFlatten(LLVMType(f))
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, scalars...)
+		}
+		if len(out) == 0 {
+			return nil, fmt.Errorf("empty struct %s", t)
+		}
+		return out, nil
+	case strings.HasPrefix(s, "[") && strings.HasSuffix(s, "]"):
+		var n int
+		var elem string
+		if c, err := fmt.Sscanf(s[1:len(s)-1], "%d x", &n); c != 1 || err != nil || n <= 0 {
+			return nil, fmt.Errorf("unsupported array %s", t)
+		}
+		_, elem, _ = strings.Cut(s[1:len(s)-1], " x ")
+		scalars, err := cFlatten(LLVMType(elem))
+		if err != nil {
+			return nil, err
+		}
+		var out []LLVMType
+		for i := 0; i < n; i++ {
+			out = append(out, scalars...)
+		}
+		return out, nil
+	}
+	if _, ok := cScalarTypes[LLVMType(s)]; !ok {
+		return nil, fmt.Errorf("no C type for %s", t)
+	}
+	return []LLVMType{LLVMType(s)}, nil
+}
+
+// splitTopLevel splits s at the commas outside braces and brackets.
+func splitTopLevel(s string) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '{', '[', '<':
+			depth++
+		case '}', ']', '>':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, strings.TrimSpace(s[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	if last := strings.TrimSpace(s[start:]); last != "" {
+		parts = append(parts, last)
+	}
+	return parts
+}
+
+// cScalarTypes maps the scalar LLVM types to C and cgo: the C type, the Go
+// type and its size.
+var cScalarTypes = map[LLVMType]struct {
+	c, goType string
+	size      int
+}{
+	I1:       {"_Bool", "bool", 1},
+	I8:       {"int8_t", "int8", 1},
+	I16:      {"int16_t", "int16", 2},
+	I32:      {"int32_t", "int32", 4},
+	I64:      {"int64_t", "int64", 8},
+	Ptr:      {"void *", "unsafe.Pointer", 8},
+	"float":  {"float", "float32", 4},
+	"double": {"double", "float64", 8},
+}
+
+// cStructReturnMatches reports whether C returns a struct of fields in the
+// registers LLVM returns the literal struct in: one register per field, in
+// order, within the two the C ABI uses.
+func cStructReturnMatches(fields []LLVMType, goarch string) bool {
+	if len(fields) != 2 {
+		return false
+	}
+	isFloat := func(t LLVMType) bool { return t == "float" || t == "double" }
+	if goarch == "arm64" && (isFloat(fields[0]) || isFloat(fields[1])) {
+		// Only a homogeneous pair of floats comes back in float registers.
+		return fields[0] == fields[1]
+	}
+	// Otherwise each field needs a word to itself.
+	if cScalarTypes[fields[0]].size != 8 && cScalarTypes[fields[1]].size != 8 {
+		return false
+	}
+	return goarch == "amd64" || goarch == "arm64"
+}
+
+// cIdent makes a C identifier of a symbol name.
+func cIdent(sym string) string {
+	var b strings.Builder
+	for _, r := range sym {
+		if r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte('_')
+		}
+	}
+	s := b.String()
+	if s == "" || s[0] >= '0' && s[0] <= '9' {
+		s = "_" + s
+	}
+	return s
+}
+
+func renderCHeader(header string, fns []cFunc, names []string, skipped map[string]string) []byte {
+	guard := strings.ToUpper(cIdent(header))
+	var b bytes.Buffer
+	b.WriteString("// Code generated by plan9asm; DO NOT EDIT.\n\n")
+	fmt.Fprintf(&b, "#ifndef %s\n#define %s\n\n#include <stdint.h>\n\n", guard, guard)
+	b.WriteString("#ifdef __cplusplus\nextern \"C\" {\n#endif\n\n")
+	for _, fn := range fns {
+		ret := "void"
+		switch len(fn.ret) {
+		case 1:
+			ret = cScalarTypes[fn.ret[0]].c
+		case 2:
+			ret = fn.ident + "_ret"
+			fmt.Fprintf(&b, "typedef struct { %s r0; %s r1; } %s;\n", cScalarTypes[fn.ret[0]].c, cScalarTypes[fn.ret[1]].c, ret)
+		}
+		params := make([]string, len(fn.params))
+		for i, t := range fn.params {
+			params[i] = cDecl(cScalarTypes[t].c, fmt.Sprintf("a%d", i))
+		}
+		if len(params) == 0 {
+			params = []string{"void"}
+		}
+		fmt.Fprintf(&b, "%s(%s) __asm__(%q);\n", cDecl(ret, fn.ident), strings.Join(params, ", "), fn.sym)
+	}
+	for _, name := range names {
+		if reason, ok := skipped[name]; ok {
+			fmt.Fprintf(&b, "/* %s: %s */\n", name, reason)
+		}
+	}
+	b.WriteString("\n#ifdef __cplusplus\n}\n#endif\n\n")
+	fmt.Fprintf(&b, "#endif /* %s */\n", guard)
+	return b.Bytes()
+}
+
+// cDecl declares name with C type t.
+func cDecl(t, name string) string {
+	if strings.HasSuffix(t, "*") {
+		return t + name
+	}
+	return t + " " + name
+}
+
+func renderCgo(pkg, header string, fns []cFunc) []byte {
+	var b bytes.Buffer
+	b.WriteString("// Code generated by plan9asm; DO NOT EDIT.\n\n")
+	fmt.Fprintf(&b, "package %s\n\n/*\n#include %q\n*/\nimport \"C\"\n\n", pkg, header)
+	usesUnsafe := false
+	for _, fn := range fns {
+		for _, t := range append(append([]LLVMType(nil), fn.params...), fn.ret...) {
+			usesUnsafe = usesUnsafe || t == Ptr
+		}
+	}
+	if usesUnsafe {
+		b.WriteString("import \"unsafe\"\n\n")
+	}
+	for _, fn := range fns {
+		params := make([]string, len(fn.params))
+		args := make([]string, len(fn.params))
+		for i, t := range fn.params {
+			params[i] = fmt.Sprintf("a%d %s", i, cScalarTypes[t].goType)
+			args[i] = cgoArg(t, fmt.Sprintf("a%d", i))
+		}
+		results := ""
+		switch len(fn.ret) {
+		case 1:
+			results = " " + cScalarTypes[fn.ret[0]].goType
+		case 2:
+			results = fmt.Sprintf(" (%s, %s)", cScalarTypes[fn.ret[0]].goType, cScalarTypes[fn.ret[1]].goType)
+		}
+		fmt.Fprintf(&b, "// %s calls %s.\n", fn.ident, fn.sym)
+		fmt.Fprintf(&b, "func %s(%s)%s {\n", fn.ident, strings.Join(params, ", "), results)
+		call := fmt.Sprintf("C.%s(%s)", fn.ident, strings.Join(args, ", "))
+		switch len(fn.ret) {
+		case 0:
+			fmt.Fprintf(&b, "\t%s\n", call)
+		case 1:
+			fmt.Fprintf(&b, "\treturn %s\n", goResult(fn.ret[0], call))
+		default:
+			fmt.Fprintf(&b, "\tr := %s\n\treturn %s, %s\n", call, goResult(fn.ret[0], "r.r0"), goResult(fn.ret[1], "r.r1"))
+		}
+		b.WriteString("}\n\n")
+	}
+	return b.Bytes()
+}
+
+// cgoArg converts the Go value v of type t for a C call.
+func cgoArg(t LLVMType, v string) string {
+	if t == Ptr {
+		return v
+	}
+	return fmt.Sprintf("C.%s(%s)", cScalarTypes[t].c, v)
+}
+
+// goResult converts the C value v of type t to Go.
+func goResult(t LLVMType, v string) string {
+	if t == Ptr {
+		return v
+	}
+	return fmt.Sprintf("%s(%s)", cScalarTypes[t].goType, v)
+}
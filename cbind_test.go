@@ -0,0 +1,212 @@
+//go:build !llgo
+// +build !llgo
+
+package plan9asm
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestGenerateCBindings(t *testing.T) {
+	sigs := map[string]FuncSig{
+		"example.Add":       {Args: []LLVMType{I64, I64}, Ret: I64},
+		"example.Has":       {Args: []LLVMType{"{ ptr, i64 }", I8}, Ret: I1},
+		"example.Sum4":      {Args: []LLVMType{"[4 x i64]"}, Ret: "double"},
+		"example.DivMod":    {Args: []LLVMType{I64, I64}, Ret: "{ i64, i64 }"},
+		"example.Split":     {Args: []LLVMType{"{ ptr, i64, i64 }"}, Ret: "{ ptr, i64, i64 }"},
+		"example.Halves":    {Args: []LLVMType{I64}, Ret: "{ i32, i32 }"},
+		"example.Vec":       {Args: []LLVMType{"<4 x i32>"}, Ret: Void},
+		"example_Add":       {Ret: Void},
+		"runtime.procyield": {Args: []LLVMType{I32}, Ret: Void},
+	}
+	b, err := GenerateCBindings(sigs, CBindingOptions{Header: "example.h", CgoPackage: "example"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := string(b.Header)
+	for _, want := range []string{
+		"#ifndef EXAMPLE_H\n",
+		`int64_t example_Add(int64_t a0, int64_t a1) __asm__("example.Add");`,
+		`void example_Add_2(void) __asm__("example_Add");`,
+		`_Bool example_Has(void *a0, int64_t a1, int8_t a2) __asm__("example.Has");`,
+		`double example_Sum4(int64_t a0, int64_t a1, int64_t a2, int64_t a3) __asm__("example.Sum4");`,
+		"typedef struct { int64_t r0; int64_t r1; } example_DivMod_ret;\n" +
+			`example_DivMod_ret example_DivMod(int64_t a0, int64_t a1) __asm__("example.DivMod");`,
+		`void runtime_procyield(int32_t a0) __asm__("runtime.procyield");`,
+		"/* example.Split: result { ptr, i64, i64 } is not returned as a C struct on amd64 */",
+	} {
+		if !strings.Contains(h, want) {
+			t.Errorf("header lacks %q:\n%s", want, h)
+		}
+	}
+	if len(b.Skipped) != 3 || b.Skipped["example.Halves"] == "" || b.Skipped["example.Vec"] == "" {
+		t.Errorf("skipped = %v", b.Skipped)
+	}
+
+	g := string(b.Cgo)
+	for _, want := range []string{
+		"package example\n",
+		"#include \"example.h\"\n",
+		"import \"unsafe\"\n",
+		"func example_Has(a0 unsafe.Pointer, a1 int64, a2 int8) bool {\n\treturn bool(C.example_Has(a0, C.int64_t(a1), C.int8_t(a2)))\n}",
+		"func example_DivMod(a0 int64, a1 int64) (int64, int64) {\n\tr := C.example_DivMod(C.int64_t(a0), C.int64_t(a1))\n\treturn int64(r.r0), int64(r.r1)\n}",
+		"func runtime_procyield(a0 int32) {\n\tC.runtime_procyield(C.int32_t(a0))\n}",
+	} {
+		if !strings.Contains(g, want) {
+			t.Errorf("cgo file lacks %q:\n%s", want, g)
+		}
+	}
+
+	// On arm64 only a homogeneous float pair comes back in float registers.
+	b, err = GenerateCBindings(map[string]FuncSig{
+		"example.F": {Ret: "{ double, double }"},
+		"example.G": {Ret: "{ i64, double }"},
+	}, CBindingOptions{Goarch: "arm64"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Cgo != nil || len(b.Skipped) != 1 || b.Skipped["example.G"] == "" {
+		t.Errorf("arm64: skipped = %v, cgo %q", b.Skipped, b.Cgo)
+	}
+}
+
+// amd64SumLen sums a []int64 and also returns its length.
+const amd64SumLen = `TEXT ·SumLen(SB),NOSPLIT,$0-40
+	MOVQ	s_base+0(FP), SI
+	MOVQ	s_len+8(FP), CX
+	MOVQ	CX, DX
+	XORQ	AX, AX
+loop:
+	TESTQ	CX, CX
+	JEQ	done
+	ADDQ	(SI), AX
+	ADDQ	$8, SI
+	DECQ	CX
+	JMP	loop
+done:
+	MOVQ	AX, ret+24(FP)
+	MOVQ	DX, ret1+32(FP)
+	RET
+`
+
+func sumLenSigs() map[string]FuncSig {
+	sig := sigWithClassicFrame("example.SumLen", []LLVMType{"{ ptr, i64, i64 }"}, Void)
+	sig.Ret = "{ i64, i64 }"
+	sig.Frame.Results = []FrameSlot{
+		{Offset: 24, Type: I64, Index: 0, Field: -1},
+		{Offset: 32, Type: I64, Index: 1, Field: -1},
+	}
+	return map[string]FuncSig{sig.Name: sig}
+}
+
+func translateSumLen(t *testing.T) string {
+	t.Helper()
+	ll, err := translateIRText(mustParse(t, ArchAMD64, amd64SumLen), Options{
+		TargetTriple: testTargetTriple(runtime.GOOS, runtime.GOARCH),
+		Goarch:       "amd64",
+		ResolveSym:   testResolveSym("example"),
+		Sigs:         sumLenSigs(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ll
+}
+
+func TestRuntimeExecAMD64CHeader(t *testing.T) {
+	if runtime.GOARCH != "amd64" || runtime.GOOS != "linux" {
+		t.Skip("runtime execution test only runs on a linux/amd64 host")
+	}
+	llc, clang, ok := findLlcAndClang(t)
+	if !ok {
+		t.Skip("llc/clang not found")
+	}
+	b, err := GenerateCBindings(sumLenSigs(), CBindingOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := filepath.Join(t.TempDir(), "plan9asm.h")
+	if err := os.WriteFile(header, b.Header, 0644); err != nil {
+		t.Fatal(err)
+	}
+	mainC := `
+#include "` + header + `"
+int main(void) {
+	int64_t xs[] = {1, 2, 3, 4};
+	example_SumLen_ret r = example_SumLen(xs, 4, 4);
+	return r.r0 == 10 && r.r1 == 4 ? 0 : 11;
+}
+`
+	compileAndRunRuntimeTest(t, llc, clang, "amd64_sumlen", translateSumLen(t), mainC)
+}
+
+func TestRuntimeExecAMD64Cgo(t *testing.T) {
+	if runtime.GOARCH != "amd64" || runtime.GOOS != "linux" {
+		t.Skip("runtime execution test only runs on a linux/amd64 host")
+	}
+	if testing.Short() {
+		t.Skip("builds a cgo program")
+	}
+	llc, _, ok := findLlcAndClang(t)
+	if !ok {
+		t.Skip("llc not found")
+	}
+	goTool := filepath.Join(runtime.GOROOT(), "bin", "go")
+	if out, err := exec.Command(goTool, "env", "CGO_ENABLED").Output(); err != nil || strings.TrimSpace(string(out)) != "1" {
+		t.Skip("cgo is not enabled")
+	}
+
+	dir := t.TempDir()
+	llPath := filepath.Join(dir, "sumlen.ll")
+	if err := os.WriteFile(llPath, []byte(translateSumLen(t)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	triple := testTargetTriple(runtime.GOOS, runtime.GOARCH)
+	out, err := exec.Command(llc, "-mtriple="+triple, "-relocation-model=pic", "-filetype=obj", llPath, "-o", filepath.Join(dir, "sumlen_amd64.syso")).CombinedOutput()
+	if err != nil {
+		t.Fatalf("llc: %v\n%s", err, out)
+	}
+	if err := os.Remove(llPath); err != nil {
+		t.Fatal(err)
+	}
+	b, err := GenerateCBindings(sumLenSigs(), CBindingOptions{CgoPackage: "main"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"go.mod":      "module sumlen\n\ngo 1.21\n",
+		"plan9asm.h":  string(b.Header),
+		"bindings.go": string(b.Cgo),
+		"main.go": `package main
+
+import (
+	"os"
+	"unsafe"
+)
+
+func main() {
+	xs := []int64{5, 6, 7}
+	sum, n := example_SumLen(unsafe.Pointer(&xs[0]), int64(len(xs)), int64(cap(xs)))
+	if sum != 18 || n != 3 {
+		os.Exit(11)
+	}
+}
+`,
+	}
+	for name, src := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cmd := exec.Command(goTool, "run", ".")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOWORK=off", "GOFLAGS=")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("go run: %v\n%s\n%s", err, out, b.Cgo)
+	}
+}
@@ -6,8 +6,6 @@ import (
 	"errors"
 	"flag"
 	"fmt"
-	"go/ast"
-	"go/types"
 	"io"
 	"os"
 	"os/exec"
@@ -82,7 +80,7 @@ func runList(args []string) error {
 		goarch string
 	)
 	fs.StringVar(&goos, "goos", runtime.GOOS, "target GOOS")
-	fs.StringVar(&goarch, "goarch", runtime.GOARCH, "target GOARCH (amd64/arm64/arm/386)")
+	fs.StringVar(&goarch, "goarch", runtime.GOARCH, "target GOARCH (amd64/arm64/arm/386/riscv64/loong64/ppc64le/s390x/mips/mipsle/mips64/mips64le/wasm)")
 	if err := fs.Parse(args); err != nil {
 		return err
 	}
@@ -150,7 +148,7 @@ func runTranspile(args []string) error {
 	fs.BoolVar(&annotate, "annotate", true, "emit source asm lines as IR comments")
 	fs.StringVar(&inFile, "i", "", "Plan9 asm .s file path")
 	fs.StringVar(&outFile, "o", "", "output .ll file path")
-	fs.StringVar(&goarch, "goarch", runtime.GOARCH, "target GOARCH (amd64/arm64/arm/386)")
+	fs.StringVar(&goarch, "goarch", runtime.GOARCH, "target GOARCH (amd64/arm64/arm/386/riscv64/loong64/ppc64le/s390x/mips/mipsle/mips64/mips64le/wasm)")
 	fs.StringVar(&goos, "goos", runtime.GOOS, "target GOOS")
 	fs.StringVar(&metaFile, "meta", "", "optional output metadata json path")
 	fs.StringVar(&patterns, "patterns", "", "deprecated comma-separated package patterns")
@@ -372,6 +370,7 @@ func translateAsmForPackage(pkg *packages.Package, asmPath, goos, goarch string,
 		ResolveSym:     resolve,
 		Sigs:           sigs,
 		Goarch:         goarch,
+		Goos:           goos,
 		AnnotateSource: annotate,
 	})
 	if err != nil {
@@ -411,14 +410,34 @@ func collectFunctionInfo(file *plan9asm.File, sigs map[string]plan9asm.FuncSig,
 
 func toPlan9Arch(goarch string) (plan9asm.Arch, error) {
 	switch goarch {
-	case "amd64", "386":
+	case "amd64":
 		return plan9asm.ArchAMD64, nil
+	case "386":
+		return plan9asm.Arch386, nil
 	case "arm":
 		return plan9asm.ArchARM, nil
 	case "arm64":
 		return plan9asm.ArchARM64, nil
+	case "riscv64":
+		return plan9asm.ArchRISCV64, nil
+	case "loong64":
+		return plan9asm.ArchLOONG64, nil
+	case "ppc64le":
+		return plan9asm.ArchPPC64, nil
+	case "s390x":
+		return plan9asm.ArchS390X, nil
+	case "mips":
+		return plan9asm.ArchMIPS, nil
+	case "mipsle":
+		return plan9asm.ArchMIPSLE, nil
+	case "mips64":
+		return plan9asm.ArchMIPS64, nil
+	case "mips64le":
+		return plan9asm.ArchMIPS64LE, nil
+	case "wasm":
+		return plan9asm.ArchWasm, nil
 	default:
-		return "", fmt.Errorf("unsupported -goarch %q (expect amd64/arm64/arm/386)", goarch)
+		return "", fmt.Errorf("unsupported -goarch %q (expect amd64/arm64/arm/386/riscv64/loong64/ppc64le/s390x/mips/mipsle/mips64/mips64le/wasm)", goarch)
 	}
 }
 
@@ -441,6 +460,22 @@ func targetTriple(goos, goarch string) string {
 			return "aarch64-unknown-linux-gnu"
 		case "386":
 			return "i386-unknown-linux-gnu"
+		case "riscv64":
+			return "riscv64-unknown-linux-gnu"
+		case "loong64":
+			return "loongarch64-unknown-linux-gnu"
+		case "ppc64le":
+			return "powerpc64le-unknown-linux-gnu"
+		case "s390x":
+			return "s390x-unknown-linux-gnu"
+		case "mips":
+			return "mips-unknown-linux-gnu"
+		case "mipsle":
+			return "mipsel-unknown-linux-gnu"
+		case "mips64":
+			return "mips64-unknown-linux-gnuabi64"
+		case "mips64le":
+			return "mips64el-unknown-linux-gnuabi64"
 		}
 	case "windows":
 		switch goarch {
@@ -451,6 +486,10 @@ func targetTriple(goos, goarch string) string {
 		case "386":
 			return "i686-pc-windows-msvc"
 		}
+	case "wasip1", "js":
+		if goarch == "wasm" {
+			return "wasm32-unknown-wasi"
+		}
 	}
 	return ""
 }
@@ -715,22 +754,13 @@ func sigsForAsmFile(pkg *packages.Package, file *plan9asm.File, resolve func(str
 	sigs := map[string]plan9asm.FuncSig{}
 	if pkg == nil || pkg.Types == nil || pkg.Types.Scope() == nil {
 		for _, fn := range file.Funcs {
-			fs := fallbackSigForAsmFunc(fn, resolve(stripABISuffix(fn.Sym)))
+			fs := fallbackSigForAsmFunc(fn, file.Arch, resolve(stripABISuffix(fn.Sym)))
 			sigs[fs.Name] = fs
 		}
 		return sigs, nil
 	}
 
-	sz := pkg.TypesSizes
-	if sz == nil {
-		sz = types.SizesFor("gc", goarch)
-	}
-	if sz == nil {
-		return nil, fmt.Errorf("missing type sizes for %q", goarch)
-	}
-
-	scope := pkg.Types.Scope()
-	linknames := linknameRemoteToLocal(pkg.Syntax)
+	gopkg := plan9asm.GoPackage{Path: pkg.PkgPath, Types: pkg.Types, Syntax: pkg.Syntax}
 
 	for _, fn := range file.Funcs {
 		sym := stripABISuffix(fn.Sym)
@@ -738,12 +768,12 @@ func sigsForAsmFile(pkg *packages.Package, file *plan9asm.File, resolve func(str
 		if resolved == "" {
 			continue
 		}
-		fs, ok, err := tryDeclSig(scope, sym, resolved, linknames, goarch, sz)
+		fs, ok, err := plan9asm.GoDeclSig(gopkg, sym, resolved, goarch)
 		if err != nil {
 			return nil, err
 		}
 		if !ok {
-			fs = fallbackSigForAsmFunc(fn, resolved)
+			fs = fallbackSigForAsmFunc(fn, file.Arch, resolved)
 		}
 		sigs[resolved] = fs
 	}
@@ -760,7 +790,7 @@ func sigsForAsmFile(pkg *packages.Package, file *plan9asm.File, resolve func(str
 		if _, ok := sigs[resolved]; ok {
 			return
 		}
-		fs, ok, err := tryDeclSig(scope, sym, resolved, linknames, goarch, sz)
+		fs, ok, err := plan9asm.GoDeclSig(gopkg, sym, resolved, goarch)
 		if err == nil && ok {
 			sigs[resolved] = fs
 			return
@@ -801,144 +831,12 @@ func sigsForAsmFile(pkg *packages.Package, file *plan9asm.File, resolve func(str
 	return sigs, nil
 }
 
-func fallbackSigForAsmFunc(fn plan9asm.Func, resolved string) plan9asm.FuncSig {
-	paramOff := map[int64]struct{}{}
-	retOff := map[int64]struct{}{}
-
-	for _, ins := range fn.Instrs {
-		op := strings.ToUpper(string(ins.Op))
-		for i, a := range ins.Args {
-			if a.Kind != plan9asm.OpFP {
-				continue
-			}
-			if isLikelyResultSlot(op, i, len(ins.Args), a.FPName) {
-				retOff[a.FPOffset] = struct{}{}
-			} else {
-				paramOff[a.FPOffset] = struct{}{}
-			}
-		}
-	}
-
-	paramList := sortOffsets(paramOff)
-	retList := sortOffsets(retOff)
-	params := make([]plan9asm.FrameSlot, 0, len(paramList))
-	for i, off := range paramList {
-		params = append(params, plan9asm.FrameSlot{Offset: off, Type: plan9asm.I64, Index: i, Field: -1})
-	}
-	results := make([]plan9asm.FrameSlot, 0, len(retList))
-	for i, off := range retList {
-		results = append(results, plan9asm.FrameSlot{Offset: off, Type: plan9asm.I64, Index: i, Field: -1})
-	}
-
-	args := make([]plan9asm.LLVMType, len(params))
-	for i := range args {
-		args[i] = plan9asm.I64
-	}
-	ret := plan9asm.Void
-	switch len(results) {
-	case 0:
-		ret = plan9asm.I64
-	case 1:
-		ret = plan9asm.I64
-	default:
-		parts := make([]string, 0, len(results))
-		for range results {
-			parts = append(parts, string(plan9asm.I64))
-		}
-		ret = plan9asm.LLVMType("{ " + strings.Join(parts, ", ") + " }")
-	}
-	return plan9asm.FuncSig{
-		Name: resolved,
-		Args: args,
-		Ret:  ret,
-		Frame: plan9asm.FrameLayout{
-			Params:  params,
-			Results: results,
-		},
-	}
-}
-
-func isLikelyResultSlot(op string, argIndex int, argCount int, fpName string) bool {
-	name := strings.ToLower(fpName)
-	if strings.HasPrefix(name, "ret") || strings.HasPrefix(name, "r") && strings.Contains(name, "ret") {
-		return true
-	}
-	if argCount > 0 && argIndex == argCount-1 {
-		switch {
-		case strings.HasPrefix(op, "MOV"), strings.HasPrefix(op, "VMOV"), strings.HasPrefix(op, "FMOV"):
-			return true
-		}
-	}
-	return false
-}
-
-func sortOffsets(m map[int64]struct{}) []int64 {
-	out := make([]int64, 0, len(m))
-	for k := range m {
-		out = append(out, k)
-	}
-	sort.Slice(out, func(i, j int) bool { return out[i] < out[j] })
-	return out
-}
-
-func tryDeclSig(scope *types.Scope, sym, resolved string, linknames map[string]string, goarch string, sz types.Sizes) (plan9asm.FuncSig, bool, error) {
-	declName := strings.TrimPrefix(sym, "·")
-	if strings.ContainsRune(declName, '·') {
-		key := strings.ReplaceAll(sym, "∕", "/")
-		key = strings.ReplaceAll(key, "·", ".")
-		if local, ok := linknames[key]; ok {
-			declName = local
-		} else {
-			return plan9asm.FuncSig{}, false, nil
-		}
-	}
-	obj := scope.Lookup(declName)
-	if obj == nil {
-		return plan9asm.FuncSig{}, false, nil
-	}
-	fn, ok := obj.(*types.Func)
-	if !ok {
-		return plan9asm.FuncSig{}, false, nil
-	}
-	sig := fn.Type().(*types.Signature)
-	if sig.Recv() != nil || sig.Variadic() {
-		return plan9asm.FuncSig{}, false, nil
-	}
-
-	args, frameParams, nextOff, err := llvmArgsAndFrameSlotsForTuple(sig.Params(), goarch, sz, 0, false)
-	if err != nil {
-		return plan9asm.FuncSig{}, false, fmt.Errorf("%s: %w", fn.FullName(), err)
-	}
-	nextOff = alignOff(nextOff, int64(wordSize(goarch)))
-	retTys, frameResults, _, err := llvmArgsAndFrameSlotsForTuple(sig.Results(), goarch, sz, nextOff, true)
-	if err != nil {
-		return plan9asm.FuncSig{}, false, fmt.Errorf("%s: %w", fn.FullName(), err)
-	}
-	ret := tupleRetType(retTys)
-	return plan9asm.FuncSig{
-		Name: resolved,
-		Args: args,
-		Ret:  ret,
-		Frame: plan9asm.FrameLayout{
-			Params:  frameParams,
-			Results: frameResults,
-		},
-	}, true, nil
-}
-
-func tupleRetType(ts []plan9asm.LLVMType) plan9asm.LLVMType {
-	switch len(ts) {
-	case 0:
-		return plan9asm.Void
-	case 1:
-		return ts[0]
-	default:
-		parts := make([]string, 0, len(ts))
-		for _, t := range ts {
-			parts = append(parts, string(t))
-		}
-		return plan9asm.LLVMType("{ " + strings.Join(parts, ", ") + " }")
-	}
+// fallbackSigForAsmFunc infers the signature of an asm function without a
+// Go declaration from its frame references.
+func fallbackSigForAsmFunc(fn plan9asm.Func, arch plan9asm.Arch, resolved string) plan9asm.FuncSig {
+	fs := plan9asm.InferSignature(fn, arch).Sig
+	fs.Name = resolved
+	return fs
 }
 
 func splitSymPlusOff(s string) (base string, off int64) {
@@ -957,196 +855,6 @@ func splitSymPlusOff(s string) (base string, off int64) {
 	return strings.TrimSpace(s[:sep]), n
 }
 
-func linknameRemoteToLocal(files []*ast.File) map[string]string {
-	m := map[string]string{}
-	for _, f := range files {
-		if f == nil {
-			continue
-		}
-		for _, cg := range f.Comments {
-			if cg == nil {
-				continue
-			}
-			for _, c := range cg.List {
-				if c == nil {
-					continue
-				}
-				text := strings.TrimSpace(strings.TrimPrefix(c.Text, "//"))
-				if !strings.HasPrefix(text, "go:linkname ") {
-					continue
-				}
-				parts := strings.Fields(text)
-				if len(parts) < 3 {
-					continue
-				}
-				local := parts[1]
-				remote := strings.ReplaceAll(parts[2], "∕", "/")
-				m[remote] = local
-			}
-		}
-	}
-	return m
-}
-
-func llvmArgsAndFrameSlotsForTuple(tup *types.Tuple, goarch string, sz types.Sizes, startOff int64, flattenAgg bool) (args []plan9asm.LLVMType, slots []plan9asm.FrameSlot, nextOff int64, err error) {
-	if tup == nil || tup.Len() == 0 {
-		return nil, nil, startOff, nil
-	}
-
-	word := int64(wordSize(goarch))
-	wordTy := plan9asm.I64
-	if word == 4 {
-		wordTy = plan9asm.LLVMType("i32")
-	}
-	align := func(off, a int64) int64 {
-		if a <= 1 {
-			return off
-		}
-		m := off % a
-		if m == 0 {
-			return off
-		}
-		return off + (a - m)
-	}
-
-	off := startOff
-	argIdx := 0
-	for i := 0; i < tup.Len(); i++ {
-		t := tup.At(i).Type()
-		off = align(off, int64(sz.Alignof(t)))
-
-		switch u := types.Unalias(t).(type) {
-		case *types.Basic:
-			if u.Kind() == types.String {
-				if flattenAgg {
-					args = append(args, plan9asm.Ptr, wordTy)
-					slots = append(slots,
-						plan9asm.FrameSlot{Offset: off + 0*word, Type: plan9asm.Ptr, Index: argIdx + 0, Field: -1},
-						plan9asm.FrameSlot{Offset: off + 1*word, Type: wordTy, Index: argIdx + 1, Field: -1},
-					)
-					argIdx += 2
-					off += int64(sz.Sizeof(t))
-					continue
-				}
-				ty, e := llvmTypeForGo(t, goarch)
-				if e != nil {
-					return nil, nil, 0, e
-				}
-				args = append(args, ty)
-				slots = append(slots,
-					plan9asm.FrameSlot{Offset: off + 0*word, Type: plan9asm.Ptr, Index: argIdx, Field: 0},
-					plan9asm.FrameSlot{Offset: off + 1*word, Type: wordTy, Index: argIdx, Field: 1},
-				)
-				argIdx++
-				off += int64(sz.Sizeof(t))
-				continue
-			}
-		case *types.Slice:
-			if flattenAgg {
-				args = append(args, plan9asm.Ptr, wordTy, wordTy)
-				slots = append(slots,
-					plan9asm.FrameSlot{Offset: off + 0*word, Type: plan9asm.Ptr, Index: argIdx + 0, Field: -1},
-					plan9asm.FrameSlot{Offset: off + 1*word, Type: wordTy, Index: argIdx + 1, Field: -1},
-					plan9asm.FrameSlot{Offset: off + 2*word, Type: wordTy, Index: argIdx + 2, Field: -1},
-				)
-				argIdx += 3
-				off += int64(sz.Sizeof(t))
-				continue
-			}
-			ty, e := llvmTypeForGo(t, goarch)
-			if e != nil {
-				return nil, nil, 0, e
-			}
-			args = append(args, ty)
-			slots = append(slots,
-				plan9asm.FrameSlot{Offset: off + 0*word, Type: plan9asm.Ptr, Index: argIdx, Field: 0},
-				plan9asm.FrameSlot{Offset: off + 1*word, Type: wordTy, Index: argIdx, Field: 1},
-				plan9asm.FrameSlot{Offset: off + 2*word, Type: wordTy, Index: argIdx, Field: 2},
-			)
-			argIdx++
-			off += int64(sz.Sizeof(t))
-			continue
-		}
-
-		ty, e := llvmTypeForGo(t, goarch)
-		if e != nil {
-			return nil, nil, 0, e
-		}
-		args = append(args, ty)
-		slots = append(slots, plan9asm.FrameSlot{Offset: off, Type: ty, Index: argIdx, Field: -1})
-		argIdx++
-		off += int64(sz.Sizeof(t))
-	}
-	return args, slots, off, nil
-}
-
-func llvmTypeForGo(t types.Type, goarch string) (plan9asm.LLVMType, error) {
-	switch tt := t.(type) {
-	case *types.Basic:
-		switch tt.Kind() {
-		case types.Bool:
-			return plan9asm.I1, nil
-		case types.UnsafePointer:
-			return plan9asm.Ptr, nil
-		case types.Int8, types.Uint8:
-			return plan9asm.I8, nil
-		case types.Int16, types.Uint16:
-			return plan9asm.I16, nil
-		case types.Int32, types.Uint32:
-			return plan9asm.I32, nil
-		case types.Int64, types.Uint64:
-			return plan9asm.I64, nil
-		case types.Int, types.Uint, types.Uintptr:
-			if wordSize(goarch) == 8 {
-				return plan9asm.I64, nil
-			}
-			return plan9asm.I32, nil
-		case types.Float32:
-			return plan9asm.LLVMType("float"), nil
-		case types.Float64:
-			return plan9asm.LLVMType("double"), nil
-		case types.String:
-			if wordSize(goarch) == 8 {
-				return plan9asm.LLVMType("{ ptr, i64 }"), nil
-			}
-			return plan9asm.LLVMType("{ ptr, i32 }"), nil
-		default:
-			return "", fmt.Errorf("unsupported basic type %s", tt.String())
-		}
-	case *types.Pointer:
-		return plan9asm.Ptr, nil
-	case *types.Slice:
-		if wordSize(goarch) == 8 {
-			return plan9asm.LLVMType("{ ptr, i64, i64 }"), nil
-		}
-		return plan9asm.LLVMType("{ ptr, i32, i32 }"), nil
-	case *types.Named:
-		return llvmTypeForGo(tt.Underlying(), goarch)
-	default:
-		return "", fmt.Errorf("unsupported type %s", t.String())
-	}
-}
-
-func wordSize(goarch string) int {
-	switch goarch {
-	case "amd64", "arm64", "loong64", "mips64", "mips64le", "ppc64", "ppc64le", "riscv64", "s390x":
-		return 8
-	default:
-		return 4
-	}
-}
-
-func alignOff(off, a int64) int64 {
-	if a <= 1 {
-		return off
-	}
-	m := off % a
-	if m == 0 {
-		return off
-	}
-	return off + (a - m)
-}
-
 func check(err error) {
 	if err == nil {
 		return
@@ -1,9 +1,17 @@
 package main
 
 import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
 	"path/filepath"
 	"reflect"
 	"testing"
+
+	"github.com/xgo-dev/plan9asm"
+	"golang.org/x/tools/go/packages"
 )
 
 func TestPackageSFilesAbsFiltersNonPlan9Asm(t *testing.T) {
@@ -25,3 +33,47 @@ func TestPackageSFilesAbsFiltersNonPlan9Asm(t *testing.T) {
 		t.Fatalf("packageSFilesAbs() = %#v, want %#v", got, want)
 	}
 }
+
+func TestSigsForAsmFileMethodsAndVariadics(t *testing.T) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "p.go", `package p
+type T int
+func (t *T) Add(x int) int
+func (t T) Get() int
+func Sum(base int, xs ...int) int
+`, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf := types.Config{Importer: importer.Default(), IgnoreFuncBodies: true, Error: func(error) {}}
+	tpkg, _ := conf.Check("p", fset, []*ast.File{f}, nil)
+	pkg := &packages.Package{PkgPath: "p", Types: tpkg, Syntax: []*ast.File{f}}
+	file, err := plan9asm.Parse(plan9asm.ArchAMD64, `TEXT ·(*T).Add(SB),NOSPLIT,$0-24
+	RET
+TEXT ·(*T).Get(SB),NOSPLIT,$0-16
+	MOVQ	$0, ret+8(FP)
+	RET
+TEXT ·Sum(SB),NOSPLIT,$0-40
+	RET
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sigs, err := sigsForAsmFile(pkg, file, resolveSymFunc("p"), "amd64")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	add := sigs["p.(*T).Add"]
+	if !reflect.DeepEqual(add.Args, []plan9asm.LLVMType{plan9asm.Ptr, plan9asm.I64}) || add.Frame.Results[0].Offset != 16 {
+		t.Fatalf("(*T).Add = %+v", add)
+	}
+	// (*T).Get is not declared (Get has a value receiver), so its
+	// signature is inferred from the frame.
+	if get := sigs["p.(*T).Get"]; len(get.Args) != 0 || get.Ret != plan9asm.I64 {
+		t.Fatalf("(*T).Get matched the value method T.Get: %+v", get)
+	}
+	if sum := sigs["p.Sum"]; !reflect.DeepEqual(sum.Args, []plan9asm.LLVMType{plan9asm.I64, "{ ptr, i64, i64 }"}) {
+		t.Fatalf("Sum = %+v", sum)
+	}
+}
@@ -4,8 +4,6 @@ import (
 	"encoding/json"
 	"flag"
 	"fmt"
-	"go/ast"
-	"go/types"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -49,6 +47,7 @@ type unsupportedHit struct {
 type runReport struct {
 	Goos           string     `json:"goos"`
 	Goarch         string     `json:"goarch"`
+	Goarm          string     `json:"goarm,omitempty"`
 	Patterns       []string   `json:"patterns"`
 	TotalPkgs      int        `json:"total_pkgs"`
 	TotalAsm       int        `json:"total_asm"`
@@ -62,6 +61,8 @@ type runReport struct {
 type targetSpec struct {
 	Goos   string `json:"goos"`
 	Goarch string `json:"goarch"`
+	// Goarm is the GOARM setting of an arm target.
+	Goarm string `json:"goarm,omitempty"`
 }
 
 type targetTasks struct {
@@ -86,9 +87,9 @@ type compileConfig struct {
 func main() {
 	var (
 		goos       = flag.String("goos", runtime.GOOS, "target GOOS")
-		goarch     = flag.String("goarch", runtime.GOARCH, "target GOARCH (amd64/arm64/arm/386)")
-		targets    = flag.String("targets", "", "comma-separated GOOS/GOARCH list (e.g. linux/amd64,windows/arm64)")
-		allTargets = flag.Bool("all-targets", false, "run matrix: darwin/{amd64,arm64} linux/{amd64,arm64,386} windows/{amd64,arm64,386}")
+		goarch     = flag.String("goarch", runtime.GOARCH, "target GOARCH (amd64/arm64/arm/386/riscv64/loong64/ppc64le/s390x/mips/mipsle/mips64/mips64le/wasm)")
+		targets    = flag.String("targets", "", "comma-separated GOOS/GOARCH[/GOARM] list (e.g. linux/amd64,windows/arm64,linux/arm/6)")
+		allTargets = flag.Bool("all-targets", false, "run matrix: darwin/{amd64,arm64} linux/{amd64,arm64,386,riscv64,ppc64le,s390x,mips,mipsle,mips64,mips64le} linux/arm/{5,6,7} windows/{amd64,arm64,386} wasip1/wasm")
 		patterns   = flag.String("patterns", "std", "comma-separated package patterns")
 		outDir     = flag.String("out", "", "output dir for generated .ll files")
 		annotate   = flag.Bool("annotate", false, "emit source asm lines as IR comments")
@@ -99,7 +100,8 @@ func main() {
 		llcPath    = flag.String("llc", "", "path to llc executable (auto-detect when empty)")
 		keepObj    = flag.Bool("keep-obj", false, "keep generated .o files when -compile is set")
 		reportOut  = flag.String("report", "", "optional report json path")
-		repoRoot   = flag.String("repo-root", "../..", "repo root for extracting supported instruction set")
+		gomips     = flag.String("gomips", "hardfloat", "GOMIPS/GOMIPS64 floating-point mode for the mips targets (hardfloat/softfloat)")
+		goarm      = flag.String("goarm", "7", "GOARM for the arm targets without a level (5/6/7, optionally followed by ,softfloat or ,hardfloat)")
 	)
 	flag.Parse()
 
@@ -108,10 +110,13 @@ func main() {
 		fatalf("empty -patterns")
 	}
 
-	specs, err := resolveTargets(*goos, *goarch, *targets, *allTargets)
+	specs, err := resolveTargets(*goos, *goarch, *goarm, *targets, *allTargets)
 	if err != nil {
 		fatalf("%v", err)
 	}
+	if *gomips != "hardfloat" && *gomips != "softfloat" {
+		fatalf("invalid -gomips %q (expect hardfloat/softfloat)", *gomips)
+	}
 	ccfg, err := resolveCompileConfig(*compile, *llcPath, *keepObj)
 	if err != nil {
 		fatalf("%v", err)
@@ -136,7 +141,7 @@ func main() {
 			runOutDir = filepath.Join(baseOut, targetID(spec))
 			fmt.Fprintf(os.Stderr, "\n== target %s ==\n", targetID(spec))
 		}
-		rep, tasks, err := runOneTarget(spec, pats, runOutDir, *annotate, *limit, *keepGoing, *listOnly, *repoRoot, ccfg)
+		rep, tasks, err := runOneTarget(spec, pats, runOutDir, *annotate, *limit, *keepGoing, *listOnly, *gomips, ccfg)
 		if err != nil {
 			fatalf("%s: %v", targetID(spec), err)
 		}
@@ -216,7 +221,10 @@ func writeReport(path string, payload any) {
 	check(os.WriteFile(path, data, 0644))
 }
 
-func resolveTargets(goos, goarch, targets string, allTargets bool) ([]targetSpec, error) {
+func resolveTargets(goos, goarch, goarm, targets string, allTargets bool) ([]targetSpec, error) {
+	if _, _, err := parseGOARM(goarm); err != nil {
+		return nil, fmt.Errorf("-goarm: %w", err)
+	}
 	if allTargets {
 		return defaultMatrixTargets(), nil
 	}
@@ -224,7 +232,11 @@ func resolveTargets(goos, goarch, targets string, allTargets bool) ([]targetSpec
 		if _, err := toPlan9Arch(goarch); err != nil {
 			return nil, err
 		}
-		return []targetSpec{{Goos: goos, Goarch: goarch}}, nil
+		ts := targetSpec{Goos: goos, Goarch: goarch}
+		if goarch == "arm" {
+			ts.Goarm = goarm
+		}
+		return []targetSpec{ts}, nil
 	}
 	parts := splitCSV(targets)
 	out := make([]targetSpec, 0, len(parts))
@@ -237,6 +249,9 @@ func resolveTargets(goos, goarch, targets string, allTargets bool) ([]targetSpec
 		if _, err := toPlan9Arch(ts.Goarch); err != nil {
 			return nil, err
 		}
+		if ts.Goarch == "arm" && ts.Goarm == "" {
+			ts.Goarm = goarm
+		}
 		id := targetID(ts)
 		if seen[id] {
 			continue
@@ -249,14 +264,24 @@ func resolveTargets(goos, goarch, targets string, allTargets bool) ([]targetSpec
 
 func parseTargetSpec(s string) (targetSpec, error) {
 	s = strings.TrimSpace(s)
-	i := strings.IndexByte(s, '/')
-	if i <= 0 || i >= len(s)-1 {
-		return targetSpec{}, fmt.Errorf("invalid target %q, expect GOOS/GOARCH", s)
-	}
-	return targetSpec{
-		Goos:   strings.TrimSpace(s[:i]),
-		Goarch: strings.TrimSpace(s[i+1:]),
-	}, nil
+	parts := strings.Split(s, "/")
+	for i := range parts {
+		parts[i] = strings.TrimSpace(parts[i])
+	}
+	if len(parts) < 2 || len(parts) > 3 || parts[0] == "" || parts[1] == "" {
+		return targetSpec{}, fmt.Errorf("invalid target %q, expect GOOS/GOARCH or GOOS/arm/GOARM", s)
+	}
+	ts := targetSpec{Goos: parts[0], Goarch: parts[1]}
+	if len(parts) == 3 {
+		if ts.Goarch != "arm" {
+			return targetSpec{}, fmt.Errorf("invalid target %q: only arm takes a GOARM level", s)
+		}
+		if _, _, err := parseGOARM(parts[2]); err != nil {
+			return targetSpec{}, fmt.Errorf("invalid target %q: %w", s, err)
+		}
+		ts.Goarm = parts[2]
+	}
+	return ts, nil
 }
 
 func defaultMatrixTargets() []targetSpec {
@@ -266,22 +291,38 @@ func defaultMatrixTargets() []targetSpec {
 		{Goos: "linux", Goarch: "amd64"},
 		{Goos: "linux", Goarch: "arm64"},
 		{Goos: "linux", Goarch: "386"},
+		{Goos: "linux", Goarch: "riscv64"},
+		{Goos: "linux", Goarch: "loong64"},
+		{Goos: "linux", Goarch: "ppc64le"},
+		{Goos: "linux", Goarch: "s390x"},
+		{Goos: "linux", Goarch: "mips"},
+		{Goos: "linux", Goarch: "mipsle"},
+		{Goos: "linux", Goarch: "mips64"},
+		{Goos: "linux", Goarch: "mips64le"},
+		{Goos: "linux", Goarch: "arm", Goarm: "5"},
+		{Goos: "linux", Goarch: "arm", Goarm: "6"},
+		{Goos: "linux", Goarch: "arm", Goarm: "7"},
 		{Goos: "windows", Goarch: "amd64"},
 		{Goos: "windows", Goarch: "arm64"},
 		{Goos: "windows", Goarch: "386"},
+		{Goos: "wasip1", Goarch: "wasm"},
 	}
 }
 
 func targetID(t targetSpec) string {
-	return t.Goos + "-" + t.Goarch
+	id := t.Goos + "-" + t.Goarch
+	if t.Goarm != "" {
+		id += "-" + strings.ReplaceAll(t.Goarm, ",", "-")
+	}
+	return id
 }
 
-func runOneTarget(spec targetSpec, pats []string, outDir string, annotate bool, limit int, keepGoing bool, listOnly bool, repoRoot string, ccfg compileConfig) (runReport, []asmTask, error) {
+func runOneTarget(spec targetSpec, pats []string, outDir string, annotate bool, limit int, keepGoing bool, listOnly bool, gomips string, ccfg compileConfig) (runReport, []asmTask, error) {
 	arch, err := toPlan9Arch(spec.Goarch)
 	if err != nil {
 		return runReport{}, nil, err
 	}
-	pkgs, err := loadPkgs(spec.Goos, spec.Goarch, pats)
+	pkgs, err := loadPkgs(spec.Goos, spec.Goarch, spec.Goarm, pats)
 	if err != nil {
 		return runReport{}, nil, fmt.Errorf("load packages: %w", err)
 	}
@@ -301,6 +342,7 @@ func runOneTarget(spec targetSpec, pats []string, outDir string, annotate bool,
 	rep := runReport{
 		Goos:      spec.Goos,
 		Goarch:    spec.Goarch,
+		Goarm:     spec.Goarm,
 		Patterns:  pats,
 		TotalPkgs: pkgCount,
 		TotalAsm:  len(tasks),
@@ -311,11 +353,8 @@ func runOneTarget(spec targetSpec, pats []string, outDir string, annotate bool,
 	}
 
 	check(os.MkdirAll(outDir, 0755))
-	triple := targetTriple(spec.Goos, spec.Goarch)
-	supportedOps, supErr := extractSupportedOps(repoRoot, spec.Goarch)
-	if supErr != nil {
-		fmt.Fprintf(os.Stderr, "warning: cannot extract supported ops from %s: %v\n", repoRoot, supErr)
-	}
+	triple := targetTriple(spec.Goos, spec.Goarch, spec.Goarm)
+	supportedOps := supportedOpsFor(arch)
 	unsupportedAgg := map[string]int{}
 	start := time.Now()
 
@@ -332,7 +371,7 @@ func runOneTarget(spec targetSpec, pats []string, outDir string, annotate bool,
 			}
 			continue
 		}
-		err := compileOne(pkg, arch, spec.Goarch, triple, t, annotate, ccfg)
+		err := compileOne(pkg, arch, spec, triple, t, annotate, gomips, ccfg)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "[%d/%d] FAIL %s\n", idx, len(tasks), t.AsmFile)
 			printFailureReason(err.Error())
@@ -373,12 +412,20 @@ func runOneTarget(spec targetSpec, pats []string, outDir string, annotate bool,
 	return rep, nil, nil
 }
 
-func compileOne(pkg *packages.Package, arch plan9asm.Arch, goarch, triple string, t asmTask, annotate bool, ccfg compileConfig) error {
+func compileOne(pkg *packages.Package, arch plan9asm.Arch, spec targetSpec, triple string, t asmTask, annotate bool, gomips string, ccfg compileConfig) error {
+	goos, goarch := spec.Goos, spec.Goarch
 	src, err := os.ReadFile(t.AsmFile)
 	if err != nil {
 		return fmt.Errorf("read asm: %w", err)
 	}
-	file, err := plan9asm.Parse(arch, string(src))
+	goarm, armSoftFloat := 0, false
+	if goarch == "arm" {
+		if goarm, armSoftFloat, err = parseGOARM(spec.Goarm); err != nil {
+			return err
+		}
+	}
+	defines := append(gomipsDefines(goarch, gomips), goarmDefines(goarm)...)
+	file, err := plan9asm.ParseWithDefines(arch, string(src), defines...)
 	if err != nil {
 		if strings.Contains(err.Error(), "no TEXT directive found") {
 			return nil
@@ -399,7 +446,10 @@ func compileOne(pkg *packages.Package, arch plan9asm.Arch, goarch, triple string
 		ResolveSym:     resolve,
 		Sigs:           sigs,
 		Goarch:         goarch,
+		Goos:           goos,
 		AnnotateSource: annotate,
+		SoftFloat:      isMIPS(goarch) && gomips == "softfloat" || armSoftFloat,
+		Goarm:          goarm,
 	})
 	if err != nil {
 		return fmt.Errorf("translate: %w", err)
@@ -443,15 +493,87 @@ func llcExtraArgs(goarch string) []string {
 			"-mcpu=haswell",
 			"-mattr=+aes,+ssse3,+sse4.1,+sse4.2,+pclmul,+avx,+avx2,+sha,+popcnt,+adx",
 		}
+	case "386":
+		// Go's default GO386=sse2 assumes SSE2, which generic i686 lacks.
+		return []string{"-mcpu=pentium4"}
 	case "arm64":
 		// CRC32 intrinsics in hash/crc32 require +crc.
 		return []string{"-mattr=+crc"}
+	case "riscv64":
+		// RV64GC, the baseline GORISCV64=rva20u64 guarantees.
+		return []string{"-mattr=+m,+a,+f,+d,+c"}
+	case "ppc64le":
+		// POWER8, the baseline GOPPC64=power8 guarantees.
+		return []string{"-mcpu=pwr8"}
+	case "s390x":
+		// z13, the first with the vector facility, is Go's minimum.
+		return []string{"-mcpu=z13"}
 	default:
 		return nil
 	}
 }
 
-func loadPkgs(goos, goarch string, patterns []string) ([]*packages.Package, error) {
+func isMIPS(goarch string) bool {
+	switch goarch {
+	case "mips", "mipsle", "mips64", "mips64le":
+		return true
+	}
+	return false
+}
+
+// gomipsDefines returns the macro the go command defines for GOMIPS (or
+// GOMIPS64 on the 64-bit targets), which the runtime asm tests.
+func gomipsDefines(goarch, gomips string) []string {
+	switch goarch {
+	case "mips", "mipsle":
+		return []string{"GOMIPS_" + gomips}
+	case "mips64", "mips64le":
+		return []string{"GOMIPS64_" + gomips}
+	}
+	return nil
+}
+
+// parseGOARM parses a GOARM setting into its level and whether it is
+// softfloat, which GOARM=5 defaults to.
+func parseGOARM(s string) (level int, softFloat bool, err error) {
+	v, mode, _ := strings.Cut(s, ",")
+	switch v {
+	case "", "7":
+		level = 7
+	case "6":
+		level = 6
+	case "5":
+		level = 5
+	default:
+		return 0, false, fmt.Errorf("invalid GOARM %q (expect 5/6/7)", s)
+	}
+	switch mode {
+	case "":
+		softFloat = level == 5
+	case "softfloat":
+		softFloat = true
+	case "hardfloat":
+	default:
+		return 0, false, fmt.Errorf("invalid GOARM %q (expect softfloat/hardfloat after the comma)", s)
+	}
+	return level, softFloat, nil
+}
+
+// goarmDefines returns the macros the go command defines for a GOARM level:
+// GOARM_5 up to GOARM_<level>.
+func goarmDefines(level int) []string {
+	var out []string
+	for l := 5; l <= level; l++ {
+		out = append(out, fmt.Sprintf("GOARM_%d", l))
+	}
+	return out
+}
+
+func loadPkgs(goos, goarch, goarm string, patterns []string) ([]*packages.Package, error) {
+	env := append(os.Environ(), "GOOS="+goos, "GOARCH="+goarch)
+	if goarm != "" {
+		env = append(env, "GOARM="+goarm)
+	}
 	cfg := &packages.Config{
 		Mode: packages.NeedName |
 			packages.NeedFiles |
@@ -461,10 +583,7 @@ func loadPkgs(goos, goarch string, patterns []string) ([]*packages.Package, erro
 			packages.NeedTypesInfo |
 			packages.NeedTypesSizes |
 			packages.NeedSyntax,
-		Env: append(os.Environ(),
-			"GOOS="+goos,
-			"GOARCH="+goarch,
-		),
+		Env: env,
 	}
 	pkgs, err := packages.Load(cfg, patterns...)
 	if err != nil {
@@ -525,18 +644,38 @@ func isAsmFile(path string) bool {
 
 func toPlan9Arch(goarch string) (plan9asm.Arch, error) {
 	switch goarch {
-	case "amd64", "386":
+	case "amd64":
 		return plan9asm.ArchAMD64, nil
+	case "386":
+		return plan9asm.Arch386, nil
 	case "arm":
 		return plan9asm.ArchARM, nil
 	case "arm64":
 		return plan9asm.ArchARM64, nil
+	case "riscv64":
+		return plan9asm.ArchRISCV64, nil
+	case "loong64":
+		return plan9asm.ArchLOONG64, nil
+	case "ppc64le":
+		return plan9asm.ArchPPC64, nil
+	case "s390x":
+		return plan9asm.ArchS390X, nil
+	case "mips":
+		return plan9asm.ArchMIPS, nil
+	case "mipsle":
+		return plan9asm.ArchMIPSLE, nil
+	case "mips64":
+		return plan9asm.ArchMIPS64, nil
+	case "mips64le":
+		return plan9asm.ArchMIPS64LE, nil
+	case "wasm":
+		return plan9asm.ArchWasm, nil
 	default:
 		return "", fmt.Errorf("unsupported arch %q", goarch)
 	}
 }
 
-func targetTriple(goos, goarch string) string {
+func targetTriple(goos, goarch, goarm string) string {
 	switch goos {
 	case "darwin":
 		switch goarch {
@@ -555,6 +694,24 @@ func targetTriple(goos, goarch string) string {
 			return "aarch64-unknown-linux-gnu"
 		case "386":
 			return "i386-unknown-linux-gnu"
+		case "riscv64":
+			return "riscv64-unknown-linux-gnu"
+		case "loong64":
+			return "loongarch64-unknown-linux-gnu"
+		case "ppc64le":
+			return "powerpc64le-unknown-linux-gnu"
+		case "s390x":
+			return "s390x-unknown-linux-gnu"
+		case "mips":
+			return "mips-unknown-linux-gnu"
+		case "mipsle":
+			return "mipsel-unknown-linux-gnu"
+		case "mips64":
+			return "mips64-unknown-linux-gnuabi64"
+		case "mips64le":
+			return "mips64el-unknown-linux-gnuabi64"
+		case "arm":
+			return armLinuxTriple(goarm)
 		}
 	case "windows":
 		switch goarch {
@@ -565,10 +722,29 @@ func targetTriple(goos, goarch string) string {
 		case "386":
 			return "i686-pc-windows-msvc"
 		}
+	case "wasip1", "js":
+		if goarch == "wasm" {
+			return "wasm32-unknown-wasi"
+		}
 	}
 	return ""
 }
 
+// armLinuxTriple picks the ARM architecture of a GOARM level, and the
+// soft-float ABI for softfloat settings.
+func armLinuxTriple(goarm string) string {
+	level, soft, err := parseGOARM(goarm)
+	if err != nil {
+		return ""
+	}
+	abi := "gnueabihf"
+	if soft {
+		abi = "gnueabi"
+	}
+	cpu := map[int]string{5: "armv5te", 6: "armv6", 7: "armv7"}[level]
+	return cpu + "-unknown-linux-" + abi
+}
+
 func resolveSymFunc(pkgPath string) func(sym string) string {
 	return func(sym string) string {
 		sym = stripABISuffix(sym)
@@ -594,12 +770,6 @@ func resolveSymFunc(pkgPath string) func(sym string) string {
 }
 
 var abiSuffixRe = regexp.MustCompile(`<ABI[^>]*>$`)
-var (
-	reCaseString = regexp.MustCompile(`case\s+"([A-Za-z0-9_.$]+)"`)
-	reCaseOp     = regexp.MustCompile(`case\s+Op([A-Za-z0-9_]+)`)
-	reQuotedOp   = regexp.MustCompile(`"([A-Za-z0-9_.$]+)"`)
-	reOpToken    = regexp.MustCompile(`Op([A-Za-z0-9_]+)`)
-)
 
 func stripABISuffix(sym string) string {
 	return abiSuffixRe.ReplaceAllString(sym, "")
@@ -609,22 +779,13 @@ func sigsForAsmFile(pkg *packages.Package, file *plan9asm.File, resolve func(str
 	sigs := map[string]plan9asm.FuncSig{}
 	if pkg == nil || pkg.Types == nil || pkg.Types.Scope() == nil {
 		for _, fn := range file.Funcs {
-			fs := fallbackSigForAsmFunc(fn, resolve(stripABISuffix(fn.Sym)))
+			fs := fallbackSigForAsmFunc(fn, file.Arch, resolve(stripABISuffix(fn.Sym)))
 			sigs[fs.Name] = fs
 		}
 		return sigs, nil
 	}
 
-	sz := pkg.TypesSizes
-	if sz == nil {
-		sz = types.SizesFor("gc", goarch)
-	}
-	if sz == nil {
-		return nil, fmt.Errorf("missing type sizes for %q", goarch)
-	}
-
-	scope := pkg.Types.Scope()
-	linknames := linknameRemoteToLocal(pkg.Syntax)
+	gopkg := plan9asm.GoPackage{Path: pkg.PkgPath, Types: pkg.Types, Syntax: pkg.Syntax}
 
 	for _, fn := range file.Funcs {
 		sym := stripABISuffix(fn.Sym)
@@ -632,12 +793,12 @@ func sigsForAsmFile(pkg *packages.Package, file *plan9asm.File, resolve func(str
 		if resolved == "" {
 			continue
 		}
-		fs, ok, err := tryDeclSig(scope, sym, resolved, linknames, goarch, sz)
+		fs, ok, err := plan9asm.GoDeclSig(gopkg, sym, resolved, goarch)
 		if err != nil {
 			return nil, err
 		}
 		if !ok {
-			fs = fallbackSigForAsmFunc(fn, resolved)
+			fs = fallbackSigForAsmFunc(fn, file.Arch, resolved)
 		}
 		sigs[resolved] = fs
 	}
@@ -654,7 +815,7 @@ func sigsForAsmFile(pkg *packages.Package, file *plan9asm.File, resolve func(str
 		if _, ok := sigs[resolved]; ok {
 			return
 		}
-		fs, ok, err := tryDeclSig(scope, sym, resolved, linknames, goarch, sz)
+		fs, ok, err := plan9asm.GoDeclSig(gopkg, sym, resolved, goarch)
 		if err == nil && ok {
 			sigs[resolved] = fs
 			return
@@ -695,144 +856,12 @@ func sigsForAsmFile(pkg *packages.Package, file *plan9asm.File, resolve func(str
 	return sigs, nil
 }
 
-func fallbackSigForAsmFunc(fn plan9asm.Func, resolved string) plan9asm.FuncSig {
-	paramOff := map[int64]struct{}{}
-	retOff := map[int64]struct{}{}
-
-	for _, ins := range fn.Instrs {
-		op := strings.ToUpper(string(ins.Op))
-		for i, a := range ins.Args {
-			if a.Kind != plan9asm.OpFP {
-				continue
-			}
-			if isLikelyResultSlot(op, i, len(ins.Args), a.FPName) {
-				retOff[a.FPOffset] = struct{}{}
-			} else {
-				paramOff[a.FPOffset] = struct{}{}
-			}
-		}
-	}
-
-	paramList := sortOffsets(paramOff)
-	retList := sortOffsets(retOff)
-	params := make([]plan9asm.FrameSlot, 0, len(paramList))
-	for i, off := range paramList {
-		params = append(params, plan9asm.FrameSlot{Offset: off, Type: plan9asm.I64, Index: i, Field: -1})
-	}
-	results := make([]plan9asm.FrameSlot, 0, len(retList))
-	for i, off := range retList {
-		results = append(results, plan9asm.FrameSlot{Offset: off, Type: plan9asm.I64, Index: i, Field: -1})
-	}
-
-	args := make([]plan9asm.LLVMType, len(params))
-	for i := range args {
-		args[i] = plan9asm.I64
-	}
-	ret := plan9asm.Void
-	switch len(results) {
-	case 0:
-		ret = plan9asm.I64
-	case 1:
-		ret = plan9asm.I64
-	default:
-		parts := make([]string, 0, len(results))
-		for range results {
-			parts = append(parts, string(plan9asm.I64))
-		}
-		ret = plan9asm.LLVMType("{ " + strings.Join(parts, ", ") + " }")
-	}
-	return plan9asm.FuncSig{
-		Name: resolved,
-		Args: args,
-		Ret:  ret,
-		Frame: plan9asm.FrameLayout{
-			Params:  params,
-			Results: results,
-		},
-	}
-}
-
-func isLikelyResultSlot(op string, argIndex int, argCount int, fpName string) bool {
-	name := strings.ToLower(fpName)
-	if strings.HasPrefix(name, "ret") || strings.HasPrefix(name, "r") && strings.Contains(name, "ret") {
-		return true
-	}
-	if argCount > 0 && argIndex == argCount-1 {
-		switch {
-		case strings.HasPrefix(op, "MOV"), strings.HasPrefix(op, "VMOV"), strings.HasPrefix(op, "FMOV"):
-			return true
-		}
-	}
-	return false
-}
-
-func sortOffsets(m map[int64]struct{}) []int64 {
-	out := make([]int64, 0, len(m))
-	for k := range m {
-		out = append(out, k)
-	}
-	sort.Slice(out, func(i, j int) bool { return out[i] < out[j] })
-	return out
-}
-
-func tryDeclSig(scope *types.Scope, sym, resolved string, linknames map[string]string, goarch string, sz types.Sizes) (plan9asm.FuncSig, bool, error) {
-	declName := strings.TrimPrefix(sym, "·")
-	if strings.ContainsRune(declName, '·') {
-		key := strings.ReplaceAll(sym, "∕", "/")
-		key = strings.ReplaceAll(key, "·", ".")
-		if local, ok := linknames[key]; ok {
-			declName = local
-		} else {
-			return plan9asm.FuncSig{}, false, nil
-		}
-	}
-	obj := scope.Lookup(declName)
-	if obj == nil {
-		return plan9asm.FuncSig{}, false, nil
-	}
-	fn, ok := obj.(*types.Func)
-	if !ok {
-		return plan9asm.FuncSig{}, false, nil
-	}
-	sig := fn.Type().(*types.Signature)
-	if sig.Recv() != nil || sig.Variadic() {
-		return plan9asm.FuncSig{}, false, nil
-	}
-
-	args, frameParams, nextOff, err := llvmArgsAndFrameSlotsForTuple(sig.Params(), goarch, sz, 0, false)
-	if err != nil {
-		return plan9asm.FuncSig{}, false, fmt.Errorf("%s: %w", fn.FullName(), err)
-	}
-	nextOff = alignOff(nextOff, int64(wordSize(goarch)))
-	retTys, frameResults, _, err := llvmArgsAndFrameSlotsForTuple(sig.Results(), goarch, sz, nextOff, true)
-	if err != nil {
-		return plan9asm.FuncSig{}, false, fmt.Errorf("%s: %w", fn.FullName(), err)
-	}
-	ret := tupleRetType(retTys)
-	return plan9asm.FuncSig{
-		Name: resolved,
-		Args: args,
-		Ret:  ret,
-		Frame: plan9asm.FrameLayout{
-			Params:  frameParams,
-			Results: frameResults,
-		},
-	}, true, nil
-}
-
-func tupleRetType(ts []plan9asm.LLVMType) plan9asm.LLVMType {
-	switch len(ts) {
-	case 0:
-		return plan9asm.Void
-	case 1:
-		return ts[0]
-	default:
-		parts := make([]string, 0, len(ts))
-		for _, t := range ts {
-			parts = append(parts, string(t))
-		}
-		return plan9asm.LLVMType("{ " + strings.Join(parts, ", ") + " }")
-	}
+// fallbackSigForAsmFunc infers the signature of an asm function without a
+// Go declaration from its frame references.
+func fallbackSigForAsmFunc(fn plan9asm.Func, arch plan9asm.Arch, resolved string) plan9asm.FuncSig {
+	fs := plan9asm.InferSignature(fn, arch).Sig
+	fs.Name = resolved
+	return fs
 }
 
 func splitSymPlusOff(s string) (base string, off int64) {
@@ -851,199 +880,6 @@ func splitSymPlusOff(s string) (base string, off int64) {
 	return strings.TrimSpace(s[:sep]), n
 }
 
-func linknameRemoteToLocal(files []*ast.File) map[string]string {
-	m := map[string]string{}
-	for _, f := range files {
-		if f == nil {
-			continue
-		}
-		for _, cg := range f.Comments {
-			if cg == nil {
-				continue
-			}
-			for _, c := range cg.List {
-				if c == nil {
-					continue
-				}
-				text := strings.TrimSpace(strings.TrimPrefix(c.Text, "//"))
-				if !strings.HasPrefix(text, "go:linkname ") {
-					continue
-				}
-				parts := strings.Fields(text)
-				if len(parts) < 3 {
-					continue
-				}
-				local := parts[1]
-				remote := strings.ReplaceAll(parts[2], "∕", "/")
-				m[remote] = local
-			}
-		}
-	}
-	return m
-}
-
-func llvmArgsAndFrameSlotsForTuple(tup *types.Tuple, goarch string, sz types.Sizes, startOff int64, flattenAgg bool) (args []plan9asm.LLVMType, slots []plan9asm.FrameSlot, nextOff int64, err error) {
-	if tup == nil || tup.Len() == 0 {
-		return nil, nil, startOff, nil
-	}
-
-	align := func(off, a int64) int64 {
-		if a <= 1 {
-			return off
-		}
-		m := off % a
-		if m == 0 {
-			return off
-		}
-		return off + (a - m)
-	}
-
-	off := startOff
-	argIdx := 0
-	for i := 0; i < tup.Len(); i++ {
-		t := tup.At(i).Type()
-		off = align(off, int64(sz.Alignof(t)))
-
-		parts, ok := framePartsForType(t, goarch)
-		if ok {
-			if flattenAgg {
-				for _, part := range parts {
-					args = append(args, part.Type)
-					slots = append(slots, plan9asm.FrameSlot{Offset: off + part.Offset, Type: part.Type, Index: argIdx, Field: -1})
-					argIdx++
-				}
-			} else {
-				ty, e := llvmTypeForGo(t, goarch)
-				if e != nil {
-					return nil, nil, 0, e
-				}
-				args = append(args, ty)
-				for _, part := range parts {
-					slots = append(slots, plan9asm.FrameSlot{Offset: off + part.Offset, Type: part.Type, Index: argIdx, Field: part.Field})
-				}
-				argIdx++
-			}
-			off += int64(sz.Sizeof(t))
-			continue
-		}
-
-		ty, e := llvmTypeForGo(t, goarch)
-		if e != nil {
-			return nil, nil, 0, e
-		}
-		args = append(args, ty)
-		slots = append(slots, plan9asm.FrameSlot{Offset: off, Type: ty, Index: argIdx, Field: -1})
-		argIdx++
-		off += int64(sz.Sizeof(t))
-	}
-	return args, slots, off, nil
-}
-
-type framePart struct {
-	Offset int64
-	Type   plan9asm.LLVMType
-	Field  int
-}
-
-func framePartsForType(t types.Type, goarch string) ([]framePart, bool) {
-	word := int64(wordSize(goarch))
-	wordTy := plan9asm.I64
-	if word == 4 {
-		wordTy = plan9asm.LLVMType("i32")
-	}
-	switch u := types.Unalias(t).(type) {
-	case *types.Basic:
-		if u.Kind() == types.String {
-			return []framePart{
-				{Offset: 0, Type: plan9asm.Ptr, Field: 0},
-				{Offset: word, Type: wordTy, Field: 1},
-			}, true
-		}
-	case *types.Slice:
-		return []framePart{
-			{Offset: 0, Type: plan9asm.Ptr, Field: 0},
-			{Offset: word, Type: wordTy, Field: 1},
-			{Offset: 2 * word, Type: wordTy, Field: 2},
-		}, true
-	case *types.Interface:
-		// Both empty and non-empty interfaces occupy two pointers in Go ABI.
-		return []framePart{
-			{Offset: 0, Type: plan9asm.Ptr, Field: 0},
-			{Offset: word, Type: plan9asm.Ptr, Field: 1},
-		}, true
-	}
-	return nil, false
-}
-
-func llvmTypeForGo(t types.Type, goarch string) (plan9asm.LLVMType, error) {
-	switch tt := t.(type) {
-	case *types.Basic:
-		switch tt.Kind() {
-		case types.Bool:
-			return plan9asm.I1, nil
-		case types.UnsafePointer:
-			return plan9asm.Ptr, nil
-		case types.Int8, types.Uint8:
-			return plan9asm.I8, nil
-		case types.Int16, types.Uint16:
-			return plan9asm.I16, nil
-		case types.Int32, types.Uint32:
-			return plan9asm.I32, nil
-		case types.Int64, types.Uint64:
-			return plan9asm.I64, nil
-		case types.Int, types.Uint, types.Uintptr:
-			if wordSize(goarch) == 8 {
-				return plan9asm.I64, nil
-			}
-			return plan9asm.I32, nil
-		case types.Float32:
-			return plan9asm.LLVMType("float"), nil
-		case types.Float64:
-			return plan9asm.LLVMType("double"), nil
-		case types.String:
-			if wordSize(goarch) == 8 {
-				return plan9asm.LLVMType("{ ptr, i64 }"), nil
-			}
-			return plan9asm.LLVMType("{ ptr, i32 }"), nil
-		default:
-			return "", fmt.Errorf("unsupported basic type %s", tt.String())
-		}
-	case *types.Pointer:
-		return plan9asm.Ptr, nil
-	case *types.Slice:
-		if wordSize(goarch) == 8 {
-			return plan9asm.LLVMType("{ ptr, i64, i64 }"), nil
-		}
-		return plan9asm.LLVMType("{ ptr, i32, i32 }"), nil
-	case *types.Interface:
-		return plan9asm.LLVMType("{ ptr, ptr }"), nil
-	case *types.Named:
-		return llvmTypeForGo(tt.Underlying(), goarch)
-	default:
-		return "", fmt.Errorf("unsupported type %s", t.String())
-	}
-}
-
-func wordSize(goarch string) int {
-	switch goarch {
-	case "amd64", "arm64", "loong64", "mips64", "mips64le", "ppc64", "ppc64le", "riscv64", "s390x":
-		return 8
-	default:
-		return 4
-	}
-}
-
-func alignOff(off, a int64) int64 {
-	if a <= 1 {
-		return off
-	}
-	m := off % a
-	if m == 0 {
-		return off
-	}
-	return off + (a - m)
-}
-
 func splitCSV(s string) []string {
 	parts := strings.Split(s, ",")
 	out := make([]string, 0, len(parts))
@@ -1109,9 +945,10 @@ func isDirective(op string) bool {
 	}
 }
 
-func extractSupportedOps(repoRoot, goarch string) (map[string]struct{}, error) {
+// supportedOpsFor returns the opcodes lowered for arch according to the
+// translator's capability registry, plus the directives handled by the parser.
+func supportedOpsFor(arch plan9asm.Arch) map[string]struct{} {
 	supported := map[string]struct{}{
-		"RET":      {},
 		"TEXT":     {},
 		"GLOBL":    {},
 		"DATA":     {},
@@ -1123,60 +960,10 @@ func extractSupportedOps(repoRoot, goarch string) (map[string]struct{}, error) {
 		"FUNCDATA": {},
 		"PCDATA":   {},
 	}
-
-	backendArch := goarch
-	if goarch == "386" {
-		backendArch = "amd64"
-	}
-	glob := filepath.Join(repoRoot, backendArch+"_*.go")
-	files, err := filepath.Glob(glob)
-	if err != nil {
-		return nil, err
-	}
-	files = append(files, filepath.Join(repoRoot, "translate.go"))
-	sort.Strings(files)
-
-	for _, f := range files {
-		if strings.HasSuffix(f, "_test.go") {
-			continue
-		}
-		src, err := os.ReadFile(f)
-		if err != nil {
-			return nil, fmt.Errorf("read %s: %w", f, err)
-		}
-		for _, m := range reCaseString.FindAllSubmatch(src, -1) {
-			nop := normalizeOp(string(m[1]))
-			if nop != "" {
-				supported[nop] = struct{}{}
-			}
-		}
-		for _, m := range reCaseOp.FindAllSubmatch(src, -1) {
-			nop := normalizeOp(string(m[1]))
-			if nop != "" {
-				supported[nop] = struct{}{}
-			}
-		}
-		// Handle multi-value case clauses like:
-		// case "A", "B", "C":
-		for _, ln := range strings.Split(string(src), "\n") {
-			if !strings.Contains(ln, "case") {
-				continue
-			}
-			for _, m := range reQuotedOp.FindAllStringSubmatch(ln, -1) {
-				nop := normalizeOp(m[1])
-				if nop != "" {
-					supported[nop] = struct{}{}
-				}
-			}
-			for _, m := range reOpToken.FindAllStringSubmatch(ln, -1) {
-				nop := normalizeOp(m[1])
-				if nop != "" {
-					supported[nop] = struct{}{}
-				}
-			}
-		}
+	for _, cp := range plan9asm.Capabilities(arch) {
+		supported[string(cp.Op)] = struct{}{}
 	}
-	return supported, nil
+	return supported
 }
 
 func unsupportedInAsmFile(path string, arch plan9asm.Arch, supported map[string]struct{}) ([]string, []unsupportedHit) {
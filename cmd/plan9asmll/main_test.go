@@ -1,80 +1,153 @@
 package main
 
 import (
+	"go/ast"
+	"go/parser"
 	"go/token"
 	"go/types"
 	"reflect"
 	"testing"
 
 	"github.com/xgo-dev/plan9asm"
+	"golang.org/x/tools/go/packages"
 )
 
-func TestLLVMArgsAndFrameSlotsForTupleSliceParam(t *testing.T) {
-	tup := types.NewTuple(types.NewVar(token.NoPos, nil, "b", types.NewSlice(types.Typ[types.Byte])))
-	sz := types.SizesFor("gc", "amd64")
-	args, slots, nextOff, err := llvmArgsAndFrameSlotsForTuple(tup, "amd64", sz, 0, false)
+func TestSigsForAsmFileFrameLayouts(t *testing.T) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "p.go", `package p
+func Count(b []byte) int
+func Clone(b []byte) []byte
+func Kind(v any) int
+func Len(m map[int]int, a [2]int32) int
+`, 0)
 	if err != nil {
 		t.Fatal(err)
 	}
-	if !reflect.DeepEqual(args, []plan9asm.LLVMType{"{ ptr, i64, i64 }"}) {
-		t.Fatalf("args mismatch: %#v", args)
+	conf := types.Config{Error: func(error) {}}
+	tpkg, _ := conf.Check("p", fset, []*ast.File{f}, nil)
+	pkg := &packages.Package{PkgPath: "p", Types: tpkg, Syntax: []*ast.File{f}}
+	file, err := plan9asm.Parse(plan9asm.ArchAMD64, `TEXT ·Count(SB),NOSPLIT,$0-32
+	RET
+TEXT ·Clone(SB),NOSPLIT,$0-48
+	RET
+TEXT ·Kind(SB),NOSPLIT,$0-24
+	RET
+TEXT ·Len(SB),NOSPLIT,$0-24
+	RET
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sigs, err := sigsForAsmFile(pkg, file, resolveSymFunc("p"), "amd64")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	count := sigs["p.Count"]
+	if !reflect.DeepEqual(count.Args, []plan9asm.LLVMType{"{ ptr, i64, i64 }"}) {
+		t.Fatalf("Count args mismatch: %#v", count.Args)
 	}
 	wantSlots := []plan9asm.FrameSlot{
 		{Offset: 0, Type: plan9asm.Ptr, Index: 0, Field: 0},
 		{Offset: 8, Type: plan9asm.I64, Index: 0, Field: 1},
 		{Offset: 16, Type: plan9asm.I64, Index: 0, Field: 2},
 	}
-	if !reflect.DeepEqual(slots, wantSlots) {
-		t.Fatalf("slots mismatch: got=%#v want=%#v", slots, wantSlots)
+	if !reflect.DeepEqual(count.Frame.Params, wantSlots) {
+		t.Fatalf("Count params mismatch: got=%#v want=%#v", count.Frame.Params, wantSlots)
 	}
-	if nextOff != 24 {
-		t.Fatalf("nextOff mismatch: got=%d want=24", nextOff)
+
+	// A slice result is returned flattened.
+	clone := sigs["p.Clone"]
+	if clone.Ret != "{ ptr, i64, i64 }" {
+		t.Fatalf("Clone ret mismatch: %q", clone.Ret)
+	}
+	wantSlots = []plan9asm.FrameSlot{
+		{Offset: 24, Type: plan9asm.Ptr, Index: 0, Field: -1},
+		{Offset: 32, Type: plan9asm.I64, Index: 1, Field: -1},
+		{Offset: 40, Type: plan9asm.I64, Index: 2, Field: -1},
+	}
+	if !reflect.DeepEqual(clone.Frame.Results, wantSlots) {
+		t.Fatalf("Clone results mismatch: got=%#v want=%#v", clone.Frame.Results, wantSlots)
+	}
+
+	kind := sigs["p.Kind"]
+	if !reflect.DeepEqual(kind.Args, []plan9asm.LLVMType{"{ ptr, ptr }"}) {
+		t.Fatalf("Kind args mismatch: %#v", kind.Args)
+	}
+	wantSlots = []plan9asm.FrameSlot{
+		{Offset: 0, Type: plan9asm.Ptr, Index: 0, Field: 0},
+		{Offset: 8, Type: plan9asm.Ptr, Index: 0, Field: 1},
+	}
+	if !reflect.DeepEqual(kind.Frame.Params, wantSlots) {
+		t.Fatalf("Kind params mismatch: got=%#v want=%#v", kind.Frame.Params, wantSlots)
+	}
+
+	if l := sigs["p.Len"]; len(l.Args) != 2 || l.Args[0] != plan9asm.Ptr || l.Frame.Results[0].Offset != 16 {
+		t.Fatalf("Len = %+v", l)
 	}
 }
 
-func TestLLVMArgsAndFrameSlotsForTupleSliceResultFlatten(t *testing.T) {
-	tup := types.NewTuple(types.NewVar(token.NoPos, nil, "r", types.NewSlice(types.Typ[types.Byte])))
-	sz := types.SizesFor("gc", "amd64")
-	args, slots, nextOff, err := llvmArgsAndFrameSlotsForTuple(tup, "amd64", sz, 0, true)
+func TestResolveTargetsGOARM(t *testing.T) {
+	specs, err := resolveTargets("linux", "amd64", "6", "linux/arm/5,linux/arm,linux/amd64,linux/arm/7,softfloat", false)
+	if err == nil {
+		t.Fatalf("resolveTargets accepted a comma inside a target: %v", specs)
+	}
+	specs, err = resolveTargets("linux", "amd64", "6", "linux/arm/5,linux/arm,linux/amd64", false)
 	if err != nil {
 		t.Fatal(err)
 	}
-	if !reflect.DeepEqual(args, []plan9asm.LLVMType{plan9asm.Ptr, plan9asm.I64, plan9asm.I64}) {
-		t.Fatalf("args mismatch: %#v", args)
+	want := []targetSpec{
+		{Goos: "linux", Goarch: "arm", Goarm: "5"},
+		{Goos: "linux", Goarch: "arm", Goarm: "6"},
+		{Goos: "linux", Goarch: "amd64"},
 	}
-	wantSlots := []plan9asm.FrameSlot{
-		{Offset: 0, Type: plan9asm.Ptr, Index: 0, Field: -1},
-		{Offset: 8, Type: plan9asm.I64, Index: 1, Field: -1},
-		{Offset: 16, Type: plan9asm.I64, Index: 2, Field: -1},
+	if !reflect.DeepEqual(specs, want) {
+		t.Fatalf("specs = %#v, want %#v", specs, want)
+	}
+	if id := targetID(targetSpec{Goos: "linux", Goarch: "arm", Goarm: "7,softfloat"}); id != "linux-arm-7-softfloat" {
+		t.Fatalf("targetID = %q", id)
 	}
-	if !reflect.DeepEqual(slots, wantSlots) {
-		t.Fatalf("slots mismatch: got=%#v want=%#v", slots, wantSlots)
+	for _, bad := range []string{"linux/amd64/7", "linux/arm/8", "linux//7"} {
+		if _, err := parseTargetSpec(bad); err == nil {
+			t.Errorf("parseTargetSpec accepted %q", bad)
+		}
 	}
-	if nextOff != 24 {
-		t.Fatalf("nextOff mismatch: got=%d want=24", nextOff)
+	if _, err := resolveTargets("linux", "arm", "v7", "", false); err == nil {
+		t.Errorf("resolveTargets accepted -goarm v7")
 	}
 }
 
-func TestLLVMArgsAndFrameSlotsForTupleInterfaceParam(t *testing.T) {
-	iface := types.NewInterfaceType(nil, nil)
-	iface.Complete()
-	tup := types.NewTuple(types.NewVar(token.NoPos, nil, "v", iface))
-	sz := types.SizesFor("gc", "amd64")
-	args, slots, nextOff, err := llvmArgsAndFrameSlotsForTuple(tup, "amd64", sz, 0, false)
-	if err != nil {
-		t.Fatal(err)
+func TestARMLinuxTriple(t *testing.T) {
+	for goarm, want := range map[string]string{
+		"5":           "armv5te-unknown-linux-gnueabi",
+		"5,hardfloat": "armv5te-unknown-linux-gnueabihf",
+		"6":           "armv6-unknown-linux-gnueabihf",
+		"7":           "armv7-unknown-linux-gnueabihf",
+		"7,softfloat": "armv7-unknown-linux-gnueabi",
+	} {
+		if got := targetTriple("linux", "arm", goarm); got != want {
+			t.Errorf("targetTriple(linux, arm, %q) = %q, want %q", goarm, got, want)
+		}
 	}
-	if !reflect.DeepEqual(args, []plan9asm.LLVMType{"{ ptr, ptr }"}) {
-		t.Fatalf("args mismatch: %#v", args)
+	if got := goarmDefines(6); !reflect.DeepEqual(got, []string{"GOARM_5", "GOARM_6"}) {
+		t.Errorf("goarmDefines(6) = %v", got)
 	}
-	wantSlots := []plan9asm.FrameSlot{
-		{Offset: 0, Type: plan9asm.Ptr, Index: 0, Field: 0},
-		{Offset: 8, Type: plan9asm.Ptr, Index: 0, Field: 1},
-	}
-	if !reflect.DeepEqual(slots, wantSlots) {
-		t.Fatalf("slots mismatch: got=%#v want=%#v", slots, wantSlots)
+}
+
+func TestDefaultMatrixTargetsCoverBackends(t *testing.T) {
+	covered := map[string]bool{}
+	for _, ts := range defaultMatrixTargets() {
+		if _, err := toPlan9Arch(ts.Goarch); err != nil {
+			t.Errorf("%s: %v", targetID(ts), err)
+		}
+		if targetTriple(ts.Goos, ts.Goarch, ts.Goarm) == "" {
+			t.Errorf("%s: no target triple", targetID(ts))
+		}
+		covered[ts.Goarch] = true
 	}
-	if nextOff != 16 {
-		t.Fatalf("nextOff mismatch: got=%d want=16", nextOff)
+	for _, goarch := range []string{"amd64", "386", "arm64", "arm", "riscv64", "loong64", "ppc64le", "s390x", "mips", "mipsle", "mips64", "mips64le", "wasm"} {
+		if !covered[goarch] {
+			t.Errorf("no default matrix target for %s", goarch)
+		}
 	}
 }
@@ -9,7 +9,6 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
-	"regexp"
 	"runtime"
 	"sort"
 	"strings"
@@ -70,22 +69,19 @@ type familyReport struct {
 	Examples  []string `json:"examples,omitempty"`
 }
 
-var (
-	reCaseClause = regexp.MustCompile(`case\s+([^:]+):`)
-)
-
 func main() {
 	var (
-		goos     = flag.String("goos", runtime.GOOS, "target GOOS")
-		goarch   = flag.String("goarch", runtime.GOARCH, "target GOARCH (amd64/arm64/arm)")
-		out      = flag.String("out", "", "write report to file (default stdout)")
-		format   = flag.String("format", "md", "output format: md|json")
-		repoRoot = flag.String("repo-root", ".", "llgo repository root for extracting supported ops")
+		goos   = flag.String("goos", runtime.GOOS, "target GOOS")
+		goarch = flag.String("goarch", runtime.GOARCH, "target GOARCH (amd64/386/arm64/arm/riscv64/loong64/ppc64le/s390x/mips/mipsle/mips64/mips64le/wasm)")
+		out    = flag.String("out", "", "write report to file (default stdout)")
+		format = flag.String("format", "md", "output format: md|json")
 	)
 	flag.Parse()
 
-	if *goarch != "amd64" && *goarch != "arm64" && *goarch != "arm" {
-		fatalf("unsupported -goarch %q (expect amd64/arm64/arm)", *goarch)
+	switch *goarch {
+	case "amd64", "386", "arm64", "arm", "riscv64", "loong64", "ppc64le", "s390x", "mips", "mipsle", "mips64", "mips64le", "wasm":
+	default:
+		fatalf("unsupported -goarch %q (expect amd64/386/arm64/arm/riscv64/loong64/ppc64le/s390x/mips/mipsle/mips64/mips64le/wasm)", *goarch)
 	}
 	arch, err := toPlan9Arch(*goarch)
 	if err != nil {
@@ -102,10 +98,7 @@ func main() {
 		fatalf("scan packages: %v", err)
 	}
 
-	supported, err := extractSupportedOps(*repoRoot, *goarch)
-	if err != nil {
-		fatalf("extract supported ops: %v", err)
-	}
+	supported := supportedOpsFor(arch)
 
 	rep := buildReport(*goos, *goarch, len(pkgs), pkgWithSFiles, asmFiles, ops, supported, parseErrs)
 
@@ -136,10 +129,30 @@ func toPlan9Arch(goarch string) (plan9asm.Arch, error) {
 	switch goarch {
 	case "amd64":
 		return plan9asm.ArchAMD64, nil
+	case "386":
+		return plan9asm.Arch386, nil
 	case "arm":
 		return plan9asm.ArchARM, nil
 	case "arm64":
 		return plan9asm.ArchARM64, nil
+	case "riscv64":
+		return plan9asm.ArchRISCV64, nil
+	case "loong64":
+		return plan9asm.ArchLOONG64, nil
+	case "ppc64le":
+		return plan9asm.ArchPPC64, nil
+	case "s390x":
+		return plan9asm.ArchS390X, nil
+	case "mips":
+		return plan9asm.ArchMIPS, nil
+	case "mipsle":
+		return plan9asm.ArchMIPSLE, nil
+	case "mips64":
+		return plan9asm.ArchMIPS64, nil
+	case "mips64le":
+		return plan9asm.ArchMIPS64LE, nil
+	case "wasm":
+		return plan9asm.ArchWasm, nil
 	default:
 		return "", fmt.Errorf("unsupported arch: %s", goarch)
 	}
@@ -294,9 +307,10 @@ func normalizeOp(op string) string {
 	return op
 }
 
-func extractSupportedOps(repoRoot, goarch string) (map[string]struct{}, error) {
+// supportedOpsFor returns the opcodes lowered for arch according to the
+// translator's capability registry, plus the directives handled by the parser.
+func supportedOpsFor(arch plan9asm.Arch) map[string]struct{} {
 	supported := map[string]struct{}{
-		"RET":      {},
 		"TEXT":     {},
 		"GLOBL":    {},
 		"DATA":     {},
@@ -308,53 +322,10 @@ func extractSupportedOps(repoRoot, goarch string) (map[string]struct{}, error) {
 		"FUNCDATA": {},
 		"PCDATA":   {},
 	}
-
-	seen := map[string]struct{}{}
-	var files []string
-	patterns := []string{
-		filepath.Join(repoRoot, goarch+"_*.go"),
-		filepath.Join(repoRoot, "parser.go"),
-	}
-	for _, pattern := range patterns {
-		matches, err := filepath.Glob(pattern)
-		if err != nil {
-			return nil, err
-		}
-		sort.Strings(matches)
-		for _, match := range matches {
-			if strings.HasSuffix(match, "_test.go") {
-				continue
-			}
-			if _, ok := seen[match]; ok {
-				continue
-			}
-			seen[match] = struct{}{}
-			files = append(files, match)
-		}
-	}
-	for _, f := range files {
-		src, err := os.ReadFile(f)
-		if err != nil {
-			return nil, fmt.Errorf("read %s: %w", f, err)
-		}
-		for _, m := range reCaseClause.FindAllSubmatch(src, -1) {
-			items := strings.Split(string(m[1]), ",")
-			for _, item := range items {
-				item = strings.TrimSpace(item)
-				switch {
-				case strings.HasPrefix(item, "\"") && strings.HasSuffix(item, "\"") && len(item) >= 2:
-					if op := normalizeOp(strings.Trim(item, "\"")); op != "" {
-						supported[op] = struct{}{}
-					}
-				case strings.HasPrefix(item, "Op"):
-					if op := normalizeOp(strings.TrimPrefix(item, "Op")); op != "" {
-						supported[op] = struct{}{}
-					}
-				}
-			}
-		}
+	for _, cp := range plan9asm.Capabilities(arch) {
+		supported[string(cp.Op)] = struct{}{}
 	}
-	return supported, nil
+	return supported
 }
 
 func buildReport(
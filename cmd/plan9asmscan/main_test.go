@@ -18,13 +18,16 @@ func TestToPlan9Arch(t *testing.T) {
 	if got, err := toPlan9Arch("amd64"); err != nil || got != plan9asm.ArchAMD64 {
 		t.Fatalf("toPlan9Arch(amd64) = (%q, %v)", got, err)
 	}
+	if got, err := toPlan9Arch("386"); err != nil || got != plan9asm.Arch386 {
+		t.Fatalf("toPlan9Arch(386) = (%q, %v)", got, err)
+	}
 	if got, err := toPlan9Arch("arm"); err != nil || got != plan9asm.ArchARM {
 		t.Fatalf("toPlan9Arch(arm) = (%q, %v)", got, err)
 	}
 	if got, err := toPlan9Arch("arm64"); err != nil || got != plan9asm.ArchARM64 {
 		t.Fatalf("toPlan9Arch(arm64) = (%q, %v)", got, err)
 	}
-	if _, err := toPlan9Arch("wasm"); err == nil {
+	if _, err := toPlan9Arch("sparc64"); err == nil {
 		t.Fatalf("expected unsupported arch error")
 	}
 }
@@ -101,30 +104,9 @@ func TestShortStdPath(t *testing.T) {
 	}
 }
 
-func TestExtractSupportedOpsScansRepoRoot(t *testing.T) {
-	dir := t.TempDir()
-	src := `package plan9asm
-
-func lower(op string) {
-	switch op {
-	case "VPXORQ", "VMOVDQU64", "AESENC":
-	}
-}
-
-func lowerOp(op any) {
-	switch op {
-	case OpMOVQ, OpRET:
-	}
-}
-`
-	if err := os.WriteFile(filepath.Join(dir, "amd64_lower_vec.go"), []byte(src), 0o644); err != nil {
-		t.Fatalf("write test file: %v", err)
-	}
-	supported, err := extractSupportedOps(dir, "amd64")
-	if err != nil {
-		t.Fatalf("extractSupportedOps: %v", err)
-	}
-	want := []string{"VPXORQ", "VMOVDQU64", "AESENC", "MOVQ", "RET"}
+func TestSupportedOpsForUsesCapabilities(t *testing.T) {
+	supported := supportedOpsFor(plan9asm.ArchAMD64)
+	want := []string{"VPXORQ", "VMOVDQU64", "AESENC", "MOVQ", "RET", "TEXT"}
 	got := make([]string, 0, len(want))
 	for _, op := range want {
 		if _, ok := supported[op]; ok {
@@ -134,6 +116,9 @@ func lowerOp(op any) {
 	if !reflect.DeepEqual(got, want) {
 		t.Fatalf("supported ops = %#v, want %#v", got, want)
 	}
+	if _, ok := supported["FROBQ"]; ok {
+		t.Fatalf("supported ops unexpectedly contain FROBQ")
+	}
 }
 
 func TestFamilyOfAMD64(t *testing.T) {
@@ -377,7 +362,6 @@ func TestMainAndFatalfSubprocess(t *testing.T) {
 			"-goos=linux",
 			"-goarch=amd64",
 			"-format=json",
-			"-repo-root=../..",
 			"-out=" + filepath.Join(os.TempDir(), "plan9asmscan-test.json"),
 		}
 		main()
@@ -402,7 +386,6 @@ func TestMainAndFatalfSubprocess(t *testing.T) {
 		"-goos=linux",
 		"-goarch=amd64",
 		"-format=json",
-		"-repo-root=../..",
 		"-out=" + outPath,
 	}
 	main()
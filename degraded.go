@@ -0,0 +1,218 @@
+package plan9asm
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/xgo-dev/llvm"
+)
+
+// FuncStatus reports how a TEXT symbol ended up in the translated module.
+type FuncStatus int
+
+const (
+	// FuncTranslated means the asm body was lowered.
+	FuncTranslated FuncStatus = iota
+	// FuncFallback means lowering failed and the symbol is a stub that
+	// tail-calls FuncReport.Fallback with the same arguments.
+	FuncFallback
+	// FuncTrap means lowering failed and the symbol is a stub that traps.
+	FuncTrap
+	// FuncInlined means the symbol is a file-local helper that was inlined
+	// into all its callers (Options.InlineHelpers) and is not emitted.
+	FuncInlined
+)
+
+func (s FuncStatus) String() string {
+	switch s {
+	case FuncTranslated:
+		return "translated"
+	case FuncFallback:
+		return "fallback"
+	case FuncTrap:
+		return "trap"
+	case FuncInlined:
+		return "inlined"
+	}
+	return fmt.Sprintf("FuncStatus(%d)", int(s))
+}
+
+// FuncReport describes the outcome for one TEXT symbol.
+type FuncReport struct {
+	Name     string // resolved symbol name
+	Status   FuncStatus
+	Fallback string // symbol called by a FuncFallback stub
+	Err      error  // lowering error for stubbed functions
+}
+
+// TranslateWithReport is like Translate but also returns one FuncReport per
+// TEXT symbol, in file order. With Options.Degraded set, functions that fail
+// to lower are replaced by stubs instead of failing the whole file.
+func TranslateWithReport(file *File, opt Options) (string, []FuncReport, error) {
+	mod, report, err := TranslateModuleWithReport(file, opt)
+	if err != nil {
+		return "", nil, err
+	}
+	defer mod.Dispose()
+	return mod.String(), report, nil
+}
+
+// TranslateModuleWithReport is like TranslateModule but also returns one
+// FuncReport per TEXT symbol, in file order. See Options.Degraded.
+//
+// Caller owns the returned module and should call Dispose when finished.
+func TranslateModuleWithReport(file *File, opt Options) (llvm.Module, []FuncReport, error) {
+	if !opt.Degraded {
+		mod, err := TranslateModule(file, opt)
+		if err != nil {
+			return llvm.Module{}, nil, err
+		}
+		return mod, translatedReport(file, opt), nil
+	}
+
+	// The direct path has no per-function recovery; any failure there just
+	// hands over to the textual path, which degrades function by function.
+	if mod, err := translateModuleDirect(file, opt); err == nil {
+		return mod, translatedReport(file, opt), nil
+	}
+
+	ir, report, err := translateIRTextReport(file, opt, nil)
+	if err != nil {
+		return llvm.Module{}, nil, err
+	}
+	mod, err := parseIRModule(ir)
+	if err == nil {
+		return mod, report, nil
+	}
+
+	// Some lowering bugs only surface when LLVM parses the IR. Find the
+	// functions responsible by parsing each one with the others stubbed.
+	stubbed := map[string]error{}
+	for _, r := range report {
+		if r.Status == FuncTranslated {
+			stubbed[r.Name] = nil
+		}
+	}
+	bad := map[string]error{}
+	for _, r := range report {
+		if r.Status != FuncTranslated {
+			continue
+		}
+		delete(stubbed, r.Name)
+		one, _, err := translateIRTextReport(file, opt, stubbed)
+		stubbed[r.Name] = nil
+		if err != nil {
+			return llvm.Module{}, nil, err
+		}
+		m, err := parseIRModule(one)
+		if err != nil {
+			bad[r.Name] = err
+			continue
+		}
+		m.Dispose()
+	}
+	if len(bad) == 0 {
+		// The failure is not attributable to a single function (e.g. data
+		// globals or the prelude), so there is nothing to degrade.
+		return llvm.Module{}, nil, err
+	}
+	ir, report, err = translateIRTextReport(file, opt, bad)
+	if err != nil {
+		return llvm.Module{}, nil, err
+	}
+	mod, err = parseIRModule(ir)
+	if err != nil {
+		return llvm.Module{}, nil, err
+	}
+	return mod, report, nil
+}
+
+func translatedReport(file *File, opt Options) []FuncReport {
+	resolve := opt.ResolveSym
+	if resolve == nil {
+		resolve = func(s string) string { return s }
+	}
+	report := make([]FuncReport, len(file.Funcs))
+	for i := range file.Funcs {
+		report[i] = FuncReport{Name: resolve(file.Funcs[i].Sym), Status: FuncTranslated}
+	}
+	return report
+}
+
+// emitDegradedStub emits a body for sig that forwards to fallback, or traps
+// when fallback is empty.
+func emitDegradedStub(b *strings.Builder, sig FuncSig, fallback string) {
+	fmt.Fprintf(b, "define %s %s(", sig.Ret, llvmGlobal(sig.Name))
+	for i, t := range sig.Args {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		fmt.Fprintf(b, "%s %%arg%d", t, i)
+	}
+	b.WriteString(") {\nentry:\n")
+	if fallback == "" {
+		b.WriteString("  call void @llvm.trap()\n")
+		b.WriteString("  unreachable\n")
+		b.WriteString("}\n")
+		return
+	}
+	args := make([]string, len(sig.Args))
+	for i, t := range sig.Args {
+		args[i] = fmt.Sprintf("%s %%arg%d", t, i)
+	}
+	if sig.Ret == Void {
+		fmt.Fprintf(b, "  tail call void %s(%s)\n", llvmGlobal(fallback), strings.Join(args, ", "))
+		b.WriteString("  ret void\n")
+	} else {
+		fmt.Fprintf(b, "  %%r = tail call %s %s(%s)\n", sig.Ret, llvmGlobal(fallback), strings.Join(args, ", "))
+		fmt.Fprintf(b, "  ret %s %%r\n", sig.Ret)
+	}
+	b.WriteString("}\n")
+}
+
+// emitDegradedDecls declares the trap intrinsic and the fallback symbols
+// that are neither defined in file nor already declared from sigs.
+func emitDegradedDecls(b *strings.Builder, report []FuncReport, sigs map[string]FuncSig, stubSigs map[string]FuncSig) {
+	defined := map[string]bool{}
+	for _, r := range report {
+		defined[r.Name] = true
+	}
+	trap := false
+	fallbacks := map[string]FuncSig{}
+	for _, r := range report {
+		switch r.Status {
+		case FuncTrap:
+			trap = true
+		case FuncFallback:
+			if defined[r.Fallback] {
+				continue
+			}
+			if s, ok := sigs[r.Fallback]; ok && s.Ret != "" {
+				continue
+			}
+			if _, ok := fallbacks[r.Fallback]; !ok {
+				fallbacks[r.Fallback] = stubSigs[r.Name]
+			}
+		}
+	}
+	names := make([]string, 0, len(fallbacks))
+	for name := range fallbacks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		sig := fallbacks[name]
+		fmt.Fprintf(b, "declare %s %s(", sig.Ret, llvmGlobal(name))
+		for i, t := range sig.Args {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			b.WriteString(string(t))
+		}
+		b.WriteString(")\n")
+	}
+	if trap && !strings.Contains(b.String(), "declare void @llvm.trap()") {
+		b.WriteString("declare void @llvm.trap()\n")
+	}
+}
@@ -0,0 +1,207 @@
+//go:build !llgo
+// +build !llgo
+
+package plan9asm
+
+import (
+	"strings"
+	"testing"
+)
+
+const degradedTestSrc = `TEXT ·good(SB),0,$0-16
+	MOVQ a+0(FP), AX
+	ADDQ $1, AX
+	MOVQ AX, ret+8(FP)
+	RET
+
+TEXT ·bad(SB),0,$0-16
+	MOVQ a+0(FP), AX
+	FROBQ AX, BX
+	MOVQ BX, ret+8(FP)
+	RET
+
+TEXT ·worse(SB),0,$0-16
+	FROBQ AX, BX
+	RET
+`
+
+func degradedTestOptions() Options {
+	sigs := map[string]FuncSig{}
+	for _, name := range []string{"good", "bad", "worse"} {
+		sigs["example."+name] = FuncSig{
+			Name: "example." + name,
+			Args: []LLVMType{I64},
+			Ret:  I64,
+			Frame: FrameLayout{
+				Params:  []FrameSlot{{Offset: 0, Type: I64, Index: 0, Field: -1}},
+				Results: []FrameSlot{{Offset: 8, Type: I64, Index: 0, Field: -1}},
+			},
+		}
+	}
+	return Options{
+		TargetTriple: "x86_64-unknown-linux-gnu",
+		Goarch:       "amd64",
+		Sigs:         sigs,
+		ResolveSym:   func(s string) string { return "example." + strings.TrimPrefix(s, "·") },
+		Degraded:     true,
+		FallbackSym: func(name string) string {
+			if name == "example.bad" {
+				return "example.badGeneric"
+			}
+			return ""
+		},
+	}
+}
+
+func TestTranslateIRTextDegraded(t *testing.T) {
+	file, err := Parse(ArchAMD64, degradedTestSrc)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	opt := degradedTestOptions()
+
+	opt.Degraded = false
+	if _, err := translateIRText(file, opt); err == nil || !strings.Contains(err.Error(), "example.bad") {
+		t.Fatalf("non-degraded translateIRText err = %v, want failure in example.bad", err)
+	}
+
+	opt.Degraded = true
+	ir, report, err := translateIRTextReport(file, opt, nil)
+	if err != nil {
+		t.Fatalf("translateIRTextReport: %v", err)
+	}
+	want := []struct {
+		name     string
+		status   FuncStatus
+		fallback string
+	}{
+		{"example.good", FuncTranslated, ""},
+		{"example.bad", FuncFallback, "example.badGeneric"},
+		{"example.worse", FuncTrap, ""},
+	}
+	if len(report) != len(want) {
+		t.Fatalf("report = %+v", report)
+	}
+	for i, w := range want {
+		r := report[i]
+		if r.Name != w.name || r.Status != w.status || r.Fallback != w.fallback {
+			t.Fatalf("report[%d] = %+v, want %s %v %q", i, r, w.name, w.status, w.fallback)
+		}
+		if (w.status == FuncTranslated) != (r.Err == nil) {
+			t.Fatalf("report[%d].Err = %v", i, r.Err)
+		}
+	}
+	if !strings.Contains(report[1].Err.Error(), "FROBQ") {
+		t.Fatalf("report[1].Err = %v, want FROBQ", report[1].Err)
+	}
+	for _, s := range []string{
+		`%r = tail call i64 @"example.badGeneric"(i64 %arg0)`,
+		`declare i64 @"example.badGeneric"(i64)`,
+		"call void @llvm.trap()",
+		"declare void @llvm.trap()",
+		`define i64 @"example.good"(i64 %arg0)`,
+	} {
+		if !strings.Contains(ir, s) {
+			t.Fatalf("missing %q in IR:\n%s", s, ir)
+		}
+	}
+}
+
+func TestTranslateIRTextDegradedForceStub(t *testing.T) {
+	file, err := Parse(ArchAMD64, degradedTestSrc)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	opt := degradedTestOptions()
+	opt.FallbackSym = nil
+	ir, report, err := translateIRTextReport(file, opt, map[string]error{"example.good": nil})
+	if err != nil {
+		t.Fatalf("translateIRTextReport: %v", err)
+	}
+	for _, r := range report {
+		if r.Status != FuncTrap {
+			t.Fatalf("report = %+v, want all trap stubs", report)
+		}
+	}
+	if n := strings.Count(ir, "declare void @llvm.trap()"); n != 1 {
+		t.Fatalf("llvm.trap declared %d times:\n%s", n, ir)
+	}
+}
+
+func TestTranslateWithReportNonDegraded(t *testing.T) {
+	file, err := Parse(ArchAMD64, "TEXT ·good(SB),0,$0-16\n\tMOVQ a+0(FP), AX\n\tMOVQ AX, ret+8(FP)\n\tRET\n")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	opt := degradedTestOptions()
+	opt.Degraded = false
+	_, report, err := TranslateWithReport(file, opt)
+	if err != nil {
+		t.Fatalf("TranslateWithReport: %v", err)
+	}
+	if len(report) != 1 || report[0].Name != "example.good" || report[0].Status != FuncTranslated {
+		t.Fatalf("report = %+v", report)
+	}
+}
+
+func TestTranslateWithReportDegraded(t *testing.T) {
+	file, err := Parse(ArchAMD64, degradedTestSrc)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	ir, report, err := TranslateWithReport(file, degradedTestOptions())
+	if err != nil {
+		t.Fatalf("TranslateWithReport: %v", err)
+	}
+	if len(report) != 3 || report[0].Status != FuncTranslated || report[1].Status != FuncFallback || report[2].Status != FuncTrap {
+		t.Fatalf("report = %+v", report)
+	}
+	if !strings.Contains(ir, "@example.badGeneric") || !strings.Contains(ir, "@llvm.trap") {
+		t.Fatalf("unexpected IR:\n%s", ir)
+	}
+}
+
+func TestTranslateGoModuleDegraded(t *testing.T) {
+	pkg := mustGoPackage(t, "test/pkg", `package testpkg
+func good(a int) int
+func bad(a int) int
+func worse(a int) int
+`)
+	opt := GoModuleOptions{
+		FileName:     "degraded_amd64.s",
+		GOARCH:       "amd64",
+		TargetTriple: "x86_64-unknown-linux-gnu",
+		ResolveSym:   testResolveSym("test/pkg"),
+	}
+	if _, err := TranslateGoModule(pkg, []byte(degradedTestSrc), opt); err == nil {
+		t.Fatalf("non-degraded TranslateGoModule unexpectedly succeeded")
+	}
+
+	opt.Degraded = true
+	opt.FallbackSym = func(name string) string {
+		if name == "test/pkg.bad" {
+			return "test/pkg.badGeneric"
+		}
+		return ""
+	}
+	tr, err := TranslateGoModule(pkg, []byte(degradedTestSrc), opt)
+	if err != nil {
+		t.Fatalf("TranslateGoModule: %v", err)
+	}
+	defer tr.Module.Dispose()
+	want := []FuncStatus{FuncTranslated, FuncFallback, FuncTrap}
+	if len(tr.Report) != len(want) {
+		t.Fatalf("report = %+v", tr.Report)
+	}
+	for i, r := range tr.Report {
+		if r.Name != tr.Functions[i].ResolvedSymbol || r.Status != want[i] {
+			t.Fatalf("report[%d] = %+v, want %s for %s", i, r, want[i], tr.Functions[i].ResolvedSymbol)
+		}
+	}
+	if tr.Report[1].Fallback != "test/pkg.badGeneric" {
+		t.Fatalf("bad falls back to %q", tr.Report[1].Fallback)
+	}
+	if tr.Module.NamedFunction("test/pkg.badGeneric").IsNil() {
+		t.Fatalf("fallback not declared")
+	}
+}
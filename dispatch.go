@@ -0,0 +1,278 @@
+package plan9asm
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DispatchMode selects how Options.Dispatch binds a function's name to the
+// version its resolver picks.
+type DispatchMode int
+
+const (
+	// DispatchNone emits one version of each function, requiring every
+	// target feature its instructions use.
+	DispatchNone DispatchMode = iota
+	// DispatchIFunc binds the name with an ELF ifunc, so the resolver runs
+	// once when the dynamic loader relocates the symbol.
+	DispatchIFunc
+	// DispatchLazy makes the name a stub calling through a pointer that the
+	// resolver fills on first use, like Go code switching on internal/cpu.
+	// It works with any object format.
+	DispatchLazy
+)
+
+// amd64CPUIDBit locates the CPUID bit reporting a target feature.
+type amd64CPUIDBit struct {
+	leaf uint32
+	// reg is the CPUID output register: 1 = EBX, 2 = ECX.
+	reg int
+	bit uint
+	// xcr0 is the register state the OS must have enabled in XCR0.
+	xcr0 uint64
+}
+
+const (
+	amd64XCR0AVX      = 0x06 // XMM, YMM
+	amd64XCR0AVX512   = 0xe6 // XMM, YMM, opmask, ZMM_Hi256, Hi16_ZMM
+	amd64CPUIDOSXSAVE = 27   // leaf 1 ECX
+)
+
+// amd64CPUIDBits covers every feature named in the amd64 feature rules.
+var amd64CPUIDBits = map[string]amd64CPUIDBit{
+	"+pclmul":       {leaf: 1, reg: 2, bit: 1},
+	"+ssse3":        {leaf: 1, reg: 2, bit: 9},
+	"+fma":          {leaf: 1, reg: 2, bit: 12, xcr0: amd64XCR0AVX},
+	"+sse4.1":       {leaf: 1, reg: 2, bit: 19},
+	"+sse4.2":       {leaf: 1, reg: 2, bit: 20},
+	"+crc32":        {leaf: 1, reg: 2, bit: 20},
+	"+popcnt":       {leaf: 1, reg: 2, bit: 23},
+	"+aes":          {leaf: 1, reg: 2, bit: 25},
+	"+avx":          {leaf: 1, reg: 2, bit: 28, xcr0: amd64XCR0AVX},
+	"+bmi":          {leaf: 7, reg: 1, bit: 3},
+	"+avx2":         {leaf: 7, reg: 1, bit: 5, xcr0: amd64XCR0AVX},
+	"+bmi2":         {leaf: 7, reg: 1, bit: 8},
+	"+avx512f":      {leaf: 7, reg: 1, bit: 16, xcr0: amd64XCR0AVX512},
+	"+avx512dq":     {leaf: 7, reg: 1, bit: 17, xcr0: amd64XCR0AVX512},
+	"+adx":          {leaf: 7, reg: 1, bit: 19},
+	"+sha":          {leaf: 7, reg: 1, bit: 29},
+	"+avx512bw":     {leaf: 7, reg: 1, bit: 30, xcr0: amd64XCR0AVX512},
+	"+avx512vl":     {leaf: 7, reg: 1, bit: 31, xcr0: amd64XCR0AVX512},
+	"+avx512vbmi":   {leaf: 7, reg: 2, bit: 1, xcr0: amd64XCR0AVX512},
+	"+gfni":         {leaf: 7, reg: 2, bit: 8},
+	"+vpclmulqdq":   {leaf: 7, reg: 2, bit: 10, xcr0: amd64XCR0AVX},
+	"+avx512bitalg": {leaf: 7, reg: 2, bit: 12, xcr0: amd64XCR0AVX512},
+}
+
+// arm64HWCAPBits maps the arm64 feature rules to Linux AT_HWCAP bits.
+var arm64HWCAPBits = map[string]uint64{
+	"+aes":  1<<3 | 1<<4, // AES, PMULL
+	"+sha2": 1<<5 | 1<<6, // SHA1, SHA2
+	"+crc":  1 << 7,
+	"+lse":  1 << 8,        // ATOMICS
+	"+sha3": 1<<17 | 1<<21, // SHA3, SHA512
+}
+
+const linuxATHWCAP = 16
+
+func emitDispatchDecls(b *strings.Builder, arch Arch, opt Options) {
+	if opt.Dispatch != DispatchNone && arch == ArchARM64 {
+		b.WriteString("declare i64 @getauxval(i64)\n\n")
+	}
+}
+
+// translateDispatchedFunc emits fn as a feature-specific and a baseline
+// version plus the resolver and entry point selected by opt.Dispatch. It
+// reports false, writing nothing, when fn uses no optional features.
+func translateDispatchedFunc(b *strings.Builder, arch Arch, fn Func, sig FuncSig, resolve func(string) string, opt Options, attrRegistry *featureAttrRegistry) (bool, error) {
+	features, required := funcFeatures(arch, fn)
+	var optional []string
+	for _, f := range features {
+		if !containsString(required, f) {
+			optional = append(optional, f)
+		}
+	}
+	if len(optional) == 0 {
+		return false, nil
+	}
+	if !tripleMatchesArch(opt.TargetTriple, arch) {
+		return true, fmt.Errorf("CPU dispatch needs a %s target triple", arch)
+	}
+	goos := opt.lowerConfig(arch).goos
+	if opt.Dispatch == DispatchIFunc {
+		switch goos {
+		case "darwin", "ios", "windows":
+			return true, fmt.Errorf("ifunc dispatch needs an ELF target, not %s", goos)
+		}
+	}
+
+	fast, base := sig, sig
+	fast.Name = sig.Name + "." + strings.ReplaceAll(strings.Join(optional, "_"), "+", "")
+	fast.Attrs = attrRegistry.ref(strings.Join(features, ","))
+	base.Name = sig.Name + ".base"
+	base.Attrs = attrRegistry.ref(strings.Join(required, ","))
+	for _, v := range []FuncSig{fast, base} {
+		var vb strings.Builder
+		if err := lowerFuncIR(&vb, arch, fn, v, resolve, opt); err != nil {
+			return true, err
+		}
+		b.WriteString(strings.Replace(vb.String(), "define ", "define internal ", 1))
+		b.WriteString("\n")
+	}
+
+	resolver := sig.Name + ".resolver"
+	var err error
+	switch arch {
+	case ArchAMD64, Arch386:
+		err = emitAMD64DispatchResolver(b, resolver, optional, fast.Name, base.Name)
+	case ArchARM64:
+		err = emitARM64DispatchResolver(b, resolver, goos, optional, fast.Name, base.Name)
+	default:
+		err = fmt.Errorf("CPU dispatch is not supported on %s", arch)
+	}
+	if err != nil {
+		return true, err
+	}
+	b.WriteString("\n")
+
+	switch opt.Dispatch {
+	case DispatchIFunc:
+		fmt.Fprintf(b, "%s = ifunc %s (%s), ptr %s\n", llvmGlobal(sig.Name), sig.Ret, joinLLVMTypes(sig.Args), llvmGlobal(resolver))
+	case DispatchLazy:
+		emitLazyDispatchEntry(b, sig, resolver)
+	default:
+		return true, fmt.Errorf("unknown dispatch mode %d", opt.Dispatch)
+	}
+	return true, nil
+}
+
+func emitAMD64DispatchResolver(b *strings.Builder, name string, features []string, fast, base string) error {
+	// masks[leaf][reg] collects the bits to test; leaf 1 ECX also carries
+	// OSXSAVE when XCR0 has to be read.
+	masks := map[uint32]*[4]uint32{1: {}, 7: {}}
+	var xcr0 uint64
+	for _, f := range features {
+		bit, ok := amd64CPUIDBits[f]
+		if !ok {
+			return fmt.Errorf("no CPUID check for target feature %s", f)
+		}
+		masks[bit.leaf][bit.reg] |= 1 << bit.bit
+		xcr0 |= bit.xcr0
+	}
+	if xcr0 != 0 {
+		masks[1][2] |= 1 << amd64CPUIDOSXSAVE
+	}
+
+	const cpuidTy = "{ i32, i32, i32, i32 }"
+	cpuid := func(res string, leaf uint32) {
+		fmt.Fprintf(b, "  %%%s = call %s asm \"cpuid\", \"={ax},={bx},={cx},={dx},{ax},{cx}\"(i32 %d, i32 0)\n", res, cpuidTy, leaf)
+	}
+	// check branches to the next block when every bit of mask is set in the
+	// given CPUID output register, and to the baseline otherwise.
+	n := 0
+	check := func(res string, reg int, mask uint32) {
+		if mask == 0 {
+			return
+		}
+		n++
+		fmt.Fprintf(b, "  %%r%d = extractvalue %s %%%s, %d\n", n, cpuidTy, res, reg)
+		fmt.Fprintf(b, "  %%m%d = and i32 %%r%d, %d\n", n, n, int32(mask))
+		fmt.Fprintf(b, "  %%ok%d = icmp eq i32 %%m%d, %d\n", n, n, int32(mask))
+		fmt.Fprintf(b, "  br i1 %%ok%d, label %%next%d, label %%base\n", n, n)
+		fmt.Fprintf(b, "next%d:\n", n)
+	}
+
+	fmt.Fprintf(b, "define internal ptr %s() {\nentry:\n", llvmGlobal(name))
+	if *masks[7] != ([4]uint32{}) {
+		// Leaf 7 is only valid when leaf 0 reports it.
+		cpuid("leaf0", 0)
+		n++
+		fmt.Fprintf(b, "  %%r%d = extractvalue %s %%leaf0, 0\n", n, cpuidTy)
+		fmt.Fprintf(b, "  %%ok%d = icmp uge i32 %%r%d, 7\n", n, n)
+		fmt.Fprintf(b, "  br i1 %%ok%d, label %%next%d, label %%base\n", n, n)
+		fmt.Fprintf(b, "next%d:\n", n)
+	}
+	cpuid("leaf1", 1)
+	check("leaf1", 2, masks[1][2])
+	if xcr0 != 0 {
+		n++
+		fmt.Fprintf(b, "  %%xcr0 = call { i32, i32 } asm \"xgetbv\", \"={ax},={dx},{cx}\"(i32 0)\n")
+		fmt.Fprintf(b, "  %%r%d = extractvalue { i32, i32 } %%xcr0, 0\n", n)
+		fmt.Fprintf(b, "  %%m%d = and i32 %%r%d, %d\n", n, n, xcr0)
+		fmt.Fprintf(b, "  %%ok%d = icmp eq i32 %%m%d, %d\n", n, n, xcr0)
+		fmt.Fprintf(b, "  br i1 %%ok%d, label %%next%d, label %%base\n", n, n)
+		fmt.Fprintf(b, "next%d:\n", n)
+	}
+	if *masks[7] != ([4]uint32{}) {
+		cpuid("leaf7", 7)
+		check("leaf7", 1, masks[7][1])
+		check("leaf7", 2, masks[7][2])
+	}
+	fmt.Fprintf(b, "  ret ptr %s\nbase:\n  ret ptr %s\n}\n", llvmGlobal(fast), llvmGlobal(base))
+	return nil
+}
+
+func emitARM64DispatchResolver(b *strings.Builder, name, goos string, features []string, fast, base string) error {
+	switch goos {
+	case "", "linux", "android":
+	default:
+		return fmt.Errorf("CPU dispatch on %s/arm64 is not supported", goos)
+	}
+	var mask uint64
+	for _, f := range features {
+		bits, ok := arm64HWCAPBits[f]
+		if !ok {
+			return fmt.Errorf("no HWCAP check for target feature %s", f)
+		}
+		mask |= bits
+	}
+	fmt.Fprintf(b, "define internal ptr %s() {\nentry:\n", llvmGlobal(name))
+	fmt.Fprintf(b, "  %%hwcap = call i64 @getauxval(i64 %d)\n", linuxATHWCAP)
+	fmt.Fprintf(b, "  %%m = and i64 %%hwcap, %d\n", mask)
+	fmt.Fprintf(b, "  %%ok = icmp eq i64 %%m, %d\n", mask)
+	fmt.Fprintf(b, "  %%fn = select i1 %%ok, ptr %s, ptr %s\n", llvmGlobal(fast), llvmGlobal(base))
+	b.WriteString("  ret ptr %fn\n}\n")
+	return nil
+}
+
+// emitLazyDispatchEntry defines sig's name as a stub that resolves the
+// version on its first call and then calls through the cached pointer.
+func emitLazyDispatchEntry(b *strings.Builder, sig FuncSig, resolver string) {
+	slot := llvmGlobal(sig.Name + ".dispatch")
+	fmt.Fprintf(b, "%s = internal global ptr null\n\n", slot)
+	fmt.Fprintf(b, "define %s %s(", sig.Ret, llvmGlobal(sig.Name))
+	args := make([]string, len(sig.Args))
+	for i, t := range sig.Args {
+		args[i] = fmt.Sprintf("%s %%arg%d", t, i)
+	}
+	b.WriteString(strings.Join(args, ", "))
+	b.WriteString(") {\nentry:\n")
+	fmt.Fprintf(b, "  %%cached = load atomic ptr, ptr %s monotonic, align 8\n", slot)
+	b.WriteString("  %resolved = icmp ne ptr %cached, null\n")
+	b.WriteString("  br i1 %resolved, label %call, label %resolve\n")
+	b.WriteString("resolve:\n")
+	fmt.Fprintf(b, "  %%picked = call ptr %s()\n", llvmGlobal(resolver))
+	fmt.Fprintf(b, "  store atomic ptr %%picked, ptr %s monotonic, align 8\n", slot)
+	b.WriteString("  br label %call\n")
+	b.WriteString("call:\n")
+	b.WriteString("  %fn = phi ptr [ %cached, %entry ], [ %picked, %resolve ]\n")
+	if sig.Ret == Void {
+		fmt.Fprintf(b, "  tail call void %%fn(%s)\n  ret void\n}\n", strings.Join(args, ", "))
+		return
+	}
+	fmt.Fprintf(b, "  %%ret = tail call %s %%fn(%s)\n  ret %s %%ret\n}\n", sig.Ret, strings.Join(args, ", "), sig.Ret)
+}
+
+func joinLLVMTypes(ts []LLVMType) string {
+	s := make([]string, len(ts))
+	for i, t := range ts {
+		s[i] = string(t)
+	}
+	return strings.Join(s, ", ")
+}
+
+func containsString(list []string, s string) bool {
+	i := sort.SearchStrings(list, s)
+	return i < len(list) && list[i] == s
+}
@@ -0,0 +1,255 @@
+//go:build !llgo
+// +build !llgo
+
+package plan9asm
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+)
+
+const dispatchAddSrc = `TEXT addv(SB),NOSPLIT,$0-16
+	MOVQ a+0(FP), SI
+	MOVQ b+8(FP), DI
+	VMOVDQU (SI), Y0
+	VMOVDQU (DI), Y1
+	VPADDQ Y0, Y1, Y1
+	VMOVDQU Y1, (SI)
+	VZEROUPPER
+	RET
+
+TEXT plain(SB),NOSPLIT,$0-16
+	MOVQ a+0(FP), AX
+	ADDQ $1, AX
+	MOVQ AX, ret+8(FP)
+	RET
+
+TEXT crc(SB),NOSPLIT,$0-16
+	MOVQ a+0(FP), SI
+	MOVQ $0, AX
+	CRC32Q (SI), AX
+	MOVQ AX, ret+8(FP)
+	RET
+`
+
+func dispatchTestSigs() map[string]FuncSig {
+	slot := func(off int64, i int) FrameSlot { return FrameSlot{Offset: off, Type: I64, Index: i, Field: -1} }
+	return map[string]FuncSig{
+		"addv": {
+			Name:  "addv",
+			Args:  []LLVMType{I64, I64},
+			Ret:   Void,
+			Frame: FrameLayout{Params: []FrameSlot{slot(0, 0), slot(8, 1)}},
+		},
+		"plain": {
+			Name:  "plain",
+			Args:  []LLVMType{I64},
+			Ret:   I64,
+			Frame: FrameLayout{Params: []FrameSlot{slot(0, 0)}, Results: []FrameSlot{slot(8, 0)}},
+		},
+		"crc": {
+			Name:  "crc",
+			Args:  []LLVMType{I64},
+			Ret:   I64,
+			Frame: FrameLayout{Params: []FrameSlot{slot(0, 0)}, Results: []FrameSlot{slot(8, 0)}},
+		},
+	}
+}
+
+func TestDispatchVersions(t *testing.T) {
+	file, err := Parse(ArchAMD64, dispatchAddSrc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		mode DispatchMode
+		want []string
+	}{
+		{DispatchIFunc, []string{`@addv = ifunc void (i64, i64), ptr @"addv.resolver"`}},
+		{DispatchLazy, []string{
+			`@"addv.dispatch" = internal global ptr null`,
+			`define void @addv(i64 %arg0, i64 %arg1) {`,
+			`tail call void %fn(i64 %arg0, i64 %arg1)`,
+		}},
+	} {
+		ll, err := translateIRText(file, Options{
+			TargetTriple: "x86_64-unknown-linux-gnu",
+			Goarch:       "amd64",
+			Sigs:         dispatchTestSigs(),
+			Dispatch:     tc.mode,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := append([]string{
+			`define internal void @"addv.avx_avx2"(i64 %arg0, i64 %arg1) #200 {`,
+			`define internal void @"addv.base"(i64 %arg0, i64 %arg1) {`,
+			`define internal ptr @"addv.resolver"() {`,
+			`ret ptr @"addv.avx_avx2"`,
+			`asm "xgetbv"`,
+			// OSXSAVE and AVX in leaf 1 ECX, AVX2 in leaf 7 EBX.
+			`and i32 %r2, 402653184`,
+			`and i32 %r4, 32`,
+			`attributes #200 = { "target-features"="+avx,+avx2" }`,
+			// Functions without optional features keep a single version.
+			`define i64 @plain(i64 %arg0)`,
+			`define i64 @crc(i64 %arg0) #201 {`,
+			`attributes #201 = { "target-features"="+crc32,+sse4.2" }`,
+		}, tc.want...)
+		for _, w := range want {
+			if !strings.Contains(ll, w) {
+				t.Errorf("mode %d: missing %q in:\n%s", tc.mode, w, ll)
+			}
+		}
+	}
+}
+
+func TestDispatchARM64(t *testing.T) {
+	file, err := Parse(ArchARM64, `TEXT add(SB),NOSPLIT,$0-24
+	MOVD p+0(FP), R0
+	MOVD v+8(FP), R1
+	LDADDALD R1, (R0), R2
+	CRC32X R1, R2
+	MOVD R2, ret+16(FP)
+	RET
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	slot := func(off int64, i int) FrameSlot { return FrameSlot{Offset: off, Type: I64, Index: i, Field: -1} }
+	sigs := map[string]FuncSig{"add": {
+		Name:  "add",
+		Args:  []LLVMType{I64, I64},
+		Ret:   I64,
+		Frame: FrameLayout{Params: []FrameSlot{slot(0, 0), slot(8, 1)}, Results: []FrameSlot{slot(16, 0)}},
+	}}
+	ll, err := translateIRText(file, Options{
+		TargetTriple: "aarch64-unknown-linux-gnu",
+		Sigs:         sigs,
+		Dispatch:     DispatchLazy,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, w := range []string{
+		`declare i64 @getauxval(i64)`,
+		`define internal i64 @"add.lse"(i64 %arg0, i64 %arg1) #200 {`,
+		`define internal i64 @"add.base"(i64 %arg0, i64 %arg1) #201 {`,
+		`call i64 @getauxval(i64 16)`,
+		`and i64 %hwcap, 256`,
+		`attributes #200 = { "target-features"="+crc,+lse" }`,
+		`attributes #201 = { "target-features"="+crc" }`,
+	} {
+		if !strings.Contains(ll, w) {
+			t.Errorf("missing %q in:\n%s", w, ll)
+		}
+	}
+
+	_, err = translateIRText(file, Options{TargetTriple: "arm64-apple-macosx", Sigs: sigs, Dispatch: DispatchLazy})
+	if err == nil || !strings.Contains(err.Error(), "CPU dispatch on darwin/arm64 is not supported") {
+		t.Fatalf("darwin/arm64 dispatch: err = %v", err)
+	}
+}
+
+func TestDispatchErrors(t *testing.T) {
+	file, err := Parse(ArchAMD64, dispatchAddSrc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		triple string
+		mode   DispatchMode
+		want   string
+	}{
+		{"x86_64-apple-macosx", DispatchIFunc, "ifunc dispatch needs an ELF target, not darwin"},
+		{"aarch64-unknown-linux-gnu", DispatchLazy, "CPU dispatch needs a amd64 target triple"},
+	} {
+		_, err := translateIRText(file, Options{
+			TargetTriple: tc.triple,
+			Goarch:       "amd64",
+			Sigs:         dispatchTestSigs(),
+			Dispatch:     tc.mode,
+		})
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s mode %d: err = %v, want %q", tc.triple, tc.mode, err, tc.want)
+		}
+	}
+	// macOS has no ifunc, but lazy dispatch works there.
+	if _, err := translateIRText(file, Options{
+		TargetTriple: "x86_64-apple-macosx",
+		Goarch:       "amd64",
+		Sigs:         dispatchTestSigs(),
+		Dispatch:     DispatchLazy,
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRuntimeExecDispatch(t *testing.T) {
+	if runtime.GOOS != "linux" || runtime.GOARCH != "amd64" {
+		t.Skip("runtime execution test only runs on linux/amd64 host")
+	}
+	llc, clang, ok := findLlcAndClang(t)
+	if !ok {
+		t.Skip("llc/clang not found")
+	}
+	file, err := Parse(ArchAMD64, dispatchAddSrc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mainC := `
+#include <stdint.h>
+extern void addv(int64_t, int64_t);
+extern int64_t plain(int64_t);
+int main(void) {
+	int64_t a[4] = {1, 2, 3, 4}, b[4] = {10, 20, 30, 40};
+	for (int i = 0; i < 2; i++) {
+		addv((int64_t)(uintptr_t)a, (int64_t)(uintptr_t)b);
+	}
+	for (int i = 0; i < 4; i++) {
+		if (a[i] != (i + 1) * 21) return 1 + i;
+	}
+	if (plain(41) != 42) return 5;
+	return 0;
+}
+`
+	for name, mode := range map[string]DispatchMode{"dispatch_ifunc": DispatchIFunc, "dispatch_lazy": DispatchLazy} {
+		ll, err := Translate(file, Options{
+			TargetTriple: testTargetTriple(runtime.GOOS, runtime.GOARCH),
+			Goarch:       "amd64",
+			Sigs:         dispatchTestSigs(),
+			Dispatch:     mode,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		compileAndRunRuntimeTest(t, llc, clang, name, ll, mainC)
+	}
+}
+
+// TestDispatchCoversFeatureRules keeps the resolvers' CPU checks in step
+// with the feature rule tables.
+func TestDispatchCoversFeatureRules(t *testing.T) {
+	checks := map[Arch]func(string) bool{
+		ArchAMD64: func(f string) bool { _, ok := amd64CPUIDBits[f]; return ok },
+		Arch386:   func(f string) bool { _, ok := amd64CPUIDBits[f]; return ok },
+		ArchARM64: func(f string) bool { _, ok := arm64HWCAPBits[f]; return ok },
+	}
+	for arch, tables := range featureRules {
+		for _, rules := range tables {
+			for _, r := range rules {
+				for _, f := range r.features {
+					if !checks[arch](f) {
+						t.Errorf("%s: no run-time check for %s", arch, f)
+					}
+				}
+			}
+		}
+	}
+	for _, f := range []string{"+avx512vl", "+avx512bw", "+avx512f", "+avx2"} {
+		if !checks[ArchAMD64](f) {
+			t.Errorf("amd64: no run-time check for width feature %s", f)
+		}
+	}
+}
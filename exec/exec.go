@@ -0,0 +1,504 @@
+// Package exec compiles translated modules in-process with LLVM's MCJIT and
+// calls their functions from Go, so the semantics of translated asm can be
+// tested without llc or a C compiler.
+//
+// Only modules translated for the host can run: the File's architecture must
+// be runtime.GOARCH, and the code must not depend on the Go runtime (g,
+// morestack, other packages' symbols).
+package exec
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"unsafe"
+
+	"github.com/xgo-dev/llvm"
+	"github.com/xgo-dev/plan9asm"
+)
+
+// Module is a translated module compiled for the host.
+//
+// Caller owns the Module and should call Dispose when finished.
+type Module struct {
+	ctx   llvm.Context
+	ee    llvm.ExecutionEngine
+	sigs  map[string]plan9asm.FuncSig
+	funcs map[string]entry
+}
+
+// entry is a function reachable through Call.
+type entry struct {
+	typ   llvm.Type  // function type of the translated function
+	thunk llvm.Value // i32 (i32, ptr) wrapper that MCJIT can run
+}
+
+// thunkPrefix names the wrapper of each callable function.
+const thunkPrefix = "plan9asm.exec."
+
+var initOnce sync.Once
+var initErr error
+
+func initJIT() error {
+	initOnce.Do(func() {
+		enableOpaquePointers()
+		llvm.LinkInMCJIT()
+		if err := llvm.InitializeNativeTarget(); err != nil {
+			initErr = fmt.Errorf("initialize native target: %w", err)
+			return
+		}
+		if err := llvm.InitializeNativeAsmPrinter(); err != nil {
+			initErr = fmt.Errorf("initialize native asm printer: %w", err)
+			return
+		}
+		// Inline asm (RawSyscall, Options.InlineAsm) is assembled by the
+		// target's asm parser; the bindings only expose the all-targets form.
+		llvm.InitializeAllAsmParsers()
+	})
+	return initErr
+}
+
+// Compile translates file with opt and compiles the result for the host.
+// Functions in opt.Sigs that the module defines can be called.
+//
+// An empty opt.TargetTriple means the host triple and an empty opt.Goarch
+// means runtime.GOARCH. Syscalls default to plan9asm.RawSyscall, which needs
+// no C library helpers.
+func Compile(file *plan9asm.File, opt plan9asm.Options) (*Module, error) {
+	if file == nil {
+		return nil, fmt.Errorf("nil file")
+	}
+	if string(file.Arch) != runtime.GOARCH {
+		return nil, fmt.Errorf("cannot run %s code on %s", file.Arch, runtime.GOARCH)
+	}
+	if err := initJIT(); err != nil {
+		return nil, err
+	}
+	if opt.TargetTriple == "" {
+		opt.TargetTriple = llvm.DefaultTargetTriple()
+	}
+	if opt.Goarch == "" {
+		opt.Goarch = runtime.GOARCH
+	}
+	if opt.Syscall == nil {
+		opt.Syscall = plan9asm.RawSyscall{}
+	}
+	ir, err := plan9asm.Translate(file, opt)
+	if err != nil {
+		return nil, err
+	}
+	return Load(ir, opt.Sigs)
+}
+
+// Load compiles LLVM IR produced for the host, as by plan9asm.Translate.
+// sigs is keyed by resolved symbol name as in plan9asm.Options.Sigs; the
+// functions in it that the module defines can be called.
+//
+// The module must not use symbols it does not define, other than LLVM
+// intrinsics.
+func Load(ir string, sigs map[string]plan9asm.FuncSig) (*Module, error) {
+	if err := initJIT(); err != nil {
+		return nil, err
+	}
+	ctx := llvm.NewContext()
+	mod, err := parseIR(ctx, ir)
+	if err != nil {
+		ctx.Dispose()
+		return nil, err
+	}
+	m := &Module{ctx: ctx, sigs: sigs, funcs: map[string]entry{}}
+	if err := m.prepare(mod, sigs); err != nil {
+		mod.Dispose()
+		ctx.Dispose()
+		return nil, err
+	}
+	opts := llvm.NewMCJITCompilerOptions()
+	opts.SetMCJITOptimizationLevel(0)
+	ee, err := llvm.NewMCJITCompiler(mod, opts)
+	if err != nil {
+		mod.Dispose()
+		ctx.Dispose()
+		return nil, fmt.Errorf("create mcjit: %w", err)
+	}
+	m.ee = ee
+	return m, nil
+}
+
+func parseIR(ctx llvm.Context, ir string) (llvm.Module, error) {
+	f, err := os.CreateTemp("", "plan9asm-exec-*.ll")
+	if err != nil {
+		return llvm.Module{}, fmt.Errorf("create temp ir file: %w", err)
+	}
+	name := f.Name()
+	_ = f.Close()
+	defer os.Remove(name)
+
+	if err := os.WriteFile(name, []byte(ir), 0644); err != nil {
+		return llvm.Module{}, fmt.Errorf("write temp ir file: %w", err)
+	}
+	buf, err := llvm.NewMemoryBufferFromFile(name)
+	if err != nil {
+		return llvm.Module{}, fmt.Errorf("open temp ir file: %w", err)
+	}
+	// ParseIR takes ownership of buf.
+	mod, err := (&ctx).ParseIR(buf)
+	if err != nil {
+		return llvm.Module{}, fmt.Errorf("parse ir: %w", err)
+	}
+	return mod, nil
+}
+
+// prepare checks that mod is self-contained and adds a thunk for each
+// function in sigs that it defines.
+func (m *Module) prepare(mod llvm.Module, sigs map[string]plan9asm.FuncSig) error {
+	var undefined []string
+	for fn := mod.FirstFunction(); !fn.IsNil(); fn = llvm.NextFunction(fn) {
+		if fn.IsDeclaration() && fn.IntrinsicID() == 0 && !fn.FirstUse().IsNil() {
+			undefined = append(undefined, fn.Name())
+		}
+	}
+	for g := mod.FirstGlobal(); !g.IsNil(); g = llvm.NextGlobal(g) {
+		if g.IsDeclaration() && !g.FirstUse().IsNil() {
+			undefined = append(undefined, g.Name())
+		}
+	}
+	if len(undefined) != 0 {
+		sort.Strings(undefined)
+		return fmt.Errorf("module uses undefined symbols: %s", strings.Join(undefined, ", "))
+	}
+
+	names := make([]string, 0, len(sigs))
+	for name := range sigs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	b := m.ctx.NewBuilder()
+	defer b.Dispose()
+	for _, name := range names {
+		fn := mod.NamedFunction(name)
+		if fn.IsNil() || fn.IsDeclaration() {
+			continue
+		}
+		thunk, err := m.buildThunk(b, mod, fn)
+		if err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+		m.funcs[name] = entry{typ: fn.GlobalValueType(), thunk: thunk}
+	}
+	if err := llvm.VerifyModule(mod, llvm.ReturnStatusAction); err != nil {
+		return fmt.Errorf("verify module: %w", err)
+	}
+	return nil
+}
+
+// buildThunk wraps fn in a function of a type MCJIT can run directly:
+//
+//	i32 (i32, ptr %frame)
+//
+// The frame holds one 8-byte slot per scalar of the flattened arguments,
+// then one per scalar of the flattened result. Each scalar sits at the
+// start of its slot in host byte order.
+func (m *Module) buildThunk(b llvm.Builder, mod llvm.Module, fn llvm.Value) (llvm.Value, error) {
+	ft := fn.GlobalValueType()
+	i32 := m.ctx.Int32Type()
+	ptr := llvm.PointerType(m.ctx.Int8Type(), 0)
+	thunk := llvm.AddFunction(mod, thunkPrefix+fn.Name(), llvm.FunctionType(i32, []llvm.Type{i32, ptr}, false))
+	b.SetInsertPointAtEnd(m.ctx.AddBasicBlock(thunk, "entry"))
+	frame := thunk.Param(1)
+
+	slot := 0
+	slotPtr := func() llvm.Value {
+		idx := llvm.ConstInt(m.ctx.Int64Type(), uint64(slot), false)
+		slot++
+		return b.CreateGEP(m.ctx.Int64Type(), frame, []llvm.Value{idx}, "")
+	}
+	var load func(t llvm.Type) (llvm.Value, error)
+	load = func(t llvm.Type) (llvm.Value, error) {
+		n, ok := aggregateLen(t)
+		if !ok {
+			if !isScalar(t) {
+				return llvm.Value{}, fmt.Errorf("unsupported type %s", t.String())
+			}
+			return b.CreateLoad(t, slotPtr(), ""), nil
+		}
+		agg := llvm.Undef(t)
+		for i := 0; i < n; i++ {
+			v, err := load(aggregateElem(t, i))
+			if err != nil {
+				return llvm.Value{}, err
+			}
+			agg = b.CreateInsertValue(agg, v, i, "")
+		}
+		return agg, nil
+	}
+	var store func(v llvm.Value, t llvm.Type) error
+	store = func(v llvm.Value, t llvm.Type) error {
+		n, ok := aggregateLen(t)
+		if !ok {
+			if !isScalar(t) {
+				return fmt.Errorf("unsupported result type %s", t.String())
+			}
+			b.CreateStore(v, slotPtr())
+			return nil
+		}
+		for i := 0; i < n; i++ {
+			if err := store(b.CreateExtractValue(v, i, ""), aggregateElem(t, i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	var args []llvm.Value
+	for _, t := range ft.ParamTypes() {
+		v, err := load(t)
+		if err != nil {
+			return llvm.Value{}, err
+		}
+		args = append(args, v)
+	}
+	call := b.CreateCall(ft, fn, args, "")
+	call.SetInstructionCallConv(fn.FunctionCallConv())
+	if rt := ft.ReturnType(); rt.TypeKind() != llvm.VoidTypeKind {
+		if err := store(call, rt); err != nil {
+			return llvm.Value{}, err
+		}
+	}
+	b.CreateRet(llvm.ConstInt(i32, 0, false))
+	return thunk, nil
+}
+
+// Dispose releases the compiled code and the module.
+func (m *Module) Dispose() {
+	if m.ee.C != nil {
+		m.ee.Dispose()
+		m.ee.C = nil
+	}
+	if m.ctx.C != nil {
+		m.ctx.Dispose()
+		m.ctx.C = nil
+	}
+}
+
+// Call calls the function sym with args, one Go value per argument of its
+// FuncSig, and returns the scalars of its result in order (none for void).
+//
+// Integer, bool and float arguments convert to the LLVM scalar they are
+// passed as, and unsafe.Pointer, uintptr and Go pointers to ptr. A slice
+// fills a { ptr, len, cap } argument and a string a { ptr, len } one, with
+// the leading fields used when the struct is shorter; an array argument
+// takes a Go array or slice of its length. Results decode by their LLVM
+// type: iN to intN (i1 to bool), ptr to unsafe.Pointer, float and double to
+// float32 and float64.
+//
+// The memory behind pointer arguments is kept alive for the duration of the
+// call.
+func (m *Module) Call(sym string, args ...any) ([]any, error) {
+	sig, ok := m.sigs[sym]
+	if !ok {
+		return nil, fmt.Errorf("no signature for %s", sym)
+	}
+	fn, ok := m.funcs[sym]
+	if !ok {
+		return nil, fmt.Errorf("%s is not defined by the module", sym)
+	}
+	if len(args) != len(sig.Args) {
+		return nil, fmt.Errorf("%s takes %d arguments, got %d", sym, len(sig.Args), len(args))
+	}
+
+	var frame frameWriter
+	for i, t := range fn.typ.ParamTypes() {
+		if err := frame.put(t, reflect.ValueOf(args[i])); err != nil {
+			return nil, fmt.Errorf("%s: argument %d: %v", sym, i, err)
+		}
+	}
+	resultAt := len(frame.slots)
+	var results []llvm.Type
+	if rt := fn.typ.ReturnType(); rt.TypeKind() != llvm.VoidTypeKind {
+		results = flatten(rt, nil)
+	}
+	frame.slots = append(frame.slots, make([]uint64, len(results)+1)...)
+
+	n := llvm.NewGenericValueFromInt(m.ctx.Int32Type(), 0, false)
+	p := llvm.NewGenericValueFromPointer(unsafe.Pointer(&frame.slots[0]))
+	r := m.ee.RunFunction(fn.thunk, []llvm.GenericValue{n, p})
+	r.Dispose()
+	p.Dispose()
+	n.Dispose()
+	runtime.KeepAlive(args)
+
+	var out []any
+	for i, t := range results {
+		out = append(out, decode(t, unsafe.Pointer(&frame.slots[resultAt+i])))
+	}
+	return out, nil
+}
+
+// frameWriter lays out arguments as the thunks expect them.
+type frameWriter struct {
+	slots []uint64
+}
+
+func (w *frameWriter) next() unsafe.Pointer {
+	w.slots = append(w.slots, 0)
+	return unsafe.Pointer(&w.slots[len(w.slots)-1])
+}
+
+func (w *frameWriter) put(t llvm.Type, v reflect.Value) error {
+	if !v.IsValid() {
+		return fmt.Errorf("nil value for %s", t.String())
+	}
+	switch t.TypeKind() {
+	case llvm.StructTypeKind:
+		fields := t.StructElementTypes()
+		var parts []reflect.Value
+		switch v.Kind() {
+		case reflect.Slice:
+			parts = []reflect.Value{reflect.ValueOf(v.Pointer()), reflect.ValueOf(v.Len()), reflect.ValueOf(v.Cap())}
+		case reflect.String:
+			s := v.String()
+			parts = []reflect.Value{reflect.ValueOf(unsafe.StringData(s)), reflect.ValueOf(len(s))}
+		default:
+			return fmt.Errorf("cannot pass %s as %s", v.Type(), t.String())
+		}
+		if len(fields) == 0 || len(fields) > len(parts) || fields[0].TypeKind() != llvm.PointerTypeKind {
+			return fmt.Errorf("cannot pass %s as %s", v.Type(), t.String())
+		}
+		for i, f := range fields {
+			if err := w.put(f, parts[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	case llvm.ArrayTypeKind:
+		if (v.Kind() != reflect.Array && v.Kind() != reflect.Slice) || v.Len() != t.ArrayLength() {
+			return fmt.Errorf("cannot pass %s as %s", v.Type(), t.String())
+		}
+		for i := 0; i < v.Len(); i++ {
+			if err := w.put(t.ElementType(), v.Index(i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case llvm.IntegerTypeKind:
+		var x uint64
+		switch v.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			x = uint64(v.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			x = v.Uint()
+		case reflect.Bool:
+			if v.Bool() {
+				x = 1
+			}
+		default:
+			return fmt.Errorf("cannot pass %s as %s", v.Type(), t.String())
+		}
+		p := w.next()
+		switch t.IntTypeWidth() {
+		case 1, 8:
+			*(*uint8)(p) = uint8(x)
+		case 16:
+			*(*uint16)(p) = uint16(x)
+		case 32:
+			*(*uint32)(p) = uint32(x)
+		case 64:
+			*(*uint64)(p) = x
+		default:
+			return fmt.Errorf("unsupported type %s", t.String())
+		}
+		return nil
+	case llvm.FloatTypeKind, llvm.DoubleTypeKind:
+		if v.Kind() != reflect.Float32 && v.Kind() != reflect.Float64 {
+			return fmt.Errorf("cannot pass %s as %s", v.Type(), t.String())
+		}
+		p := w.next()
+		if t.TypeKind() == llvm.FloatTypeKind {
+			*(*float32)(p) = float32(v.Float())
+		} else {
+			*(*float64)(p) = v.Float()
+		}
+		return nil
+	case llvm.PointerTypeKind:
+		var x uintptr
+		switch v.Kind() {
+		case reflect.Pointer, reflect.UnsafePointer:
+			x = v.Pointer()
+		case reflect.Uintptr:
+			x = uintptr(v.Uint())
+		default:
+			return fmt.Errorf("cannot pass %s as %s", v.Type(), t.String())
+		}
+		*(*uintptr)(w.next()) = x
+		return nil
+	}
+	return fmt.Errorf("unsupported type %s", t.String())
+}
+
+func decode(t llvm.Type, p unsafe.Pointer) any {
+	switch t.TypeKind() {
+	case llvm.IntegerTypeKind:
+		switch t.IntTypeWidth() {
+		case 1:
+			return *(*uint8)(p)&1 != 0
+		case 8:
+			return *(*int8)(p)
+		case 16:
+			return *(*int16)(p)
+		case 32:
+			return *(*int32)(p)
+		}
+		return *(*int64)(p)
+	case llvm.FloatTypeKind:
+		return *(*float32)(p)
+	case llvm.DoubleTypeKind:
+		return *(*float64)(p)
+	}
+	return *(*unsafe.Pointer)(p)
+}
+
+// flatten appends the scalars t is made of to out.
+func flatten(t llvm.Type, out []llvm.Type) []llvm.Type {
+	n, ok := aggregateLen(t)
+	if !ok {
+		return append(out, t)
+	}
+	for i := 0; i < n; i++ {
+		out = flatten(aggregateElem(t, i), out)
+	}
+	return out
+}
+
+func aggregateLen(t llvm.Type) (int, bool) {
+	switch t.TypeKind() {
+	case llvm.StructTypeKind:
+		return t.StructElementTypesCount(), true
+	case llvm.ArrayTypeKind:
+		return t.ArrayLength(), true
+	}
+	return 0, false
+}
+
+func aggregateElem(t llvm.Type, i int) llvm.Type {
+	if t.TypeKind() == llvm.StructTypeKind {
+		return t.StructElementTypes()[i]
+	}
+	return t.ElementType()
+}
+
+func isScalar(t llvm.Type) bool {
+	switch t.TypeKind() {
+	case llvm.IntegerTypeKind:
+		switch t.IntTypeWidth() {
+		case 1, 8, 16, 32, 64:
+			return true
+		}
+	case llvm.FloatTypeKind, llvm.DoubleTypeKind, llvm.PointerTypeKind:
+		return true
+	}
+	return false
+}
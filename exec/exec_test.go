@@ -0,0 +1,265 @@
+//go:build !llgo
+// +build !llgo
+
+package exec
+
+import (
+	"io"
+	"os"
+	"runtime"
+	"strings"
+	"syscall"
+	"testing"
+	"unsafe"
+
+	"github.com/xgo-dev/plan9asm"
+)
+
+const amd64Src = `TEXT ·SumLen(SB),NOSPLIT,$0-40
+	MOVQ	s_base+0(FP), SI
+	MOVQ	s_len+8(FP), CX
+	MOVQ	CX, DX
+	XORQ	AX, AX
+loop:
+	TESTQ	CX, CX
+	JEQ	done
+	ADDQ	(SI), AX
+	ADDQ	$8, SI
+	DECQ	CX
+	JMP	loop
+done:
+	MOVQ	AX, ret+24(FP)
+	MOVQ	DX, ret1+32(FP)
+	RET
+
+TEXT ·Count(SB),NOSPLIT,$0-32
+	MOVQ	s_base+0(FP), SI
+	MOVQ	s_len+8(FP), CX
+	MOVBLZX	c+16(FP), DX
+	XORQ	AX, AX
+loop:
+	TESTQ	CX, CX
+	JEQ	done
+	MOVBLZX	(SI), BX
+	CMPQ	BX, DX
+	JNE	next
+	INCQ	AX
+next:
+	INCQ	SI
+	DECQ	CX
+	JMP	loop
+done:
+	MOVQ	AX, ret+24(FP)
+	RET
+
+TEXT ·Less(SB),NOSPLIT,$0-17
+	MOVQ	a+0(FP), AX
+	CMPQ	AX, b+8(FP)
+	SETLT	ret+16(FP)
+	RET
+
+TEXT ·Base(SB),NOSPLIT,$0-16
+	MOVQ	p+0(FP), AX
+	MOVQ	AX, ret+8(FP)
+	RET
+`
+
+const syscallSrc = `TEXT ·Getpid(SB),NOSPLIT,$0-8
+	MOVQ	$39, AX
+	SYSCALL
+	MOVQ	AX, ret+0(FP)
+	RET
+
+TEXT ·Write(SB),NOSPLIT,$0-32
+	MOVQ	fd+0(FP), DI
+	MOVQ	p+8(FP), SI
+	MOVQ	n+16(FP), DX
+	MOVQ	$1, AX
+	SYSCALL
+	MOVQ	AX, ret+24(FP)
+	RET
+`
+
+func slot(off int64, t plan9asm.LLVMType, index, field int) plan9asm.FrameSlot {
+	return plan9asm.FrameSlot{Offset: off, Type: t, Index: index, Field: field}
+}
+
+func testSigs() map[string]plan9asm.FuncSig {
+	slice := plan9asm.LLVMType("{ ptr, i64, i64 }")
+	str := plan9asm.LLVMType("{ ptr, i64 }")
+	return map[string]plan9asm.FuncSig{
+		"example.SumLen": {
+			Name: "example.SumLen", Args: []plan9asm.LLVMType{slice}, Ret: "{ i64, i64 }",
+			Frame: plan9asm.FrameLayout{
+				Params:  []plan9asm.FrameSlot{slot(0, plan9asm.Ptr, 0, 0), slot(8, plan9asm.I64, 0, 1), slot(16, plan9asm.I64, 0, 2)},
+				Results: []plan9asm.FrameSlot{slot(24, plan9asm.I64, 0, -1), slot(32, plan9asm.I64, 1, -1)},
+			},
+		},
+		"example.Count": {
+			Name: "example.Count", Args: []plan9asm.LLVMType{str, plan9asm.I8}, Ret: plan9asm.I64,
+			Frame: plan9asm.FrameLayout{
+				Params:  []plan9asm.FrameSlot{slot(0, plan9asm.Ptr, 0, 0), slot(8, plan9asm.I64, 0, 1), slot(16, plan9asm.I8, 1, -1)},
+				Results: []plan9asm.FrameSlot{slot(24, plan9asm.I64, 0, -1)},
+			},
+		},
+		"example.Less": {
+			Name: "example.Less", Args: []plan9asm.LLVMType{plan9asm.I64, plan9asm.I64}, Ret: plan9asm.I1,
+			Frame: plan9asm.FrameLayout{
+				Params:  []plan9asm.FrameSlot{slot(0, plan9asm.I64, 0, -1), slot(8, plan9asm.I64, 1, -1)},
+				Results: []plan9asm.FrameSlot{slot(16, plan9asm.I1, 0, -1)},
+			},
+		},
+		"example.Getpid": {
+			Name: "example.Getpid", Ret: plan9asm.I64,
+			Frame: plan9asm.FrameLayout{Results: []plan9asm.FrameSlot{slot(0, plan9asm.I64, 0, -1)}},
+		},
+		"example.Write": {
+			Name: "example.Write", Args: []plan9asm.LLVMType{plan9asm.I64, plan9asm.Ptr, plan9asm.I64}, Ret: plan9asm.I64,
+			Frame: plan9asm.FrameLayout{
+				Params:  []plan9asm.FrameSlot{slot(0, plan9asm.I64, 0, -1), slot(8, plan9asm.Ptr, 1, -1), slot(16, plan9asm.I64, 2, -1)},
+				Results: []plan9asm.FrameSlot{slot(24, plan9asm.I64, 0, -1)},
+			},
+		},
+		"example.Base": {
+			Name: "example.Base", Args: []plan9asm.LLVMType{plan9asm.Ptr}, Ret: plan9asm.Ptr,
+			Frame: plan9asm.FrameLayout{
+				Params:  []plan9asm.FrameSlot{slot(0, plan9asm.Ptr, 0, -1)},
+				Results: []plan9asm.FrameSlot{slot(8, plan9asm.Ptr, 0, -1)},
+			},
+		},
+	}
+}
+
+func resolveExample(sym string) string {
+	return "example." + strings.TrimPrefix(sym, "·")
+}
+
+func compileAMD64(t *testing.T, src string) *Module {
+	t.Helper()
+	if runtime.GOARCH != "amd64" {
+		t.Skip("JIT test only runs on an amd64 host")
+	}
+	file, err := plan9asm.Parse(plan9asm.ArchAMD64, src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := Compile(file, plan9asm.Options{ResolveSym: resolveExample, Sigs: testSigs()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(m.Dispose)
+	return m
+}
+
+func call(t *testing.T, m *Module, sym string, args ...any) []any {
+	t.Helper()
+	out, err := m.Call(sym, args...)
+	if err != nil {
+		t.Fatalf("%s: %v", sym, err)
+	}
+	return out
+}
+
+func TestCall(t *testing.T) {
+	m := compileAMD64(t, amd64Src)
+
+	xs := make([]int64, 4, 10)
+	copy(xs, []int64{1, 2, 3, 4})
+	if got := call(t, m, "example.SumLen", xs); len(got) != 2 || got[0] != int64(10) || got[1] != int64(4) {
+		t.Errorf("SumLen = %v", got)
+	}
+	if got := call(t, m, "example.SumLen", []int64(nil)); got[0] != int64(0) || got[1] != int64(0) {
+		t.Errorf("SumLen(nil) = %v", got)
+	}
+	if got := call(t, m, "example.Count", "banana", 'a'); len(got) != 1 || got[0] != int64(3) {
+		t.Errorf("Count = %v", got)
+	}
+	for _, tc := range []struct {
+		a, b int
+		want bool
+	}{{1, 2, true}, {2, 1, false}, {-5, 3, true}} {
+		if got := call(t, m, "example.Less", tc.a, tc.b); got[0] != tc.want {
+			t.Errorf("Less(%d, %d) = %v", tc.a, tc.b, got)
+		}
+	}
+	if got := call(t, m, "example.Base", &xs[2]); got[0] != unsafe.Pointer(&xs[2]) {
+		t.Errorf("Base = %v, want %p", got, &xs[2])
+	}
+}
+
+// TestRawSyscall runs SYSCALL, lowered to the kernel trap by the default
+// RawSyscall strategy, in this process.
+func TestRawSyscall(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("raw syscall numbers are Linux's")
+	}
+	m := compileAMD64(t, syscallSrc)
+
+	if got := call(t, m, "example.Getpid"); got[0] != int64(os.Getpid()) {
+		t.Errorf("Getpid = %v, want %d", got, os.Getpid())
+	}
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+	msg := []byte("plan9asm")
+	if got := call(t, m, "example.Write", int64(w.Fd()), &msg[0], int64(len(msg))); got[0] != int64(len(msg)) {
+		t.Fatalf("Write = %v, want %d", got, len(msg))
+	}
+	buf := make([]byte, len(msg))
+	if _, err := io.ReadFull(r, buf); err != nil || string(buf) != string(msg) {
+		t.Errorf("read %q, %v; want %q", buf, err, msg)
+	}
+	// The raw trap returns -errno.
+	if got := call(t, m, "example.Write", -1, &msg[0], int64(len(msg))); got[0] != -int64(syscall.EBADF) {
+		t.Errorf("Write(-1) = %v, want %d", got, -int64(syscall.EBADF))
+	}
+}
+
+func TestCallErrors(t *testing.T) {
+	m := compileAMD64(t, amd64Src)
+	for _, tc := range []struct {
+		sym  string
+		args []any
+		want string
+	}{
+		{"example.Missing", nil, "no signature"},
+		{"example.Less", []any{1}, "takes 2 arguments, got 1"},
+		{"example.Less", []any{1, "x"}, "argument 1: cannot pass string as i64"},
+		{"example.SumLen", []any{"x"}, "cannot pass string as { ptr, i64, i64 }"},
+		{"example.Base", []any{nil}, "argument 0: nil value"},
+	} {
+		_, err := m.Call(tc.sym, tc.args...)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("Call(%s, %v) = %v, want %q", tc.sym, tc.args, err, tc.want)
+		}
+	}
+}
+
+func TestCompileRejects(t *testing.T) {
+	file, err := plan9asm.Parse(plan9asm.ArchS390X, "TEXT ·F(SB),NOSPLIT,$0-0\n\tRET\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Compile(file, plan9asm.Options{ResolveSym: resolveExample}); err == nil || !strings.Contains(err.Error(), "cannot run s390x code") {
+		t.Errorf("Compile(s390x) = %v", err)
+	}
+
+	if runtime.GOARCH != "amd64" {
+		return
+	}
+	file, err = plan9asm.Parse(plan9asm.ArchAMD64, "TEXT ·F(SB),NOSPLIT,$0-0\n\tCALL\t·G(SB)\n\tRET\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sigs := map[string]plan9asm.FuncSig{
+		"example.F": {Name: "example.F", Ret: plan9asm.Void},
+		"example.G": {Name: "example.G", Ret: plan9asm.Void},
+	}
+	_, err = Compile(file, plan9asm.Options{ResolveSym: resolveExample, Sigs: sigs})
+	if err == nil || !strings.Contains(err.Error(), "undefined symbols: example.G") {
+		t.Errorf("Compile(call to G) = %v", err)
+	}
+}
@@ -0,0 +1,7 @@
+//go:build !llvm14
+
+package exec
+
+// enableOpaquePointers is a no-op: LLVM 15 and later use opaque pointers by
+// default.
+func enableOpaquePointers() {}
@@ -0,0 +1,12 @@
+//go:build llvm14
+
+package exec
+
+import "github.com/xgo-dev/llvm"
+
+// enableOpaquePointers switches LLVM 14 to opaque pointers, which translated
+// IR uses. It affects contexts created afterwards, including the global
+// context plan9asm.Translate parses into if that is not yet in use.
+func enableOpaquePointers() {
+	llvm.ParseCommandLineOptions([]string{"plan9asm-exec", "-opaque-pointers"}, "")
+}
@@ -40,50 +40,92 @@ func (r *featureAttrRegistry) emit(b *strings.Builder) {
 	b.WriteString("\n")
 }
 
-func inferFuncTargetFeatures(arch Arch, fn Func) string {
-	var featureSet []string
-	add := func(features ...string) {
-		for _, feature := range features {
-			if feature == "" {
-				continue
+// featureRule names the LLVM target features the lowering of some opcodes
+// requires. Each backend declares its rules next to the lowering they
+// describe; featureRules collects them per architecture.
+type featureRule struct {
+	// ops lists the opcodes the rule covers. An entry ending in "*" matches
+	// every opcode with that prefix.
+	ops      []string
+	features []string
+	// required lists features of target intrinsics the lowering calls. Unlike
+	// the others, they cannot be dropped from a baseline version of the
+	// function (see Options.Dispatch).
+	required []string
+	// operands, when set, returns extra features that depend on the
+	// instruction's operands, such as the vector register width.
+	operands func(ins Instr) []string
+}
+
+func (r featureRule) matches(op string) bool {
+	for _, o := range r.ops {
+		if prefix, ok := strings.CutSuffix(o, "*"); ok {
+			if strings.HasPrefix(op, prefix) {
+				return true
 			}
-			exists := false
-			for _, v := range featureSet {
-				if v == feature {
-					exists = true
-					break
-				}
+		} else if op == o {
+			return true
+		}
+	}
+	return false
+}
+
+var featureRules = map[Arch][][]featureRule{
+	ArchAMD64: {amd64ArithFeatures, amd64FPFeatures, amd64CRC32Features, amd64VecFeatures},
+	Arch386:   {amd64ArithFeatures, amd64FPFeatures, amd64CRC32Features, amd64VecFeatures},
+	ArchARM64: {arm64ArithFeatures, arm64AtomicFeatures, arm64VecFeatures},
+}
+
+// instrFeatures returns the target features needed to lower ins on arch,
+// and the subset of them its target intrinsics require.
+func instrFeatures(arch Arch, ins Instr) (features, required []string) {
+	op := strings.ToUpper(strings.TrimSpace(string(ins.Op)))
+	if arch == ArchAMD64 || arch == Arch386 {
+		// Drop AVX-512 suffixes such as ".Z" and ".BCST".
+		op, _, _ = strings.Cut(op, ".")
+	}
+	for _, rules := range featureRules[arch] {
+		for _, r := range rules {
+			if !r.matches(op) {
+				continue
 			}
-			if !exists {
-				featureSet = append(featureSet, feature)
+			features = append(features, r.features...)
+			features = append(features, r.required...)
+			required = append(required, r.required...)
+			if r.operands != nil {
+				features = append(features, r.operands(ins)...)
 			}
 		}
 	}
+	return features, required
+}
 
+// funcFeatures returns the sorted target features fn needs on arch, and the
+// subset its target intrinsics require.
+func funcFeatures(arch Arch, fn Func) (features, required []string) {
+	seen := map[string]bool{}
+	seenRequired := map[string]bool{}
 	for _, ins := range fn.Instrs {
-		op := strings.ToUpper(string(ins.Op))
-		switch arch {
-		case ArchAMD64:
-			switch {
-			case strings.HasPrefix(op, "CRC32"):
-				add("+crc32", "+sse4.2")
-			case op == "PCLMULQDQ":
-				add("+pclmul", "+sse4.1")
-			case op == "PSHUFB" || op == "VPSHUFB":
-				add("+ssse3")
-			case op == "AESENC" || op == "AESENCLAST" || op == "AESDEC" || op == "AESDECLAST" || op == "AESIMC" || op == "AESKEYGENASSIST":
-				add("+aes")
+		all, req := instrFeatures(arch, ins)
+		for _, f := range all {
+			if !seen[f] {
+				seen[f] = true
+				features = append(features, f)
 			}
-		case ArchARM64:
-			if strings.HasPrefix(op, "CRC32") {
-				add("+crc")
+		}
+		for _, f := range req {
+			if !seenRequired[f] {
+				seenRequired[f] = true
+				required = append(required, f)
 			}
 		}
 	}
+	sort.Strings(features)
+	sort.Strings(required)
+	return features, required
+}
 
-	if len(featureSet) == 0 {
-		return ""
-	}
-	sort.Strings(featureSet)
-	return strings.Join(featureSet, ",")
+func inferFuncTargetFeatures(arch Arch, fn Func) string {
+	features, _ := funcFeatures(arch, fn)
+	return strings.Join(features, ",")
 }
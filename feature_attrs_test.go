@@ -4,6 +4,8 @@
 package plan9asm
 
 import (
+	"os"
+	"os/exec"
 	"strings"
 	"testing"
 )
@@ -31,7 +33,7 @@ func TestInferFuncTargetFeatures(t *testing.T) {
 			name: "amd64 pshufb",
 			arch: ArchAMD64,
 			ops:  []Op{"PSHUFB", "VPSHUFB"},
-			want: "+ssse3",
+			want: "+avx,+ssse3",
 		},
 		{
 			name: "amd64 aes",
@@ -43,7 +45,37 @@ func TestInferFuncTargetFeatures(t *testing.T) {
 			name: "amd64 combined sorted deduped",
 			arch: ArchAMD64,
 			ops:  []Op{"AESENC", "CRC32L", "PCLMULQDQ", "PSHUFB", "CRC32Q", "AESDEC", "VPSHUFB"},
-			want: "+aes,+crc32,+pclmul,+sse4.1,+sse4.2,+ssse3",
+			want: "+aes,+avx,+crc32,+pclmul,+sse4.1,+sse4.2,+ssse3",
+		},
+		{
+			name: "amd64 bmi adx",
+			arch: ArchAMD64,
+			ops:  []Op{"SHLXQ", "RORXQ", "MULXQ", "ANDNQ", "ADCXQ", "POPCNTQ"},
+			want: "+adx,+bmi,+bmi2,+popcnt",
+		},
+		{
+			name: "amd64 sha fma",
+			arch: ArchAMD64,
+			ops:  []Op{"SHA256RNDS2", "VFMADD213SD"},
+			want: "+fma,+sha",
+		},
+		{
+			name: "amd64 mask registers",
+			arch: ArchAMD64,
+			ops:  []Op{"KMOVQ", "KXORQ", "KMOVB"},
+			want: "+avx512bw,+avx512dq",
+		},
+		{
+			name: "arm64 crypto",
+			arch: ArchARM64,
+			ops:  []Op{"AESE", "VPMULL", "SHA256H", "SHA512H", "VEOR3"},
+			want: "+aes,+sha2,+sha3",
+		},
+		{
+			name: "arm64 lse",
+			arch: ArchARM64,
+			ops:  []Op{"LDADDALD", "SWPALW", "CASALD", "LDAXR"},
+			want: "+lse",
 		},
 		{
 			name: "arm64 crc32",
@@ -72,6 +104,106 @@ func TestInferFuncTargetFeatures(t *testing.T) {
 	}
 }
 
+func TestInferFuncTargetFeaturesOperands(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"VPXOR X1, X2, X3", "+avx"},
+		{"VPXOR Y1, Y2, Y3", "+avx,+avx2"},
+		{"VMOVDQU Y1, (AX)", "+avx"},
+		{"VPSHUFB Z1, Z2, Z3", "+avx,+avx512bw,+avx512f,+ssse3"},
+		{"VPTERNLOGD $0x96, Z1, Z2, Z3", "+avx512f"},
+		{"VPTERNLOGD $0x96, Y1, Y2, Y3", "+avx512f,+avx512vl"},
+		{"VPCLMULQDQ $0, Z1, Z2, Z3", "+avx,+avx512f,+pclmul,+vpclmulqdq"},
+		{"VGF2P8AFFINEQB $0, Y1, Y2, Y3", "+avx,+avx2,+gfni"},
+		{"VPOPCNTB Z1, K1, Z2", "+avx512bitalg,+avx512f"},
+		{"VPXORQ.Z Z1, Z2, K1, Z3", "+avx512f"},
+	}
+	for _, tt := range tests {
+		file, err := Parse(ArchAMD64, "TEXT ·f(SB),0,$0\n\t"+tt.src+"\n\tRET\n")
+		if err != nil {
+			t.Fatalf("%s: %v", tt.src, err)
+		}
+		if got := inferFuncTargetFeatures(ArchAMD64, file.Funcs[0]); got != tt.want {
+			t.Errorf("%s: features %q, want %q", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestFuncRequiredFeatures(t *testing.T) {
+	tests := []struct {
+		arch Arch
+		ops  []Op
+		want string
+	}{
+		{ArchAMD64, []Op{"VPXOR", "VPCLMULQDQ", "PCLMULQDQ"}, "+pclmul"},
+		{ArchAMD64, []Op{"VPSHUFB", "CRC32Q", "SHLXQ"}, "+crc32,+sse4.2,+ssse3"},
+		{ArchAMD64, []Op{"VPTERNLOGD", "ADCXQ"}, ""},
+		{ArchARM64, []Op{"CRC32X", "LDADDALD"}, "+crc"},
+	}
+	for _, tt := range tests {
+		fn := Func{Instrs: make([]Instr, len(tt.ops))}
+		for i, op := range tt.ops {
+			fn.Instrs[i] = Instr{Op: op}
+		}
+		if _, got := funcFeatures(tt.arch, fn); strings.Join(got, ",") != tt.want {
+			t.Errorf("%s %v: required %q, want %q", tt.arch, tt.ops, got, tt.want)
+		}
+	}
+}
+
+// TestVEXOpsHaveFeatures keeps the amd64 rule table in step with the
+// lowerers: every lowered VEX/EVEX or mask-register opcode needs at least AVX.
+func TestVEXOpsHaveFeatures(t *testing.T) {
+	for _, cp := range Capabilities(ArchAMD64) {
+		op := string(cp.Op)
+		if (strings.HasPrefix(op, "V") || strings.HasPrefix(op, "K")) && len(cp.Features) == 0 {
+			t.Errorf("%s is lowered but has no feature rule", op)
+		}
+	}
+}
+
+// TestFeatureRulesKnownToLLC checks every feature named by the rule tables
+// is one llc recognizes for the architecture.
+func TestFeatureRulesKnownToLLC(t *testing.T) {
+	llc, _, _ := findLlcAndClang(t)
+	if llc == "" {
+		t.Skip("llc not found")
+	}
+	triples := map[Arch]string{
+		ArchAMD64: "x86_64-unknown-linux-gnu",
+		ArchARM64: "aarch64-unknown-linux-gnu",
+	}
+	for arch, tables := range featureRules {
+		seen := map[string]bool{}
+		var features []string
+		for _, rules := range tables {
+			for _, r := range rules {
+				for _, f := range append(r.features, r.required...) {
+					if !seen[f] {
+						seen[f] = true
+						features = append(features, f)
+					}
+				}
+			}
+		}
+		// Operand-dependent features come from the width helpers.
+		if arch == ArchAMD64 {
+			features = append(features, "+avx512vl", "+avx512bw")
+		}
+		cmd := exec.Command(llc, "-mtriple="+triples[arch], "-mattr="+strings.Join(features, ","), "-o", os.DevNull)
+		cmd.Stdin = strings.NewReader("define void @f() {\n  ret void\n}\n")
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("%s: llc failed: %v\n%s", arch, err, out)
+		}
+		if strings.Contains(string(out), "not a recognized feature") {
+			t.Errorf("%s: %s", arch, out)
+		}
+	}
+}
+
 func TestFeatureAttrRegistry(t *testing.T) {
 	r := newFeatureAttrRegistry()
 	if got := r.ref(""); got != "" {
@@ -0,0 +1,85 @@
+package plan9asm
+
+// Flag liveness.
+//
+// The CFG translators model condition flags as one i1 alloca per flag, and
+// a flag-setting instruction would otherwise compute and store every flag
+// it defines. Most of those values are overwritten before anything reads
+// them. Before lowering a function the amd64 and arm64 translators run a
+// backward dataflow pass over their basic blocks; the flag helpers then
+// skip flags that are dead after the current instruction.
+
+// flagMask is a set of modeled flags in an architecture's own numbering
+// (amd64FlagCF..., arm64FlagN...).
+type flagMask uint8
+
+// flagEffect summarizes how one instruction touches the modeled flags.
+// use holds every flag the lowering may read, including flags it only
+// conditionally preserves (a shift by a register count); def holds flags it
+// always overwrites. Instructions not known to touch flags have neither.
+type flagEffect struct {
+	use flagMask
+	def flagMask
+}
+
+// flagSuccs lists the blocks control can reach from the end of a block. A
+// block that leaves the function (RET, tail call) has none. unknown marks
+// control flow the analysis cannot follow; every flag is then live.
+type flagSuccs struct {
+	blocks  []int
+	unknown bool
+}
+
+// flagLiveOut returns, for each instruction of each block, the flags that
+// may still be read after it executes. all is the architecture's full set.
+func flagLiveOut(effects [][]flagEffect, succs []flagSuccs, all flagMask) [][]flagMask {
+	liveIn := make([]flagMask, len(effects))
+	blockOut := func(bi int) flagMask {
+		if succs[bi].unknown {
+			return all
+		}
+		var out flagMask
+		for _, s := range succs[bi].blocks {
+			out |= liveIn[s]
+		}
+		return out
+	}
+	// Live sets only grow, so iterating to a fixed point terminates.
+	for changed := true; changed; {
+		changed = false
+		for bi := len(effects) - 1; bi >= 0; bi-- {
+			live := blockOut(bi)
+			for ii := len(effects[bi]) - 1; ii >= 0; ii-- {
+				live = live&^effects[bi][ii].def | effects[bi][ii].use
+			}
+			if live != liveIn[bi] {
+				liveIn[bi] = live
+				changed = true
+			}
+		}
+	}
+
+	out := make([][]flagMask, len(effects))
+	for bi := range effects {
+		out[bi] = make([]flagMask, len(effects[bi]))
+		live := blockOut(bi)
+		for ii := len(effects[bi]) - 1; ii >= 0; ii-- {
+			out[bi][ii] = live
+			live = live&^effects[bi][ii].def | effects[bi][ii].use
+		}
+	}
+	return out
+}
+
+// flagAllLive keeps every flag live after every instruction, which turns the
+// pass off (lowerConfig.keepDeadFlags).
+func flagAllLive(effects [][]flagEffect, all flagMask) [][]flagMask {
+	out := make([][]flagMask, len(effects))
+	for bi := range effects {
+		out[bi] = make([]flagMask, len(effects[bi]))
+		for ii := range out[bi] {
+			out[bi][ii] = all
+		}
+	}
+	return out
+}
@@ -0,0 +1,350 @@
+//go:build !llgo
+// +build !llgo
+
+package plan9asm
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+var (
+	flagLoadRE  = regexp.MustCompile(`load i1, ptr (%flags_\w+)`)
+	flagStoreRE = regexp.MustCompile(`store i1 [^,]+, ptr (%flags_\w+)`)
+)
+
+var flagSlotMasks = map[Arch]map[string]flagMask{
+	ArchAMD64: {
+		"%flags_cf": amd64FlagCF, "%flags_pf": amd64FlagPF, "%flags_af": amd64FlagAF,
+		"%flags_z": amd64FlagZF, "%flags_sf": amd64FlagSF, "%flags_of": amd64FlagOF,
+	},
+	ArchARM64: {
+		"%flags_n": arm64FlagN, "%flags_z": arm64FlagZ, "%flags_c": arm64FlagC, "%flags_v": arm64FlagV,
+	},
+}
+
+// flagAccessBySource maps each "; s:" source comment in ir to the flags read
+// and written by the IR emitted for that instruction.
+func flagAccessBySource(arch Arch, ir string) map[string]flagEffect {
+	out := map[string]flagEffect{}
+	cur := ""
+	for _, line := range strings.Split(ir, "\n") {
+		if s, ok := strings.CutPrefix(strings.TrimSpace(line), "; s: "); ok {
+			cur = s
+			continue
+		}
+		e := out[cur]
+		if m := flagLoadRE.FindStringSubmatch(line); m != nil {
+			e.use |= flagSlotMasks[arch][m[1]]
+		}
+		if m := flagStoreRE.FindStringSubmatch(line); m != nil {
+			e.def |= flagSlotMasks[arch][m[1]]
+		}
+		out[cur] = e
+	}
+	return out
+}
+
+// TestFlagEffectsMatchLowerers checks the liveness tables against what the
+// lowerers emit: every flag an instruction loads must be in its use set, and
+// every flag in its def set must be stored. A following instruction keeps
+// all flags live so nothing is skipped.
+func TestFlagEffectsMatchLowerers(t *testing.T) {
+	tests := []struct {
+		arch    Arch
+		effect  func(Instr) flagEffect
+		readAll string
+	}{
+		{ArchAMD64, amd64FlagEffect, "PUSHFQ"},
+		{ArchARM64, arm64FlagEffect, "CSET EQ, R0\n\tCSET HS, R0\n\tCSET LT, R0"},
+	}
+	for _, tc := range tests {
+		for _, cp := range Capabilities(tc.arch) {
+			for _, form := range cp.Forms {
+				capabilityFormTuples(form, func(tuple []OperandClass) bool {
+					for v := 0; v < capabilitySampleVariants(tc.arch, tuple); v++ {
+						ins := capabilitySampleInstr(tc.arch, string(cp.Op), tuple, v)
+						ir, ok := translateFlagProbe(t, tc.arch, ins, tc.readAll)
+						if !ok {
+							continue
+						}
+						got := flagAccessBySource(tc.arch, ir)[strings.TrimSpace(ins.Raw)]
+						want := tc.effect(ins)
+						if got.use&^want.use != 0 {
+							t.Errorf("%s %q reads flags %#x outside its use set %#x", tc.arch, ins.Raw, got.use, want.use)
+						}
+						if want.def&^got.def != 0 {
+							t.Errorf("%s %q def set %#x but stores only %#x", tc.arch, ins.Raw, want.def, got.def)
+						}
+						break
+					}
+					return true
+				})
+			}
+		}
+	}
+}
+
+func translateFlagProbe(t *testing.T, arch Arch, ins Instr, readAll string) (ir string, ok bool) {
+	t.Helper()
+	file, err := Parse(arch, "TEXT ·probe(SB),0,$0-16\n\t"+readAll+"\n\tRET\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fn := file.Funcs[0]
+	fn.Instrs = append([]Instr{fn.Instrs[0], ins,
+		{Op: OpLABEL, Args: []Operand{{Kind: OpLabel, Sym: "probe_target"}}, Raw: "probe_target:"},
+	}, fn.Instrs[1:]...)
+	sig := FuncSig{
+		Name: "·probe",
+		Args: []LLVMType{I64},
+		Ret:  I64,
+		Frame: FrameLayout{
+			Params:  []FrameSlot{{Offset: 0, Type: I64, Index: 0, Field: -1}},
+			Results: []FrameSlot{{Offset: 8, Type: I64, Index: 0, Field: -1}},
+		},
+	}
+	sigs := map[string]FuncSig{"·probe": sig, "·probe_sym": {Name: "·probe_sym", Ret: Void}}
+	resolve := func(s string) string { return s }
+	cfg := lowerConfig{annotateSource: true}
+	var b strings.Builder
+	switch arch {
+	case ArchAMD64:
+		err = translateFuncAMD64(&b, fn, sig, resolve, sigs, cfg)
+	case ArchARM64:
+		err = translateFuncARM64(&b, fn, sig, resolve, sigs, cfg)
+	}
+	return b.String(), err == nil
+}
+
+func TestFlagLivenessSkipsDeadFlags(t *testing.T) {
+	tests := []struct {
+		name string
+		arch Arch
+		src  string
+		// want maps source lines to the flags their IR must store.
+		want map[string]flagMask
+	}{
+		{
+			name: "amd64 overwritten",
+			arch: ArchAMD64,
+			src: `TEXT ·f(SB),0,$0-16
+	MOVQ a+0(FP), AX
+	ADDQ $1, AX
+	SHLQ $3, AX
+	CMPQ AX, $8
+	JEQ done
+	MOVQ $0, AX
+done:
+	MOVQ AX, ret+8(FP)
+	RET
+`,
+			want: map[string]flagMask{
+				"ADDQ $1, AX":        0,
+				"SHLQ $3, AX":        0,
+				"CMPQ AX, $8":        amd64FlagZF,
+				"MOVQ $0, AX":        0,
+				"RET":                0,
+				"MOVQ AX, ret+8(FP)": 0,
+			},
+		},
+		{
+			name: "amd64 carry live around loop",
+			arch: ArchAMD64,
+			src: `TEXT ·f(SB),0,$0-16
+	MOVQ a+0(FP), AX
+	MOVQ $4, CX
+	CMPQ AX, $3
+loop:
+	DECQ CX
+	JNE loop
+	JCS small
+	MOVQ $0, AX
+small:
+	MOVQ AX, ret+8(FP)
+	RET
+`,
+			want: map[string]flagMask{
+				"CMPQ AX, $3": amd64FlagCF,
+				"DECQ CX":     amd64FlagZF,
+			},
+		},
+		{
+			name: "amd64 rotate by zero",
+			arch: ArchAMD64,
+			src: `TEXT ·f(SB),0,$0-16
+	MOVQ a+0(FP), AX
+	CMPQ AX, $3
+	ROLQ $64, AX
+	RORL $32, AX
+	JCS small
+	ROLQ $1, AX
+small:
+	MOVQ AX, ret+8(FP)
+	RET
+`,
+			want: map[string]flagMask{
+				"CMPQ AX, $3":  amd64FlagCF,
+				"ROLQ $64, AX": 0,
+				"RORL $32, AX": 0,
+				"ROLQ $1, AX":  0,
+			},
+		},
+		{
+			name: "amd64 pushfq keeps all",
+			arch: ArchAMD64,
+			src: `TEXT ·f(SB),0,$0-16
+	MOVQ a+0(FP), AX
+	XORQ $5, AX
+	PUSHFQ
+	POPQ AX
+	MOVQ AX, ret+8(FP)
+	RET
+`,
+			want: map[string]flagMask{
+				"XORQ $5, AX": amd64FlagsAll &^ amd64FlagAF,
+			},
+		},
+		{
+			name: "arm64 overwritten",
+			arch: ArchARM64,
+			src: `TEXT ·f(SB),0,$0-16
+	MOVD a+0(FP), R0
+	ADDS $1, R0, R0
+	CMP $8, R0
+	BLT less
+	MOVD $0, R0
+less:
+	MOVD R0, ret+8(FP)
+	RET
+`,
+			want: map[string]flagMask{
+				"ADDS $1, R0, R0": 0,
+				"CMP $8, R0":      arm64FlagN | arm64FlagV,
+			},
+		},
+		{
+			name: "arm64 carry chain",
+			arch: ArchARM64,
+			src: `TEXT ·f(SB),0,$0-16
+	MOVD a+0(FP), R0
+	ADDS R0, R0, R1
+	ADCS R0, R0, R2
+	ADC ZR, ZR, R3
+	MOVD R3, ret+8(FP)
+	RET
+`,
+			want: map[string]flagMask{
+				"ADDS R0, R0, R1": arm64FlagC,
+				"ADCS R0, R0, R2": arm64FlagC,
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			file, err := Parse(tc.arch, tc.src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sig := FuncSig{
+				Name: "example.f",
+				Args: []LLVMType{I64},
+				Ret:  I64,
+				Frame: FrameLayout{
+					Params:  []FrameSlot{{Offset: 0, Type: I64, Index: 0, Field: -1}},
+					Results: []FrameSlot{{Offset: 8, Type: I64, Index: 0, Field: -1}},
+				},
+			}
+			ir, err := translateIRText(file, Options{
+				Goarch:         string(tc.arch),
+				ResolveSym:     testResolveSym("example"),
+				Sigs:           map[string]FuncSig{"example.f": sig},
+				AnnotateSource: true,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := flagAccessBySource(tc.arch, ir)
+			for src, want := range tc.want {
+				if got[src].def != want {
+					t.Errorf("%q stores flags %#x, want %#x\n%s", src, got[src].def, want, ir)
+				}
+			}
+		})
+	}
+}
+
+// TestFlagLivenessCorpus translates stdlib assembly with and without flag
+// liveness and checks that liveness only ever removes flag stores.
+func TestFlagLivenessCorpus(t *testing.T) {
+	corpus := map[Arch][]string{
+		ArchAMD64: {
+			"internal/bytealg/compare_amd64.s", "internal/bytealg/count_amd64.s",
+			"internal/bytealg/equal_amd64.s", "internal/bytealg/index_amd64.s",
+			"internal/bytealg/indexbyte_amd64.s", "math/big/arith_amd64.s",
+			"hash/crc32/crc32_amd64.s",
+		},
+		ArchARM64: {
+			"internal/bytealg/compare_arm64.s", "internal/bytealg/count_arm64.s",
+			"internal/bytealg/equal_arm64.s", "internal/bytealg/index_arm64.s",
+			"internal/bytealg/indexbyte_arm64.s", "math/big/arith_arm64.s",
+			"hash/crc32/crc32_arm64.s",
+		},
+	}
+	goroot := runtime.GOROOT()
+	for _, arch := range []Arch{ArchAMD64, ArchARM64} {
+		var funcs, with, without int
+		for _, rel := range corpus[arch] {
+			src, err := os.ReadFile(filepath.Join(goroot, "src", filepath.FromSlash(rel)))
+			if err != nil {
+				continue
+			}
+			file, err := Parse(arch, string(src))
+			if err != nil {
+				t.Fatalf("%s: %v", rel, err)
+			}
+			sigs := map[string]FuncSig{}
+			for _, fn := range file.Funcs {
+				sigs[fn.Sym] = InferSignature(fn, arch).Sig
+			}
+			for _, fn := range file.Funcs {
+				on, okOn := translateFlagCorpusFunc(arch, fn, sigs, lowerConfig{})
+				off, okOff := translateFlagCorpusFunc(arch, fn, sigs, lowerConfig{keepDeadFlags: true})
+				if !okOn || !okOff {
+					continue
+				}
+				n, m := len(flagStoreRE.FindAllString(on, -1)), len(flagStoreRE.FindAllString(off, -1))
+				if n > m {
+					t.Errorf("%s %s: %d flag stores with liveness, %d without", rel, fn.Sym, n, m)
+				}
+				funcs++
+				with += n
+				without += m
+			}
+		}
+		if funcs == 0 {
+			t.Skipf("no %s corpus files in %s", arch, goroot)
+		}
+		t.Logf("%s: %d funcs, %d flag stores with liveness, %d without", arch, funcs, with, without)
+		if with >= without {
+			t.Errorf("%s: liveness did not remove any flag stores (%d vs %d)", arch, with, without)
+		}
+	}
+}
+
+func translateFlagCorpusFunc(arch Arch, fn Func, sigs map[string]FuncSig, cfg lowerConfig) (ir string, ok bool) {
+	resolve := func(s string) string { return s }
+	var b strings.Builder
+	var err error
+	switch arch {
+	case ArchAMD64:
+		err = translateFuncAMD64(&b, fn, sigs[fn.Sym], resolve, sigs, cfg)
+	case ArchARM64:
+		err = translateFuncARM64(&b, fn, sigs[fn.Sym], resolve, sigs, cfg)
+	}
+	return b.String(), err == nil
+}
@@ -24,7 +24,9 @@ type GoPackage struct {
 
 // GoModuleOptions configures TranslateGoModule.
 //
-// GOARCH is required and currently accepts only "amd64", "386", and "arm64".
+// GOARCH is required and accepts "amd64", "386", "arm", "arm64",
+// "riscv64", "loong64", "ppc64le", "s390x", "mips", "mipsle", "mips64" and
+// "mips64le".
 // If ResolveSym is nil, the default resolver only strips ABI suffixes.
 type GoModuleOptions struct {
 	FileName       string
@@ -33,6 +35,28 @@ type GoModuleOptions struct {
 	TargetTriple   string
 	AnnotateSource bool
 
+	// GOMIPS selects the floating-point mode on the MIPS dialects, as the
+	// GOMIPS and GOMIPS64 variables do: "hardfloat" (the default) or
+	// "softfloat". Softfloat defines GOMIPS_softfloat (GOMIPS64_softfloat)
+	// for the preprocessor and sets Options.SoftFloat.
+	GOMIPS string
+
+	// GOARM selects the ARM architecture level and floating-point mode, as
+	// the GOARM variable does: "5", "6" or "7" (the default), optionally
+	// followed by ",softfloat" or ",hardfloat"; 5 defaults to softfloat. It
+	// defines GOARM_5 through GOARM_<level> for the preprocessor and sets
+	// Options.Goarm and Options.SoftFloat.
+	GOARM string
+
+	// InlineHelpers sets Options.InlineHelpers.
+	InlineHelpers bool
+
+	// Degraded and FallbackSym set Options.Degraded and Options.FallbackSym:
+	// a function that fails to lower becomes a stub, recorded in
+	// GoModuleTranslation.Report, instead of failing the file.
+	Degraded    bool
+	FallbackSym func(name string) string
+
 	ResolveSym func(sym string) string
 	KeepFunc   func(textSym, resolved string) bool
 	ManualSig  func(resolved string) (FuncSig, bool)
@@ -51,13 +75,17 @@ type GoModuleTranslation struct {
 	Module     llvm.Module
 	Signatures map[string]FuncSig
 	Functions  []GoFunction
+	// Report has one FuncReport per translated TEXT symbol, in file order.
+	Report []FuncReport
 }
 
 // TranslateGoModule binds Go declarations to a Plan 9 asm file and translates
 // the result into an LLVM module in one call.
 //
 // The package must provide go/types information for the declarations referenced
-// by the assembly. Methods and variadic functions are not supported.
+// by the assembly. A method, spelled T.m or (*T).m as in its linker symbol,
+// takes its receiver in the first frame slot, and a variadic parameter is
+// the slice it is passed as.
 func TranslateGoModule(pkg GoPackage, src []byte, opt GoModuleOptions) (*GoModuleTranslation, error) {
 	pkgPath := pkg.Path
 	if pkgPath == "" && pkg.Types != nil {
@@ -90,7 +118,11 @@ func TranslateGoModule(pkg GoPackage, src []byte, opt GoModuleOptions) (*GoModul
 		src = goExpandConsts(src, pkg.Types, pkg.Imports)
 	}
 
-	file, err := Parse(arch, string(src))
+	defines, err := goArchDefines(arch, opt)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", pkgPath, err)
+	}
+	file, err := ParseWithDefines(arch, string(src), defines...)
 	if err != nil {
 		return nil, fmt.Errorf("%s: parse %s: %w", pkgPath, asmName, err)
 	}
@@ -109,12 +141,23 @@ func TranslateGoModule(pkg GoPackage, src []byte, opt GoModuleOptions) (*GoModul
 	if err != nil {
 		return nil, fmt.Errorf("%s: sigs %s: %w", pkgPath, asmName, err)
 	}
-	mod, err := TranslateModule(file, Options{
+	goarm, armSoftFloat := 0, false
+	if arch == ArchARM {
+		// goArchDefines has validated the setting.
+		goarm, armSoftFloat, _ = goParseGOARM(opt.GOARM)
+	}
+	mod, report, err := TranslateModuleWithReport(file, Options{
 		TargetTriple:   opt.TargetTriple,
 		ResolveSym:     resolve,
 		Sigs:           sigs,
 		Goarch:         opt.GOARCH,
+		Goos:           opt.GOOS,
 		AnnotateSource: opt.AnnotateSource,
+		SoftFloat:      opt.GOMIPS == "softfloat" || armSoftFloat,
+		Goarm:          goarm,
+		InlineHelpers:  opt.InlineHelpers,
+		Degraded:       opt.Degraded,
+		FallbackSym:    opt.FallbackSym,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("%s: translate %s: %w", pkgPath, asmName, err)
@@ -126,7 +169,7 @@ func TranslateGoModule(pkg GoPackage, src []byte, opt GoModuleOptions) (*GoModul
 		funcs = append(funcs, GoFunction{TextSymbol: fn.Sym, ResolvedSymbol: resolve(sym)})
 	}
 
-	return &GoModuleTranslation{Module: mod, Signatures: sigs, Functions: funcs}, nil
+	return &GoModuleTranslation{Module: mod, Signatures: sigs, Functions: funcs, Report: report}, nil
 }
 
 var goABISuffixRe = regexp.MustCompile(`<ABI[^>]*>$`)
@@ -140,17 +183,132 @@ func goStripABISuffix(sym string) string {
 
 func goArchFor(goarch string) (Arch, error) {
 	switch goarch {
-	case "amd64", "386":
+	case "amd64":
 		return ArchAMD64, nil
+	case "386":
+		return Arch386, nil
 	case "arm":
 		return ArchARM, nil
 	case "arm64":
 		return ArchARM64, nil
+	case "riscv64":
+		return ArchRISCV64, nil
+	case "loong64":
+		return ArchLOONG64, nil
+	case "ppc64le":
+		return ArchPPC64, nil
+	case "s390x":
+		return ArchS390X, nil
+	case "mips":
+		return ArchMIPS, nil
+	case "mipsle":
+		return ArchMIPSLE, nil
+	case "mips64":
+		return ArchMIPS64, nil
+	case "mips64le":
+		return ArchMIPS64LE, nil
+	case "wasm":
+		return ArchWasm, nil
+	case "ppc64":
+		return "", fmt.Errorf("Plan 9 asm unsupported arch %q (only little-endian ppc64le is supported)", goarch)
 	default:
 		return "", fmt.Errorf("Plan 9 asm unsupported arch %q", goarch)
 	}
 }
 
+// goArchDefines returns the macros the go command defines for opt's
+// architecture variant: GOMIPS_hardfloat or GOMIPS_softfloat (GOMIPS64_*
+// on mips64 and mips64le), and GOARM_5 up to the GOARM level on arm.
+func goArchDefines(arch Arch, opt GoModuleOptions) ([]string, error) {
+	if arch != ArchARM && opt.GOARM != "" {
+		return nil, fmt.Errorf("GOARM set for GOARCH %q", opt.GOARCH)
+	}
+	if arch == ArchARM {
+		level, _, err := goParseGOARM(opt.GOARM)
+		if err != nil {
+			return nil, err
+		}
+		var defines []string
+		for l := 5; l <= level; l++ {
+			defines = append(defines, fmt.Sprintf("GOARM_%d", l))
+		}
+		return defines, nil
+	}
+	if !archIsMIPS(arch) {
+		if opt.GOMIPS != "" {
+			return nil, fmt.Errorf("GOMIPS set for GOARCH %q", opt.GOARCH)
+		}
+		return nil, nil
+	}
+	mode := opt.GOMIPS
+	switch mode {
+	case "":
+		mode = "hardfloat"
+	case "hardfloat", "softfloat":
+	default:
+		return nil, fmt.Errorf("invalid GOMIPS %q (want hardfloat or softfloat)", opt.GOMIPS)
+	}
+	name := "GOMIPS_"
+	if mipsIs64(arch) {
+		name = "GOMIPS64_"
+	}
+	return []string{name + mode}, nil
+}
+
+// goParseGOARM parses a GOARM setting into its level and whether it is
+// softfloat.
+func goParseGOARM(s string) (level int, softFloat bool, err error) {
+	v, mode, _ := strings.Cut(s, ",")
+	switch v {
+	case "", "7":
+		level = 7
+	case "6":
+		level = 6
+	case "5":
+		level = 5
+	default:
+		return 0, false, fmt.Errorf("invalid GOARM %q (want 5, 6 or 7)", s)
+	}
+	switch mode {
+	case "":
+		softFloat = level == 5
+	case "softfloat":
+		softFloat = true
+	case "hardfloat":
+	default:
+		return 0, false, fmt.Errorf("invalid GOARM %q (want softfloat or hardfloat after the comma)", s)
+	}
+	return level, softFloat, nil
+}
+
+// GoDeclSig returns the signature, argument frame included, that the Go
+// declaration of the asm TEXT symbol sym in pkg gives it, named resolved.
+// The declaration is found as TranslateGoModule finds it, through
+// go:linkname directives too. ok is false if pkg declares no such function,
+// as for every file-local helper<>.
+func GoDeclSig(pkg GoPackage, sym, resolved, goarch string) (fs FuncSig, ok bool, err error) {
+	if pkg.Types == nil || pkg.Types.Scope() == nil || strings.HasSuffix(sym, "<>") {
+		return FuncSig{}, false, nil
+	}
+	sz := types.SizesFor("gc", goarch)
+	if sz == nil {
+		return FuncSig{}, false, fmt.Errorf("missing sizes for goarch %q", goarch)
+	}
+	declName, err := goDeclNameForSymbol(goStripABISuffix(sym), goLinknameRemoteToLocal(pkg.Syntax))
+	if err != nil {
+		return FuncSig{}, false, nil
+	}
+	fn, ok := goLookupDecl(pkg.Types.Scope(), declName).(*types.Func)
+	if !ok {
+		return FuncSig{}, false, nil
+	}
+	fs, err = goFuncSigForDeclaredFunc(resolved, fn, goarch, sz, true)
+	if err != nil {
+		return FuncSig{}, false, err
+	}
+	return fs, true, nil
+}
+
 func goSigsForAsmFile(pkg GoPackage, file *File, resolve func(sym string) string, goarch string, manualSig func(string) (FuncSig, bool)) (map[string]FuncSig, error) {
 	sz := types.SizesFor("gc", goarch)
 	if sz == nil {
@@ -197,12 +355,24 @@ func (b *goSigBuilder) addDeclaredFuncSigs(file *File) error {
 			b.sigs[resolved] = ms
 			continue
 		}
+		if b.goarch == "wasm" {
+			// memchr<> and the like have no Go declaration.
+			if ws, ok := wasmABISig(file.Funcs[i].Sym, resolved); ok {
+				b.sigs[resolved] = ws
+				continue
+			}
+		}
 
 		declName, err := goDeclNameForSymbol(sym, b.linknames)
 		if err != nil {
 			return err
 		}
-		obj := b.scope.Lookup(declName)
+		obj := goLookupDecl(b.scope, declName)
+		if obj == nil && strings.HasSuffix(file.Funcs[i].Sym, "<>") {
+			// File-local helpers take a register contract derived at
+			// translation time (see applyRegContracts).
+			continue
+		}
 		if obj == nil {
 			return fmt.Errorf("missing Go declaration for asm symbol %q", sym)
 		}
@@ -220,6 +390,9 @@ func (b *goSigBuilder) addDeclaredFuncSigs(file *File) error {
 }
 
 func (b *goSigBuilder) addReferencedFuncSigs(file *File) error {
+	// Where register contracts are derived, a helper<> without a signature
+	// gets one at translation time; the caller's would hide it.
+	_, contracts := regContractArchFor(file.Arch)
 	for _, fn := range file.Funcs {
 		callerResolved := b.resolve(goStripABISuffix(fn.Sym))
 		callerSig, hasCallerSig := b.sigs[callerResolved]
@@ -235,12 +408,13 @@ func (b *goSigBuilder) addReferencedFuncSigs(file *File) error {
 			if _, ok := b.sigs[targetResolved]; ok {
 				continue
 			}
-			if !tailJump || !hasCallerSig {
+			if !tailJump || !hasCallerSig || contracts {
 				continue
 			}
 			// Best-effort fallback for helper<> tail-jumps where the callee is an
-			// internal asm label with no Go declaration. Callers can override this
-			// via ManualSig when the inferred signature is not identical.
+			// internal asm label with no Go declaration, on architectures that
+			// derive no register contracts. Callers can override this via
+			// ManualSig when the inferred signature is not identical.
 			fs := callerSig
 			fs.Name = targetResolved
 			b.sigs[targetResolved] = fs
@@ -277,7 +451,7 @@ func (b *goSigBuilder) addGoDeclSig(sym string) error {
 		return nil
 	}
 
-	obj := b.scope.Lookup(declName)
+	obj := goLookupDecl(b.scope, declName)
 	if obj == nil {
 		return nil
 	}
@@ -359,16 +533,70 @@ func goDeclNameForSymbol(sym string, linknames map[string]string) (string, error
 	return declName, nil
 }
 
-func goFuncSigForDeclaredFunc(name string, fn *types.Func, goarch string, sz types.Sizes, withFrame bool) (FuncSig, error) {
-	sig := fn.Type().(*types.Signature)
-	if sig.Recv() != nil {
-		return FuncSig{}, fmt.Errorf("methods in asm not supported: %s", fn.FullName())
+// goLookupDecl finds the object an assembly declaration name refers to in a
+// package scope: a package-level object, or for T.m and (*T).m the method m
+// declared with that receiver. It returns nil if there is none.
+func goLookupDecl(scope *types.Scope, declName string) types.Object {
+	typeName, ptr, method, ok := goSplitMethodName(declName)
+	if !ok {
+		return scope.Lookup(declName)
 	}
-	if sig.Variadic() {
-		return FuncSig{}, fmt.Errorf("variadic asm not supported: %s", fn.FullName())
+	tn, ok := scope.Lookup(typeName).(*types.TypeName)
+	if !ok {
+		return nil
+	}
+	named, ok := tn.Type().(*types.Named)
+	if !ok {
+		return nil
+	}
+	for i := 0; i < named.NumMethods(); i++ {
+		m := named.Method(i)
+		if m.Name() != method {
+			continue
+		}
+		_, isPtr := m.Type().(*types.Signature).Recv().Type().(*types.Pointer)
+		if isPtr == ptr {
+			return m
+		}
+	}
+	return nil
+}
+
+// goSplitMethodName splits a method name spelled T.m or (*T).m.
+func goSplitMethodName(name string) (typeName string, ptr bool, method string, ok bool) {
+	dot := strings.LastIndexByte(name, '.')
+	if dot <= 0 || dot == len(name)-1 {
+		return "", false, "", false
 	}
+	recv, method := name[:dot], name[dot+1:]
+	if strings.HasPrefix(recv, "(*") && strings.HasSuffix(recv, ")") {
+		recv, ptr = recv[2:len(recv)-1], true
+	}
+	if recv == "" || strings.ContainsAny(recv, "().*") {
+		return "", false, "", false
+	}
+	return recv, ptr, method, true
+}
+
+// goFuncParams returns the parameters of sig as its argument frame lays them
+// out: the receiver first, and a variadic parameter as its slice type.
+func goFuncParams(sig *types.Signature) *types.Tuple {
+	recv := sig.Recv()
+	if recv == nil {
+		return sig.Params()
+	}
+	vars := []*types.Var{recv}
+	for i := 0; i < sig.Params().Len(); i++ {
+		vars = append(vars, sig.Params().At(i))
+	}
+	return types.NewTuple(vars...)
+}
+
+func goFuncSigForDeclaredFunc(name string, fn *types.Func, goarch string, sz types.Sizes, withFrame bool) (FuncSig, error) {
+	sig := fn.Type().(*types.Signature)
+	params := goFuncParams(sig)
 	if withFrame {
-		args, frameParams, nextOff, err := goLLVMArgsAndFrameSlotsForTuple(sig.Params(), goarch, sz, 0, false)
+		args, frameParams, nextOff, err := goLLVMArgsAndFrameSlotsForTuple(params, goarch, sz, 0, false)
 		if err != nil {
 			return FuncSig{}, fmt.Errorf("%s: %w", fn.FullName(), err)
 		}
@@ -379,7 +607,7 @@ func goFuncSigForDeclaredFunc(name string, fn *types.Func, goarch string, sz typ
 		}
 		return FuncSig{Name: name, Args: args, Ret: goTupleRetType(retTys), Frame: FrameLayout{Params: frameParams, Results: frameResults}}, nil
 	}
-	args, _, _, err := goLLVMArgsAndFrameSlotsForTuple(sig.Params(), goarch, sz, 0, false)
+	args, _, _, err := goLLVMArgsAndFrameSlotsForTuple(params, goarch, sz, 0, false)
 	if err != nil {
 		return FuncSig{}, fmt.Errorf("%s: %w", fn.FullName(), err)
 	}
@@ -527,6 +755,10 @@ func goLLVMTypeForType(t types.Type, goarch string) (LLVMType, error) {
 			return LLVMType("float"), nil
 		case types.Float64:
 			return LLVMType("double"), nil
+		case types.Complex64:
+			return LLVMType("{ float, float }"), nil
+		case types.Complex128:
+			return LLVMType("{ double, double }"), nil
 		case types.String:
 			if goWordSize(goarch) == 8 {
 				return LLVMType("{ ptr, i64 }"), nil
@@ -535,7 +767,8 @@ func goLLVMTypeForType(t types.Type, goarch string) (LLVMType, error) {
 		default:
 			return "", fmt.Errorf("unsupported basic type %s", tt.String())
 		}
-	case *types.Pointer:
+	case *types.Pointer, *types.Signature, *types.Map, *types.Chan:
+		// Func values, maps and channels are single pointers.
 		return Ptr, nil
 	case *types.Slice:
 		if goWordSize(goarch) == 8 {
@@ -546,11 +779,114 @@ func goLLVMTypeForType(t types.Type, goarch string) (LLVMType, error) {
 		return LLVMType("{ ptr, ptr }"), nil
 	case *types.Named:
 		return goLLVMTypeForType(tt.Underlying(), goarch)
+	case *types.Struct, *types.Array:
+		sz := types.SizesFor("gc", goarch)
+		if sz == nil {
+			return "", fmt.Errorf("missing sizes for goarch %q", goarch)
+		}
+		leaves, err := goAppendLeaves(nil, t, 0, goarch, sz)
+		if err != nil {
+			return "", err
+		}
+		return goAggregateType(t, leaves), nil
 	default:
+		// Aliases such as any lay out as their underlying type.
+		if u := t.Underlying(); u != t {
+			return goLLVMTypeForType(u, goarch)
+		}
 		return "", fmt.Errorf("unsupported type %s", t.String())
 	}
 }
 
+// goAggregateType returns the LLVM type of a struct or array with the given
+// scalar leaves. Nested aggregates are flattened, so that a FrameSlot's
+// Field indexes the leaf directly: an array of one scalar type becomes an
+// LLVM array, anything else a literal struct, and a single leaf stands for
+// itself.
+func goAggregateType(t types.Type, leaves []goFramePart) LLVMType {
+	switch len(leaves) {
+	case 0:
+		return "{}"
+	case 1:
+		return leaves[0].Type
+	}
+	parts := make([]string, len(leaves))
+	same := true
+	for i, l := range leaves {
+		parts[i] = string(l.Type)
+		same = same && l.Type == leaves[0].Type
+	}
+	if _, isArray := t.Underlying().(*types.Array); isArray && same {
+		return LLVMType(fmt.Sprintf("[%d x %s]", len(leaves), leaves[0].Type))
+	}
+	return LLVMType("{ " + strings.Join(parts, ", ") + " }")
+}
+
+// goAppendLeaves appends the scalar leaves of t, placed at off, in memory
+// order with the offsets sz gives them. Field is the leaf's index.
+func goAppendLeaves(parts []goFramePart, t types.Type, off int64, goarch string, sz types.Sizes) ([]goFramePart, error) {
+	leaf := func(off int64, ty LLVMType) {
+		parts = append(parts, goFramePart{Offset: off, Type: ty, Field: len(parts)})
+	}
+	word := int64(goWordSize(goarch))
+	wordTy := I64
+	if word == 4 {
+		wordTy = I32
+	}
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		switch u.Kind() {
+		case types.String:
+			leaf(off, Ptr)
+			leaf(off+word, wordTy)
+		case types.Complex64:
+			leaf(off, "float")
+			leaf(off+4, "float")
+		case types.Complex128:
+			leaf(off, "double")
+			leaf(off+8, "double")
+		default:
+			ty, err := goLLVMTypeForType(u, goarch)
+			if err != nil {
+				return nil, err
+			}
+			leaf(off, ty)
+		}
+	case *types.Slice:
+		leaf(off, Ptr)
+		leaf(off+word, wordTy)
+		leaf(off+2*word, wordTy)
+	case *types.Interface:
+		leaf(off, Ptr)
+		leaf(off+word, Ptr)
+	case *types.Pointer, *types.Signature, *types.Map, *types.Chan:
+		leaf(off, Ptr)
+	case *types.Struct:
+		fields := make([]*types.Var, u.NumFields())
+		for i := range fields {
+			fields[i] = u.Field(i)
+		}
+		offs := sz.Offsetsof(fields)
+		var err error
+		for i, f := range fields {
+			if parts, err = goAppendLeaves(parts, f.Type(), off+offs[i], goarch, sz); err != nil {
+				return nil, err
+			}
+		}
+	case *types.Array:
+		stride := sz.Sizeof(u.Elem())
+		var err error
+		for i := int64(0); i < u.Len(); i++ {
+			if parts, err = goAppendLeaves(parts, u.Elem(), off+i*stride, goarch, sz); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unsupported type %s", t.String())
+	}
+	return parts, nil
+}
+
 func goLLVMArgsAndFrameSlotsForTuple(tup *types.Tuple, goarch string, sz types.Sizes, startOff int64, flattenAgg bool) (args []LLVMType, slots []FrameSlot, nextOff int64, err error) {
 	if tup == nil || tup.Len() == 0 {
 		return nil, nil, startOff, nil
@@ -563,7 +899,10 @@ func goLLVMArgsAndFrameSlotsForTuple(tup *types.Tuple, goarch string, sz types.S
 		a := int64(sz.Alignof(t))
 		off = goAlignOff(off, a)
 
-		parts, ok := goFramePartsForType(t, goarch)
+		parts, ok, err := goFramePartsForType(t, goarch, sz)
+		if err != nil {
+			return nil, nil, 0, err
+		}
 		if ok {
 			if flattenAgg {
 				for _, part := range parts {
@@ -604,28 +943,34 @@ type goFramePart struct {
 	Field  int
 }
 
-func goFramePartsForType(t types.Type, goarch string) ([]goFramePart, bool) {
-	word := int64(goWordSize(goarch))
-	wordTy := I64
-	if word == 4 {
-		wordTy = I32
-	}
+// goFramePartsForType returns the frame slots of an aggregate t relative to
+// its start, and false for a scalar. A single-leaf aggregate passes as its
+// leaf, with Field -1.
+func goFramePartsForType(t types.Type, goarch string, sz types.Sizes) ([]goFramePart, bool, error) {
 	switch u := t.Underlying().(type) {
 	case *types.Basic:
-		if u.Kind() == types.String {
-			return []goFramePart{{Offset: 0, Type: Ptr, Field: 0}, {Offset: word, Type: wordTy, Field: 1}}, true
+		switch u.Kind() {
+		case types.String, types.Complex64, types.Complex128:
+		default:
+			return nil, false, nil
 		}
-	case *types.Slice:
-		return []goFramePart{{Offset: 0, Type: Ptr, Field: 0}, {Offset: word, Type: wordTy, Field: 1}, {Offset: 2 * word, Type: wordTy, Field: 2}}, true
-	case *types.Interface:
-		return []goFramePart{{Offset: 0, Type: Ptr, Field: 0}, {Offset: word, Type: Ptr, Field: 1}}, true
+	case *types.Slice, *types.Interface, *types.Struct, *types.Array:
+	default:
+		return nil, false, nil
+	}
+	parts, err := goAppendLeaves(nil, t, 0, goarch, sz)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(parts) == 1 {
+		parts[0].Field = -1
 	}
-	return nil, false
+	return parts, true, nil
 }
 
 func goWordSize(goarch string) int {
 	switch goarch {
-	case "amd64", "arm64":
+	case "amd64", "arm64", "riscv64", "loong64", "ppc64le", "s390x", "mips64", "mips64le", "wasm":
 		return 8
 	default:
 		return 4
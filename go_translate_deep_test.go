@@ -30,8 +30,8 @@ func mustGoPackageWithImports(t *testing.T, pkgPath string, files map[string]str
 }
 
 func TestGoTranslateHelperCoverage(t *testing.T) {
-	if _, err := goArchFor("mips64"); err == nil {
-		t.Fatalf("goArchFor(mips64) unexpectedly succeeded")
+	if _, err := goArchFor("sparc64"); err == nil {
+		t.Fatalf("goArchFor(sparc64) unexpectedly succeeded")
 	}
 
 	for _, tc := range []struct {
@@ -130,8 +130,14 @@ func TestGoTranslateTypeCoverage(t *testing.T) {
 		{types.NewSlice(types.Typ[types.Byte]), "arm64", LLVMType("{ ptr, i64, i64 }"), true},
 		{types.NewInterfaceType(nil, nil), "amd64", LLVMType("{ ptr, ptr }"), true},
 		{named, "amd64", I32, true},
-		{types.Typ[types.Complex64], "amd64", "", false},
-		{types.NewStruct(nil, nil), "amd64", "", false},
+		{types.Typ[types.Complex64], "amd64", LLVMType("{ float, float }"), true},
+		{types.Typ[types.Complex128], "arm", LLVMType("{ double, double }"), true},
+		{types.NewStruct(nil, nil), "amd64", LLVMType("{}"), true},
+		{types.NewArray(types.Typ[types.Uint64], 4), "amd64", LLVMType("[4 x i64]"), true},
+		{types.NewMap(types.Typ[types.String], types.Typ[types.Int]), "amd64", Ptr, true},
+		{types.NewChan(types.SendRecv, types.Typ[types.Int]), "amd64", Ptr, true},
+		{types.NewSignatureType(nil, nil, nil, nil, nil, false), "amd64", Ptr, true},
+		{types.Typ[types.UntypedInt], "amd64", "", false},
 	} {
 		got, err := goLLVMTypeForType(tc.typ, tc.goarch)
 		if (err == nil) != tc.ok || got != tc.want {
@@ -139,16 +145,16 @@ func TestGoTranslateTypeCoverage(t *testing.T) {
 		}
 	}
 
-	if parts, ok := goFramePartsForType(types.Typ[types.String], "amd64"); !ok || len(parts) != 2 || parts[1].Offset != 8 {
+	if parts, ok, _ := goFramePartsForType(types.Typ[types.String], "amd64", types.SizesFor("gc", "amd64")); !ok || len(parts) != 2 || parts[1].Offset != 8 {
 		t.Fatalf("goFramePartsForType(string) = (%v, %v)", parts, ok)
 	}
-	if parts, ok := goFramePartsForType(types.NewSlice(types.Typ[types.Int]), "arm"); !ok || len(parts) != 3 || parts[2].Field != 2 {
+	if parts, ok, _ := goFramePartsForType(types.NewSlice(types.Typ[types.Int]), "arm", types.SizesFor("gc", "arm")); !ok || len(parts) != 3 || parts[2].Field != 2 {
 		t.Fatalf("goFramePartsForType(slice) = (%v, %v)", parts, ok)
 	}
-	if parts, ok := goFramePartsForType(types.NewInterfaceType(nil, nil), "amd64"); !ok || len(parts) != 2 {
+	if parts, ok, _ := goFramePartsForType(types.NewInterfaceType(nil, nil), "amd64", types.SizesFor("gc", "amd64")); !ok || len(parts) != 2 {
 		t.Fatalf("goFramePartsForType(interface) = (%v, %v)", parts, ok)
 	}
-	if _, ok := goFramePartsForType(types.Typ[types.Int], "amd64"); ok {
+	if _, ok, _ := goFramePartsForType(types.Typ[types.Int], "amd64", types.SizesFor("gc", "amd64")); ok {
 		t.Fatalf("goFramePartsForType(int) unexpectedly succeeded")
 	}
 
@@ -177,7 +183,7 @@ func TestGoTranslateTypeCoverage(t *testing.T) {
 
 func TestGoTranslateSignatureCoverage(t *testing.T) {
 	pkg := mustGoPackage(t, "test/pkg", `package testpkg
-type S struct{}
+type S int
 func (S) Method(x int) int { return x }
 func Variadic(x ...int) {}
 func Plain(a string, b []byte, c any, d uintptr) (int, string) { return 0, "" }
@@ -192,12 +198,24 @@ func cmp(a, b int) int { return a }
 		t.Fatalf("goFuncSigForDeclaredFunc(Plain) = (%v, %v)", sig, err)
 	}
 	variadic := scope.Lookup("Variadic").(*types.Func)
-	if _, err := goFuncSigForDeclaredFunc("test/pkg.Variadic", variadic, "amd64", types.SizesFor("gc", "amd64"), false); err == nil {
-		t.Fatalf("goFuncSigForDeclaredFunc(Variadic) unexpectedly succeeded")
+	if sig, err := goFuncSigForDeclaredFunc("test/pkg.Variadic", variadic, "amd64", types.SizesFor("gc", "amd64"), false); err != nil || !sameLLVMTypes(sig.Args, []LLVMType{"{ ptr, i64, i64 }"}) {
+		t.Fatalf("goFuncSigForDeclaredFunc(Variadic) = (%v, %v)", sig, err)
 	}
-	named := scope.Lookup("S").Type().(*types.Named)
-	if _, err := goFuncSigForDeclaredFunc("test/pkg.Method", named.Method(0), "amd64", types.SizesFor("gc", "amd64"), false); err == nil {
-		t.Fatalf("goFuncSigForDeclaredFunc(Method) unexpectedly succeeded")
+	if obj := goLookupDecl(scope, "S.Method"); obj == nil {
+		t.Fatalf("goLookupDecl(S.Method) found nothing")
+	} else if sig, err := goFuncSigForDeclaredFunc("test/pkg.S.Method", obj.(*types.Func), "amd64", types.SizesFor("gc", "amd64"), false); err != nil || len(sig.Args) != 2 {
+		t.Fatalf("goFuncSigForDeclaredFunc(Method) = (%v, %v)", sig, err)
+	}
+	if obj := goLookupDecl(scope, "(*S).Method"); obj != nil {
+		t.Fatalf("goLookupDecl((*S).Method) = %v, want nil for a value receiver", obj)
+	}
+	if sig, ok, err := GoDeclSig(pkg, "runtime·cmp<ABIInternal>", "runtime.cmp", "arm64"); err != nil || !ok || len(sig.Frame.Params) != 2 || len(sig.Frame.Results) != 1 {
+		t.Fatalf("GoDeclSig(linkname) = (%v, %v, %v)", sig, ok, err)
+	}
+	for _, sym := range []string{"helper<>", "·missing", "other·cmp"} {
+		if _, ok, err := GoDeclSig(pkg, sym, "x", "arm64"); ok || err != nil {
+			t.Fatalf("GoDeclSig(%q) = (%v, %v), want no declaration", sym, ok, err)
+		}
 	}
 
 	file := &File{
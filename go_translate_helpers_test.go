@@ -6,6 +6,7 @@ import (
 	"go/parser"
 	"go/token"
 	"go/types"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -44,13 +45,39 @@ func TestGoHelperArchTupleAndSymParsing(t *testing.T) {
 	if got, err := goArchFor("amd64"); err != nil || got != ArchAMD64 {
 		t.Fatalf("goArchFor amd64 = (%q, %v), want %q", got, err, ArchAMD64)
 	}
+	if got, err := goArchFor("386"); err != nil || got != Arch386 {
+		t.Fatalf("goArchFor 386 = (%q, %v), want %q", got, err, Arch386)
+	}
 	if got, err := goArchFor("arm"); err != nil || got != ArchARM {
 		t.Fatalf("goArchFor arm = (%q, %v), want %q", got, err, ArchARM)
 	}
 	if got, err := goArchFor("arm64"); err != nil || got != ArchARM64 {
 		t.Fatalf("goArchFor arm64 = (%q, %v), want %q", got, err, ArchARM64)
 	}
-	if _, err := goArchFor("wasm"); err == nil {
+	if got, err := goArchFor("riscv64"); err != nil || got != ArchRISCV64 {
+		t.Fatalf("goArchFor riscv64 = (%q, %v), want %q", got, err, ArchRISCV64)
+	}
+	if got, err := goArchFor("loong64"); err != nil || got != ArchLOONG64 {
+		t.Fatalf("goArchFor loong64 = (%q, %v), want %q", got, err, ArchLOONG64)
+	}
+	if got, err := goArchFor("ppc64le"); err != nil || got != ArchPPC64 {
+		t.Fatalf("goArchFor ppc64le = (%q, %v), want %q", got, err, ArchPPC64)
+	}
+	if got, err := goArchFor("s390x"); err != nil || got != ArchS390X {
+		t.Fatalf("goArchFor s390x = (%q, %v), want %q", got, err, ArchS390X)
+	}
+	for goarch, want := range map[string]Arch{"mips": ArchMIPS, "mipsle": ArchMIPSLE, "mips64": ArchMIPS64, "mips64le": ArchMIPS64LE} {
+		if got, err := goArchFor(goarch); err != nil || got != want {
+			t.Fatalf("goArchFor %s = (%q, %v), want %q", goarch, got, err, want)
+		}
+	}
+	if _, err := goArchFor("ppc64"); err == nil || !strings.Contains(err.Error(), "little-endian") {
+		t.Fatalf("goArchFor ppc64 error = %v, want little-endian only", err)
+	}
+	if got, err := goArchFor("wasm"); err != nil || got != ArchWasm {
+		t.Fatalf("goArchFor wasm = (%q, %v), want %q", got, err, ArchWasm)
+	}
+	if _, err := goArchFor("sparc64"); err == nil {
 		t.Fatalf("expected unsupported arch error")
 	}
 
@@ -157,7 +184,7 @@ func TestGoLLVMHelpers(t *testing.T) {
 		}
 	}
 
-	if _, err := goLLVMTypeForType(types.NewMap(types.Typ[types.String], types.Typ[types.Int]), "arm64"); err == nil {
+	if _, err := goLLVMTypeForType(types.NewTuple(), "arm64"); err == nil {
 		t.Fatalf("expected unsupported type error")
 	}
 
@@ -200,29 +227,138 @@ func TestGoLLVMHelpers(t *testing.T) {
 	}
 }
 
-func TestGoFuncSigForDeclaredFuncRejectsUnsupportedForms(t *testing.T) {
+func TestGoFuncSigForDeclaredFuncMethodsAndVariadics(t *testing.T) {
 	sz := types.SizesFor("gc", "arm64")
 	recvType := types.NewNamed(types.NewTypeName(token.NoPos, nil, "Receiver", nil), types.NewStruct(nil, nil), nil)
-	recv := types.NewVar(token.NoPos, nil, "r", recvType)
+	recv := types.NewVar(token.NoPos, nil, "r", types.NewPointer(recvType))
 
-	methodSig := types.NewSignature(recv, types.NewTuple(), types.NewTuple(), false)
+	methodSig := types.NewSignature(recv, types.NewTuple(types.NewVar(token.NoPos, nil, "x", types.Typ[types.Int32])), types.NewTuple(types.NewVar(token.NoPos, nil, "", types.Typ[types.Int])), false)
 	method := types.NewFunc(token.NoPos, nil, "Method", methodSig)
-	if _, err := goFuncSigForDeclaredFunc("pkg.Method", method, "arm64", sz, true); err == nil || !strings.Contains(err.Error(), "methods in asm not supported") {
-		t.Fatalf("expected method rejection, got %v", err)
+	sig, err := goFuncSigForDeclaredFunc("pkg.(*Receiver).Method", method, "arm64", sz, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantParams := []FrameSlot{
+		{Offset: 0, Type: Ptr, Index: 0, Field: -1},
+		{Offset: 8, Type: I32, Index: 1, Field: -1},
+	}
+	if !sameLLVMTypes(sig.Args, []LLVMType{Ptr, I32}) || !reflect.DeepEqual(sig.Frame.Params, wantParams) {
+		t.Fatalf("method sig = %+v", sig)
+	}
+	if len(sig.Frame.Results) != 1 || sig.Frame.Results[0].Offset != 16 {
+		t.Fatalf("method results = %+v", sig.Frame.Results)
 	}
 
 	variadicSig := types.NewSignature(
 		nil,
-		types.NewTuple(types.NewVar(token.NoPos, nil, "args", types.NewSlice(types.Typ[types.Int]))),
+		types.NewTuple(
+			types.NewVar(token.NoPos, nil, "sep", types.Typ[types.Byte]),
+			types.NewVar(token.NoPos, nil, "args", types.NewSlice(types.Typ[types.Int])),
+		),
 		types.NewTuple(),
 		true,
 	)
 	variadic := types.NewFunc(token.NoPos, nil, "Variadic", variadicSig)
-	if _, err := goFuncSigForDeclaredFunc("pkg.Variadic", variadic, "arm64", sz, true); err == nil || !strings.Contains(err.Error(), "variadic asm not supported") {
-		t.Fatalf("expected variadic rejection, got %v", err)
+	sig, err = goFuncSigForDeclaredFunc("pkg.Variadic", variadic, "arm64", sz, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sameLLVMTypes(sig.Args, []LLVMType{I8, "{ ptr, i64, i64 }"}) || len(sig.Frame.Params) != 4 || sig.Frame.Params[1].Offset != 8 {
+		t.Fatalf("variadic sig = %+v", sig)
 	}
 }
 
 func addIntConst(pkg *types.Package, name string, v int64) {
 	pkg.Scope().Insert(types.NewConst(token.NoPos, pkg, name, types.Typ[types.Int], constant.MakeInt64(v)))
 }
+
+func TestGoFrameLayoutAggregates(t *testing.T) {
+	pkg := mustGoPackage(t, "test/pkg", `package testpkg
+type Element [4]uint64
+type Point struct {
+	X, Y Element
+	Inf  bool
+}
+type mixed struct {
+	a uint8
+	b struct {
+		c uint16
+		d [2]uint32
+	}
+	e string
+	f complex64
+}
+type wrap struct{ v uint64 }
+func Add(p, q Element) Element
+func Conj(z complex128) complex128
+func Mixed(m mixed, w wrap, f func(), ch chan int, mp map[int]int) (uint8, mixed)
+func Double(p *Point, q Point)
+`)
+	scope := pkg.Types.Scope()
+	sig := func(goarch, name string) FuncSig {
+		t.Helper()
+		fs, err := goFuncSigForDeclaredFunc("test/pkg."+name, scope.Lookup(name).(*types.Func), goarch, types.SizesFor("gc", goarch), true)
+		if err != nil {
+			t.Fatalf("%s/%s: %v", goarch, name, err)
+		}
+		return fs
+	}
+
+	add := sig("amd64", "Add")
+	if !sameLLVMTypes(add.Args, []LLVMType{"[4 x i64]", "[4 x i64]"}) || add.Ret != "{ i64, i64, i64, i64 }" {
+		t.Fatalf("Add = %+v", add)
+	}
+	if s := add.Frame.Params[6]; s != (FrameSlot{Offset: 48, Type: I64, Index: 1, Field: 2}) {
+		t.Fatalf("Add q[2] slot = %+v", s)
+	}
+	if s := add.Frame.Results[3]; s != (FrameSlot{Offset: 88, Type: I64, Index: 3, Field: -1}) {
+		t.Fatalf("Add result[3] slot = %+v", s)
+	}
+
+	conj := sig("386", "Conj")
+	wantConj := []FrameSlot{
+		{Offset: 16, Type: "double", Index: 0, Field: -1},
+		{Offset: 24, Type: "double", Index: 1, Field: -1},
+	}
+	if !sameLLVMTypes(conj.Args, []LLVMType{"{ double, double }"}) || !reflect.DeepEqual(conj.Frame.Results, wantConj) {
+		t.Fatalf("Conj = %+v", conj)
+	}
+
+	for _, tc := range []struct {
+		goarch  string
+		offsets []int64
+	}{
+		// a, c, d[0], d[1], e.ptr, e.len, f.real, f.imag
+		{"amd64", []int64{0, 4, 8, 12, 16, 24, 32, 36}},
+		{"arm", []int64{0, 4, 8, 12, 16, 20, 24, 28}},
+	} {
+		mixed := sig(tc.goarch, "Mixed")
+		word := "i64"
+		if tc.goarch == "arm" {
+			word = "i32"
+		}
+		want := LLVMType("{ i8, i16, i32, i32, ptr, " + word + ", float, float }")
+		if !sameLLVMTypes(mixed.Args, []LLVMType{want, I64, Ptr, Ptr, Ptr}) {
+			t.Fatalf("%s Mixed args = %v", tc.goarch, mixed.Args)
+		}
+		for i, off := range tc.offsets {
+			if s := mixed.Frame.Params[i]; s.Offset != off || s.Index != 0 || s.Field != i {
+				t.Fatalf("%s Mixed m slot %d = %+v, want offset %d", tc.goarch, i, s, off)
+			}
+		}
+		if s := mixed.Frame.Params[len(tc.offsets)]; s.Type != I64 || s.Index != 1 || s.Field != -1 {
+			t.Fatalf("%s Mixed w slot = %+v", tc.goarch, s)
+		}
+		if n := len(mixed.Frame.Results); n != 1+len(tc.offsets) {
+			t.Fatalf("%s Mixed results = %+v", tc.goarch, mixed.Frame.Results)
+		}
+	}
+
+	double := sig("arm64", "Double")
+	if !sameLLVMTypes(double.Args, []LLVMType{Ptr, "{ i64, i64, i64, i64, i64, i64, i64, i64, i1 }"}) {
+		t.Fatalf("Double args = %v", double.Args)
+	}
+	if s := double.Frame.Params[9]; s != (FrameSlot{Offset: 72, Type: I1, Index: 1, Field: 8}) {
+		t.Fatalf("Double q.Inf slot = %+v", s)
+	}
+}
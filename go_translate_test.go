@@ -82,6 +82,167 @@ TEXT indexbytebody<>(SB),NOSPLIT,$0
 	}
 }
 
+func TestTranslateGoModule_DerivesUndeclaredHelperContract(t *testing.T) {
+	pkg := mustGoPackage(t, "test/pkg", `package testpkg
+func Equal(a, b *byte, n int) bool
+`)
+	asm := []byte(`TEXT ·Equal(SB),NOSPLIT,$0-25
+	MOVQ	a+0(FP), SI
+	MOVQ	b+8(FP), DI
+	MOVQ	n+16(FP), BX
+	LEAQ	ret+24(FP), AX
+	JMP	memeqbody<>(SB)
+
+TEXT memeqbody<>(SB),NOSPLIT,$0-0
+loop:
+	TESTQ	BX, BX
+	JEQ	equal
+	MOVBQZX	(SI), CX
+	CMPB	CX, (DI)
+	JNE	notequal
+	INCQ	SI
+	INCQ	DI
+	DECQ	BX
+	JMP	loop
+equal:
+	MOVB	$1, (AX)
+	RET
+notequal:
+	MOVB	$0, (AX)
+	RET
+`)
+
+	tr, err := TranslateGoModule(pkg, asm, GoModuleOptions{
+		FileName:     "equal_amd64.s",
+		GOARCH:       "amd64",
+		TargetTriple: "x86_64-unknown-linux-gnu",
+		ResolveSym:   testResolveSym("test/pkg"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tr.Module.Dispose()
+	if _, ok := tr.Signatures["test/pkg.memeqbody"]; ok {
+		t.Fatalf("memeqbody<> took a Go-side signature: %v", tr.Signatures["test/pkg.memeqbody"])
+	}
+	fn := tr.Module.NamedFunction("test/pkg.memeqbody")
+	if fn.IsNil() {
+		t.Fatalf("missing memeqbody<> in module")
+	}
+	// AX, BX, SI and DI carry the register contract.
+	if got := len(fn.Params()); got != 4 {
+		t.Fatalf("memeqbody<> takes %d params, want 4", got)
+	}
+}
+
+func TestGoSigsForMethodsAndVariadics(t *testing.T) {
+	pkg := mustGoPackage(t, "test/pkg", `package testpkg
+type T int
+func (t *T) Add(x int) int
+func (t T) Get() int
+func Sum(base int, xs ...int) int
+`)
+	file, err := Parse(ArchAMD64, `TEXT ·(*T).Add(SB),NOSPLIT,$24-24
+	MOVQ t+0(FP), AX
+	MOVQ AX, 0(SP)
+	CALL ·T.Get(SB)
+	MOVQ 8(SP), BX
+	ADDQ x+8(FP), BX
+	MOVQ BX, ret+16(FP)
+	RET
+
+TEXT ·T.Get(SB),NOSPLIT,$0-16
+	MOVQ t+0(FP), AX
+	MOVQ AX, ret+8(FP)
+	RET
+
+TEXT ·Sum(SB),NOSPLIT,$0-40
+	MOVQ base+0(FP), AX
+	MOVQ xs_len+16(FP), BX
+	ADDQ BX, AX
+	MOVQ AX, ret+32(FP)
+	RET
+`)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	resolve := testResolveSym("test/pkg")
+	sigs, err := goSigsForAsmFile(pkg, file, resolve, "amd64", nil)
+	if err != nil {
+		t.Fatalf("goSigsForAsmFile: %v", err)
+	}
+	add, ok := sigs["test/pkg.(*T).Add"]
+	if !ok || !sameLLVMTypes(add.Args, []LLVMType{Ptr, I64}) || add.Frame.Params[0].Offset != 0 || add.Frame.Results[0].Offset != 16 {
+		t.Fatalf("(*T).Add signature = %+v, %v", add, ok)
+	}
+	if get, ok := sigs["test/pkg.T.Get"]; !ok || len(get.Args) != 1 || get.Frame.Results[0].Offset != 8 {
+		t.Fatalf("T.Get signature = %+v, %v", get, ok)
+	}
+	if sum, ok := sigs["test/pkg.Sum"]; !ok || !sameLLVMTypes(sum.Args, []LLVMType{I64, "{ ptr, i64, i64 }"}) || sum.Frame.Results[0].Offset != 32 {
+		t.Fatalf("Sum signature = %+v, %v", sum, ok)
+	}
+
+	ll, err := translateIRText(file, Options{
+		TargetTriple: "x86_64-unknown-linux-gnu",
+		Goarch:       "amd64",
+		ResolveSym:   resolve,
+		Sigs:         sigs,
+	})
+	if err != nil {
+		t.Fatalf("translateIRText: %v", err)
+	}
+	wantIR(t, ll,
+		`define i64 @"test/pkg.(*T).Add"(ptr %arg0, i64 %arg1)`,
+		`define i64 @"test/pkg.Sum"(i64 %arg0, { ptr, i64, i64 } %arg1)`,
+		`call i64 @"test/pkg.T.Get"(`,
+	)
+}
+
+func TestGoSigsForAggregateFrames(t *testing.T) {
+	pkg := mustGoPackage(t, "test/pkg", `package testpkg
+type Element [4]uint64
+func Sum4(e Element) uint64
+func Swap(z complex128) complex128
+`)
+	file, err := Parse(ArchAMD64, `TEXT ·Sum4(SB),NOSPLIT,$0-40
+	MOVQ e+0(FP), AX
+	ADDQ e+24(FP), AX
+	MOVQ AX, ret+32(FP)
+	RET
+
+TEXT ·Swap(SB),NOSPLIT,$0-32
+	MOVSD z_real+0(FP), X0
+	MOVSD z_imag+8(FP), X1
+	MOVSD X1, ret_real+16(FP)
+	MOVSD X0, ret_imag+24(FP)
+	RET
+`)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	resolve := testResolveSym("test/pkg")
+	sigs, err := goSigsForAsmFile(pkg, file, resolve, "amd64", nil)
+	if err != nil {
+		t.Fatalf("goSigsForAsmFile: %v", err)
+	}
+	ll, err := translateIRText(file, Options{
+		TargetTriple: "x86_64-unknown-linux-gnu",
+		Goarch:       "amd64",
+		ResolveSym:   resolve,
+		Sigs:         sigs,
+	})
+	if err != nil {
+		t.Fatalf("translateIRText: %v", err)
+	}
+	wantIR(t, ll,
+		`define i64 @"test/pkg.Sum4"([4 x i64] %arg0)`,
+		"extractvalue [4 x i64] %arg0, 3",
+		`define { double, double } @"test/pkg.Swap"({ double, double } %arg0)`,
+		"extractvalue { double, double } %arg0, 1",
+		"ret { double, double }",
+	)
+}
+
 func mustGoPackage(t *testing.T, pkgPath, src string) GoPackage {
 	t.Helper()
 	fset := token.NewFileSet()
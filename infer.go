@@ -0,0 +1,357 @@
+package plan9asm
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SigConfidence grades a signature returned by InferSignature.
+type SigConfidence int
+
+const (
+	// SigGuessed means fn gave nothing to go on: it makes no FP
+	// references, so the signature is a placeholder with no arguments and,
+	// when TEXT declares argument space, one word result.
+	SigGuessed SigConfidence = iota
+	// SigPartial means the frame was read off FP references, but the split
+	// into arguments and results rests on how slots are used, or the TEXT
+	// argument size leaves part of the frame unexplained.
+	SigPartial
+	// SigConsistent means results are named by convention (ret, ret1, ...)
+	// or absent, and the TEXT argument size agrees with the slots.
+	SigConsistent
+)
+
+func (c SigConfidence) String() string {
+	switch c {
+	case SigGuessed:
+		return "guessed"
+	case SigPartial:
+		return "partial"
+	case SigConsistent:
+		return "consistent"
+	}
+	return fmt.Sprintf("SigConfidence(%d)", int(c))
+}
+
+// SigInference is the result of InferSignature.
+type SigInference struct {
+	Sig        FuncSig
+	Confidence SigConfidence
+	// Evidence lists the observations the signature rests on, one per
+	// line, in the order they were made.
+	Evidence []string
+}
+
+// InferSignature derives a signature for fn from its name+off(FP)
+// references, for asm without a Go declaration. A slot's type comes from
+// the size of the widest access to it (MOVL makes an i32 on amd64, MOVH an
+// i16 on arm64), or is a machine word of arch when no access gives a size; a
+// slot that starts inside the one before it is merged into that one. A slot
+// named ret, ret<digits> or ret_<suffix> is a
+// result, and so is every slot after it, as results follow arguments in the
+// frame; without one, the first slot that is only stored to starts the
+// results. The argument size of the TEXT directive ($frame-args) is checked
+// against the slots.
+//
+// Sig.Name is fn.Sym; callers set it to the resolved symbol.
+func InferSignature(fn Func, arch Arch) SigInference {
+	word, size := I64, int64(8)
+	switch arch {
+	case Arch386, ArchARM, ArchMIPS, ArchMIPSLE:
+		word, size = I32, 4
+	}
+	inf := SigInference{Sig: FuncSig{Name: fn.Sym}}
+	evidence := func(format string, args ...any) {
+		inf.Evidence = append(inf.Evidence, fmt.Sprintf(format, args...))
+	}
+
+	type slotUse struct {
+		name          string
+		read, written bool
+		ty            LLVMType // "" until an access gives a size
+		size          int64
+	}
+	uses := map[int64]*slotUse{}
+	for _, ins := range fn.Instrs {
+		op := strings.ToUpper(string(ins.Op))
+		for i, a := range ins.Args {
+			if a.Kind != OpFP && a.Kind != OpFPAddr {
+				continue
+			}
+			u := uses[a.FPOffset]
+			if u == nil {
+				u = &slotUse{name: a.FPName}
+				uses[a.FPOffset] = u
+			}
+			if a.Kind == OpFP {
+				if ty, n, ok := fpAccessType(arch, op); ok && n > u.size {
+					u.ty, u.size = ty, n
+				}
+			}
+			switch {
+			case a.Kind == OpFPAddr || strings.HasPrefix(op, "LEA"):
+				// Taking the address says nothing of the direction.
+			case i == len(ins.Args)-1 && i > 0 && isStoreOp(op):
+				u.written = true
+			default:
+				u.read = true
+			}
+		}
+	}
+	offs := make([]int64, 0, len(uses))
+	for off := range uses {
+		offs = append(offs, off)
+	}
+	sort.Slice(offs, func(i, j int) bool { return offs[i] < offs[j] })
+	argSize, hasArgSize := textArgSize(fn)
+	for _, u := range uses {
+		if u.ty == "" {
+			u.ty, u.size = word, size
+		}
+	}
+
+	if len(offs) == 0 {
+		switch {
+		case hasArgSize && argSize == 0:
+			inf.Sig.Ret = Void
+			inf.Confidence = SigConsistent
+			evidence("no FP references and TEXT argument size 0")
+		case !hasArgSize:
+			inf.Sig.Ret = Void
+			evidence("no FP references and no TEXT argument size")
+		default:
+			inf.Sig.Ret = word
+			evidence("no FP references in %d bytes of arguments; assuming register arguments and a word result", argSize)
+		}
+		return inf
+	}
+
+	inf.Confidence = SigConsistent
+	merged := offs[:1]
+	for _, off := range offs[1:] {
+		prev := merged[len(merged)-1]
+		p, u := uses[prev], uses[off]
+		if off >= prev+p.size {
+			merged = append(merged, off)
+			continue
+		}
+		// Two fields read through one wide access, or one wide field read
+		// in halves: either way the frame has one slot here, and accesses
+		// at off no longer name an argument of their own.
+		p.read = p.read || u.read
+		p.written = p.written || u.written
+		evidence("%s+%d(FP) starts inside the %s at %s+%d(FP) and is merged into it", u.name, off, p.ty, p.name, prev)
+		inf.Confidence = SigPartial
+	}
+	offs = merged
+
+	resultStart, haveResults := int64(0), false
+	for _, off := range offs {
+		if isResultName(uses[off].name) {
+			resultStart, haveResults = off, true
+			evidence("%s+%d(FP) is named as a result", uses[off].name, off)
+			break
+		}
+	}
+	if !haveResults {
+		for _, off := range offs {
+			if u := uses[off]; u.written && !u.read {
+				resultStart, haveResults = off, true
+				inf.Confidence = SigPartial
+				evidence("%s+%d(FP) is only stored to, taken as the first result", u.name, off)
+				break
+			}
+		}
+	}
+
+	var params, results []FrameSlot
+	for _, off := range offs {
+		u := uses[off]
+		if haveResults && off >= resultStart {
+			if u.read && !u.written && !isResultName(u.name) {
+				evidence("%s+%d(FP) follows a result but is only read", u.name, off)
+				inf.Confidence = SigPartial
+			}
+			results = append(results, FrameSlot{Offset: off, Type: u.ty, Index: len(results), Field: -1})
+			continue
+		}
+		params = append(params, FrameSlot{Offset: off, Type: u.ty, Index: len(params), Field: -1})
+		inf.Sig.Args = append(inf.Sig.Args, u.ty)
+	}
+	inf.Sig.Frame = FrameLayout{Params: params, Results: results}
+	evidence("%d argument and %d result slots", len(params), len(results))
+
+	switch len(results) {
+	case 0:
+		inf.Sig.Ret = Void
+	case 1:
+		inf.Sig.Ret = results[0].Type
+	default:
+		parts := make([]string, len(results))
+		for i, r := range results {
+			parts[i] = string(r.Type)
+		}
+		inf.Sig.Ret = LLVMType("{ " + strings.Join(parts, ", ") + " }")
+	}
+
+	last := offs[len(offs)-1]
+	end := last + uses[last].size
+	switch {
+	case !hasArgSize:
+		inf.Confidence = SigPartial
+		evidence("TEXT gives no argument size")
+	case argSize <= last:
+		inf.Confidence = SigPartial
+		evidence("TEXT argument size %d ends before the slot at +%d", argSize, last)
+	case argSize > end:
+		inf.Confidence = SigPartial
+		evidence("TEXT argument size %d leaves bytes %d to %d unreferenced", argSize, end, argSize)
+	case argSize < end:
+		evidence("TEXT argument size %d ends %d bytes into the last slot", argSize, argSize-last)
+	default:
+		evidence("TEXT argument size %d matches the slots", argSize)
+	}
+	return inf
+}
+
+// isResultName reports whether an FP slot name follows the convention for
+// unnamed results: ret, ret<digits> or ret_<anything> (ret1, ret_hi), but
+// not argument names that merely start with ret, such as retry.
+func isResultName(name string) bool {
+	rest, ok := strings.CutPrefix(strings.ToLower(name), "ret")
+	if !ok {
+		return false
+	}
+	if rest == "" || rest[0] == '_' {
+		return true
+	}
+	for _, c := range rest {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// fpAccessType returns the type and size of the frame slot that op reads or
+// writes, from its size suffix: MOVL and ADDL move four bytes on amd64, MOVH
+// and MOVHU two on arm64. ok is false when op gives no size, as for LEAQ or
+// vector moves.
+func fpAccessType(arch Arch, op string) (ty LLVMType, size int64, ok bool) {
+	switch arch {
+	case ArchAMD64, Arch386:
+		return x86FPAccessType(op)
+	}
+	switch op {
+	case "FMOVD":
+		return LLVMType("double"), 8, true
+	case "FMOVS", "MOVF":
+		return LLVMType("float"), 4, true
+	case "MOV":
+		if arch == ArchRISCV64 {
+			return I64, 8, true
+		}
+		return "", 0, false
+	}
+	rest, isMove := strings.CutPrefix(op, "MOV")
+	if !isMove {
+		return "", 0, false
+	}
+	// MOVBU, MOVHZ, MOVBS: the extension does not change the slot's width.
+	if len(rest) == 2 && strings.ContainsRune("USZ", rune(rest[1])) {
+		rest = rest[:1]
+	}
+	switch rest {
+	case "B":
+		return I8, 1, true
+	case "H":
+		return I16, 2, true
+	case "W":
+		return I32, 4, true
+	case "V":
+		return I64, 8, true
+	case "D":
+		switch arch {
+		case ArchARM64, ArchPPC64, ArchS390X:
+			return I64, 8, true
+		}
+		return LLVMType("double"), 8, true
+	}
+	return "", 0, false
+}
+
+// x86SizedOps are the amd64 and 386 mnemonics that take a B, W, L or Q
+// operand size suffix.
+var x86SizedOps = map[string]bool{
+	"MOV": true, "ADD": true, "SUB": true, "CMP": true, "AND": true, "OR": true,
+	"XOR": true, "TEST": true, "ADC": true, "SBB": true, "INC": true, "DEC": true,
+	"NEG": true, "NOT": true, "MUL": true, "IMUL": true, "DIV": true, "IDIV": true,
+	"XCHG": true, "XADD": true, "CMPXCHG": true, "SHL": true, "SHR": true, "SAR": true,
+	"ROL": true, "ROR": true, "BSF": true, "BSR": true, "BT": true, "BTS": true,
+	"BTR": true, "POPCNT": true, "LZCNT": true, "TZCNT": true, "MOVBE": true,
+}
+
+func x86FPAccessType(op string) (LLVMType, int64, bool) {
+	if _, ok := amd64SetccCond(op); ok {
+		return I8, 1, true
+	}
+	switch op {
+	case "MOVSD":
+		return LLVMType("double"), 8, true
+	case "MOVSS":
+		return LLVMType("float"), 4, true
+	}
+	// MOVBLZX, MOVLQSX: the source size is the first letter.
+	if len(op) == 7 && strings.HasPrefix(op, "MOV") && (strings.HasSuffix(op, "ZX") || strings.HasSuffix(op, "SX")) {
+		return x86SizeSuffix(op[3])
+	}
+	if len(op) < 2 || !x86SizedOps[op[:len(op)-1]] {
+		return "", 0, false
+	}
+	return x86SizeSuffix(op[len(op)-1])
+}
+
+func x86SizeSuffix(c byte) (LLVMType, int64, bool) {
+	switch c {
+	case 'B':
+		return I8, 1, true
+	case 'W':
+		return I16, 2, true
+	case 'L':
+		return I32, 4, true
+	case 'Q':
+		return I64, 8, true
+	}
+	return "", 0, false
+}
+
+// isStoreOp reports whether op writes its last operand as a plain move.
+func isStoreOp(op string) bool {
+	return strings.HasPrefix(op, "MOV") || strings.HasPrefix(op, "VMOV") || strings.HasPrefix(op, "FMOV")
+}
+
+// textArgSize returns the argument size from the TEXT directive
+// ("TEXT sym(SB), flags, $frame-args").
+func textArgSize(fn Func) (int64, bool) {
+	if len(fn.Instrs) == 0 || fn.Instrs[0].Op != OpTEXT {
+		return 0, false
+	}
+	raw := fn.Instrs[0].Raw
+	i := strings.LastIndexByte(raw, '$')
+	if i < 0 {
+		return 0, false
+	}
+	// The frame size may be negative, as in $-4-8.
+	_, args, ok := strings.Cut(strings.TrimPrefix(strings.TrimSpace(raw[i+1:]), "-"), "-")
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(strings.TrimSpace(args), 0, 64)
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	return n, true
+}
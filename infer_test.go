@@ -0,0 +1,201 @@
+//go:build !llgo
+// +build !llgo
+
+package plan9asm
+
+import (
+	"strings"
+	"testing"
+)
+
+func inferOne(t *testing.T, arch Arch, src string) SigInference {
+	t.Helper()
+	return InferSignature(mustParse(t, arch, src).Funcs[0], arch)
+}
+
+func wantSlots(t *testing.T, slots []FrameSlot, offs ...int64) {
+	t.Helper()
+	if len(slots) != len(offs) {
+		t.Fatalf("slots %+v, want offsets %v", slots, offs)
+	}
+	for i, s := range slots {
+		if s.Offset != offs[i] || s.Index != i || s.Field != -1 {
+			t.Fatalf("slot %d = %+v, want offset %d", i, s, offs[i])
+		}
+	}
+}
+
+func TestInferSignatureNamedResults(t *testing.T) {
+	inf := inferOne(t, ArchAMD64, `TEXT ·DivMod(SB),NOSPLIT,$0-32
+	MOVQ	a+0(FP), AX
+	MOVQ	b+8(FP), CX
+	XORQ	DX, DX
+	MOVQ	AX, ret+16(FP)
+	MOVQ	DX, ret1+24(FP)
+	RET
+`)
+	if inf.Confidence != SigConsistent {
+		t.Fatalf("confidence %v, evidence %q", inf.Confidence, inf.Evidence)
+	}
+	if inf.Sig.Name != "·DivMod" || len(inf.Sig.Args) != 2 || inf.Sig.Ret != "{ i64, i64 }" {
+		t.Fatalf("sig = %+v", inf.Sig)
+	}
+	wantSlots(t, inf.Sig.Frame.Params, 0, 8)
+	wantSlots(t, inf.Sig.Frame.Results, 16, 24)
+	if !strings.Contains(strings.Join(inf.Evidence, "\n"), "ret+16(FP) is named as a result") {
+		t.Fatalf("evidence %q", inf.Evidence)
+	}
+}
+
+func TestInferSignatureRetPrefixedArgs(t *testing.T) {
+	// retry and retaddr are arguments, not results.
+	inf := inferOne(t, ArchAMD64, `TEXT ·Retry(SB),NOSPLIT,$0-24
+	MOVQ	retry+0(FP), AX
+	MOVQ	retaddr+8(FP), BX
+	ADDQ	BX, AX
+	MOVQ	AX, ret_sum+16(FP)
+	RET
+`)
+	if inf.Confidence != SigConsistent || len(inf.Sig.Args) != 2 || inf.Sig.Ret != I64 {
+		t.Fatalf("sig = %+v, confidence %v, evidence %q", inf.Sig, inf.Confidence, inf.Evidence)
+	}
+	wantSlots(t, inf.Sig.Frame.Params, 0, 8)
+	wantSlots(t, inf.Sig.Frame.Results, 16)
+
+	for name, want := range map[string]bool{
+		"ret": true, "RET": true, "ret1": true, "ret_hi": true,
+		"retry": false, "retaddr": false, "return_pc": false, "ret1x": false, "r": false,
+	} {
+		if got := isResultName(name); got != want {
+			t.Errorf("isResultName(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestInferSignatureStoredResult(t *testing.T) {
+	// n is not named like a result, but it is only stored to; taking its
+	// address does not count as a read.
+	inf := inferOne(t, Arch386, `TEXT ·Add(SB),NOSPLIT,$0-12
+	LEAL	n+8(FP), DX
+	MOVL	x+0(FP), AX
+	ADDL	y+4(FP), AX
+	MOVL	AX, n+8(FP)
+	RET
+`)
+	if inf.Confidence != SigPartial || inf.Sig.Ret != I32 || len(inf.Sig.Args) != 2 || inf.Sig.Args[0] != I32 {
+		t.Fatalf("inference = %+v", inf)
+	}
+	wantSlots(t, inf.Sig.Frame.Results, 8)
+
+	// The bool result ends the frame mid-word.
+	inf = inferOne(t, ArchAMD64, `TEXT ·Eq(SB),NOSPLIT,$0-17
+	MOVQ	a+0(FP), AX
+	CMPQ	AX, b+8(FP)
+	SETEQ	ret+16(FP)
+	RET
+`)
+	if inf.Confidence != SigConsistent || inf.Sig.Ret != I8 {
+		t.Fatalf("inference = %+v", inf)
+	}
+	wantSlots(t, inf.Sig.Frame.Params, 0, 8)
+	wantSlots(t, inf.Sig.Frame.Results, 16)
+}
+
+func TestInferSignatureSubWordSlots(t *testing.T) {
+	inf := inferOne(t, ArchAMD64, `TEXT ·Add32(SB),NOSPLIT,$0-12
+	MOVL	a+0(FP), AX
+	ADDL	b+4(FP), AX
+	MOVL	AX, ret+8(FP)
+	RET
+`)
+	if inf.Confidence != SigConsistent || inf.Sig.Ret != I32 || len(inf.Sig.Args) != 2 ||
+		inf.Sig.Args[0] != I32 || inf.Sig.Args[1] != I32 {
+		t.Fatalf("inference = %+v", inf)
+	}
+	wantSlots(t, inf.Sig.Frame.Params, 0, 4)
+	wantSlots(t, inf.Sig.Frame.Results, 8)
+
+	// Mixed widths, with zero extension and a byte-sized result.
+	inf = inferOne(t, ArchARM64, `TEXT ·Pick(SB),NOSPLIT,$0-17
+	MOVHU	h+0(FP), R0
+	MOVBU	b+2(FP), R1
+	MOVW	w+4(FP), R2
+	MOVD	p+8(FP), R3
+	MOVB	R0, ret+16(FP)
+	RET
+`)
+	want := []LLVMType{I16, I8, I32, I64}
+	if inf.Confidence != SigConsistent || inf.Sig.Ret != I8 || len(inf.Sig.Args) != len(want) {
+		t.Fatalf("inference = %+v", inf)
+	}
+	for i, ty := range want {
+		if inf.Sig.Args[i] != ty {
+			t.Fatalf("arg %d is %s, want %s", i, inf.Sig.Args[i], ty)
+		}
+	}
+	wantSlots(t, inf.Sig.Frame.Params, 0, 2, 4, 8)
+
+	// The high half of x is read on its own; it is part of x, not a
+	// second argument.
+	inf = inferOne(t, ArchAMD64, `TEXT ·Hi(SB),NOSPLIT,$0-12
+	MOVQ	x+0(FP), AX
+	MOVL	x_hi+4(FP), BX
+	MOVL	BX, ret+8(FP)
+	RET
+`)
+	if inf.Confidence != SigPartial || len(inf.Sig.Args) != 1 || inf.Sig.Args[0] != I64 || inf.Sig.Ret != I32 {
+		t.Fatalf("inference = %+v", inf)
+	}
+	wantSlots(t, inf.Sig.Frame.Params, 0)
+	if !strings.Contains(strings.Join(inf.Evidence, "\n"), "x_hi+4(FP) starts inside the i64 at x+0(FP)") {
+		t.Fatalf("evidence %q", inf.Evidence)
+	}
+}
+
+func TestInferSignatureArgSize(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		src  string
+		conf SigConfidence
+		ret  LLVMType
+		args int
+	}{
+		{"no frame use", "TEXT ·Pause(SB),NOSPLIT,$0-0\n\tPAUSE\n\tRET\n", SigConsistent, Void, 0},
+		{"no arg size or frame use", "TEXT ·stub(SB),NOSPLIT|NOFRAME,$0\n\tRET\n", SigGuessed, Void, 0},
+		{"register abi", "TEXT ·f<ABIInternal>(SB),NOSPLIT,$0-8\n\tRET\n", SigGuessed, I64, 0},
+		{"unused slot", "TEXT ·Store(SB),NOSPLIT,$0-24\n\tMOVQ\tp+0(FP), AX\n\tMOVQ\t$0, (AX)\n\tRET\n", SigPartial, Void, 1},
+		{"no arg size", "TEXT ·Load(SB),NOSPLIT,$0\n\tMOVQ\tp+0(FP), AX\n\tMOVQ\t(AX), AX\n\tMOVQ\tAX, ret+8(FP)\n\tRET\n", SigPartial, I64, 1},
+		{"negative frame", "TEXT ·Load(SB),NOSPLIT,$-8-16\n\tMOVQ\tp+0(FP), AX\n\tMOVQ\tAX, ret+8(FP)\n\tRET\n", SigConsistent, I64, 1},
+	} {
+		inf := inferOne(t, ArchAMD64, tc.src)
+		if inf.Confidence != tc.conf || inf.Sig.Ret != tc.ret || len(inf.Sig.Args) != tc.args {
+			t.Errorf("%s: got %v %s %d args (evidence %q), want %v %s %d", tc.name,
+				inf.Confidence, inf.Sig.Ret, len(inf.Sig.Args), inf.Evidence, tc.conf, tc.ret, tc.args)
+		}
+		if len(inf.Evidence) == 0 {
+			t.Errorf("%s: no evidence", tc.name)
+		}
+	}
+}
+
+func TestInferSignatureTranslates(t *testing.T) {
+	file := mustParse(t, ArchARM64, `TEXT ·Sub(SB),NOSPLIT,$0-24
+	MOVD	a+0(FP), R0
+	MOVD	b+8(FP), R1
+	SUB	R1, R0, R0
+	MOVD	R0, ret+16(FP)
+	RET
+`)
+	sig := InferSignature(file.Funcs[0], ArchARM64).Sig
+	sig.Name = "example.Sub"
+	ll, err := translateIRText(file, Options{
+		ResolveSym: testResolveSym("example"),
+		Sigs:       map[string]FuncSig{sig.Name: sig},
+	})
+	if err != nil {
+		t.Fatalf("translate: %v", err)
+	}
+	if !strings.Contains(ll, `define i64 @"example.Sub"(i64 %arg0, i64 %arg1)`) {
+		t.Fatalf("unexpected IR:\n%s", ll)
+	}
+}
@@ -0,0 +1,299 @@
+package plan9asm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Helper inlining (Options.InlineHelpers).
+//
+// A CALL to a file-local helper<> is replaced by a copy of the helper's
+// body: its labels are renamed apart and each RET becomes a jump to a label
+// after the call site. The copy is lowered as part of the caller, so it
+// works on the caller's register and flag slots and the helper needs no
+// signature.
+//
+// Only helpers that behave the same in the caller's frame are inlined:
+// no FP or SP references, no local frame, and no way out but RET (no tail
+// or indirect jumps). A helper that calls itself, directly or through other
+// helpers, keeps the calls that would recurse. A call site is also kept
+// when an n(PC) branch of the caller spans it, as the splice would move the
+// branch target.
+
+// inlineHelpers returns file with helper calls inlined. inlined holds the
+// resolved names of the helpers that are no longer referenced after that;
+// they stay in out.Funcs but are not emitted.
+func inlineHelpers(file *File, resolve func(string) string) (out *File, inlined map[string]bool) {
+	if file.Arch == ArchWasm {
+		return file, nil
+	}
+	in := &helperInliner{
+		helpers:   map[string]Func{},
+		bodies:    map[string][]Instr{},
+		busy:      map[string]bool{},
+		recursive: map[string]bool{},
+		spliced:   map[string]bool{},
+	}
+	for _, fn := range file.Funcs {
+		if inlinableHelper(fn) {
+			in.helpers[fn.Sym] = fn
+		}
+	}
+	if len(in.helpers) == 0 {
+		return file, nil
+	}
+
+	funcs := make([]Func, len(file.Funcs))
+	changed := false
+	for i, fn := range file.Funcs {
+		instrs, ok := in.expand(fn.Instrs)
+		funcs[i] = Func{Sym: fn.Sym, Instrs: instrs}
+		changed = changed || ok
+	}
+	if !changed {
+		return file, nil
+	}
+	out = &File{Arch: file.Arch, Funcs: funcs, Data: file.Data, Globl: file.Globl}
+
+	// Drop inlined helpers until the rest no longer mention them.
+	dropped := map[string]bool{}
+	for again := true; again; {
+		again = false
+		for _, h := range funcs {
+			if !in.spliced[h.Sym] || dropped[h.Sym] {
+				continue
+			}
+			used := false
+			for _, fn := range funcs {
+				if fn.Sym != h.Sym && !dropped[fn.Sym] && mentionsSym(fn, h.Sym) {
+					used = true
+					break
+				}
+			}
+			if !used {
+				dropped[h.Sym], again = true, true
+			}
+		}
+	}
+	inlined = map[string]bool{}
+	for sym := range dropped {
+		inlined[resolve(sym)] = true
+	}
+	return out, inlined
+}
+
+type helperInliner struct {
+	helpers   map[string]Func    // inlinable helpers by TEXT symbol
+	bodies    map[string][]Instr // expanded helper bodies
+	busy      map[string]bool    // helpers being expanded
+	recursive map[string]bool
+	spliced   map[string]bool // helpers inlined at least once
+}
+
+// body returns the helper sym with its own helper calls inlined, or false
+// if sym is not inlinable or reached itself.
+func (in *helperInliner) body(sym string) ([]Instr, bool) {
+	if b, ok := in.bodies[sym]; ok {
+		return b, true
+	}
+	fn, ok := in.helpers[sym]
+	if !ok || in.recursive[sym] {
+		return nil, false
+	}
+	if in.busy[sym] {
+		in.recursive[sym] = true
+		return nil, false
+	}
+	in.busy[sym] = true
+	b, _ := in.expand(fn.Instrs)
+	delete(in.busy, sym)
+	if in.recursive[sym] {
+		return nil, false
+	}
+	in.bodies[sym] = b
+	return b, true
+}
+
+// expand inlines the helper calls in instrs and reports whether there were
+// any.
+func (in *helperInliner) expand(instrs []Instr) ([]Instr, bool) {
+	labels := inlineLabels(instrs)
+	out := make([]Instr, 0, len(instrs))
+	n, changed := 0, false
+	for i, ins := range instrs {
+		base, tail, ok := goReferencedFunc(Instr{Op: Op(strings.ToUpper(string(ins.Op))), Args: ins.Args})
+		if !ok || tail || pcRelSpans(instrs, i) {
+			out = append(out, ins)
+			continue
+		}
+		body, ok := in.body(base)
+		if !ok {
+			out = append(out, ins)
+			continue
+		}
+		var site string
+		for {
+			n++
+			site = fmt.Sprintf("inline%d", n)
+			if !hasLabelPrefix(labels, site) {
+				break
+			}
+		}
+		out = append(out, spliceHelper(body, site)...)
+		in.spliced[base] = true
+		changed = true
+	}
+	return out, changed
+}
+
+// spliceHelper copies body for the call site named site, prefixing its
+// labels with the site and turning each RET into a jump to the site label
+// after the copy.
+func spliceHelper(body []Instr, site string) []Instr {
+	labels := inlineLabels(body)
+	prefix, cont := site+"_", site
+	out := make([]Instr, 0, len(body)+1)
+	for _, ins := range body {
+		switch {
+		case ins.Op == OpTEXT:
+			continue
+		case ins.Op == OpLABEL && len(ins.Args) == 1 && ins.Args[0].Kind == OpLabel:
+			name := prefix + ins.Args[0].Sym
+			ins = Instr{Op: OpLABEL, Args: []Operand{{Kind: OpLabel, Sym: name}}, Raw: name + ":"}
+		case strings.EqualFold(string(ins.Op), string(OpRET)):
+			ins = Instr{Op: "JMP", Args: []Operand{{Kind: OpIdent, Ident: cont}}, Raw: "JMP\t" + cont}
+		default:
+			var args []Operand
+			for j, a := range ins.Args {
+				if name, ok := labelRef(a); ok && labels[name] {
+					if args == nil {
+						args = append([]Operand(nil), ins.Args...)
+					}
+					args[j] = Operand{Kind: OpIdent, Ident: prefix + name}
+				}
+			}
+			if args != nil {
+				ins.Args = args
+			}
+		}
+		out = append(out, ins)
+	}
+	return append(out, Instr{Op: OpLABEL, Args: []Operand{{Kind: OpLabel, Sym: cont}}, Raw: cont + ":"})
+}
+
+// inlinableHelper reports whether fn is a file-local helper that can run in
+// its caller's frame.
+func inlinableHelper(fn Func) bool {
+	if !strings.HasSuffix(fn.Sym, "<>") || textFrameSize(fn) != 0 ||
+		regContractUsesFrame(fn) || regContractFallsThrough(fn) {
+		return false
+	}
+	labels := inlineLabels(fn.Instrs)
+	for _, ins := range fn.Instrs {
+		for _, a := range ins.Args {
+			for _, r := range regOperands(a) {
+				if strings.EqualFold(string(r), string(SP)) {
+					return false
+				}
+			}
+		}
+		op := strings.ToUpper(string(ins.Op))
+		switch op {
+		case "JMP", "B", "BR", "JR", "BCTR", "BX", "JALR":
+		default:
+			continue
+		}
+		if len(ins.Args) != 1 {
+			return false
+		}
+		if name, ok := labelRef(ins.Args[0]); ok && labels[name] {
+			continue
+		}
+		if isPCRel(ins.Args[0]) && (op == "JMP" || op == "B") {
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+// labelRef returns the label an operand may name.
+func labelRef(a Operand) (string, bool) {
+	switch a.Kind {
+	case OpIdent:
+		return a.Ident, true
+	case OpReg:
+		return string(a.Reg), true
+	case OpSym:
+		// Local labels sometimes appear with "<>" or (SB) suffix.
+		return strings.TrimSuffix(strings.TrimSuffix(strings.TrimSpace(a.Sym), "(SB)"), "<>"), true
+	}
+	return "", false
+}
+
+func inlineLabels(instrs []Instr) map[string]bool {
+	labels := map[string]bool{}
+	for _, ins := range instrs {
+		if ins.Op == OpLABEL && len(ins.Args) == 1 && ins.Args[0].Kind == OpLabel {
+			labels[ins.Args[0].Sym] = true
+		}
+	}
+	return labels
+}
+
+func hasLabelPrefix(labels map[string]bool, prefix string) bool {
+	for l := range labels {
+		if strings.HasPrefix(l, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func isPCRel(a Operand) bool {
+	return a.Kind == OpMem && strings.EqualFold(string(a.Mem.Base), string(PC))
+}
+
+// pcRelSpans reports whether an n(PC) branch in instrs jumps across, or
+// to, the instruction at index at.
+func pcRelSpans(instrs []Instr, at int) bool {
+	for i, ins := range instrs {
+		for _, a := range ins.Args {
+			if !isPCRel(a) || i == at {
+				continue
+			}
+			lo, hi := i, i+int(a.Mem.Off)
+			if lo > hi {
+				lo, hi = hi, lo
+			}
+			if lo <= at && at <= hi {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// mentionsSym reports whether an instruction of fn refers to sym.
+func mentionsSym(fn Func, sym string) bool {
+	for _, ins := range fn.Instrs {
+		if ins.Op == OpTEXT {
+			continue
+		}
+		for s := ins.Raw; ; {
+			i := strings.Index(s, sym)
+			if i < 0 {
+				break
+			}
+			if i == 0 || !isSymByte(s[i-1]) {
+				return true
+			}
+			s = s[i+len(sym):]
+		}
+	}
+	return false
+}
+
+func isSymByte(c byte) bool {
+	return c == '_' || c == '.' || c >= 0x80 || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
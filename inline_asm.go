@@ -0,0 +1,225 @@
+package plan9asm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// inlineAsmCall builds an `asm sideeffect` call that passes one instruction
+// through to the target assembler (Options.InlineAsm).
+//
+// Register operands become read-write outputs tied to inputs loaded from the
+// current register slots, since the translator does not know which operands
+// an unknown instruction writes. Addresses become plain inputs.
+type inlineAsmCall struct {
+	tmpl    []inlineAsmPart
+	outs    []inlineAsmOperand
+	ins     []inlineAsmOperand
+	flags   []inlineAsmFlag
+	regOut  map[Reg]int
+	clobber string
+}
+
+// inlineAsmFlag is a condition flag the instruction writes, returned through
+// a flag output constraint such as {@ccz}.
+type inlineAsmFlag struct {
+	constraint string
+	store      func(v string)
+}
+
+type inlineAsmOperand struct {
+	reg        Reg
+	ty         string
+	constraint string
+	val        string
+}
+
+// inlineAsmPart is a literal template fragment, or a reference to output
+// (out >= 0) or input (in >= 0) operand number with an optional modifier.
+type inlineAsmPart struct {
+	lit      string
+	out, in  int
+	modifier string
+}
+
+// inlineAsmOp is the assembler spelling of a Plan 9 opcode that may be passed
+// through. Plan 9 names are not assembler mnemonics in general (DIVHW is
+// sdiv, MULS is mls), so each backend lists only the opcodes whose spelling
+// and operand order have been checked against the Go assembler's encoding.
+type inlineAsmOp struct {
+	mnemonic string
+	// gpr is the template modifier selecting the general-purpose register
+	// width, e.g. "w" for arm64 W registers or "k" for amd64 32-bit ones.
+	gpr string
+	// order gives, for each assembler operand, the index of the Plan 9
+	// operand it comes from.
+	order []int
+}
+
+// Operand orders shared by the passthrough tables.
+var (
+	inlineAsmSame1     = []int{0}
+	inlineAsmSame2     = []int{0, 1}
+	inlineAsmSame3     = []int{0, 1, 2}
+	inlineAsmSame4     = []int{0, 1, 2, 3}
+	inlineAsmReversed2 = []int{1, 0}
+	inlineAsmReversed3 = []int{2, 1, 0}
+)
+
+// operands returns ins's operands in assembler order. With shorthand set, a
+// two-operand "OP Rm, Rd" of a three-operand opcode stands for
+// "OP Rm, Rd, Rd", as on arm and arm64.
+func (op inlineAsmOp) operands(ins Instr, shorthand bool) ([]Operand, error) {
+	args := ins.Args
+	if shorthand && len(op.order) == 3 && len(args) == 2 {
+		args = []Operand{args[0], args[1], args[1]}
+	}
+	if len(args) != len(op.order) {
+		return nil, fmt.Errorf("inline asm: %s takes %d operands: %q", ins.Op, len(op.order), ins.Raw)
+	}
+	out := make([]Operand, len(op.order))
+	for i, j := range op.order {
+		out[i] = args[j]
+	}
+	return out, nil
+}
+
+// llvmStringLit quotes s as an LLVM IR string literal, which escapes bytes
+// as \XX rather than Go's \n.
+func llvmStringLit(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c < 0x20 || c >= 0x7f || c == '"' || c == '\\' {
+			fmt.Fprintf(&b, "\\%02X", c)
+			continue
+		}
+		b.WriteByte(c)
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+func newInlineAsmCall(clobber string) *inlineAsmCall {
+	return &inlineAsmCall{regOut: map[Reg]int{}, clobber: clobber}
+}
+
+func (a *inlineAsmCall) text(s string) {
+	a.tmpl = append(a.tmpl, inlineAsmPart{lit: s, out: -1, in: -1})
+}
+
+// reg references register r, loading its current value with load on first
+// use. Repeated references share one operand.
+func (a *inlineAsmCall) reg(r Reg, ty, constraint, modifier string, load func() (string, error)) error {
+	idx, ok := a.regOut[r]
+	if !ok {
+		v, err := load()
+		if err != nil {
+			return err
+		}
+		idx = len(a.outs)
+		a.regOut[r] = idx
+		a.outs = append(a.outs, inlineAsmOperand{reg: r, ty: ty, constraint: constraint, val: v})
+	}
+	a.tmpl = append(a.tmpl, inlineAsmPart{out: idx, in: -1, modifier: modifier})
+	return nil
+}
+
+// input references a read-only value such as a computed address.
+func (a *inlineAsmCall) input(ty, constraint, val string) {
+	a.tmpl = append(a.tmpl, inlineAsmPart{out: -1, in: len(a.ins)})
+	a.ins = append(a.ins, inlineAsmOperand{ty: ty, constraint: constraint, val: val})
+}
+
+// flag returns the condition flag named by constraint from the asm as an
+// extra i8 output, and hands it to store as an i1 after the call.
+func (a *inlineAsmCall) flag(constraint string, store func(v string)) {
+	a.flags = append(a.flags, inlineAsmFlag{constraint: constraint, store: store})
+}
+
+// emit writes the call and stores every register output back with store.
+func (a *inlineAsmCall) emit(b *strings.Builder, newTmp func() string, store func(r Reg, v string) error) error {
+	var tmpl strings.Builder
+	for _, p := range a.tmpl {
+		n := -1
+		switch {
+		case p.out >= 0:
+			n = p.out
+		case p.in >= 0:
+			// Tied copies of the register outputs come first among the
+			// inputs, after the flag outputs.
+			n = 2*len(a.outs) + len(a.flags) + p.in
+		default:
+			tmpl.WriteString(strings.ReplaceAll(p.lit, "$", "$$"))
+			continue
+		}
+		if p.modifier != "" {
+			fmt.Fprintf(&tmpl, "${%d:%s}", n, p.modifier)
+		} else {
+			fmt.Fprintf(&tmpl, "$%d", n)
+		}
+	}
+
+	var cons, args []string
+	for _, o := range a.outs {
+		cons = append(cons, "="+o.constraint)
+	}
+	for _, f := range a.flags {
+		cons = append(cons, "="+f.constraint)
+	}
+	for i, o := range a.outs {
+		cons = append(cons, fmt.Sprint(i))
+		args = append(args, o.ty+" "+o.val)
+	}
+	for _, in := range a.ins {
+		cons = append(cons, in.constraint)
+		args = append(args, in.ty+" "+in.val)
+	}
+	if a.clobber != "" {
+		cons = append(cons, a.clobber)
+	}
+
+	tys := make([]string, 0, len(a.outs)+len(a.flags))
+	for _, o := range a.outs {
+		tys = append(tys, o.ty)
+	}
+	for range a.flags {
+		tys = append(tys, "i8")
+	}
+	retTy := "void"
+	switch len(tys) {
+	case 0:
+	case 1:
+		retTy = tys[0]
+	default:
+		retTy = "{ " + strings.Join(tys, ", ") + " }"
+	}
+	call := fmt.Sprintf("call %s asm sideeffect %q, %q(%s)", retTy, tmpl.String(), strings.Join(cons, ","), strings.Join(args, ", "))
+	if len(tys) == 0 {
+		fmt.Fprintf(b, "  %s\n", call)
+		return nil
+	}
+	res := newTmp()
+	fmt.Fprintf(b, "  %%%s = %s\n", res, call)
+	result := func(i int) string {
+		if len(tys) == 1 {
+			return "%" + res
+		}
+		v := newTmp()
+		fmt.Fprintf(b, "  %%%s = extractvalue %s %%%s, %d\n", v, retTy, res, i)
+		return "%" + v
+	}
+	for i, o := range a.outs {
+		if err := store(o.reg, result(i)); err != nil {
+			return err
+		}
+	}
+	for i, f := range a.flags {
+		v := result(len(a.outs) + i)
+		t := newTmp()
+		fmt.Fprintf(b, "  %%%s = trunc i8 %s to i1\n", t, v)
+		f.store("%" + t)
+	}
+	return nil
+}
@@ -0,0 +1,363 @@
+//go:build !llgo
+// +build !llgo
+
+package plan9asm
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInlineAsmPassthrough(t *testing.T) {
+	tests := []struct {
+		name   string
+		arch   Arch
+		goarch string
+		triple string
+		word   LLVMType
+		src    string
+		want   []string
+	}{
+		{
+			name:   "amd64 gpr",
+			arch:   ArchAMD64,
+			goarch: "amd64",
+			triple: "x86_64-unknown-linux-gnu",
+			word:   I64,
+			src: `TEXT ·f(SB),0,$0-16
+	MOVQ a+0(FP), BX
+	MOVQ $0xff, CX
+	PDEPQ BX, CX, DX
+	MOVQ DX, ret+8(FP)
+	RET
+`,
+			want: []string{
+				`call { i64, i64, i64 } asm sideeffect "pdepq $0, $1, $2", "=r,=r,=r,0,1,2,~{memory},~{dirflag},~{fpsr},~{flags}"(i64 `,
+				"store i64 %",
+			},
+		},
+		{
+			name:   "amd64 vector imm and mem",
+			arch:   ArchAMD64,
+			goarch: "amd64",
+			triple: "x86_64-unknown-linux-gnu",
+			word:   I64,
+			src: `TEXT ·f(SB),0,$0-16
+	MOVQ a+0(FP), SI
+	VPTERNLOGD $0x96, 16(SI), Y2, Y2
+	MOVQ SI, ret+8(FP)
+	RET
+`,
+			want: []string{
+				`asm sideeffect "vpternlogd $$150, ($2), $0, $0", "=x,0,r,~{memory},~{dirflag},~{fpsr},~{flags}"(<32 x i8> `,
+			},
+		},
+		{
+			name:   "amd64 flags",
+			arch:   ArchAMD64,
+			goarch: "amd64",
+			triple: "x86_64-unknown-linux-gnu",
+			word:   I64,
+			src: `TEXT ·f(SB),0,$0-16
+	MOVQ a+0(FP), BX
+	TZCNTL BX, CX
+	JCC nonzero
+	MOVQ $-1, CX
+nonzero:
+	MOVQ CX, ret+8(FP)
+	RET
+`,
+			want: []string{
+				// Only CF is read afterwards.
+				`call { i64, i64, i8 } asm sideeffect "tzcntl ${0:k}, ${1:k}", "=r,=r,={@ccc},0,1,~{memory},~{dirflag},~{fpsr},~{flags}"(i64 `,
+				"store i1 %",
+			},
+		},
+		{
+			name:   "arm64 reversed operands",
+			arch:   ArchARM64,
+			triple: "aarch64-unknown-linux-gnu",
+			word:   I64,
+			src: `TEXT ·f(SB),0,$0-16
+	MOVD a+0(FP), R1
+	MOVD $3, R2
+	SDIV R2, R1, R3
+	MOVD R3, ret+8(FP)
+	RET
+`,
+			want: []string{
+				`asm sideeffect "sdiv ${0:x}, ${1:x}, ${2:x}", "=r,=r,=r,0,1,2,~{memory},~{cc}"(i64 `,
+			},
+		},
+		{
+			name:   "arm64 four operands",
+			arch:   ArchARM64,
+			triple: "aarch64-unknown-linux-gnu",
+			word:   I64,
+			src: `TEXT ·f(SB),0,$0-16
+	MOVD a+0(FP), R1
+	MOVD $3, R2
+	MADDW R1, R2, R2, R3
+	MOVD R3, ret+8(FP)
+	RET
+`,
+			want: []string{
+				// MADDW Rm, Ra, Rn, Rd is madd wd, wn, wm, wa.
+				`asm sideeffect "madd ${0:w}, ${1:w}, ${2:w}, ${1:w}", "=r,=r,=r,0,1,2,~{memory},~{cc}"(i64 `,
+			},
+		},
+		{
+			name:   "arm64 vector arrangement",
+			arch:   ArchARM64,
+			triple: "aarch64-unknown-linux-gnu",
+			word:   I64,
+			src: `TEXT ·f(SB),0,$0-16
+	VUMAX V1.B16, V2.B16, V3.B16
+	MOVD $0, R0
+	MOVD R0, ret+8(FP)
+	RET
+`,
+			want: []string{
+				`asm sideeffect "umax $0.16b, $1.16b, $2.16b", "=w,=w,=w,0,1,2,~{memory},~{cc}"(<16 x i8> `,
+			},
+		},
+		{
+			name:   "arm",
+			arch:   ArchARM,
+			triple: "armv7-unknown-linux-gnueabihf",
+			word:   I32,
+			src: `TEXT ·f(SB),0,$0-8
+	MOVW a+0(FP), R1
+	MOVW $3, R2
+	MULS R1, R2, R1, R3
+	MOVW R3, ret+4(FP)
+	RET
+`,
+			want: []string{
+				// MULS Rm, Rn, Ra, Rd is mls rd, rn, rm, ra.
+				`asm sideeffect "mls $0, $1, $2, $2", "=r,=r,=r,0,1,2,~{memory},~{cc}"(i32 `,
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			file, err := Parse(tc.arch, tc.src)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			opt := Options{
+				TargetTriple: tc.triple,
+				Goarch:       tc.goarch,
+				ResolveSym:   testResolveSym("example"),
+				Sigs:         map[string]FuncSig{"example.f": inlineAsmTestSig(tc.word)},
+			}
+			if _, err := translateIRText(file, opt); err == nil || !strings.Contains(err.Error(), "unsupported instruction") {
+				t.Fatalf("without InlineAsm: err = %v, want unsupported instruction", err)
+			}
+
+			opt.InlineAsm = true
+			ir, err := translateIRText(file, opt)
+			if err != nil {
+				t.Fatalf("translateIRText: %v", err)
+			}
+			for _, s := range tc.want {
+				if !strings.Contains(ir, s) {
+					t.Fatalf("missing %q in IR:\n%s", s, ir)
+				}
+			}
+
+			// Passthrough is only valid when the target runs the source ISA.
+			opt.TargetTriple = "riscv64-unknown-linux-gnu"
+			if _, err := translateIRText(file, opt); err == nil || !strings.Contains(err.Error(), "unsupported instruction") {
+				t.Fatalf("foreign triple: err = %v, want unsupported instruction", err)
+			}
+		})
+	}
+}
+
+// inlineAsmTestSig is the signature of func f(a word) word.
+func inlineAsmTestSig(word LLVMType) FuncSig {
+	resultOff := int64(8)
+	if word == I32 {
+		resultOff = 4
+	}
+	return FuncSig{
+		Name: "example.f",
+		Args: []LLVMType{word},
+		Ret:  word,
+		Frame: FrameLayout{
+			Params:  []FrameSlot{{Offset: 0, Type: word, Index: 0, Field: -1}},
+			Results: []FrameSlot{{Offset: resultOff, Type: word, Index: 0, Field: -1}},
+		},
+	}
+}
+
+func TestInlineAsmRejectsUnverified(t *testing.T) {
+	tests := []struct {
+		arch   Arch
+		triple string
+		word   LLVMType
+		insn   string
+		want   string
+	}{
+		{ArchAMD64, "x86_64-unknown-linux-gnu", I64, "FOOQ BX, CX", "no verified inline asm spelling"},
+		{ArchAMD64, "x86_64-unknown-linux-gnu", I64, "PDEPQ BX, CX", "PDEPQ takes 3 operands"},
+		{ArchARM64, "aarch64-unknown-linux-gnu", I64, "SM3SS1 V1.S4, V2.S4, V3.S4, V4.S4", "no verified inline asm spelling"},
+		{ArchARM64, "aarch64-unknown-linux-gnu", I64, "VSUB V1, V2, V3", "V3 needs an arrangement"},
+		{ArchARM, "armv7-unknown-linux-gnueabihf", I32, "QADD R1, R2, R3", "no verified inline asm spelling"},
+		{ArchARM, "armv7-unknown-linux-gnueabihf", I32, "REV.EQ R1, R2", "unsupported opcode suffix"},
+	}
+	for _, tc := range tests {
+		file, err := Parse(tc.arch, "TEXT ·f(SB),0,$0-16\n\t"+tc.insn+"\n\tRET\n")
+		if err != nil {
+			t.Fatalf("%s: Parse: %v", tc.insn, err)
+		}
+		_, err = translateIRText(file, Options{
+			TargetTriple: tc.triple,
+			Goarch:       string(tc.arch),
+			InlineAsm:    true,
+			ResolveSym:   testResolveSym("example"),
+			Sigs:         map[string]FuncSig{"example.f": inlineAsmTestSig(tc.word)},
+		})
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: err = %v, want %q", tc.insn, err, tc.want)
+		}
+	}
+}
+
+// TestInlineAsmAssembles runs every passthrough opcode through llc, whose
+// integrated assembler rejects a wrong mnemonic or operand form. The samples
+// come from the Go assembler's encoding tests.
+func TestInlineAsmAssembles(t *testing.T) {
+	llc, _, ok := findLlcAndClang(t)
+	if !ok {
+		t.Skip("llc not found")
+	}
+	tests := []struct {
+		arch   Arch
+		triple string
+		mattr  string
+		ops    map[Op]inlineAsmOp
+		mov    string
+		word   LLVMType
+		insns  []string
+	}{
+		{
+			arch:   ArchAMD64,
+			triple: "x86_64-unknown-linux-gnu",
+			mattr:  "+bmi,+bmi2,+lzcnt,+movbe,+ssse3,+sse4.1,+avx,+vaes,+avx512f,+avx512vl",
+			ops:    amd64InlineAsmOps,
+			mov:    "MOVQ",
+			word:   I64,
+			insns: []string{
+				"PDEPQ DX, R14, DX", "PDEPL DX, R9, DX", "PEXTQ DX, R14, DX", "PEXTL DX, R9, DX",
+				"SARXQ R14, DX, DX", "SARXL R9, DX, DX",
+				"BLSIQ DX, R14", "BLSMSKQ DX, R14", "BLSRQ DX, R14",
+				"LZCNTQ DX, DX", "LZCNTL DX, DX", "TZCNTL DX, DX",
+				"MOVBEQ (R8), BX", "PREFETCHT0 (R8)",
+				"PMADDUBSW X2, X2", "PMULLD X2, X2", "PSIGNB X2, X2",
+				"VAESENC X2, X9, X2", "VPTERNLOGD $42, Y1, Y2, Y3",
+			},
+		},
+		{
+			arch:   ArchARM64,
+			triple: "aarch64-unknown-linux-gnu",
+			ops:    arm64InlineAsmOps,
+			mov:    "MOVD",
+			word:   I64,
+			insns: []string{
+				"SDIV R13, R21, R9", "SDIVW R22, R14, R9", "UDIVW R8, R21", "SMULH R17, R21, R21",
+				"MADDW R13, R23, R3, R10", "MSUBW R1, R1, R12, R5",
+				"CLZW R1, R14", "RBITW R9, R22", "REVW R8, R10", "REV16W R21, R19",
+				"VBIC V0.B8, V1.B8, V2.B8", "VCNT V0.B16, V0.B16", "VMUL V0.S4, V0.S4, V1.S4",
+				"VNOT V0.B16, V1.B16", "VRBIT V24.B16, V24.B16", "VSUB V2.B8, V30.B8, V30.B8",
+				"VUMAX V3.B16, V2.B16, V1.B16", "VUMIN V3.B8, V2.B8, V1.B8", "VUZP1 V1.B16, V29.B16, V2.B16",
+			},
+		},
+		{
+			arch:   ArchARM,
+			triple: "armv7-unknown-linux-gnueabihf",
+			ops:    armInlineAsmOps,
+			mov:    "MOVW",
+			word:   I32,
+			insns: []string{
+				"REV R1, R2", "REV16 R1, R2", "REVSH R1, R2", "RBIT R1, R2",
+				"MULS R1, R2, R3, R4",
+			},
+		},
+	}
+	dir := t.TempDir()
+	for _, tc := range tests {
+		covered := map[Op]bool{}
+		for _, insn := range tc.insns {
+			op, _, _ := strings.Cut(insn, " ")
+			covered[Op(op)] = true
+		}
+		for op := range tc.ops {
+			if !covered[op] {
+				t.Errorf("%s: no sample for passthrough opcode %s", tc.arch, op)
+			}
+		}
+
+		sig := inlineAsmTestSig(tc.word)
+		src := fmt.Sprintf("TEXT ·f(SB),0,$0-16\n\t%s a+0(FP), R8\n\t%s\n\t%s R8, ret+%d(FP)\n\tRET\n",
+			tc.mov, strings.Join(tc.insns, "\n\t"), tc.mov, sig.Frame.Results[0].Offset)
+		file, err := Parse(tc.arch, src)
+		if err != nil {
+			t.Fatalf("%s: Parse: %v", tc.arch, err)
+		}
+		ir, err := translateIRText(file, Options{
+			TargetTriple: tc.triple,
+			Goarch:       string(tc.arch),
+			InlineAsm:    true,
+			ResolveSym:   testResolveSym("example"),
+			Sigs:         map[string]FuncSig{"example.f": sig},
+		})
+		if err != nil {
+			t.Fatalf("%s: translateIRText: %v", tc.arch, err)
+		}
+		for _, spec := range tc.ops {
+			if !strings.Contains(ir, `asm sideeffect "`+spec.mnemonic+" ") {
+				t.Errorf("%s: %s not passed through:\n%s", tc.arch, spec.mnemonic, ir)
+			}
+		}
+		llPath := filepath.Join(dir, string(tc.arch)+".ll")
+		if err := os.WriteFile(llPath, []byte(ir), 0644); err != nil {
+			t.Fatal(err)
+		}
+		args := []string{"-mtriple=" + tc.triple, "-filetype=obj", llPath, "-o", filepath.Join(dir, string(tc.arch)+".o")}
+		if tc.mattr != "" {
+			args = append(args, "-mattr="+tc.mattr)
+		}
+		if out, err := exec.Command(llc, args...).CombinedOutput(); err != nil {
+			t.Fatalf("llc %s: %v\n%s\n%s", tc.triple, err, out, ir)
+		}
+	}
+}
+
+func TestTripleMatchesArch(t *testing.T) {
+	tests := []struct {
+		triple string
+		arch   Arch
+		want   bool
+	}{
+		{"", ArchAMD64, true},
+		{"x86_64-apple-macosx", ArchAMD64, true},
+		{"i686-unknown-linux-gnu", ArchAMD64, false},
+		{"aarch64-unknown-linux-gnu", ArchARM64, true},
+		{"arm64-apple-macosx", ArchARM64, true},
+		{"arm64-apple-macosx", ArchARM, false},
+		{"armv7-unknown-linux-gnueabihf", ArchARM, true},
+		{"thumbv7-unknown-linux-gnueabihf", ArchARM, true},
+		{"x86_64-unknown-linux-gnu", ArchARM64, false},
+	}
+	for _, tc := range tests {
+		if got := tripleMatchesArch(tc.triple, tc.arch); got != tc.want {
+			t.Fatalf("tripleMatchesArch(%q, %s) = %v, want %v", tc.triple, tc.arch, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,265 @@
+//go:build !llgo
+// +build !llgo
+
+package plan9asm
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// amd64PopCount calls a counting helper twice, with a loop and a shared
+// label name in both.
+const amd64PopCount = `TEXT ·PopCount2(SB),NOSPLIT,$0-24
+	MOVQ	a+0(FP), AX
+	CALL	count<>(SB)
+	MOVQ	CX, DX
+	MOVQ	b+8(FP), AX
+	CALL	count<>(SB)
+	ADDQ	DX, CX
+	MOVQ	CX, ret+16(FP)
+	RET
+
+TEXT count<>(SB),NOSPLIT,$0-0
+	XORQ	CX, CX
+loop:
+	TESTQ	AX, AX
+	JEQ	done
+	INCQ	CX
+	MOVQ	AX, BX
+	DECQ	BX
+	ANDQ	BX, AX
+	JMP	loop
+done:
+	RET
+`
+
+func inlineSigs() map[string]FuncSig {
+	return map[string]FuncSig{
+		"example.PopCount2": sigWithClassicFrame("example.PopCount2", []LLVMType{I64, I64}, I64),
+	}
+}
+
+func TestInlineHelpersSplicesCallSites(t *testing.T) {
+	file := mustParse(t, ArchAMD64, amd64PopCount)
+	out, inlined := inlineHelpers(file, testResolveSym("example"))
+	if !inlined["example.count"] || len(inlined) != 1 {
+		t.Fatalf("inlined = %v", inlined)
+	}
+	var labels []string
+	for _, ins := range out.Funcs[0].Instrs {
+		if ins.Op == "CALL" {
+			t.Fatalf("call left after inlining: %q", ins.Raw)
+		}
+		if ins.Op == OpLABEL {
+			labels = append(labels, ins.Args[0].Sym)
+		}
+	}
+	want := []string{"inline1_loop", "inline1_done", "inline1", "inline2_loop", "inline2_done", "inline2"}
+	if strings.Join(labels, " ") != strings.Join(want, " ") {
+		t.Fatalf("labels = %v, want %v", labels, want)
+	}
+	// The helper itself is untouched.
+	if len(out.Funcs[1].Instrs) != len(file.Funcs[1].Instrs) {
+		t.Fatalf("helper changed: %v", out.Funcs[1].Instrs)
+	}
+
+	ll, report, err := translateIRTextReport(file, Options{
+		TargetTriple:  "x86_64-unknown-linux-gnu",
+		Goarch:        "amd64",
+		ResolveSym:    testResolveSym("example"),
+		Sigs:          inlineSigs(),
+		InlineHelpers: true,
+	}, nil)
+	if err != nil {
+		t.Fatalf("translate: %v", err)
+	}
+	if len(report) != 2 || report[1].Name != "example.count" || report[1].Status != FuncInlined {
+		t.Fatalf("report = %+v", report)
+	}
+	if strings.Contains(ll, `@example.count`) {
+		t.Fatalf("inlined helper still emitted or called:\n%s", ll)
+	}
+}
+
+func TestInlineHelpersRecursion(t *testing.T) {
+	src := `TEXT ·F(SB),NOSPLIT,$0-8
+	CALL	outer<>(SB)
+	MOVQ	AX, ret+0(FP)
+	RET
+
+TEXT outer<>(SB),NOSPLIT,$0-0
+	MOVQ	$1, AX
+	CALL	inner<>(SB)
+	RET
+
+TEXT inner<>(SB),NOSPLIT,$0-0
+	DECQ	AX
+	JEQ	done
+	CALL	outer<>(SB)
+done:
+	RET
+`
+	out, inlined := inlineHelpers(mustParse(t, ArchAMD64, src), testResolveSym("example"))
+	// outer<> reaches itself through inner<>, so calls to it stay; inner<>
+	// is inlined into outer<> and has no other callers.
+	if len(inlined) != 1 || !inlined["example.inner"] {
+		t.Fatalf("inlined = %v", inlined)
+	}
+	calls := func(fn Func) []string {
+		var syms []string
+		for _, ins := range fn.Instrs {
+			if ins.Op == "CALL" {
+				syms = append(syms, ins.Args[0].Sym)
+			}
+		}
+		return syms
+	}
+	if got := calls(out.Funcs[0]); len(got) != 1 || got[0] != "outer<>(SB)" {
+		t.Fatalf("F calls %v", got)
+	}
+	if got := calls(out.Funcs[1]); len(got) != 1 || got[0] != "outer<>(SB)" {
+		t.Fatalf("outer<> calls %v", got)
+	}
+
+	_, report, err := translateIRTextReport(mustParse(t, ArchAMD64, src), Options{
+		TargetTriple:  "x86_64-unknown-linux-gnu",
+		Goarch:        "amd64",
+		ResolveSym:    testResolveSym("example"),
+		Sigs:          map[string]FuncSig{"example.F": sigWithClassicFrame("example.F", nil, I64)},
+		InlineHelpers: true,
+	}, nil)
+	if err != nil {
+		t.Fatalf("translate: %v", err)
+	}
+	want := []FuncStatus{FuncTranslated, FuncTranslated, FuncInlined}
+	for i, r := range report {
+		if r.Status != want[i] {
+			t.Fatalf("report = %+v", report)
+		}
+	}
+}
+
+func TestInlineHelpersGoModule(t *testing.T) {
+	// count<> has no Go declaration; TranslateGoModule must leave it to the
+	// inliner.
+	pkg := mustGoPackage(t, "test/pkg", `package testpkg
+func PopCount2(a, b uint64) int
+`)
+	tr, err := TranslateGoModule(pkg, []byte(amd64PopCount), GoModuleOptions{
+		FileName:      "popcount_amd64.s",
+		GOARCH:        "amd64",
+		TargetTriple:  "x86_64-unknown-linux-gnu",
+		ResolveSym:    testResolveSym("test/pkg"),
+		InlineHelpers: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tr.Module.Dispose()
+	if fn := tr.Module.NamedFunction("test/pkg.count"); !fn.IsNil() {
+		t.Fatalf("inlined helper still in module")
+	}
+	fn := tr.Module.NamedFunction("test/pkg.PopCount2")
+	if fn.IsNil() || fn.IsDeclaration() {
+		t.Fatalf("missing PopCount2 body")
+	}
+}
+
+func TestInlineHelpersKeepsCalls(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		src  string
+	}{
+		{"frame", `TEXT ·F(SB),NOSPLIT,$0-8
+	CALL	h<>(SB)
+	RET
+TEXT h<>(SB),NOSPLIT,$16-0
+	MOVQ	AX, 0(SP)
+	RET
+`},
+		{"tail jump", `TEXT ·F(SB),NOSPLIT,$0-8
+	CALL	h<>(SB)
+	RET
+TEXT h<>(SB),NOSPLIT,$0-0
+	JMP	·G(SB)
+`},
+		{"pc-relative branch", `TEXT ·F(SB),NOSPLIT,$0-8
+	CMPQ	AX, $0
+	JEQ	2(PC)
+	CALL	h<>(SB)
+	RET
+TEXT h<>(SB),NOSPLIT,$0-0
+	INCQ	AX
+	RET
+`},
+	} {
+		file := mustParse(t, ArchAMD64, tc.src)
+		if out, inlined := inlineHelpers(file, testResolveSym("example")); out != file || inlined != nil {
+			t.Fatalf("%s: inlined %v", tc.name, inlined)
+		}
+	}
+}
+
+func TestInlineHelpersCompile(t *testing.T) {
+	llc, _, ok := findLlcAndClang(t)
+	if !ok {
+		t.Skip("llc not found")
+	}
+	arm64Src := `TEXT ·PopCount2(SB),NOSPLIT,$0-24
+	MOVD	a+0(FP), R0
+	BL	count<>(SB)
+	MOVD	R2, R3
+	MOVD	b+8(FP), R0
+	BL	count<>(SB)
+	ADD	R3, R2, R2
+	MOVD	R2, ret+16(FP)
+	RET
+
+TEXT count<>(SB),NOSPLIT,$0-0
+	MOVD	ZR, R2
+loop:
+	CBZ	R0, done
+	ADD	$1, R2, R2
+	SUB	$1, R0, R1
+	AND	R1, R0, R0
+	B	loop
+done:
+	RET
+`
+	for _, tc := range []struct {
+		arch   Arch
+		goarch string
+		triple string
+		src    string
+	}{
+		{ArchAMD64, "amd64", "x86_64-unknown-linux-gnu", amd64PopCount},
+		{ArchARM64, "arm64", "aarch64-unknown-linux-gnu", arm64Src},
+	} {
+		ll, err := translateIRText(mustParse(t, tc.arch, tc.src), Options{
+			TargetTriple:  tc.triple,
+			Goarch:        tc.goarch,
+			ResolveSym:    testResolveSym("example"),
+			Sigs:          inlineSigs(),
+			InlineHelpers: true,
+		})
+		if err != nil {
+			t.Fatalf("%s: translateIRText: %v", tc.goarch, err)
+		}
+		if strings.Contains(ll, "@example.count") {
+			t.Fatalf("%s: helper not inlined:\n%s", tc.goarch, ll)
+		}
+		dir := t.TempDir()
+		llPath := filepath.Join(dir, "inline.ll")
+		if err := os.WriteFile(llPath, []byte(ll), 0644); err != nil {
+			t.Fatal(err)
+		}
+		out, err := exec.Command(llc, "-mtriple="+tc.triple, "-filetype=obj", llPath, "-o", filepath.Join(dir, "inline.o")).CombinedOutput()
+		if err != nil {
+			t.Fatalf("%s: llc: %v\n%s\n%s", tc.goarch, err, out, ll)
+		}
+	}
+}
@@ -0,0 +1,330 @@
+package plan9asm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// loong64Ctx lowers one loong64 TEXT body. R1-R31 and F0-F31 live in i64
+// slots (F registers hold the raw bits, singles in the low word); R0 reads
+// as zero and drops writes. The FCC0-FCC7 condition flags set by the
+// floating-point compares hold 0 or 1 in slots of their own; integer
+// branches compare registers directly.
+type loong64Ctx struct {
+	b       *strings.Builder
+	sig     FuncSig
+	resolve func(string) string
+	sigs    map[string]FuncSig
+	cfg     lowerConfig
+
+	tmp int
+
+	blocks     []loong64Block
+	blockBase  []int
+	blockByIdx map[int]int
+
+	regSlot   map[Reg]string // reg -> alloca name
+	frameSize int64
+	// argFrameSize is the size of an in-memory copy of the argument frame,
+	// made when the body takes the address of an FP slot that is not a
+	// result, or 0.
+	argFrameSize int64
+
+	reservedValidSlot string
+	reservedPtrSlot   string
+	reservedValueSlot string
+
+	fpParams       map[int64]FrameSlot // off(FP) -> slot
+	fpResults      []FrameSlot         // result slots (Index is result index)
+	fpResAllocaOff map[int64]string    // off(FP) -> alloca
+	fpResAllocaIdx map[int]string      // result index -> alloca
+	fpResWritten   map[int]bool        // result index -> direct writes to fp slot
+	fpResAddrTaken map[int]bool        // result index -> fp result slot address escaped
+}
+
+func newLOONG64Ctx(b *strings.Builder, fn Func, sig FuncSig, resolve func(string) string, sigs map[string]FuncSig, cfg lowerConfig) *loong64Ctx {
+	c := &loong64Ctx{
+		b:              b,
+		sig:            sig,
+		resolve:        resolve,
+		sigs:           sigs,
+		cfg:            cfg,
+		blocks:         loong64SplitBlocks(fn),
+		blockByIdx:     map[int]int{},
+		regSlot:        map[Reg]string{},
+		frameSize:      textFrameSize(fn),
+		fpParams:       map[int64]FrameSlot{},
+		fpResAllocaOff: map[int64]string{},
+		fpResAllocaIdx: map[int]string{},
+		fpResWritten:   map[int]bool{},
+		fpResAddrTaken: map[int]bool{},
+	}
+	for _, s := range sig.Frame.Params {
+		c.fpParams[s.Offset] = s
+	}
+	c.fpResults = append([]FrameSlot(nil), sig.Frame.Results...)
+	c.argFrameSize = riscv64ArgFrameSize(fn, sig)
+	base := 0
+	for i, blk := range c.blocks {
+		c.blockBase = append(c.blockBase, base)
+		c.blockByIdx[base] = i
+		base += len(blk.instrs)
+	}
+	return c
+}
+
+func (c *loong64Ctx) emitSourceComment(ins Instr) {
+	if !c.cfg.annotateSource {
+		return
+	}
+	emitIRSourceComment(c.b, ins.Raw)
+}
+
+func (c *loong64Ctx) newTmp() string {
+	c.tmp++
+	return fmt.Sprintf("t%d", c.tmp)
+}
+
+func (c *loong64Ctx) emitEntryAllocasAndArgInit() error {
+	c.b.WriteString("entry:\n")
+	regs := []Reg{SP}
+	for i := 1; i <= 31; i++ {
+		if i != 3 {
+			regs = append(regs, Reg(fmt.Sprintf("R%d", i)))
+		}
+	}
+	for i := 0; i <= 31; i++ {
+		regs = append(regs, Reg(fmt.Sprintf("F%d", i)))
+	}
+	for i := 0; i <= 7; i++ {
+		regs = append(regs, Reg(fmt.Sprintf("FCC%d", i)))
+	}
+	for _, r := range regs {
+		name := "%" + arm64LLVMBlockName("reg_"+string(r))
+		c.regSlot[r] = name
+		fmt.Fprintf(c.b, "  %s = alloca i64\n", name)
+		fmt.Fprintf(c.b, "  store i64 0, ptr %s\n", name)
+	}
+
+	// SP points at the bottom of the TEXT frame, whose first word holds
+	// the saved return address in Go's layout.
+	if c.frameSize > 0 {
+		fmt.Fprintf(c.b, "  %%frame = alloca [%d x i8], align 8\n", c.frameSize+8)
+		t := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = ptrtoint ptr %%frame to i64\n", t)
+		if err := c.storeReg(SP, "%"+t); err != nil {
+			return err
+		}
+	}
+
+	// Reservation state for LL/SC lowering.
+	c.reservedValidSlot = "%reserved_valid"
+	c.reservedPtrSlot = "%reserved_ptr"
+	c.reservedValueSlot = "%reserved_value"
+	fmt.Fprintf(c.b, "  %s = alloca i1\n", c.reservedValidSlot)
+	fmt.Fprintf(c.b, "  store i1 false, ptr %s\n", c.reservedValidSlot)
+	fmt.Fprintf(c.b, "  %s = alloca ptr\n", c.reservedPtrSlot)
+	fmt.Fprintf(c.b, "  store ptr null, ptr %s\n", c.reservedPtrSlot)
+	fmt.Fprintf(c.b, "  %s = alloca i64\n", c.reservedValueSlot)
+	fmt.Fprintf(c.b, "  store i64 0, ptr %s\n", c.reservedValueSlot)
+
+	for _, r := range c.fpResults {
+		name := fmt.Sprintf("%%fp_ret_%d", r.Index)
+		c.fpResAllocaIdx[r.Index] = name
+		c.fpResAllocaOff[r.Offset] = name
+		fmt.Fprintf(c.b, "  %s = alloca %s\n", name, r.Type)
+		fmt.Fprintf(c.b, "  store %s %s, ptr %s\n", r.Type, llvmZeroValue(r.Type), name)
+	}
+
+	if c.argFrameSize > 0 {
+		if err := c.spillArgFrame(); err != nil {
+			return err
+		}
+	}
+
+	// Seed the argument registers too, for ABIInternal bodies and helper<>
+	// register assignments; ABI0 bodies read the FP slots instead.
+	if len(c.sig.ArgRegs) > 0 {
+		for i := 0; i < len(c.sig.Args) && i < len(c.sig.ArgRegs); i++ {
+			v, ok, err := c.valueAsI64(c.sig.Args[i], fmt.Sprintf("%%arg%d", i))
+			if err != nil {
+				return err
+			}
+			if !ok {
+				continue
+			}
+			if err := c.storeReg(c.sig.ArgRegs[i], v); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	var cur loong64ArgCursor
+	for ai, argTy := range c.sig.Args {
+		arg := fmt.Sprintf("%%arg%d", ai)
+		fields, isAgg := parseLiteralStructFields(argTy)
+		if !isAgg {
+			fields = []LLVMType{argTy}
+		}
+		for fi, fTy := range fields {
+			r, ok := cur.next(fTy)
+			if !ok {
+				return nil
+			}
+			v := arg
+			if isAgg {
+				t := c.newTmp()
+				fmt.Fprintf(c.b, "  %%%s = extractvalue %s %s, %d\n", t, argTy, arg, fi)
+				v = "%" + t
+			}
+			v64, ok, err := c.valueAsI64(fTy, v)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				continue
+			}
+			if err := c.storeReg(r, v64); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// spillArgFrame stores the FP parameter slots into %argframe.
+func (c *loong64Ctx) spillArgFrame() error {
+	fmt.Fprintf(c.b, "  %%argframe = alloca [%d x i8], align 8\n", c.argFrameSize)
+	for _, s := range c.sig.Frame.Params {
+		if s.Index < 0 || s.Index >= len(c.sig.Args) {
+			continue
+		}
+		v := fmt.Sprintf("%%arg%d", s.Index)
+		if s.Field >= 0 {
+			t := c.newTmp()
+			fmt.Fprintf(c.b, "  %%%s = extractvalue %s %s, %d\n", t, c.sig.Args[s.Index], v, s.Field)
+			v = "%" + t
+		}
+		p := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = getelementptr i8, ptr %%argframe, i64 %d\n", p, s.Offset)
+		fmt.Fprintf(c.b, "  store %s %s, ptr %%%s\n", s.Type, v, p)
+	}
+	return nil
+}
+
+// loong64ArgCursor hands out ABIInternal argument (or result) registers.
+type loong64ArgCursor struct{ ints, floats int }
+
+func (a *loong64ArgCursor) next(ty LLVMType) (Reg, bool) {
+	if riscv64IsFloatType(ty) {
+		if a.floats >= len(loong64FloatArgRegs) {
+			return "", false
+		}
+		a.floats++
+		return loong64FloatArgRegs[a.floats-1], true
+	}
+	if a.ints >= len(loong64IntArgRegs) {
+		return "", false
+	}
+	a.ints++
+	return loong64IntArgRegs[a.ints-1], true
+}
+
+func (c *loong64Ctx) valueAsI64(ty LLVMType, v string) (out string, ok bool, err error) {
+	switch ty {
+	case I64:
+		return v, true, nil
+	case Ptr:
+		t := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = ptrtoint ptr %s to i64\n", t, v)
+		return "%" + t, true, nil
+	case I1, I8, I16, I32:
+		t := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = zext %s %s to i64\n", t, ty, v)
+		return "%" + t, true, nil
+	case LLVMType("double"):
+		t := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = bitcast double %s to i64\n", t, v)
+		return "%" + t, true, nil
+	case LLVMType("float"):
+		t := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = bitcast float %s to i32\n", t, v)
+		z := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = zext i32 %%%s to i64\n", z, t)
+		return "%" + z, true, nil
+	}
+	return "", false, nil
+}
+
+// i64ToValue converts register bits back to ty.
+func (c *loong64Ctx) i64ToValue(v string, ty LLVMType) (string, error) {
+	switch ty {
+	case I64:
+		return v, nil
+	case I32, I16, I8, I1:
+		t := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = trunc i64 %s to %s\n", t, v, ty)
+		return "%" + t, nil
+	case Ptr:
+		t := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = inttoptr i64 %s to ptr\n", t, v)
+		return "%" + t, nil
+	case LLVMType("double"):
+		t := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = bitcast i64 %s to double\n", t, v)
+		return "%" + t, nil
+	case LLVMType("float"):
+		w := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = trunc i64 %s to i32\n", w, v)
+		t := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = bitcast i32 %%%s to float\n", t, w)
+		return "%" + t, nil
+	}
+	return "", fmt.Errorf("loong64: unsupported value type %s", ty)
+}
+
+func (c *loong64Ctx) loadReg(r Reg) (string, error) {
+	if r == "R0" {
+		return "0", nil
+	}
+	slot, ok := c.regSlot[r]
+	if !ok {
+		return "", fmt.Errorf("loong64: unknown reg %s", r)
+	}
+	t := c.newTmp()
+	fmt.Fprintf(c.b, "  %%%s = load i64, ptr %s\n", t, slot)
+	return "%" + t, nil
+}
+
+func (c *loong64Ctx) storeReg(r Reg, v string) error {
+	if r == "R0" {
+		return nil
+	}
+	slot, ok := c.regSlot[r]
+	if !ok {
+		return fmt.Errorf("loong64: unknown reg %s", r)
+	}
+	fmt.Fprintf(c.b, "  store i64 %s, ptr %s\n", v, slot)
+	return nil
+}
+
+func (c *loong64Ctx) ptrFromSB(sym string) (string, error) {
+	base, off, ok := parseSBRef(sym)
+	if !ok {
+		return "", fmt.Errorf("invalid (SB) sym ref: %q", sym)
+	}
+	base = strings.TrimPrefix(base, "$")
+	res := base
+	if strings.Contains(base, "·") || strings.Contains(base, "/") || strings.Contains(base, ".") {
+		res = c.resolve(base)
+	} else {
+		res = c.resolve("·" + base)
+	}
+	p := llvmGlobal(res)
+	if off == 0 {
+		return p, nil
+	}
+	t := c.newTmp()
+	fmt.Fprintf(c.b, "  %%%s = getelementptr i8, ptr %s, i64 %d\n", t, p, off)
+	return "%" + t, nil
+}
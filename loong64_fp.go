@@ -0,0 +1,66 @@
+package plan9asm
+
+import "fmt"
+
+func (c *loong64Ctx) fpResultSlotByOffset(off int64) (slot FrameSlot, ok bool) {
+	for _, s := range c.fpResults {
+		if s.Offset == off {
+			return s, true
+		}
+	}
+	return FrameSlot{}, false
+}
+
+func (c *loong64Ctx) markFPResultAddrTaken(off int64) {
+	if s, ok := c.fpResultSlotByOffset(off); ok {
+		c.fpResAddrTaken[s.Index] = true
+	}
+}
+
+// storeFPResult64 stores register bits to the result slot at off(FP),
+// converting them to the slot's type.
+func (c *loong64Ctx) storeFPResult64(off int64, v64 string) error {
+	p, ok := c.fpResAllocaOff[off]
+	if !ok {
+		return fmt.Errorf("loong64: unsupported FP result slot +%d(FP)", off)
+	}
+	meta, _ := c.fpResultSlotByOffset(off)
+	v, err := c.i64ToValue(v64, meta.Type)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(c.b, "  store %s %s, ptr %s\n", meta.Type, v, p)
+	c.fpResWritten[meta.Index] = true
+	return nil
+}
+
+func (c *loong64Ctx) loadFPResult(slot FrameSlot) (string, error) {
+	p, ok := c.fpResAllocaIdx[slot.Index]
+	if !ok {
+		return "", fmt.Errorf("loong64: missing FP result alloca for index %d", slot.Index)
+	}
+	t := c.newTmp()
+	fmt.Fprintf(c.b, "  %%%s = load %s, ptr %s\n", t, slot.Type, p)
+	return "%" + t, nil
+}
+
+// loadRetSlotFallback reads a result the body never stored to its FP slot
+// from the ABIInternal result register it would be returned in.
+func (c *loong64Ctx) loadRetSlotFallback(slot FrameSlot) (string, error) {
+	var cur loong64ArgCursor
+	var r Reg
+	for _, s := range c.fpResults {
+		var ok bool
+		if r, ok = cur.next(s.Type); !ok {
+			return llvmZeroValue(slot.Type), nil
+		}
+		if s.Index == slot.Index {
+			break
+		}
+	}
+	v64, err := c.loadReg(r)
+	if err != nil {
+		return "", err
+	}
+	return c.i64ToValue(v64, slot.Type)
+}
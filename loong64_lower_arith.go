@@ -0,0 +1,417 @@
+package plan9asm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// loong64ALUOp describes a two-source integer operation. w32 operations
+// (the .w forms, which Go spells without a V suffix) compute on the low
+// words and sign-extend the result.
+type loong64ALUOp struct {
+	kind string
+	w32  bool
+}
+
+var loong64ALUOps = map[string]loong64ALUOp{
+	"ADDV": {"add", false}, "ADDVU": {"add", false},
+	"ADD": {"add", true}, "ADDU": {"add", true}, "ADDW": {"add", true},
+	"SUBV": {"sub", false}, "SUBVU": {"sub", false},
+	"SUB": {"sub", true}, "SUBU": {"sub", true}, "SUBW": {"sub", true},
+	"AND": {"and", false}, "OR": {"or", false}, "XOR": {"xor", false},
+	"NOR": {"nor", false}, "ANDN": {"andn", false}, "ORN": {"orn", false},
+	"SLLV": {"shl", false}, "SLL": {"shl", true},
+	"SRLV": {"lshr", false}, "SRL": {"lshr", true},
+	"SRAV": {"ashr", false}, "SRA": {"ashr", true},
+	"ROTRV": {"ror", false}, "ROTR": {"ror", true},
+	"SGT": {"slt", false}, "SGTU": {"sltu", false},
+	"MULV": {"mul", false}, "MULVU": {"mul", false},
+	"MUL": {"mul", true}, "MULU": {"mul", true}, "MULW": {"mul", true},
+	"MULHV": {"mulh", false}, "MULHVU": {"mulhu", false},
+	"MULH": {"mulh", true}, "MULHU": {"mulhu", true},
+	"MULWVW": {"mulwv", false}, "MULWVWU": {"mulwvu", false},
+	"DIVV": {"div", false}, "DIV": {"div", true}, "DIVW": {"div", true},
+	"DIVVU": {"divu", false}, "DIVU": {"divu", true}, "DIVWU": {"divu", true},
+	"REMV": {"rem", false}, "REM": {"rem", true}, "REMW": {"rem", true},
+	"REMVU": {"remu", false}, "REMU": {"remu", true}, "REMWU": {"remu", true},
+	"MASKEQZ": {"maskeqz", false}, "MASKNEZ": {"masknez", false},
+}
+
+func (c *loong64Ctx) lowerArith(op string, ins Instr) (ok bool, terminated bool, err error) {
+	if alu, found := loong64ALUOps[op]; found {
+		return true, false, c.lowerALU(op, alu, ins)
+	}
+	if _, found := loong64UnaryOps[op]; found {
+		return true, false, c.lowerUnary(op, ins)
+	}
+	switch op {
+	case "ALSLV", "ALSLW", "ALSLWU":
+		return true, false, c.lowerALSL(op, ins)
+	case "BSTRPICKV", "BSTRPICKW", "BSTRINSV", "BSTRINSW":
+		return true, false, c.lowerBitField(op, ins)
+	case "CRCWBW", "CRCWHW", "CRCWWW", "CRCWVW", "CRCCWBW", "CRCCWHW", "CRCCWWW", "CRCCWVW":
+		return true, false, c.lowerCRC(op, ins)
+	case "CPUCFG":
+		// Report no optional features, so that feature-gated code takes
+		// the baseline path; the real CPUCFG words are not known at
+		// compile time.
+		if len(ins.Args) != 2 || !loong64IsRReg(ins.Args[1]) {
+			return true, false, fmt.Errorf("loong64 CPUCFG expects reg, reg: %q", ins.Raw)
+		}
+		return true, false, c.storeReg(ins.Args[1].Reg, "0")
+	case "RDTIMED", "RDTIMELW", "RDTIMEHW":
+		// "RDTIMED rj, rd" puts the counter in rd and the counter ID in rj.
+		if len(ins.Args) != 2 || !loong64IsRReg(ins.Args[0]) || !loong64IsRReg(ins.Args[1]) {
+			return true, false, fmt.Errorf("loong64 %s expects reg, reg: %q", op, ins.Raw)
+		}
+		v := c.emit("call i64 @llvm.readcyclecounter()")
+		switch op {
+		case "RDTIMELW":
+			v = c.narrow(v, 32, true)
+		case "RDTIMEHW":
+			v = c.narrow(c.emit("lshr i64 %s, 32", v), 32, true)
+		}
+		if err := c.storeReg(ins.Args[0].Reg, "0"); err != nil {
+			return true, false, err
+		}
+		return true, false, c.storeReg(ins.Args[1].Reg, v)
+	}
+	return false, false, nil
+}
+
+// lowerALU lowers "OP rk, rj, rd", which computes rd = rj OP rk, and the
+// two-operand "OP rk, rd" form, which reads rd as rj. rk may be an
+// immediate; the assembler materializes the ones without an encoding.
+func (c *loong64Ctx) lowerALU(op string, alu loong64ALUOp, ins Instr) error {
+	if len(ins.Args) != 2 && len(ins.Args) != 3 {
+		return fmt.Errorf("loong64 %s expects 2 or 3 operands: %q", op, ins.Raw)
+	}
+	src := ins.Args[0]
+	if src.Kind != OpImm && !loong64IsRReg(src) {
+		return fmt.Errorf("loong64 %s expects a register or immediate source: %q", op, ins.Raw)
+	}
+	for _, a := range ins.Args[1:] {
+		if !loong64IsRReg(a) {
+			return fmt.Errorf("loong64 %s expects R registers: %q", op, ins.Raw)
+		}
+	}
+	dst := ins.Args[len(ins.Args)-1].Reg
+	rk, err := c.eval64(src)
+	if err != nil {
+		return err
+	}
+	rj, err := c.loadReg(ins.Args[1].Reg)
+	if err != nil {
+		return err
+	}
+	ty := "i64"
+	if alu.w32 {
+		ty = "i32"
+		rj, rk = c.trunc32(rj), c.trunc32(rk)
+	}
+	v := c.aluValue(alu.kind, ty, rj, rk)
+	if alu.w32 {
+		v = c.extend(v, 32, true)
+	}
+	return c.storeReg(dst, v)
+}
+
+func (c *loong64Ctx) trunc32(v string) string {
+	t := c.newTmp()
+	fmt.Fprintf(c.b, "  %%%s = trunc i64 %s to i32\n", t, v)
+	return "%" + t
+}
+
+func (c *loong64Ctx) emit(format string, args ...any) string {
+	t := c.newTmp()
+	fmt.Fprintf(c.b, "  %%%s = "+format+"\n", append([]any{t}, args...)...)
+	return "%" + t
+}
+
+// aluValue computes a OP b in ty. Shift amounts are taken modulo the
+// width; division by zero, which LoongArch leaves unspecified, does not
+// trap.
+func (c *loong64Ctx) aluValue(kind, ty, a, b string) string {
+	bits := 64
+	if ty == "i32" {
+		bits = 32
+	}
+	switch kind {
+	case "add", "sub", "and", "or", "xor", "mul":
+		return c.emit("%s %s %s, %s", kind, ty, a, b)
+	case "nor":
+		o := c.emit("or i64 %s, %s", a, b)
+		return c.emit("xor i64 %s, -1", o)
+	case "andn", "orn":
+		nb := c.emit("xor i64 %s, -1", b)
+		return c.emit("%s i64 %s, %s", kind[:len(kind)-1], a, nb)
+	case "shl", "lshr", "ashr":
+		sh := c.emit("and %s %s, %d", ty, b, bits-1)
+		return c.emit("%s %s %s, %s", kind, ty, a, sh)
+	case "ror":
+		return c.emit("call %s @llvm.fshr.%s(%s %s, %s %s, %s %s)", ty, ty, ty, a, ty, a, ty, b)
+	case "slt", "sltu":
+		// SGT rk, rj, rd sets rd = rk > rj, that is rj < rk.
+		pred := "slt"
+		if kind == "sltu" {
+			pred = "ult"
+		}
+		cmp := c.emit("icmp %s i64 %s, %s", pred, a, b)
+		return c.emit("zext i1 %s to i64", cmp)
+	case "mulh", "mulhu":
+		ext := "sext"
+		if kind == "mulhu" {
+			ext = "zext"
+		}
+		wide := fmt.Sprintf("i%d", 2*bits)
+		wa := c.emit("%s %s %s to %s", ext, ty, a, wide)
+		wb := c.emit("%s %s %s to %s", ext, ty, b, wide)
+		p := c.emit("mul %s %s, %s", wide, wa, wb)
+		hi := c.emit("lshr %s %s, %d", wide, p, bits)
+		return c.emit("trunc %s %s to %s", wide, hi, ty)
+	case "mulwv", "mulwvu":
+		// mulw.d.w[u] multiplies the low words into a 64-bit product.
+		signed := kind == "mulwv"
+		return c.emit("mul i64 %s, %s", c.narrow(a, 32, signed), c.narrow(b, 32, signed))
+	case "div", "divu", "rem", "remu":
+		// The results of x/0 are not architected; use the RISC-V values
+		// (all ones and x). MIN/-1 gives MIN and MIN%-1 gives 0, which
+		// dividing by 1 produces.
+		zero := c.emit("icmp eq %s %s, 0", ty, b)
+		bad := zero
+		if kind == "div" || kind == "rem" {
+			isMin := c.emit("icmp eq %s %s, %d", ty, a, int64(-1)<<(bits-1))
+			isNeg1 := c.emit("icmp eq %s %s, -1", ty, b)
+			ov := c.emit("and i1 %s, %s", isMin, isNeg1)
+			bad = c.emit("or i1 %s, %s", zero, ov)
+		}
+		safe := c.emit("select i1 %s, %s 1, %s %s", bad, ty, ty, b)
+		insn := map[string]string{"div": "sdiv", "divu": "udiv", "rem": "srem", "remu": "urem"}[kind]
+		q := c.emit("%s %s %s, %s", insn, ty, a, safe)
+		onZero := "-1"
+		if kind == "rem" || kind == "remu" {
+			onZero = a
+		}
+		return c.emit("select i1 %s, %s %s, %s %s", zero, ty, onZero, ty, q)
+	case "maskeqz", "masknez":
+		// MASKEQZ rk, rj, rd sets rd = rk == 0 ? 0 : rj; MASKNEZ tests
+		// rk != 0.
+		pred := "eq"
+		if kind == "masknez" {
+			pred = "ne"
+		}
+		cmp := c.emit("icmp %s i64 %s, 0", pred, b)
+		return c.emit("select i1 %s, i64 0, i64 %s", cmp, a)
+	}
+	panic("loong64: unknown ALU kind " + kind)
+}
+
+// lowerALSL lowers "ALSLV $sa, rj, rk, rd", which computes
+// rd = (rj << sa) + rk. ALSLW sign-extends and ALSLWU zero-extends the low
+// word of the sum.
+func (c *loong64Ctx) lowerALSL(op string, ins Instr) error {
+	if len(ins.Args) != 4 || ins.Args[0].Kind != OpImm || ins.Args[0].Imm < 1 || ins.Args[0].Imm > 4 {
+		return fmt.Errorf("loong64 %s expects $1-$4, rj, rk, rd: %q", op, ins.Raw)
+	}
+	for _, a := range ins.Args[1:] {
+		if !loong64IsRReg(a) {
+			return fmt.Errorf("loong64 %s expects R registers: %q", op, ins.Raw)
+		}
+	}
+	rj, err := c.loadReg(ins.Args[1].Reg)
+	if err != nil {
+		return err
+	}
+	rk, err := c.loadReg(ins.Args[2].Reg)
+	if err != nil {
+		return err
+	}
+	sh := c.emit("shl i64 %s, %d", rj, ins.Args[0].Imm)
+	v := c.emit("add i64 %s, %s", sh, rk)
+	switch op {
+	case "ALSLW":
+		v = c.narrow(v, 32, true)
+	case "ALSLWU":
+		v = c.narrow(v, 32, false)
+	}
+	return c.storeReg(ins.Args[3].Reg, v)
+}
+
+// lowerBitField lowers "BSTRPICKV $msb, rj, $lsb, rd", which sets rd to
+// rj[msb:lsb] zero-extended, and BSTRINSV, which replaces rd[msb:lsb] with
+// the low bits of rj. The W forms work on the low word and sign-extend it.
+func (c *loong64Ctx) lowerBitField(op string, ins Instr) error {
+	width := int64(64)
+	if op[len(op)-1] == 'W' {
+		width = 32
+	}
+	if len(ins.Args) != 4 || ins.Args[0].Kind != OpImm || ins.Args[2].Kind != OpImm ||
+		!loong64IsRReg(ins.Args[1]) || !loong64IsRReg(ins.Args[3]) {
+		return fmt.Errorf("loong64 %s expects $msb, rj, $lsb, rd: %q", op, ins.Raw)
+	}
+	msb, lsb := ins.Args[0].Imm, ins.Args[2].Imm
+	if lsb < 0 || msb < lsb || msb >= width {
+		return fmt.Errorf("loong64 %s: bad bit range %d:%d: %q", op, msb, lsb, ins.Raw)
+	}
+	n := msb - lsb + 1
+	mask := int64(-1)
+	if n < 64 {
+		mask = int64(1)<<n - 1
+	}
+	rj, err := c.loadReg(ins.Args[1].Reg)
+	if err != nil {
+		return err
+	}
+	var v string
+	if op == "BSTRPICKV" || op == "BSTRPICKW" {
+		sh := c.emit("lshr i64 %s, %d", rj, lsb)
+		v = c.emit("and i64 %s, %d", sh, mask)
+	} else {
+		rd, err := c.loadReg(ins.Args[3].Reg)
+		if err != nil {
+			return err
+		}
+		field := c.emit("and i64 %s, %d", rj, mask)
+		field = c.emit("shl i64 %s, %d", field, lsb)
+		keep := c.emit("and i64 %s, %d", rd, ^(mask << lsb))
+		v = c.emit("or i64 %s, %s", keep, field)
+	}
+	if width == 32 {
+		v = c.narrow(v, 32, true)
+	}
+	return c.storeReg(ins.Args[3].Reg, v)
+}
+
+// lowerCRC lowers "CRCWBW rk, rj, rd", which folds the low byte (H, W:
+// halfword, word; V: doubleword) of rj into the CRC-32 in rk; the CRCC
+// forms use the Castagnoli polynomial. The result is sign-extended.
+func (c *loong64Ctx) lowerCRC(op string, ins Instr) error {
+	if len(ins.Args) != 3 {
+		return fmt.Errorf("loong64 %s expects 3 registers: %q", op, ins.Raw)
+	}
+	for _, a := range ins.Args {
+		if !loong64IsRReg(a) {
+			return fmt.Errorf("loong64 %s expects R registers: %q", op, ins.Raw)
+		}
+	}
+	crc, err := c.loadReg(ins.Args[0].Reg)
+	if err != nil {
+		return err
+	}
+	data, err := c.loadReg(ins.Args[1].Reg)
+	if err != nil {
+		return err
+	}
+	kind := "crc"
+	if strings.HasPrefix(op, "CRCC") {
+		kind = "crcc"
+	}
+	width := map[byte]string{'B': "b", 'H': "h", 'W': "w", 'V': "d"}[op[len(op)-2]]
+	dataTy := "i32"
+	if width == "d" {
+		dataTy = "i64"
+	} else {
+		data = c.trunc32(data)
+	}
+	v := c.emit("call i32 @llvm.loongarch.%s.w.%s.w(%s %s, i32 %s)", kind, width, dataTy, data, c.trunc32(crc))
+	return c.storeReg(ins.Args[2].Reg, c.extend(v, 32, true))
+}
+
+// loong64UnaryOps lists the "OP rj, rd" operations; NEGV and NEGW may also
+// name a single register, which is both source and destination.
+var loong64UnaryOps = map[string]bool{
+	"NEGV": true, "NEGW": true,
+	"CLOW": true, "CLZW": true, "CTOW": true, "CTZW": true,
+	"CLOV": true, "CLZV": true, "CTOV": true, "CTZV": true,
+	"REVB2H": true, "REVB4H": true, "REVB2W": true, "REVBV": true,
+	"REVH2W": true, "REVHV": true,
+	"BITREV4B": true, "BITREV8B": true, "BITREVW": true, "BITREVV": true,
+	"EXTWB": true, "EXTWH": true,
+}
+
+func (c *loong64Ctx) lowerUnary(op string, ins Instr) error {
+	oneReg := op == "NEGV" || op == "NEGW"
+	if !(len(ins.Args) == 2 || len(ins.Args) == 1 && oneReg) {
+		return fmt.Errorf("loong64 %s expects reg, reg: %q", op, ins.Raw)
+	}
+	for _, a := range ins.Args {
+		if !loong64IsRReg(a) {
+			return fmt.Errorf("loong64 %s expects R registers: %q", op, ins.Raw)
+		}
+	}
+	a, err := c.loadReg(ins.Args[0].Reg)
+	if err != nil {
+		return err
+	}
+	var v string
+	switch op {
+	case "NEGV":
+		v = c.emit("sub i64 0, %s", a)
+	case "NEGW":
+		v = c.extend(c.emit("sub i32 0, %s", c.trunc32(a)), 32, true)
+	case "CLZV", "CTZV":
+		v = c.bitCount(op[:3], "i64", a)
+	case "CLOV", "CTOV":
+		v = c.bitCount(op[:3], "i64", c.emit("xor i64 %s, -1", a))
+	case "CLZW", "CTZW":
+		v = c.extend(c.bitCount(op[:3], "i32", c.trunc32(a)), 32, false)
+	case "CLOW", "CTOW":
+		inv := c.emit("xor i32 %s, -1", c.trunc32(a))
+		v = c.extend(c.bitCount(op[:3], "i32", inv), 32, false)
+	case "REVBV":
+		v = c.emit("call i64 @llvm.bswap.i64(i64 %s)", a)
+	case "REVB2W":
+		// Byte-swap each word: swap the whole register, then its words.
+		s := c.emit("call i64 @llvm.bswap.i64(i64 %s)", a)
+		v = c.emit("call i64 @llvm.fshr.i64(i64 %s, i64 %s, i64 32)", s, s)
+	case "REVB2H":
+		v = c.narrow(c.swapLanes(a, 8, 16), 32, true)
+	case "REVB4H":
+		v = c.swapLanes(a, 8, 16)
+	case "REVH2W":
+		v = c.swapLanes(a, 16, 32)
+	case "REVHV":
+		// Reverse the four halfwords: swap halves within words, then words.
+		s := c.swapLanes(a, 16, 32)
+		v = c.emit("call i64 @llvm.fshr.i64(i64 %s, i64 %s, i64 32)", s, s)
+	case "BITREVV":
+		v = c.emit("call i64 @llvm.bitreverse.i64(i64 %s)", a)
+	case "BITREVW":
+		r := c.emit("call i32 @llvm.bitreverse.i32(i32 %s)", c.trunc32(a))
+		v = c.extend(r, 32, true)
+	case "BITREV8B", "BITREV4B":
+		// Reverse the bits of each byte: reversing all bits also reverses
+		// the byte order, which a byte swap undoes.
+		r := c.emit("call i64 @llvm.bitreverse.i64(i64 %s)", a)
+		v = c.emit("call i64 @llvm.bswap.i64(i64 %s)", r)
+		if op == "BITREV4B" {
+			v = c.narrow(v, 32, true)
+		}
+	case "EXTWB":
+		v = c.narrow(a, 8, true)
+	case "EXTWH":
+		v = c.narrow(a, 16, true)
+	}
+	return c.storeReg(ins.Args[len(ins.Args)-1].Reg, v)
+}
+
+// swapLanes swaps adjacent lane-bit fields inside every group-bit field
+// of v: ((v >> lane) & m) | ((v & m) << lane).
+func (c *loong64Ctx) swapLanes(v string, lane, group int) string {
+	var m uint64
+	for i := 0; i < 64; i += group {
+		m |= (uint64(1)<<lane - 1) << i
+	}
+	hi := c.emit("lshr i64 %s, %d", v, lane)
+	hi = c.emit("and i64 %s, %d", hi, int64(m))
+	lo := c.emit("and i64 %s, %d", v, int64(m))
+	lo = c.emit("shl i64 %s, %d", lo, lane)
+	return c.emit("or i64 %s, %s", hi, lo)
+}
+
+func (c *loong64Ctx) bitCount(op, ty, a string) string {
+	if op == "CLZ" || op == "CLO" {
+		return c.emit("call %s @llvm.ctlz.%s(%s %s, i1 false)", ty, ty, ty, a)
+	}
+	return c.emit("call %s @llvm.cttz.%s(%s %s, i1 false)", ty, ty, ty, a)
+}
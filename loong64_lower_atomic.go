@@ -0,0 +1,178 @@
+package plan9asm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// loong64AMOps maps the AM<op> base names to atomicrmw operations; the U
+// forms of MAX and MIN compare unsigned. AMCAS is lowered to a cmpxchg.
+var loong64AMOps = map[string]string{
+	"SWAP": "xchg",
+	"ADD":  "add",
+	"AND":  "and",
+	"OR":   "or",
+	"XOR":  "xor",
+	"MAX":  "max",
+	"MAXU": "umax",
+	"MIN":  "min",
+	"MINU": "umin",
+}
+
+var loong64AtomicBits = map[byte]int{'B': 8, 'H': 16, 'W': 32, 'V': 64}
+
+// loong64SplitAM splits AM<op>[DB]<width>[U] into the operation (with the
+// U suffix moved onto it) and the access width. The DB ("data barrier")
+// forms only add ordering, which seq_cst already provides.
+func loong64SplitAM(op string) (base string, bits int, ok bool) {
+	if !strings.HasPrefix(op, "AM") {
+		return "", 0, false
+	}
+	s := op[len("AM"):]
+	unsigned := strings.HasSuffix(s, "U")
+	if unsigned {
+		s = s[:len(s)-1]
+	}
+	if s == "" {
+		return "", 0, false
+	}
+	bits, ok = loong64AtomicBits[s[len(s)-1]]
+	if !ok {
+		return "", 0, false
+	}
+	base = strings.TrimSuffix(s[:len(s)-1], "DB")
+	if unsigned {
+		base += "U"
+	}
+	if _, known := loong64AMOps[base]; !known && base != "CAS" {
+		return "", 0, false
+	}
+	return base, bits, true
+}
+
+func (c *loong64Ctx) lowerAtomic(op string, ins Instr) (ok bool, terminated bool, err error) {
+	switch op {
+	case "DBAR":
+		// The hint only weakens the barrier; a full fence is always valid.
+		c.b.WriteString("  fence seq_cst\n")
+		return true, false, nil
+	case "LL", "LLW", "LLV":
+		return true, false, c.lowerLL(op, ins)
+	case "SC", "SCW", "SCV":
+		return true, false, c.lowerSC(op, ins)
+	}
+	base, bits, ok := loong64SplitAM(op)
+	if !ok {
+		return false, false, nil
+	}
+	ty := fmt.Sprintf("i%d", bits)
+
+	// AMADDDBW rk, (rj), rd: rd = old value (sign-extended), memory =
+	// old OP rk. AMCAS compares memory with rd and stores rk on a match.
+	if len(ins.Args) != 3 || !loong64IsRReg(ins.Args[0]) || ins.Args[1].Kind != OpMem || !loong64IsRReg(ins.Args[2]) {
+		return true, false, fmt.Errorf("loong64 %s expects reg, (reg), reg: %q", op, ins.Raw)
+	}
+	src, err := c.loadReg(ins.Args[0].Reg)
+	if err != nil {
+		return true, false, err
+	}
+	ptr, err := c.memPtr(ins.Args[1].Mem)
+	if err != nil {
+		return true, false, err
+	}
+	narrow := func(v string) string {
+		if bits == 64 {
+			return v
+		}
+		return c.emit("trunc i64 %s to %s", v, ty)
+	}
+	var old string
+	if base == "CAS" {
+		expected, err := c.loadReg(ins.Args[2].Reg)
+		if err != nil {
+			return true, false, err
+		}
+		cx := c.emit("cmpxchg ptr %s, %s %s, %s %s seq_cst seq_cst, align %d", ptr, ty, narrow(expected), ty, narrow(src), bits/8)
+		old = c.emit("extractvalue {%s, i1} %s, 0", ty, cx)
+	} else {
+		old = c.emit("atomicrmw %s ptr %s, %s %s seq_cst, align %d", loong64AMOps[base], ptr, ty, narrow(src), bits/8)
+	}
+	if bits < 64 {
+		old = c.extend(old, bits, true)
+	}
+	return true, false, c.storeReg(ins.Args[2].Reg, old)
+}
+
+// lowerLL lowers "LL (rj), rd", which loads and reserves rj; LL and LLW
+// load a sign-extended word and LLV a doubleword.
+func (c *loong64Ctx) lowerLL(op string, ins Instr) error {
+	if len(ins.Args) != 2 || ins.Args[0].Kind != OpMem || !loong64IsRReg(ins.Args[1]) {
+		return fmt.Errorf("loong64 %s expects (reg), reg: %q", op, ins.Raw)
+	}
+	ty, bits := "i32", 32
+	if op == "LLV" {
+		ty, bits = "i64", 64
+	}
+	ptr, err := c.memPtr(ins.Args[0].Mem)
+	if err != nil {
+		return err
+	}
+	v := c.emit("load atomic %s, ptr %s seq_cst, align %d", ty, ptr, bits/8)
+	if bits == 32 {
+		v = c.extend(v, 32, true)
+	}
+	fmt.Fprintf(c.b, "  store i1 true, ptr %s\n", c.reservedValidSlot)
+	fmt.Fprintf(c.b, "  store ptr %s, ptr %s\n", ptr, c.reservedPtrSlot)
+	fmt.Fprintf(c.b, "  store i64 %s, ptr %s\n", v, c.reservedValueSlot)
+	return c.storeReg(ins.Args[1].Reg, v)
+}
+
+// lowerSC lowers "SC rd, (rj)", which stores rd if the reservation still
+// holds and then sets rd to 1 on success and 0 on failure. The reservation
+// is modeled as the value LL loaded, so the store is a cmpxchg against it.
+func (c *loong64Ctx) lowerSC(op string, ins Instr) error {
+	if len(ins.Args) != 2 || !loong64IsRReg(ins.Args[0]) || ins.Args[1].Kind != OpMem {
+		return fmt.Errorf("loong64 %s expects reg, (reg): %q", op, ins.Raw)
+	}
+	ty, bits := "i32", 32
+	if op == "SCV" {
+		ty, bits = "i64", 64
+	}
+	src, err := c.loadReg(ins.Args[0].Reg)
+	if err != nil {
+		return err
+	}
+	ptr, err := c.memPtr(ins.Args[1].Mem)
+	if err != nil {
+		return err
+	}
+	valid := c.emit("load i1, ptr %s", c.reservedValidSlot)
+	resPtr := c.emit("load ptr, ptr %s", c.reservedPtrSlot)
+	same := c.emit("icmp eq ptr %s, %s", resPtr, ptr)
+	canTry := c.emit("and i1 %s, %s", valid, same)
+
+	id := c.newTmp()
+	tryLabel := "sc_try_" + id
+	failLabel := "sc_fail_" + id
+	mergeLabel := "sc_merge_" + id
+	fmt.Fprintf(c.b, "  br i1 %s, label %%%s, label %%%s\n", canTry, tryLabel, failLabel)
+
+	fmt.Fprintf(c.b, "\n%s:\n", tryLabel)
+	expected := c.emit("load i64, ptr %s", c.reservedValueSlot)
+	newv := src
+	if bits == 32 {
+		expected, newv = c.trunc32(expected), c.trunc32(src)
+	}
+	cx := c.emit("cmpxchg ptr %s, %s %s, %s %s seq_cst seq_cst, align %d", ptr, ty, expected, ty, newv, bits/8)
+	stored := c.emit("extractvalue {%s, i1} %s, 1", ty, cx)
+	tryStatus := c.emit("zext i1 %s to i64", stored)
+	fmt.Fprintf(c.b, "  br label %%%s\n", mergeLabel)
+
+	fmt.Fprintf(c.b, "\n%s:\n", failLabel)
+	fmt.Fprintf(c.b, "  br label %%%s\n", mergeLabel)
+
+	fmt.Fprintf(c.b, "\n%s:\n", mergeLabel)
+	status := c.emit("phi i64 [ %s, %%%s ], [ 0, %%%s ]", tryStatus, tryLabel, failLabel)
+	fmt.Fprintf(c.b, "  store i1 false, ptr %s\n", c.reservedValidSlot)
+	return c.storeReg(ins.Args[0].Reg, status)
+}
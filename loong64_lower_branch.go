@@ -0,0 +1,323 @@
+package plan9asm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// loong64BranchConds maps the conditional branches to icmp predicates.
+// "BLT rj, rd, label" branches if rj < rd; with a single register the
+// comparison is against zero (R0). BLEZ, BGTZ, BLTZ and BGEZ take a single
+// register only.
+var loong64BranchConds = map[string]string{
+	"BEQ": "eq", "BNE": "ne", "BLT": "slt", "BLTU": "ult", "BGE": "sge", "BGEU": "uge",
+	"BLEZ": "sle", "BGTZ": "sgt", "BLTZ": "slt", "BGEZ": "sge",
+}
+
+// branchTarget resolves a label, local symbol or n(PC) operand of the
+// instruction at index ii of block bi to a block name.
+func (c *loong64Ctx) branchTarget(bi, ii int, op Operand) (string, bool) {
+	switch op.Kind {
+	case OpIdent:
+		return op.Ident, true
+	case OpSym:
+		s := strings.TrimSpace(op.Sym)
+		if strings.HasSuffix(s, "(SB)") {
+			return "", false
+		}
+		return strings.TrimSuffix(s, "<>"), s != ""
+	case OpMem:
+		if op.Mem.Base != PC {
+			return "", false
+		}
+		tbi, ok := c.blockByIdx[c.blockBase[bi]+ii+int(op.Mem.Off)]
+		if !ok {
+			return "", false
+		}
+		return c.blocks[tbi].name, true
+	}
+	return "", false
+}
+
+func (c *loong64Ctx) condBr(bi int, cond, tgt string) {
+	fmt.Fprintf(c.b, "  br i1 %s, label %%%s, label %%%s\n", cond, arm64LLVMBlockName(tgt), arm64LLVMBlockName(c.blocks[bi+1].name))
+}
+
+func (c *loong64Ctx) lowerBranch(bi, ii int, op string, ins Instr) (ok bool, terminated bool, err error) {
+	if pred, found := loong64BranchConds[op]; found {
+		nregs := len(ins.Args) - 1
+		if strings.HasSuffix(op, "Z") && nregs != 1 || nregs < 1 || nregs > 2 {
+			return true, false, fmt.Errorf("loong64 %s expects registers and a target: %q", op, ins.Raw)
+		}
+		for _, a := range ins.Args[:nregs] {
+			if !loong64IsRReg(a) {
+				return true, false, fmt.Errorf("loong64 %s expects R registers: %q", op, ins.Raw)
+			}
+		}
+		tgt, ok := c.branchTarget(bi, ii, ins.Args[nregs])
+		if !ok {
+			return true, false, fmt.Errorf("loong64 %s invalid target: %q", op, ins.Raw)
+		}
+		if bi+1 >= len(c.blocks) {
+			return true, false, fmt.Errorf("loong64 %s needs fallthrough block: %q", op, ins.Raw)
+		}
+		a, err := c.loadReg(ins.Args[0].Reg)
+		if err != nil {
+			return true, false, err
+		}
+		b := "0"
+		if nregs == 2 {
+			if b, err = c.loadReg(ins.Args[1].Reg); err != nil {
+				return true, false, err
+			}
+		}
+		c.condBr(bi, c.emit("icmp %s i64 %s, %s", pred, a, b), tgt)
+		return true, true, nil
+	}
+
+	switch op {
+	case "BFPT", "BFPF":
+		// BFPT [FCCn,] label branches if FCCn (FCC0 by default) is set.
+		fcc := Operand{Kind: OpReg, Reg: "FCC0"}
+		if len(ins.Args) == 2 {
+			if fcc = ins.Args[0]; !loong64IsFCCOp(fcc) {
+				return true, false, fmt.Errorf("loong64 %s expects [FCC,] target: %q", op, ins.Raw)
+			}
+		} else if len(ins.Args) != 1 {
+			return true, false, fmt.Errorf("loong64 %s expects [FCC,] target: %q", op, ins.Raw)
+		}
+		tgt, ok := c.branchTarget(bi, ii, ins.Args[len(ins.Args)-1])
+		if !ok {
+			return true, false, fmt.Errorf("loong64 %s invalid target: %q", op, ins.Raw)
+		}
+		if bi+1 >= len(c.blocks) {
+			return true, false, fmt.Errorf("loong64 %s needs fallthrough block: %q", op, ins.Raw)
+		}
+		v, err := c.loadReg(fcc.Reg)
+		if err != nil {
+			return true, false, err
+		}
+		pred := "ne"
+		if op == "BFPF" {
+			pred = "eq"
+		}
+		c.condBr(bi, c.emit("icmp %s i64 %s, 0", pred, v), tgt)
+		return true, true, nil
+
+	case "JMP":
+		if len(ins.Args) != 1 {
+			return true, false, fmt.Errorf("loong64 JMP expects 1 operand: %q", ins.Raw)
+		}
+		return true, true, c.jump(bi, ii, ins.Args[0], ins)
+
+	case "CALL", "JAL":
+		// JAL sym(SB) and JAL (reg) call through R1, which
+		// "JAL R1, target" names explicitly.
+		if len(ins.Args) == 2 && ins.Args[0].Kind == OpReg && ins.Args[0].Reg == "R1" {
+			ins.Args = ins.Args[1:]
+		}
+		if len(ins.Args) != 1 {
+			return true, false, fmt.Errorf("loong64 %s expects 1 operand: %q", op, ins.Raw)
+		}
+		return true, false, c.call(ins.Args[0], ins)
+	}
+	return false, false, nil
+}
+
+func (c *loong64Ctx) jump(bi, ii int, target Operand, ins Instr) error {
+	if riscv64IsSBSym(target) {
+		return c.tailCallAndRet(target)
+	}
+	if loong64IsRReg(target) {
+		target = Operand{Kind: OpMem, Mem: MemRef{Base: target.Reg}}
+	}
+	if target.Kind == OpMem && target.Mem.Base != PC {
+		addr, err := c.addrI64(target.Mem)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(c.b, "  call void asm sideeffect %q, %q(i64 %s)\n", "jr $0", "r,~{memory}", addr)
+		c.lowerRetZero()
+		return nil
+	}
+	tgt, ok := c.branchTarget(bi, ii, target)
+	if !ok {
+		return fmt.Errorf("loong64 %s invalid target: %q", ins.Op, ins.Raw)
+	}
+	c.br(tgt)
+	return nil
+}
+
+func (c *loong64Ctx) call(target Operand, ins Instr) error {
+	if riscv64IsSBSym(target) {
+		return c.callSym(target)
+	}
+	if loong64IsRReg(target) {
+		target = Operand{Kind: OpMem, Mem: MemRef{Base: target.Reg}}
+	}
+	if target.Kind != OpMem || target.Mem.Base == PC {
+		return fmt.Errorf("loong64 %s expects symbol(SB)|reg|(reg): %q", ins.Op, ins.Raw)
+	}
+	addr, err := c.addrI64(target.Mem)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(c.b, "  call void asm sideeffect %q, %q(i64 %s)\n", "jirl $$ra, $0, 0", "r,~{$r1},~{memory}", addr)
+	return nil
+}
+
+// callArgs reads csig's arguments from the ABIInternal registers (or
+// csig.ArgRegs), assembling aggregates from consecutive registers.
+func (c *loong64Ctx) callArgs(csig FuncSig) ([]string, error) {
+	args := make([]string, 0, len(csig.Args))
+	var cur loong64ArgCursor
+	for i, argTy := range csig.Args {
+		if len(csig.ArgRegs) > 0 {
+			if i >= len(csig.ArgRegs) {
+				return nil, fmt.Errorf("no register for arg %d", i)
+			}
+			v, err := c.loadReg(csig.ArgRegs[i])
+			if err != nil {
+				return nil, err
+			}
+			val, err := c.i64ToValue(v, argTy)
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, fmt.Sprintf("%s %s", argTy, val))
+			continue
+		}
+		fields, isAgg := parseLiteralStructFields(argTy)
+		if !isAgg {
+			fields = []LLVMType{argTy}
+		}
+		agg := "undef"
+		val := ""
+		for fi, fTy := range fields {
+			r, ok := cur.next(fTy)
+			if !ok {
+				return nil, fmt.Errorf("too many register args")
+			}
+			v, err := c.loadReg(r)
+			if err != nil {
+				return nil, err
+			}
+			if val, err = c.i64ToValue(v, fTy); err != nil {
+				return nil, err
+			}
+			if isAgg {
+				agg = c.emit("insertvalue %s %s, %s %s, %d", argTy, agg, fTy, val, fi)
+				val = agg
+			}
+		}
+		args = append(args, fmt.Sprintf("%s %s", argTy, val))
+	}
+	return args, nil
+}
+
+// storeCallResult writes a call's result to the ABIInternal result
+// registers.
+func (c *loong64Ctx) storeCallResult(ty LLVMType, v string) error {
+	fields, isAgg := parseLiteralStructFields(ty)
+	if !isAgg {
+		fields = []LLVMType{ty}
+	}
+	var cur loong64ArgCursor
+	for fi, fTy := range fields {
+		r, ok := cur.next(fTy)
+		if !ok {
+			return fmt.Errorf("too many register results")
+		}
+		fv := v
+		if isAgg {
+			fv = c.emit("extractvalue %s %s, %d", ty, v, fi)
+		}
+		v64, ok, err := c.valueAsI64(fTy, fv)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("unsupported result type %s", fTy)
+		}
+		if err := c.storeReg(r, v64); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *loong64Ctx) callSym(symOp Operand) error {
+	callee := c.resolve(strings.TrimSuffix(strings.TrimSpace(symOp.Sym), "(SB)"))
+	// Syscall stubs invoke runtime entersyscall/exitsyscall around SYSCALL.
+	// llgo runtime does not require these scheduler hooks at this layer.
+	if callee == "runtime.entersyscall" || callee == "runtime.exitsyscall" {
+		return nil
+	}
+	csig, ok := c.sigs[callee]
+	if !ok {
+		csig = FuncSig{Name: callee, Ret: Void}
+	}
+	args, err := c.callArgs(csig)
+	if err != nil {
+		return fmt.Errorf("loong64 call %q: %v", callee, err)
+	}
+	if csig.Ret == Void {
+		fmt.Fprintf(c.b, "  call void %s(%s)\n", llvmGlobal(callee), strings.Join(args, ", "))
+		return nil
+	}
+	r := c.emit("call %s %s(%s)", csig.Ret, llvmGlobal(callee), strings.Join(args, ", "))
+	if err := c.storeCallResult(csig.Ret, r); err != nil {
+		return fmt.Errorf("loong64 call %q: %v", callee, err)
+	}
+	return nil
+}
+
+func (c *loong64Ctx) tailCallAndRet(symOp Operand) error {
+	callee := c.resolve(strings.TrimSuffix(strings.TrimSpace(symOp.Sym), "(SB)"))
+	csig, ok := c.sigs[callee]
+	if !ok {
+		// Cross-package trampoline (e.g. sync/atomic -> internal/runtime/atomic).
+		// Without an explicit signature, fall back to the caller's.
+		csig = c.sig
+		csig.Name = callee
+	}
+
+	// A JMP to a function with the caller's signature is an ABI0 tail call
+	// made before any register shuffling, so pass the caller's own args.
+	var args []string
+	if len(csig.ArgRegs) == 0 && sameLLVMTypes(csig.Args, c.sig.Args) && csig.Ret == c.sig.Ret {
+		for i, ty := range csig.Args {
+			args = append(args, fmt.Sprintf("%s %%arg%d", ty, i))
+		}
+	} else {
+		var err error
+		if args, err = c.callArgs(csig); err != nil {
+			return fmt.Errorf("loong64 tailcall %q: %v", callee, err)
+		}
+	}
+
+	if csig.Ret == Void {
+		fmt.Fprintf(c.b, "  call void %s(%s)\n", llvmGlobal(callee), strings.Join(args, ", "))
+		if len(c.fpResults) > 0 {
+			return c.lowerRET()
+		}
+		c.lowerRetZero()
+		return nil
+	}
+	r := c.emit("call %s %s(%s)", csig.Ret, llvmGlobal(callee), strings.Join(args, ", "))
+	if c.sig.Ret == Void {
+		c.b.WriteString("  ret void\n")
+		return nil
+	}
+	if csig.Ret != c.sig.Ret {
+		v64, ok, err := c.valueAsI64(csig.Ret, r)
+		if err != nil || !ok {
+			return fmt.Errorf("loong64 tailcall return mismatch: caller %s callee %s", c.sig.Ret, csig.Ret)
+		}
+		if r, err = c.i64ToValue(v64, c.sig.Ret); err != nil {
+			return fmt.Errorf("loong64 tailcall return mismatch: caller %s callee %s", c.sig.Ret, csig.Ret)
+		}
+	}
+	fmt.Fprintf(c.b, "  ret %s %s\n", c.sig.Ret, r)
+	return nil
+}
@@ -0,0 +1,153 @@
+package plan9asm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// loong64MovWidth gives the access width and signedness of the MOV forms.
+// MOVF and MOVD move F register bits; MOVWP and MOVVP are the ldptr/stptr
+// encodings of MOVW and MOVV.
+var loong64MovWidth = map[string]struct {
+	bits   int
+	signed bool
+	float  bool
+}{
+	"MOVV":  {64, true, false},
+	"MOVVP": {64, true, false},
+	"MOVW":  {32, true, false},
+	"MOVWP": {32, true, false},
+	"MOVWU": {32, false, false},
+	"MOVH":  {16, true, false},
+	"MOVHU": {16, false, false},
+	"MOVB":  {8, true, false},
+	"MOVBU": {8, false, false},
+	"MOVF":  {32, false, true},
+	"MOVD":  {64, false, true},
+}
+
+func (c *loong64Ctx) lowerData(op string, ins Instr) (ok bool, terminated bool, err error) {
+	w, found := loong64MovWidth[op]
+	if !found {
+		return false, false, nil
+	}
+	if len(ins.Args) != 2 {
+		return true, false, fmt.Errorf("loong64 %s expects 2 operands: %q", op, ins.Raw)
+	}
+	src, dst := ins.Args[0], ins.Args[1]
+	isF, isFCC := loong64IsFRegOp, loong64IsFCCOp
+	signed := w.signed
+	switch {
+	case w.float:
+		// MOVF and MOVD move between F registers and memory.
+		if (!isF(src) && !isF(dst)) || src.Kind == OpImm || loong64IsRReg(src) || loong64IsRReg(dst) || isFCC(src) || isFCC(dst) {
+			return true, false, fmt.Errorf("loong64 %s moves between F registers and memory: %q", op, ins.Raw)
+		}
+	case isF(src) || isF(dst) || isFCC(src) || isFCC(dst):
+		// MOVV and MOVW also move bits between R, F and FCC registers
+		// (movgr2fr, movfr2gr, movcf2gr, ...); MOVW F, R sign-extends the
+		// low word.
+		if src.Kind != OpReg || dst.Kind != OpReg || (op != "MOVV" && op != "MOVW") {
+			return true, false, fmt.Errorf("loong64 %s: bad F register move: %q", op, ins.Raw)
+		}
+		signed = true
+	}
+	if src.Kind != OpReg && dst.Kind != OpReg {
+		// Only zero can be stored without a register.
+		if src.Kind != OpImm || src.Imm != 0 {
+			return true, false, fmt.Errorf("loong64 %s needs a register operand: %q", op, ins.Raw)
+		}
+	}
+
+	v, err := c.movSrc(src, w.bits, signed)
+	if err != nil {
+		return true, false, fmt.Errorf("loong64 %s: %v: %q", op, err, ins.Raw)
+	}
+	if isFCC(dst) {
+		v = c.emit("and i64 %s, 1", v)
+	}
+	if err := c.movDst(dst, w.bits, v); err != nil {
+		return true, false, fmt.Errorf("loong64 %s: %v: %q", op, err, ins.Raw)
+	}
+	return true, false, nil
+}
+
+// movSrc reads a MOV source as an i64, extended from bits.
+func (c *loong64Ctx) movSrc(src Operand, bits int, signed bool) (string, error) {
+	switch src.Kind {
+	case OpImm:
+		v := src.Imm
+		switch {
+		case bits == 64:
+		case signed:
+			v = v << (64 - bits) >> (64 - bits)
+		default:
+			v = int64(uint64(v) << (64 - bits) >> (64 - bits))
+		}
+		return fmt.Sprintf("%d", v), nil
+	case OpReg:
+		v, err := c.loadReg(src.Reg)
+		if err != nil {
+			return "", err
+		}
+		return c.narrow(v, bits, signed), nil
+	case OpMem:
+		return c.loadMem(src.Mem, bits, signed)
+	case OpFP:
+		v, err := c.evalFPValue64(src)
+		if err != nil {
+			return "", err
+		}
+		return c.narrow(v, bits, signed), nil
+	case OpFPAddr:
+		return c.evalFPAddr64(src)
+	case OpSym:
+		s := strings.TrimSpace(src.Sym)
+		if strings.HasPrefix(s, "$") && (strings.HasSuffix(s, "(SB)") || strings.HasSuffix(s, "(FP)")) {
+			return c.symAddr(s)
+		}
+		if !strings.HasSuffix(s, "(SB)") {
+			break
+		}
+		p, err := c.ptrFromSB(s)
+		if err != nil {
+			return "", err
+		}
+		t := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = load i%d, ptr %s\n", t, bits, p)
+		if bits == 64 {
+			return "%" + t, nil
+		}
+		return c.extend("%"+t, bits, signed), nil
+	}
+	return "", fmt.Errorf("unsupported source %s", src.String())
+}
+
+// movDst writes the low bits of v to a MOV destination.
+func (c *loong64Ctx) movDst(dst Operand, bits int, v string) error {
+	switch dst.Kind {
+	case OpReg:
+		return c.storeReg(dst.Reg, v)
+	case OpMem:
+		return c.storeMem(dst.Mem, bits, v)
+	case OpFP:
+		return c.storeFPResult64(dst.FPOffset, v)
+	case OpSym:
+		s := strings.TrimSpace(dst.Sym)
+		if strings.HasPrefix(s, "$") || !strings.HasSuffix(s, "(SB)") {
+			break
+		}
+		p, err := c.ptrFromSB(s)
+		if err != nil {
+			return err
+		}
+		if bits < 64 {
+			t := c.newTmp()
+			fmt.Fprintf(c.b, "  %%%s = trunc i64 %s to i%d\n", t, v, bits)
+			v = "%" + t
+		}
+		fmt.Fprintf(c.b, "  store i%d %s, ptr %s\n", bits, v, p)
+		return nil
+	}
+	return fmt.Errorf("unsupported destination %s", dst.String())
+}
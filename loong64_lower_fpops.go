@@ -0,0 +1,312 @@
+package plan9asm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// loong64FPType returns the LLVM type and intrinsic suffix for an F
+// (single) or D (double) precision opcode suffix.
+func loong64FPType(prec byte) (ty, suffix string) {
+	if prec == 'F' {
+		return "float", "f32"
+	}
+	return "double", "f64"
+}
+
+func (c *loong64Ctx) loadF(r Reg, ty string) (string, error) {
+	v, err := c.loadReg(r)
+	if err != nil {
+		return "", err
+	}
+	return c.i64ToValue(v, LLVMType(ty))
+}
+
+func (c *loong64Ctx) storeF(r Reg, ty, v string) error {
+	v64, _, err := c.valueAsI64(LLVMType(ty), v)
+	if err != nil {
+		return err
+	}
+	return c.storeReg(r, v64)
+}
+
+// loong64CheckFRegs reports whether ins has exactly n operands, all of
+// them F registers.
+func loong64CheckFRegs(ins Instr, n int) bool {
+	if len(ins.Args) != n {
+		return false
+	}
+	for _, a := range ins.Args {
+		if !loong64IsFRegOp(a) {
+			return false
+		}
+	}
+	return true
+}
+
+// loong64RoundFns maps the rounding part of the FTINT and conversion
+// mnemonics to the LLVM intrinsic applied before truncating. The bare
+// forms round in the current mode, which Go leaves at round-to-nearest.
+var loong64RoundFns = map[string]string{
+	"": "roundeven", "RNE": "roundeven", "RM": "floor", "RP": "ceil", "RZ": "",
+}
+
+func (c *loong64Ctx) lowerFP(op string, ins Instr) (ok bool, terminated bool, err error) {
+	if ok, err := c.lowerFPConv(op, ins); ok {
+		return true, false, err
+	}
+	if op == "FSEL" {
+		return true, false, c.lowerFSEL(ins)
+	}
+	if len(op) < 4 || (op[len(op)-1] != 'D' && op[len(op)-1] != 'F') {
+		return false, false, nil
+	}
+	base, prec := op[:len(op)-1], op[len(op)-1]
+	ty, suffix := loong64FPType(prec)
+
+	switch base {
+	case "ADD", "SUB", "MUL", "DIV", "FMIN", "FMAX", "FCOPYSG":
+		// "OP fk, fj, fd" computes fd = fj OP fk; "OP fk, fd" reads fd.
+		if !loong64CheckFRegs(ins, 3) && !loong64CheckFRegs(ins, 2) {
+			return true, false, fmt.Errorf("loong64 %s expects F registers: %q", op, ins.Raw)
+		}
+		b, err := c.loadF(ins.Args[0].Reg, ty)
+		if err != nil {
+			return true, false, err
+		}
+		a, err := c.loadF(ins.Args[1].Reg, ty)
+		if err != nil {
+			return true, false, err
+		}
+		var v string
+		switch base {
+		case "FMIN", "FMAX":
+			v = c.emit("call %s @llvm.%snum.%s(%s %s, %s %s)", ty, strings.ToLower(base[1:]), suffix, ty, a, ty, b)
+		case "FCOPYSG":
+			v = c.emit("call %s @llvm.copysign.%s(%s %s, %s %s)", ty, suffix, ty, a, ty, b)
+		default:
+			v = c.emit("f%s %s %s, %s", strings.ToLower(base), ty, a, b)
+		}
+		return true, false, c.storeF(ins.Args[len(ins.Args)-1].Reg, ty, v)
+
+	case "ABS", "NEG", "SQRT", "FRINT":
+		if !loong64CheckFRegs(ins, 2) {
+			return true, false, fmt.Errorf("loong64 %s expects F, F: %q", op, ins.Raw)
+		}
+		a, err := c.loadF(ins.Args[0].Reg, ty)
+		if err != nil {
+			return true, false, err
+		}
+		var v string
+		switch base {
+		case "NEG":
+			v = c.emit("fneg %s %s", ty, a)
+		case "ABS":
+			v = c.emit("call %s @llvm.fabs.%s(%s %s)", ty, suffix, ty, a)
+		case "FRINT":
+			v = c.emit("call %s @llvm.roundeven.%s(%s %s)", ty, suffix, ty, a)
+		default:
+			v = c.emit("call %s @llvm.sqrt.%s(%s %s)", ty, suffix, ty, a)
+		}
+		return true, false, c.storeF(ins.Args[1].Reg, ty, v)
+
+	case "FMADD", "FMSUB", "FNMADD", "FNMSUB":
+		// "OP fa, fj, fk, fd": fd = ±(fj*fk ± fa).
+		if !loong64CheckFRegs(ins, 4) {
+			return true, false, fmt.Errorf("loong64 %s expects four F registers: %q", op, ins.Raw)
+		}
+		var in [3]string
+		for i := range in {
+			if in[i], err = c.loadF(ins.Args[i].Reg, ty); err != nil {
+				return true, false, err
+			}
+		}
+		if base == "FMSUB" || base == "FNMSUB" {
+			in[0] = c.emit("fneg %s %s", ty, in[0])
+		}
+		v := c.emit("call %s @llvm.fma.%s(%s %s, %s %s, %s %s)", ty, suffix, ty, in[1], ty, in[2], ty, in[0])
+		if base == "FNMADD" || base == "FNMSUB" {
+			v = c.emit("fneg %s %s", ty, v)
+		}
+		return true, false, c.storeF(ins.Args[3].Reg, ty, v)
+
+	case "CMPEQ", "CMPGT", "CMPGE":
+		// "CMPGTD fj, fk, FCCn" sets FCCn = fj > fk.
+		if len(ins.Args) != 3 || !loong64IsFRegOp(ins.Args[0]) || !loong64IsFRegOp(ins.Args[1]) || !loong64IsFCCOp(ins.Args[2]) {
+			return true, false, fmt.Errorf("loong64 %s expects F, F, FCC: %q", op, ins.Raw)
+		}
+		fcc := ins.Args[2]
+		a, err := c.loadF(ins.Args[0].Reg, ty)
+		if err != nil {
+			return true, false, err
+		}
+		b, err := c.loadF(ins.Args[1].Reg, ty)
+		if err != nil {
+			return true, false, err
+		}
+		pred := map[string]string{"CMPEQ": "oeq", "CMPGT": "ogt", "CMPGE": "oge"}[base]
+		cmp := c.emit("fcmp %s %s %s, %s", pred, ty, a, b)
+		return true, false, c.storeReg(fcc.Reg, c.emit("zext i1 %s to i64", cmp))
+
+	case "FCLASS":
+		if !loong64CheckFRegs(ins, 2) {
+			return true, false, fmt.Errorf("loong64 %s expects F, F: %q", op, ins.Raw)
+		}
+		v, err := c.loadReg(ins.Args[0].Reg)
+		if err != nil {
+			return true, false, err
+		}
+		return true, false, c.storeReg(ins.Args[1].Reg, c.fclass(prec, v))
+	}
+	return false, false, nil
+}
+
+// lowerFSEL lowers "FSEL FCCn, fa, fb, fd", which sets fd = FCCn ? fa : fb,
+// and "FSEL FCCn, fa, fd", which keeps fd when FCCn is clear.
+func (c *loong64Ctx) lowerFSEL(ins Instr) error {
+	if len(ins.Args) != 3 && len(ins.Args) != 4 {
+		return fmt.Errorf("loong64 FSEL expects FCC, F, F[, F]: %q", ins.Raw)
+	}
+	fcc := ins.Args[0]
+	if !loong64IsFCCOp(fcc) {
+		return fmt.Errorf("loong64 FSEL expects FCC, F, F[, F]: %q", ins.Raw)
+	}
+	for _, a := range ins.Args[1:] {
+		if !loong64IsFRegOp(a) {
+			return fmt.Errorf("loong64 FSEL expects FCC, F, F[, F]: %q", ins.Raw)
+		}
+	}
+	cond, err := c.loadReg(fcc.Reg)
+	if err != nil {
+		return err
+	}
+	a, err := c.loadReg(ins.Args[1].Reg)
+	if err != nil {
+		return err
+	}
+	b, err := c.loadReg(ins.Args[len(ins.Args)-2].Reg)
+	if err != nil {
+		return err
+	}
+	set := c.emit("icmp ne i64 %s, 0", cond)
+	v := c.emit("select i1 %s, i64 %s, i64 %s", set, a, b)
+	return c.storeReg(ins.Args[len(ins.Args)-1].Reg, v)
+}
+
+// fclass computes the FCLASS bit mask from the raw bits of a value:
+// sNaN, qNaN, -inf, -normal, -subnormal, -0, +inf, +normal, +subnormal, +0.
+func (c *loong64Ctx) fclass(prec byte, bits string) string {
+	ity, expBits, fracBits := "i64", 11, 52
+	v := bits
+	if prec == 'F' {
+		ity, expBits, fracBits = "i32", 8, 23
+		v = c.trunc32(bits)
+	}
+	sh := c.emit("lshr %s %s, %d", ity, v, fracBits)
+	exp := c.emit("and %s %s, %d", ity, sh, (1<<expBits)-1)
+	frac := c.emit("and %s %s, %d", ity, v, (int64(1)<<fracBits)-1)
+	neg := c.emit("icmp slt %s %s, 0", ity, v)
+	expZero := c.emit("icmp eq %s %s, 0", ity, exp)
+	expMax := c.emit("icmp eq %s %s, %d", ity, exp, (1<<expBits)-1)
+	fracZero := c.emit("icmp eq %s %s, 0", ity, frac)
+	quiet := c.emit("icmp uge %s %s, %d", ity, frac, int64(1)<<(fracBits-1))
+
+	// Class index for negative values: 2 inf, 3 normal, 4 subnormal,
+	// 5 zero; positive values add 4. NaNs are 0 (signaling) and 1 (quiet)
+	// whatever their sign.
+	sub := c.emit("select i1 %s, i64 5, i64 4", fracZero)
+	fin := c.emit("select i1 %s, i64 %s, i64 3", expZero, sub)
+	nan := c.emit("select i1 %s, i64 1, i64 0", quiet)
+	inf := c.emit("select i1 %s, i64 2, i64 %s", fracZero, nan)
+	idx := c.emit("select i1 %s, i64 %s, i64 %s", expMax, inf, fin)
+	shifted := c.emit("add i64 %s, 4", idx)
+	isNaN := c.emit("icmp ult i64 %s, 2", idx)
+	pos := c.emit("xor i1 %s, true", neg)
+	flip := c.emit("and i1 %s, %s", pos, c.emit("xor i1 %s, true", isNaN))
+	cls := c.emit("select i1 %s, i64 %s, i64 %s", flip, shifted, idx)
+	return c.emit("shl i64 1, %s", cls)
+}
+
+// lowerFPConv lowers the conversions, all of which stay in F registers:
+// MOV<to><from> between F and D (float) and W and V (integer) formats,
+// FFINT<to><from> (integer to float), FTINT[RM|RP|RZ|RNE]<to><from> and
+// TRUNC<from><to> (float to integer). Integer results saturate, NaN
+// giving 0; W results occupy the low word.
+func (c *loong64Ctx) lowerFPConv(op string, ins Instr) (ok bool, err error) {
+	var from, to, round string
+	switch {
+	case strings.HasPrefix(op, "FFINT") && len(op) == 7:
+		to, from = op[5:6], op[6:7]
+	case strings.HasPrefix(op, "FTINT"):
+		spec := op[len("FTINT"):]
+		if len(spec) < 2 {
+			return false, nil
+		}
+		round, to, from = spec[:len(spec)-2], spec[len(spec)-2:len(spec)-1], spec[len(spec)-1:]
+	case strings.HasPrefix(op, "TRUNC") && len(op) == 7:
+		from, to, round = op[5:6], op[6:7], "RZ"
+	case strings.HasPrefix(op, "MOV") && len(op) == 5:
+		from, to = op[3:4], op[4:5]
+	default:
+		return false, nil
+	}
+	isFloat := func(k string) bool { return k == "F" || k == "D" }
+	isInt := func(k string) bool { return k == "W" || k == "V" }
+	fn, roundOK := loong64RoundFns[round]
+	switch {
+	case strings.HasPrefix(op, "MOV") && ((isFloat(from) && isFloat(to) && from != to) || (isFloat(from) && isInt(to)) || (isInt(from) && isFloat(to))):
+	case strings.HasPrefix(op, "FFINT") && isInt(from) && isFloat(to):
+	case (strings.HasPrefix(op, "FTINT") || strings.HasPrefix(op, "TRUNC")) && isFloat(from) && isInt(to) && roundOK:
+	default:
+		return false, nil
+	}
+	if !loong64CheckFRegs(ins, 2) {
+		return true, fmt.Errorf("loong64 %s expects F, F: %q", op, ins.Raw)
+	}
+	src, err := c.loadReg(ins.Args[0].Reg)
+	if err != nil {
+		return true, err
+	}
+	dst := ins.Args[1].Reg
+
+	if isFloat(from) && isFloat(to) {
+		fromTy, _ := loong64FPType(from[0])
+		toTy, _ := loong64FPType(to[0])
+		v, err := c.i64ToValue(src, LLVMType(fromTy))
+		if err != nil {
+			return true, err
+		}
+		cast := "fpext"
+		if to == "F" {
+			cast = "fptrunc"
+		}
+		return true, c.storeF(dst, toTy, c.emit("%s %s %s to %s", cast, fromTy, v, toTy))
+	}
+
+	if isInt(from) {
+		toTy, _ := loong64FPType(to[0])
+		iv, ity := src, "i64"
+		if from == "W" {
+			iv, ity = c.trunc32(src), "i32"
+		}
+		return true, c.storeF(dst, toTy, c.emit("sitofp %s %s to %s", ity, iv, toTy))
+	}
+
+	fromTy, suffix := loong64FPType(from[0])
+	v, err := c.i64ToValue(src, LLVMType(fromTy))
+	if err != nil {
+		return true, err
+	}
+	if fn != "" {
+		v = c.emit("call %s @llvm.%s.%s(%s %s)", fromTy, fn, suffix, fromTy, v)
+	}
+	ity := "i64"
+	if to == "W" {
+		ity = "i32"
+	}
+	r := c.emit("call %s @llvm.fptosi.sat.%s.%s(%s %s)", ity, ity, suffix, fromTy, v)
+	if ity == "i32" {
+		r = c.extend(r, 32, false)
+	}
+	return true, c.storeReg(dst, r)
+}
@@ -0,0 +1,34 @@
+package plan9asm
+
+func (c *loong64Ctx) lowerSyscall(op string, ins Instr) (ok bool, terminated bool, err error) {
+	switch op {
+	case "SYSCALL":
+		// Linux takes the trap number in R11 and the arguments in R4-R9,
+		// and returns a single result in R4.
+		s := c.cfg.syscallSite(ArchLOONG64, c.b, c.newTmp)
+		if s.Num, err = c.loadReg("R11"); err != nil {
+			return true, false, err
+		}
+		for _, r := range []Reg{"R4", "R5", "R6", "R7", "R8", "R9"} {
+			v, err := c.loadReg(r)
+			if err != nil {
+				return true, false, err
+			}
+			s.Args = append(s.Args, v)
+		}
+		res, err := c.cfg.syscallStrategy().Lower(s)
+		if err != nil {
+			return true, false, err
+		}
+		return true, false, c.storeReg("R4", res.ret(s))
+
+	case "BREAK":
+		c.b.WriteString("  call void @llvm.debugtrap()\n")
+		return true, false, nil
+
+	case "UNDEF":
+		c.b.WriteString("  call void @llvm.trap()\n  unreachable\n")
+		return true, true, nil
+	}
+	return false, false, nil
+}
@@ -0,0 +1,44 @@
+package plan9asm
+
+// loong64RegAlias maps the register names the loong64 assembler accepts
+// besides R0-R31 and F0-F31. R3 is the hardware stack pointer, so it
+// shares the SP slot.
+var loong64RegAlias = map[string]string{
+	"R3": "SP",
+	"g":  "R22",
+}
+
+// Go's ABIInternal assigns integer arguments and results to R4-R19 and
+// floating-point ones to F0-F15.
+var (
+	loong64IntArgRegs = []Reg{"R4", "R5", "R6", "R7", "R8", "R9", "R10", "R11",
+		"R12", "R13", "R14", "R15", "R16", "R17", "R18", "R19"}
+	loong64FloatArgRegs = []Reg{"F0", "F1", "F2", "F3", "F4", "F5", "F6", "F7",
+		"F8", "F9", "F10", "F11", "F12", "F13", "F14", "F15"}
+)
+
+func loong64IsFReg(r Reg) bool {
+	s := string(r)
+	return len(s) >= 2 && s[0] == 'F' && s[1] >= '0' && s[1] <= '9'
+}
+
+func loong64IsFCC(r Reg) bool {
+	s := string(r)
+	return len(s) == 4 && s[:3] == "FCC" && s[3] >= '0' && s[3] <= '7'
+}
+
+func loong64IsRReg(o Operand) bool {
+	if o.Kind != OpReg {
+		return false
+	}
+	s := string(o.Reg)
+	return o.Reg == SP || len(s) >= 2 && s[0] == 'R' && s[1] >= '0' && s[1] <= '9'
+}
+
+func loong64IsFRegOp(o Operand) bool {
+	return o.Kind == OpReg && loong64IsFReg(o.Reg)
+}
+
+func loong64IsFCCOp(o Operand) bool {
+	return o.Kind == OpReg && loong64IsFCC(o.Reg)
+}
@@ -0,0 +1,191 @@
+package plan9asm
+
+import (
+	"fmt"
+	"strings"
+)
+
+func emitLOONG64Prelude(b *strings.Builder) {
+	for _, w := range []string{"i32", "i64"} {
+		fmt.Fprintf(b, "declare %s @llvm.fshr.%s(%s, %s, %s)\n", w, w, w, w, w)
+		fmt.Fprintf(b, "declare %s @llvm.ctlz.%s(%s, i1)\n", w, w, w)
+		fmt.Fprintf(b, "declare %s @llvm.cttz.%s(%s, i1)\n", w, w, w)
+		fmt.Fprintf(b, "declare %s @llvm.bswap.%s(%s)\n", w, w, w)
+		fmt.Fprintf(b, "declare %s @llvm.bitreverse.%s(%s)\n", w, w, w)
+	}
+	for _, f := range []string{"f32", "f64"} {
+		ty := riscv64FloatTypes[f]
+		fmt.Fprintf(b, "declare %s @llvm.fma.%s(%s, %s, %s)\n", ty, f, ty, ty, ty)
+		for _, fn := range []string{"minnum", "maxnum", "copysign"} {
+			fmt.Fprintf(b, "declare %s @llvm.%s.%s(%s, %s)\n", ty, fn, f, ty, ty)
+		}
+		for _, fn := range []string{"sqrt", "fabs", "roundeven", "floor", "ceil", "trunc"} {
+			fmt.Fprintf(b, "declare %s @llvm.%s.%s(%s)\n", ty, fn, f, ty)
+		}
+		for _, w := range []string{"i32", "i64"} {
+			fmt.Fprintf(b, "declare %s @llvm.fptosi.sat.%s.%s(%s)\n", w, w, f, ty)
+		}
+	}
+	for _, kind := range []string{"crc", "crcc"} {
+		for _, w := range []string{"b", "h", "w"} {
+			fmt.Fprintf(b, "declare i32 @llvm.loongarch.%s.w.%s.w(i32, i32)\n", kind, w)
+		}
+		fmt.Fprintf(b, "declare i32 @llvm.loongarch.%s.w.d.w(i64, i32)\n", kind)
+	}
+	b.WriteString("declare i64 @llvm.readcyclecounter()\n")
+	b.WriteString("declare void @llvm.debugtrap()\n")
+	b.WriteString("declare void @llvm.trap()\n")
+	b.WriteString("\n")
+}
+
+func translateFuncLOONG64(b *strings.Builder, fn Func, sig FuncSig, resolve func(string) string, sigs map[string]FuncSig, cfg lowerConfig) error {
+	fmt.Fprintf(b, "define %s %s(", sig.Ret, llvmGlobal(sig.Name))
+	for i, t := range sig.Args {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		fmt.Fprintf(b, "%s %%arg%d", t, i)
+	}
+	b.WriteString(")")
+	if sig.Attrs != "" {
+		b.WriteString(" " + sig.Attrs)
+	}
+	b.WriteString(" {\n")
+
+	c := newLOONG64Ctx(b, fn, sig, resolve, sigs, cfg)
+	if err := c.emitEntryAllocasAndArgInit(); err != nil {
+		return err
+	}
+	if err := c.lowerBlocks(); err != nil {
+		return err
+	}
+
+	b.WriteString("}\n")
+	return nil
+}
+
+func (c *loong64Ctx) br(target string) {
+	fmt.Fprintf(c.b, "  br label %%%s\n", arm64LLVMBlockName(target))
+}
+
+func (c *loong64Ctx) lowerBlocks() error {
+	// The allocas get a block of their own so that a branch back to the
+	// first instruction stays valid.
+	c.br(c.blocks[0].name)
+	for bi, blk := range c.blocks {
+		fmt.Fprintf(c.b, "\n%s:\n", arm64LLVMBlockName(blk.name))
+		terminated := false
+		for ii, ins := range blk.instrs {
+			c.emitSourceComment(ins)
+			term, err := c.lowerInstr(bi, ii, ins)
+			if err != nil {
+				return err
+			}
+			if term {
+				terminated = true
+				break
+			}
+		}
+		if terminated {
+			continue
+		}
+		if bi+1 < len(c.blocks) {
+			c.br(c.blocks[bi+1].name)
+			continue
+		}
+		c.lowerRetZero()
+	}
+	return nil
+}
+
+func (c *loong64Ctx) lowerInstr(bi, ii int, ins Instr) (terminated bool, err error) {
+	op := strings.ToUpper(string(ins.Op))
+	switch Op(op) {
+	case OpTEXT, OpBYTE:
+		return false, nil
+	case OpRET:
+		return true, c.lowerRET()
+	}
+	switch op {
+	case "PCALIGN", "NO_LOCAL_POINTERS", "PCDATA", "FUNCDATA", "GO_ARGS", "WORD", "NOP", "NOOP", "PRELD", "PRELDX":
+		return false, nil
+	}
+
+	if ok, term, err := c.lowerData(op, ins); ok {
+		return term, err
+	}
+	if ok, term, err := c.lowerArith(op, ins); ok {
+		return term, err
+	}
+	if ok, term, err := c.lowerFP(op, ins); ok {
+		return term, err
+	}
+	if ok, term, err := c.lowerAtomic(op, ins); ok {
+		return term, err
+	}
+	if ok, term, err := c.lowerSyscall(op, ins); ok {
+		return term, err
+	}
+	if ok, term, err := c.lowerBranch(bi, ii, op, ins); ok {
+		return term, err
+	}
+	return false, fmt.Errorf("loong64: unsupported instruction %s", ins.Op)
+}
+
+func (c *loong64Ctx) lowerRET() error {
+	if len(c.fpResults) == 0 {
+		if c.sig.Ret == Void {
+			c.b.WriteString("  ret void\n")
+			return nil
+		}
+		var cur loong64ArgCursor
+		r, _ := cur.next(c.sig.Ret)
+		v64, err := c.loadReg(r)
+		if err != nil {
+			return err
+		}
+		v, err := c.i64ToValue(v64, c.sig.Ret)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(c.b, "  ret %s %s\n", c.sig.Ret, v)
+		return nil
+	}
+
+	// Results come from their FP slots when the body stores to them (or
+	// takes their address), otherwise from the ABIInternal registers.
+	load := func(slot FrameSlot) (string, error) {
+		if c.fpResWritten[slot.Index] || c.fpResAddrTaken[slot.Index] {
+			return c.loadFPResult(slot)
+		}
+		return c.loadRetSlotFallback(slot)
+	}
+	if len(c.fpResults) == 1 {
+		v, err := load(c.fpResults[0])
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(c.b, "  ret %s %s\n", c.sig.Ret, v)
+		return nil
+	}
+	cur := "undef"
+	for _, slot := range c.fpResults {
+		v, err := load(slot)
+		if err != nil {
+			return err
+		}
+		t := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = insertvalue %s %s, %s %s, %d\n", t, c.sig.Ret, cur, slot.Type, v, slot.Index)
+		cur = "%" + t
+	}
+	fmt.Fprintf(c.b, "  ret %s %s\n", c.sig.Ret, cur)
+	return nil
+}
+
+func (c *loong64Ctx) lowerRetZero() {
+	if c.sig.Ret == Void {
+		c.b.WriteString("  ret void\n")
+		return
+	}
+	fmt.Fprintf(c.b, "  ret %s %s\n", c.sig.Ret, llvmZeroValue(c.sig.Ret))
+}
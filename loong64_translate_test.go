@@ -0,0 +1,230 @@
+//go:build !llgo
+// +build !llgo
+
+package plan9asm
+
+import (
+	"strings"
+	"testing"
+)
+
+func translateLOONG64(t *testing.T, src string, sigs map[string]FuncSig) string {
+	t.Helper()
+	file, err := Parse(ArchLOONG64, src)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	ll, err := translateIRText(file, Options{
+		TargetTriple: "loongarch64-unknown-linux-gnu",
+		Goarch:       "loong64",
+		ResolveSym:   testResolveSym("example"),
+		Sigs:         sigs,
+	})
+	if err != nil {
+		t.Fatalf("translateIRText: %v", err)
+	}
+	return ll
+}
+
+func TestTranslateLOONG64Registers(t *testing.T) {
+	ll := translateLOONG64(t, `TEXT ·f(SB),NOSPLIT,$0-24
+	MOVV a+0(FP), R4
+	MOVV b+8(FP), R5
+	ADDV R5, R4, R6
+	SUB R5, R4, R7
+	SGT R5, R4, R8
+	MASKNEZ R8, R6, R9
+	ALSLV $3, R4, R9, R10
+	BSTRPICKV $15, R10, $4, R11
+	MULHVU R5, R4, R12
+	ADDV R7, R11
+	ADDV R12, R11
+	MOVV R11, ret+16(FP)
+	RET
+`, map[string]FuncSig{
+		"example.f": sigWithClassicFrame("example.f", []LLVMType{I64, I64}, I64),
+	})
+	wantIR(t, ll,
+		`define i64 @"example.f"(i64 %arg0, i64 %arg1)`,
+		"%reg_R4 = alloca i64",
+		"add i64",
+		"sub i32",
+		"sext i32",
+		"icmp slt i64",
+		"select i1",
+		"shl i64",
+		"lshr i64",
+		"mul i128",
+		"ret i64",
+	)
+}
+
+func TestTranslateLOONG64Branches(t *testing.T) {
+	ll := translateLOONG64(t, `TEXT ·sum(SB),NOSPLIT,$0-16
+	MOVV n+0(FP), R4
+	MOVV R0, R5
+	MOVV $1, R6
+loop:
+	BLT R4, R6, done
+	ADDV R6, R5
+	ADDV $1, R6
+	JMP loop
+done:
+	BEQ R5, 2(PC)
+	NEGV R5, R5
+	MOVV R5, ret+8(FP)
+	RET
+
+TEXT ·gt(SB),NOSPLIT,$0-17
+	MOVD a+0(FP), F0
+	MOVD b+8(FP), F1
+	CMPGTD F1, F0, FCC0
+	MOVV $1, R4
+	BFPT 2(PC)
+	MOVV R0, R4
+	MOVB R4, ret+16(FP)
+	RET
+`, map[string]FuncSig{
+		"example.sum": sigWithClassicFrame("example.sum", []LLVMType{I64}, I64),
+		"example.gt":  sigWithClassicFrame("example.gt", []LLVMType{LLVMType("double"), LLVMType("double")}, I1),
+	})
+	wantIR(t, ll, "icmp slt i64", "icmp eq i64", "br label %loop", "label %done", "fcmp ogt double")
+}
+
+func TestTranslateLOONG64Float(t *testing.T) {
+	ll := translateLOONG64(t, `TEXT ·fma(SB),NOSPLIT,$0-32
+	MOVD a+0(FP), F0
+	MOVD b+8(FP), F1
+	MOVD c+16(FP), F2
+	FMADDD F2, F0, F1, F3
+	CMPGTD F3, F0, FCC1
+	FSEL FCC1, F3, F0, F4
+	FTINTRZVD F4, F5
+	MOVV F5, R4
+	FCLASSD F3, F6
+	MOVV F6, R5
+	ADDV R5, R4
+	MOVV R4, ret+24(FP)
+	RET
+`, map[string]FuncSig{
+		"example.fma": sigWithClassicFrame("example.fma", []LLVMType{LLVMType("double"), LLVMType("double"), LLVMType("double")}, I64),
+	})
+	wantIR(t, ll,
+		"@llvm.fma.f64",
+		"fcmp ogt double",
+		"select i1",
+		"@llvm.fptosi.sat.i64.f64",
+		"@llvm.fabs.f64",
+	)
+}
+
+func TestTranslateLOONG64Atomics(t *testing.T) {
+	ll := translateLOONG64(t, `TEXT ·cas(SB),NOSPLIT,$0-25
+	MOVV ptr+0(FP), R4
+	MOVV old+8(FP), R5
+	MOVV new+16(FP), R6
+	DBAR
+again:
+	MOVV R6, R8
+	LLV (R4), R7
+	BNE R7, R5, fail
+	SCV R8, (R4)
+	BEQ R8, again
+	MOVV $1, R4
+	MOVB R4, ret+24(FP)
+	DBAR
+	RET
+fail:
+	MOVB R0, ret+24(FP)
+	DBAR
+	RET
+
+TEXT ·xadd(SB),NOSPLIT,$0-24
+	MOVV ptr+0(FP), R4
+	MOVW delta+8(FP), R5
+	AMADDDBW R5, (R4), R6
+	ADDW R5, R6
+	MOVV R6, ret+16(FP)
+	RET
+
+TEXT ·casv(SB),NOSPLIT,$0-32
+	MOVV ptr+0(FP), R4
+	MOVV old+8(FP), R5
+	MOVV new+16(FP), R6
+	AMCASDBV R6, (R4), R5
+	MOVV R5, ret+24(FP)
+	RET
+
+TEXT ·xchg8(SB),NOSPLIT,$0-10
+	MOVV ptr+0(FP), R4
+	MOVBU new+8(FP), R5
+	AMSWAPDBB R5, (R4), R6
+	MOVB R6, ret+9(FP)
+	RET
+`, map[string]FuncSig{
+		"example.cas":   sigWithClassicFrame("example.cas", []LLVMType{Ptr, I64, I64}, I1),
+		"example.xadd":  sigWithClassicFrame("example.xadd", []LLVMType{Ptr, I32}, I64),
+		"example.casv":  sigWithClassicFrame("example.casv", []LLVMType{Ptr, I64, I64}, I64),
+		"example.xchg8": sigWithClassicFrame("example.xchg8", []LLVMType{Ptr, I8}, I8),
+	})
+	wantIR(t, ll,
+		"fence seq_cst",
+		"load atomic i64",
+		"cmpxchg ptr",
+		"atomicrmw add ptr %",
+		", i32 ",
+		"atomicrmw xchg ptr",
+		", i8 ",
+	)
+}
+
+func TestTranslateLOONG64Syscall(t *testing.T) {
+	src := `TEXT ·getpid(SB),NOSPLIT,$0-8
+	MOVV $172, R11
+	SYSCALL
+	MOVV R4, ret+0(FP)
+	RET
+`
+	sigs := map[string]FuncSig{
+		"example.getpid": sigWithClassicFrame("example.getpid", nil, I64),
+	}
+	wantIR(t, translateLOONG64(t, src, sigs), "call i64 @syscall(i64 ")
+
+	file, err := Parse(ArchLOONG64, src)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	ll, err := translateIRText(file, Options{
+		TargetTriple: "loongarch64-unknown-linux-gnu",
+		Goarch:       "loong64",
+		ResolveSym:   testResolveSym("example"),
+		Sigs:         sigs,
+		Syscall:      RawSyscall{},
+	})
+	if err != nil {
+		t.Fatalf("translateIRText: %v", err)
+	}
+	wantIR(t, ll, `asm sideeffect "syscall 0", "={$r4},={$r5},{$r11},{$r4},{$r5},{$r6},{$r7},{$r8},{$r9},~{memory}"`)
+}
+
+func TestTranslateLOONG64RejectsMixedRegisters(t *testing.T) {
+	for _, tc := range []struct{ insn, want string }{
+		{"ADDV F1, R4", "expects a register or immediate source"},
+		{"ADDD R4, F1, F2", "expects F registers"},
+		{"BEQ F1, R4, 2(PC)", "expects R registers"},
+		{"VMOVQ (R4), V0", "unsupported"},
+	} {
+		file, err := Parse(ArchLOONG64, "TEXT ·f(SB),NOSPLIT,$0-0\n\t"+tc.insn+"\n\tRET\n")
+		if err != nil {
+			t.Fatalf("%s: Parse: %v", tc.insn, err)
+		}
+		_, err = translateIRText(file, Options{
+			Goarch:     "loong64",
+			ResolveSym: testResolveSym("example"),
+			Sigs:       map[string]FuncSig{"example.f": {Name: "example.f", Ret: Void}},
+		})
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: err = %v, want %q", tc.insn, err, tc.want)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package plan9asm
+
+import (
+	"fmt"
+	"strings"
+)
+
+type mipsBlock struct {
+	name   string // source label (or "anon_N")
+	instrs []Instr
+}
+
+// mipsIsTerminator reports whether ins ends a basic block: RET, JMP or a
+// conditional branch.
+func mipsIsTerminator(ins Instr) bool {
+	if ins.Op == OpRET {
+		return true
+	}
+	switch strings.ToUpper(string(ins.Op)) {
+	case "JMP", "BEQ", "BNE", "BLEZ", "BGTZ", "BLTZ", "BGEZ", "BFPT", "BFPF":
+		return true
+	}
+	return false
+}
+
+// mipsPCRelTarget returns the instruction offset of a branch to n(PC).
+func mipsPCRelTarget(ins Instr) (off int64, ok bool) {
+	if !mipsIsTerminator(ins) || len(ins.Args) == 0 {
+		return 0, false
+	}
+	last := ins.Args[len(ins.Args)-1]
+	if last.Kind != OpMem || last.Mem.Base != PC {
+		return 0, false
+	}
+	return last.Mem.Off, true
+}
+
+func mipsSplitBlocks(fn Func) []mipsBlock {
+	// The first block is anonymous too, as "entry" is a common label.
+	blocks := []mipsBlock{{name: "anon_0"}}
+	cur := 0
+	anon := 0
+
+	startAnon := func() {
+		anon++
+		blocks = append(blocks, mipsBlock{name: fmt.Sprintf("anon_%d", anon)})
+		cur = len(blocks) - 1
+	}
+
+	linear := make([]Instr, 0, len(fn.Instrs))
+	for _, ins := range fn.Instrs {
+		if ins.Op == OpLABEL {
+			continue
+		}
+		linear = append(linear, ins)
+	}
+	splitAt := map[int]bool{}
+	for i, ins := range linear {
+		if off, ok := mipsPCRelTarget(ins); ok {
+			t := i + int(off)
+			if 0 <= t && t < len(linear) {
+				splitAt[t] = true
+			}
+		}
+	}
+
+	li := 0
+	for _, ins := range fn.Instrs {
+		if ins.Op == OpLABEL && len(ins.Args) == 1 && ins.Args[0].Kind == OpLabel {
+			lbl := ins.Args[0].Sym
+			if len(blocks[cur].instrs) == 0 && strings.HasPrefix(blocks[cur].name, "anon_") {
+				blocks[cur].name = lbl
+				continue
+			}
+			blocks = append(blocks, mipsBlock{name: lbl})
+			cur = len(blocks) - 1
+			continue
+		}
+		if splitAt[li] && len(blocks[cur].instrs) != 0 {
+			startAnon()
+		}
+		blocks[cur].instrs = append(blocks[cur].instrs, ins)
+		li++
+		if mipsIsTerminator(ins) {
+			startAnon()
+		}
+	}
+
+	if len(blocks) > 1 && len(blocks[len(blocks)-1].instrs) == 0 && strings.HasPrefix(blocks[len(blocks)-1].name, "anon_") {
+		blocks = blocks[:len(blocks)-1]
+	}
+	return blocks
+}
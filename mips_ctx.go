@@ -0,0 +1,290 @@
+package plan9asm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// mipsCtx lowers one TEXT body of any of the MIPS dialects. R1-R31, HI,
+// LO and F0-F31 live in i64 slots; R0 reads as zero and drops writes. On
+// mips and mipsle the integer registers hold their 32-bit value
+// sign-extended, as the 64-bit ISA keeps 32-bit results, so that one set
+// of compares serves both widths. F registers hold the raw bits, singles
+// in the low word. A double lives in the slot of the register it names
+// rather than in an even/odd pair, which is all Go's FR=0 code relies on.
+// FCC0, set by the floating-point compares, holds 0 or 1. The MSA
+// registers W0-W31 that the body names live in i128 slots.
+type mipsCtx struct {
+	b       *strings.Builder
+	arch    Arch
+	sig     FuncSig
+	resolve func(string) string
+	sigs    map[string]FuncSig
+	cfg     lowerConfig
+
+	// wide is set on mips64 and mips64le.
+	wide      bool
+	bigEndian bool
+	ptrBytes  int64
+
+	tmp int
+
+	blocks     []mipsBlock
+	blockBase  []int
+	blockByIdx map[int]int
+
+	regSlot   map[Reg]string // reg -> alloca name
+	wregs     []Reg          // MSA registers the body names
+	frameSize int64
+	// argFrameSize is the size of an in-memory copy of the argument frame,
+	// made when the body takes the address of an FP slot that is not a
+	// result, or 0.
+	argFrameSize int64
+
+	reservedValidSlot string
+	reservedPtrSlot   string
+	reservedValueSlot string
+
+	fpResults      []FrameSlot      // result slots (Index is result index)
+	fpResAllocaOff map[int64]string // off(FP) -> alloca
+	fpResAllocaIdx map[int]string   // result index -> alloca
+}
+
+func newMIPSCtx(b *strings.Builder, arch Arch, fn Func, sig FuncSig, resolve func(string) string, sigs map[string]FuncSig, cfg lowerConfig) *mipsCtx {
+	c := &mipsCtx{
+		b:              b,
+		arch:           arch,
+		sig:            sig,
+		resolve:        resolve,
+		sigs:           sigs,
+		cfg:            cfg,
+		wide:           mipsIs64(arch),
+		bigEndian:      archBigEndian(arch),
+		ptrBytes:       4,
+		blocks:         mipsSplitBlocks(fn),
+		blockByIdx:     map[int]int{},
+		regSlot:        map[Reg]string{},
+		frameSize:      textFrameSize(fn),
+		fpResAllocaOff: map[int64]string{},
+		fpResAllocaIdx: map[int]string{},
+	}
+	if c.wide {
+		c.ptrBytes = 8
+	}
+	c.fpResults = append([]FrameSlot(nil), sig.Frame.Results...)
+	c.argFrameSize = riscv64ArgFrameSize(fn, sig)
+	seen := map[Reg]bool{}
+	for _, ins := range fn.Instrs {
+		for _, a := range ins.Args {
+			if mipsIsWRegOp(a) && !seen[a.Reg] {
+				seen[a.Reg] = true
+				c.wregs = append(c.wregs, a.Reg)
+			}
+		}
+	}
+	base := 0
+	for i, blk := range c.blocks {
+		c.blockBase = append(c.blockBase, base)
+		c.blockByIdx[base] = i
+		base += len(blk.instrs)
+	}
+	return c
+}
+
+func (c *mipsCtx) emitSourceComment(ins Instr) {
+	if !c.cfg.annotateSource {
+		return
+	}
+	emitIRSourceComment(c.b, ins.Raw)
+}
+
+func (c *mipsCtx) newTmp() string {
+	c.tmp++
+	return fmt.Sprintf("t%d", c.tmp)
+}
+
+func (c *mipsCtx) emitEntryAllocasAndArgInit() error {
+	c.b.WriteString("entry:\n")
+	regs := []Reg{SP, "HI", "LO", "FCC0"}
+	for i := 1; i <= 31; i++ {
+		if i != 29 {
+			regs = append(regs, Reg(fmt.Sprintf("R%d", i)))
+		}
+	}
+	for i := 0; i <= 31; i++ {
+		regs = append(regs, Reg(fmt.Sprintf("F%d", i)))
+	}
+	for _, r := range regs {
+		name := "%" + arm64LLVMBlockName("reg_"+string(r))
+		c.regSlot[r] = name
+		fmt.Fprintf(c.b, "  %s = alloca i64\n", name)
+		fmt.Fprintf(c.b, "  store i64 0, ptr %s\n", name)
+	}
+	for _, r := range c.wregs {
+		name := "%" + arm64LLVMBlockName("reg_"+string(r))
+		c.regSlot[r] = name
+		fmt.Fprintf(c.b, "  %s = alloca i128\n", name)
+		fmt.Fprintf(c.b, "  store i128 0, ptr %s\n", name)
+	}
+
+	// SP points at the bottom of the TEXT frame, whose first word holds
+	// the saved return address in Go's layout.
+	if c.frameSize > 0 {
+		fmt.Fprintf(c.b, "  %%frame = alloca [%d x i8], align 8\n", c.frameSize+c.ptrBytes)
+		t := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = ptrtoint ptr %%frame to i64\n", t)
+		if err := c.storeReg(SP, "%"+t); err != nil {
+			return err
+		}
+	}
+
+	// Reservation state for LL/SC lowering.
+	c.reservedValidSlot = "%reserved_valid"
+	c.reservedPtrSlot = "%reserved_ptr"
+	c.reservedValueSlot = "%reserved_value"
+	fmt.Fprintf(c.b, "  %s = alloca i1\n", c.reservedValidSlot)
+	fmt.Fprintf(c.b, "  store i1 false, ptr %s\n", c.reservedValidSlot)
+	fmt.Fprintf(c.b, "  %s = alloca ptr\n", c.reservedPtrSlot)
+	fmt.Fprintf(c.b, "  store ptr null, ptr %s\n", c.reservedPtrSlot)
+	fmt.Fprintf(c.b, "  %s = alloca i64\n", c.reservedValueSlot)
+	fmt.Fprintf(c.b, "  store i64 0, ptr %s\n", c.reservedValueSlot)
+
+	for _, r := range c.fpResults {
+		name := fmt.Sprintf("%%fp_ret_%d", r.Index)
+		c.fpResAllocaIdx[r.Index] = name
+		c.fpResAllocaOff[r.Offset] = name
+		fmt.Fprintf(c.b, "  %s = alloca %s\n", name, r.Type)
+		fmt.Fprintf(c.b, "  store %s %s, ptr %s\n", r.Type, llvmZeroValue(r.Type), name)
+	}
+
+	if c.argFrameSize > 0 {
+		c.spillArgFrame()
+	}
+
+	// Go has no register ABI on MIPS, so only helper<> register
+	// assignments seed registers; bodies read the FP slots.
+	for i := 0; i < len(c.sig.Args) && i < len(c.sig.ArgRegs); i++ {
+		v, ok, err := c.valueAsI64(c.sig.Args[i], fmt.Sprintf("%%arg%d", i))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		if err := c.storeReg(c.sig.ArgRegs[i], v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// spillArgFrame stores the FP parameter slots into %argframe.
+func (c *mipsCtx) spillArgFrame() {
+	fmt.Fprintf(c.b, "  %%argframe = alloca [%d x i8], align 8\n", c.argFrameSize)
+	for _, s := range c.sig.Frame.Params {
+		if s.Index < 0 || s.Index >= len(c.sig.Args) {
+			continue
+		}
+		v := fmt.Sprintf("%%arg%d", s.Index)
+		if s.Field >= 0 {
+			v = c.emit("extractvalue %s %s, %d", c.sig.Args[s.Index], v, s.Field)
+		}
+		p := c.emit("getelementptr i8, ptr %%argframe, i64 %d", s.Offset)
+		fmt.Fprintf(c.b, "  store %s %s, ptr %s\n", s.Type, v, p)
+	}
+}
+
+func (c *mipsCtx) valueAsI64(ty LLVMType, v string) (out string, ok bool, err error) {
+	switch ty {
+	case I64:
+		return v, true, nil
+	case Ptr:
+		return c.emit("ptrtoint ptr %s to i64", v), true, nil
+	case I1, I8, I16, I32:
+		return c.emit("zext %s %s to i64", ty, v), true, nil
+	case LLVMType("double"):
+		return c.emit("bitcast double %s to i64", v), true, nil
+	case LLVMType("float"):
+		t := c.emit("bitcast float %s to i32", v)
+		return c.emit("zext i32 %s to i64", t), true, nil
+	}
+	return "", false, nil
+}
+
+// i64ToValue converts register bits back to ty.
+func (c *mipsCtx) i64ToValue(v string, ty LLVMType) (string, error) {
+	switch ty {
+	case I64:
+		return v, nil
+	case I32, I16, I8, I1:
+		return c.emit("trunc i64 %s to %s", v, ty), nil
+	case Ptr:
+		return c.emit("inttoptr i64 %s to ptr", v), nil
+	case LLVMType("double"):
+		return c.emit("bitcast i64 %s to double", v), nil
+	case LLVMType("float"):
+		w := c.emit("trunc i64 %s to i32", v)
+		return c.emit("bitcast i32 %s to float", w), nil
+	}
+	return "", fmt.Errorf("%s: unsupported value type %s", c.arch, ty)
+}
+
+// mipsIsIntReg reports whether r is an integer register, whose value is
+// kept sign-extended from 32 bits on mips and mipsle.
+func mipsIsIntReg(r Reg) bool {
+	return r == SP || r == "HI" || r == "LO" || strings.HasPrefix(string(r), "R")
+}
+
+func (c *mipsCtx) loadReg(r Reg) (string, error) {
+	if r == "R0" {
+		return "0", nil
+	}
+	if mipsIsFCRName(string(r)) {
+		// The FCSR reads as its reset value: round to nearest, no
+		// exceptions enabled or flagged.
+		return "0", nil
+	}
+	slot, ok := c.regSlot[r]
+	if !ok {
+		return "", fmt.Errorf("%s: unknown reg %s", c.arch, r)
+	}
+	t := c.newTmp()
+	fmt.Fprintf(c.b, "  %%%s = load i64, ptr %s\n", t, slot)
+	return "%" + t, nil
+}
+
+func (c *mipsCtx) storeReg(r Reg, v string) error {
+	if r == "R0" || mipsIsFCRName(string(r)) {
+		return nil
+	}
+	slot, ok := c.regSlot[r]
+	if !ok {
+		return fmt.Errorf("%s: unknown reg %s", c.arch, r)
+	}
+	if !c.wide && mipsIsIntReg(r) {
+		v = c.narrow(v, 32, true)
+	}
+	fmt.Fprintf(c.b, "  store i64 %s, ptr %s\n", v, slot)
+	return nil
+}
+
+func (c *mipsCtx) ptrFromSB(sym string) (string, error) {
+	base, off, ok := parseSBRef(sym)
+	if !ok {
+		return "", fmt.Errorf("invalid (SB) sym ref: %q", sym)
+	}
+	base = strings.TrimPrefix(base, "$")
+	res := base
+	if strings.Contains(base, "·") || strings.Contains(base, "/") || strings.Contains(base, ".") {
+		res = c.resolve(base)
+	} else {
+		res = c.resolve("·" + base)
+	}
+	p := llvmGlobal(res)
+	if off == 0 {
+		return p, nil
+	}
+	t := c.newTmp()
+	fmt.Fprintf(c.b, "  %%%s = getelementptr i8, ptr %s, i64 %d\n", t, p, off)
+	return "%" + t, nil
+}
@@ -0,0 +1,248 @@
+package plan9asm
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+func (c *mipsCtx) emit(format string, args ...any) string {
+	t := c.newTmp()
+	fmt.Fprintf(c.b, "  %%%s = "+format+"\n", append([]any{t}, args...)...)
+	return "%" + t
+}
+
+func (c *mipsCtx) trunc32(v string) string {
+	return c.emit("trunc i64 %s to i32", v)
+}
+
+// addrI64 computes the i64 address of an off(base) reference. On mips and
+// mipsle the sum wraps when the pointer is truncated to 32 bits.
+func (c *mipsCtx) addrI64(mem MemRef) (string, error) {
+	base, err := c.loadReg(mem.Base)
+	if err != nil {
+		return "", err
+	}
+	if mem.Index != "" {
+		idx, err := c.loadReg(mem.Index)
+		if err != nil {
+			return "", err
+		}
+		base = c.emit("add i64 %s, %s", base, idx)
+	}
+	if mem.Off == 0 {
+		return base, nil
+	}
+	return c.emit("add i64 %s, %d", base, mem.Off), nil
+}
+
+func (c *mipsCtx) memPtr(mem MemRef) (string, error) {
+	addr, err := c.addrI64(mem)
+	if err != nil {
+		return "", err
+	}
+	return c.emit("inttoptr i64 %s to ptr", addr), nil
+}
+
+// loadMem loads bits from mem and sign- or zero-extends them to i64.
+func (c *mipsCtx) loadMem(mem MemRef, bits int, signed bool) (string, error) {
+	ptr, err := c.memPtr(mem)
+	if err != nil {
+		return "", err
+	}
+	v := c.emit("load i%d, ptr %s", bits, ptr)
+	if bits == 64 {
+		return v, nil
+	}
+	return c.extend(v, bits, signed), nil
+}
+
+func (c *mipsCtx) storeMem(mem MemRef, bits int, v64 string) error {
+	ptr, err := c.memPtr(mem)
+	if err != nil {
+		return err
+	}
+	if bits < 64 {
+		v64 = c.emit("trunc i64 %s to i%d", v64, bits)
+	}
+	fmt.Fprintf(c.b, "  store i%d %s, ptr %s\n", bits, v64, ptr)
+	return nil
+}
+
+// extend sign- or zero-extends an i<bits> value to i64.
+func (c *mipsCtx) extend(v string, bits int, signed bool) string {
+	ext := "zext"
+	if signed {
+		ext = "sext"
+	}
+	return c.emit("%s i%d %s to i64", ext, bits, v)
+}
+
+// narrow truncates v64 to bits and extends it back.
+func (c *mipsCtx) narrow(v64 string, bits int, signed bool) string {
+	if bits == 64 {
+		return v64
+	}
+	return c.extend(c.emit("trunc i64 %s to i%d", v64, bits), bits, signed)
+}
+
+// symAddr returns the address of a $sym(SB) operand, of a $name-off(FP)
+// operand naming a word below the argument frame, or of an $off(Rn)
+// address constant.
+func (c *mipsCtx) symAddr(sym string) (string, error) {
+	if s := strings.TrimSpace(sym); strings.HasSuffix(s, "(FP)") {
+		return c.callerFrameAddr(s)
+	}
+	if m, ok := ppc64AddrConst(sym); ok {
+		return c.addrI64(m)
+	}
+	p, err := c.ptrFromSB(strings.TrimPrefix(strings.TrimSpace(sym), "$"))
+	if err != nil {
+		return "", err
+	}
+	return c.emit("ptrtoint ptr %s to i64", p), nil
+}
+
+// callerFrameAddr evaluates $name-off(FP). The pseudo FP lies just above
+// the TEXT frame and its saved return address word, as in Go's layout.
+func (c *mipsCtx) callerFrameAddr(s string) (string, error) {
+	inner := strings.TrimSuffix(strings.TrimPrefix(s, "$"), "(FP)")
+	i := strings.LastIndexAny(inner, "+-")
+	if i < 0 {
+		return "", fmt.Errorf("%s: unsupported FP address %s", c.arch, s)
+	}
+	off, err := strconv.ParseInt(inner[i:], 0, 64)
+	if err != nil {
+		return "", fmt.Errorf("%s: unsupported FP address %s", c.arch, s)
+	}
+	sp, err := c.loadReg(SP)
+	if err != nil {
+		return "", err
+	}
+	return c.emit("add i64 %s, %d", sp, c.frameSize+c.ptrBytes+off), nil
+}
+
+// mipsIsAddrSym reports whether op is an address constant: $sym(SB),
+// $name-off(FP) or $off(Rn).
+func mipsIsAddrSym(op Operand) bool {
+	if op.Kind != OpSym {
+		return false
+	}
+	s := strings.TrimSpace(op.Sym)
+	if _, ok := ppc64AddrConst(s); ok {
+		return true
+	}
+	return strings.HasPrefix(s, "$") && (strings.HasSuffix(s, "(SB)") || strings.HasSuffix(s, "(FP)"))
+}
+
+// imm returns an immediate as the register value it produces: on mips
+// and mipsle a 32-bit constant such as $0xffffffff is sign-extended.
+func (c *mipsCtx) imm(v int64) string {
+	if !c.wide {
+		v = int64(int32(v))
+	}
+	return fmt.Sprintf("%d", v)
+}
+
+// eval64 evaluates a source operand of an integer operation: a register,
+// an immediate or an address constant.
+func (c *mipsCtx) eval64(op Operand) (string, error) {
+	switch op.Kind {
+	case OpImm:
+		return c.imm(op.Imm), nil
+	case OpReg:
+		return c.loadReg(op.Reg)
+	case OpFPAddr:
+		return c.evalFPAddr64(op)
+	case OpSym:
+		if mipsIsAddrSym(op) {
+			return c.symAddr(op.Sym)
+		}
+	}
+	return "", fmt.Errorf("%s: unsupported source operand %s", c.arch, op.String())
+}
+
+// slotSize returns the size of an argument frame slot of type ty.
+func (c *mipsCtx) slotSize(ty LLVMType) int64 {
+	if ty == Ptr {
+		return c.ptrBytes
+	}
+	return riscv64SlotSize(ty)
+}
+
+// mipsSplittable reports whether part of a slot of type ty can be read or
+// written on its own, as mips code does with the words of an int64.
+func mipsSplittable(ty LLVMType) bool {
+	switch ty {
+	case I64, I32, I16, LLVMType("double"), LLVMType("float"):
+		return true
+	}
+	return false
+}
+
+// slotAt returns the slot among slots that holds the bytes+off(FP) being
+// accessed, and the shift of those bytes within the slot's bits.
+func (c *mipsCtx) slotAt(slots []FrameSlot, off int64, bytes int64) (FrameSlot, int64, bool) {
+	for _, s := range slots {
+		n := c.slotSize(s.Type)
+		if off == s.Offset && (bytes >= n || !mipsSplittable(s.Type)) {
+			return s, 0, true
+		}
+		if off < s.Offset || off+bytes > s.Offset+n || !mipsSplittable(s.Type) {
+			continue
+		}
+		k := off - s.Offset
+		if c.bigEndian {
+			return s, 8 * (n - k - bytes), true
+		}
+		return s, 8 * k, true
+	}
+	return FrameSlot{}, 0, false
+}
+
+// evalFPLoad reads bits from the argument at off(FP), extended to i64. A
+// narrower access reads part of the slot, such as one word of an int64.
+func (c *mipsCtx) evalFPLoad(op Operand, bits int, signed bool) (string, error) {
+	slot, shift, ok := c.slotAt(c.sig.Frame.Params, op.FPOffset, int64(bits/8))
+	if !ok {
+		return "", fmt.Errorf("%s: unsupported FP param slot: %s", c.arch, op.String())
+	}
+	v, err := c.evalFPValue64(slot, op)
+	if err != nil {
+		return "", err
+	}
+	if shift != 0 {
+		v = c.emit("lshr i64 %s, %d", v, shift)
+	}
+	return c.narrow(v, bits, signed), nil
+}
+
+func (c *mipsCtx) evalFPValue64(slot FrameSlot, op Operand) (string, error) {
+	idx := slot.Index
+	if idx < 0 || idx >= len(c.sig.Args) {
+		return "", fmt.Errorf("%s: FP slot %s invalid arg index %d", c.arch, op.String(), idx)
+	}
+	arg := fmt.Sprintf("%%arg%d", idx)
+	if slot.Field >= 0 {
+		arg = c.emit("extractvalue %s %s, %d", c.sig.Args[idx], arg, slot.Field)
+	}
+	v, ok, err := c.valueAsI64(slot.Type, arg)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", fmt.Errorf("%s: FP slot %s unsupported arg type %q", c.arch, op.String(), slot.Type)
+	}
+	return v, nil
+}
+
+func (c *mipsCtx) evalFPAddr64(op Operand) (string, error) {
+	p, ok := c.fpResAllocaOff[op.FPOffset]
+	if !ok {
+		if c.argFrameSize == 0 {
+			return "", fmt.Errorf("%s: unsupported FP address %s", c.arch, op.String())
+		}
+		p = c.emit("getelementptr i8, ptr %%argframe, i64 %d", op.FPOffset)
+	}
+	return c.emit("ptrtoint ptr %s to i64", p), nil
+}
@@ -0,0 +1,44 @@
+package plan9asm
+
+import "fmt"
+
+// storeFPResult stores the low bits of v64 to the result slot holding
+// off(FP). A narrower store replaces part of the slot, such as one word of
+// an int64 on mips and mipsle.
+func (c *mipsCtx) storeFPResult(off int64, bits int, v64 string) error {
+	slot, shift, ok := c.slotAt(c.fpResults, off, int64(bits/8))
+	if !ok {
+		return fmt.Errorf("%s: unsupported FP result slot +%d(FP)", c.arch, off)
+	}
+	p := c.fpResAllocaIdx[slot.Index]
+	n := c.slotSize(slot.Type)
+	if off == slot.Offset && int64(bits/8) >= n || !mipsSplittable(slot.Type) {
+		v, err := c.i64ToValue(v64, slot.Type)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(c.b, "  store %s %s, ptr %s\n", slot.Type, v, p)
+		return nil
+	}
+	ity := fmt.Sprintf("i%d", 8*n)
+	mask := (uint64(1)<<bits - 1) << shift
+	old := c.emit("load %s, ptr %s", ity, p)
+	part := c.emit("and i64 %s, %d", v64, int64(uint64(1)<<bits-1))
+	if shift != 0 {
+		part = c.emit("shl i64 %s, %d", part, shift)
+	}
+	if n < 8 {
+		part = c.emit("trunc i64 %s to %s", part, ity)
+	}
+	kept := c.emit("and %s %s, %d", ity, old, int64(^mask)<<(64-8*n)>>(64-8*n))
+	fmt.Fprintf(c.b, "  store %s %s, ptr %s\n", ity, c.emit("or %s %s, %s", ity, kept, part), p)
+	return nil
+}
+
+func (c *mipsCtx) loadFPResult(slot FrameSlot) (string, error) {
+	p, ok := c.fpResAllocaIdx[slot.Index]
+	if !ok {
+		return "", fmt.Errorf("%s: missing FP result alloca for index %d", c.arch, slot.Index)
+	}
+	return c.emit("load %s, ptr %s", slot.Type, p), nil
+}
@@ -0,0 +1,381 @@
+package plan9asm
+
+import "fmt"
+
+// mipsALUOp describes a two-source integer operation. w32 operations
+// compute on the low words and sign-extend the result; wide ones exist on
+// mips64 and mips64le only.
+type mipsALUOp struct {
+	kind string
+	w32  bool
+	wide bool
+}
+
+var mipsALUOps = map[string]mipsALUOp{
+	"ADD": {"add", true, false}, "ADDU": {"add", true, false},
+	"ADDV": {"add", false, true}, "ADDVU": {"add", false, true},
+	"SUB": {"sub", true, false}, "SUBU": {"sub", true, false},
+	"SUBV": {"sub", false, true}, "SUBVU": {"sub", false, true},
+	"AND": {"and", false, false}, "OR": {"or", false, false},
+	"XOR": {"xor", false, false}, "NOR": {"nor", false, false},
+	"SLL": {"shl", true, false}, "SLLV": {"shl", false, true},
+	"SRL": {"lshr", true, false}, "SRLV": {"lshr", false, true},
+	"SRA": {"ashr", true, false}, "SRAV": {"ashr", false, true},
+	"ROTR": {"ror", true, false}, "ROTRV": {"ror", false, true},
+	"SGT": {"slt", false, false}, "SGTU": {"sltu", false, false},
+}
+
+// mipsHILOOps lists the multiplies and divides that leave their result in
+// HI and LO. REM is the assembler's spelling of DIV for code that reads HI.
+var mipsHILOOps = map[string]struct {
+	kind string
+	wide bool
+}{
+	"MUL": {"mul", false}, "MULU": {"mulu", false},
+	"MULV": {"mul", true}, "MULVU": {"mulu", true},
+	"DIV": {"div", false}, "DIVU": {"divu", false},
+	"DIVV": {"div", true}, "DIVVU": {"divu", true},
+	"REM": {"div", false}, "REMU": {"divu", false},
+	"REMV": {"div", true}, "REMVU": {"divu", true},
+	"MADD": {"madd", false}, "MSUB": {"msub", false},
+}
+
+func (c *mipsCtx) lowerArith(op string, ins Instr) (ok bool, terminated bool, err error) {
+	if alu, found := mipsALUOps[op]; found {
+		if alu.wide && !c.wide {
+			return true, false, fmt.Errorf("%s: %s needs mips64: %q", c.arch, op, ins.Raw)
+		}
+		return true, false, c.lowerALU(op, alu, ins)
+	}
+	if (op == "MUL" || op == "MULU") && len(ins.Args) == 3 {
+		// The three-operand MUL keeps the low word of the product.
+		return true, false, c.lowerALU(op, mipsALUOp{"mul", true, false}, ins)
+	}
+	if hl, found := mipsHILOOps[op]; found {
+		if hl.wide && !c.wide {
+			return true, false, fmt.Errorf("%s: %s needs mips64: %q", c.arch, op, ins.Raw)
+		}
+		return true, false, c.lowerHILO(op, hl.kind, hl.wide, ins)
+	}
+	if _, found := mipsUnaryOps[op]; found {
+		return true, false, c.lowerUnary(op, ins)
+	}
+	switch op {
+	case "CMOVN", "CMOVZ", "CMOVT", "CMOVF":
+		return true, false, c.lowerCMOV(op, ins)
+	case "TEQ", "TNE":
+		return true, false, c.lowerTrap(op, ins)
+	}
+	return false, false, nil
+}
+
+// lowerALU lowers "OP rt, rs, rd", which computes rd = rs OP rt, and the
+// two-operand "OP rt, rd" form, which reads rd as rs. rt may be an
+// immediate; the assembler materializes the ones without an encoding.
+func (c *mipsCtx) lowerALU(op string, alu mipsALUOp, ins Instr) error {
+	if len(ins.Args) != 2 && len(ins.Args) != 3 {
+		return fmt.Errorf("%s %s expects 2 or 3 operands: %q", c.arch, op, ins.Raw)
+	}
+	src := ins.Args[0]
+	if src.Kind != OpImm && !mipsIsRReg(src) && !mipsIsAddrSym(src) {
+		return fmt.Errorf("%s %s expects a register or immediate source: %q", c.arch, op, ins.Raw)
+	}
+	for _, a := range ins.Args[1:] {
+		if !mipsIsRReg(a) {
+			return fmt.Errorf("%s %s expects R registers: %q", c.arch, op, ins.Raw)
+		}
+	}
+	dst := ins.Args[len(ins.Args)-1].Reg
+	rt, err := c.eval64(src)
+	if err != nil {
+		return err
+	}
+	rs, err := c.loadReg(ins.Args[1].Reg)
+	if err != nil {
+		return err
+	}
+	ty := "i64"
+	if alu.w32 {
+		ty = "i32"
+		rs, rt = c.trunc32(rs), c.trunc32(rt)
+	}
+	v := c.aluValue(alu.kind, ty, rs, rt)
+	if alu.w32 {
+		v = c.extend(v, 32, true)
+	}
+	return c.storeReg(dst, v)
+}
+
+// aluValue computes a OP b in ty. Shift amounts are taken modulo the
+// width.
+func (c *mipsCtx) aluValue(kind, ty, a, b string) string {
+	bits := 64
+	if ty == "i32" {
+		bits = 32
+	}
+	switch kind {
+	case "add", "sub", "and", "or", "xor", "mul":
+		return c.emit("%s %s %s, %s", kind, ty, a, b)
+	case "nor":
+		o := c.emit("or i64 %s, %s", a, b)
+		return c.emit("xor i64 %s, -1", o)
+	case "shl", "lshr", "ashr":
+		sh := c.emit("and %s %s, %d", ty, b, bits-1)
+		return c.emit("%s %s %s, %s", kind, ty, a, sh)
+	case "ror":
+		return c.emit("call %s @llvm.fshr.%s(%s %s, %s %s, %s %s)", ty, ty, ty, a, ty, a, ty, b)
+	case "slt", "sltu":
+		// SGT rt, rs, rd sets rd = rt > rs, that is rs < rt.
+		pred := "slt"
+		if kind == "sltu" {
+			pred = "ult"
+		}
+		cmp := c.emit("icmp %s i64 %s, %s", pred, a, b)
+		return c.emit("zext i1 %s to i64", cmp)
+	}
+	panic("mips: unknown ALU kind " + kind)
+}
+
+// lowerHILO lowers "MUL rt, rs", which puts the double-width product in
+// HI:LO, and "DIV rt, rs", which puts rs/rt in LO and rs%rt in HI. MADD
+// and MSUB add the signed product of the words to HI:LO or subtract it.
+func (c *mipsCtx) lowerHILO(op, kind string, wide bool, ins Instr) error {
+	if len(ins.Args) != 2 || !mipsIsRReg(ins.Args[0]) || !mipsIsRReg(ins.Args[1]) {
+		return fmt.Errorf("%s %s expects reg, reg: %q", c.arch, op, ins.Raw)
+	}
+	rt, err := c.loadReg(ins.Args[0].Reg)
+	if err != nil {
+		return err
+	}
+	rs, err := c.loadReg(ins.Args[1].Reg)
+	if err != nil {
+		return err
+	}
+	var hi, lo string
+	switch kind {
+	case "mul", "mulu", "madd", "msub":
+		signed := kind != "mulu"
+		if wide {
+			ext := "zext"
+			if signed {
+				ext = "sext"
+			}
+			p := c.emit("mul i128 %s, %s", c.emit("%s i64 %s to i128", ext, rs), c.emit("%s i64 %s to i128", ext, rt))
+			lo = c.emit("trunc i128 %s to i64", p)
+			hi = c.emit("trunc i128 %s to i64", c.emit("lshr i128 %s, 64", p))
+			break
+		}
+		p := c.emit("mul i64 %s, %s", c.narrow(rs, 32, signed), c.narrow(rt, 32, signed))
+		if kind == "madd" || kind == "msub" {
+			oldHi, err := c.loadReg("HI")
+			if err != nil {
+				return err
+			}
+			oldLo, err := c.loadReg("LO")
+			if err != nil {
+				return err
+			}
+			acc := c.emit("or i64 %s, %s", c.emit("shl i64 %s, 32", oldHi), c.narrow(oldLo, 32, false))
+			insn := "add"
+			if kind == "msub" {
+				insn = "sub"
+			}
+			p = c.emit("%s i64 %s, %s", insn, acc, p)
+		}
+		lo = c.narrow(p, 32, true)
+		hi = c.narrow(c.emit("lshr i64 %s, 32", p), 32, true)
+	default:
+		ty := "i32"
+		if wide {
+			ty = "i64"
+		} else {
+			rs, rt = c.trunc32(rs), c.trunc32(rt)
+		}
+		lo = c.divValue(kind, ty, rs, rt)
+		rem := "rem"
+		if kind == "divu" {
+			rem = "remu"
+		}
+		hi = c.divValue(rem, ty, rs, rt)
+		if !wide {
+			lo, hi = c.extend(lo, 32, true), c.extend(hi, 32, true)
+		}
+	}
+	if err := c.storeReg("LO", lo); err != nil {
+		return err
+	}
+	return c.storeReg("HI", hi)
+}
+
+// divValue computes a/b or a%b in ty. Division by zero, whose results MIPS
+// leaves unpredictable, does not trap; it gives the RISC-V values (all ones
+// and a). MIN/-1 gives MIN and MIN%-1 gives 0.
+func (c *mipsCtx) divValue(kind, ty, a, b string) string {
+	bits := 64
+	if ty == "i32" {
+		bits = 32
+	}
+	zero := c.emit("icmp eq %s %s, 0", ty, b)
+	bad := zero
+	if kind == "div" || kind == "rem" {
+		isMin := c.emit("icmp eq %s %s, %d", ty, a, int64(-1)<<(bits-1))
+		isNeg1 := c.emit("icmp eq %s %s, -1", ty, b)
+		ov := c.emit("and i1 %s, %s", isMin, isNeg1)
+		bad = c.emit("or i1 %s, %s", zero, ov)
+	}
+	safe := c.emit("select i1 %s, %s 1, %s %s", bad, ty, ty, b)
+	insn := map[string]string{"div": "sdiv", "divu": "udiv", "rem": "srem", "remu": "urem"}[kind]
+	q := c.emit("%s %s %s, %s", insn, ty, a, safe)
+	onZero := "-1"
+	if kind == "rem" || kind == "remu" {
+		onZero = a
+	}
+	return c.emit("select i1 %s, %s %s, %s %s", zero, ty, onZero, ty, q)
+}
+
+// lowerCMOV lowers "CMOVN rt, rs, rd", which sets rd = rs if rt != 0
+// (CMOVZ: if rt == 0), and "CMOVT rs, rd", which tests FCC0 (CMOVF: its
+// complement).
+func (c *mipsCtx) lowerCMOV(op string, ins Instr) error {
+	var cond Operand
+	args := ins.Args
+	if op == "CMOVN" || op == "CMOVZ" {
+		if len(args) != 3 || !mipsIsRReg(args[0]) {
+			return fmt.Errorf("%s %s expects reg, reg, reg: %q", c.arch, op, ins.Raw)
+		}
+		cond, args = args[0], args[1:]
+	} else {
+		if len(args) != 2 {
+			return fmt.Errorf("%s %s expects reg, reg: %q", c.arch, op, ins.Raw)
+		}
+		cond = Operand{Kind: OpReg, Reg: "FCC0"}
+	}
+	for _, a := range args {
+		if !mipsIsRReg(a) {
+			return fmt.Errorf("%s %s expects R registers: %q", c.arch, op, ins.Raw)
+		}
+	}
+	cv, err := c.loadReg(cond.Reg)
+	if err != nil {
+		return err
+	}
+	src, err := c.loadReg(args[0].Reg)
+	if err != nil {
+		return err
+	}
+	old, err := c.loadReg(args[1].Reg)
+	if err != nil {
+		return err
+	}
+	pred := "ne"
+	if op == "CMOVZ" || op == "CMOVF" {
+		pred = "eq"
+	}
+	take := c.emit("icmp %s i64 %s, 0", pred, cv)
+	return c.storeReg(args[1].Reg, c.emit("select i1 %s, i64 %s, i64 %s", take, src, old))
+}
+
+// lowerTrap lowers "TEQ $code, rs, rt", which traps if rs == rt (TNE: if
+// they differ). Without rs the comparison is against R0.
+func (c *mipsCtx) lowerTrap(op string, ins Instr) error {
+	if len(ins.Args) != 2 && len(ins.Args) != 3 || ins.Args[0].Kind != OpImm {
+		return fmt.Errorf("%s %s expects $code, [reg,] reg: %q", c.arch, op, ins.Raw)
+	}
+	for _, a := range ins.Args[1:] {
+		if !mipsIsRReg(a) {
+			return fmt.Errorf("%s %s expects R registers: %q", c.arch, op, ins.Raw)
+		}
+	}
+	a, b := "0", ""
+	var err error
+	if len(ins.Args) == 3 {
+		if a, err = c.loadReg(ins.Args[1].Reg); err != nil {
+			return err
+		}
+	}
+	if b, err = c.loadReg(ins.Args[len(ins.Args)-1].Reg); err != nil {
+		return err
+	}
+	pred := "eq"
+	if op == "TNE" {
+		pred = "ne"
+	}
+	cond := c.emit("icmp %s i64 %s, %s", pred, a, b)
+	id := c.newTmp()
+	fmt.Fprintf(c.b, "  br i1 %s, label %%trap_%s, label %%trap_cont_%s\n", cond, id, id)
+	fmt.Fprintf(c.b, "\ntrap_%s:\n  call void @llvm.trap()\n  unreachable\n", id)
+	fmt.Fprintf(c.b, "\ntrap_cont_%s:\n", id)
+	return nil
+}
+
+// mipsUnaryOps lists the "OP rs, rd" operations, with the width they need;
+// NEGW and NEGV may also name a single register, which is both source and
+// destination.
+var mipsUnaryOps = map[string]bool{
+	"NEGW": false, "NEGV": true,
+	"CLZ": false, "CLO": false, "DCLZ": true, "DCLO": true,
+	"SEB": false, "SEH": false,
+	"WSBH": false, "DSBH": true, "DSHD": true,
+}
+
+func (c *mipsCtx) lowerUnary(op string, ins Instr) error {
+	if mipsUnaryOps[op] && !c.wide {
+		return fmt.Errorf("%s: %s needs mips64: %q", c.arch, op, ins.Raw)
+	}
+	oneReg := op == "NEGV" || op == "NEGW"
+	if !(len(ins.Args) == 2 || len(ins.Args) == 1 && oneReg) {
+		return fmt.Errorf("%s %s expects reg, reg: %q", c.arch, op, ins.Raw)
+	}
+	for _, a := range ins.Args {
+		if !mipsIsRReg(a) {
+			return fmt.Errorf("%s %s expects R registers: %q", c.arch, op, ins.Raw)
+		}
+	}
+	a, err := c.loadReg(ins.Args[0].Reg)
+	if err != nil {
+		return err
+	}
+	var v string
+	switch op {
+	case "NEGV":
+		v = c.emit("sub i64 0, %s", a)
+	case "NEGW":
+		v = c.extend(c.emit("sub i32 0, %s", c.trunc32(a)), 32, true)
+	case "CLZ":
+		v = c.extend(c.emit("call i32 @llvm.ctlz.i32(i32 %s, i1 false)", c.trunc32(a)), 32, false)
+	case "CLO":
+		inv := c.emit("xor i32 %s, -1", c.trunc32(a))
+		v = c.extend(c.emit("call i32 @llvm.ctlz.i32(i32 %s, i1 false)", inv), 32, false)
+	case "DCLZ":
+		v = c.emit("call i64 @llvm.ctlz.i64(i64 %s, i1 false)", a)
+	case "DCLO":
+		v = c.emit("call i64 @llvm.ctlz.i64(i64 %s, i1 false)", c.emit("xor i64 %s, -1", a))
+	case "SEB":
+		v = c.narrow(a, 8, true)
+	case "SEH":
+		v = c.narrow(a, 16, true)
+	case "WSBH":
+		v = c.narrow(c.swapLanes(a, 8, 16), 32, true)
+	case "DSBH":
+		v = c.swapLanes(a, 8, 16)
+	case "DSHD":
+		// Reverse the four halfwords: swap halves within words, then words.
+		s := c.swapLanes(a, 16, 32)
+		v = c.emit("call i64 @llvm.fshr.i64(i64 %s, i64 %s, i64 32)", s, s)
+	}
+	return c.storeReg(ins.Args[len(ins.Args)-1].Reg, v)
+}
+
+// swapLanes swaps adjacent lane-bit fields inside every group-bit field
+// of v: ((v >> lane) & m) | ((v & m) << lane).
+func (c *mipsCtx) swapLanes(v string, lane, group int) string {
+	var m uint64
+	for i := 0; i < 64; i += group {
+		m |= (uint64(1)<<lane - 1) << i
+	}
+	hi := c.emit("lshr i64 %s, %d", v, lane)
+	hi = c.emit("and i64 %s, %d", hi, int64(m))
+	lo := c.emit("and i64 %s, %d", v, int64(m))
+	lo = c.emit("shl i64 %s, %d", lo, lane)
+	return c.emit("or i64 %s, %s", hi, lo)
+}
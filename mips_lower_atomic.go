@@ -0,0 +1,96 @@
+package plan9asm
+
+import "fmt"
+
+func (c *mipsCtx) lowerAtomic(op string, ins Instr) (ok bool, terminated bool, err error) {
+	switch op {
+	case "SYNC":
+		c.b.WriteString("  fence seq_cst\n")
+		return true, false, nil
+	case "LL", "LLV":
+		if op == "LLV" && !c.wide {
+			return true, false, fmt.Errorf("%s: %s needs mips64: %q", c.arch, op, ins.Raw)
+		}
+		return true, false, c.lowerLL(op, ins)
+	case "SC", "SCV":
+		if op == "SCV" && !c.wide {
+			return true, false, fmt.Errorf("%s: %s needs mips64: %q", c.arch, op, ins.Raw)
+		}
+		return true, false, c.lowerSC(op, ins)
+	}
+	return false, false, nil
+}
+
+// lowerLL lowers "LL (rs), rt", which loads and reserves rs; LL loads a
+// sign-extended word and LLV a doubleword.
+func (c *mipsCtx) lowerLL(op string, ins Instr) error {
+	if len(ins.Args) != 2 || ins.Args[0].Kind != OpMem || !mipsIsRReg(ins.Args[1]) {
+		return fmt.Errorf("%s %s expects (reg), reg: %q", c.arch, op, ins.Raw)
+	}
+	ty, bits := "i32", 32
+	if op == "LLV" {
+		ty, bits = "i64", 64
+	}
+	ptr, err := c.memPtr(ins.Args[0].Mem)
+	if err != nil {
+		return err
+	}
+	v := c.emit("load atomic %s, ptr %s seq_cst, align %d", ty, ptr, bits/8)
+	if bits == 32 {
+		v = c.extend(v, 32, true)
+	}
+	fmt.Fprintf(c.b, "  store i1 true, ptr %s\n", c.reservedValidSlot)
+	fmt.Fprintf(c.b, "  store ptr %s, ptr %s\n", ptr, c.reservedPtrSlot)
+	fmt.Fprintf(c.b, "  store i64 %s, ptr %s\n", v, c.reservedValueSlot)
+	return c.storeReg(ins.Args[1].Reg, v)
+}
+
+// lowerSC lowers "SC rt, (rs)", which stores rt if the reservation still
+// holds and then sets rt to 1 on success and 0 on failure. The reservation
+// is modeled as the value LL loaded, so the store is a cmpxchg against it.
+func (c *mipsCtx) lowerSC(op string, ins Instr) error {
+	if len(ins.Args) != 2 || !mipsIsRReg(ins.Args[0]) || ins.Args[1].Kind != OpMem {
+		return fmt.Errorf("%s %s expects reg, (reg): %q", c.arch, op, ins.Raw)
+	}
+	ty, bits := "i32", 32
+	if op == "SCV" {
+		ty, bits = "i64", 64
+	}
+	src, err := c.loadReg(ins.Args[0].Reg)
+	if err != nil {
+		return err
+	}
+	ptr, err := c.memPtr(ins.Args[1].Mem)
+	if err != nil {
+		return err
+	}
+	valid := c.emit("load i1, ptr %s", c.reservedValidSlot)
+	resPtr := c.emit("load ptr, ptr %s", c.reservedPtrSlot)
+	same := c.emit("icmp eq ptr %s, %s", resPtr, ptr)
+	canTry := c.emit("and i1 %s, %s", valid, same)
+
+	id := c.newTmp()
+	tryLabel := "sc_try_" + id
+	failLabel := "sc_fail_" + id
+	mergeLabel := "sc_merge_" + id
+	fmt.Fprintf(c.b, "  br i1 %s, label %%%s, label %%%s\n", canTry, tryLabel, failLabel)
+
+	fmt.Fprintf(c.b, "\n%s:\n", tryLabel)
+	expected := c.emit("load i64, ptr %s", c.reservedValueSlot)
+	newv := src
+	if bits == 32 {
+		expected, newv = c.trunc32(expected), c.trunc32(src)
+	}
+	cx := c.emit("cmpxchg ptr %s, %s %s, %s %s seq_cst seq_cst, align %d", ptr, ty, expected, ty, newv, bits/8)
+	stored := c.emit("extractvalue {%s, i1} %s, 1", ty, cx)
+	tryStatus := c.emit("zext i1 %s to i64", stored)
+	fmt.Fprintf(c.b, "  br label %%%s\n", mergeLabel)
+
+	fmt.Fprintf(c.b, "\n%s:\n", failLabel)
+	fmt.Fprintf(c.b, "  br label %%%s\n", mergeLabel)
+
+	fmt.Fprintf(c.b, "\n%s:\n", mergeLabel)
+	status := c.emit("phi i64 [ %s, %%%s ], [ 0, %%%s ]", tryStatus, tryLabel, failLabel)
+	fmt.Fprintf(c.b, "  store i1 false, ptr %s\n", c.reservedValidSlot)
+	return c.storeReg(ins.Args[0].Reg, status)
+}
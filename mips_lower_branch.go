@@ -0,0 +1,409 @@
+package plan9asm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// mipsBranchConds maps the conditional branches to icmp predicates.
+// "BEQ rs, rt, label" branches if rs == rt; with a single register the
+// comparison is against zero (R0). BLEZ, BGTZ, BLTZ and BGEZ take a single
+// register only.
+var mipsBranchConds = map[string]string{
+	"BEQ": "eq", "BNE": "ne",
+	"BLEZ": "sle", "BGTZ": "sgt", "BLTZ": "slt", "BGEZ": "sge",
+}
+
+// branchTarget resolves a label, local symbol or n(PC) operand of the
+// instruction at index ii of block bi to a block name.
+func (c *mipsCtx) branchTarget(bi, ii int, op Operand) (string, bool) {
+	switch op.Kind {
+	case OpIdent:
+		return op.Ident, true
+	case OpSym:
+		s := strings.TrimSpace(op.Sym)
+		if strings.HasSuffix(s, "(SB)") {
+			return "", false
+		}
+		return strings.TrimSuffix(s, "<>"), s != ""
+	case OpMem:
+		if op.Mem.Base != PC {
+			return "", false
+		}
+		tbi, ok := c.blockByIdx[c.blockBase[bi]+ii+int(op.Mem.Off)]
+		if !ok {
+			return "", false
+		}
+		return c.blocks[tbi].name, true
+	}
+	return "", false
+}
+
+func (c *mipsCtx) condBr(bi int, cond, tgt string) {
+	fmt.Fprintf(c.b, "  br i1 %s, label %%%s, label %%%s\n", cond, arm64LLVMBlockName(tgt), arm64LLVMBlockName(c.blocks[bi+1].name))
+}
+
+func (c *mipsCtx) lowerBranch(bi, ii int, op string, ins Instr) (ok bool, terminated bool, err error) {
+	if pred, found := mipsBranchConds[op]; found {
+		nregs := len(ins.Args) - 1
+		if strings.HasSuffix(op, "Z") && nregs != 1 || nregs < 1 || nregs > 2 {
+			return true, false, fmt.Errorf("%s %s expects registers and a target: %q", c.arch, op, ins.Raw)
+		}
+		for _, a := range ins.Args[:nregs] {
+			if !mipsIsRReg(a) {
+				return true, false, fmt.Errorf("%s %s expects R registers: %q", c.arch, op, ins.Raw)
+			}
+		}
+		tgt, ok := c.branchTarget(bi, ii, ins.Args[nregs])
+		if !ok {
+			return true, false, fmt.Errorf("%s %s invalid target: %q", c.arch, op, ins.Raw)
+		}
+		if bi+1 >= len(c.blocks) {
+			return true, false, fmt.Errorf("%s %s needs fallthrough block: %q", c.arch, op, ins.Raw)
+		}
+		a, err := c.loadReg(ins.Args[0].Reg)
+		if err != nil {
+			return true, false, err
+		}
+		b := "0"
+		if nregs == 2 {
+			if b, err = c.loadReg(ins.Args[1].Reg); err != nil {
+				return true, false, err
+			}
+		}
+		c.condBr(bi, c.emit("icmp %s i64 %s, %s", pred, a, b), tgt)
+		return true, true, nil
+	}
+
+	switch op {
+	case "BFPT", "BFPF":
+		// BFPT branches if FCC0 is set, BFPF if it is clear.
+		if len(ins.Args) != 1 {
+			return true, false, fmt.Errorf("%s %s expects a target: %q", c.arch, op, ins.Raw)
+		}
+		tgt, ok := c.branchTarget(bi, ii, ins.Args[0])
+		if !ok {
+			return true, false, fmt.Errorf("%s %s invalid target: %q", c.arch, op, ins.Raw)
+		}
+		if bi+1 >= len(c.blocks) {
+			return true, false, fmt.Errorf("%s %s needs fallthrough block: %q", c.arch, op, ins.Raw)
+		}
+		v, err := c.loadReg("FCC0")
+		if err != nil {
+			return true, false, err
+		}
+		pred := "ne"
+		if op == "BFPF" {
+			pred = "eq"
+		}
+		c.condBr(bi, c.emit("icmp %s i64 %s, 0", pred, v), tgt)
+		return true, true, nil
+
+	case "BGEZAL":
+		// "BGEZAL R0, 1(PC)" branches to the next instruction only to read
+		// the PC into R31, which code uses for the address of its own
+		// text; the function's address stands in for it.
+		if len(ins.Args) != 2 || ins.Args[0].Kind != OpReg || ins.Args[0].Reg != "R0" ||
+			ins.Args[1].Kind != OpMem || ins.Args[1].Mem.Base != PC || ins.Args[1].Mem.Off != 1 {
+			return true, false, fmt.Errorf("%s: only BGEZAL R0, 1(PC) is supported: %q", c.arch, ins.Raw)
+		}
+		return true, false, c.storeReg("R31", c.emit("ptrtoint ptr %s to i64", llvmGlobal(c.sig.Name)))
+
+	case "JMP":
+		if len(ins.Args) != 1 {
+			return true, false, fmt.Errorf("%s JMP expects 1 operand: %q", c.arch, ins.Raw)
+		}
+		return true, true, c.jump(bi, ii, ins.Args[0], ins)
+
+	case "CALL", "JAL":
+		// JAL sym(SB) and JAL (reg) link R31.
+		if len(ins.Args) != 1 {
+			return true, false, fmt.Errorf("%s %s expects 1 operand: %q", c.arch, op, ins.Raw)
+		}
+		return true, false, c.call(ins.Args[0], ins)
+	}
+	return false, false, nil
+}
+
+func (c *mipsCtx) jump(bi, ii int, target Operand, ins Instr) error {
+	if riscv64IsSBSym(target) {
+		return c.tailCallAndRet(target)
+	}
+	if mipsIsRReg(target) {
+		target = Operand{Kind: OpMem, Mem: MemRef{Base: target.Reg}}
+	}
+	if target.Kind == OpMem && target.Mem.Base != PC {
+		if target.Mem.Base == "R31" && target.Mem.Off == 0 && target.Mem.Index == "" {
+			// JMP (R31) returns.
+			return c.lowerRET()
+		}
+		p, err := c.memPtr(target.Mem)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(c.b, "  call void asm sideeffect %q, %q(ptr %s)\n", "jr $0", "r,~{memory}", p)
+		c.lowerRetZero()
+		return nil
+	}
+	tgt, ok := c.branchTarget(bi, ii, target)
+	if !ok {
+		return fmt.Errorf("%s %s invalid target: %q", c.arch, ins.Op, ins.Raw)
+	}
+	c.br(tgt)
+	return nil
+}
+
+func (c *mipsCtx) call(target Operand, ins Instr) error {
+	if riscv64IsSBSym(target) {
+		return c.callSym(target)
+	}
+	if mipsIsRReg(target) {
+		target = Operand{Kind: OpMem, Mem: MemRef{Base: target.Reg}}
+	}
+	if target.Kind != OpMem || target.Mem.Base == PC {
+		return fmt.Errorf("%s %s expects symbol(SB)|reg|(reg): %q", c.arch, ins.Op, ins.Raw)
+	}
+	p, err := c.memPtr(target.Mem)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(c.b, "  call void asm sideeffect %q, %q(ptr %s)\n", "jalr $0", "r,~{$31},~{memory}", p)
+	return nil
+}
+
+// buildArg assembles argument i of csig, of type ty, from the values load
+// returns for its FP slots.
+func (c *mipsCtx) buildArg(csig FuncSig, i int, ty LLVMType, load func(FrameSlot) (string, error)) (string, error) {
+	slots := i386ArgSlots(csig, i)
+	if len(slots) == 0 {
+		return "", fmt.Errorf("no frame slot for arg %d", i)
+	}
+	if len(slots) == 1 && slots[0].Field < 0 {
+		return load(slots[0])
+	}
+	agg := "undef"
+	for _, s := range slots {
+		v, err := load(s)
+		if err != nil {
+			return "", err
+		}
+		agg = c.emit("insertvalue %s %s, %s %s, %d", ty, agg, s.Type, v, s.Field)
+	}
+	return agg, nil
+}
+
+// outArgPtr returns a pointer to the outgoing ABI0 slot s, which lies
+// above the word at 0(R29) that holds the callee's return address.
+func (c *mipsCtx) outArgPtr(s FrameSlot) (string, error) {
+	sp, err := c.loadReg(SP)
+	if err != nil {
+		return "", err
+	}
+	return c.emit("inttoptr i64 %s to ptr", c.emit("add i64 %s, %d", sp, c.ptrBytes+s.Offset)), nil
+}
+
+// callArgs reads csig's arguments. A callee with a frame layout takes
+// them from the outgoing area of the caller's frame, as ABI0 passes them;
+// otherwise they come from csig.ArgRegs, or the C argument registers R4-R7
+// (R4-R11 on mips64).
+func (c *mipsCtx) callArgs(csig FuncSig) ([]string, error) {
+	args := make([]string, 0, len(csig.Args))
+	if c.frameSize > 0 && len(csig.ArgRegs) == 0 && len(csig.Frame.Params) > 0 {
+		for i, ty := range csig.Args {
+			v, err := c.buildArg(csig, i, ty, func(s FrameSlot) (string, error) {
+				p, err := c.outArgPtr(s)
+				if err != nil {
+					return "", err
+				}
+				return c.emit("load %s, ptr %s, align 4", s.Type, p), nil
+			})
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, fmt.Sprintf("%s %s", ty, v))
+		}
+		return args, nil
+	}
+	regs := csig.ArgRegs
+	if len(regs) == 0 {
+		regs = []Reg{"R4", "R5", "R6", "R7"}
+		if c.wide {
+			regs = append(regs, "R8", "R9", "R10", "R11")
+		}
+	}
+	next := 0
+	for i, argTy := range csig.Args {
+		fields, isAgg := parseLiteralStructFields(argTy)
+		if !isAgg || len(csig.ArgRegs) > 0 {
+			fields = []LLVMType{argTy}
+		}
+		agg := "undef"
+		val := ""
+		for fi, fTy := range fields {
+			if next >= len(regs) {
+				return nil, fmt.Errorf("no register for arg %d", i)
+			}
+			if !c.wide && (fTy == I64 || fTy == LLVMType("double")) {
+				return nil, fmt.Errorf("arg %d: a %s argument needs a frame layout", i, fTy)
+			}
+			v, err := c.loadReg(regs[next])
+			if err != nil {
+				return nil, err
+			}
+			next++
+			if val, err = c.i64ToValue(v, fTy); err != nil {
+				return nil, err
+			}
+			if isAgg && len(fields) > 1 {
+				agg = c.emit("insertvalue %s %s, %s %s, %d", argTy, agg, fTy, val, fi)
+				val = agg
+			}
+		}
+		args = append(args, fmt.Sprintf("%s %s", argTy, val))
+	}
+	return args, nil
+}
+
+// storeCallResult writes the result r of a call to csig back where the
+// caller reads it: the outgoing area after the arguments when csig has a
+// frame layout, or R2 (and R3 for a second value).
+func (c *mipsCtx) storeCallResult(csig FuncSig, r string) error {
+	if c.frameSize > 0 && len(csig.Frame.Results) > 0 {
+		multi := len(csig.Frame.Results) > 1 && csig.Frame.Results[len(csig.Frame.Results)-1].Index > 0
+		for _, s := range csig.Frame.Results {
+			v, ty := r, csig.Ret
+			if multi {
+				fields, ok := parseLiteralStructFields(ty)
+				if !ok || s.Index >= len(fields) {
+					return fmt.Errorf("result %d of %s", s.Index, ty)
+				}
+				v, ty = c.emit("extractvalue %s %s, %d", ty, v, s.Index), fields[s.Index]
+			}
+			if s.Field >= 0 {
+				v = c.emit("extractvalue %s %s, %d", ty, v, s.Field)
+			}
+			p, err := c.outArgPtr(s)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(c.b, "  store %s %s, ptr %s, align 4\n", s.Type, v, p)
+		}
+		return nil
+	}
+	fields, isAgg := parseLiteralStructFields(csig.Ret)
+	if !isAgg {
+		fields = []LLVMType{csig.Ret}
+	}
+	if len(fields) > 2 {
+		return fmt.Errorf("too many register results")
+	}
+	for fi, fTy := range fields {
+		fv := r
+		if isAgg {
+			fv = c.emit("extractvalue %s %s, %d", csig.Ret, r, fi)
+		}
+		v64, ok, err := c.valueAsI64(fTy, fv)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("unsupported result type %s", fTy)
+		}
+		if err := c.storeReg(Reg(fmt.Sprintf("R%d", 2+fi)), v64); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *mipsCtx) callSym(symOp Operand) error {
+	callee := c.resolve(strings.TrimSuffix(strings.TrimSpace(symOp.Sym), "(SB)"))
+	// Syscall stubs invoke runtime entersyscall/exitsyscall around SYSCALL.
+	// llgo runtime does not require these scheduler hooks at this layer.
+	if callee == "runtime.entersyscall" || callee == "runtime.exitsyscall" {
+		return nil
+	}
+	csig, ok := c.sigs[callee]
+	if !ok {
+		csig = FuncSig{Name: callee, Ret: Void}
+	}
+	args, err := c.callArgs(csig)
+	if err != nil {
+		return fmt.Errorf("%s call %q: %v", c.arch, callee, err)
+	}
+	if csig.Ret == Void {
+		fmt.Fprintf(c.b, "  call void %s(%s)\n", llvmGlobal(callee), strings.Join(args, ", "))
+		return nil
+	}
+	r := c.emit("call %s %s(%s)", csig.Ret, llvmGlobal(callee), strings.Join(args, ", "))
+	if err := c.storeCallResult(csig, r); err != nil {
+		return fmt.Errorf("%s call %q: %v", c.arch, callee, err)
+	}
+	return nil
+}
+
+func (c *mipsCtx) tailCallAndRet(symOp Operand) error {
+	callee := c.resolve(strings.TrimSuffix(strings.TrimSpace(symOp.Sym), "(SB)"))
+	csig, ok := c.sigs[callee]
+	if !ok {
+		// Cross-package trampoline (e.g. sync/atomic -> internal/runtime/atomic).
+		// Without an explicit signature, fall back to the caller's.
+		csig = c.sig
+		csig.Name = callee
+	}
+
+	// A JMP hands the callee the caller's own argument frame. With the
+	// caller's signature that is the caller's args; otherwise the callee's
+	// slots are read from the caller's frame.
+	var args []string
+	switch {
+	case len(csig.ArgRegs) == 0 && sameLLVMTypes(csig.Args, c.sig.Args) && csig.Ret == c.sig.Ret:
+		for i, ty := range csig.Args {
+			args = append(args, fmt.Sprintf("%s %%arg%d", ty, i))
+		}
+	case len(csig.ArgRegs) == 0 && len(csig.Frame.Params) > 0:
+		for i, ty := range csig.Args {
+			v, err := c.buildArg(csig, i, ty, func(s FrameSlot) (string, error) {
+				v, err := c.evalFPLoad(Operand{Kind: OpFP, FPOffset: s.Offset}, int(8*c.slotSize(s.Type)), false)
+				if err != nil {
+					return "", err
+				}
+				return c.i64ToValue(v, s.Type)
+			})
+			if err != nil {
+				return fmt.Errorf("%s tailcall %q: %v", c.arch, callee, err)
+			}
+			args = append(args, fmt.Sprintf("%s %s", ty, v))
+		}
+	default:
+		var err error
+		if args, err = c.callArgs(csig); err != nil {
+			return fmt.Errorf("%s tailcall %q: %v", c.arch, callee, err)
+		}
+	}
+
+	if csig.Ret == Void {
+		fmt.Fprintf(c.b, "  call void %s(%s)\n", llvmGlobal(callee), strings.Join(args, ", "))
+		if len(c.fpResults) > 0 {
+			return c.lowerRET()
+		}
+		c.lowerRetZero()
+		return nil
+	}
+	r := c.emit("call %s %s(%s)", csig.Ret, llvmGlobal(callee), strings.Join(args, ", "))
+	if c.sig.Ret == Void {
+		c.b.WriteString("  ret void\n")
+		return nil
+	}
+	if csig.Ret != c.sig.Ret {
+		v64, ok, err := c.valueAsI64(csig.Ret, r)
+		if err != nil || !ok {
+			return fmt.Errorf("%s tailcall return mismatch: caller %s callee %s", c.arch, c.sig.Ret, csig.Ret)
+		}
+		if r, err = c.i64ToValue(v64, c.sig.Ret); err != nil {
+			return fmt.Errorf("%s tailcall return mismatch: caller %s callee %s", c.arch, c.sig.Ret, csig.Ret)
+		}
+	}
+	fmt.Fprintf(c.b, "  ret %s %s\n", c.sig.Ret, r)
+	return nil
+}
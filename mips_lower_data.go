@@ -0,0 +1,229 @@
+package plan9asm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// mipsMovWidth gives the access width and signedness of the MOV forms.
+// MOVF and MOVD move F register bits; MOVV and MOVWU exist on mips64 and
+// mips64le only.
+var mipsMovWidth = map[string]struct {
+	bits   int
+	signed bool
+	float  bool
+	wide   bool
+}{
+	"MOVV":  {64, true, false, true},
+	"MOVW":  {32, true, false, false},
+	"MOVWU": {32, false, false, true},
+	"MOVH":  {16, true, false, false},
+	"MOVHU": {16, false, false, false},
+	"MOVB":  {8, true, false, false},
+	"MOVBU": {8, false, false, false},
+	"MOVF":  {32, false, true, false},
+	"MOVD":  {64, false, true, false},
+}
+
+func (c *mipsCtx) lowerData(op string, ins Instr) (ok bool, terminated bool, err error) {
+	switch op {
+	case "MOVWL", "MOVWR", "MOVVL", "MOVVR":
+		return true, false, c.lowerUnaligned(op, ins)
+	}
+	w, found := mipsMovWidth[op]
+	if !found {
+		return false, false, nil
+	}
+	if w.wide && !c.wide {
+		return true, false, fmt.Errorf("%s: %s needs mips64: %q", c.arch, op, ins.Raw)
+	}
+	if len(ins.Args) != 2 {
+		return true, false, fmt.Errorf("%s %s expects 2 operands: %q", c.arch, op, ins.Raw)
+	}
+	src, dst := ins.Args[0], ins.Args[1]
+	if mipsIsWRegOp(src) || mipsIsWRegOp(dst) {
+		return true, false, fmt.Errorf("%s %s: W registers move with VMOV*: %q", c.arch, op, ins.Raw)
+	}
+	isF := mipsIsFRegOp
+	signed := w.signed
+	switch {
+	case w.float:
+		// MOVF and MOVD move between F registers and memory.
+		if (!isF(src) && !isF(dst)) || src.Kind == OpImm || mipsIsRReg(src) || mipsIsRReg(dst) || mipsIsHILO(src) || mipsIsHILO(dst) {
+			return true, false, fmt.Errorf("%s %s moves between F registers and memory: %q", c.arch, op, ins.Raw)
+		}
+	case isF(src) || isF(dst):
+		// MOVW moves the low word between R and F registers (mtc1, mfc1,
+		// which sign-extends); MOVV moves all 64 bits.
+		if src.Kind != OpReg || dst.Kind != OpReg || (op != "MOVV" && op != "MOVW") {
+			return true, false, fmt.Errorf("%s %s: bad F register move: %q", c.arch, op, ins.Raw)
+		}
+		signed = true
+	}
+	if src.Kind != OpReg && dst.Kind != OpReg {
+		// Only zero can be stored without a register.
+		if src.Kind != OpImm || src.Imm != 0 {
+			return true, false, fmt.Errorf("%s %s needs a register operand: %q", c.arch, op, ins.Raw)
+		}
+	}
+
+	v, err := c.movSrc(src, w.bits, signed)
+	if err != nil {
+		return true, false, fmt.Errorf("%s %s: %v: %q", c.arch, op, err, ins.Raw)
+	}
+	if err := c.movDst(dst, w.bits, v); err != nil {
+		return true, false, fmt.Errorf("%s %s: %v: %q", c.arch, op, err, ins.Raw)
+	}
+	return true, false, nil
+}
+
+// movSrc reads a MOV source as an i64, extended from bits.
+func (c *mipsCtx) movSrc(src Operand, bits int, signed bool) (string, error) {
+	switch src.Kind {
+	case OpImm:
+		v := src.Imm
+		switch {
+		case bits == 64:
+		case signed:
+			v = v << (64 - bits) >> (64 - bits)
+		default:
+			v = int64(uint64(v) << (64 - bits) >> (64 - bits))
+		}
+		return fmt.Sprintf("%d", v), nil
+	case OpReg:
+		v, err := c.loadReg(src.Reg)
+		if err != nil {
+			return "", err
+		}
+		return c.narrow(v, bits, signed), nil
+	case OpMem:
+		return c.loadMem(src.Mem, bits, signed)
+	case OpFP:
+		return c.evalFPLoad(src, bits, signed)
+	case OpFPAddr:
+		return c.evalFPAddr64(src)
+	case OpSym:
+		if mipsIsAddrSym(src) {
+			return c.symAddr(src.Sym)
+		}
+		s := strings.TrimSpace(src.Sym)
+		if !strings.HasSuffix(s, "(SB)") {
+			break
+		}
+		p, err := c.ptrFromSB(s)
+		if err != nil {
+			return "", err
+		}
+		v := c.emit("load i%d, ptr %s", bits, p)
+		if bits == 64 {
+			return v, nil
+		}
+		return c.extend(v, bits, signed), nil
+	}
+	return "", fmt.Errorf("unsupported source %s", src.String())
+}
+
+// movDst writes the low bits of v to a MOV destination.
+func (c *mipsCtx) movDst(dst Operand, bits int, v string) error {
+	switch dst.Kind {
+	case OpReg:
+		return c.storeReg(dst.Reg, v)
+	case OpMem:
+		return c.storeMem(dst.Mem, bits, v)
+	case OpFP:
+		return c.storeFPResult(dst.FPOffset, bits, v)
+	case OpSym:
+		s := strings.TrimSpace(dst.Sym)
+		if strings.HasPrefix(s, "$") || !strings.HasSuffix(s, "(SB)") {
+			break
+		}
+		p, err := c.ptrFromSB(s)
+		if err != nil {
+			return err
+		}
+		if bits < 64 {
+			v = c.emit("trunc i64 %s to i%d", v, bits)
+		}
+		fmt.Fprintf(c.b, "  store i%d %s, ptr %s\n", bits, v, p)
+		return nil
+	}
+	return fmt.Errorf("unsupported destination %s", dst.String())
+}
+
+// lowerUnaligned lowers the unaligned halves MOVWL/MOVWR (lwl/lwr,
+// swl/swr) and their doubleword forms MOVVL/MOVVR. Each touches the part
+// of the aligned word holding the address: with k the offset within the
+// word, the L form moves the bytes from the address to the word's end in
+// memory order into the register's high end, and the R form the bytes
+// from the word's start up to the address into its low end. Which shift
+// that takes depends on the byte order.
+func (c *mipsCtx) lowerUnaligned(op string, ins Instr) error {
+	n := int64(4)
+	if op == "MOVVL" || op == "MOVVR" {
+		if !c.wide {
+			return fmt.Errorf("%s: %s needs mips64: %q", c.arch, op, ins.Raw)
+		}
+		n = 8
+	}
+	if len(ins.Args) != 2 {
+		return fmt.Errorf("%s %s expects 2 operands: %q", c.arch, op, ins.Raw)
+	}
+	load := ins.Args[0].Kind == OpMem && mipsIsRReg(ins.Args[1])
+	if !load && !(mipsIsRReg(ins.Args[0]) && ins.Args[1].Kind == OpMem) {
+		return fmt.Errorf("%s %s expects mem, reg or reg, mem: %q", c.arch, op, ins.Raw)
+	}
+	mem, reg := ins.Args[1].Mem, ins.Args[0].Reg
+	if load {
+		mem, reg = ins.Args[0].Mem, ins.Args[1].Reg
+	}
+	ty := fmt.Sprintf("i%d", 8*n)
+	addr, err := c.addrI64(mem)
+	if err != nil {
+		return err
+	}
+	k := c.emit("and i64 %s, %d", addr, n-1)
+	p := c.emit("inttoptr i64 %s to ptr", c.emit("and i64 %s, %d", addr, -n))
+	a := c.emit("shl i64 %s, 3", k)
+	b := c.emit("sub i64 %d, %s", 8*(n-1), a)
+	// L shifts by b on little-endian and by a on big-endian; R the other
+	// way round.
+	sh := a
+	if c.bigEndian == (op[len(op)-1] == 'R') {
+		sh = b
+	}
+	if n == 4 {
+		sh = c.trunc32(sh)
+	}
+	rt, err := c.loadReg(reg)
+	if err != nil {
+		return err
+	}
+	if n == 4 {
+		rt = c.trunc32(rt)
+	}
+	w := c.emit("load %s, ptr %s, align %d", ty, p, n)
+	// low keeps the sh low bits, notHigh clears the bits lshr -1, sh keeps.
+	low := func() string { return c.emit("sub %s %s, 1", ty, c.emit("shl %s 1, %s", ty, sh)) }
+	notHigh := func() string { return c.emit("xor %s %s, -1", ty, c.emit("lshr %s -1, %s", ty, sh)) }
+	var v string
+	switch {
+	case load && op[len(op)-1] == 'L':
+		v = c.emit("or %s %s, %s", ty, c.emit("shl %s %s, %s", ty, w, sh), c.emit("and %s %s, %s", ty, rt, low()))
+	case load:
+		keep := c.emit("and %s %s, %s", ty, rt, notHigh())
+		v = c.emit("or %s %s, %s", ty, c.emit("lshr %s %s, %s", ty, w, sh), keep)
+	case op[len(op)-1] == 'L':
+		keep := c.emit("and %s %s, %s", ty, w, notHigh())
+		v = c.emit("or %s %s, %s", ty, keep, c.emit("lshr %s %s, %s", ty, rt, sh))
+	default:
+		v = c.emit("or %s %s, %s", ty, c.emit("and %s %s, %s", ty, w, low()), c.emit("shl %s %s, %s", ty, rt, sh))
+	}
+	if !load {
+		fmt.Fprintf(c.b, "  store %s %s, ptr %s, align %d\n", ty, v, p, n)
+		return nil
+	}
+	if n == 4 {
+		v = c.extend(v, 32, true)
+	}
+	return c.storeReg(reg, v)
+}
@@ -0,0 +1,174 @@
+package plan9asm
+
+import (
+	"fmt"
+	"strings"
+)
+
+func (c *mipsCtx) loadF(r Reg, ty string) (string, error) {
+	v, err := c.loadReg(r)
+	if err != nil {
+		return "", err
+	}
+	return c.i64ToValue(v, LLVMType(ty))
+}
+
+func (c *mipsCtx) storeF(r Reg, ty, v string) error {
+	v64, _, err := c.valueAsI64(LLVMType(ty), v)
+	if err != nil {
+		return err
+	}
+	return c.storeReg(r, v64)
+}
+
+// mipsCheckFRegs reports whether ins has exactly n operands, all of them
+// F registers.
+func mipsCheckFRegs(ins Instr, n int) bool {
+	if len(ins.Args) != n {
+		return false
+	}
+	for _, a := range ins.Args {
+		if !mipsIsFRegOp(a) {
+			return false
+		}
+	}
+	return true
+}
+
+// mipsFPFormats maps the format letters of the conversion mnemonics
+// (MOVWD converts the word in an F register to a double) to LLVM types.
+var mipsFPFormats = map[byte]string{'F': "float", 'D': "double", 'W': "i32", 'V': "i64"}
+
+func (c *mipsCtx) lowerFP(op string, ins Instr) (ok bool, terminated bool, err error) {
+	if ok, err := c.lowerFPConv(op, ins); ok {
+		return true, false, err
+	}
+	if len(op) < 4 || (op[len(op)-1] != 'D' && op[len(op)-1] != 'F') {
+		return false, false, nil
+	}
+	base, prec := op[:len(op)-1], op[len(op)-1]
+	ty, suffix := loong64FPType(prec)
+
+	switch base {
+	case "ADD", "SUB", "MUL", "DIV":
+		// "OP ft, fs, fd" computes fd = fs OP ft; "OP ft, fd" reads fd.
+		if !mipsCheckFRegs(ins, 3) && !mipsCheckFRegs(ins, 2) {
+			return true, false, fmt.Errorf("%s %s expects F registers: %q", c.arch, op, ins.Raw)
+		}
+		b, err := c.loadF(ins.Args[0].Reg, ty)
+		if err != nil {
+			return true, false, err
+		}
+		a, err := c.loadF(ins.Args[1].Reg, ty)
+		if err != nil {
+			return true, false, err
+		}
+		v := c.emit("f%s %s %s, %s", strings.ToLower(base), ty, a, b)
+		return true, false, c.storeF(ins.Args[len(ins.Args)-1].Reg, ty, v)
+
+	case "ABS", "NEG", "SQRT":
+		if !mipsCheckFRegs(ins, 2) {
+			return true, false, fmt.Errorf("%s %s expects F, F: %q", c.arch, op, ins.Raw)
+		}
+		a, err := c.loadF(ins.Args[0].Reg, ty)
+		if err != nil {
+			return true, false, err
+		}
+		var v string
+		switch base {
+		case "NEG":
+			v = c.emit("fneg %s %s", ty, a)
+		case "ABS":
+			v = c.emit("call %s @llvm.fabs.%s(%s %s)", ty, suffix, ty, a)
+		default:
+			v = c.emit("call %s @llvm.sqrt.%s(%s %s)", ty, suffix, ty, a)
+		}
+		return true, false, c.storeF(ins.Args[1].Reg, ty, v)
+
+	case "CMPEQ", "CMPGT", "CMPGE":
+		// "CMPGTD fs, ft" sets FCC0 = fs > ft; a NaN operand clears it.
+		if !mipsCheckFRegs(ins, 2) {
+			return true, false, fmt.Errorf("%s %s expects F, F: %q", c.arch, op, ins.Raw)
+		}
+		a, err := c.loadF(ins.Args[0].Reg, ty)
+		if err != nil {
+			return true, false, err
+		}
+		b, err := c.loadF(ins.Args[1].Reg, ty)
+		if err != nil {
+			return true, false, err
+		}
+		pred := map[string]string{"CMPEQ": "oeq", "CMPGT": "ogt", "CMPGE": "oge"}[base]
+		cmp := c.emit("fcmp %s %s %s, %s", pred, ty, a, b)
+		return true, false, c.storeReg("FCC0", c.emit("zext i1 %s to i64", cmp))
+	}
+	return false, false, nil
+}
+
+// lowerFPConv lowers the conversions between the formats an F register
+// holds: "MOVFD fs, fd" widens a single, "MOVWD fs, fd" converts the word
+// in fs, and "MOVDW fs, fd" converts to a word rounding to nearest, the
+// mode Go leaves the FCSR in. TRUNCDW and the other TRUNC forms round
+// toward zero. Out-of-range results saturate.
+func (c *mipsCtx) lowerFPConv(op string, ins Instr) (bool, error) {
+	var from, to string
+	round := "roundeven"
+	switch {
+	case strings.HasPrefix(op, "TRUNC") && len(op) == 7:
+		from, to, round = mipsFPFormats[op[5]], mipsFPFormats[op[6]], ""
+		if from != "float" && from != "double" || to != "i32" && to != "i64" {
+			return false, nil
+		}
+	case strings.HasPrefix(op, "MOV") && len(op) == 5:
+		from, to = mipsFPFormats[op[3]], mipsFPFormats[op[4]]
+		if from == "" || to == "" || from == to || from[0] == 'i' && to[0] == 'i' {
+			return false, nil
+		}
+	default:
+		return false, nil
+	}
+	if !mipsCheckFRegs(ins, 2) {
+		return true, fmt.Errorf("%s %s expects F, F: %q", c.arch, op, ins.Raw)
+	}
+	if (from == "i64" || to == "i64") && !c.wide {
+		return true, fmt.Errorf("%s: %s needs mips64: %q", c.arch, op, ins.Raw)
+	}
+	src, err := c.loadReg(ins.Args[0].Reg)
+	if err != nil {
+		return true, err
+	}
+	dst := ins.Args[1].Reg
+	var v string
+	switch {
+	case from[0] == 'i':
+		if from == "i32" {
+			src = c.trunc32(src)
+		}
+		v = c.emit("sitofp %s %s to %s", from, src, to)
+		return true, c.storeF(dst, to, v)
+	case to[0] == 'i':
+		f, err := c.i64ToValue(src, LLVMType(from))
+		if err != nil {
+			return true, err
+		}
+		sfx := map[string]string{"float": "f32", "double": "f64"}[from]
+		if round != "" {
+			f = c.emit("call %s @llvm.%s.%s(%s %s)", from, round, sfx, from, f)
+		}
+		v = c.emit("call %s @llvm.fptosi.sat.%s.%s(%s %s)", to, to, sfx, from, f)
+		if to == "i32" {
+			v = c.extend(v, 32, false)
+		}
+		return true, c.storeReg(dst, v)
+	}
+	f, err := c.i64ToValue(src, LLVMType(from))
+	if err != nil {
+		return true, err
+	}
+	if from == "float" {
+		v = c.emit("fpext float %s to double", f)
+	} else {
+		v = c.emit("fptrunc double %s to float", f)
+	}
+	return true, c.storeF(dst, to, v)
+}
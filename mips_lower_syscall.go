@@ -0,0 +1,59 @@
+package plan9asm
+
+import "fmt"
+
+func (c *mipsCtx) lowerSyscall(op string, ins Instr) (ok bool, terminated bool, err error) {
+	switch op {
+	case "SYSCALL":
+		// Linux takes the trap number in R2 and the arguments in R4-R7,
+		// then R8 and R9 on mips64; o32 passes the fifth and sixth on the
+		// stack at 16(R29) and 20(R29). It returns the results in R2 and
+		// R3 and flags failure in R7, leaving the positive errno in R2,
+		// which is the BSD convention.
+		s := c.cfg.syscallSite(c.arch, c.b, c.newTmp)
+		s.conv = syscallConvBSD
+		if s.Num, err = c.loadReg("R2"); err != nil {
+			return true, false, err
+		}
+		for _, r := range []Reg{"R4", "R5", "R6", "R7"} {
+			v, err := c.loadReg(r)
+			if err != nil {
+				return true, false, err
+			}
+			s.Args = append(s.Args, v)
+		}
+		for i := int64(0); i < 2; i++ {
+			v := "0"
+			switch {
+			case c.wide:
+				v, err = c.loadReg(Reg(fmt.Sprintf("R%d", 8+i)))
+			case c.frameSize+c.ptrBytes >= 24:
+				v, err = c.loadMem(MemRef{Base: SP, Off: 16 + 4*i}, 32, true)
+			}
+			if err != nil {
+				return true, false, err
+			}
+			s.Args = append(s.Args, v)
+		}
+		res, err := c.cfg.syscallStrategy().Lower(s)
+		if err != nil {
+			return true, false, err
+		}
+		if err := c.storeReg("R2", res.ret(s)); err != nil {
+			return true, false, err
+		}
+		if err := c.storeReg("R3", res.R2); err != nil {
+			return true, false, err
+		}
+		return true, false, c.storeReg("R7", c.emit("select i1 %s, i64 1, i64 0", res.IsErr))
+
+	case "BREAK":
+		c.b.WriteString("  call void @llvm.debugtrap()\n")
+		return true, false, nil
+
+	case "UNDEF":
+		c.b.WriteString("  call void @llvm.trap()\n  unreachable\n")
+		return true, true, nil
+	}
+	return false, false, nil
+}
@@ -0,0 +1,50 @@
+package plan9asm
+
+import "fmt"
+
+// mipsVecLanes gives the lane width of the MSA moves.
+var mipsVecLanes = map[string]int{"VMOVB": 8, "VMOVH": 16, "VMOVW": 32, "VMOVD": 64}
+
+// lowerVec lowers the MSA moves mips64 code uses: "VMOVB $imm, Wd"
+// replicates the immediate into every lane (ldi.b), and "VMOVB off(R), Wd"
+// and "VMOVB Wd, off(R)" load and store all 128 bits. Since every lane
+// keeps its place, the lane width matters only to the replication.
+func (c *mipsCtx) lowerVec(op string, ins Instr) (ok bool, terminated bool, err error) {
+	lane, found := mipsVecLanes[op]
+	if !found {
+		return false, false, nil
+	}
+	if !c.wide {
+		return true, false, fmt.Errorf("%s: %s needs mips64: %q", c.arch, op, ins.Raw)
+	}
+	if len(ins.Args) != 2 {
+		return true, false, fmt.Errorf("%s %s expects 2 operands: %q", c.arch, op, ins.Raw)
+	}
+	src, dst := ins.Args[0], ins.Args[1]
+	switch {
+	case src.Kind == OpImm && mipsIsWRegOp(dst):
+		var v uint64
+		for i := 0; i < 64; i += lane {
+			v |= uint64(src.Imm) & (uint64(1)<<(lane-1)<<1 - 1) << i
+		}
+		// Both halves of the register hold the same pattern.
+		fmt.Fprintf(c.b, "  store i128 %s, ptr %s\n", s390xI128Const(v, v), c.regSlot[dst.Reg])
+	case src.Kind == OpMem && mipsIsWRegOp(dst):
+		p, err := c.memPtr(src.Mem)
+		if err != nil {
+			return true, false, err
+		}
+		v := c.emit("load i128, ptr %s, align 1", p)
+		fmt.Fprintf(c.b, "  store i128 %s, ptr %s\n", v, c.regSlot[dst.Reg])
+	case mipsIsWRegOp(src) && dst.Kind == OpMem:
+		p, err := c.memPtr(dst.Mem)
+		if err != nil {
+			return true, false, err
+		}
+		v := c.emit("load i128, ptr %s", c.regSlot[src.Reg])
+		fmt.Fprintf(c.b, "  store i128 %s, ptr %s, align 1\n", v, p)
+	default:
+		return true, false, fmt.Errorf("%s %s expects $imm, W | mem, W | W, mem: %q", c.arch, op, ins.Raw)
+	}
+	return true, false, nil
+}
@@ -0,0 +1,113 @@
+package plan9asm
+
+import (
+	"strconv"
+	"strings"
+)
+
+// mipsRegAlias maps the register names the mips assemblers accept besides
+// R0-R31 and F0-F31. R29 is the hardware stack pointer, so it shares the
+// SP slot. g must be listed because parseReg reads it as the arm64 alias
+// of R28, which mips64 code calls RSB.
+var mipsRegAlias = map[string]string{
+	"R29": "SP",
+	"g":   "R30",
+	"RSB": "R28",
+}
+
+// mipsIs64 reports whether arch is one of the 64-bit MIPS dialects.
+func mipsIs64(arch Arch) bool {
+	return arch == ArchMIPS64 || arch == ArchMIPS64LE
+}
+
+// archIsMIPS reports whether arch is one of the four MIPS dialects, which
+// share one backend.
+func archIsMIPS(arch Arch) bool {
+	switch arch {
+	case ArchMIPS, ArchMIPSLE, ArchMIPS64, ArchMIPS64LE:
+		return true
+	}
+	return false
+}
+
+// mipsParseOperands parses an operand list in the mips dialect. Besides
+// the aliases it reads HI, LO, FCRn and the MSA registers W0-W31 (which
+// parseReg would take for arm64 aliases of Rn) as registers of their own,
+// keeps $off(Rn) address constants as symbols, and reads lower-case names
+// as labels rather than registers.
+func mipsParseOperands(s string) ([]Operand, error) {
+	if s == "" {
+		return nil, nil
+	}
+	s = canonicalRegAliases(s, mipsRegAlias)
+	parts := splitTopLevelCSV(s)
+	out := make([]Operand, 0, len(parts))
+	for _, p := range parts {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		if p == "HI" || p == "LO" || mipsIsWName(p) || mipsIsFCRName(p) {
+			out = append(out, Operand{Kind: OpReg, Reg: Reg(p)})
+			continue
+		}
+		if _, ok := ppc64AddrConst(p); ok {
+			out = append(out, Operand{Kind: OpSym, Sym: p})
+			continue
+		}
+		op, err := parseOperand(p)
+		if err != nil {
+			return nil, err
+		}
+		if op.Kind == OpReg && p != strings.ToUpper(p) {
+			op = Operand{Kind: OpIdent, Ident: p}
+		}
+		out = append(out, op)
+	}
+	return out, nil
+}
+
+func mipsRegNum(s, prefix string) (int, bool) {
+	if !strings.HasPrefix(s, prefix) {
+		return 0, false
+	}
+	n, err := strconv.Atoi(s[len(prefix):])
+	return n, err == nil && 0 <= n && n <= 31
+}
+
+func mipsIsWName(s string) bool {
+	_, ok := mipsRegNum(s, "W")
+	return ok
+}
+
+func mipsIsFCRName(s string) bool {
+	_, ok := mipsRegNum(s, "FCR")
+	return ok
+}
+
+func mipsIsFReg(r Reg) bool {
+	_, ok := mipsRegNum(string(r), "F")
+	return ok
+}
+
+func mipsIsRReg(o Operand) bool {
+	if o.Kind != OpReg {
+		return false
+	}
+	_, ok := mipsRegNum(string(o.Reg), "R")
+	return ok || o.Reg == SP
+}
+
+func mipsIsFRegOp(o Operand) bool {
+	return o.Kind == OpReg && mipsIsFReg(o.Reg)
+}
+
+func mipsIsWRegOp(o Operand) bool {
+	return o.Kind == OpReg && mipsIsWName(string(o.Reg))
+}
+
+// mipsIsHILO reports whether o names HI or LO, the multiply and divide
+// result registers.
+func mipsIsHILO(o Operand) bool {
+	return o.Kind == OpReg && (o.Reg == "HI" || o.Reg == "LO")
+}
@@ -0,0 +1,183 @@
+package plan9asm
+
+import (
+	"fmt"
+	"strings"
+)
+
+func emitMIPSPrelude(b *strings.Builder) {
+	for _, w := range []string{"i32", "i64"} {
+		fmt.Fprintf(b, "declare %s @llvm.fshr.%s(%s, %s, %s)\n", w, w, w, w, w)
+		fmt.Fprintf(b, "declare %s @llvm.ctlz.%s(%s, i1)\n", w, w, w)
+	}
+	for _, w := range []string{"i16", "i32", "i64"} {
+		fmt.Fprintf(b, "declare %s @llvm.bswap.%s(%s)\n", w, w, w)
+	}
+	for _, f := range []string{"f32", "f64"} {
+		ty := riscv64FloatTypes[f]
+		for _, fn := range []string{"sqrt", "fabs", "roundeven", "floor", "ceil", "trunc"} {
+			fmt.Fprintf(b, "declare %s @llvm.%s.%s(%s)\n", ty, fn, f, ty)
+		}
+		for _, w := range []string{"i32", "i64"} {
+			fmt.Fprintf(b, "declare %s @llvm.fptosi.sat.%s.%s(%s)\n", w, w, f, ty)
+		}
+	}
+	b.WriteString("declare void @llvm.debugtrap()\n")
+	b.WriteString("declare void @llvm.trap()\n")
+	b.WriteString("\n")
+}
+
+func translateFuncMIPS(b *strings.Builder, arch Arch, fn Func, sig FuncSig, resolve func(string) string, sigs map[string]FuncSig, cfg lowerConfig) error {
+	fmt.Fprintf(b, "define %s %s(", sig.Ret, llvmGlobal(sig.Name))
+	for i, t := range sig.Args {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		fmt.Fprintf(b, "%s %%arg%d", t, i)
+	}
+	b.WriteString(")")
+	if sig.Attrs != "" {
+		b.WriteString(" " + sig.Attrs)
+	}
+	b.WriteString(" {\n")
+
+	c := newMIPSCtx(b, arch, fn, sig, resolve, sigs, cfg)
+	if err := c.emitEntryAllocasAndArgInit(); err != nil {
+		return err
+	}
+	if err := c.lowerBlocks(); err != nil {
+		return err
+	}
+
+	b.WriteString("}\n")
+	return nil
+}
+
+func (c *mipsCtx) br(target string) {
+	fmt.Fprintf(c.b, "  br label %%%s\n", arm64LLVMBlockName(target))
+}
+
+func (c *mipsCtx) lowerBlocks() error {
+	// The allocas get a block of their own so that a branch back to the
+	// first instruction stays valid.
+	c.br(c.blocks[0].name)
+	for bi, blk := range c.blocks {
+		fmt.Fprintf(c.b, "\n%s:\n", arm64LLVMBlockName(blk.name))
+		terminated := false
+		for ii, ins := range blk.instrs {
+			c.emitSourceComment(ins)
+			term, err := c.lowerInstr(bi, ii, ins)
+			if err != nil {
+				return err
+			}
+			if term {
+				terminated = true
+				break
+			}
+		}
+		if terminated {
+			continue
+		}
+		if bi+1 < len(c.blocks) {
+			c.br(c.blocks[bi+1].name)
+			continue
+		}
+		c.lowerRetZero()
+	}
+	return nil
+}
+
+func (c *mipsCtx) lowerInstr(bi, ii int, ins Instr) (terminated bool, err error) {
+	op := strings.ToUpper(string(ins.Op))
+	switch Op(op) {
+	case OpTEXT:
+		return false, nil
+	case OpRET:
+		return true, c.lowerRET()
+	case OpBYTE:
+		return false, fmt.Errorf("%s: raw instruction encoding %s is not lowered", c.arch, ins.Op)
+	}
+	switch op {
+	case "WORD":
+		// runtime/atomic_mips64x.s spells SYNC as WORD $0xf.
+		if len(ins.Args) == 1 && ins.Args[0].Kind == OpImm && ins.Args[0].Imm == 0xf {
+			c.b.WriteString("  fence seq_cst\n")
+			return false, nil
+		}
+		return false, fmt.Errorf("%s: raw instruction encoding %s is not lowered", c.arch, ins.Op)
+	case "PCALIGN", "NO_LOCAL_POINTERS", "PCDATA", "FUNCDATA", "GO_ARGS", "NOP", "NOOP":
+		return false, nil
+	}
+
+	if ok, term, err := c.lowerData(op, ins); ok {
+		return term, err
+	}
+	if ok, term, err := c.lowerArith(op, ins); ok {
+		return term, err
+	}
+	if ok, term, err := c.lowerFP(op, ins); ok {
+		return term, err
+	}
+	if ok, term, err := c.lowerVec(op, ins); ok {
+		return term, err
+	}
+	if ok, term, err := c.lowerAtomic(op, ins); ok {
+		return term, err
+	}
+	if ok, term, err := c.lowerSyscall(op, ins); ok {
+		return term, err
+	}
+	if ok, term, err := c.lowerBranch(bi, ii, op, ins); ok {
+		return term, err
+	}
+	return false, fmt.Errorf("%s: unsupported instruction %s", c.arch, ins.Op)
+}
+
+// lowerRET returns the results the body stored to their FP slots. A body
+// without a frame layout returns its single result in R2, as the C ABI
+// does.
+func (c *mipsCtx) lowerRET() error {
+	if len(c.fpResults) == 0 {
+		if c.sig.Ret == Void {
+			c.b.WriteString("  ret void\n")
+			return nil
+		}
+		v64, err := c.loadReg("R2")
+		if err != nil {
+			return err
+		}
+		v, err := c.i64ToValue(v64, c.sig.Ret)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(c.b, "  ret %s %s\n", c.sig.Ret, v)
+		return nil
+	}
+
+	if len(c.fpResults) == 1 {
+		v, err := c.loadFPResult(c.fpResults[0])
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(c.b, "  ret %s %s\n", c.sig.Ret, v)
+		return nil
+	}
+	cur := "undef"
+	for _, slot := range c.fpResults {
+		v, err := c.loadFPResult(slot)
+		if err != nil {
+			return err
+		}
+		cur = c.emit("insertvalue %s %s, %s %s, %d", c.sig.Ret, cur, slot.Type, v, slot.Index)
+	}
+	fmt.Fprintf(c.b, "  ret %s %s\n", c.sig.Ret, cur)
+	return nil
+}
+
+func (c *mipsCtx) lowerRetZero() {
+	if c.sig.Ret == Void {
+		c.b.WriteString("  ret void\n")
+		return
+	}
+	fmt.Fprintf(c.b, "  ret %s %s\n", c.sig.Ret, llvmZeroValue(c.sig.Ret))
+}
@@ -0,0 +1,284 @@
+//go:build !llgo
+// +build !llgo
+
+package plan9asm
+
+import (
+	"strings"
+	"testing"
+)
+
+var mipsTestTriples = map[Arch]string{
+	ArchMIPS:     "mips-unknown-linux-gnu",
+	ArchMIPSLE:   "mipsel-unknown-linux-gnu",
+	ArchMIPS64:   "mips64-unknown-linux-gnuabi64",
+	ArchMIPS64LE: "mips64el-unknown-linux-gnuabi64",
+}
+
+func translateMIPS(t *testing.T, arch Arch, src string, sigs map[string]FuncSig) string {
+	t.Helper()
+	file, err := Parse(arch, src)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	ll, err := translateIRText(file, Options{
+		TargetTriple: mipsTestTriples[arch],
+		Goarch:       string(arch),
+		ResolveSym:   testResolveSym("example"),
+		Sigs:         sigs,
+	})
+	if err != nil {
+		t.Fatalf("translateIRText: %v", err)
+	}
+	return ll
+}
+
+func TestTranslateMIPS64Registers(t *testing.T) {
+	ll := translateMIPS(t, ArchMIPS64LE, `TEXT ·f(SB),NOSPLIT,$0-24
+	MOVV a+0(FP), R4
+	MOVV b+8(FP), R5
+	ADDVU R5, R4, R6
+	SUBVU R5, R4, R7
+	MULVU R5, R4
+	MOVV HI, R8
+	MOVV LO, R9
+	DIVV R5, R4
+	MOVV LO, R10
+	SLLV $3, R6
+	SGTU R4, R5, R11
+	ADDVU R7, R6
+	ADDVU R8, R6
+	ADDVU R9, R6
+	ADDVU R10, R6
+	ADDVU R11, R6
+	MOVV R6, ret+16(FP)
+	RET
+`, map[string]FuncSig{
+		"example.f": sigWithClassicFrame("example.f", []LLVMType{I64, I64}, I64),
+	})
+	wantIR(t, ll,
+		`define i64 @"example.f"(i64 %arg0, i64 %arg1)`,
+		"%reg_R4 = alloca i64",
+		"%reg_HI = alloca i64",
+		"add i64",
+		"sub i64",
+		"mul i128",
+		"sdiv i64",
+		"icmp ult i64",
+		"ret i64",
+	)
+}
+
+func TestTranslateMIPSRegisters(t *testing.T) {
+	ll := translateMIPS(t, ArchMIPSLE, `TEXT ·f(SB),NOSPLIT,$0-12
+	MOVW a+0(FP), R4
+	MOVW b+4(FP), R5
+	ADDU R5, R4, R6
+	MULU R5, R4
+	MOVW HI, R7
+	SRA $1, R6
+	ADDU R7, R6
+	MOVW R6, ret+8(FP)
+	RET
+`, map[string]FuncSig{
+		"example.f": sigWithClassicFrame32("example.f", []LLVMType{I32, I32}, I32),
+	})
+	wantIR(t, ll, `define i32 @"example.f"(i32 %arg0, i32 %arg1)`, "add i32", "mul i64", "ashr i32", "ret i32")
+
+	file, err := Parse(ArchMIPS, "TEXT ·f(SB),NOSPLIT,$0-0\n\tADDVU R4, R5\n\tRET\n")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	_, err = translateIRText(file, Options{
+		Goarch:     "mips",
+		ResolveSym: testResolveSym("example"),
+		Sigs:       map[string]FuncSig{"example.f": {Name: "example.f", Ret: Void}},
+	})
+	if err == nil || !strings.Contains(err.Error(), "mips64") {
+		t.Fatalf("ADDVU on mips: err = %v, want mips64 only", err)
+	}
+}
+
+func TestTranslateMIPSBranches(t *testing.T) {
+	ll := translateMIPS(t, ArchMIPS64, `TEXT ·sum(SB),NOSPLIT,$0-16
+	MOVV n+0(FP), R4
+	MOVV R0, R5
+	BLEZ R4, done
+loop:
+	ADDVU R4, R5
+	ADDVU $-1, R4
+	BNE R4, R0, loop
+done:
+	MOVV R5, ret+8(FP)
+	RET
+
+TEXT ·tail(SB),NOSPLIT,$0-16
+	JMP ·sum(SB)
+`, map[string]FuncSig{
+		"example.sum":  sigWithClassicFrame("example.sum", []LLVMType{I64}, I64),
+		"example.tail": sigWithClassicFrame("example.tail", []LLVMType{I64}, I64),
+	})
+	wantIR(t, ll, "icmp sle i64", "icmp ne i64", `call i64 @"example.sum"(i64 %arg0)`)
+}
+
+func TestTranslateMIPSFloat(t *testing.T) {
+	ll := translateMIPS(t, ArchMIPS64LE, `TEXT ·f(SB),NOSPLIT,$0-24
+	MOVD a+0(FP), F0
+	MOVD b+8(FP), F2
+	ADDD F2, F0, F4
+	SQRTD F4, F4
+	CMPGTD F0, F2
+	BFPT less
+	TRUNCDV F4, F6
+	MOVVD F6, F4
+less:
+	MOVD F4, ret+16(FP)
+	RET
+`, map[string]FuncSig{
+		"example.f": sigWithClassicFrame("example.f", []LLVMType{LLVMType("double"), LLVMType("double")}, LLVMType("double")),
+	})
+	wantIR(t, ll, "fadd double", "@llvm.sqrt.f64", "fcmp ogt double", "@llvm.fptosi.sat.i64.f64", "sitofp i64")
+}
+
+func TestTranslateMIPSAtomics(t *testing.T) {
+	ll := translateMIPS(t, ArchMIPSLE, `TEXT ·cas(SB),NOSPLIT,$0-13
+	MOVW ptr+0(FP), R1
+	MOVW old+4(FP), R2
+	MOVW new+8(FP), R5
+	SYNC
+try:
+	MOVW R5, R3
+	LL (R1), R4
+	BNE R2, R4, fail
+	SC R3, (R1)
+	BEQ R3, try
+	SYNC
+	MOVB R3, ret+12(FP)
+	RET
+fail:
+	MOVB R0, ret+12(FP)
+	RET
+`, map[string]FuncSig{
+		"example.cas": sigWithClassicFrame32("example.cas", []LLVMType{Ptr, I32, I32}, I1),
+	})
+	wantIR(t, ll, "fence seq_cst", "load atomic i32", "cmpxchg ptr")
+}
+
+func TestTranslateMIPSSyscall(t *testing.T) {
+	src := `TEXT ·getpid(SB),NOSPLIT,$0-8
+	MOVW $4020, R2
+	SYSCALL
+	BEQ R7, ok
+	MOVW $-1, R2
+ok:
+	MOVW R2, ret+0(FP)
+	RET
+`
+	sigs := map[string]FuncSig{
+		"example.getpid": sigWithClassicFrame32("example.getpid", nil, I32),
+	}
+	wantIR(t, translateMIPS(t, ArchMIPS, src, sigs), "call i64 @syscall(i64 ", "call i32 @cliteErrno()")
+
+	file, err := Parse(ArchMIPS, src)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	ll, err := translateIRText(file, Options{
+		TargetTriple: "mips-unknown-linux-gnu",
+		Goarch:       "mips",
+		ResolveSym:   testResolveSym("example"),
+		Sigs:         sigs,
+		Syscall:      RawSyscall{},
+	})
+	if err != nil {
+		t.Fatalf("translateIRText: %v", err)
+	}
+	wantIR(t, ll, `"={$2},={$3},={$7},{$2},{$4},{$5},{$6},{$7},`, "select i1")
+}
+
+func TestTranslateMIPSUnalignedWord(t *testing.T) {
+	src := `TEXT ·load(SB),NOSPLIT,$0-8
+	MOVW p+0(FP), R1
+	MOVWL 0(R1), R2
+	MOVWR 3(R1), R2
+	MOVW R2, ret+4(FP)
+	RET
+`
+	sigs := map[string]FuncSig{
+		"example.load": sigWithClassicFrame32("example.load", []LLVMType{Ptr}, I32),
+	}
+	// The merge shifts by the byte offset on opposite ends of the word for
+	// big- and little-endian.
+	for _, arch := range []Arch{ArchMIPS, ArchMIPSLE} {
+		wantIR(t, translateMIPS(t, arch, src, sigs), "and i64 %t", "load i32, ptr", "shl i32", "lshr i32")
+	}
+}
+
+func TestTranslateMIPSFloatMode(t *testing.T) {
+	src := `TEXT ·f(SB),NOSPLIT,$0-0
+#ifdef GOMIPS_softfloat
+	MOVW $1, R2
+#else
+	MOVD F0, F2
+#endif
+	RET
+`
+	for _, tc := range []struct {
+		gomips string
+		want   string
+	}{
+		{"", "%reg_F2"},
+		{"hardfloat", "%reg_F2"},
+		{"softfloat", `"target-features"="+soft-float"`},
+	} {
+		defines, err := goArchDefines("mips", GoModuleOptions{GOMIPS: tc.gomips})
+		if err != nil {
+			t.Fatalf("goArchDefines(%q): %v", tc.gomips, err)
+		}
+		file, err := ParseWithDefines(ArchMIPS, src, defines...)
+		if err != nil {
+			t.Fatalf("ParseWithDefines: %v", err)
+		}
+		ll, err := translateIRText(file, Options{
+			TargetTriple: "mips-unknown-linux-gnu",
+			Goarch:       "mips",
+			ResolveSym:   testResolveSym("example"),
+			Sigs:         map[string]FuncSig{"example.f": {Name: "example.f", Ret: Void}},
+			SoftFloat:    tc.gomips == "softfloat",
+		})
+		if err != nil {
+			t.Fatalf("translateIRText(%q): %v", tc.gomips, err)
+		}
+		wantIR(t, ll, tc.want)
+	}
+	if _, err := goArchDefines("mips", GoModuleOptions{GOMIPS: "fast"}); err == nil {
+		t.Fatalf("goArchDefines accepted GOMIPS=fast")
+	}
+	if _, err := goArchDefines("amd64", GoModuleOptions{GOMIPS: "softfloat"}); err == nil {
+		t.Fatalf("goArchDefines accepted GOMIPS for amd64")
+	}
+	if got, err := goArchDefines("mips64le", GoModuleOptions{GOMIPS: "softfloat"}); err != nil || len(got) != 1 || got[0] != "GOMIPS64_softfloat" {
+		t.Fatalf("goArchDefines(mips64le) = %v, %v", got, err)
+	}
+}
+
+func TestTranslateMIPSRejectsUnsupported(t *testing.T) {
+	for _, tc := range []struct{ insn, want string }{
+		{"ADDD R4, F0, F2", "expects F registers"},
+		{"VMOVB $1, W0", "needs mips64"},
+		{"JAL R4, R5, R6", "JAL"},
+	} {
+		file, err := Parse(ArchMIPS, "TEXT ·f(SB),NOSPLIT,$0-0\n\t"+tc.insn+"\n\tRET\n")
+		if err != nil {
+			t.Fatalf("%s: Parse: %v", tc.insn, err)
+		}
+		_, err = translateIRText(file, Options{
+			Goarch:     "mips",
+			ResolveSym: testResolveSym("example"),
+			Sigs:       map[string]FuncSig{"example.f": {Name: "example.f", Ret: Void}},
+		})
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: err = %v, want %q", tc.insn, err, tc.want)
+		}
+	}
+}
@@ -42,9 +42,26 @@ type Func struct {
 //   - #define NAME <body> with optional single-line continuation via '\' and
 //     macro invocation when the entire statement is just NAME.
 func Parse(arch Arch, src string) (*File, error) {
+	return ParseWithDefines(arch, src)
+}
+
+// ParseWithDefines is like Parse, with the given macros defined (as empty)
+// before src is preprocessed, as the go command defines GOMIPS_softfloat
+// and the like.
+func ParseWithDefines(arch Arch, src string, defines ...string) (*File, error) {
 	f := &File{Arch: arch}
 
-	pp, err := preprocess(src)
+	// Only the architecture selectors are predefined; sources pick their
+	// little-endian ppc64 paths with #ifdef GOARCH_ppc64le, and the mips
+	// files shared by two dialects test GOARCH_mips or GOARCH_mips64.
+	predefined := append([]string(nil), defines...)
+	switch arch {
+	case ArchPPC64:
+		predefined = append(predefined, "GOARCH_ppc64le")
+	case ArchMIPS, ArchMIPSLE, ArchMIPS64, ArchMIPS64LE:
+		predefined = append(predefined, "GOARCH_"+string(arch))
+	}
+	pp, err := preprocess(src, predefined...)
 	if err != nil {
 		return nil, err
 	}
@@ -182,7 +199,24 @@ func Parse(arch Arch, src string) (*File, error) {
 				}
 				// For now, parse unknown opcodes as generic instructions. The translator
 				// is responsible for rejecting unsupported ones.
-				args, err := parseOperandsCSV(rest)
+				switch arch {
+				case ArchRISCV64:
+					rest = canonicalRegAliases(rest, riscv64RegAlias)
+				case ArchLOONG64:
+					rest = canonicalRegAliases(rest, loong64RegAlias)
+				}
+				parseOperands := parseOperandsCSV
+				switch arch {
+				case ArchPPC64:
+					parseOperands = ppc64ParseOperands
+				case ArchS390X:
+					parseOperands = s390xParseOperands
+				case ArchMIPS, ArchMIPSLE, ArchMIPS64, ArchMIPS64LE:
+					parseOperands = mipsParseOperands
+				case ArchWasm:
+					parseOperands = wasmParseOperands
+				}
+				args, err := parseOperands(rest)
 				if err != nil {
 					return nil, fmt.Errorf("line %d: %v", lineno, err)
 				}
@@ -264,7 +298,7 @@ func parseWidth(arch Arch, s string) (int64, error) {
 	switch strings.ToUpper(s) {
 	case "PTRSIZE":
 		switch arch {
-		case ArchAMD64, ArchARM64:
+		case ArchAMD64, ArchARM64, ArchRISCV64, ArchLOONG64, ArchPPC64, ArchS390X, ArchMIPS64, ArchMIPS64LE, ArchWasm:
 			return 8, nil
 		default:
 			return 4, nil
@@ -332,6 +366,43 @@ func parseInt(s string) (int64, error) {
 	return strconv.ParseInt(s, 0, 64)
 }
 
+// canonicalRegAliases rewrites register aliases in an operand list, both
+// as whole operands ("A0") and inside memory references ("8(A0)",
+// "(R3)(R5)").
+func canonicalRegAliases(operands string, aliases map[string]string) string {
+	if operands == "" {
+		return operands
+	}
+	parts := splitTopLevelCSV(operands)
+	for i, p := range parts {
+		p = strings.TrimSpace(p)
+		if r, ok := aliases[p]; ok {
+			parts[i] = r
+			continue
+		}
+		var out strings.Builder
+		for {
+			open := strings.IndexByte(p, '(')
+			if open < 0 {
+				break
+			}
+			n := strings.IndexByte(p[open:], ')')
+			if n < 0 {
+				break
+			}
+			inner := p[open+1 : open+n]
+			if r, ok := aliases[strings.TrimSpace(inner)]; ok {
+				inner = r
+			}
+			out.WriteString(p[:open+1] + inner + ")")
+			p = p[open+n+1:]
+		}
+		out.WriteString(p)
+		parts[i] = out.String()
+	}
+	return strings.Join(parts, ", ")
+}
+
 func parseOperandsCSV(s string) ([]Operand, error) {
 	if s == "" {
 		return nil, nil
@@ -0,0 +1,93 @@
+package plan9asm
+
+import (
+	"fmt"
+	"strings"
+)
+
+type ppc64Block struct {
+	name   string // source label (or "entry")
+	instrs []Instr
+}
+
+// ppc64IsTerminator reports whether ins ends a basic block: RET, JMP, BR
+// or a conditional branch.
+func ppc64IsTerminator(ins Instr) bool {
+	if ins.Op == OpRET {
+		return true
+	}
+	switch strings.ToUpper(string(ins.Op)) {
+	case "JMP", "BR", "BEQ", "BNE", "BLT", "BGE", "BGT", "BLE", "BVS", "BVC",
+		"BC", "BDNZ", "BDZ":
+		return true
+	}
+	return false
+}
+
+// ppc64PCRelTarget returns the instruction offset of a branch to n(PC).
+func ppc64PCRelTarget(ins Instr) (off int64, ok bool) {
+	if !ppc64IsTerminator(ins) || len(ins.Args) == 0 {
+		return 0, false
+	}
+	last := ins.Args[len(ins.Args)-1]
+	if last.Kind != OpMem || last.Mem.Base != PC {
+		return 0, false
+	}
+	return last.Mem.Off, true
+}
+
+func ppc64SplitBlocks(fn Func) []ppc64Block {
+	blocks := []ppc64Block{{name: "entry"}}
+	cur := 0
+	anon := 0
+
+	startAnon := func() {
+		anon++
+		blocks = append(blocks, ppc64Block{name: fmt.Sprintf("anon_%d", anon)})
+		cur = len(blocks) - 1
+	}
+
+	linear := make([]Instr, 0, len(fn.Instrs))
+	for _, ins := range fn.Instrs {
+		if ins.Op == OpLABEL {
+			continue
+		}
+		linear = append(linear, ins)
+	}
+	splitAt := map[int]bool{}
+	for i, ins := range linear {
+		if off, ok := ppc64PCRelTarget(ins); ok {
+			t := i + int(off)
+			if 0 <= t && t < len(linear) {
+				splitAt[t] = true
+			}
+		}
+	}
+
+	li := 0
+	for _, ins := range fn.Instrs {
+		if ins.Op == OpLABEL && len(ins.Args) == 1 && ins.Args[0].Kind == OpLabel {
+			lbl := ins.Args[0].Sym
+			if len(blocks[cur].instrs) == 0 && strings.HasPrefix(blocks[cur].name, "anon_") {
+				blocks[cur].name = lbl
+				continue
+			}
+			blocks = append(blocks, ppc64Block{name: lbl})
+			cur = len(blocks) - 1
+			continue
+		}
+		if splitAt[li] && len(blocks[cur].instrs) != 0 {
+			startAnon()
+		}
+		blocks[cur].instrs = append(blocks[cur].instrs, ins)
+		li++
+		if ppc64IsTerminator(ins) {
+			startAnon()
+		}
+	}
+
+	if len(blocks) > 1 && len(blocks[len(blocks)-1].instrs) == 0 && strings.HasPrefix(blocks[len(blocks)-1].name, "anon_") {
+		blocks = blocks[:len(blocks)-1]
+	}
+	return blocks
+}
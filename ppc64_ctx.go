@@ -0,0 +1,395 @@
+package plan9asm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ppc64Ctx lowers one ppc64 TEXT body. R0 and R2-R31, F0-F31, the
+// condition register fields CR0-CR7 and LR, CTR and XER live in i64
+// slots; R0 is an ordinary register that Go keeps zero, and reads as zero
+// only when it is the base of a memory reference. F registers hold double
+// bits (singles are kept widened, as the hardware does). The 128-bit VSX
+// registers are V0-V31 (VS32-VS63) in i128 slots and VS0-VS31, whose
+// first doubleword is the F register and whose second lives in a VSL
+// slot. Vector values use the ISA's big-endian element numbering: element
+// 0 is the most significant.
+//
+// A CR field holds LT, GT, EQ and SO as 8, 4, 2 and 1. XER holds the
+// carry at bit 29 (CA) and the summary overflow at bit 31.
+type ppc64Ctx struct {
+	b       *strings.Builder
+	sig     FuncSig
+	resolve func(string) string
+	sigs    map[string]FuncSig
+	cfg     lowerConfig
+
+	tmp int
+
+	blocks     []ppc64Block
+	blockBase  []int
+	blockByIdx map[int]int
+
+	regSlot   map[Reg]string // reg -> alloca name
+	frameSize int64
+	// argFrameSize is the size of an in-memory copy of the argument frame,
+	// made when the body takes the address of an FP slot that is not a
+	// result, or 0.
+	argFrameSize int64
+
+	reservedValidSlot string
+	reservedPtrSlot   string
+	reservedValueSlot string
+
+	fpParams       map[int64]FrameSlot // off(FP) -> slot
+	fpResults      []FrameSlot         // result slots (Index is result index)
+	fpResAllocaOff map[int64]string    // off(FP) -> alloca
+	fpResAllocaIdx map[int]string      // result index -> alloca
+	fpResWritten   map[int]bool        // result index -> direct writes to fp slot
+	fpResAddrTaken map[int]bool        // result index -> fp result slot address escaped
+}
+
+const (
+	ppc64CRLT = 8
+	ppc64CRGT = 4
+	ppc64CREQ = 2
+	ppc64CRSO = 1
+
+	ppc64XERCA = 1 << 29
+	ppc64XERSO = 1 << 31
+)
+
+func newPPC64Ctx(b *strings.Builder, fn Func, sig FuncSig, resolve func(string) string, sigs map[string]FuncSig, cfg lowerConfig) *ppc64Ctx {
+	c := &ppc64Ctx{
+		b:              b,
+		sig:            sig,
+		resolve:        resolve,
+		sigs:           sigs,
+		cfg:            cfg,
+		blocks:         ppc64SplitBlocks(fn),
+		blockByIdx:     map[int]int{},
+		regSlot:        map[Reg]string{},
+		frameSize:      textFrameSize(fn),
+		fpParams:       map[int64]FrameSlot{},
+		fpResAllocaOff: map[int64]string{},
+		fpResAllocaIdx: map[int]string{},
+		fpResWritten:   map[int]bool{},
+		fpResAddrTaken: map[int]bool{},
+	}
+	for _, s := range sig.Frame.Params {
+		c.fpParams[s.Offset] = s
+	}
+	c.fpResults = append([]FrameSlot(nil), sig.Frame.Results...)
+	c.argFrameSize = riscv64ArgFrameSize(fn, sig)
+	base := 0
+	for i, blk := range c.blocks {
+		c.blockBase = append(c.blockBase, base)
+		c.blockByIdx[base] = i
+		base += len(blk.instrs)
+	}
+	return c
+}
+
+func (c *ppc64Ctx) emitSourceComment(ins Instr) {
+	if !c.cfg.annotateSource {
+		return
+	}
+	emitIRSourceComment(c.b, ins.Raw)
+}
+
+func (c *ppc64Ctx) newTmp() string {
+	c.tmp++
+	return fmt.Sprintf("t%d", c.tmp)
+}
+
+func (c *ppc64Ctx) emit(format string, args ...any) string {
+	t := c.newTmp()
+	fmt.Fprintf(c.b, "  %%%s = "+format+"\n", append([]any{t}, args...)...)
+	return "%" + t
+}
+
+func (c *ppc64Ctx) emitEntryAllocasAndArgInit() error {
+	c.b.WriteString("entry:\n")
+	regs := []Reg{SP, "LR", "CTR", "XER"}
+	for i := 0; i <= 31; i++ {
+		if i != 1 {
+			regs = append(regs, Reg(fmt.Sprintf("R%d", i)))
+		}
+	}
+	for i := 0; i <= 31; i++ {
+		regs = append(regs, Reg(fmt.Sprintf("F%d", i)), Reg(fmt.Sprintf("VSL%d", i)))
+	}
+	for i := 0; i <= 7; i++ {
+		regs = append(regs, Reg(fmt.Sprintf("CR%d", i)))
+	}
+	for _, r := range regs {
+		name := "%" + arm64LLVMBlockName("reg_"+string(r))
+		c.regSlot[r] = name
+		fmt.Fprintf(c.b, "  %s = alloca i64\n", name)
+		fmt.Fprintf(c.b, "  store i64 0, ptr %s\n", name)
+	}
+	for i := 0; i <= 31; i++ {
+		r := Reg(fmt.Sprintf("V%d", i))
+		name := "%" + arm64LLVMBlockName("reg_"+string(r))
+		c.regSlot[r] = name
+		fmt.Fprintf(c.b, "  %s = alloca i128\n", name)
+		fmt.Fprintf(c.b, "  store i128 0, ptr %s\n", name)
+	}
+
+	// R1 points at the bottom of the TEXT frame, whose fixed header holds
+	// the back chain and the LR save word. Leaf functions still get the
+	// header, since bodies address FIXED_FRAME+off(R1) regardless.
+	fmt.Fprintf(c.b, "  %%frame = alloca [%d x i8], align 16\n", c.frameSize+ppc64FixedFrame)
+	t := c.emit("ptrtoint ptr %%frame to i64")
+	if err := c.storeReg(SP, t); err != nil {
+		return err
+	}
+
+	// Scratch buffer for the variable-length vector accesses.
+	c.b.WriteString("  %vlbuf = alloca i128, align 16\n")
+
+	// Reservation state for LL/SC lowering.
+	c.reservedValidSlot = "%reserved_valid"
+	c.reservedPtrSlot = "%reserved_ptr"
+	c.reservedValueSlot = "%reserved_value"
+	fmt.Fprintf(c.b, "  %s = alloca i1\n", c.reservedValidSlot)
+	fmt.Fprintf(c.b, "  store i1 false, ptr %s\n", c.reservedValidSlot)
+	fmt.Fprintf(c.b, "  %s = alloca ptr\n", c.reservedPtrSlot)
+	fmt.Fprintf(c.b, "  store ptr null, ptr %s\n", c.reservedPtrSlot)
+	fmt.Fprintf(c.b, "  %s = alloca i64\n", c.reservedValueSlot)
+	fmt.Fprintf(c.b, "  store i64 0, ptr %s\n", c.reservedValueSlot)
+
+	for _, r := range c.fpResults {
+		name := fmt.Sprintf("%%fp_ret_%d", r.Index)
+		c.fpResAllocaIdx[r.Index] = name
+		c.fpResAllocaOff[r.Offset] = name
+		fmt.Fprintf(c.b, "  %s = alloca %s\n", name, r.Type)
+		fmt.Fprintf(c.b, "  store %s %s, ptr %s\n", r.Type, llvmZeroValue(r.Type), name)
+	}
+
+	if c.argFrameSize > 0 {
+		if err := c.spillArgFrame(); err != nil {
+			return err
+		}
+	}
+
+	// Seed the argument registers too, for ABIInternal bodies and helper<>
+	// register assignments; ABI0 bodies read the FP slots instead.
+	if len(c.sig.ArgRegs) > 0 {
+		for i := 0; i < len(c.sig.Args) && i < len(c.sig.ArgRegs); i++ {
+			v, ok, err := c.valueAsI64(c.sig.Args[i], fmt.Sprintf("%%arg%d", i))
+			if err != nil {
+				return err
+			}
+			if !ok {
+				continue
+			}
+			if err := c.storeReg(c.sig.ArgRegs[i], v); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	var cur ppc64ArgCursor
+	for ai, argTy := range c.sig.Args {
+		arg := fmt.Sprintf("%%arg%d", ai)
+		fields, isAgg := parseLiteralStructFields(argTy)
+		if !isAgg {
+			fields = []LLVMType{argTy}
+		}
+		for fi, fTy := range fields {
+			r, ok := cur.next(fTy)
+			if !ok {
+				return nil
+			}
+			v := arg
+			if isAgg {
+				v = c.emit("extractvalue %s %s, %d", argTy, arg, fi)
+			}
+			v64, ok, err := c.valueAsI64(fTy, v)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				continue
+			}
+			if err := c.storeReg(r, v64); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// spillArgFrame stores the FP parameter slots into %argframe.
+func (c *ppc64Ctx) spillArgFrame() error {
+	fmt.Fprintf(c.b, "  %%argframe = alloca [%d x i8], align 8\n", c.argFrameSize)
+	for _, s := range c.sig.Frame.Params {
+		if s.Index < 0 || s.Index >= len(c.sig.Args) {
+			continue
+		}
+		v := fmt.Sprintf("%%arg%d", s.Index)
+		if s.Field >= 0 {
+			v = c.emit("extractvalue %s %s, %d", c.sig.Args[s.Index], v, s.Field)
+		}
+		p := c.emit("getelementptr i8, ptr %%argframe, i64 %d", s.Offset)
+		fmt.Fprintf(c.b, "  store %s %s, ptr %s\n", s.Type, v, p)
+	}
+	return nil
+}
+
+// ppc64ArgCursor hands out ABIInternal argument (or result) registers.
+type ppc64ArgCursor struct{ ints, floats int }
+
+func (a *ppc64ArgCursor) next(ty LLVMType) (Reg, bool) {
+	if riscv64IsFloatType(ty) {
+		if a.floats >= len(ppc64FloatArgRegs) {
+			return "", false
+		}
+		a.floats++
+		return ppc64FloatArgRegs[a.floats-1], true
+	}
+	if a.ints >= len(ppc64IntArgRegs) {
+		return "", false
+	}
+	a.ints++
+	return ppc64IntArgRegs[a.ints-1], true
+}
+
+// valueAsI64 converts a value of type ty to register bits. Singles are
+// widened to double, the form F registers hold them in.
+func (c *ppc64Ctx) valueAsI64(ty LLVMType, v string) (out string, ok bool, err error) {
+	switch ty {
+	case I64:
+		return v, true, nil
+	case Ptr:
+		return c.emit("ptrtoint ptr %s to i64", v), true, nil
+	case I1, I8, I16, I32:
+		return c.emit("zext %s %s to i64", ty, v), true, nil
+	case LLVMType("double"):
+		return c.emit("bitcast double %s to i64", v), true, nil
+	case LLVMType("float"):
+		d := c.emit("fpext float %s to double", v)
+		return c.emit("bitcast double %s to i64", d), true, nil
+	}
+	return "", false, nil
+}
+
+// i64ToValue converts register bits back to ty.
+func (c *ppc64Ctx) i64ToValue(v string, ty LLVMType) (string, error) {
+	switch ty {
+	case I64:
+		return v, nil
+	case I32, I16, I8, I1:
+		return c.emit("trunc i64 %s to %s", v, ty), nil
+	case Ptr:
+		return c.emit("inttoptr i64 %s to ptr", v), nil
+	case LLVMType("double"):
+		return c.emit("bitcast i64 %s to double", v), nil
+	case LLVMType("float"):
+		d := c.emit("bitcast i64 %s to double", v)
+		return c.emit("fptrunc double %s to float", d), nil
+	}
+	return "", fmt.Errorf("ppc64: unsupported value type %s", ty)
+}
+
+func (c *ppc64Ctx) loadReg(r Reg) (string, error) {
+	slot, ok := c.regSlot[r]
+	if !ok || ppc64IsVReg(r) {
+		return "", fmt.Errorf("ppc64: unknown reg %s", r)
+	}
+	return c.emit("load i64, ptr %s", slot), nil
+}
+
+func (c *ppc64Ctx) storeReg(r Reg, v string) error {
+	slot, ok := c.regSlot[r]
+	if !ok || ppc64IsVReg(r) {
+		return fmt.Errorf("ppc64: unknown reg %s", r)
+	}
+	fmt.Fprintf(c.b, "  store i64 %s, ptr %s\n", v, slot)
+	return nil
+}
+
+// loadVec reads a VSX register as an i128. VS0-VS31 (and the F registers
+// naming them) combine the F register and the VSL slot.
+func (c *ppc64Ctx) loadVec(r Reg) (string, error) {
+	if ppc64IsVReg(r) {
+		return c.emit("load i128, ptr %s", c.regSlot[r]), nil
+	}
+	n, ok := ppc64VSLowNum(r)
+	if !ok {
+		return "", fmt.Errorf("ppc64: %s is not a vector register", r)
+	}
+	hi, err := c.loadReg(Reg(fmt.Sprintf("F%d", n)))
+	if err != nil {
+		return "", err
+	}
+	lo, err := c.loadReg(Reg(fmt.Sprintf("VSL%d", n)))
+	if err != nil {
+		return "", err
+	}
+	return c.join128(hi, lo), nil
+}
+
+func (c *ppc64Ctx) storeVec(r Reg, v string) error {
+	if ppc64IsVReg(r) {
+		fmt.Fprintf(c.b, "  store i128 %s, ptr %s\n", v, c.regSlot[r])
+		return nil
+	}
+	n, ok := ppc64VSLowNum(r)
+	if !ok {
+		return fmt.Errorf("ppc64: %s is not a vector register", r)
+	}
+	hi, lo := c.split128(v)
+	if err := c.storeReg(Reg(fmt.Sprintf("F%d", n)), hi); err != nil {
+		return err
+	}
+	return c.storeReg(Reg(fmt.Sprintf("VSL%d", n)), lo)
+}
+
+// ppc64VSLowNum returns n for VSn or Fn with n < 32.
+func ppc64VSLowNum(r Reg) (int, bool) {
+	var n int
+	switch {
+	case ppc64IsVSLow(r):
+		fmt.Sscanf(string(r), "VS%d", &n)
+	case ppc64IsFReg(r):
+		fmt.Sscanf(string(r), "F%d", &n)
+	default:
+		return 0, false
+	}
+	return n, true
+}
+
+// join128 builds hi:lo, with hi as doubleword 0.
+func (c *ppc64Ctx) join128(hi, lo string) string {
+	h := c.emit("zext i64 %s to i128", hi)
+	h = c.emit("shl i128 %s, 64", h)
+	l := c.emit("zext i64 %s to i128", lo)
+	return c.emit("or i128 %s, %s", h, l)
+}
+
+// split128 returns doublewords 0 and 1 of v.
+func (c *ppc64Ctx) split128(v string) (hi, lo string) {
+	h := c.emit("lshr i128 %s, 64", v)
+	return c.emit("trunc i128 %s to i64", h), c.emit("trunc i128 %s to i64", v)
+}
+
+func (c *ppc64Ctx) ptrFromSB(sym string) (string, error) {
+	base, off, ok := parseSBRef(sym)
+	if !ok {
+		return "", fmt.Errorf("invalid (SB) sym ref: %q", sym)
+	}
+	base = strings.TrimPrefix(base, "$")
+	res := base
+	if strings.Contains(base, "·") || strings.Contains(base, "/") || strings.Contains(base, ".") {
+		res = c.resolve(base)
+	} else {
+		res = c.resolve("·" + base)
+	}
+	p := llvmGlobal(res)
+	if off == 0 {
+		return p, nil
+	}
+	return c.emit("getelementptr i8, ptr %s, i64 %d", p, off), nil
+}
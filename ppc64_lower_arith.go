@@ -0,0 +1,560 @@
+package plan9asm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ppc64ALUOp describes a two-source integer operation. w32 operations
+// compute on the low words; ext says how their result is widened.
+type ppc64ALUOp struct {
+	kind   string
+	w32    bool
+	signed bool // w32 results are sign-extended rather than zero-extended
+}
+
+var ppc64ALUOps = map[string]ppc64ALUOp{
+	"ADD": {kind: "add"}, "SUB": {kind: "sub"},
+	"ADDIS": {kind: "addis"}, "ORIS": {kind: "oris"}, "XORIS": {kind: "xoris"}, "ANDIS": {kind: "andis"},
+	"AND": {kind: "and"}, "OR": {kind: "or"}, "XOR": {kind: "xor"},
+	"ANDN": {kind: "andn"}, "ORN": {kind: "orn"},
+	"NAND": {kind: "nand"}, "NOR": {kind: "nor"}, "EQV": {kind: "eqv"},
+	"MULLD": {kind: "mul"}, "MULLW": {kind: "mullw"},
+	"MULHD": {kind: "mulh"}, "MULHDU": {kind: "mulhu"},
+	"MULHW": {kind: "mulh", w32: true, signed: true}, "MULHWU": {kind: "mulhu", w32: true},
+	"DIVD": {kind: "div"}, "DIVDU": {kind: "divu"},
+	"DIVW": {kind: "div", w32: true, signed: true}, "DIVWU": {kind: "divu", w32: true},
+	"MODSD": {kind: "rem"}, "MODUD": {kind: "remu"},
+	"MODSW": {kind: "rem", w32: true, signed: true}, "MODUW": {kind: "remu", w32: true},
+	"SLD": {kind: "shl"}, "SRD": {kind: "lshr"}, "SRAD": {kind: "ashr"},
+	"SLW": {kind: "shl", w32: true}, "SRW": {kind: "lshr", w32: true}, "SRAW": {kind: "ashr", w32: true, signed: true},
+	"ROTL": {kind: "rotl"}, "ROTLW": {kind: "rotl", w32: true},
+	"CMPB": {kind: "cmpb"},
+}
+
+// ppc64CarryOps gives the operands of the XER.CA arithmetic as
+// x + y + carry-in: "a" and "b" are Ra and Rb of "OP Rb, Ra, Rt" (the
+// unary forms "OP Ra, Rt" have no Rb), "~" complements, "ca" is XER.CA.
+var ppc64CarryOps = map[string][3]string{
+	"ADDC":  {"a", "b", "0"},
+	"ADDE":  {"a", "b", "ca"},
+	"SUBC":  {"~b", "a", "1"},
+	"SUBE":  {"~b", "a", "ca"},
+	"ADDZE": {"a", "0", "ca"},
+	"ADDME": {"a", "-1", "ca"},
+	"SUBZE": {"~a", "0", "ca"},
+	"SUBME": {"~a", "-1", "ca"},
+}
+
+func (c *ppc64Ctx) lowerArith(op string, ins Instr) (ok bool, terminated bool, err error) {
+	if ok, err := c.lowerArithOp(op, ins); ok {
+		return true, false, err
+	}
+	// The CC forms also compare the result with zero into CR0.
+	base, cc := strings.CutSuffix(op, "CC")
+	if !cc || len(ins.Args) == 0 {
+		return false, false, nil
+	}
+	if ok, err := c.lowerArithOp(base, ins); !ok || err != nil {
+		return ok, false, err
+	}
+	dst := ins.Args[len(ins.Args)-1]
+	v, err := c.loadReg(dst.Reg)
+	if err != nil {
+		return true, false, err
+	}
+	return true, false, c.setCR0(v)
+}
+
+func (c *ppc64Ctx) lowerArithOp(op string, ins Instr) (bool, error) {
+	if alu, found := ppc64ALUOps[op]; found {
+		return true, c.lowerALU(op, alu, ins)
+	}
+	if _, found := ppc64CarryOps[op]; found {
+		return true, c.lowerCarry(op, ins)
+	}
+	if _, found := ppc64UnaryOps[op]; found {
+		return true, c.lowerUnary(op, ins)
+	}
+	switch op {
+	case "RLDICL", "RLDICR", "RLDIC", "RLDIMI", "RLDCL", "RLDCR", "CLRLSLDI":
+		return true, c.lowerRLD(op, ins)
+	case "RLWNM", "RLWMI", "CLRLSLWI":
+		return true, c.lowerRLW(op, ins)
+	case "EXTSWSLI":
+		// EXTSWSLI $sh, rs, ra sign-extends the low word of rs and
+		// shifts it left.
+		if len(ins.Args) != 3 || ins.Args[0].Kind != OpImm || !ppc64IsRReg(ins.Args[1]) || !ppc64IsRReg(ins.Args[2]) {
+			return true, fmt.Errorf("ppc64 EXTSWSLI expects $sh, reg, reg: %q", ins.Raw)
+		}
+		v, err := c.loadReg(ins.Args[1].Reg)
+		if err != nil {
+			return true, err
+		}
+		v = c.emit("shl i64 %s, %d", c.narrow(v, 32, true), ins.Args[0].Imm&63)
+		return true, c.storeReg(ins.Args[2].Reg, v)
+	}
+	return false, nil
+}
+
+// aluOperands checks "OP rb|$imm, [ra|$imm,] rt" and evaluates ra and rb;
+// the two-operand form reads rt as ra.
+func (c *ppc64Ctx) aluOperands(op string, ins Instr) (a, b string, dst Reg, err error) {
+	if len(ins.Args) != 2 && len(ins.Args) != 3 {
+		return "", "", "", fmt.Errorf("ppc64 %s expects 2 or 3 operands: %q", op, ins.Raw)
+	}
+	for _, o := range ins.Args[:len(ins.Args)-1] {
+		if o.Kind != OpImm && !ppc64IsRReg(o) {
+			return "", "", "", fmt.Errorf("ppc64 %s expects a register or immediate source: %q", op, ins.Raw)
+		}
+	}
+	last := ins.Args[len(ins.Args)-1]
+	if !ppc64IsRReg(last) {
+		return "", "", "", fmt.Errorf("ppc64 %s expects an R register destination: %q", op, ins.Raw)
+	}
+	if b, err = c.eval64(ins.Args[0]); err != nil {
+		return "", "", "", err
+	}
+	if a, err = c.eval64(ins.Args[len(ins.Args)-2]); err != nil {
+		return "", "", "", err
+	}
+	if len(ins.Args) == 2 {
+		if a, err = c.loadReg(last.Reg); err != nil {
+			return "", "", "", err
+		}
+	}
+	return a, b, last.Reg, nil
+}
+
+// lowerALU lowers "OP rb, ra, rt", which computes rt = ra OP rb; rb may be
+// an immediate, which the assembler materializes when it has no encoding.
+func (c *ppc64Ctx) lowerALU(op string, alu ppc64ALUOp, ins Instr) error {
+	a, b, dst, err := c.aluOperands(op, ins)
+	if err != nil {
+		return err
+	}
+	if alu.kind == "ashr" {
+		// The algebraic shifts also set XER.CA.
+		v, ca := c.shiftRightAlgebraic(a, b, alu.w32)
+		if err := c.setCA(ca); err != nil {
+			return err
+		}
+		return c.storeReg(dst, v)
+	}
+	ty := "i64"
+	if alu.w32 {
+		ty = "i32"
+		a, b = c.trunc32(a), c.trunc32(b)
+	}
+	v := c.aluValue(alu.kind, ty, a, b)
+	if alu.w32 {
+		v = c.extend(v, 32, alu.signed)
+	}
+	return c.storeReg(dst, v)
+}
+
+func (c *ppc64Ctx) trunc32(v string) string {
+	return c.emit("trunc i64 %s to i32", v)
+}
+
+// aluValue computes a OP b in ty. Register shift amounts take one more bit
+// than the width, and shifting by the width or more gives zero; division
+// by zero, which the ISA leaves undefined, gives zero without trapping.
+func (c *ppc64Ctx) aluValue(kind, ty, a, b string) string {
+	bits := 64
+	if ty == "i32" {
+		bits = 32
+	}
+	switch kind {
+	case "add", "sub", "and", "or", "xor", "mul":
+		return c.emit("%s %s %s, %s", kind, ty, a, b)
+	case "addis", "oris", "xoris", "andis":
+		sh := c.emit("shl i64 %s, 16", b)
+		if kind == "oris" || kind == "xoris" || kind == "andis" {
+			sh = c.emit("and i64 %s, 4294901760", sh)
+		}
+		return c.emit("%s i64 %s, %s", strings.TrimSuffix(kind, "is"), a, sh)
+	case "nand", "nor", "eqv":
+		inner := map[string]string{"nand": "and", "nor": "or", "eqv": "xor"}[kind]
+		v := c.emit("%s i64 %s, %s", inner, a, b)
+		return c.emit("xor i64 %s, -1", v)
+	case "andn", "orn":
+		nb := c.emit("xor i64 %s, -1", b)
+		return c.emit("%s i64 %s, %s", kind[:len(kind)-1], a, nb)
+	case "mullw":
+		return c.emit("mul i64 %s, %s", c.narrow(a, 32, true), c.narrow(b, 32, true))
+	case "shl", "lshr":
+		n := c.emit("and %s %s, %d", ty, b, 2*bits-1)
+		big := c.emit("icmp uge %s %s, %d", ty, n, bits)
+		safe := c.emit("and %s %s, %d", ty, n, bits-1)
+		v := c.emit("%s %s %s, %s", kind, ty, a, safe)
+		return c.emit("select i1 %s, %s 0, %s %s", big, ty, ty, v)
+	case "rotl":
+		return c.emit("call %s @llvm.fshl.%s(%s %s, %s %s, %s %s)", ty, ty, ty, a, ty, a, ty, b)
+	case "cmpb":
+		// Each result byte is 0xff where the bytes of a and b are equal.
+		x := c.emit("xor i64 %s, %s", a, b)
+		v := "0"
+		for i := 0; i < 8; i++ {
+			by := c.emit("lshr i64 %s, %d", x, 8*i)
+			by = c.emit("and i64 %s, 255", by)
+			eq := c.emit("icmp eq i64 %s, 0", by)
+			m := c.emit("select i1 %s, i64 %d, i64 0", eq, int64(0xff)<<(8*i))
+			v = c.emit("or i64 %s, %s", v, m)
+		}
+		return v
+	case "mulh", "mulhu":
+		ext := "sext"
+		if kind == "mulhu" {
+			ext = "zext"
+		}
+		wide := fmt.Sprintf("i%d", 2*bits)
+		wa := c.emit("%s %s %s to %s", ext, ty, a, wide)
+		wb := c.emit("%s %s %s to %s", ext, ty, b, wide)
+		p := c.emit("mul %s %s, %s", wide, wa, wb)
+		hi := c.emit("lshr %s %s, %d", wide, p, bits)
+		return c.emit("trunc %s %s to %s", wide, hi, ty)
+	case "div", "divu", "rem", "remu":
+		zero := c.emit("icmp eq %s %s, 0", ty, b)
+		bad := zero
+		if kind == "div" || kind == "rem" {
+			isMin := c.emit("icmp eq %s %s, %d", ty, a, int64(-1)<<(bits-1))
+			isNeg1 := c.emit("icmp eq %s %s, -1", ty, b)
+			ov := c.emit("and i1 %s, %s", isMin, isNeg1)
+			bad = c.emit("or i1 %s, %s", zero, ov)
+		}
+		safe := c.emit("select i1 %s, %s 1, %s %s", bad, ty, ty, b)
+		insn := map[string]string{"div": "sdiv", "divu": "udiv", "rem": "srem", "remu": "urem"}[kind]
+		q := c.emit("%s %s %s, %s", insn, ty, a, safe)
+		return c.emit("select i1 %s, %s 0, %s %s", bad, ty, ty, q)
+	}
+	panic("ppc64: unknown ALU kind " + kind)
+}
+
+// shiftRightAlgebraic computes SRAD (or SRAW with w32) of a by b and the
+// carry it sets: a is negative and 1 bits were shifted out.
+func (c *ppc64Ctx) shiftRightAlgebraic(a, b string, w32 bool) (v, ca string) {
+	ty, bits := "i64", 64
+	if w32 {
+		ty, bits = "i32", 32
+		a, b = c.trunc32(a), c.trunc32(b)
+	}
+	n := c.emit("and %s %s, %d", ty, b, 2*bits-1)
+	big := c.emit("icmp uge %s %s, %d", ty, n, bits)
+	safe := c.emit("and %s %s, %d", ty, n, bits-1)
+	sh := c.emit("ashr %s %s, %s", ty, a, safe)
+	fill := c.emit("ashr %s %s, %d", ty, a, bits-1)
+	v = c.emit("select i1 %s, %s %s, %s %s", big, ty, fill, ty, sh)
+	one := c.emit("shl %s 1, %s", ty, safe)
+	lowMask := c.emit("sub %s %s, 1", ty, one)
+	lowMask = c.emit("select i1 %s, %s -1, %s %s", big, ty, ty, lowMask)
+	lost := c.emit("and %s %s, %s", ty, a, lowMask)
+	lostAny := c.emit("icmp ne %s %s, 0", ty, lost)
+	neg := c.emit("icmp slt %s %s, 0", ty, a)
+	ca = c.emit("and i1 %s, %s", neg, lostAny)
+	if w32 {
+		v = c.extend(v, 32, true)
+	}
+	return v, ca
+}
+
+// loadCA returns XER.CA as an i64 0 or 1.
+func (c *ppc64Ctx) loadCA() (string, error) {
+	x, err := c.loadReg("XER")
+	if err != nil {
+		return "", err
+	}
+	x = c.emit("lshr i64 %s, 29", x)
+	return c.emit("and i64 %s, 1", x), nil
+}
+
+// setCA sets XER.CA from an i1.
+func (c *ppc64Ctx) setCA(ca string) error {
+	x, err := c.loadReg("XER")
+	if err != nil {
+		return err
+	}
+	keep := c.emit("and i64 %s, %d", x, ^int64(ppc64XERCA))
+	v := c.emit("select i1 %s, i64 %d, i64 0", ca, ppc64XERCA)
+	return c.storeReg("XER", c.emit("or i64 %s, %s", keep, v))
+}
+
+// lowerCarry lowers the operations that add with XER.CA: ADDC, ADDE,
+// SUBC and SUBE as "OP rb, ra, rt" (or "OP rb, rt"), and ADDZE, ADDME,
+// SUBZE and SUBME as "OP ra, rt" (or "OP rt").
+func (c *ppc64Ctx) lowerCarry(op string, ins Instr) error {
+	spec := ppc64CarryOps[op]
+	vals := map[string]string{}
+	var dst Reg
+	if strings.Contains(op, "Z") || strings.Contains(op, "M") {
+		if len(ins.Args) < 1 || len(ins.Args) > 2 || !ppc64IsRReg(ins.Args[0]) || !ppc64IsRReg(ins.Args[len(ins.Args)-1]) {
+			return fmt.Errorf("ppc64 %s expects reg[, reg]: %q", op, ins.Raw)
+		}
+		a, err := c.loadReg(ins.Args[0].Reg)
+		if err != nil {
+			return err
+		}
+		vals["a"], dst = a, ins.Args[len(ins.Args)-1].Reg
+	} else {
+		a, b, d, err := c.aluOperands(op, ins)
+		if err != nil {
+			return err
+		}
+		vals["a"], vals["b"], dst = a, b, d
+	}
+	var in [3]string
+	for i, s := range spec {
+		switch {
+		case s == "ca":
+			ca, err := c.loadCA()
+			if err != nil {
+				return err
+			}
+			in[i] = ca
+		case strings.HasPrefix(s, "~"):
+			in[i] = c.emit("xor i64 %s, -1", vals[s[1:]])
+		case vals[s] != "":
+			in[i] = vals[s]
+		default:
+			in[i] = s
+		}
+	}
+	// Add in 128 bits; bit 64 of the sum is the carry out.
+	sum := "0"
+	for _, v := range in {
+		w := c.emit("zext i64 %s to i128", v)
+		sum = c.emit("add i128 %s, %s", sum, w)
+	}
+	hi := c.emit("lshr i128 %s, 64", sum)
+	ca := c.emit("trunc i128 %s to i1", hi)
+	if err := c.setCA(ca); err != nil {
+		return err
+	}
+	return c.storeReg(dst, c.emit("trunc i128 %s to i64", sum))
+}
+
+// ppc64UnaryOps lists the "OP rs, ra" operations; a single register is
+// both source and destination.
+var ppc64UnaryOps = map[string]bool{
+	"NEG": true, "EXTSB": true, "EXTSH": true, "EXTSW": true,
+	"CNTLZD": true, "CNTLZW": true, "CNTTZD": true, "CNTTZW": true,
+	"POPCNTD": true, "POPCNTW": true, "POPCNTB": true,
+	"BRD": true, "BRW": true, "BRH": true,
+}
+
+func (c *ppc64Ctx) lowerUnary(op string, ins Instr) error {
+	if len(ins.Args) != 1 && len(ins.Args) != 2 {
+		return fmt.Errorf("ppc64 %s expects reg, reg: %q", op, ins.Raw)
+	}
+	for _, a := range ins.Args {
+		if !ppc64IsRReg(a) {
+			return fmt.Errorf("ppc64 %s expects R registers: %q", op, ins.Raw)
+		}
+	}
+	a, err := c.loadReg(ins.Args[0].Reg)
+	if err != nil {
+		return err
+	}
+	var v string
+	switch op {
+	case "NEG":
+		v = c.emit("sub i64 0, %s", a)
+	case "EXTSB":
+		v = c.narrow(a, 8, true)
+	case "EXTSH":
+		v = c.narrow(a, 16, true)
+	case "EXTSW":
+		v = c.narrow(a, 32, true)
+	case "CNTLZD":
+		v = c.emit("call i64 @llvm.ctlz.i64(i64 %s, i1 false)", a)
+	case "CNTTZD":
+		v = c.emit("call i64 @llvm.cttz.i64(i64 %s, i1 false)", a)
+	case "CNTLZW", "CNTTZW":
+		fn := map[string]string{"CNTLZW": "ctlz", "CNTTZW": "cttz"}[op]
+		v = c.extend(c.emit("call i32 @llvm.%s.i32(i32 %s, i1 false)", fn, c.trunc32(a)), 32, false)
+	case "POPCNTD", "POPCNTW", "POPCNTB":
+		lanes := map[string]ppc64VecLane{"POPCNTD": {64, 1}, "POPCNTW": {32, 2}, "POPCNTB": {8, 8}}[op]
+		vec := c.emit("bitcast i64 %s to %s", a, lanes.vecType())
+		cnt := c.emit("call %s @llvm.ctpop.%s(%s %s)", lanes.vecType(), lanes.intrinsicSuffix(), lanes.vecType(), vec)
+		v = c.emit("bitcast %s %s to i64", lanes.vecType(), cnt)
+	case "BRD":
+		v = c.emit("call i64 @llvm.bswap.i64(i64 %s)", a)
+	case "BRW", "BRH":
+		// Byte-reverse each word (halfword).
+		lanes := map[string]ppc64VecLane{"BRW": {32, 2}, "BRH": {16, 4}}[op]
+		vec := c.emit("bitcast i64 %s to %s", a, lanes.vecType())
+		r := c.emit("call %s @llvm.bswap.%s(%s %s)", lanes.vecType(), lanes.intrinsicSuffix(), lanes.vecType(), vec)
+		v = c.emit("bitcast %s %s to i64", lanes.vecType(), r)
+	}
+	return c.storeReg(ins.Args[len(ins.Args)-1].Reg, v)
+}
+
+// ppc64Mask64 returns the IBM-numbered mask of bits mb through me (bit 0 is
+// the most significant), wrapping around when mb > me.
+func ppc64Mask64(mb, me int64) uint64 {
+	ones := func(from, to int64) uint64 {
+		return (^uint64(0) >> from) & (^uint64(0) << (63 - to))
+	}
+	if mb <= me {
+		return ones(mb, me)
+	}
+	return ones(mb, 63) | ones(0, me)
+}
+
+// rotAmount evaluates the shift operand of a rotate: an immediate or the
+// low bits of a register.
+func (c *ppc64Ctx) rotAmount(o Operand, bits int64) (string, error) {
+	if o.Kind == OpImm {
+		return fmt.Sprintf("%d", o.Imm&(bits-1)), nil
+	}
+	if !ppc64IsRReg(o) {
+		return "", fmt.Errorf("expects $sh or a register shift")
+	}
+	v, err := c.loadReg(o.Reg)
+	if err != nil {
+		return "", err
+	}
+	return c.emit("and i64 %s, %d", v, bits-1), nil
+}
+
+// lowerRLD lowers the 64-bit rotates "OP $sh|rb, rs, $m, ra".
+// RLDICL, RLDICR and RLDIC take a mask begin (end for RLDICR) bit; RLDCL
+// and RLDCR take the mask value itself. RLDIMI inserts the rotated bits
+// under the mask into ra. "CLRLSLDI $b, rs, $n, ra" clears the b high
+// bits of rs and shifts it left by n.
+func (c *ppc64Ctx) lowerRLD(op string, ins Instr) error {
+	if len(ins.Args) != 4 || !ppc64IsRReg(ins.Args[1]) || ins.Args[2].Kind != OpImm || !ppc64IsRReg(ins.Args[3]) {
+		return fmt.Errorf("ppc64 %s expects $sh|reg, reg, $mask, reg: %q", op, ins.Raw)
+	}
+	sh, m := ins.Args[0], ins.Args[2].Imm
+	if op == "CLRLSLDI" {
+		if sh.Kind != OpImm || m > sh.Imm || sh.Imm > 63 {
+			return fmt.Errorf("ppc64 CLRLSLDI: bad bit counts: %q", ins.Raw)
+		}
+		b := sh.Imm
+		sh, m, op = Operand{Kind: OpImm, Imm: m}, b-m, "RLDIC"
+	}
+	var mask uint64
+	switch op {
+	case "RLDCL", "RLDCR":
+		mask = uint64(m)
+	case "RLDICR":
+		mask = ppc64Mask64(0, m&63)
+	case "RLDICL":
+		mask = ppc64Mask64(m&63, 63)
+	default: // RLDIC, RLDIMI
+		if sh.Kind != OpImm {
+			return fmt.Errorf("ppc64 %s expects an immediate shift: %q", op, ins.Raw)
+		}
+		mask = ppc64Mask64(m&63, 63-sh.Imm&63)
+	}
+	n, err := c.rotAmount(sh, 64)
+	if err != nil {
+		return fmt.Errorf("ppc64 %s %v: %q", op, err, ins.Raw)
+	}
+	rs, err := c.loadReg(ins.Args[1].Reg)
+	if err != nil {
+		return err
+	}
+	r := c.emit("call i64 @llvm.fshl.i64(i64 %s, i64 %s, i64 %s)", rs, rs, n)
+	v := c.emit("and i64 %s, %d", r, int64(mask))
+	if op == "RLDIMI" {
+		ra, err := c.loadReg(ins.Args[3].Reg)
+		if err != nil {
+			return err
+		}
+		keep := c.emit("and i64 %s, %d", ra, int64(^mask))
+		v = c.emit("or i64 %s, %s", keep, v)
+	}
+	return c.storeReg(ins.Args[3].Reg, v)
+}
+
+// lowerRLW lowers the 32-bit rotates "RLWNM $sh|rb, rs, $mask, ra" and
+// RLWMI, whose mask may also be given as "$mb, $me". The low word of rs
+// is rotated and copied into both halves before masking, so a wrapping
+// mask keeps the high word, as on the hardware. "CLRLSLWI $b, rs, $n, ra"
+// clears the b high bits of the low word and shifts it left by n.
+func (c *ppc64Ctx) lowerRLW(op string, ins Instr) error {
+	args := ins.Args
+	if len(args) < 4 || len(args) > 5 || !ppc64IsRReg(args[1]) || !ppc64IsRReg(args[len(args)-1]) {
+		return fmt.Errorf("ppc64 %s expects $sh|reg, reg, $mask, reg: %q", op, ins.Raw)
+	}
+	for _, a := range args[2 : len(args)-1] {
+		if a.Kind != OpImm {
+			return fmt.Errorf("ppc64 %s expects an immediate mask: %q", op, ins.Raw)
+		}
+	}
+	sh := args[0]
+	var mb, me int64
+	switch {
+	case op == "CLRLSLWI":
+		if len(args) != 4 || sh.Kind != OpImm || args[2].Imm > sh.Imm || sh.Imm > 31 {
+			return fmt.Errorf("ppc64 CLRLSLWI: bad bit counts: %q", ins.Raw)
+		}
+		n := args[2].Imm
+		mb, me, sh = sh.Imm-n, 31-n, Operand{Kind: OpImm, Imm: n}
+	case len(args) == 5:
+		mb, me = args[2].Imm&31, args[3].Imm&31
+	default:
+		var ok bool
+		if mb, me, ok = ppc64DecodeMask32(uint32(args[2].Imm)); !ok {
+			return fmt.Errorf("ppc64 %s: invalid mask %#x: %q", op, args[2].Imm, ins.Raw)
+		}
+	}
+	mask := ppc64Mask64(mb+32, me+32)
+	n, err := c.rotAmount(sh, 32)
+	if err != nil {
+		return fmt.Errorf("ppc64 %s %v: %q", op, err, ins.Raw)
+	}
+	rs, err := c.loadReg(args[1].Reg)
+	if err != nil {
+		return err
+	}
+	w := c.trunc32(rs)
+	r := c.emit("call i32 @llvm.fshl.i32(i32 %s, i32 %s, i32 %s)", w, w, c.trunc32(n))
+	r64 := c.extend(r, 32, false)
+	dup := c.emit("shl i64 %s, 32", r64)
+	dup = c.emit("or i64 %s, %s", dup, r64)
+	v := c.emit("and i64 %s, %d", dup, int64(mask))
+	dst := args[len(args)-1].Reg
+	if op == "RLWMI" {
+		ra, err := c.loadReg(dst)
+		if err != nil {
+			return err
+		}
+		keep := c.emit("and i64 %s, %d", ra, int64(^mask))
+		v = c.emit("or i64 %s, %s", keep, v)
+	}
+	return c.storeReg(dst, v)
+}
+
+// ppc64DecodeMask32 returns the IBM-numbered begin and end bits of a
+// contiguous (possibly wrapping) 32-bit mask.
+func ppc64DecodeMask32(m uint32) (mb, me int64, ok bool) {
+	if m == 0 {
+		return 0, 0, false
+	}
+	wrap := m&1 != 0 && m&0x80000000 != 0 && m != 0xffffffff
+	if wrap {
+		m = ^m
+	}
+	// m is now a single run of ones; find its ends.
+	first, last := int64(-1), int64(-1)
+	for i := int64(0); i < 32; i++ {
+		if m&(0x80000000>>i) != 0 {
+			if first < 0 {
+				first = i
+			}
+			last = i
+		}
+	}
+	for i := first; i <= last; i++ {
+		if m&(0x80000000>>i) == 0 {
+			return 0, 0, false
+		}
+	}
+	if wrap {
+		return last + 1, first - 1, true
+	}
+	return first, last, true
+}
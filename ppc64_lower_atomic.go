@@ -0,0 +1,113 @@
+package plan9asm
+
+import "fmt"
+
+// ppc64ReserveBits gives the access width of the load-and-reserve and
+// store-conditional instructions.
+var ppc64ReserveBits = map[string]int{
+	"LBAR": 8, "LHAR": 16, "LWAR": 32, "LDAR": 64,
+	"STBCCC": 8, "STHCCC": 16, "STWCCC": 32, "STDCCC": 64,
+}
+
+func (c *ppc64Ctx) lowerAtomic(op string, ins Instr) (ok bool, terminated bool, err error) {
+	switch op {
+	case "SYNC", "HWSYNC", "LWSYNC", "ISYNC", "EIEIO":
+		// LWSYNC and EIEIO order less than SYNC, and ISYNC only orders
+		// the instruction stream; a full fence is always valid.
+		c.b.WriteString("  fence seq_cst\n")
+		return true, false, nil
+	case "LBAR", "LHAR", "LWAR", "LDAR":
+		return true, false, c.lowerLoadReserve(op, ins)
+	case "STBCCC", "STHCCC", "STWCCC", "STDCCC":
+		return true, false, c.lowerStoreCond(op, ins)
+	case "DCBZ":
+		// DCBZ zeroes the 128-byte cache block containing the address.
+		if len(ins.Args) != 1 || ins.Args[0].Kind != OpMem {
+			return true, false, fmt.Errorf("ppc64 DCBZ expects a memory operand: %q", ins.Raw)
+		}
+		ea, err := c.addrI64(ins.Args[0].Mem)
+		if err != nil {
+			return true, false, err
+		}
+		ea = c.emit("and i64 %s, -128", ea)
+		p := c.emit("inttoptr i64 %s to ptr", ea)
+		fmt.Fprintf(c.b, "  call void @llvm.memset.p0.i64(ptr %s, i8 0, i64 128, i1 false)\n", p)
+		return true, false, nil
+	}
+	return false, false, nil
+}
+
+// lowerLoadReserve lowers "LDAR (ra)[(rb)][, $hint], rt", which loads
+// zero-extended and reserves the address; the hint is ignored.
+func (c *ppc64Ctx) lowerLoadReserve(op string, ins Instr) error {
+	args := ins.Args
+	if len(args) == 3 && args[1].Kind == OpImm {
+		args = []Operand{args[0], args[2]}
+	}
+	if len(args) != 2 || args[0].Kind != OpMem || !ppc64IsRReg(args[1]) {
+		return fmt.Errorf("ppc64 %s expects (reg), reg: %q", op, ins.Raw)
+	}
+	bits := ppc64ReserveBits[op]
+	ptr, err := c.memPtr(args[0].Mem)
+	if err != nil {
+		return err
+	}
+	v := c.emit("load atomic i%d, ptr %s seq_cst, align %d", bits, ptr, bits/8)
+	if bits < 64 {
+		v = c.extend(v, bits, false)
+	}
+	fmt.Fprintf(c.b, "  store i1 true, ptr %s\n", c.reservedValidSlot)
+	fmt.Fprintf(c.b, "  store ptr %s, ptr %s\n", ptr, c.reservedPtrSlot)
+	fmt.Fprintf(c.b, "  store i64 %s, ptr %s\n", v, c.reservedValueSlot)
+	return c.storeReg(args[1].Reg, v)
+}
+
+// lowerStoreCond lowers "STDCCC rs, (ra)", which stores rs if the
+// reservation still holds and sets CR0 to EQ on success and to 0 on
+// failure. The reservation is modeled as the value the reserving load
+// saw, so the store is a cmpxchg against it.
+func (c *ppc64Ctx) lowerStoreCond(op string, ins Instr) error {
+	if len(ins.Args) != 2 || !ppc64IsRReg(ins.Args[0]) || ins.Args[1].Kind != OpMem {
+		return fmt.Errorf("ppc64 %s expects reg, (reg): %q", op, ins.Raw)
+	}
+	bits := ppc64ReserveBits[op]
+	ty := fmt.Sprintf("i%d", bits)
+	src, err := c.loadReg(ins.Args[0].Reg)
+	if err != nil {
+		return err
+	}
+	ptr, err := c.memPtr(ins.Args[1].Mem)
+	if err != nil {
+		return err
+	}
+	valid := c.emit("load i1, ptr %s", c.reservedValidSlot)
+	resPtr := c.emit("load ptr, ptr %s", c.reservedPtrSlot)
+	same := c.emit("icmp eq ptr %s, %s", resPtr, ptr)
+	canTry := c.emit("and i1 %s, %s", valid, same)
+
+	id := c.newTmp()
+	tryLabel := "stcx_try_" + id
+	failLabel := "stcx_fail_" + id
+	mergeLabel := "stcx_merge_" + id
+	fmt.Fprintf(c.b, "  br i1 %s, label %%%s, label %%%s\n", canTry, tryLabel, failLabel)
+
+	fmt.Fprintf(c.b, "\n%s:\n", tryLabel)
+	expected := c.emit("load i64, ptr %s", c.reservedValueSlot)
+	newv := src
+	if bits < 64 {
+		expected = c.emit("trunc i64 %s to %s", expected, ty)
+		newv = c.emit("trunc i64 %s to %s", src, ty)
+	}
+	cx := c.emit("cmpxchg ptr %s, %s %s, %s %s seq_cst seq_cst, align %d", ptr, ty, expected, ty, newv, bits/8)
+	stored := c.emit("extractvalue {%s, i1} %s, 1", ty, cx)
+	tryCR := c.emit("select i1 %s, i64 %d, i64 0", stored, ppc64CREQ)
+	fmt.Fprintf(c.b, "  br label %%%s\n", mergeLabel)
+
+	fmt.Fprintf(c.b, "\n%s:\n", failLabel)
+	fmt.Fprintf(c.b, "  br label %%%s\n", mergeLabel)
+
+	fmt.Fprintf(c.b, "\n%s:\n", mergeLabel)
+	cr := c.emit("phi i64 [ %s, %%%s ], [ 0, %%%s ]", tryCR, tryLabel, failLabel)
+	fmt.Fprintf(c.b, "  store i1 false, ptr %s\n", c.reservedValidSlot)
+	return c.storeReg("CR0", cr)
+}
@@ -0,0 +1,368 @@
+package plan9asm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ppc64BranchConds maps the conditional branches to the CR field bit they
+// test and the value they branch on. "BEQ [CRn,] target" tests CRn (CR0
+// by default); BGE, BLE, BNE and BVC branch when the bit is clear.
+var ppc64BranchConds = map[string]struct {
+	bit int64
+	set bool
+}{
+	"BLT": {0, true}, "BGE": {0, false},
+	"BGT": {1, true}, "BLE": {1, false},
+	"BEQ": {2, true}, "BNE": {2, false},
+	"BVS": {3, true}, "BVC": {3, false},
+}
+
+// branchTarget resolves a label, local symbol or n(PC) operand of the
+// instruction at index ii of block bi to a block name.
+func (c *ppc64Ctx) branchTarget(bi, ii int, op Operand) (string, bool) {
+	switch op.Kind {
+	case OpIdent:
+		return op.Ident, true
+	case OpSym:
+		s := strings.TrimSpace(op.Sym)
+		if strings.HasSuffix(s, "(SB)") {
+			return "", false
+		}
+		return strings.TrimSuffix(s, "<>"), s != ""
+	case OpMem:
+		if op.Mem.Base != PC {
+			return "", false
+		}
+		tbi, ok := c.blockByIdx[c.blockBase[bi]+ii+int(op.Mem.Off)]
+		if !ok {
+			return "", false
+		}
+		return c.blocks[tbi].name, true
+	}
+	return "", false
+}
+
+func (c *ppc64Ctx) condBr(bi int, cond, tgt string) {
+	fmt.Fprintf(c.b, "  br i1 %s, label %%%s, label %%%s\n", cond, arm64LLVMBlockName(tgt), arm64LLVMBlockName(c.blocks[bi+1].name))
+}
+
+// ppc64IsLinkReg reports whether a branch target is LR or (LR), a return.
+func ppc64IsLinkReg(o Operand) bool {
+	return (o.Kind == OpReg || o.Kind == OpMem && o.Mem.Index == "" && o.Mem.Off == 0) && (o.Reg == "LR" || o.Mem.Base == "LR")
+}
+
+// ppc64IsCountReg reports whether a branch target is CTR or (CTR).
+func ppc64IsCountReg(o Operand) bool {
+	return (o.Kind == OpReg || o.Kind == OpMem && o.Mem.Index == "" && o.Mem.Off == 0) && (o.Reg == "CTR" || o.Mem.Base == "CTR")
+}
+
+func (c *ppc64Ctx) lowerBranch(bi, ii int, op string, ins Instr) (ok bool, terminated bool, err error) {
+	if cond, found := ppc64BranchConds[op]; found {
+		// "BEQ [CRn,] target".
+		field := Reg("CR0")
+		switch {
+		case len(ins.Args) == 2 && ppc64IsCRFieldOp(ins.Args[0]):
+			field = ins.Args[0].Reg
+		case len(ins.Args) != 1:
+			return true, false, fmt.Errorf("ppc64 %s expects [CRn,] target: %q", op, ins.Raw)
+		}
+		bo := int64(4) // no CTR decrement
+		if cond.set {
+			bo |= 8
+		}
+		return true, true, c.bc(bi, ii, bo, 4*int64(field[2]-'0')+cond.bit, ins.Args[len(ins.Args)-1], ins)
+	}
+
+	switch op {
+	case "BC":
+		// "BC BO, BI, target": BO&16 ignores the CR bit BI and BO&8 is
+		// the value to branch on; unless BO&4, CTR is decremented and
+		// tested for zero (BO&2) or nonzero.
+		if len(ins.Args) != 3 {
+			return true, false, fmt.Errorf("ppc64 BC expects BO, BI, target: %q", ins.Raw)
+		}
+		if ins.Args[0].Kind != OpImm {
+			return true, false, fmt.Errorf("ppc64 BC expects a numeric BO: %q", ins.Raw)
+		}
+		bit, ok := ppc64CRBit(ins.Args[1])
+		if !ok {
+			return true, false, fmt.Errorf("ppc64 BC expects a CR bit: %q", ins.Raw)
+		}
+		return true, true, c.bc(bi, ii, ins.Args[0].Imm, bit, ins.Args[2], ins)
+
+	case "BDNZ", "BDZ":
+		if len(ins.Args) != 1 {
+			return true, false, fmt.Errorf("ppc64 %s expects a target: %q", op, ins.Raw)
+		}
+		bo := int64(16)
+		if op == "BDZ" {
+			bo = 18
+		}
+		return true, true, c.bc(bi, ii, bo, 0, ins.Args[0], ins)
+
+	case "JMP", "BR":
+		if len(ins.Args) != 1 {
+			return true, false, fmt.Errorf("ppc64 %s expects 1 operand: %q", op, ins.Raw)
+		}
+		return true, true, c.jump(bi, ii, ins.Args[0], ins)
+
+	case "CALL", "BL":
+		if len(ins.Args) != 1 {
+			return true, false, fmt.Errorf("ppc64 %s expects 1 operand: %q", op, ins.Raw)
+		}
+		return true, false, c.call(ins.Args[0], ins)
+	}
+	return false, false, nil
+}
+
+// bc lowers a conditional branch with BO and BI decoded.
+func (c *ppc64Ctx) bc(bi, ii int, bo, crBit int64, target Operand, ins Instr) error {
+	cond := ""
+	if bo&4 == 0 {
+		ctr, err := c.loadReg("CTR")
+		if err != nil {
+			return err
+		}
+		ctr = c.emit("sub i64 %s, 1", ctr)
+		if err := c.storeReg("CTR", ctr); err != nil {
+			return err
+		}
+		pred := "ne"
+		if bo&2 != 0 {
+			pred = "eq"
+		}
+		cond = c.emit("icmp %s i64 %s, 0", pred, ctr)
+	}
+	if bo&16 == 0 {
+		bit, err := c.crBit(crBit)
+		if err != nil {
+			return err
+		}
+		if bo&8 == 0 {
+			bit = c.emit("xor i1 %s, true", bit)
+		}
+		if cond == "" {
+			cond = bit
+		} else {
+			cond = c.emit("and i1 %s, %s", cond, bit)
+		}
+	}
+	if cond == "" {
+		return c.jump(bi, ii, target, ins)
+	}
+	if bi+1 >= len(c.blocks) {
+		return fmt.Errorf("ppc64 %s needs fallthrough block: %q", ins.Op, ins.Raw)
+	}
+	if ppc64IsLinkReg(target) {
+		// A conditional return.
+		ret := "cond_ret_" + c.newTmp()
+		c.condBr(bi, cond, ret)
+		fmt.Fprintf(c.b, "\n%s:\n", ret)
+		return c.lowerRET()
+	}
+	tgt, ok := c.branchTarget(bi, ii, target)
+	if !ok {
+		return fmt.Errorf("ppc64 %s invalid target: %q", ins.Op, ins.Raw)
+	}
+	c.condBr(bi, cond, tgt)
+	return nil
+}
+
+// jump lowers an unconditional branch: to a label, a tail call to
+// sym(SB), a return through LR or an indirect jump through CTR. ELFv2
+// expects the target address in R12 as well as CTR.
+func (c *ppc64Ctx) jump(bi, ii int, target Operand, ins Instr) error {
+	switch {
+	case riscv64IsSBSym(target):
+		return c.tailCallAndRet(target)
+	case ppc64IsLinkReg(target):
+		return c.lowerRET()
+	case ppc64IsCountReg(target):
+		addr, err := c.loadReg("CTR")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(c.b, "  call void asm sideeffect \"mtctr 12; bctr\", %q(i64 %s)\n", "{r12},~{ctr},~{memory}", addr)
+		c.lowerRetZero()
+		return nil
+	}
+	tgt, ok := c.branchTarget(bi, ii, target)
+	if !ok {
+		return fmt.Errorf("ppc64 %s invalid target: %q", ins.Op, ins.Raw)
+	}
+	c.br(tgt)
+	return nil
+}
+
+// call lowers "BL sym(SB)" and the indirect "BL (CTR)".
+func (c *ppc64Ctx) call(target Operand, ins Instr) error {
+	if riscv64IsSBSym(target) {
+		return c.callSym(target)
+	}
+	if !ppc64IsCountReg(target) {
+		return fmt.Errorf("ppc64 %s expects symbol(SB) or (CTR): %q", ins.Op, ins.Raw)
+	}
+	addr, err := c.loadReg("CTR")
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(c.b, "  call void asm sideeffect \"mtctr 12; bctrl\", %q(i64 %s)\n", "{r12},~{lr},~{ctr},~{memory}", addr)
+	return nil
+}
+
+// callArgs reads csig's arguments from the ABIInternal registers (or
+// csig.ArgRegs), assembling aggregates from consecutive registers.
+func (c *ppc64Ctx) callArgs(csig FuncSig) ([]string, error) {
+	args := make([]string, 0, len(csig.Args))
+	var cur ppc64ArgCursor
+	for i, argTy := range csig.Args {
+		if len(csig.ArgRegs) > 0 {
+			if i >= len(csig.ArgRegs) {
+				return nil, fmt.Errorf("no register for arg %d", i)
+			}
+			v, err := c.loadReg(csig.ArgRegs[i])
+			if err != nil {
+				return nil, err
+			}
+			val, err := c.i64ToValue(v, argTy)
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, fmt.Sprintf("%s %s", argTy, val))
+			continue
+		}
+		fields, isAgg := parseLiteralStructFields(argTy)
+		if !isAgg {
+			fields = []LLVMType{argTy}
+		}
+		agg := "undef"
+		val := ""
+		for fi, fTy := range fields {
+			r, ok := cur.next(fTy)
+			if !ok {
+				return nil, fmt.Errorf("too many register args")
+			}
+			v, err := c.loadReg(r)
+			if err != nil {
+				return nil, err
+			}
+			if val, err = c.i64ToValue(v, fTy); err != nil {
+				return nil, err
+			}
+			if isAgg {
+				agg = c.emit("insertvalue %s %s, %s %s, %d", argTy, agg, fTy, val, fi)
+				val = agg
+			}
+		}
+		args = append(args, fmt.Sprintf("%s %s", argTy, val))
+	}
+	return args, nil
+}
+
+// storeCallResult writes a call's result to the ABIInternal result
+// registers.
+func (c *ppc64Ctx) storeCallResult(ty LLVMType, v string) error {
+	fields, isAgg := parseLiteralStructFields(ty)
+	if !isAgg {
+		fields = []LLVMType{ty}
+	}
+	var cur ppc64ArgCursor
+	for fi, fTy := range fields {
+		r, ok := cur.next(fTy)
+		if !ok {
+			return fmt.Errorf("too many register results")
+		}
+		fv := v
+		if isAgg {
+			fv = c.emit("extractvalue %s %s, %d", ty, v, fi)
+		}
+		v64, ok, err := c.valueAsI64(fTy, fv)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("unsupported result type %s", fTy)
+		}
+		if err := c.storeReg(r, v64); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *ppc64Ctx) callSym(symOp Operand) error {
+	callee := c.resolve(strings.TrimSuffix(strings.TrimSpace(symOp.Sym), "(SB)"))
+	// Syscall stubs invoke runtime entersyscall/exitsyscall around SYSCALL.
+	// llgo runtime does not require these scheduler hooks at this layer.
+	if callee == "runtime.entersyscall" || callee == "runtime.exitsyscall" {
+		return nil
+	}
+	csig, ok := c.sigs[callee]
+	if !ok {
+		csig = FuncSig{Name: callee, Ret: Void}
+	}
+	args, err := c.callArgs(csig)
+	if err != nil {
+		return fmt.Errorf("ppc64 call %q: %v", callee, err)
+	}
+	if csig.Ret == Void {
+		fmt.Fprintf(c.b, "  call void %s(%s)\n", llvmGlobal(callee), strings.Join(args, ", "))
+		return nil
+	}
+	r := c.emit("call %s %s(%s)", csig.Ret, llvmGlobal(callee), strings.Join(args, ", "))
+	if err := c.storeCallResult(csig.Ret, r); err != nil {
+		return fmt.Errorf("ppc64 call %q: %v", callee, err)
+	}
+	return nil
+}
+
+func (c *ppc64Ctx) tailCallAndRet(symOp Operand) error {
+	callee := c.resolve(strings.TrimSuffix(strings.TrimSpace(symOp.Sym), "(SB)"))
+	csig, ok := c.sigs[callee]
+	if !ok {
+		// Cross-package trampoline (e.g. sync/atomic -> internal/runtime/atomic).
+		// Without an explicit signature, fall back to the caller's.
+		csig = c.sig
+		csig.Name = callee
+	}
+
+	// A JMP to a function with the caller's signature is an ABI0 tail call
+	// made before any register shuffling, so pass the caller's own args.
+	var args []string
+	if len(csig.ArgRegs) == 0 && sameLLVMTypes(csig.Args, c.sig.Args) && csig.Ret == c.sig.Ret {
+		for i, ty := range csig.Args {
+			args = append(args, fmt.Sprintf("%s %%arg%d", ty, i))
+		}
+	} else {
+		var err error
+		if args, err = c.callArgs(csig); err != nil {
+			return fmt.Errorf("ppc64 tailcall %q: %v", callee, err)
+		}
+	}
+
+	if csig.Ret == Void {
+		fmt.Fprintf(c.b, "  call void %s(%s)\n", llvmGlobal(callee), strings.Join(args, ", "))
+		if len(c.fpResults) > 0 {
+			return c.lowerRET()
+		}
+		c.lowerRetZero()
+		return nil
+	}
+	r := c.emit("call %s %s(%s)", csig.Ret, llvmGlobal(callee), strings.Join(args, ", "))
+	if c.sig.Ret == Void {
+		c.b.WriteString("  ret void\n")
+		return nil
+	}
+	if csig.Ret != c.sig.Ret {
+		v64, ok, err := c.valueAsI64(csig.Ret, r)
+		if err != nil || !ok {
+			return fmt.Errorf("ppc64 tailcall return mismatch: caller %s callee %s", c.sig.Ret, csig.Ret)
+		}
+		if r, err = c.i64ToValue(v64, c.sig.Ret); err != nil {
+			return fmt.Errorf("ppc64 tailcall return mismatch: caller %s callee %s", c.sig.Ret, csig.Ret)
+		}
+	}
+	fmt.Fprintf(c.b, "  ret %s %s\n", c.sig.Ret, r)
+	return nil
+}
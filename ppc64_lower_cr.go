@@ -0,0 +1,280 @@
+package plan9asm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ppc64CRLogic maps the condition register logical operations to the
+// function of (ba, bb) they compute; "CRAND bb, ba, bt" sets bit bt.
+var ppc64CRLogic = map[string]string{
+	"CRAND": "and", "CROR": "or", "CRXOR": "xor",
+	"CRNAND": "nand", "CRNOR": "nor", "CREQV": "eqv",
+	"CRANDN": "andn", "CRORN": "orn",
+}
+
+func (c *ppc64Ctx) lowerCR(op string, ins Instr) (ok bool, terminated bool, err error) {
+	if kind, found := ppc64CRLogic[op]; found {
+		return true, false, c.lowerCRLogic(op, kind, ins)
+	}
+	switch op {
+	case "CMP", "CMPU", "CMPW", "CMPWU":
+		return true, false, c.lowerCMP(op, ins)
+	case "FCMPU", "FCMPO":
+		return true, false, c.lowerFCMP(op, ins)
+	case "ISEL":
+		return true, false, c.lowerISEL(ins)
+	case "SETB":
+		// SETB CRn, Rt sets Rt to -1, 1 or 0 for LT, GT and neither.
+		if len(ins.Args) != 2 || !ppc64IsCRFieldOp(ins.Args[0]) || !ppc64IsRReg(ins.Args[1]) {
+			return true, false, fmt.Errorf("ppc64 SETB expects CRn, reg: %q", ins.Raw)
+		}
+		f, err := c.loadReg(ins.Args[0].Reg)
+		if err != nil {
+			return true, false, err
+		}
+		lt := c.emit("and i64 %s, %d", f, ppc64CRLT)
+		lt = c.emit("icmp ne i64 %s, 0", lt)
+		gt := c.emit("and i64 %s, %d", f, ppc64CRGT)
+		gt = c.emit("icmp ne i64 %s, 0", gt)
+		v := c.emit("select i1 %s, i64 1, i64 0", gt)
+		v = c.emit("select i1 %s, i64 -1, i64 %s", lt, v)
+		return true, false, c.storeReg(ins.Args[1].Reg, v)
+	case "MOVFL":
+		return true, false, c.lowerMOVFL(ins)
+	}
+	return false, false, nil
+}
+
+// crField returns the CR field value (LT, GT or EQ) of comparing a with b
+// under the lt and gt predicates.
+func (c *ppc64Ctx) crField(ty, ltPred, gtPred, a, b string) string {
+	lt := c.emit("icmp %s %s %s, %s", ltPred, ty, a, b)
+	gt := c.emit("icmp %s %s %s, %s", gtPred, ty, a, b)
+	v := c.emit("select i1 %s, i64 %d, i64 %d", gt, ppc64CRGT, ppc64CREQ)
+	return c.emit("select i1 %s, i64 %d, i64 %s", lt, ppc64CRLT, v)
+}
+
+// setCR0 records the signed comparison of a result with zero in CR0, as
+// the CC forms do.
+func (c *ppc64Ctx) setCR0(v string) error {
+	return c.storeReg("CR0", c.crField("i64", "slt", "sgt", v, "0"))
+}
+
+// ppc64CRFieldArg returns the CR field named by an optional operand after
+// the first n, CR0 by default.
+func ppc64CRFieldArg(args []Operand, n int) (Reg, bool) {
+	if len(args) == n {
+		return "CR0", true
+	}
+	if len(args) == n+1 && ppc64IsCRFieldOp(args[n]) {
+		return args[n].Reg, true
+	}
+	return "", false
+}
+
+// lowerCMP lowers "CMP ra, rb|$imm[, CRn]", which compares ra with rb
+// (signed; CMPU unsigned, the W forms on the low words) into CRn.
+// "CMP $imm, ra" also compares ra with imm.
+func (c *ppc64Ctx) lowerCMP(op string, ins Instr) error {
+	cr, ok := ppc64CRFieldArg(ins.Args, 2)
+	if ok && ins.Args[0].Kind == OpImm {
+		// The assembler also accepts the immediate first.
+		ins.Args[0], ins.Args[1] = ins.Args[1], ins.Args[0]
+	}
+	if !ok || !ppc64IsRReg(ins.Args[0]) || (ins.Args[1].Kind != OpImm && !ppc64IsRReg(ins.Args[1])) {
+		return fmt.Errorf("ppc64 %s expects reg, reg|$imm[, CRn]: %q", op, ins.Raw)
+	}
+	a, err := c.loadReg(ins.Args[0].Reg)
+	if err != nil {
+		return err
+	}
+	b, err := c.eval64(ins.Args[1])
+	if err != nil {
+		return err
+	}
+	ty := "i64"
+	if op == "CMPW" || op == "CMPWU" {
+		ty = "i32"
+		a, b = c.trunc32(a), c.trunc32(b)
+	}
+	lt, gt := "slt", "sgt"
+	if strings.HasSuffix(op, "U") {
+		lt, gt = "ult", "ugt"
+	}
+	return c.storeReg(cr, c.crField(ty, lt, gt, a, b))
+}
+
+// lowerFCMP lowers "FCMPU fa, fb[, CRn]". An unordered result sets SO
+// (FU) alone.
+func (c *ppc64Ctx) lowerFCMP(op string, ins Instr) error {
+	cr, ok := ppc64CRFieldArg(ins.Args, 2)
+	if !ok || !ppc64IsFRegOp(ins.Args[0]) || !ppc64IsFRegOp(ins.Args[1]) {
+		return fmt.Errorf("ppc64 %s expects F, F[, CRn]: %q", op, ins.Raw)
+	}
+	a, err := c.loadF(ins.Args[0].Reg)
+	if err != nil {
+		return err
+	}
+	b, err := c.loadF(ins.Args[1].Reg)
+	if err != nil {
+		return err
+	}
+	lt := c.emit("fcmp olt double %s, %s", a, b)
+	gt := c.emit("fcmp ogt double %s, %s", a, b)
+	eq := c.emit("fcmp oeq double %s, %s", a, b)
+	v := c.emit("select i1 %s, i64 %d, i64 %d", eq, ppc64CREQ, ppc64CRSO)
+	v = c.emit("select i1 %s, i64 %d, i64 %s", gt, ppc64CRGT, v)
+	v = c.emit("select i1 %s, i64 %d, i64 %s", lt, ppc64CRLT, v)
+	return c.storeReg(cr, v)
+}
+
+// crBit reads condition register bit n as an i1.
+func (c *ppc64Ctx) crBit(n int64) (string, error) {
+	f, err := c.loadReg(Reg(fmt.Sprintf("CR%d", n/4)))
+	if err != nil {
+		return "", err
+	}
+	m := c.emit("and i64 %s, %d", f, ppc64CRLT>>(n%4))
+	return c.emit("icmp ne i64 %s, 0", m), nil
+}
+
+func (c *ppc64Ctx) setCRBit(n int64, bit string) error {
+	field := Reg(fmt.Sprintf("CR%d", n/4))
+	f, err := c.loadReg(field)
+	if err != nil {
+		return err
+	}
+	mask := int64(ppc64CRLT >> (n % 4))
+	keep := c.emit("and i64 %s, %d", f, ^mask)
+	v := c.emit("select i1 %s, i64 %d, i64 0", bit, mask)
+	return c.storeReg(field, c.emit("or i64 %s, %s", keep, v))
+}
+
+func (c *ppc64Ctx) lowerCRLogic(op, kind string, ins Instr) error {
+	if len(ins.Args) != 3 {
+		return fmt.Errorf("ppc64 %s expects three CR bits: %q", op, ins.Raw)
+	}
+	var bits [3]int64
+	for i, a := range ins.Args {
+		n, ok := ppc64CRBit(a)
+		if !ok {
+			return fmt.Errorf("ppc64 %s expects CR bits: %q", op, ins.Raw)
+		}
+		bits[i] = n
+	}
+	bb, err := c.crBit(bits[0])
+	if err != nil {
+		return err
+	}
+	ba, err := c.crBit(bits[1])
+	if err != nil {
+		return err
+	}
+	var v string
+	switch kind {
+	case "and", "or", "xor":
+		v = c.emit("%s i1 %s, %s", kind, ba, bb)
+	case "nand", "nor", "eqv":
+		inner := map[string]string{"nand": "and", "nor": "or", "eqv": "xor"}[kind]
+		v = c.emit("%s i1 %s, %s", inner, ba, bb)
+		v = c.emit("xor i1 %s, true", v)
+	case "andn", "orn":
+		nb := c.emit("xor i1 %s, true", bb)
+		v = c.emit("%s i1 %s, %s", kind[:len(kind)-1], ba, nb)
+	}
+	return c.setCRBit(bits[2], v)
+}
+
+// lowerISEL lowers "ISEL bi, ra, rb, rt", which sets rt to ra if CR bit
+// bi is set and to rb otherwise; ra names zero when it is R0.
+func (c *ppc64Ctx) lowerISEL(ins Instr) error {
+	if len(ins.Args) != 4 {
+		return fmt.Errorf("ppc64 ISEL expects bi, ra, rb, rt: %q", ins.Raw)
+	}
+	bi, ok := ppc64CRBit(ins.Args[0])
+	if !ok {
+		return fmt.Errorf("ppc64 ISEL expects a CR bit: %q", ins.Raw)
+	}
+	for _, a := range ins.Args[1:] {
+		if !ppc64IsRReg(a) {
+			return fmt.Errorf("ppc64 ISEL expects R registers: %q", ins.Raw)
+		}
+	}
+	bit, err := c.crBit(bi)
+	if err != nil {
+		return err
+	}
+	a := "0"
+	if ins.Args[1].Reg != "R0" {
+		if a, err = c.loadReg(ins.Args[1].Reg); err != nil {
+			return err
+		}
+	}
+	b, err := c.loadReg(ins.Args[2].Reg)
+	if err != nil {
+		return err
+	}
+	return c.storeReg(ins.Args[3].Reg, c.emit("select i1 %s, i64 %s, i64 %s", bit, a, b))
+}
+
+// loadCR assembles the 32-bit condition register from its fields, CR0
+// in the most significant nibble.
+func (c *ppc64Ctx) loadCR() string {
+	v := "0"
+	for n := 0; n < 8; n++ {
+		f := c.emit("load i64, ptr %s", c.regSlot[Reg(fmt.Sprintf("CR%d", n))])
+		f = c.emit("shl i64 %s, %d", f, 28-4*n)
+		v = c.emit("or i64 %s, %s", v, f)
+	}
+	return v
+}
+
+// storeCR sets the CR fields selected by the FXM mask (0x80 for CR0)
+// from the corresponding nibbles of v.
+func (c *ppc64Ctx) storeCR(v string, fxm int64) {
+	for n := 0; n < 8; n++ {
+		if fxm&(0x80>>n) == 0 {
+			continue
+		}
+		f := c.emit("lshr i64 %s, %d", v, 28-4*n)
+		f = c.emit("and i64 %s, 15", f)
+		fmt.Fprintf(c.b, "  store i64 %s, ptr %s\n", f, c.regSlot[Reg(fmt.Sprintf("CR%d", n))])
+	}
+}
+
+// lowerMOVFL lowers the condition register moves: "MOVFL CRa, CRb"
+// copies a field, and "MOVFL rs, CRn|$fxm|CR" sets fields from rs.
+func (c *ppc64Ctx) lowerMOVFL(ins Instr) error {
+	if len(ins.Args) != 2 {
+		return fmt.Errorf("ppc64 MOVFL expects 2 operands: %q", ins.Raw)
+	}
+	src, dst := ins.Args[0], ins.Args[1]
+	if ppc64IsCRFieldOp(src) && ppc64IsCRFieldOp(dst) {
+		v, err := c.loadReg(src.Reg)
+		if err != nil {
+			return err
+		}
+		return c.storeReg(dst.Reg, v)
+	}
+	if !ppc64IsRReg(src) {
+		return fmt.Errorf("ppc64 MOVFL: unsupported operands (FPSCR is not modeled): %q", ins.Raw)
+	}
+	var fxm int64
+	switch {
+	case dst.Kind == OpImm && dst.Imm >= 0 && dst.Imm <= 0xff:
+		fxm = dst.Imm
+	case dst.Kind == OpReg && dst.Reg == "CR":
+		fxm = 0xff
+	case ppc64IsCRFieldOp(dst):
+		fxm = 0x80 >> (dst.Reg[2] - '0')
+	default:
+		return fmt.Errorf("ppc64 MOVFL expects CRn, $fxm or CR: %q", ins.Raw)
+	}
+	v, err := c.loadReg(src.Reg)
+	if err != nil {
+		return err
+	}
+	c.storeCR(v, fxm)
+	return nil
+}
@@ -0,0 +1,232 @@
+package plan9asm
+
+import (
+	"fmt"
+	"strings"
+)
+
+type ppc64MovKind int
+
+const (
+	ppc64MovInt    ppc64MovKind = iota
+	ppc64MovRev                 // byte-reversed (lwbrx, ldbrx, ...)
+	ppc64MovSingle              // FMOVS: a single in memory, a double in F
+)
+
+// ppc64MovForm gives the access width, extension and addressing of a MOV
+// form. The U forms write the effective address back to the base register;
+// float forms move F registers.
+type ppc64MovForm struct {
+	bits   int
+	signed bool
+	update bool
+	float  bool
+	kind   ppc64MovKind
+}
+
+var ppc64MovForms = map[string]ppc64MovForm{
+	"MOVD":   {bits: 64, signed: true},
+	"MOVDU":  {bits: 64, signed: true, update: true},
+	"MOVW":   {bits: 32, signed: true},
+	"MOVWU":  {bits: 32, signed: true, update: true},
+	"MOVWZ":  {bits: 32},
+	"MOVWZU": {bits: 32, update: true},
+	"MOVH":   {bits: 16, signed: true},
+	"MOVHU":  {bits: 16, signed: true, update: true},
+	"MOVHZ":  {bits: 16},
+	"MOVHZU": {bits: 16, update: true},
+	"MOVB":   {bits: 8, signed: true},
+	"MOVBU":  {bits: 8, signed: true, update: true},
+	"MOVBZ":  {bits: 8},
+	"MOVBZU": {bits: 8, update: true},
+	"MOVDBR": {bits: 64, kind: ppc64MovRev},
+	"MOVWBR": {bits: 32, kind: ppc64MovRev},
+	"MOVHBR": {bits: 16, kind: ppc64MovRev},
+	"FMOVD":  {bits: 64, float: true},
+	"FMOVDU": {bits: 64, float: true, update: true},
+	"FMOVS":  {bits: 32, float: true, kind: ppc64MovSingle},
+	"FMOVSU": {bits: 32, float: true, update: true, kind: ppc64MovSingle},
+	"FMOVSX": {bits: 32, signed: true, float: true},
+	"FMOVSZ": {bits: 32, float: true},
+}
+
+func (c *ppc64Ctx) lowerData(op string, ins Instr) (ok bool, terminated bool, err error) {
+	f, found := ppc64MovForms[op]
+	if !found {
+		return false, false, nil
+	}
+	if len(ins.Args) != 2 {
+		return true, false, fmt.Errorf("ppc64 %s expects 2 operands: %q", op, ins.Raw)
+	}
+	src, dst := ins.Args[0], ins.Args[1]
+	isF := ppc64IsFRegOp
+	isVec := func(o Operand) bool { return o.Kind == OpReg && (ppc64IsVReg(o.Reg) || ppc64IsVSLow(o.Reg)) }
+	switch {
+	case isVec(src) || isVec(dst):
+		return true, false, fmt.Errorf("ppc64 %s cannot move vector registers: %q", op, ins.Raw)
+	case f.float:
+		if (!isF(src) && !isF(dst)) || ppc64IsRReg(src) || ppc64IsRReg(dst) {
+			return true, false, fmt.Errorf("ppc64 %s moves between F registers and memory: %q", op, ins.Raw)
+		}
+	case isF(src) || isF(dst):
+		return true, false, fmt.Errorf("ppc64 %s cannot move F registers (use MFVSRD/MTVSRD): %q", op, ins.Raw)
+	}
+	if f.kind == ppc64MovRev && src.Kind != OpMem && dst.Kind != OpMem {
+		return true, false, fmt.Errorf("ppc64 %s needs a memory operand: %q", op, ins.Raw)
+	}
+	if src.Kind != OpReg && dst.Kind != OpReg && (src.Kind != OpImm || dst.Kind != OpMem) {
+		return true, false, fmt.Errorf("ppc64 %s needs a register operand: %q", op, ins.Raw)
+	}
+
+	// The effective address of a memory operand is computed once, since
+	// the update forms write it back after the access.
+	var ea string
+	var mem *MemRef
+	for _, o := range []Operand{src, dst} {
+		if o.Kind == OpMem {
+			m := o.Mem
+			mem = &m
+			if ea, err = c.addrI64(m); err != nil {
+				return true, false, err
+			}
+		}
+	}
+	if f.update && (mem == nil || mem.Base == "R0") {
+		return true, false, fmt.Errorf("ppc64 %s needs a base register: %q", op, ins.Raw)
+	}
+
+	v, err := c.movSrc(src, f, ea)
+	if err != nil {
+		return true, false, fmt.Errorf("ppc64 %s: %v: %q", op, err, ins.Raw)
+	}
+	if err := c.movDst(dst, f, v, ea); err != nil {
+		return true, false, fmt.Errorf("ppc64 %s: %v: %q", op, err, ins.Raw)
+	}
+	if f.update {
+		return true, false, c.storeReg(mem.Base, ea)
+	}
+	return true, false, nil
+}
+
+// movSrc reads a MOV source as register bits. ea is the effective address
+// of a memory source.
+func (c *ppc64Ctx) movSrc(src Operand, f ppc64MovForm, ea string) (string, error) {
+	switch src.Kind {
+	case OpImm:
+		// Float immediates already hold double bits.
+		v := src.Imm
+		switch {
+		case f.bits == 64 || f.float:
+		case f.signed:
+			v = v << (64 - f.bits) >> (64 - f.bits)
+		default:
+			v = int64(uint64(v) << (64 - f.bits) >> (64 - f.bits))
+		}
+		return fmt.Sprintf("%d", v), nil
+	case OpReg:
+		var v string
+		var err error
+		if src.Reg == "CR" {
+			v = c.loadCR()
+		} else if v, err = c.loadReg(src.Reg); err != nil {
+			return "", err
+		}
+		if f.float {
+			return v, nil
+		}
+		return c.narrow(v, f.bits, f.signed), nil
+	case OpMem:
+		return c.loadAs(ea, f), nil
+	case OpFP:
+		v, err := c.evalFPValue64(src)
+		if err != nil {
+			return "", err
+		}
+		if f.float {
+			return v, nil
+		}
+		return c.narrow(v, f.bits, f.signed), nil
+	case OpFPAddr:
+		return c.evalFPAddr64(src)
+	case OpSym:
+		if ppc64IsAddrSym(src) {
+			return c.symAddr(src.Sym)
+		}
+		s := strings.TrimSpace(src.Sym)
+		if !strings.HasSuffix(s, "(SB)") {
+			break
+		}
+		p, err := c.ptrFromSB(s)
+		if err != nil {
+			return "", err
+		}
+		return c.loadAs(c.emit("ptrtoint ptr %s to i64", p), f), nil
+	}
+	return "", fmt.Errorf("unsupported source %s", src.String())
+}
+
+// movDst writes v to a MOV destination. ea is the effective address of a
+// memory destination.
+func (c *ppc64Ctx) movDst(dst Operand, f ppc64MovForm, v, ea string) error {
+	switch dst.Kind {
+	case OpReg:
+		if dst.Reg == "CR" {
+			c.storeCR(v, 0xff)
+			return nil
+		}
+		return c.storeReg(dst.Reg, v)
+	case OpMem:
+		c.storeAs(ea, f, v)
+		return nil
+	case OpFP:
+		return c.storeFPResult64(dst.FPOffset, v)
+	case OpSym:
+		s := strings.TrimSpace(dst.Sym)
+		if strings.HasPrefix(s, "$") || !strings.HasSuffix(s, "(SB)") {
+			break
+		}
+		p, err := c.ptrFromSB(s)
+		if err != nil {
+			return err
+		}
+		c.storeAs(c.emit("ptrtoint ptr %s to i64", p), f, v)
+		return nil
+	}
+	return fmt.Errorf("unsupported destination %s", dst.String())
+}
+
+// loadAs performs a MOV form's load from the i64 address addr.
+func (c *ppc64Ctx) loadAs(addr string, f ppc64MovForm) string {
+	p := c.emit("inttoptr i64 %s to ptr", addr)
+	if f.kind == ppc64MovSingle {
+		v := c.emit("load float, ptr %s", p)
+		d := c.emit("fpext float %s to double", v)
+		return c.emit("bitcast double %s to i64", d)
+	}
+	v := c.emit("load i%d, ptr %s, align 1", f.bits, p)
+	if f.kind == ppc64MovRev && f.bits > 8 {
+		v = c.emit("call i%d @llvm.bswap.i%d(i%d %s)", f.bits, f.bits, f.bits, v)
+	}
+	if f.bits == 64 {
+		return v
+	}
+	return c.extend(v, f.bits, f.signed)
+}
+
+// storeAs performs a MOV form's store of the register bits v to addr.
+func (c *ppc64Ctx) storeAs(addr string, f ppc64MovForm, v string) {
+	p := c.emit("inttoptr i64 %s to ptr", addr)
+	if f.kind == ppc64MovSingle {
+		d := c.emit("bitcast i64 %s to double", v)
+		s := c.emit("fptrunc double %s to float", d)
+		fmt.Fprintf(c.b, "  store float %s, ptr %s\n", s, p)
+		return
+	}
+	if f.bits < 64 {
+		v = c.emit("trunc i64 %s to i%d", v, f.bits)
+	}
+	if f.kind == ppc64MovRev && f.bits > 8 {
+		v = c.emit("call i%d @llvm.bswap.i%d(i%d %s)", f.bits, f.bits, f.bits, v)
+	}
+	fmt.Fprintf(c.b, "  store i%d %s, ptr %s, align 1\n", f.bits, v, p)
+}
@@ -0,0 +1,220 @@
+package plan9asm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// loadF reads an F register as a double.
+func (c *ppc64Ctx) loadF(r Reg) (string, error) {
+	v, err := c.loadReg(r)
+	if err != nil {
+		return "", err
+	}
+	return c.emit("bitcast i64 %s to double", v), nil
+}
+
+func (c *ppc64Ctx) storeF(r Reg, v string) error {
+	return c.storeReg(r, c.emit("bitcast double %s to i64", v))
+}
+
+// roundSingle rounds a double to single precision, keeping it widened as
+// the single-precision instructions leave their results.
+func (c *ppc64Ctx) roundSingle(v string) string {
+	s := c.emit("fptrunc double %s to float", v)
+	return c.emit("fpext float %s to double", s)
+}
+
+// ppc64CheckFRegs reports whether ins has exactly n operands, all of them
+// F registers.
+func ppc64CheckFRegs(ins Instr, n int) bool {
+	if len(ins.Args) != n {
+		return false
+	}
+	for _, a := range ins.Args {
+		if !ppc64IsFRegOp(a) {
+			return false
+		}
+	}
+	return true
+}
+
+// ppc64FPUnary maps the "OP fb, ft" operations to the intrinsic (or, with
+// a leading '-', instruction) computing them on a double.
+var ppc64FPUnary = map[string]string{
+	"FABS": "fabs", "FSQRT": "sqrt", "FSQRTS": "sqrt",
+	"FRIM": "floor", "FRIP": "ceil", "FRIZ": "trunc", "FRIN": "round",
+	"FNEG": "-fneg", "FNABS": "-fnabs", "FRSP": "-frsp",
+	"FRES": "-fres", "FRSQRTE": "-frsqrte",
+}
+
+// ppc64FPToInt gives the result width, signedness and rounding of the
+// FCTI conversions; the Z forms truncate, the others round to nearest
+// even, the mode Go leaves FPSCR in. Results saturate, NaN giving 0.
+var ppc64FPToInt = map[string]struct {
+	bits   int
+	signed bool
+	round  string
+}{
+	"FCTID": {64, true, "roundeven"}, "FCTIDZ": {64, true, ""},
+	"FCTIW": {32, true, "roundeven"}, "FCTIWZ": {32, true, ""},
+	"FCTIDUZ": {64, false, ""}, "FCTIWUZ": {32, false, ""},
+}
+
+func (c *ppc64Ctx) lowerFP(op string, ins Instr) (ok bool, terminated bool, err error) {
+	switch op {
+	case "FADD", "FSUB", "FMUL", "FDIV", "FADDS", "FSUBS", "FMULS", "FDIVS", "FCPSGN":
+		// "OP fb, fa, ft" computes ft = fa OP fb; "OP fb, ft" reads ft.
+		if !ppc64CheckFRegs(ins, 3) && !ppc64CheckFRegs(ins, 2) {
+			return true, false, fmt.Errorf("ppc64 %s expects F registers: %q", op, ins.Raw)
+		}
+		b, err := c.loadF(ins.Args[0].Reg)
+		if err != nil {
+			return true, false, err
+		}
+		a, err := c.loadF(ins.Args[len(ins.Args)-2].Reg)
+		if err != nil {
+			return true, false, err
+		}
+		if len(ins.Args) == 2 {
+			if a, err = c.loadF(ins.Args[1].Reg); err != nil {
+				return true, false, err
+			}
+		}
+		var v string
+		if op == "FCPSGN" {
+			// The sign comes from fa, the magnitude from fb.
+			v = c.emit("call double @llvm.copysign.f64(double %s, double %s)", b, a)
+		} else {
+			v = c.emit("f%s double %s, %s", strings.ToLower(op[1:4]), a, b)
+			if strings.HasSuffix(op, "S") {
+				v = c.roundSingle(v)
+			}
+		}
+		return true, false, c.storeF(ins.Args[len(ins.Args)-1].Reg, v)
+
+	case "FMADD", "FMSUB", "FNMADD", "FNMSUB", "FMADDS", "FMSUBS", "FNMADDS", "FNMSUBS":
+		// "OP fa, fb, fc, ft": ft = ±(fa*fc ± fb).
+		if !ppc64CheckFRegs(ins, 4) {
+			return true, false, fmt.Errorf("ppc64 %s expects four F registers: %q", op, ins.Raw)
+		}
+		var in [3]string
+		for i := range in {
+			if in[i], err = c.loadF(ins.Args[i].Reg); err != nil {
+				return true, false, err
+			}
+		}
+		base := strings.TrimSuffix(op, "S")
+		if base == "FMSUB" || base == "FNMSUB" {
+			in[1] = c.emit("fneg double %s", in[1])
+		}
+		v := c.emit("call double @llvm.fma.f64(double %s, double %s, double %s)", in[0], in[2], in[1])
+		if base == "FNMADD" || base == "FNMSUB" {
+			v = c.emit("fneg double %s", v)
+		}
+		if op != base {
+			v = c.roundSingle(v)
+		}
+		return true, false, c.storeF(ins.Args[3].Reg, v)
+
+	case "FSEL":
+		// "FSEL fa, fb, fc, ft" sets ft = fa >= 0 ? fc : fb; a NaN fa
+		// selects fb.
+		if !ppc64CheckFRegs(ins, 4) {
+			return true, false, fmt.Errorf("ppc64 FSEL expects four F registers: %q", ins.Raw)
+		}
+		a, err := c.loadF(ins.Args[0].Reg)
+		if err != nil {
+			return true, false, err
+		}
+		b, err := c.loadReg(ins.Args[1].Reg)
+		if err != nil {
+			return true, false, err
+		}
+		fc, err := c.loadReg(ins.Args[2].Reg)
+		if err != nil {
+			return true, false, err
+		}
+		ge := c.emit("fcmp oge double %s, 0.0", a)
+		return true, false, c.storeReg(ins.Args[3].Reg, c.emit("select i1 %s, i64 %s, i64 %s", ge, fc, b))
+
+	case "FCFID", "FCFIDU", "FCFIDS", "FCFIDUS":
+		// The source holds a doubleword integer.
+		if !ppc64CheckFRegs(ins, 2) {
+			return true, false, fmt.Errorf("ppc64 %s expects F, F: %q", op, ins.Raw)
+		}
+		src, err := c.loadReg(ins.Args[0].Reg)
+		if err != nil {
+			return true, false, err
+		}
+		conv := "sitofp"
+		if strings.Contains(op, "U") {
+			conv = "uitofp"
+		}
+		var v string
+		if strings.HasSuffix(op, "S") {
+			s := c.emit("%s i64 %s to float", conv, src)
+			v = c.emit("fpext float %s to double", s)
+		} else {
+			v = c.emit("%s i64 %s to double", conv, src)
+		}
+		return true, false, c.storeF(ins.Args[1].Reg, v)
+	}
+
+	if conv, found := ppc64FPToInt[op]; found {
+		if !ppc64CheckFRegs(ins, 2) {
+			return true, false, fmt.Errorf("ppc64 %s expects F, F: %q", op, ins.Raw)
+		}
+		v, err := c.loadF(ins.Args[0].Reg)
+		if err != nil {
+			return true, false, err
+		}
+		if conv.round != "" {
+			v = c.emit("call double @llvm.%s.f64(double %s)", conv.round, v)
+		}
+		fn := "fptoui"
+		if conv.signed {
+			fn = "fptosi"
+		}
+		ity := fmt.Sprintf("i%d", conv.bits)
+		r := c.emit("call %s @llvm.%s.sat.%s.f64(double %s)", ity, fn, ity, v)
+		if conv.bits == 32 {
+			r = c.extend(r, 32, false)
+		}
+		return true, false, c.storeReg(ins.Args[1].Reg, r)
+	}
+
+	fn, found := ppc64FPUnary[op]
+	if !found {
+		return false, false, nil
+	}
+	if !ppc64CheckFRegs(ins, 2) {
+		return true, false, fmt.Errorf("ppc64 %s expects F, F: %q", op, ins.Raw)
+	}
+	a, err := c.loadF(ins.Args[0].Reg)
+	if err != nil {
+		return true, false, err
+	}
+	var v string
+	switch fn {
+	case "-fneg":
+		v = c.emit("fneg double %s", a)
+	case "-fnabs":
+		v = c.emit("call double @llvm.fabs.f64(double %s)", a)
+		v = c.emit("fneg double %s", v)
+	case "-frsp":
+		v = c.roundSingle(a)
+	case "-fres":
+		// The estimates are computed exactly.
+		v = c.emit("fdiv double 1.0, %s", a)
+	case "-frsqrte":
+		v = c.emit("call double @llvm.sqrt.f64(double %s)", a)
+		v = c.emit("fdiv double 1.0, %s", v)
+	default:
+		v = c.emit("call double @llvm.%s.f64(double %s)", fn, a)
+	}
+	if op == "FSQRTS" {
+		v = c.roundSingle(v)
+	}
+	return true, false, c.storeF(ins.Args[1].Reg, v)
+}
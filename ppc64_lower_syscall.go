@@ -0,0 +1,97 @@
+package plan9asm
+
+import "fmt"
+
+func (c *ppc64Ctx) lowerSyscall(op string, ins Instr) (ok bool, terminated bool, err error) {
+	switch op {
+	case "SYSCALL":
+		// Linux takes the trap number in R0 ("SYSCALL $n" sets it) and
+		// the arguments in R3-R8. It returns the result in R3, or a
+		// positive errno there with CR0.SO set.
+		s := c.cfg.syscallSite(ArchPPC64, c.b, c.newTmp)
+		s.conv = syscallConvBSD
+		switch {
+		case len(ins.Args) == 0:
+			s.Num, err = c.loadReg("R0")
+		case len(ins.Args) == 1 && ins.Args[0].Kind == OpImm:
+			s.Num = fmt.Sprintf("%d", ins.Args[0].Imm)
+		case len(ins.Args) == 1 && ppc64IsRReg(ins.Args[0]):
+			s.Num, err = c.loadReg(ins.Args[0].Reg)
+		default:
+			return true, false, fmt.Errorf("ppc64 SYSCALL expects an optional $n or register: %q", ins.Raw)
+		}
+		if err != nil {
+			return true, false, err
+		}
+		for _, r := range []Reg{"R3", "R4", "R5", "R6", "R7", "R8"} {
+			v, err := c.loadReg(r)
+			if err != nil {
+				return true, false, err
+			}
+			s.Args = append(s.Args, v)
+		}
+		res, err := c.cfg.syscallStrategy().Lower(s)
+		if err != nil {
+			return true, false, err
+		}
+		if err := c.storeReg("R3", res.ret(s)); err != nil {
+			return true, false, err
+		}
+		if err := c.storeReg("R4", res.R2); err != nil {
+			return true, false, err
+		}
+		return true, false, c.storeReg("CR0", c.emit("select i1 %s, i64 %d, i64 0", res.IsErr, ppc64CRSO))
+
+	case "TW", "TD":
+		return c.lowerTrap(op, ins)
+
+	case "UNDEF":
+		c.b.WriteString("  call void @llvm.trap()\n  unreachable\n")
+		return true, true, nil
+	}
+	return false, false, nil
+}
+
+// lowerTrap lowers "TW $to, ra, rb|$imm", which traps when a comparison
+// of ra with rb selected by TO holds: 16 signed less, 8 signed greater,
+// 4 equal, 2 unsigned less, 1 unsigned greater. TW $31 always traps and
+// ends the block.
+func (c *ppc64Ctx) lowerTrap(op string, ins Instr) (bool, bool, error) {
+	if len(ins.Args) != 3 || ins.Args[0].Kind != OpImm || !ppc64IsRReg(ins.Args[1]) ||
+		(!ppc64IsRReg(ins.Args[2]) && ins.Args[2].Kind != OpImm) {
+		return true, false, fmt.Errorf("ppc64 %s expects $to, reg, reg|$imm: %q", op, ins.Raw)
+	}
+	to := ins.Args[0].Imm & 31
+	if to == 31 {
+		c.b.WriteString("  call void @llvm.trap()\n  unreachable\n")
+		return true, true, nil
+	}
+	a, err := c.loadReg(ins.Args[1].Reg)
+	if err != nil {
+		return true, false, err
+	}
+	b, err := c.eval64(ins.Args[2])
+	if err != nil {
+		return true, false, err
+	}
+	ty := "i64"
+	if op == "TW" {
+		ty = "i32"
+		a, b = c.trunc32(a), c.trunc32(b)
+	}
+	cond := "false"
+	for _, t := range []struct {
+		bit  int64
+		pred string
+	}{{16, "slt"}, {8, "sgt"}, {4, "eq"}, {2, "ult"}, {1, "ugt"}} {
+		if to&t.bit != 0 {
+			cmp := c.emit("icmp %s %s %s, %s", t.pred, ty, a, b)
+			cond = c.emit("or i1 %s, %s", cond, cmp)
+		}
+	}
+	id := c.newTmp()
+	fmt.Fprintf(c.b, "  br i1 %s, label %%trap_%s, label %%trap_cont_%s\n", cond, id, id)
+	fmt.Fprintf(c.b, "\ntrap_%s:\n  call void @llvm.trap()\n  unreachable\n", id)
+	fmt.Fprintf(c.b, "\ntrap_cont_%s:\n", id)
+	return true, false, nil
+}
@@ -0,0 +1,675 @@
+package plan9asm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ppc64VecLane is a vector shape: n lanes of bits each.
+type ppc64VecLane struct{ bits, n int }
+
+func (l ppc64VecLane) vecType() string {
+	return fmt.Sprintf("<%d x i%d>", l.n, l.bits)
+}
+
+func (l ppc64VecLane) intrinsicSuffix() string {
+	return fmt.Sprintf("v%di%d", l.n, l.bits)
+}
+
+// ppc64VecLanes lists the shapes the lowering uses: the byte, halfword,
+// word and doubleword lanes of a vector register, and the same lanes of a
+// doubleword for the POPCNT and BR instructions.
+var ppc64VecLanes = []ppc64VecLane{
+	{8, 16}, {16, 8}, {32, 4}, {64, 2},
+	{8, 8}, {16, 4}, {32, 2}, {64, 1},
+}
+
+// ppc64LaneBits maps the lane letter of a vector mnemonic to its width.
+var ppc64LaneBits = map[byte]int{'B': 8, 'H': 16, 'W': 32, 'D': 64}
+
+func ppc64Lane(letter byte) (ppc64VecLane, bool) {
+	bits, ok := ppc64LaneBits[letter]
+	return ppc64VecLane{bits, 128 / bits}, ok
+}
+
+// ppc64VecLogic maps the bitwise vector operations, in their V and XXL
+// spellings, to the function of (a, b) they compute.
+var ppc64VecLogic = map[string]string{
+	"VAND": "and", "VANDC": "andc", "VOR": "or", "VORC": "orc", "VXOR": "xor",
+	"VNOR": "nor", "VNAND": "nand", "VEQV": "eqv",
+	"XXLAND": "and", "XXLANDC": "andc", "XXLOR": "or", "XXLORC": "orc", "XXLXOR": "xor",
+	"XXLNOR": "nor", "XXLNAND": "nand", "XXLEQV": "eqv",
+}
+
+// lowerVec lowers the VMX and VSX instructions Go's ppc64 assembly uses:
+// the vector loads and stores, bitwise, lane and quadword arithmetic,
+// compares, splats, shifts, permutes (XXPERMDI, VSLDOI, VPERM, VBPERMQ)
+// and the moves between R, F and vector registers. The crypto and
+// polynomial instructions (VCIPHER, VSHASIGMA, VPMSUMD, VPERMXOR, ...)
+// are not lowered.
+func (c *ppc64Ctx) lowerVec(op string, ins Instr) (ok bool, terminated bool, err error) {
+	if ok, err := c.lowerVecMem(op, ins); ok {
+		return true, false, err
+	}
+	if ok, err := c.lowerVecMove(op, ins); ok {
+		return true, false, err
+	}
+	if kind, found := ppc64VecLogic[op]; found {
+		return true, false, c.vecBinary(op, ins, func(a, b string) string {
+			switch kind {
+			case "andc", "orc":
+				nb := c.emit("xor i128 %s, -1", b)
+				return c.emit("%s i128 %s, %s", kind[:len(kind)-1], a, nb)
+			case "nor", "nand", "eqv":
+				inner := map[string]string{"nor": "or", "nand": "and", "eqv": "xor"}[kind]
+				v := c.emit("%s i128 %s, %s", inner, a, b)
+				return c.emit("xor i128 %s, -1", v)
+			}
+			return c.emit("%s i128 %s, %s", kind, a, b)
+		})
+	}
+
+	switch {
+	case op == "VSEL" || op == "XXSEL":
+		// "VSEL va, vb, vc, vt" takes the bits of vb where vc is set and
+		// those of va elsewhere.
+		regs, err := c.vecRegs(op, ins, 4)
+		if err != nil {
+			return true, false, err
+		}
+		in, err := c.loadVecs(regs[:3])
+		if err != nil {
+			return true, false, err
+		}
+		b := c.emit("and i128 %s, %s", in[1], in[2])
+		nc := c.emit("xor i128 %s, -1", in[2])
+		a := c.emit("and i128 %s, %s", in[0], nc)
+		return true, false, c.storeVec(regs[3], c.emit("or i128 %s, %s", a, b))
+
+	case op == "XXPERMDI":
+		// "XXPERMDI xa, xb, $dm, xt" takes doubleword dm>>1 of xa and
+		// doubleword dm&1 of xb.
+		if len(ins.Args) != 4 || ins.Args[2].Kind != OpImm || !ppc64IsVecOp(ins.Args[0]) || !ppc64IsVecOp(ins.Args[1]) || !ppc64IsVecOp(ins.Args[3]) {
+			return true, false, fmt.Errorf("ppc64 XXPERMDI expects VS, VS, $dm, VS: %q", ins.Raw)
+		}
+		dm := ins.Args[2].Imm
+		a, err := c.loadVec(ins.Args[0].Reg)
+		if err != nil {
+			return true, false, err
+		}
+		b, err := c.loadVec(ins.Args[1].Reg)
+		if err != nil {
+			return true, false, err
+		}
+		ahi, alo := c.split128(a)
+		bhi, blo := c.split128(b)
+		hi, lo := ahi, bhi
+		if dm&2 != 0 {
+			hi = alo
+		}
+		if dm&1 != 0 {
+			lo = blo
+		}
+		return true, false, c.storeVec(ins.Args[3].Reg, c.join128(hi, lo))
+
+	case op == "VSLDOI":
+		// "VSLDOI $sh, va, vb, vt" takes bytes sh through sh+15 of va:vb.
+		if len(ins.Args) != 4 || ins.Args[0].Kind != OpImm || ins.Args[0].Imm < 0 || ins.Args[0].Imm > 15 {
+			return true, false, fmt.Errorf("ppc64 VSLDOI expects $sh, V, V, V: %q", ins.Raw)
+		}
+		regs, err := c.vecRegs(op, Instr{Raw: ins.Raw, Args: ins.Args[1:]}, 3)
+		if err != nil {
+			return true, false, err
+		}
+		in, err := c.loadVecs(regs[:2])
+		if err != nil {
+			return true, false, err
+		}
+		v := c.emit("call i128 @llvm.fshl.i128(i128 %s, i128 %s, i128 %d)", in[0], in[1], 8*ins.Args[0].Imm)
+		return true, false, c.storeVec(regs[2], v)
+
+	case op == "VSPLTISB" || op == "VSPLTISH" || op == "VSPLTISW" || op == "XXSPLTIB":
+		// VSPLTIS splats a 5-bit signed immediate, XXSPLTIB an 8-bit one.
+		if len(ins.Args) != 2 || ins.Args[0].Kind != OpImm || !ppc64IsVecOp(ins.Args[1]) {
+			return true, false, fmt.Errorf("ppc64 %s expects $imm, V: %q", op, ins.Raw)
+		}
+		lane, _ := ppc64Lane(op[len(op)-1])
+		imm := ins.Args[0].Imm << 59 >> 59
+		if op == "XXSPLTIB" {
+			imm = ins.Args[0].Imm & 0xff
+		}
+		v := c.emit("bitcast %s %s to i128", lane.vecType(), c.splat(lane, fmt.Sprintf("%d", imm)))
+		return true, false, c.storeVec(ins.Args[1].Reg, v)
+
+	case op == "VSPLTB" || op == "VSPLTH" || op == "VSPLTW" || op == "XXSPLTW":
+		// "VSPLTW $i, vb, vt" splats element i of vb.
+		lane, _ := ppc64Lane(op[len(op)-1])
+		if len(ins.Args) != 3 || ins.Args[0].Kind != OpImm || ins.Args[0].Imm < 0 || ins.Args[0].Imm >= int64(lane.n) {
+			return true, false, fmt.Errorf("ppc64 %s expects $i, V, V: %q", op, ins.Raw)
+		}
+		regs, err := c.vecRegs(op, Instr{Raw: ins.Raw, Args: ins.Args[1:]}, 2)
+		if err != nil {
+			return true, false, err
+		}
+		src, err := c.loadVec(regs[0])
+		if err != nil {
+			return true, false, err
+		}
+		vec := c.emit("bitcast i128 %s to %s", src, lane.vecType())
+		e := c.emit("extractelement %s %s, i32 %d", lane.vecType(), vec, c.vecElem(lane, ins.Args[0].Imm))
+		v := c.emit("bitcast %s %s to i128", lane.vecType(), c.splat(lane, e))
+		return true, false, c.storeVec(regs[1], v)
+	}
+
+	if ok, err := c.lowerVecQuad(op, ins); ok {
+		return true, false, err
+	}
+	if ok, err := c.lowerVecPerm(op, ins); ok {
+		return true, false, err
+	}
+	if rest, found := strings.CutPrefix(op, "VADDU"); found {
+		return c.lowerVecAddSub("add", op, rest, ins)
+	}
+	if rest, found := strings.CutPrefix(op, "VSUBU"); found {
+		return c.lowerVecAddSub("sub", op, rest, ins)
+	}
+	if strings.HasPrefix(op, "VCMP") {
+		return c.lowerVecCmp(op, ins)
+	}
+	for prefix, fn := range map[string]string{"VPOPCNT": "ctpop", "VCLZ": "ctlz", "VCTZ": "cttz", "XXBR": "bswap"} {
+		rest, found := strings.CutPrefix(op, prefix)
+		if !found || len(rest) != 1 {
+			continue
+		}
+		if rest == "Q" && fn == "bswap" {
+			return true, false, c.vecUnary(op, ins, func(v string) string {
+				return c.emit("call i128 @llvm.bswap.i128(i128 %s)", v)
+			})
+		}
+		lane, ok := ppc64Lane(rest[0])
+		if !ok || (fn == "bswap" && lane.bits == 8) {
+			return false, false, nil
+		}
+		return true, false, c.vecUnary(op, ins, func(v string) string {
+			vec := c.emit("bitcast i128 %s to %s", v, lane.vecType())
+			zeroArg := ""
+			if fn == "ctlz" || fn == "cttz" {
+				zeroArg = ", i1 false"
+			}
+			r := c.emit("call %s @llvm.%s.%s(%s %s%s)", lane.vecType(), fn, lane.intrinsicSuffix(), lane.vecType(), vec, zeroArg)
+			return c.emit("bitcast %s %s to i128", lane.vecType(), r)
+		})
+	}
+	return false, false, nil
+}
+
+// vecElem converts the ISA element number i, counted from the most
+// significant end, to the LLVM lane index of the bitcast vector.
+func (c *ppc64Ctx) vecElem(lane ppc64VecLane, i int64) int64 {
+	return int64(lane.n) - 1 - i
+}
+
+// splat builds a vector with every lane set to the scalar v.
+func (c *ppc64Ctx) splat(lane ppc64VecLane, v string) string {
+	ty := lane.vecType()
+	one := c.emit("insertelement %s poison, i%d %s, i32 0", ty, lane.bits, v)
+	return c.emit("shufflevector %s %s, %s poison, <%d x i32> zeroinitializer", ty, one, ty, lane.n)
+}
+
+// vecRegs checks that ins has n vector register operands.
+func (c *ppc64Ctx) vecRegs(op string, ins Instr, n int) ([]Reg, error) {
+	if len(ins.Args) != n {
+		return nil, fmt.Errorf("ppc64 %s expects %d vector registers: %q", op, n, ins.Raw)
+	}
+	regs := make([]Reg, n)
+	for i, a := range ins.Args {
+		if !ppc64IsVecOp(a) {
+			return nil, fmt.Errorf("ppc64 %s expects vector registers: %q", op, ins.Raw)
+		}
+		regs[i] = a.Reg
+	}
+	return regs, nil
+}
+
+func (c *ppc64Ctx) loadVecs(regs []Reg) ([]string, error) {
+	out := make([]string, len(regs))
+	for i, r := range regs {
+		v, err := c.loadVec(r)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = v
+	}
+	return out, nil
+}
+
+// vecBinary lowers "OP va, vb, vt" as vt = f(va, vb).
+func (c *ppc64Ctx) vecBinary(op string, ins Instr, f func(a, b string) string) error {
+	regs, err := c.vecRegs(op, ins, 3)
+	if err != nil {
+		return err
+	}
+	in, err := c.loadVecs(regs[:2])
+	if err != nil {
+		return err
+	}
+	return c.storeVec(regs[2], f(in[0], in[1]))
+}
+
+// vecUnary lowers "OP vb, vt" as vt = f(vb).
+func (c *ppc64Ctx) vecUnary(op string, ins Instr, f func(v string) string) error {
+	regs, err := c.vecRegs(op, ins, 2)
+	if err != nil {
+		return err
+	}
+	v, err := c.loadVec(regs[0])
+	if err != nil {
+		return err
+	}
+	return c.storeVec(regs[1], f(v))
+}
+
+// lowerVecAddSub lowers the modular VADDU<lane>M and VSUBU<lane>M, with
+// Q for the whole quadword; "VSUBUWM va, vb, vt" computes va - vb.
+func (c *ppc64Ctx) lowerVecAddSub(kind, op, rest string, ins Instr) (bool, bool, error) {
+	if len(rest) != 2 || rest[1] != 'M' {
+		return false, false, nil
+	}
+	if rest[0] == 'Q' {
+		return true, false, c.vecBinary(op, ins, func(a, b string) string {
+			return c.emit("%s i128 %s, %s", kind, a, b)
+		})
+	}
+	lane, ok := ppc64Lane(rest[0])
+	if !ok {
+		return false, false, nil
+	}
+	return true, false, c.vecBinary(op, ins, func(a, b string) string {
+		va := c.emit("bitcast i128 %s to %s", a, lane.vecType())
+		vb := c.emit("bitcast i128 %s to %s", b, lane.vecType())
+		r := c.emit("%s %s %s, %s", kind, lane.vecType(), va, vb)
+		return c.emit("bitcast %s %s to i128", lane.vecType(), r)
+	})
+}
+
+// lowerVecCmp lowers VCMPEQU<lane>, VCMPGTU<lane> and VCMPGTS<lane>,
+// which set each lane of vt to all ones where the compare of va with vb
+// holds. The CC forms also set CR6 to LT when every lane holds and to EQ
+// when none does.
+func (c *ppc64Ctx) lowerVecCmp(op string, ins Instr) (bool, bool, error) {
+	base, cc := strings.CutSuffix(op, "CC")
+	preds := map[string]string{"VCMPEQU": "eq", "VCMPGTU": "ugt", "VCMPGTS": "sgt"}
+	if len(base) != len("VCMPEQU")+1 {
+		return false, false, nil
+	}
+	pred, found := preds[base[:len(base)-1]]
+	lane, ok := ppc64Lane(base[len(base)-1])
+	if !found || !ok {
+		return false, false, nil
+	}
+	var mask string
+	err := c.vecBinary(op, ins, func(a, b string) string {
+		va := c.emit("bitcast i128 %s to %s", a, lane.vecType())
+		vb := c.emit("bitcast i128 %s to %s", b, lane.vecType())
+		m := c.emit("icmp %s %s %s, %s", pred, lane.vecType(), va, vb)
+		mask = c.emit("bitcast <%d x i1> %s to i%d", lane.n, m, lane.n)
+		r := c.emit("sext <%d x i1> %s to %s", lane.n, m, lane.vecType())
+		return c.emit("bitcast %s %s to i128", lane.vecType(), r)
+	})
+	if err != nil || !cc {
+		return true, false, err
+	}
+	all := c.emit("icmp eq i%d %s, -1", lane.n, mask)
+	none := c.emit("icmp eq i%d %s, 0", lane.n, mask)
+	lt := c.emit("select i1 %s, i64 %d, i64 0", all, ppc64CRLT)
+	eq := c.emit("select i1 %s, i64 %d, i64 0", none, ppc64CREQ)
+	return true, false, c.storeReg("CR6", c.emit("or i64 %s, %s", lt, eq))
+}
+
+// lowerVecMem lowers the vector loads and stores. The element-ordered
+// forms (LXVD2X, LXVW4X, LXVH8X, LXVB16X) place element 0, which is at
+// the lowest address, in the most significant bits, loading each element
+// little-endian; LXV and LVX load the quadword little-endian, LVX from
+// the 16-byte aligned address. The stores are their inverses.
+func (c *ppc64Ctx) lowerVecMem(op string, ins Instr) (bool, error) {
+	loads := map[string]int{"LXVD2X": 64, "LXVW4X": 32, "LXVH8X": 16, "LXVB16X": 8, "LXV": 128, "LVX": 128, "LXVDSX": 0}
+	stores := map[string]int{"STXVD2X": 64, "STXVW4X": 32, "STXVH8X": 16, "STXVB16X": 8, "STXV": 128, "STVX": 128}
+	switch op {
+	case "LXVL", "LXVLL", "STXVL", "STXVLL":
+		return true, c.lowerVecLen(op, ins)
+	}
+	elem, isLoad := loads[op]
+	if !isLoad {
+		var isStore bool
+		if elem, isStore = stores[op]; !isStore {
+			return false, nil
+		}
+	}
+	memIdx, regIdx := 0, 1
+	if !isLoad {
+		memIdx, regIdx = 1, 0
+	}
+	if len(ins.Args) != 2 || ins.Args[memIdx].Kind != OpMem || !ppc64IsVecOp(ins.Args[regIdx]) {
+		return true, fmt.Errorf("ppc64 %s expects a memory operand and a vector register: %q", op, ins.Raw)
+	}
+	ea, err := c.addrI64(ins.Args[memIdx].Mem)
+	if err != nil {
+		return true, err
+	}
+	if op == "LVX" || op == "STVX" {
+		ea = c.emit("and i64 %s, -16", ea)
+	}
+	r := ins.Args[regIdx].Reg
+	at := func(off int) string {
+		p := c.emit("inttoptr i64 %s to ptr", ea)
+		if off == 0 {
+			return p
+		}
+		return c.emit("getelementptr i8, ptr %s, i64 %d", p, off)
+	}
+
+	if isLoad {
+		var v string
+		switch elem {
+		case 0:
+			d := c.emit("load i64, ptr %s, align 1", at(0))
+			v = c.join128(d, d)
+		case 128:
+			v = c.emit("load i128, ptr %s, align 1", at(0))
+		default:
+			v = "0"
+			for i := 0; i < 128/elem; i++ {
+				e := c.emit("load i%d, ptr %s, align 1", elem, at(i*elem/8))
+				e = c.emit("zext i%d %s to i128", elem, e)
+				e = c.emit("shl i128 %s, %d", e, 128-elem*(i+1))
+				v = c.emit("or i128 %s, %s", v, e)
+			}
+		}
+		return true, c.storeVec(r, v)
+	}
+
+	v, err := c.loadVec(r)
+	if err != nil {
+		return true, err
+	}
+	if elem == 128 {
+		fmt.Fprintf(c.b, "  store i128 %s, ptr %s, align 1\n", v, at(0))
+		return true, nil
+	}
+	for i := 0; i < 128/elem; i++ {
+		e := c.emit("lshr i128 %s, %d", v, 128-elem*(i+1))
+		e = c.emit("trunc i128 %s to i%d", e, elem)
+		fmt.Fprintf(c.b, "  store i%d %s, ptr %s, align 1\n", elem, e, at(i*elem/8))
+	}
+	return true, nil
+}
+
+// lowerVecLen lowers the variable-length accesses "LXVL ra, rb, vt" and
+// "STXVL vs, ra, rb", which move rb>>56 bytes (at most 16) at ra. LXVL
+// zero-fills the rest of vt, keeping the little-endian layout of LXV; the
+// LL forms are left-justified, with the byte at ra most significant. The
+// bytes go through the %vlbuf scratch buffer.
+func (c *ppc64Ctx) lowerVecLen(op string, ins Instr) error {
+	load := strings.HasPrefix(op, "L")
+	ra, rb, vr := 0, 1, 2
+	if !load {
+		vr, ra, rb = 0, 1, 2
+	}
+	if len(ins.Args) != 3 || !ppc64IsRReg(ins.Args[ra]) || !ppc64IsRReg(ins.Args[rb]) || !ppc64IsVecOp(ins.Args[vr]) {
+		return fmt.Errorf("ppc64 %s expects its R and vector register operands: %q", op, ins.Raw)
+	}
+	addr, err := c.loadReg(ins.Args[ra].Reg)
+	if err != nil {
+		return err
+	}
+	n, err := c.loadReg(ins.Args[rb].Reg)
+	if err != nil {
+		return err
+	}
+	n = c.emit("lshr i64 %s, 56", n)
+	big := c.emit("icmp ugt i64 %s, 16", n)
+	n = c.emit("select i1 %s, i64 16, i64 %s", big, n)
+	p := c.emit("inttoptr i64 %s to ptr", addr)
+	leftJustified := strings.HasSuffix(op, "LL")
+
+	if load {
+		fmt.Fprintf(c.b, "  store i128 0, ptr %%vlbuf\n")
+		fmt.Fprintf(c.b, "  call void @llvm.memcpy.p0.p0.i64(ptr %%vlbuf, ptr %s, i64 %s, i1 false)\n", p, n)
+		v := c.emit("load i128, ptr %%vlbuf")
+		if leftJustified {
+			v = c.emit("call i128 @llvm.bswap.i128(i128 %s)", v)
+		}
+		return c.storeVec(ins.Args[vr].Reg, v)
+	}
+	v, err := c.loadVec(ins.Args[vr].Reg)
+	if err != nil {
+		return err
+	}
+	if leftJustified {
+		v = c.emit("call i128 @llvm.bswap.i128(i128 %s)", v)
+	}
+	fmt.Fprintf(c.b, "  store i128 %s, ptr %%vlbuf\n", v)
+	fmt.Fprintf(c.b, "  call void @llvm.memcpy.p0.p0.i64(ptr %s, ptr %%vlbuf, i64 %s, i1 false)\n", p, n)
+	return nil
+}
+
+// lowerVecMove lowers the moves between R registers and doublewords or
+// words of vector registers. MFVSRD and MTVSRD (and their MFVRD, MTVRD,
+// MFFPRD and MTFPRD spellings) move doubleword 0, which for VS0-VS31 is
+// the F register; MTVSRD leaves doubleword 1 alone. The W forms move the
+// low word of doubleword 0, MFVSRLD reads doubleword 1, MTVSRDD sets
+// both (ra reading zero as R0), and MTVSRWS splats a word.
+func (c *ppc64Ctx) lowerVecMove(op string, ins Instr) (bool, error) {
+	switch op {
+	case "MFVSRD", "MFVRD", "MFFPRD", "MFVSRWZ", "MFVSRLD":
+		if len(ins.Args) != 2 || !ppc64IsVecOp(ins.Args[0]) || !ppc64IsRReg(ins.Args[1]) {
+			return true, fmt.Errorf("ppc64 %s expects VS, reg: %q", op, ins.Raw)
+		}
+		v, err := c.loadVec(ins.Args[0].Reg)
+		if err != nil {
+			return true, err
+		}
+		hi, lo := c.split128(v)
+		r := hi
+		switch op {
+		case "MFVSRLD":
+			r = lo
+		case "MFVSRWZ":
+			r = c.emit("and i64 %s, 4294967295", hi)
+		}
+		return true, c.storeReg(ins.Args[1].Reg, r)
+
+	case "MTVSRD", "MTVRD", "MTFPRD", "MTVSRWZ", "MTVSRWA", "MTVSRWS":
+		if len(ins.Args) != 2 || !ppc64IsRReg(ins.Args[0]) || !ppc64IsVecOp(ins.Args[1]) {
+			return true, fmt.Errorf("ppc64 %s expects reg, VS: %q", op, ins.Raw)
+		}
+		src, err := c.loadReg(ins.Args[0].Reg)
+		if err != nil {
+			return true, err
+		}
+		dst := ins.Args[1].Reg
+		switch op {
+		case "MTVSRWS":
+			lane := ppc64VecLane{32, 4}
+			v := c.splat(lane, c.trunc32(src))
+			return true, c.storeVec(dst, c.emit("bitcast %s %s to i128", lane.vecType(), v))
+		case "MTVSRWZ":
+			src = c.narrow(src, 32, false)
+		case "MTVSRWA":
+			src = c.narrow(src, 32, true)
+		}
+		if ppc64IsFReg(dst) || ppc64IsVSLow(dst) {
+			n, _ := ppc64VSLowNum(dst)
+			return true, c.storeReg(Reg(fmt.Sprintf("F%d", n)), src)
+		}
+		old, err := c.loadVec(dst)
+		if err != nil {
+			return true, err
+		}
+		_, lo := c.split128(old)
+		return true, c.storeVec(dst, c.join128(src, lo))
+
+	case "MTVSRDD":
+		if len(ins.Args) != 3 || !ppc64IsRReg(ins.Args[0]) || !ppc64IsRReg(ins.Args[1]) || !ppc64IsVecOp(ins.Args[2]) {
+			return true, fmt.Errorf("ppc64 MTVSRDD expects reg, reg, VS: %q", ins.Raw)
+		}
+		hi := "0"
+		var err error
+		if ins.Args[0].Reg != "R0" {
+			if hi, err = c.loadReg(ins.Args[0].Reg); err != nil {
+				return true, err
+			}
+		}
+		lo, err := c.loadReg(ins.Args[1].Reg)
+		if err != nil {
+			return true, err
+		}
+		return true, c.storeVec(ins.Args[2].Reg, c.join128(hi, lo))
+	}
+	return false, nil
+}
+
+// ppc64QuadOps gives the quadword add and subtract with carry as
+// x + y + carry-in over VA and VB, with the carry-in from bit 127 of VC
+// for the extended forms; the C forms produce the carry out, the others
+// the sum.
+var ppc64QuadOps = map[string]struct {
+	sub, extended, carry bool
+}{
+	"VADDCUQ": {carry: true}, "VSUBCUQ": {sub: true, carry: true},
+	"VADDEUQM": {extended: true}, "VSUBEUQM": {sub: true, extended: true},
+	"VADDECUQ": {extended: true, carry: true}, "VSUBECUQ": {sub: true, extended: true, carry: true},
+}
+
+// lowerVecQuad lowers "VADDCUQ va, vb, vt" and "VADDEUQM va, vb, vc, vt"
+// and their subtract and carry variants; subtraction adds the complement
+// of vb with a carry-in of 1 (or vc's).
+func (c *ppc64Ctx) lowerVecQuad(op string, ins Instr) (bool, error) {
+	q, found := ppc64QuadOps[op]
+	if !found {
+		return false, nil
+	}
+	n := 3
+	if q.extended {
+		n = 4
+	}
+	regs, err := c.vecRegs(op, ins, n)
+	if err != nil {
+		return true, err
+	}
+	in, err := c.loadVecs(regs[:n-1])
+	if err != nil {
+		return true, err
+	}
+	b := in[1]
+	cin := "0"
+	if q.sub {
+		b = c.emit("xor i128 %s, -1", b)
+		cin = "1"
+	}
+	if q.extended {
+		cin = c.emit("and i128 %s, 1", in[2])
+	}
+	wa := c.emit("zext i128 %s to i256", in[0])
+	wb := c.emit("zext i128 %s to i256", b)
+	wc := c.emit("zext i128 %s to i256", cin)
+	sum := c.emit("add i256 %s, %s", wa, wb)
+	sum = c.emit("add i256 %s, %s", sum, wc)
+	if q.carry {
+		sum = c.emit("lshr i256 %s, 128", sum)
+	}
+	return true, c.storeVec(regs[n-1], c.emit("trunc i256 %s to i128", sum))
+}
+
+// lowerVecPerm lowers the byte and bit shifts and permutes:
+//
+//	VSL, VSR    va shifted by the low 3 bits of vb
+//	VSLO, VSRO  va shifted by bits 3-6 of vb's last byte, in octets
+//	VPERM       byte i of vt is byte vc[i]&31 of va:vb
+//	VBPERMQ     gathers the bits of va numbered by the bytes of vb into
+//	            bits 48-63 of doubleword 0 (an index of 128 or more gives 0)
+//	LVSL, LVSR  the VPERM control vector for shifting by EA&15 bytes
+func (c *ppc64Ctx) lowerVecPerm(op string, ins Instr) (bool, error) {
+	switch op {
+	case "VSL", "VSR", "VSLO", "VSRO":
+		shift := "shl"
+		if op[2] == 'R' {
+			shift = "lshr"
+		}
+		return true, c.vecBinary(op, ins, func(a, b string) string {
+			n := c.emit("and i128 %s, 7", b)
+			if strings.HasSuffix(op, "O") {
+				n = c.emit("and i128 %s, 120", b)
+			}
+			return c.emit("%s i128 %s, %s", shift, a, n)
+		})
+
+	case "VPERM":
+		regs, err := c.vecRegs(op, ins, 4)
+		if err != nil {
+			return true, err
+		}
+		in, err := c.loadVecs(regs[:3])
+		if err != nil {
+			return true, err
+		}
+		// Byte k of va or vb is lane 15-k of its <16 x i8>.
+		a := c.emit("bitcast i128 %s to <16 x i8>", in[0])
+		b := c.emit("bitcast i128 %s to <16 x i8>", in[1])
+		ctl := c.emit("bitcast i128 %s to <16 x i8>", in[2])
+		v := "zeroinitializer"
+		for i := 0; i < 16; i++ {
+			idx := c.emit("extractelement <16 x i8> %s, i32 %d", ctl, 15-i)
+			fromB := c.emit("and i8 %s, 16", idx)
+			fromB = c.emit("icmp ne i8 %s, 0", fromB)
+			lane := c.emit("and i8 %s, 15", idx)
+			lane = c.emit("sub i8 15, %s", lane)
+			ea := c.emit("extractelement <16 x i8> %s, i8 %s", a, lane)
+			eb := c.emit("extractelement <16 x i8> %s, i8 %s", b, lane)
+			by := c.emit("select i1 %s, i8 %s, i8 %s", fromB, eb, ea)
+			v = c.emit("insertelement <16 x i8> %s, i8 %s, i32 %d", v, by, 15-i)
+		}
+		v = c.emit("bitcast <16 x i8> %s to i128", v)
+		return true, c.storeVec(regs[3], v)
+
+	case "VBPERMQ":
+		return true, c.vecBinary(op, ins, func(a, b string) string {
+			v := "0"
+			for i := 0; i < 16; i++ {
+				idx := c.emit("lshr i128 %s, %d", b, 8*(15-i))
+				idx = c.emit("and i128 %s, 255", idx)
+				inRange := c.emit("icmp ult i128 %s, 128", idx)
+				pos := c.emit("sub i128 127, %s", idx)
+				pos = c.emit("and i128 %s, 127", pos)
+				bit := c.emit("lshr i128 %s, %s", a, pos)
+				bit = c.emit("and i128 %s, 1", bit)
+				bit = c.emit("select i1 %s, i128 %s, i128 0", inRange, bit)
+				bit = c.emit("shl i128 %s, %d", bit, 64+15-i)
+				v = c.emit("or i128 %s, %s", v, bit)
+			}
+			return v
+		})
+
+	case "LVSL", "LVSR":
+		if len(ins.Args) != 2 || ins.Args[0].Kind != OpMem || !ppc64IsVecOp(ins.Args[1]) {
+			return true, fmt.Errorf("ppc64 %s expects a memory operand and a vector register: %q", op, ins.Raw)
+		}
+		ea, err := c.addrI64(ins.Args[0].Mem)
+		if err != nil {
+			return true, err
+		}
+		sh := c.emit("and i64 %s, 15", ea)
+		if op == "LVSR" {
+			sh = c.emit("sub i64 16, %s", sh)
+		}
+		// Byte i is sh+i: sh in every byte plus 0x00 0x01 ... 0x0f.
+		const iota = "5233100606242806050955395731361295"
+		by := c.emit("mul i64 %s, 72340172838076673", sh)
+		w := c.emit("zext i64 %s to i128", by)
+		v := c.emit("shl i128 %s, 64", w)
+		v = c.emit("or i128 %s, %s", v, w)
+		v = c.emit("add i128 %s, %s", v, iota)
+		return true, c.storeVec(ins.Args[1].Reg, v)
+	}
+	return false, nil
+}
@@ -0,0 +1,205 @@
+package plan9asm
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ppc64RegAlias maps the register names the ppc64 assembler accepts
+// besides R0-R31, F0-F31 and V0-V31. R1 is the hardware stack pointer, so
+// it shares the SP slot; VS32-VS63 are the vector registers V0-V31.
+var ppc64RegAlias = map[string]string{
+	"R1": "SP",
+	"g":  "R30",
+}
+
+func init() {
+	for i := 0; i < 32; i++ {
+		ppc64RegAlias[fmt.Sprintf("VS%d", 32+i)] = fmt.Sprintf("V%d", i)
+	}
+}
+
+// ppc64FixedFrame is FIXED_FRAME from runtime/asm_ppc64x.h: the ELFv2
+// caller frame header (back chain, CR save, LR save and TOC save words)
+// that sits below the outgoing arguments of every frame.
+const ppc64FixedFrame = 32
+
+// Go's ABIInternal assigns integer arguments and results to R3-R10 and
+// R14-R17 and floating-point ones to F1-F12.
+var (
+	ppc64IntArgRegs = []Reg{"R3", "R4", "R5", "R6", "R7", "R8", "R9", "R10",
+		"R14", "R15", "R16", "R17"}
+	ppc64FloatArgRegs = []Reg{"F1", "F2", "F3", "F4", "F5", "F6", "F7", "F8",
+		"F9", "F10", "F11", "F12"}
+)
+
+// ppc64SpecialRegs are the registers parseReg does not know: the
+// condition register and its fields, the link, count and fixed-point
+// exception registers, and FPSCR. LR must be listed because parseReg
+// reads it as the arm64 alias of R30, which on ppc64 is g.
+var ppc64SpecialRegs = map[string]bool{
+	"CR": true, "LR": true, "CTR": true, "XER": true, "FPSCR": true,
+	"CR0": true, "CR1": true, "CR2": true, "CR3": true,
+	"CR4": true, "CR5": true, "CR6": true, "CR7": true,
+}
+
+// ppc64ParseOperands parses an operand list in the ppc64 dialect. Besides
+// the aliases it handles the forms the generic operand parser does not:
+// the special registers, VS0-VS31, bare numbers, (Ra+Rb) indexed
+// references, branches through (LR) and (CTR), $off(Rn) address constants
+// and FIXED_FRAME offsets.
+func ppc64ParseOperands(s string) ([]Operand, error) {
+	if s == "" {
+		return nil, nil
+	}
+	s = canonicalRegAliases(ppc64ExpandFixedFrame(s), ppc64RegAlias)
+	parts := splitTopLevelCSV(s)
+	out := make([]Operand, 0, len(parts))
+	for _, p := range parts {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		if ppc64SpecialRegs[p] || ppc64IsVSLow(Reg(p)) {
+			out = append(out, Operand{Kind: OpReg, Reg: Reg(p)})
+			continue
+		}
+		if n, err := strconv.ParseInt(p, 0, 64); err == nil {
+			// BC takes its BO and BI fields without '$'.
+			out = append(out, Operand{Kind: OpImm, Imm: n})
+			continue
+		}
+		if p == "(LR)" || p == "(CTR)" {
+			out = append(out, Operand{Kind: OpMem, Mem: MemRef{Base: Reg(p[1 : len(p)-1])}})
+			continue
+		}
+		// (Ra+Rb) is the indexed form (Ra)(Rb).
+		if i := strings.LastIndex(p, "("); i >= 0 && strings.HasSuffix(p, ")") {
+			if base, idx, ok := strings.Cut(p[i+1:len(p)-1], "+"); ok && ppc64IsRName(base) && ppc64IsRName(idx) {
+				p = p[:i] + "(" + strings.TrimSpace(base) + ")(" + strings.TrimSpace(idx) + ")"
+			}
+		}
+		if _, ok := ppc64AddrConst(p); ok {
+			// $off(Rn) is an address constant (addi); the generic parser
+			// would read it as a memory operand, so keep it as a symbol
+			// for the backend.
+			out = append(out, Operand{Kind: OpSym, Sym: p})
+			continue
+		}
+		op, err := parseOperand(p)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, op)
+	}
+	return out, nil
+}
+
+// ppc64ExpandFixedFrame replaces the FIXED_FRAME macro, which the
+// ignored asm_ppc64x.h include would define.
+func ppc64ExpandFixedFrame(s string) string {
+	if !strings.Contains(s, "FIXED_FRAME") {
+		return s
+	}
+	return strings.ReplaceAll(s, "FIXED_FRAME", strconv.Itoa(ppc64FixedFrame))
+}
+
+func ppc64IsRName(s string) bool {
+	s = strings.TrimSpace(s)
+	if s == "SP" {
+		return true
+	}
+	n, err := strconv.Atoi(strings.TrimPrefix(s, "R"))
+	return strings.HasPrefix(s, "R") && err == nil && 0 <= n && n <= 31
+}
+
+// ppc64AddrConst parses an $off(Rn) or $off(Ra)(Rb) address constant.
+func ppc64AddrConst(s string) (MemRef, bool) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "$") || !strings.HasSuffix(s, ")") {
+		return MemRef{}, false
+	}
+	body := s[1:]
+	open := strings.LastIndex(body, "(")
+	if open < 0 || !ppc64IsRName(body[open+1:len(body)-1]) {
+		return MemRef{}, false
+	}
+	m := MemRef{Base: Reg(strings.TrimSpace(body[open+1 : len(body)-1]))}
+	body = body[:open]
+	if strings.HasSuffix(body, ")") {
+		if o := strings.LastIndex(body, "("); o >= 0 && ppc64IsRName(body[o+1:len(body)-1]) {
+			m.Index, m.Base = m.Base, Reg(strings.TrimSpace(body[o+1:len(body)-1]))
+			body = body[:o]
+		}
+	}
+	if body != "" {
+		v, ok := parseImm("$" + body)
+		if !ok {
+			return MemRef{}, false
+		}
+		m.Off = v
+	}
+	return m, true
+}
+
+func ppc64IsFReg(r Reg) bool {
+	s := string(r)
+	n, err := strconv.Atoi(strings.TrimPrefix(s, "F"))
+	return strings.HasPrefix(s, "F") && err == nil && 0 <= n && n <= 31
+}
+
+func ppc64IsVReg(r Reg) bool {
+	s := string(r)
+	n, err := strconv.Atoi(strings.TrimPrefix(s, "V"))
+	return strings.HasPrefix(s, "V") && err == nil && 0 <= n && n <= 31
+}
+
+// ppc64IsVSLow reports whether r is one of VS0-VS31, whose first
+// doubleword is the F register of the same number.
+func ppc64IsVSLow(r Reg) bool {
+	s := string(r)
+	n, err := strconv.Atoi(strings.TrimPrefix(s, "VS"))
+	return strings.HasPrefix(s, "VS") && err == nil && 0 <= n && n <= 31
+}
+
+func ppc64IsCRField(r Reg) bool {
+	s := string(r)
+	return len(s) == 3 && s[:2] == "CR" && s[2] >= '0' && s[2] <= '7'
+}
+
+func ppc64IsRReg(o Operand) bool {
+	return o.Kind == OpReg && (o.Reg == SP || ppc64IsRName(string(o.Reg)))
+}
+
+func ppc64IsFRegOp(o Operand) bool {
+	return o.Kind == OpReg && ppc64IsFReg(o.Reg)
+}
+
+// ppc64IsVecOp reports whether o names a 128-bit VSX register: V0-V31,
+// VS0-VS31, or F0-F31 standing for VS0-VS31.
+func ppc64IsVecOp(o Operand) bool {
+	return o.Kind == OpReg && (ppc64IsVReg(o.Reg) || ppc64IsVSLow(o.Reg) || ppc64IsFReg(o.Reg))
+}
+
+func ppc64IsCRFieldOp(o Operand) bool {
+	return o.Kind == OpReg && ppc64IsCRField(o.Reg)
+}
+
+// ppc64CRBit returns the condition register bit number of a BI operand:
+// $n or a CRnLT, CRnGT, CRnEQ or CRnSO name.
+func ppc64CRBit(o Operand) (int64, bool) {
+	switch o.Kind {
+	case OpImm:
+		return o.Imm, 0 <= o.Imm && o.Imm < 32
+	case OpIdent, OpSym:
+		s := strings.ToUpper(o.Ident + o.Sym)
+		if len(s) != 5 || s[:2] != "CR" || s[2] < '0' || s[2] > '7' {
+			return 0, false
+		}
+		bit := map[string]int64{"LT": 0, "GT": 1, "EQ": 2, "SO": 3}
+		b, ok := bit[s[3:]]
+		return 4*int64(s[2]-'0') + b, ok
+	}
+	return 0, false
+}
@@ -0,0 +1,217 @@
+//go:build !llgo
+// +build !llgo
+
+package plan9asm
+
+import (
+	"strings"
+	"testing"
+)
+
+func translatePPC64(t *testing.T, src string, sigs map[string]FuncSig) string {
+	t.Helper()
+	file, err := Parse(ArchPPC64, src)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	ll, err := translateIRText(file, Options{
+		TargetTriple: "powerpc64le-unknown-linux-gnu",
+		Goarch:       "ppc64le",
+		ResolveSym:   testResolveSym("example"),
+		Sigs:         sigs,
+	})
+	if err != nil {
+		t.Fatalf("translateIRText: %v", err)
+	}
+	return ll
+}
+
+func TestTranslatePPC64Registers(t *testing.T) {
+	ll := translatePPC64(t, `TEXT ·f(SB),NOSPLIT,$0-24
+	MOVD a+0(FP), R3
+	MOVD b+8(FP), R4
+	ADD R4, R3, R5
+	SUB R4, R3, R6
+	MULHDU R4, R3, R7
+	RLDICL $8, R5, $56, R8
+	ADDC R3, R4, R9
+	ADDE R0, R0, R10
+	EXTSW R6, R11
+	ADD R7, R8
+	ADD R9, R8
+	ADD R10, R8
+	ADD R11, R8
+	MOVD R8, ret+16(FP)
+	RET
+`, map[string]FuncSig{
+		"example.f": sigWithClassicFrame("example.f", []LLVMType{I64, I64}, I64),
+	})
+	wantIR(t, ll,
+		`define i64 @"example.f"(i64 %arg0, i64 %arg1)`,
+		"%reg_R3 = alloca i64",
+		"add i64",
+		"sub i64",
+		"mul i128",
+		"@llvm.fshl.i64",
+		"add i128",
+		"sext i32",
+		"ret i64",
+	)
+}
+
+func TestTranslatePPC64Branches(t *testing.T) {
+	ll := translatePPC64(t, `TEXT ·sum(SB),NOSPLIT,$0-16
+	MOVD n+0(FP), R3
+	MOVD $0, R4
+	CMP R3, $0
+	BLE done
+	MOVD R3, CTR
+loop:
+	ADD R3, R4
+	ADD $-1, R3
+	BDNZ loop
+done:
+	MOVD R4, ret+8(FP)
+	RET
+
+TEXT ·max(SB),NOSPLIT,$0-24
+	MOVD a+0(FP), R3
+	MOVD b+8(FP), R4
+	CMPU R3, R4, CR7
+	ISEL CR7LT, R4, R3, R5
+	BC 12, 30, eq
+	MOVD R5, ret+16(FP)
+	RET
+eq:
+	MOVD R0, ret+16(FP)
+	RET
+`, map[string]FuncSig{
+		"example.sum": sigWithClassicFrame("example.sum", []LLVMType{I64}, I64),
+		"example.max": sigWithClassicFrame("example.max", []LLVMType{I64, I64}, I64),
+	})
+	wantIR(t, ll, "icmp slt i64", "icmp ult i64", "%reg_CTR", "label %loop", "label %done", "label %eq", "select i1")
+}
+
+func TestTranslatePPC64Float(t *testing.T) {
+	ll := translatePPC64(t, `TEXT ·fma(SB),NOSPLIT,$0-32
+	FMOVD a+0(FP), F1
+	FMOVD b+8(FP), F2
+	FMOVD c+16(FP), F3
+	FMADD F1, F3, F2, F4
+	FCTIDZ F4, F5
+	MFVSRD VS5, R3
+	MOVD R3, ret+24(FP)
+	RET
+`, map[string]FuncSig{
+		"example.fma": sigWithClassicFrame("example.fma", []LLVMType{LLVMType("double"), LLVMType("double"), LLVMType("double")}, I64),
+	})
+	wantIR(t, ll, "@llvm.fma.f64", "@llvm.fptosi.sat.i64.f64", "bitcast i64")
+}
+
+func TestTranslatePPC64Atomics(t *testing.T) {
+	ll := translatePPC64(t, `TEXT ·cas(SB),NOSPLIT,$0-25
+	MOVD ptr+0(FP), R3
+	MOVD old+8(FP), R4
+	MOVD new+16(FP), R5
+	LWSYNC
+again:
+	LDAR (R3), R6
+	CMP R6, R4
+	BNE fail
+	STDCCC R5, (R3)
+	BNE again
+	LWSYNC
+	MOVD $1, R3
+	MOVB R3, ret+24(FP)
+	RET
+fail:
+	LWSYNC
+	MOVB R0, ret+24(FP)
+	RET
+`, map[string]FuncSig{
+		"example.cas": sigWithClassicFrame("example.cas", []LLVMType{Ptr, I64, I64}, I1),
+	})
+	wantIR(t, ll, "fence seq_cst", "load atomic i64", "cmpxchg ptr", "stcx_try_")
+}
+
+func TestTranslatePPC64Vector(t *testing.T) {
+	ll := translatePPC64(t, `TEXT ·eq16(SB),NOSPLIT,$0-17
+	MOVD a+0(FP), R3
+	MOVD b+8(FP), R4
+	LXVD2X (R3)(R0), VS32
+	LXVD2X (R4)(R0), VS33
+	VCMPEQUBCC V0, V1, V2
+	BLT CR6, equal
+	MOVB R0, ret+16(FP)
+	RET
+equal:
+	MOVD $1, R3
+	MOVB R3, ret+16(FP)
+	RET
+
+TEXT ·sum(SB),NOSPLIT,$0-16
+	MOVD a+0(FP), R3
+	MOVD b+8(FP), R4
+	LXVW4X (R3)(R0), VS32
+	LXVW4X (R4)(R0), VS33
+	VADDUWM V0, V1, V2
+	VSPLTISB $3, V3
+	VPERM V2, V2, V3, V2
+	STXVW4X VS34, (R3)(R0)
+	RET
+`, map[string]FuncSig{
+		"example.eq16": sigWithClassicFrame("example.eq16", []LLVMType{Ptr, Ptr}, I1),
+		"example.sum":  sigWithClassicFrame("example.sum", []LLVMType{Ptr, Ptr}, Void),
+	})
+	wantIR(t, ll, "%reg_V0 = alloca i128", "icmp eq <16 x i8>", "add <4 x i32>", "extractelement <16 x i8>", "store i128")
+}
+
+func TestTranslatePPC64Syscall(t *testing.T) {
+	src := `TEXT ·getpid(SB),NOSPLIT,$0-8
+	MOVD $20, R0
+	SYSCALL
+	MOVD R3, ret+0(FP)
+	RET
+`
+	sigs := map[string]FuncSig{
+		"example.getpid": sigWithClassicFrame("example.getpid", nil, I64),
+	}
+	wantIR(t, translatePPC64(t, src, sigs), "call i64 @syscall(i64 ")
+
+	file, err := Parse(ArchPPC64, src)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	ll, err := translateIRText(file, Options{
+		TargetTriple: "powerpc64le-unknown-linux-gnu",
+		Goarch:       "ppc64le",
+		ResolveSym:   testResolveSym("example"),
+		Sigs:         sigs,
+		Syscall:      RawSyscall{},
+	})
+	if err != nil {
+		t.Fatalf("translateIRText: %v", err)
+	}
+	wantIR(t, ll, `"={r3},={r4},=r,{r0},{r3},{r4},{r5},{r6},{r7},{r8},`)
+}
+
+func TestTranslatePPC64RejectsUnsupported(t *testing.T) {
+	for _, tc := range []struct{ insn, want string }{
+		{"FADD R3, F1, F2", "expects F registers"},
+		{"VSHASIGMAW $0, V0, $0, V1", "unsupported"},
+		{"MOVFL FPSCR, F0", "FPSCR is not modeled"},
+	} {
+		file, err := Parse(ArchPPC64, "TEXT ·f(SB),NOSPLIT,$0-0\n\t"+tc.insn+"\n\tRET\n")
+		if err != nil {
+			t.Fatalf("%s: Parse: %v", tc.insn, err)
+		}
+		_, err = translateIRText(file, Options{
+			Goarch:     "ppc64le",
+			ResolveSym: testResolveSym("example"),
+			Sigs:       map[string]FuncSig{"example.f": {Name: "example.f", Ret: Void}},
+		})
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: err = %v, want %q", tc.insn, err, tc.want)
+		}
+	}
+}
@@ -17,8 +17,13 @@ type ppMacro struct {
 //   - ignores #include
 //   - supports #define NAME <body> with optional single-line continuation via '\'
 //   - expands macros only when a statement is exactly NAME
-func preprocess(src string) (string, error) {
+//
+// The predefined names count as defined in #ifdef and #if conditions.
+func preprocess(src string, predefined ...string) (string, error) {
 	macros := map[string]ppMacro{}
+	for _, name := range predefined {
+		macros[name] = ppMacro{}
+	}
 
 	type ifState struct {
 		outerActive bool
@@ -162,6 +167,10 @@ func preprocess(src string) (string, error) {
 		}
 
 		trim := strings.TrimSpace(line)
+		if strings.HasPrefix(trim, "#") {
+			// Directives may be indented after the '#': "#  ifdef X".
+			trim = "#" + strings.TrimSpace(trim[1:])
+		}
 		if strings.HasPrefix(trim, "#include") || strings.HasPrefix(trim, "#undef") {
 			// Ignore includes for now. We don't need textflag.h values because
 			// we treat flags as opaque in TEXT.
@@ -319,7 +328,8 @@ func expandPPLine(line string, macros map[string]ppMacro, macroNames []string, d
 		}
 		return out
 	}
-	if m, ok := macros[trimLine]; ok && len(m.params) == 0 {
+	// NAME and NAME() both invoke a macro without parameters.
+	if m, ok := macros[strings.TrimSuffix(trimLine, "()")]; ok && len(m.params) == 0 {
 		chunks := strings.Split(m.body, "\n")
 		out := make([]string, 0, len(chunks))
 		for _, ch := range chunks {
@@ -0,0 +1,572 @@
+package plan9asm
+
+import "strings"
+
+// Register contracts.
+//
+// Stdlib asm often enters a file-local helper such as memeqbody<> or
+// indexbody<> by JMP or CALL after loading its inputs into registers, and
+// the helper leaves its results in registers for the caller to read. A Go
+// declaration says nothing about that interface, so it is recovered from
+// the file: a backward liveness pass over every TEXT symbol finds the
+// registers and flags a helper reads before writing them (its inputs) and
+// the registers it may write that a caller reads after the helper returns
+// (its outputs). The helper's signature then takes the inputs in ArgRegs
+// and returns the outputs in RetRegs, and the CFG translators pass that
+// state across the call explicitly.
+//
+// The register effects are a conservative model: an instruction uses every
+// register it names and only kills the destination of plain moves and
+// non-destructive three-operand forms. A spurious input costs an argument;
+// a missing one would read zero. Outputs are always inputs too, so a
+// helper that leaves an output untouched on some path hands back the value
+// it was given.
+
+// FLAGS names the condition flags in ArgRegs and RetRegs. They travel as
+// one word: the low 16 bits of RFLAGS on amd64 and 386, NZCV in bits 28-31
+// on arm64.
+const FLAGS Reg = "FLAGS"
+
+// regContractArch describes an architecture to the contract analysis.
+type regContractArch struct {
+	word LLVMType
+	// regs lists the registers a contract can carry, FLAGS last, in the
+	// order they are passed.
+	regs []Reg
+	// canon returns the name operand register r is tracked under. vector
+	// is set for registers a contract cannot carry; ok is false for
+	// registers outside the model (SP, ZR, g).
+	canon func(r Reg) (name Reg, vector bool, ok bool)
+	// retRegs lists the registers a Go-ABI function returns results in.
+	retRegs []Reg
+	// callRegs lists the registers a call out of the file may read.
+	callRegs []Reg
+	// effect returns the registers ins uses, may write and always
+	// overwrites, before canon. Calls, jumps and flags are handled by the
+	// analysis.
+	effect     func(ins Instr) regEffect
+	flagEffect func(ins Instr) flagEffect
+	// flagsKill is the set of flags an instruction must write, without
+	// reading any, to overwrite FLAGS.
+	flagsKill flagMask
+	// cfg splits fn into basic blocks and resolves their successors as the
+	// translator does.
+	cfg func(fn Func) ([][]Instr, []flagSuccs)
+}
+
+type regEffect struct {
+	use, def, kill []Reg
+}
+
+func regContractArchFor(arch Arch) (*regContractArch, bool) {
+	switch arch {
+	case ArchAMD64:
+		return amd64RegContractArch(false), true
+	case Arch386:
+		return amd64RegContractArch(true), true
+	case ArchARM64:
+		return arm64RegContractArch(), true
+	}
+	return nil, false
+}
+
+// regSet is a set of tracked register names, FLAGS included.
+type regSet map[Reg]bool
+
+func (s regSet) addAll(o regSet) bool {
+	changed := false
+	for r := range o {
+		if !s[r] {
+			s[r] = true
+			changed = true
+		}
+	}
+	return changed
+}
+
+func (s regSet) clone() regSet {
+	out := make(regSet, len(s))
+	out.addAll(s)
+	return out
+}
+
+// rcFunc is one TEXT symbol of the file under analysis.
+type rcFunc struct {
+	name   string
+	sig    FuncSig
+	helper bool
+	blocks [][]Instr
+	succs  []flagSuccs
+
+	in, out, need regSet
+}
+
+type regContractAnalysis struct {
+	arch    *regContractArch
+	resolve func(string) string
+	sigs    map[string]FuncSig
+	funcs   []*rcFunc
+	byName  map[string]*rcFunc
+}
+
+// applyRegContracts derives register contracts for the file-local helpers
+// of file that other TEXT symbols enter by JMP or CALL (or by running off
+// their end into them), and returns the file and signatures to translate.
+//
+// A helper is a TEXT symbol that has no signature in sigs and never
+// references its argument frame; a signature the caller supplies, for a <>
+// symbol too, is used as given. A helper with a vector register among its
+// inputs or outputs is left without a contract. Only amd64, 386 and arm64
+// derive contracts; derived reports the signatures that were added.
+func applyRegContracts(file *File, resolve func(string) string, sigs map[string]FuncSig) (out *File, all map[string]FuncSig, derived map[string]FuncSig) {
+	arch, ok := regContractArchFor(file.Arch)
+	if !ok {
+		return file, sigs, nil
+	}
+	isHelper := func(fn Func) bool {
+		// A signature the caller supplies always wins.
+		if _, hasSig := sigs[resolve(fn.Sym)]; hasSig {
+			return false
+		}
+		return !regContractUsesFrame(fn)
+	}
+
+	// A TEXT symbol that runs off its end continues in the next one; make
+	// that an explicit tail jump into a helper.
+	funcs, rewritten := file.Funcs, false
+	for i := 0; i+1 < len(file.Funcs); i++ {
+		if !regContractFallsThrough(file.Funcs[i]) || !isHelper(file.Funcs[i+1]) {
+			continue
+		}
+		if !rewritten {
+			funcs, rewritten = append([]Func(nil), file.Funcs...), true
+		}
+		next := file.Funcs[i+1].Sym + "(SB)"
+		funcs[i].Instrs = append(append([]Instr(nil), funcs[i].Instrs...), Instr{
+			Op:   "JMP",
+			Args: []Operand{{Kind: OpSym, Sym: next}},
+			Raw:  "JMP\t" + next,
+		})
+	}
+
+	a := &regContractAnalysis{arch: arch, resolve: resolve, sigs: sigs, byName: map[string]*rcFunc{}}
+	for _, fn := range funcs {
+		f := &rcFunc{name: resolve(fn.Sym), helper: isHelper(fn)}
+		f.sig = sigs[f.name]
+		f.blocks, f.succs = arch.cfg(fn)
+		a.funcs = append(a.funcs, f)
+		a.byName[f.name] = f
+	}
+	entered := map[string]bool{}
+	for _, f := range a.funcs {
+		a.forSites(f, func(bi, ii int, h *rcFunc, tail bool) {
+			entered[h.name] = true
+		})
+	}
+	for _, f := range a.funcs {
+		f.helper = f.helper && entered[f.name]
+		f.in, f.out, f.need = regSet{}, regSet{}, regSet{}
+	}
+	a.solve()
+
+	all = make(map[string]FuncSig, len(sigs))
+	for k, v := range sigs {
+		all[k] = v
+	}
+	derived = map[string]FuncSig{}
+	for _, f := range a.funcs {
+		if !f.helper || a.carriesVector(f) {
+			continue
+		}
+		sig := FuncSig{Name: f.name, Ret: Void}
+		sig.ArgRegs = a.ordered(f.in)
+		for range sig.ArgRegs {
+			sig.Args = append(sig.Args, arch.word)
+		}
+		sig.RetRegs = a.ordered(f.out)
+		switch len(sig.RetRegs) {
+		case 0:
+		case 1:
+			sig.Ret = arch.word
+		default:
+			parts := make([]string, len(sig.RetRegs))
+			for i := range parts {
+				parts[i] = string(arch.word)
+			}
+			sig.Ret = LLVMType("{ " + strings.Join(parts, ", ") + " }")
+		}
+		all[f.name] = sig
+		derived[f.name] = sig
+	}
+	if len(derived) == 0 && !rewritten {
+		return file, sigs, nil
+	}
+	cp := *file
+	cp.Funcs = funcs
+	return &cp, all, derived
+}
+
+// regContractSig reports whether calls to sig pass register state: it
+// hands back RetRegs, or takes only register words of type word and
+// returns nothing.
+func regContractSig(sig FuncSig, word LLVMType) bool {
+	if len(sig.RetRegs) > 0 {
+		return true
+	}
+	if sig.Ret != Void || len(sig.ArgRegs) == 0 || len(sig.ArgRegs) != len(sig.Args) {
+		return false
+	}
+	for _, t := range sig.Args {
+		if t != word {
+			return false
+		}
+	}
+	return true
+}
+
+// regContractFlagEffect adds to e the flags a register contract passes
+// across ins in a function with signature sig: FLAGS among the arguments
+// of a callee or the results of a RET, and FLAGS handed back by a CALL.
+func regContractFlagEffect(ins Instr, e flagEffect, sig FuncSig, resolve func(string) string, sigs map[string]FuncSig, all flagMask) flagEffect {
+	if ins.Op == OpRET {
+		if regListHas(sig.RetRegs, FLAGS) {
+			e.use |= all
+		}
+		return e
+	}
+	base, tail, ok := goReferencedFunc(Instr{Op: Op(strings.ToUpper(string(ins.Op))), Args: ins.Args})
+	if !ok || resolve == nil {
+		return e
+	}
+	csig, ok := sigs[resolve(base)]
+	if !ok {
+		return e
+	}
+	if regListHas(csig.ArgRegs, FLAGS) || tail && regListHas(sig.RetRegs, FLAGS) {
+		e.use |= all
+	}
+	if !tail && regListHas(csig.RetRegs, FLAGS) {
+		e.def = all
+	}
+	return e
+}
+
+func regListHas(regs []Reg, r Reg) bool {
+	for _, x := range regs {
+		if x == r {
+			return true
+		}
+	}
+	return false
+}
+
+// regContractUsesFrame reports whether fn references its argument frame.
+func regContractUsesFrame(fn Func) bool {
+	for _, ins := range fn.Instrs {
+		for _, a := range ins.Args {
+			if a.Kind == OpFP || a.Kind == OpFPAddr {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// regContractFallsThrough reports whether control can run off the end of
+// fn.
+func regContractFallsThrough(fn Func) bool {
+	for i := len(fn.Instrs) - 1; i >= 0; i-- {
+		ins := fn.Instrs[i]
+		switch strings.ToUpper(string(ins.Op)) {
+		case string(OpLABEL), string(OpTEXT), "PCALIGN", "NOP", "PCDATA", "FUNCDATA", "NO_LOCAL_POINTERS":
+			continue
+		case string(OpRET), "JMP", "B", "UNDEF":
+			return false
+		}
+		return true
+	}
+	return true
+}
+
+// forSites calls visit for every direct JMP or CALL in f to a helper of
+// the file.
+func (a *regContractAnalysis) forSites(f *rcFunc, visit func(bi, ii int, h *rcFunc, tail bool)) {
+	for bi, blk := range f.blocks {
+		for ii, ins := range blk {
+			if h, tail, ok := a.site(ins); ok {
+				visit(bi, ii, h, tail)
+			}
+		}
+	}
+}
+
+func (a *regContractAnalysis) site(ins Instr) (h *rcFunc, tail bool, ok bool) {
+	base, tail, ok := goReferencedFunc(Instr{Op: Op(strings.ToUpper(string(ins.Op))), Args: ins.Args})
+	if !ok {
+		return nil, false, false
+	}
+	h = a.byName[a.resolve(base)]
+	if h == nil || !h.helper {
+		return nil, false, false
+	}
+	return h, tail, true
+}
+
+// solve iterates the per-function liveness to a fixed point. Inputs,
+// outputs and the registers live after each entry only grow.
+func (a *regContractAnalysis) solve() {
+	for changed := true; changed; {
+		changed = false
+		for _, f := range a.funcs {
+			entry, after := a.liveness(f)
+			a.forSites(f, func(bi, ii int, h *rcFunc, tail bool) {
+				need := after[[2]int{bi, ii}]
+				if tail {
+					need = a.retLive(f)
+				}
+				if h.need.addAll(need) {
+					changed = true
+				}
+			})
+			if !f.helper {
+				continue
+			}
+			written := a.written(f)
+			out := regSet{}
+			for r := range f.need {
+				if written[r] {
+					out[r] = true
+				}
+			}
+			if f.out.addAll(out) {
+				changed = true
+			}
+			if f.in.addAll(entry) {
+				changed = true
+			}
+			if f.in.addAll(f.out) {
+				changed = true
+			}
+		}
+	}
+}
+
+// retLive returns the registers f's RET reads: a helper's outputs, or the
+// result registers of a Go function.
+func (a *regContractAnalysis) retLive(f *rcFunc) regSet {
+	if f.helper {
+		return f.out
+	}
+	n := 0
+	switch {
+	case len(f.sig.Frame.Results) > 0:
+		n = len(f.sig.Frame.Results)
+	case f.sig.Ret != Void:
+		n = 1
+	}
+	live := regSet{}
+	for i := 0; i < n && i < len(a.arch.retRegs); i++ {
+		live[a.arch.retRegs[i]] = true
+	}
+	return live
+}
+
+// liveness returns the registers live into f and after each instruction
+// that enters a helper.
+func (a *regContractAnalysis) liveness(f *rcFunc) (entry regSet, after map[[2]int]regSet) {
+	liveIn := make([]regSet, len(f.blocks))
+	for bi := range liveIn {
+		liveIn[bi] = regSet{}
+	}
+	universe := regSet{}
+	for _, r := range a.arch.regs {
+		universe[r] = true
+	}
+	blockOut := func(bi int) regSet {
+		if f.succs[bi].unknown {
+			return universe.clone()
+		}
+		out := regSet{}
+		for _, s := range f.succs[bi].blocks {
+			out.addAll(liveIn[s])
+		}
+		return out
+	}
+	after = map[[2]int]regSet{}
+	for changed := true; changed; {
+		changed = false
+		for bi := len(f.blocks) - 1; bi >= 0; bi-- {
+			live := blockOut(bi)
+			for ii := len(f.blocks[bi]) - 1; ii >= 0; ii-- {
+				if _, _, ok := a.site(f.blocks[bi][ii]); ok {
+					after[[2]int{bi, ii}] = live.clone()
+				}
+				live = a.transfer(f, f.blocks[bi][ii], live)
+			}
+			if liveIn[bi].addAll(live) {
+				changed = true
+			}
+		}
+	}
+	if len(liveIn) == 0 {
+		return regSet{}, after
+	}
+	return liveIn[0], after
+}
+
+// transfer returns the registers live before ins given those live after.
+func (a *regContractAnalysis) transfer(f *rcFunc, ins Instr, live regSet) regSet {
+	live = live.clone()
+	if h, tail, ok := a.site(ins); ok {
+		if tail {
+			live = a.retLive(f).clone()
+		} else {
+			for r := range h.out {
+				delete(live, r)
+			}
+		}
+		live.addAll(h.in)
+		return live
+	}
+	use, _, kill := a.effect(f, ins)
+	for r := range kill {
+		delete(live, r)
+	}
+	live.addAll(use)
+	return live
+}
+
+// written returns the registers f may write, including the outputs of the
+// helpers it enters.
+func (a *regContractAnalysis) written(f *rcFunc) regSet {
+	w := regSet{}
+	for _, blk := range f.blocks {
+		for _, ins := range blk {
+			if h, _, ok := a.site(ins); ok {
+				w.addAll(h.out)
+				continue
+			}
+			_, def, kill := a.effect(f, ins)
+			w.addAll(def)
+			w.addAll(kill)
+		}
+	}
+	return w
+}
+
+// effect returns the canonical registers ins uses, may write and always
+// overwrites in f, with calls and jumps out of the file modeled as the
+// translators lower them.
+func (a *regContractAnalysis) effect(f *rcFunc, ins Instr) (use, def, kill regSet) {
+	use, def, kill = regSet{}, regSet{}, regSet{}
+	add := func(s regSet, regs []Reg) {
+		for _, r := range regs {
+			if name, _, ok := a.arch.canon(r); ok {
+				s[name] = true
+			}
+		}
+	}
+	e := a.arch.effect(ins)
+	add(use, e.use)
+	add(def, e.def)
+	add(kill, e.kill)
+
+	op := strings.ToUpper(string(ins.Op))
+	if dot := strings.IndexByte(op, '.'); dot >= 0 {
+		op = op[:dot]
+	}
+	switch {
+	case ins.Op == OpRET:
+		use.addAll(a.retLive(f))
+	case op == "CALL" || op == "BL":
+		if base, _, ok := goReferencedFunc(Instr{Op: Op(op), Args: ins.Args}); ok {
+			if csig, ok := a.sigs[a.resolve(base)]; ok && len(csig.ArgRegs) > 0 {
+				add(use, csig.ArgRegs)
+				add(def, csig.RetRegs)
+			} else {
+				add(use, a.arch.callRegs)
+			}
+		} else {
+			add(use, a.arch.callRegs)
+		}
+		add(def, a.arch.retRegs[:1])
+	case op == "JMP" || op == "B":
+		if len(ins.Args) == 1 && a.leavesFunc(f, ins.Args[0]) {
+			add(use, a.arch.callRegs)
+			use.addAll(a.retLive(f))
+		}
+	}
+
+	fe := a.arch.flagEffect(ins)
+	if fe.use != 0 {
+		use[FLAGS] = true
+	}
+	if fe.def != 0 {
+		def[FLAGS] = true
+	}
+	if fe.def&a.arch.flagsKill == a.arch.flagsKill && fe.use == 0 {
+		kill[FLAGS] = true
+	}
+	return use, def, kill
+}
+
+// leavesFunc reports whether an unconditional jump to target leaves f: a
+// tail call to a symbol or through a register or memory.
+func (a *regContractAnalysis) leavesFunc(f *rcFunc, target Operand) bool {
+	switch target.Kind {
+	case OpSym:
+		return strings.HasSuffix(strings.TrimSpace(target.Sym), "(SB)")
+	case OpMem:
+		return !strings.EqualFold(string(target.Mem.Base), "PC")
+	case OpReg:
+		_, _, ok := a.arch.canon(target.Reg)
+		return ok
+	}
+	return false
+}
+
+// carriesVector reports whether f's contract would need a vector register.
+func (a *regContractAnalysis) carriesVector(f *rcFunc) bool {
+	for _, s := range []regSet{f.in, f.out} {
+		for r := range s {
+			if _, vector, _ := a.arch.canon(r); vector {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ordered returns the registers of s a contract carries, in argument order.
+func (a *regContractAnalysis) ordered(s regSet) []Reg {
+	var out []Reg
+	for _, r := range a.arch.regs {
+		if s[r] {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// regOperands returns the registers named by op, the base and index of a
+// memory operand included.
+func regOperands(op Operand) []Reg {
+	switch op.Kind {
+	case OpReg, OpRegExtend:
+		return []Reg{op.Reg}
+	case OpRegShift:
+		if op.ShiftReg != "" {
+			return []Reg{op.Reg, op.ShiftReg}
+		}
+		return []Reg{op.Reg}
+	case OpMem:
+		regs := []Reg{op.Mem.Base}
+		if op.Mem.Index != "" {
+			regs = append(regs, op.Mem.Index)
+		}
+		return regs
+	case OpRegList:
+		return append([]Reg(nil), op.RegList...)
+	}
+	return nil
+}
@@ -0,0 +1,329 @@
+//go:build !llgo
+// +build !llgo
+
+package plan9asm
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// amd64MemEqual is memequal and memeqbody<> of internal/bytealg, with
+// the body cut down to a byte loop.
+const amd64MemEqual = `TEXT runtime·memequal<ABIInternal>(SB),NOSPLIT,$0-25
+	CMPQ	AX, BX
+	JNE	neq
+	MOVQ	$1, AX
+	RET
+neq:
+	MOVQ	AX, SI
+	MOVQ	BX, DI
+	MOVQ	CX, BX
+	JMP	memeqbody<>(SB)
+
+TEXT memeqbody<>(SB),NOSPLIT,$0-0
+loop:
+	TESTQ	BX, BX
+	JEQ	equal
+	MOVBQZX	(SI), CX
+	CMPB	CX, (DI)
+	JNE	notequal
+	INCQ	SI
+	INCQ	DI
+	DECQ	BX
+	JMP	loop
+equal:
+	SETEQ	AX
+	RET
+notequal:
+	XORQ	AX, AX
+	RET
+`
+
+func regContractSigs(t *testing.T, arch Arch, src string, sigs map[string]FuncSig) (*File, map[string]FuncSig, map[string]FuncSig) {
+	t.Helper()
+	return applyRegContracts(mustParse(t, arch, src), testResolveSym("example"), sigs)
+}
+
+func wantContract(t *testing.T, sig FuncSig, args, rets []Reg) {
+	t.Helper()
+	if !sameRegs(sig.ArgRegs, args) || !sameRegs(sig.RetRegs, rets) {
+		t.Fatalf("%s: ArgRegs %v RetRegs %v, want %v and %v", sig.Name, sig.ArgRegs, sig.RetRegs, args, rets)
+	}
+	if len(sig.Args) != len(sig.ArgRegs) {
+		t.Fatalf("%s: %d args for ArgRegs %v", sig.Name, len(sig.Args), sig.ArgRegs)
+	}
+}
+
+func sameRegs(a, b []Reg) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestRegContractTailJump(t *testing.T) {
+	sigs := map[string]FuncSig{
+		"runtime.memequal": {Name: "runtime.memequal", Args: []LLVMType{Ptr, Ptr, I64}, Ret: I1},
+	}
+	_, all, derived := regContractSigs(t, ArchAMD64, amd64MemEqual, sigs)
+	if len(derived) != 1 {
+		t.Fatalf("derived = %v", derived)
+	}
+	// AX is an output, so it is passed in too.
+	wantContract(t, derived["example.memeqbody"], []Reg{AX, BX, SI, DI}, []Reg{AX})
+	if _, ok := sigs["example.memeqbody"]; ok {
+		t.Fatalf("applyRegContracts modified the caller's map")
+	}
+
+	ll, err := translateIRText(mustParse(t, ArchAMD64, amd64MemEqual), Options{Goarch: "amd64", ResolveSym: testResolveSym("example"), Sigs: sigs})
+	if err != nil {
+		t.Fatalf("translateIRText: %v", err)
+	}
+	wantIR(t, ll,
+		`define i64 @"example.memeqbody"(i64 %arg0, i64 %arg1, i64 %arg2, i64 %arg3)`,
+		`call i64 @"example.memeqbody"(i64`,
+		"ret i64 %",
+	)
+	if all["example.memeqbody"].Ret != I64 {
+		t.Fatalf("memeqbody returns %s", all["example.memeqbody"].Ret)
+	}
+}
+
+func TestRegContractCallOutputs(t *testing.T) {
+	// mul128<> hands back both halves; its caller reads DX after the call.
+	_, _, derived := regContractSigs(t, ArchAMD64, `TEXT ·MulHi(SB),NOSPLIT,$0-24
+	MOVQ	x+0(FP), AX
+	MOVQ	y+8(FP), CX
+	CALL	mul128<>(SB)
+	MOVQ	DX, ret+16(FP)
+	RET
+
+TEXT mul128<>(SB),NOSPLIT,$0-0
+	MULQ	CX
+	RET
+`, map[string]FuncSig{
+		"example.MulHi": sigWithClassicFrame("example.MulHi", []LLVMType{I64, I64}, I64),
+	})
+	// MulHi's RET falls back to AX for a result slot it has not written, so
+	// AX is handed back as well.
+	wantContract(t, derived["example.mul128"], []Reg{AX, CX, DX}, []Reg{AX, DX})
+}
+
+func TestRegContractFlags(t *testing.T) {
+	src := `TEXT ·Less(SB),NOSPLIT,$0-17
+	MOVQ	a+0(FP), AX
+	MOVQ	b+8(FP), BX
+	CALL	cmp<>(SB)
+	SETLT	ret+16(FP)
+	RET
+
+TEXT cmp<>(SB),NOSPLIT,$0-0
+	CMPQ	AX, BX
+	RET
+`
+	sigs := map[string]FuncSig{
+		"example.Less": sigWithClassicFrame("example.Less", []LLVMType{I64, I64}, I8),
+	}
+	_, _, derived := regContractSigs(t, ArchAMD64, src, sigs)
+	wantContract(t, derived["example.cmp"], []Reg{AX, BX, FLAGS}, []Reg{FLAGS})
+
+	ll, err := translateIRText(mustParse(t, ArchAMD64, src), Options{Goarch: "amd64", ResolveSym: testResolveSym("example"), Sigs: sigs})
+	if err != nil {
+		t.Fatalf("translateIRText: %v", err)
+	}
+	wantIR(t, ll,
+		`define i64 @"example.cmp"(i64 %arg0, i64 %arg1, i64 %arg2)`,
+		`call i64 @"example.cmp"(`,
+		"lshr i64",
+	)
+}
+
+func TestRegContractFallthrough(t *testing.T) {
+	src := `TEXT ·Inc(SB),NOSPLIT,$0-16
+	MOVQ	x+0(FP), AX
+	ADDQ	$1, AX
+
+TEXT double<>(SB),NOSPLIT,$0-0
+	ADDQ	AX, AX
+	RET
+`
+	sigs := map[string]FuncSig{
+		"example.Inc": sigWithClassicFrame("example.Inc", []LLVMType{I64}, I64),
+	}
+	out, _, derived := regContractSigs(t, ArchAMD64, src, sigs)
+	wantContract(t, derived["example.double"], []Reg{AX}, []Reg{AX})
+	inc := out.Funcs[0].Instrs
+	if last := inc[len(inc)-1]; last.Op != "JMP" || last.Args[0].Sym != "double<>(SB)" {
+		t.Fatalf("Inc ends in %q, want a jump to double<>", last.Raw)
+	}
+}
+
+func TestRegContractKeepsExplicitSigs(t *testing.T) {
+	sigs := map[string]FuncSig{
+		"runtime.memequal":  {Name: "runtime.memequal", Args: []LLVMType{Ptr, Ptr, I64}, Ret: I1},
+		"example.memeqbody": {Name: "example.memeqbody", Args: []LLVMType{Ptr, Ptr, I64}, ArgRegs: []Reg{SI, DI, BX}, Ret: I1},
+	}
+	out, all, derived := regContractSigs(t, ArchAMD64, amd64MemEqual, sigs)
+	if derived != nil || all["example.memeqbody"].RetRegs != nil {
+		t.Fatalf("derived %v for a file with explicit ArgRegs", derived)
+	}
+	if len(out.Funcs[0].Instrs) != len(mustParse(t, ArchAMD64, amd64MemEqual).Funcs[0].Instrs) {
+		t.Fatalf("file rewritten")
+	}
+}
+
+func TestRegContractKeepsExplicitHelperSig(t *testing.T) {
+	// A <> helper given a plain signature, as with a manual signature, is
+	// translated with it rather than a derived contract.
+	body := FuncSig{Name: "example.memeqbody", Args: []LLVMType{Ptr, Ptr, I64}, Ret: I1}
+	sigs := map[string]FuncSig{
+		"runtime.memequal":  {Name: "runtime.memequal", Args: []LLVMType{Ptr, Ptr, I64}, Ret: I1},
+		"example.memeqbody": body,
+	}
+	_, all, derived := regContractSigs(t, ArchAMD64, amd64MemEqual, sigs)
+	if _, ok := derived["example.memeqbody"]; ok {
+		t.Fatalf("derived %+v over an explicit signature", derived["example.memeqbody"])
+	}
+	if got := all["example.memeqbody"]; !reflect.DeepEqual(got, body) {
+		t.Fatalf("memeqbody<> signature = %+v, want %+v", got, body)
+	}
+}
+
+func TestRegContractVectorHelper(t *testing.T) {
+	// A helper reading X0 set up by its caller keeps its signature.
+	_, _, derived := regContractSigs(t, ArchAMD64, `TEXT ·Splat(SB),NOSPLIT,$0-0
+	MOVQ	$1, AX
+	MOVQ	AX, X0
+	JMP	splat<>(SB)
+
+TEXT splat<>(SB),NOSPLIT,$0-0
+	PSHUFD	$0, X0, X1
+	RET
+`, map[string]FuncSig{"example.Splat": {Name: "example.Splat", Ret: Void}})
+	if _, ok := derived["example.splat"]; ok {
+		t.Fatalf("derived %v for a helper reading X0", derived["example.splat"])
+	}
+}
+
+// arm64IndexByte is IndexByte jumping to indexbytebody<> the way
+// indexbyte_arm64.s does, with a byte loop body.
+const arm64IndexByte = `TEXT ·IndexByte(SB),NOSPLIT,$0-40
+	MOVD	b_base+0(FP), R0
+	MOVD	b_len+8(FP), R2
+	MOVBU	c+24(FP), R1
+	MOVD	$ret+32(FP), R8
+	B	indexbytebody<>(SB)
+
+TEXT indexbytebody<>(SB),NOSPLIT,$0
+	MOVD	R0, R9
+	ADD	R0, R2, R3
+loop:
+	CMP	R0, R3
+	BEQ	notfound
+	MOVBU.P	1(R0), R4
+	CMP	R4, R1
+	BNE	loop
+	SUB	$1, R0
+	SUB	R9, R0, R0
+	MOVD	R0, (R8)
+	RET
+notfound:
+	MOVD	$-1, R0
+	MOVD	R0, (R8)
+	RET
+`
+
+func TestRegContractARM64(t *testing.T) {
+	sigs := map[string]FuncSig{
+		"example.IndexByte": sigWithClassicFrame("example.IndexByte", []LLVMType{LLVMType("{ ptr, i64, i64 }"), I8}, I64),
+	}
+	_, _, derived := regContractSigs(t, ArchARM64, arm64IndexByte, sigs)
+	// IndexByte's RET reads R0 when ret+32(FP) is not written directly.
+	wantContract(t, derived["example.indexbytebody"], []Reg{"R0", "R1", "R2", "R8"}, []Reg{"R0"})
+
+	file := mustParse(t, ArchARM64, arm64IndexByte)
+	ll, err := translateIRText(file, Options{Goarch: "arm64", ResolveSym: testResolveSym("example"), Sigs: sigs})
+	if err != nil {
+		t.Fatalf("translateIRText: %v", err)
+	}
+	wantIR(t, ll,
+		`define i64 @"example.indexbytebody"(i64 %arg0, i64 %arg1, i64 %arg2, i64 %arg3)`,
+		`call i64 @"example.indexbytebody"(i64`,
+	)
+}
+
+func TestRegContractARM64Flags(t *testing.T) {
+	_, _, derived := regContractSigs(t, ArchARM64, `TEXT ·Max(SB),NOSPLIT,$0-24
+	MOVD	a+0(FP), R0
+	MOVD	b+8(FP), R1
+	BL	cmp<>(SB)
+	CSEL	GT, R0, R1, R0
+	MOVD	R0, ret+16(FP)
+	RET
+
+TEXT cmp<>(SB),NOSPLIT,$0
+	CMP	R1, R0
+	RET
+`, map[string]FuncSig{
+		"example.Max": sigWithClassicFrame("example.Max", []LLVMType{I64, I64}, I64),
+	})
+	wantContract(t, derived["example.cmp"], []Reg{"R0", "R1", FLAGS}, []Reg{FLAGS})
+}
+
+func TestRegContractCompile(t *testing.T) {
+	llc, _, ok := findLlcAndClang(t)
+	if !ok {
+		t.Skip("llc not found")
+	}
+	for _, tc := range []struct {
+		arch   Arch
+		goarch string
+		triple string
+		src    string
+		sigs   map[string]FuncSig
+	}{
+		{ArchAMD64, "amd64", "x86_64-unknown-linux-gnu", amd64MemEqual, map[string]FuncSig{
+			"runtime.memequal": {Name: "runtime.memequal", Args: []LLVMType{Ptr, Ptr, I64}, Ret: I1},
+		}},
+		{ArchARM64, "arm64", "aarch64-unknown-linux-gnu", arm64IndexByte, map[string]FuncSig{
+			"example.IndexByte": sigWithClassicFrame("example.IndexByte", []LLVMType{LLVMType("{ ptr, i64, i64 }"), I8}, I64),
+		}},
+	} {
+		ll, err := translateIRText(mustParse(t, tc.arch, tc.src), Options{
+			TargetTriple: tc.triple,
+			Goarch:       tc.goarch,
+			ResolveSym:   testResolveSym("example"),
+			Sigs:         tc.sigs,
+		})
+		if err != nil {
+			t.Fatalf("%s: translateIRText: %v", tc.goarch, err)
+		}
+		dir := t.TempDir()
+		llPath := filepath.Join(dir, "contract.ll")
+		if err := os.WriteFile(llPath, []byte(ll), 0644); err != nil {
+			t.Fatal(err)
+		}
+		out, err := exec.Command(llc, "-mtriple="+tc.triple, "-filetype=obj", llPath, "-o", filepath.Join(dir, "contract.o")).CombinedOutput()
+		if err != nil {
+			t.Fatalf("%s: llc: %v\n%s\n%s", tc.goarch, err, out, ll)
+		}
+	}
+}
+
+func mustParse(t *testing.T, arch Arch, src string) *File {
+	t.Helper()
+	file, err := Parse(arch, src)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	return file
+}
@@ -0,0 +1,307 @@
+package plan9asm
+
+import "fmt"
+
+// riscv64ALUOp describes a two-source integer operation. w32 operations
+// compute on the low words and sign-extend the result; imm tells whether
+// the first source may (immOK) or must (immOnly) be an immediate.
+type riscv64ALUOp struct {
+	kind    string
+	w32     bool
+	immOK   bool
+	immOnly bool
+}
+
+var riscv64ALUOps = func() map[string]riscv64ALUOp {
+	m := map[string]riscv64ALUOp{}
+	add := func(kind string, w32 bool, reg, imm string) {
+		if reg != "" {
+			m[reg] = riscv64ALUOp{kind: kind, w32: w32, immOK: imm != ""}
+		}
+		if imm != "" {
+			m[imm] = riscv64ALUOp{kind: kind, w32: w32, immOK: true, immOnly: true}
+		}
+	}
+	add("add", false, "ADD", "ADDI")
+	add("add", true, "ADDW", "ADDIW")
+	add("sub", false, "SUB", "")
+	add("sub", true, "SUBW", "")
+	add("and", false, "AND", "ANDI")
+	add("or", false, "OR", "ORI")
+	add("xor", false, "XOR", "XORI")
+	add("shl", false, "SLL", "SLLI")
+	add("shl", true, "SLLW", "SLLIW")
+	add("lshr", false, "SRL", "SRLI")
+	add("lshr", true, "SRLW", "SRLIW")
+	add("ashr", false, "SRA", "SRAI")
+	add("ashr", true, "SRAW", "SRAIW")
+	add("slt", false, "SLT", "SLTI")
+	add("sltu", false, "SLTU", "SLTIU")
+	add("mul", false, "MUL", "")
+	add("mul", true, "MULW", "")
+	add("div", false, "DIV", "")
+	add("div", true, "DIVW", "")
+	add("divu", false, "DIVU", "")
+	add("divu", true, "DIVUW", "")
+	add("rem", false, "REM", "")
+	add("rem", true, "REMW", "")
+	add("remu", false, "REMU", "")
+	add("remu", true, "REMUW", "")
+	add("mulh", false, "MULH", "")
+	add("mulhu", false, "MULHU", "")
+	add("mulhsu", false, "MULHSU", "")
+	// Zba, Zbb and Zbs. Go assembles them natively or synthesizes them
+	// depending on GORISCV64, so their use does not imply a CPU feature.
+	add("andn", false, "ANDN", "")
+	add("orn", false, "ORN", "")
+	add("xnor", false, "XNOR", "")
+	add("min", false, "MIN", "")
+	add("max", false, "MAX", "")
+	add("minu", false, "MINU", "")
+	add("maxu", false, "MAXU", "")
+	add("rol", false, "ROL", "")
+	add("rol", true, "ROLW", "")
+	add("ror", false, "ROR", "RORI")
+	add("ror", true, "RORW", "RORIW")
+	add("bclr", false, "BCLR", "BCLRI")
+	add("bset", false, "BSET", "BSETI")
+	add("binv", false, "BINV", "BINVI")
+	add("bext", false, "BEXT", "BEXTI")
+	add("sh1add", false, "SH1ADD", "")
+	add("sh2add", false, "SH2ADD", "")
+	add("sh3add", false, "SH3ADD", "")
+	add("adduw", false, "ADDUW", "")
+	add("sh1adduw", false, "SH1ADDUW", "")
+	add("sh2adduw", false, "SH2ADDUW", "")
+	add("sh3adduw", false, "SH3ADDUW", "")
+	add("slliuw", false, "", "SLLIUW")
+	// The assembler rewrites SUB $imm into ADDI $-imm.
+	for _, op := range []string{"SUB", "SUBW"} {
+		alu := m[op]
+		alu.immOK = true
+		m[op] = alu
+	}
+	return m
+}()
+
+func (c *riscv64Ctx) lowerArith(op string, ins Instr) (ok bool, terminated bool, err error) {
+	if alu, found := riscv64ALUOps[op]; found {
+		return true, false, c.lowerALU(op, alu, ins)
+	}
+	if _, found := riscv64UnaryOps[op]; found {
+		return true, false, c.lowerUnary(op, ins)
+	}
+	return false, false, nil
+}
+
+// lowerALU lowers "OP rs2, rs1, rd", which computes rd = rs1 OP rs2, and
+// the two-operand "OP rs2, rd" form, which reads rd as rs1.
+func (c *riscv64Ctx) lowerALU(op string, alu riscv64ALUOp, ins Instr) error {
+	if len(ins.Args) != 2 && len(ins.Args) != 3 {
+		return fmt.Errorf("riscv64 %s expects 2 or 3 operands: %q", op, ins.Raw)
+	}
+	src := ins.Args[0]
+	switch {
+	case src.Kind == OpImm && !alu.immOK, src.Kind != OpImm && alu.immOnly:
+		return fmt.Errorf("riscv64 %s: bad first operand %s: %q", op, src.String(), ins.Raw)
+	case src.Kind != OpImm && !riscv64IsXReg(src):
+		return fmt.Errorf("riscv64 %s expects a register or immediate source: %q", op, ins.Raw)
+	}
+	for _, a := range ins.Args[1:] {
+		if !riscv64IsXReg(a) {
+			return fmt.Errorf("riscv64 %s expects X registers: %q", op, ins.Raw)
+		}
+	}
+	dst := ins.Args[len(ins.Args)-1].Reg
+	rs2, err := c.eval64(src)
+	if err != nil {
+		return err
+	}
+	rs1, err := c.loadReg(ins.Args[1].Reg)
+	if err != nil {
+		return err
+	}
+	ty := "i64"
+	if alu.w32 {
+		ty = "i32"
+		rs1, rs2 = c.trunc32(rs1), c.trunc32(rs2)
+	}
+	v := c.aluValue(alu.kind, ty, rs1, rs2)
+	if alu.w32 {
+		v = c.extend(v, 32, true)
+	}
+	return c.storeReg(dst, v)
+}
+
+func (c *riscv64Ctx) trunc32(v string) string {
+	t := c.newTmp()
+	fmt.Fprintf(c.b, "  %%%s = trunc i64 %s to i32\n", t, v)
+	return "%" + t
+}
+
+func (c *riscv64Ctx) emit(format string, args ...any) string {
+	t := c.newTmp()
+	fmt.Fprintf(c.b, "  %%%s = "+format+"\n", append([]any{t}, args...)...)
+	return "%" + t
+}
+
+// aluValue computes a OP b in ty following the RISC-V semantics: shift
+// amounts are taken modulo the width and division never traps.
+func (c *riscv64Ctx) aluValue(kind, ty, a, b string) string {
+	bits := 64
+	if ty == "i32" {
+		bits = 32
+	}
+	shamt := func() string { return c.emit("and %s %s, %d", ty, b, bits-1) }
+	bit := func() string { return c.emit("shl i64 1, %s", shamt()) }
+	switch kind {
+	case "add", "sub", "and", "or", "xor", "mul":
+		return c.emit("%s %s %s, %s", kind, ty, a, b)
+	case "shl", "lshr", "ashr":
+		return c.emit("%s %s %s, %s", kind, ty, a, shamt())
+	case "slt", "sltu":
+		pred := "slt"
+		if kind == "sltu" {
+			pred = "ult"
+		}
+		cmp := c.emit("icmp %s i64 %s, %s", pred, a, b)
+		return c.emit("zext i1 %s to i64", cmp)
+	case "mulh", "mulhu", "mulhsu":
+		extA, extB := "sext", "sext"
+		if kind == "mulhu" {
+			extA, extB = "zext", "zext"
+		} else if kind == "mulhsu" {
+			extB = "zext"
+		}
+		wa := c.emit("%s i64 %s to i128", extA, a)
+		wb := c.emit("%s i64 %s to i128", extB, b)
+		p := c.emit("mul i128 %s, %s", wa, wb)
+		hi := c.emit("lshr i128 %s, 64", p)
+		return c.emit("trunc i128 %s to i64", hi)
+	case "div", "divu", "rem", "remu":
+		// x/0 is all ones and x%0 is x; the signed overflow MIN/-1 gives
+		// MIN and MIN%-1 gives 0, which dividing by 1 produces.
+		zero := c.emit("icmp eq %s %s, 0", ty, b)
+		bad := zero
+		if kind == "div" || kind == "rem" {
+			isMin := c.emit("icmp eq %s %s, %d", ty, a, int64(-1)<<(bits-1))
+			isNeg1 := c.emit("icmp eq %s %s, -1", ty, b)
+			ov := c.emit("and i1 %s, %s", isMin, isNeg1)
+			bad = c.emit("or i1 %s, %s", zero, ov)
+		}
+		safe := c.emit("select i1 %s, %s 1, %s %s", bad, ty, ty, b)
+		insn := map[string]string{"div": "sdiv", "divu": "udiv", "rem": "srem", "remu": "urem"}[kind]
+		q := c.emit("%s %s %s, %s", insn, ty, a, safe)
+		onZero := "-1"
+		if kind == "rem" || kind == "remu" {
+			onZero = a
+		}
+		return c.emit("select i1 %s, %s %s, %s %s", zero, ty, onZero, ty, q)
+	case "andn", "orn":
+		nb := c.emit("xor i64 %s, -1", b)
+		return c.emit("%s i64 %s, %s", kind[:len(kind)-1], a, nb)
+	case "xnor":
+		x := c.emit("xor i64 %s, %s", a, b)
+		return c.emit("xor i64 %s, -1", x)
+	case "min", "max", "minu", "maxu":
+		pred := map[string]string{"min": "slt", "max": "sgt", "minu": "ult", "maxu": "ugt"}[kind]
+		cmp := c.emit("icmp %s i64 %s, %s", pred, a, b)
+		return c.emit("select i1 %s, i64 %s, i64 %s", cmp, a, b)
+	case "rol", "ror":
+		fn := "fshl"
+		if kind == "ror" {
+			fn = "fshr"
+		}
+		return c.emit("call %s @llvm.%s.%s(%s %s, %s %s, %s %s)", ty, fn, ty, ty, a, ty, a, ty, b)
+	case "bclr":
+		nb := c.emit("xor i64 %s, -1", bit())
+		return c.emit("and i64 %s, %s", a, nb)
+	case "bset":
+		return c.emit("or i64 %s, %s", a, bit())
+	case "binv":
+		return c.emit("xor i64 %s, %s", a, bit())
+	case "bext":
+		sh := c.emit("lshr i64 %s, %s", a, shamt())
+		return c.emit("and i64 %s, 1", sh)
+	case "sh1add", "sh2add", "sh3add":
+		sh := c.emit("shl i64 %s, %c", a, kind[2])
+		return c.emit("add i64 %s, %s", b, sh)
+	case "adduw", "sh1adduw", "sh2adduw", "sh3adduw":
+		u := c.narrow(a, 32, false)
+		if kind != "adduw" {
+			u = c.emit("shl i64 %s, %c", u, kind[2])
+		}
+		return c.emit("add i64 %s, %s", b, u)
+	case "slliuw":
+		u := c.narrow(a, 32, false)
+		return c.emit("shl i64 %s, %s", u, shamt())
+	}
+	panic("riscv64: unknown ALU kind " + kind)
+}
+
+// riscv64UnaryOps lists the "OP rs, rd" operations; NEG, NEGW and NOT may
+// also name a single register, which is both source and destination.
+var riscv64UnaryOps = map[string]bool{
+	"NEG": true, "NEGW": true, "NOT": true, "SEQZ": true, "SNEZ": true,
+	"CLZ": true, "CLZW": true, "CTZ": true, "CTZW": true, "CPOP": true, "CPOPW": true,
+	"SEXTB": true, "SEXTH": true, "ZEXTH": true, "REV8": true, "ORCB": true,
+}
+
+func (c *riscv64Ctx) lowerUnary(op string, ins Instr) error {
+	oneReg := op == "NEG" || op == "NEGW" || op == "NOT"
+	if !(len(ins.Args) == 2 || len(ins.Args) == 1 && oneReg) {
+		return fmt.Errorf("riscv64 %s expects reg, reg: %q", op, ins.Raw)
+	}
+	for _, a := range ins.Args {
+		if !riscv64IsXReg(a) {
+			return fmt.Errorf("riscv64 %s expects X registers: %q", op, ins.Raw)
+		}
+	}
+	a, err := c.loadReg(ins.Args[0].Reg)
+	if err != nil {
+		return err
+	}
+	var v string
+	switch op {
+	case "NEG":
+		v = c.emit("sub i64 0, %s", a)
+	case "NEGW":
+		v = c.extend(c.emit("sub i32 0, %s", c.trunc32(a)), 32, true)
+	case "NOT":
+		v = c.emit("xor i64 %s, -1", a)
+	case "SEQZ", "SNEZ":
+		pred := "eq"
+		if op == "SNEZ" {
+			pred = "ne"
+		}
+		v = c.emit("zext i1 %s to i64", c.emit("icmp %s i64 %s, 0", pred, a))
+	case "CLZ", "CTZ", "CPOP":
+		v = c.bitCount(op, "i64", a)
+	case "CLZW", "CTZW", "CPOPW":
+		v = c.extend(c.bitCount(op[:len(op)-1], "i32", c.trunc32(a)), 32, false)
+	case "SEXTB":
+		v = c.narrow(a, 8, true)
+	case "SEXTH":
+		v = c.narrow(a, 16, true)
+	case "ZEXTH":
+		v = c.narrow(a, 16, false)
+	case "REV8":
+		v = c.emit("call i64 @llvm.bswap.i64(i64 %s)", a)
+	case "ORCB":
+		bytes := c.emit("bitcast i64 %s to <8 x i8>", a)
+		nz := c.emit("icmp ne <8 x i8> %s, zeroinitializer", bytes)
+		ones := c.emit("sext <8 x i1> %s to <8 x i8>", nz)
+		v = c.emit("bitcast <8 x i8> %s to i64", ones)
+	}
+	return c.storeReg(ins.Args[len(ins.Args)-1].Reg, v)
+}
+
+func (c *riscv64Ctx) bitCount(op, ty, a string) string {
+	switch op {
+	case "CLZ":
+		return c.emit("call %s @llvm.ctlz.%s(%s %s, i1 false)", ty, ty, ty, a)
+	case "CTZ":
+		return c.emit("call %s @llvm.cttz.%s(%s %s, i1 false)", ty, ty, ty, a)
+	}
+	return c.emit("call %s @llvm.ctpop.%s(%s %s)", ty, ty, ty, a)
+}
@@ -0,0 +1,139 @@
+package plan9asm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// riscv64AMOOps maps the AMO<op>W/D base names to atomicrmw operations.
+var riscv64AMOOps = map[string]string{
+	"AMOSWAP": "xchg",
+	"AMOADD":  "add",
+	"AMOAND":  "and",
+	"AMOOR":   "or",
+	"AMOXOR":  "xor",
+	"AMOMAX":  "max",
+	"AMOMAXU": "umax",
+	"AMOMIN":  "min",
+	"AMOMINU": "umin",
+}
+
+// riscv64AtomicWidth splits the W or D width suffix off an atomic opcode.
+func riscv64AtomicWidth(op string) (base string, ty string, ok bool) {
+	switch {
+	case strings.HasSuffix(op, "W"):
+		return op[:len(op)-1], "i32", true
+	case strings.HasSuffix(op, "D"):
+		return op[:len(op)-1], "i64", true
+	}
+	return "", "", false
+}
+
+func (c *riscv64Ctx) lowerAtomic(op string, ins Instr) (ok bool, terminated bool, err error) {
+	switch op {
+	case "FENCE":
+		// FENCE, FENCE.TSO and FENCE with explicit sets all order at least
+		// as much as Go relies on.
+		c.b.WriteString("  fence seq_cst\n")
+		return true, false, nil
+	}
+	base, ty, ok := riscv64AtomicWidth(op)
+	if !ok {
+		return false, false, nil
+	}
+	rmw, isAMO := riscv64AMOOps[base]
+	if !isAMO && base != "LR" && base != "SC" {
+		return false, false, nil
+	}
+	bits := 64
+	if ty == "i32" {
+		bits = 32
+	}
+
+	switch {
+	case base == "LR":
+		// LRW (addr), rd loads and reserves addr.
+		if len(ins.Args) != 2 || ins.Args[0].Kind != OpMem || !riscv64IsXReg(ins.Args[1]) {
+			return true, false, fmt.Errorf("riscv64 %s expects (reg), reg: %q", op, ins.Raw)
+		}
+		ptr, err := c.memPtr(ins.Args[0].Mem)
+		if err != nil {
+			return true, false, err
+		}
+		v := c.emit("load atomic %s, ptr %s seq_cst, align %d", ty, ptr, bits/8)
+		if bits == 32 {
+			v = c.extend(v, 32, true)
+		}
+		fmt.Fprintf(c.b, "  store i1 true, ptr %s\n", c.reservedValidSlot)
+		fmt.Fprintf(c.b, "  store ptr %s, ptr %s\n", ptr, c.reservedPtrSlot)
+		fmt.Fprintf(c.b, "  store i64 %s, ptr %s\n", v, c.reservedValueSlot)
+		return true, false, c.storeReg(ins.Args[1].Reg, v)
+
+	case base == "SC":
+		// SCW rs2, (addr), rd stores rs2 if the reservation still holds and
+		// sets rd to 0 on success. The reservation is modeled as the value
+		// LR loaded, so the store is a cmpxchg against it.
+		if len(ins.Args) != 3 || !riscv64IsXReg(ins.Args[0]) || ins.Args[1].Kind != OpMem || !riscv64IsXReg(ins.Args[2]) {
+			return true, false, fmt.Errorf("riscv64 %s expects reg, (reg), reg: %q", op, ins.Raw)
+		}
+		src, err := c.loadReg(ins.Args[0].Reg)
+		if err != nil {
+			return true, false, err
+		}
+		ptr, err := c.memPtr(ins.Args[1].Mem)
+		if err != nil {
+			return true, false, err
+		}
+		valid := c.emit("load i1, ptr %s", c.reservedValidSlot)
+		resPtr := c.emit("load ptr, ptr %s", c.reservedPtrSlot)
+		same := c.emit("icmp eq ptr %s, %s", resPtr, ptr)
+		canTry := c.emit("and i1 %s, %s", valid, same)
+
+		id := c.newTmp()
+		tryLabel := "sc_try_" + id
+		failLabel := "sc_fail_" + id
+		mergeLabel := "sc_merge_" + id
+		fmt.Fprintf(c.b, "  br i1 %s, label %%%s, label %%%s\n", canTry, tryLabel, failLabel)
+
+		fmt.Fprintf(c.b, "\n%s:\n", tryLabel)
+		expected := c.emit("load i64, ptr %s", c.reservedValueSlot)
+		newv := src
+		if bits == 32 {
+			expected, newv = c.trunc32(expected), c.trunc32(src)
+		}
+		cx := c.emit("cmpxchg ptr %s, %s %s, %s %s seq_cst seq_cst, align %d", ptr, ty, expected, ty, newv, bits/8)
+		stored := c.emit("extractvalue {%s, i1} %s, 1", ty, cx)
+		failed := c.emit("xor i1 %s, true", stored)
+		tryStatus := c.emit("zext i1 %s to i64", failed)
+		fmt.Fprintf(c.b, "  br label %%%s\n", mergeLabel)
+
+		fmt.Fprintf(c.b, "\n%s:\n", failLabel)
+		fmt.Fprintf(c.b, "  br label %%%s\n", mergeLabel)
+
+		fmt.Fprintf(c.b, "\n%s:\n", mergeLabel)
+		status := c.emit("phi i64 [ %s, %%%s ], [ 1, %%%s ]", tryStatus, tryLabel, failLabel)
+		fmt.Fprintf(c.b, "  store i1 false, ptr %s\n", c.reservedValidSlot)
+		return true, false, c.storeReg(ins.Args[2].Reg, status)
+	}
+
+	// AMOADDW rs2, (addr), rd: rd = old value, memory = old OP rs2.
+	if len(ins.Args) != 3 || !riscv64IsXReg(ins.Args[0]) || ins.Args[1].Kind != OpMem || !riscv64IsXReg(ins.Args[2]) {
+		return true, false, fmt.Errorf("riscv64 %s expects reg, (reg), reg: %q", op, ins.Raw)
+	}
+	src, err := c.loadReg(ins.Args[0].Reg)
+	if err != nil {
+		return true, false, err
+	}
+	ptr, err := c.memPtr(ins.Args[1].Mem)
+	if err != nil {
+		return true, false, err
+	}
+	if bits == 32 {
+		src = c.trunc32(src)
+	}
+	old := c.emit("atomicrmw %s ptr %s, %s %s seq_cst, align %d", rmw, ptr, ty, src, bits/8)
+	if bits == 32 {
+		old = c.extend(old, 32, true)
+	}
+	return true, false, c.storeReg(ins.Args[2].Reg, old)
+}
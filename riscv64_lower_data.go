@@ -0,0 +1,160 @@
+package plan9asm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// riscv64MovWidth gives the access width and signedness of the integer MOV
+// forms; MOVF and MOVD move F register bits.
+var riscv64MovWidth = map[string]struct {
+	bits   int
+	signed bool
+	float  bool
+}{
+	"MOV":   {64, true, false},
+	"MOVW":  {32, true, false},
+	"MOVWU": {32, false, false},
+	"MOVH":  {16, true, false},
+	"MOVHU": {16, false, false},
+	"MOVB":  {8, true, false},
+	"MOVBU": {8, false, false},
+	"MOVF":  {32, false, true},
+	"MOVD":  {64, false, true},
+}
+
+func (c *riscv64Ctx) lowerData(op string, ins Instr) (ok bool, terminated bool, err error) {
+	switch op {
+	case "LUI":
+		if len(ins.Args) != 2 || ins.Args[0].Kind != OpImm || !riscv64IsXReg(ins.Args[1]) {
+			return true, false, fmt.Errorf("riscv64 LUI expects $imm, reg: %q", ins.Raw)
+		}
+		v := int64(int32(uint32(ins.Args[0].Imm) << 12))
+		return true, false, c.storeReg(ins.Args[1].Reg, fmt.Sprintf("%d", v))
+	}
+
+	w, found := riscv64MovWidth[op]
+	if !found {
+		return false, false, nil
+	}
+	if len(ins.Args) != 2 {
+		return true, false, fmt.Errorf("riscv64 %s expects 2 operands: %q", op, ins.Raw)
+	}
+	src, dst := ins.Args[0], ins.Args[1]
+	// Integer moves never name an F register. MOVF and MOVD need one on at
+	// least one side; the other may be an X register only in a register to
+	// register move (FMV.X.W/FMV.W.X and their D forms).
+	isF := func(o Operand) bool { return o.Kind == OpReg && riscv64IsFReg(o.Reg) }
+	signed := w.signed
+	if w.float {
+		xToF := riscv64IsXReg(src) && isF(dst)
+		fToX := isF(src) && riscv64IsXReg(dst)
+		if (!isF(src) && !isF(dst)) || src.Kind == OpImm ||
+			((riscv64IsXReg(src) || riscv64IsXReg(dst)) && !xToF && !fToX) {
+			return true, false, fmt.Errorf("riscv64 %s moves between F registers and memory or X registers: %q", op, ins.Raw)
+		}
+		// FMV.X.W sign-extends the single's bits.
+		signed = fToX
+	} else if isF(src) || isF(dst) {
+		return true, false, fmt.Errorf("riscv64 %s cannot move F registers: %q", op, ins.Raw)
+	}
+	if src.Kind != OpReg && dst.Kind != OpReg {
+		// Only zero can be stored without a register.
+		if src.Kind != OpImm || src.Imm != 0 {
+			return true, false, fmt.Errorf("riscv64 %s needs a register operand: %q", op, ins.Raw)
+		}
+	}
+
+	v, err := c.movSrc(src, w.bits, signed)
+	if err != nil {
+		return true, false, fmt.Errorf("riscv64 %s: %v: %q", op, err, ins.Raw)
+	}
+	if err := c.movDst(dst, w.bits, v); err != nil {
+		return true, false, fmt.Errorf("riscv64 %s: %v: %q", op, err, ins.Raw)
+	}
+	return true, false, nil
+}
+
+func riscv64IsXReg(o Operand) bool {
+	return o.Kind == OpReg && (o.Reg == SP || strings.HasPrefix(string(o.Reg), "X"))
+}
+
+// movSrc reads a MOV source as an i64, extended from bits.
+func (c *riscv64Ctx) movSrc(src Operand, bits int, signed bool) (string, error) {
+	switch src.Kind {
+	case OpImm:
+		v := src.Imm
+		switch {
+		case bits == 64:
+		case signed:
+			v = v << (64 - bits) >> (64 - bits)
+		default:
+			v = int64(uint64(v) << (64 - bits) >> (64 - bits))
+		}
+		return fmt.Sprintf("%d", v), nil
+	case OpReg:
+		v, err := c.loadReg(src.Reg)
+		if err != nil {
+			return "", err
+		}
+		return c.narrow(v, bits, signed), nil
+	case OpMem:
+		return c.loadMem(src.Mem, bits, signed)
+	case OpFP:
+		v, err := c.evalFPValue64(src)
+		if err != nil {
+			return "", err
+		}
+		return c.narrow(v, bits, signed), nil
+	case OpFPAddr:
+		return c.evalFPAddr64(src)
+	case OpSym:
+		s := strings.TrimSpace(src.Sym)
+		if strings.HasPrefix(s, "$") && (strings.HasSuffix(s, "(SB)") || strings.HasSuffix(s, "(FP)")) {
+			return c.symAddr(s)
+		}
+		if !strings.HasSuffix(s, "(SB)") {
+			break
+		}
+		p, err := c.ptrFromSB(s)
+		if err != nil {
+			return "", err
+		}
+		t := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = load i%d, ptr %s\n", t, bits, p)
+		if bits == 64 {
+			return "%" + t, nil
+		}
+		return c.extend("%"+t, bits, signed), nil
+	}
+	return "", fmt.Errorf("unsupported source %s", src.String())
+}
+
+// movDst writes the low bits of v to a MOV destination.
+func (c *riscv64Ctx) movDst(dst Operand, bits int, v string) error {
+	switch dst.Kind {
+	case OpReg:
+		return c.storeReg(dst.Reg, v)
+	case OpMem:
+		return c.storeMem(dst.Mem, bits, v)
+	case OpFP:
+		return c.storeFPResult64(dst.FPOffset, v)
+	case OpSym:
+		s := strings.TrimSpace(dst.Sym)
+		if strings.HasPrefix(s, "$") || !strings.HasSuffix(s, "(SB)") {
+			break
+		}
+		p, err := c.ptrFromSB(s)
+		if err != nil {
+			return err
+		}
+		if bits < 64 {
+			t := c.newTmp()
+			fmt.Fprintf(c.b, "  %%%s = trunc i64 %s to i%d\n", t, v, bits)
+			v = "%" + t
+		}
+		fmt.Fprintf(c.b, "  store i%d %s, ptr %s\n", bits, v, p)
+		return nil
+	}
+	return fmt.Errorf("unsupported destination %s", dst.String())
+}
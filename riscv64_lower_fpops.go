@@ -0,0 +1,330 @@
+package plan9asm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// riscv64FPType returns the LLVM type and intrinsic suffix for an S
+// (single) or D (double) precision opcode suffix.
+func riscv64FPType(prec byte) (ty, suffix string) {
+	if prec == 'S' {
+		return "float", "f32"
+	}
+	return "double", "f64"
+}
+
+func (c *riscv64Ctx) loadF(r Reg, ty string) (string, error) {
+	v, err := c.loadReg(r)
+	if err != nil {
+		return "", err
+	}
+	return c.i64ToValue(v, LLVMType(ty))
+}
+
+func (c *riscv64Ctx) storeF(r Reg, ty, v string) error {
+	v64, _, err := c.valueAsI64(LLVMType(ty), v)
+	if err != nil {
+		return err
+	}
+	return c.storeReg(r, v64)
+}
+
+func riscv64CheckRegs(ins Instr, kinds string) bool {
+	if len(ins.Args) != len(kinds) {
+		return false
+	}
+	for i, a := range ins.Args {
+		if a.Kind != OpReg {
+			return false
+		}
+		if (kinds[i] == 'F') != riscv64IsFReg(a.Reg) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *riscv64Ctx) lowerFP(op string, ins Instr) (ok bool, terminated bool, err error) {
+	if strings.HasPrefix(op, "FCVT") {
+		return true, false, c.lowerFCVT(op, ins)
+	}
+	switch op {
+	case "FMVXD", "FMVXW":
+		if !riscv64CheckRegs(ins, "FX") {
+			return true, false, fmt.Errorf("riscv64 %s expects F, X: %q", op, ins.Raw)
+		}
+		v, err := c.loadReg(ins.Args[0].Reg)
+		if err != nil {
+			return true, false, err
+		}
+		if op == "FMVXW" {
+			v = c.narrow(v, 32, true)
+		}
+		return true, false, c.storeReg(ins.Args[1].Reg, v)
+	case "FMVDX", "FMVWX":
+		if !riscv64CheckRegs(ins, "XF") {
+			return true, false, fmt.Errorf("riscv64 %s expects X, F: %q", op, ins.Raw)
+		}
+		v, err := c.loadReg(ins.Args[0].Reg)
+		if err != nil {
+			return true, false, err
+		}
+		if op == "FMVWX" {
+			v = c.narrow(v, 32, false)
+		}
+		return true, false, c.storeReg(ins.Args[1].Reg, v)
+	}
+	if len(op) < 4 || op[0] != 'F' || (op[len(op)-1] != 'D' && op[len(op)-1] != 'S') {
+		return false, false, nil
+	}
+	base, prec := op[:len(op)-1], op[len(op)-1]
+	ty, suffix := riscv64FPType(prec)
+
+	switch base {
+	case "FADD", "FSUB", "FMUL", "FDIV", "FMIN", "FMAX", "FSGNJ", "FSGNJN", "FSGNJX":
+		// "OP rs2, rs1, rd" computes rd = rs1 OP rs2; "OP rs2, rd" reads rd.
+		if !riscv64CheckRegs(ins, "FFF") && !riscv64CheckRegs(ins, "FF") {
+			return true, false, fmt.Errorf("riscv64 %s expects F registers: %q", op, ins.Raw)
+		}
+		b, err := c.loadF(ins.Args[0].Reg, ty)
+		if err != nil {
+			return true, false, err
+		}
+		a, err := c.loadF(ins.Args[1].Reg, ty)
+		if err != nil {
+			return true, false, err
+		}
+		var v string
+		switch base {
+		case "FADD", "FSUB", "FMUL", "FDIV":
+			v = c.emit("f%s %s %s, %s", strings.ToLower(base[1:]), ty, a, b)
+		case "FMIN", "FMAX":
+			v = c.emit("call %s @llvm.%snum.%s(%s %s, %s %s)", ty, strings.ToLower(base[1:]), suffix, ty, a, ty, b)
+		case "FSGNJ":
+			v = c.emit("call %s @llvm.copysign.%s(%s %s, %s %s)", ty, suffix, ty, a, ty, b)
+		case "FSGNJN":
+			nb := c.emit("fneg %s %s", ty, b)
+			v = c.emit("call %s @llvm.copysign.%s(%s %s, %s %s)", ty, suffix, ty, a, ty, nb)
+		case "FSGNJX":
+			v, err = c.signXor(ty, a, b)
+			if err != nil {
+				return true, false, err
+			}
+		}
+		return true, false, c.storeF(ins.Args[len(ins.Args)-1].Reg, ty, v)
+
+	case "FSQRT", "FABS", "FNEG":
+		if !riscv64CheckRegs(ins, "FF") {
+			return true, false, fmt.Errorf("riscv64 %s expects F, F: %q", op, ins.Raw)
+		}
+		a, err := c.loadF(ins.Args[0].Reg, ty)
+		if err != nil {
+			return true, false, err
+		}
+		var v string
+		switch base {
+		case "FNEG":
+			v = c.emit("fneg %s %s", ty, a)
+		case "FABS":
+			v = c.emit("call %s @llvm.fabs.%s(%s %s)", ty, suffix, ty, a)
+		default:
+			v = c.emit("call %s @llvm.sqrt.%s(%s %s)", ty, suffix, ty, a)
+		}
+		return true, false, c.storeF(ins.Args[1].Reg, ty, v)
+
+	case "FMADD", "FMSUB", "FNMADD", "FNMSUB":
+		// "OP rs1, rs2, rs3, rd": rd = ±(rs1*rs2) ± rs3.
+		if !riscv64CheckRegs(ins, "FFFF") {
+			return true, false, fmt.Errorf("riscv64 %s expects four F registers: %q", op, ins.Raw)
+		}
+		var in [3]string
+		for i := range in {
+			if in[i], err = c.loadF(ins.Args[i].Reg, ty); err != nil {
+				return true, false, err
+			}
+		}
+		if base == "FNMADD" || base == "FNMSUB" {
+			in[0] = c.emit("fneg %s %s", ty, in[0])
+		}
+		if base == "FMSUB" || base == "FNMADD" {
+			in[2] = c.emit("fneg %s %s", ty, in[2])
+		}
+		v := c.emit("call %s @llvm.fma.%s(%s %s, %s %s, %s %s)", ty, suffix, ty, in[0], ty, in[1], ty, in[2])
+		return true, false, c.storeF(ins.Args[3].Reg, ty, v)
+
+	case "FEQ", "FLT", "FLE", "FNE":
+		// "FLTD rs2, rs1, rd" sets rd = rs1 < rs2.
+		if !riscv64CheckRegs(ins, "FFX") {
+			return true, false, fmt.Errorf("riscv64 %s expects F, F, X: %q", op, ins.Raw)
+		}
+		b, err := c.loadF(ins.Args[0].Reg, ty)
+		if err != nil {
+			return true, false, err
+		}
+		a, err := c.loadF(ins.Args[1].Reg, ty)
+		if err != nil {
+			return true, false, err
+		}
+		pred := map[string]string{"FEQ": "oeq", "FLT": "olt", "FLE": "ole", "FNE": "une"}[base]
+		cmp := c.emit("fcmp %s %s %s, %s", pred, ty, a, b)
+		return true, false, c.storeReg(ins.Args[2].Reg, c.emit("zext i1 %s to i64", cmp))
+
+	case "FCLASS":
+		if !riscv64CheckRegs(ins, "FX") {
+			return true, false, fmt.Errorf("riscv64 %s expects F, X: %q", op, ins.Raw)
+		}
+		v, err := c.loadReg(ins.Args[0].Reg)
+		if err != nil {
+			return true, false, err
+		}
+		return true, false, c.storeReg(ins.Args[1].Reg, c.fclass(prec, v))
+	}
+	return false, false, nil
+}
+
+// signXor returns a with its sign bit xored with b's.
+func (c *riscv64Ctx) signXor(ty, a, b string) (string, error) {
+	ity := "i64"
+	if ty == "float" {
+		ity = "i32"
+	}
+	ia := c.emit("bitcast %s %s to %s", ty, a, ity)
+	ib := c.emit("bitcast %s %s to %s", ty, b, ity)
+	sign := c.emit("and %s %s, %s", ity, ib, map[string]string{"i64": "-9223372036854775808", "i32": "-2147483648"}[ity])
+	x := c.emit("xor %s %s, %s", ity, ia, sign)
+	return c.emit("bitcast %s %s to %s", ity, x, ty), nil
+}
+
+// fclass computes the FCLASS bit mask from the raw bits of a value:
+// -inf, -normal, -subnormal, -0, +0, +subnormal, +normal, +inf, sNaN, qNaN.
+func (c *riscv64Ctx) fclass(prec byte, bits string) string {
+	ity, expBits, fracBits := "i64", 11, 52
+	v := bits
+	if prec == 'S' {
+		ity, expBits, fracBits = "i32", 8, 23
+		v = c.trunc32(bits)
+	}
+	sh := c.emit("lshr %s %s, %d", ity, v, fracBits)
+	exp := c.emit("and %s %s, %d", ity, sh, (1<<expBits)-1)
+	frac := c.emit("and %s %s, %d", ity, v, (int64(1)<<fracBits)-1)
+	neg := c.emit("icmp slt %s %s, 0", ity, v)
+	expZero := c.emit("icmp eq %s %s, 0", ity, exp)
+	expMax := c.emit("icmp eq %s %s, %d", ity, exp, (1<<expBits)-1)
+	fracZero := c.emit("icmp eq %s %s, 0", ity, frac)
+	quiet := c.emit("icmp uge %s %s, %d", ity, frac, int64(1)<<(fracBits-1))
+
+	// Class index: 0 inf, 1 normal, 2 subnormal, 3 zero for negative
+	// values (mirrored to 7-4 for positive ones), 8 sNaN, 9 qNaN.
+	sub := c.emit("select i1 %s, i64 3, i64 2", fracZero)
+	fin := c.emit("select i1 %s, i64 %s, i64 1", expZero, sub)
+	nan := c.emit("select i1 %s, i64 9, i64 8", quiet)
+	inf := c.emit("select i1 %s, i64 0, i64 %s", fracZero, nan)
+	idx := c.emit("select i1 %s, i64 %s, i64 %s", expMax, inf, fin)
+	mirrored := c.emit("sub i64 7, %s", idx)
+	isNaN := c.emit("icmp uge i64 %s, 8", idx)
+	pos := c.emit("xor i1 %s, true", neg)
+	flip := c.emit("and i1 %s, %s", pos, c.emit("xor i1 %s, true", isNaN))
+	cls := c.emit("select i1 %s, i64 %s, i64 %s", flip, mirrored, idx)
+	return c.emit("shl i64 1, %s", cls)
+}
+
+// lowerFCVT lowers FCVT<to><from>, where L/W are signed 64/32-bit
+// integers, LU/WU unsigned ones and S/D floats. Float to integer
+// conversions truncate unless a rounding suffix (.RNE, .RDN, .RUP, .RMM)
+// says otherwise, and saturate like the hardware: NaN gives the largest
+// value and W results are sign-extended.
+func (c *riscv64Ctx) lowerFCVT(op string, ins Instr) error {
+	spec := strings.TrimPrefix(op, "FCVT")
+	var to, from string
+	for _, k := range []string{"LU", "WU", "L", "W", "S", "D"} {
+		if strings.HasPrefix(spec, k) {
+			to, from = k, spec[len(k):]
+			break
+		}
+	}
+	isFloat := func(k string) bool { return k == "S" || k == "D" }
+	switch from {
+	case "LU", "WU", "L", "W", "S", "D":
+	default:
+		return fmt.Errorf("riscv64: unsupported instruction %s", ins.Op)
+	}
+	if to == "" || (!isFloat(to) && !isFloat(from)) || to == from {
+		return fmt.Errorf("riscv64: unsupported instruction %s", ins.Op)
+	}
+	kinds := "FF"
+	switch {
+	case !isFloat(from):
+		kinds = "XF"
+	case !isFloat(to):
+		kinds = "FX"
+	}
+	if !riscv64CheckRegs(ins, kinds) {
+		return fmt.Errorf("riscv64 %s expects %c, %c registers: %q", op, kinds[0], kinds[1], ins.Raw)
+	}
+	src, err := c.loadReg(ins.Args[0].Reg)
+	if err != nil {
+		return err
+	}
+	dst := ins.Args[1].Reg
+
+	if isFloat(from) && isFloat(to) {
+		fromTy, _ := riscv64FPType(from[0])
+		toTy, _ := riscv64FPType(to[0])
+		v, err := c.i64ToValue(src, LLVMType(fromTy))
+		if err != nil {
+			return err
+		}
+		cast := "fpext"
+		if to == "S" {
+			cast = "fptrunc"
+		}
+		return c.storeF(dst, toTy, c.emit("%s %s %s to %s", cast, fromTy, v, toTy))
+	}
+
+	if !isFloat(from) {
+		toTy, _ := riscv64FPType(to[0])
+		iv := src
+		if from[0] == 'W' {
+			iv = c.trunc32(src)
+		}
+		ity := map[byte]string{'L': "i64", 'W': "i32"}[from[0]]
+		cast := "sitofp"
+		if strings.HasSuffix(from, "U") {
+			cast = "uitofp"
+		}
+		return c.storeF(dst, toTy, c.emit("%s %s %s to %s", cast, ity, iv, toTy))
+	}
+
+	fromTy, suffix := riscv64FPType(from[0])
+	v, err := c.i64ToValue(src, LLVMType(fromTy))
+	if err != nil {
+		return err
+	}
+	rawOp := strings.ToUpper(string(ins.Op))
+	if dot := strings.IndexByte(rawOp, '.'); dot >= 0 {
+		round := map[string]string{"RNE": "roundeven", "RDN": "floor", "RUP": "ceil", "RMM": "round", "RTZ": ""}
+		fn, ok := round[rawOp[dot+1:]]
+		if !ok {
+			return fmt.Errorf("riscv64 %s: unsupported rounding mode: %q", op, ins.Raw)
+		}
+		if fn != "" {
+			v = c.emit("call %s @llvm.%s.%s(%s %s)", fromTy, fn, suffix, fromTy, v)
+		}
+	}
+	ity, max := "i64", "9223372036854775807"
+	if to[0] == 'W' {
+		ity, max = "i32", "2147483647"
+	}
+	sat := "fptosi"
+	if strings.HasSuffix(to, "U") {
+		sat, max = "fptoui", "-1"
+	}
+	r := c.emit("call %s @llvm.%s.sat.%s.%s(%s %s)", ity, sat, ity, suffix, fromTy, v)
+	isNaN := c.emit("fcmp uno %s %s, %s", fromTy, v, v)
+	r = c.emit("select i1 %s, %s %s, %s %s", isNaN, ity, max, ity, r)
+	if ity == "i32" {
+		r = c.extend(r, 32, true)
+	}
+	return c.storeReg(dst, r)
+}
@@ -0,0 +1,66 @@
+package plan9asm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// lowerInlineAsm passes an instruction without an IR lowering through to the
+// assembler (Options.InlineAsm). Plan 9 lists the destination last, so the
+// operands are reversed into GNU order and memory operands are written as
+// off(reg).
+func (c *riscv64Ctx) lowerInlineAsm(ins Instr) error {
+	a := newInlineAsmCall("~{memory}")
+	a.text(strings.ToLower(string(ins.Op)))
+	for i := len(ins.Args) - 1; i >= 0; i-- {
+		arg := ins.Args[i]
+		if i == len(ins.Args)-1 {
+			a.text(" ")
+		} else {
+			a.text(", ")
+		}
+		switch arg.Kind {
+		case OpImm:
+			a.text(fmt.Sprintf("%d", arg.Imm))
+		case OpReg:
+			r := arg.Reg
+			if r == "X0" {
+				a.text("zero")
+				continue
+			}
+			ty, cons := "i64", "r"
+			if riscv64IsFReg(r) {
+				ty, cons = "double", "f"
+			}
+			if err := a.reg(r, ty, cons, "", func() (string, error) {
+				v, err := c.loadReg(r)
+				if err != nil || ty == "i64" {
+					return v, err
+				}
+				return c.i64ToValue(v, LLVMType(ty))
+			}); err != nil {
+				return err
+			}
+		case OpMem:
+			addr, err := c.addrI64(arg.Mem)
+			if err != nil {
+				return err
+			}
+			a.text("0(")
+			a.input("i64", "r", addr)
+			a.text(")")
+		default:
+			return fmt.Errorf("riscv64: inline asm: unsupported operand %q in %q", arg.String(), ins.Raw)
+		}
+	}
+	return a.emit(c.b, c.newTmp, func(r Reg, v string) error {
+		if riscv64IsFReg(r) {
+			v64, _, err := c.valueAsI64(LLVMType("double"), v)
+			if err != nil {
+				return err
+			}
+			v = v64
+		}
+		return c.storeReg(r, v)
+	})
+}
@@ -0,0 +1,56 @@
+package plan9asm
+
+import "fmt"
+
+func (c *riscv64Ctx) lowerSyscall(op string, ins Instr) (ok bool, terminated bool, err error) {
+	switch op {
+	case "ECALL":
+		s := c.cfg.syscallSite(ArchRISCV64, c.b, c.newTmp)
+		// Linux takes the trap number in A7; FreeBSD takes it in T0 and
+		// reports failure there.
+		numReg := Reg("X17")
+		if s.conv == syscallConvBSD {
+			numReg = "X5"
+		}
+		if s.Num, err = c.loadReg(numReg); err != nil {
+			return true, false, err
+		}
+		for _, r := range []Reg{"X10", "X11", "X12", "X13", "X14", "X15"} {
+			v, err := c.loadReg(r)
+			if err != nil {
+				return true, false, err
+			}
+			s.Args = append(s.Args, v)
+		}
+		res, err := c.cfg.syscallStrategy().Lower(s)
+		if err != nil {
+			return true, false, err
+		}
+		if err := c.storeReg("X10", res.ret(s)); err != nil {
+			return true, false, err
+		}
+		if err := c.storeReg("X11", res.R2); err != nil {
+			return true, false, err
+		}
+		if s.conv == syscallConvBSD {
+			return true, false, c.storeReg("X5", c.emit("zext i1 %s to i64", res.IsErr))
+		}
+		return true, false, nil
+
+	case "EBREAK":
+		c.b.WriteString("  call void @llvm.debugtrap()\n")
+		return true, false, nil
+
+	case "UNDEF":
+		c.b.WriteString("  call void @llvm.trap()\n  unreachable\n")
+		return true, true, nil
+
+	case "RDCYCLE", "RDTIME", "RDINSTRET":
+		if len(ins.Args) != 1 || !riscv64IsXReg(ins.Args[0]) {
+			return true, false, fmt.Errorf("riscv64 %s expects reg: %q", op, ins.Raw)
+		}
+		v := c.emit("call i64 @llvm.readcyclecounter()")
+		return true, false, c.storeReg(ins.Args[0].Reg, v)
+	}
+	return false, false, nil
+}
@@ -0,0 +1,52 @@
+package plan9asm
+
+import "fmt"
+
+// riscv64RegAlias maps the ABI and Go runtime register names the riscv64
+// assembler accepts to X0-X31 and F0-F31. X2 becomes SP so both spellings
+// share one slot.
+var riscv64RegAlias = func() map[string]string {
+	m := map[string]string{
+		"ZERO": "X0", "RA": "X1", "X2": "SP", "GP": "X3", "TP": "X4",
+		"T0": "X5", "T1": "X6", "T2": "X7", "S0": "X8", "S1": "X9",
+		"T3": "X28", "T4": "X29", "T5": "X30", "T6": "X31",
+		"g": "X27", "CTXT": "X26", "TMP": "X31",
+	}
+	for i := 0; i < 8; i++ {
+		m[fmt.Sprintf("A%d", i)] = fmt.Sprintf("X%d", 10+i)
+		m[fmt.Sprintf("FT%d", i)] = fmt.Sprintf("F%d", i)
+		m[fmt.Sprintf("FA%d", i)] = fmt.Sprintf("F%d", 10+i)
+	}
+	for i := 2; i <= 10; i++ {
+		m[fmt.Sprintf("S%d", i)] = fmt.Sprintf("X%d", 16+i)
+	}
+	for i := 0; i < 12; i++ {
+		if i < 2 {
+			m[fmt.Sprintf("FS%d", i)] = fmt.Sprintf("F%d", 8+i)
+		} else {
+			m[fmt.Sprintf("FS%d", i)] = fmt.Sprintf("F%d", 16+i)
+		}
+	}
+	for i := 8; i < 12; i++ {
+		m[fmt.Sprintf("FT%d", i)] = fmt.Sprintf("F%d", 20+i)
+	}
+	return m
+}()
+
+// Go's ABIInternal assigns integer arguments and results to these registers
+// in order, and floating-point ones to the matching F registers.
+var (
+	riscv64IntArgRegs = []Reg{"X10", "X11", "X12", "X13", "X14", "X15", "X16", "X17",
+		"X8", "X9", "X18", "X19", "X20", "X21", "X22", "X23"}
+	riscv64FloatArgRegs = []Reg{"F10", "F11", "F12", "F13", "F14", "F15", "F16", "F17",
+		"F8", "F9", "F18", "F19", "F20", "F21", "F22", "F23"}
+)
+
+func riscv64IsFReg(r Reg) bool {
+	s := string(r)
+	return len(s) >= 2 && s[0] == 'F' && s[1] >= '0' && s[1] <= '9'
+}
+
+func riscv64IsFloatType(ty LLVMType) bool {
+	return ty == LLVMType("double") || ty == LLVMType("float")
+}
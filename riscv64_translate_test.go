@@ -0,0 +1,240 @@
+//go:build !llgo
+// +build !llgo
+
+package plan9asm
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func translateRISCV64(t *testing.T, src string, sigs map[string]FuncSig) string {
+	t.Helper()
+	file, err := Parse(ArchRISCV64, src)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	ll, err := translateIRText(file, Options{
+		TargetTriple: "riscv64-unknown-linux-gnu",
+		Goarch:       "riscv64",
+		ResolveSym:   testResolveSym("example"),
+		Sigs:         sigs,
+	})
+	if err != nil {
+		t.Fatalf("translateIRText: %v", err)
+	}
+	return ll
+}
+
+func wantIR(t *testing.T, ll string, wants ...string) {
+	t.Helper()
+	for _, want := range wants {
+		if !strings.Contains(ll, want) {
+			t.Fatalf("missing %q in IR:\n%s", want, ll)
+		}
+	}
+}
+
+func TestTranslateRISCV64Registers(t *testing.T) {
+	ll := translateRISCV64(t, `TEXT ·f(SB),NOSPLIT,$0-24
+	MOV a+0(FP), A0
+	MOV b+8(FP), X11
+	ADD A1, A0, T0
+	SUB $3, T0
+	ADDW A1, A0, A2
+	XOR ZERO, A2
+	DIVU A1, A0, A3
+	REM A1, A3
+	SLL $3, A3
+	ADD A2, T0
+	ADD A3, T0
+	MOV T0, ret+16(FP)
+	RET
+`, map[string]FuncSig{
+		"example.f": sigWithClassicFrame("example.f", []LLVMType{I64, I64}, I64),
+	})
+	wantIR(t, ll,
+		`define i64 @"example.f"(i64 %arg0, i64 %arg1)`,
+		"%reg_X10 = alloca i64",
+		"%reg_X5 = alloca i64",
+		"add i64",
+		"sub i64 %t4, 3",
+		"trunc i64",
+		"sext i32",
+		"udiv i64",
+		"srem i64",
+		"shl i64",
+		"ret i64",
+	)
+	if strings.Contains(ll, "%reg_A0") || strings.Contains(ll, "%reg_X0 ") {
+		t.Fatalf("ABI aliases or X0 should not get their own slots:\n%s", ll)
+	}
+}
+
+func TestTranslateRISCV64Branches(t *testing.T) {
+	ll := translateRISCV64(t, `TEXT ·sum(SB),NOSPLIT,$0-16
+	MOV n+0(FP), A0
+	MOV ZERO, A1
+	MOV $1, A2
+loop:
+	BLT A0, A2, done
+	ADD A2, A1
+	ADDI $1, A2, A2
+	JMP loop
+done:
+	BLEZ A1, 2(PC)
+	NEG A1, A1
+	MOV A1, ret+8(FP)
+	RET
+`, map[string]FuncSig{
+		"example.sum": sigWithClassicFrame("example.sum", []LLVMType{I64}, I64),
+	})
+	wantIR(t, ll, "icmp slt i64", "icmp sle i64", "br label %loop", "label %done")
+}
+
+func TestTranslateRISCV64FloatAndAtomics(t *testing.T) {
+	ll := translateRISCV64(t, `TEXT ·fma(SB),NOSPLIT,$0-32
+	MOVD a+0(FP), F0
+	MOVD b+8(FP), F1
+	MOVD c+16(FP), F2
+	FMADDD F0, F1, F2, F3
+	FLTD F0, F1, X5
+	FCVTLD.RNE F3, X6
+	ADD X5, X6
+	MOV X6, ret+24(FP)
+	RET
+
+TEXT ·cas(SB),NOSPLIT,$0-25
+	MOV ptr+0(FP), A0
+	MOV old+8(FP), A1
+	MOV new+16(FP), A2
+again:
+	LRD (A0), A3
+	BNE A3, A1, fail
+	SCD A2, (A0), A4
+	BNEZ A4, again
+	MOV $1, A0
+	MOVB A0, ret+24(FP)
+	RET
+fail:
+	MOVB ZERO, ret+24(FP)
+	RET
+
+TEXT ·xadd(SB),NOSPLIT,$0-24
+	MOV ptr+0(FP), A0
+	MOV delta+8(FP), A1
+	AMOADDW A1, (A0), A2
+	ADDW A1, A2
+	MOV A2, ret+16(FP)
+	RET
+`, map[string]FuncSig{
+		"example.fma":  sigWithClassicFrame("example.fma", []LLVMType{LLVMType("double"), LLVMType("double"), LLVMType("double")}, I64),
+		"example.cas":  sigWithClassicFrame("example.cas", []LLVMType{Ptr, I64, I64}, I1),
+		"example.xadd": sigWithClassicFrame("example.xadd", []LLVMType{Ptr, I64}, I64),
+	})
+	wantIR(t, ll,
+		"@llvm.fma.f64",
+		"fcmp olt double",
+		"@llvm.roundeven.f64",
+		"@llvm.fptosi.sat.i64.f64",
+		"cmpxchg ptr",
+		"atomicrmw add ptr",
+	)
+}
+
+func TestTranslateRISCV64Syscall(t *testing.T) {
+	src := `TEXT ·getpid(SB),NOSPLIT,$0-8
+	MOV $172, A7
+	ECALL
+	MOV A0, ret+0(FP)
+	RET
+`
+	sigs := map[string]FuncSig{
+		"example.getpid": sigWithClassicFrame("example.getpid", nil, I64),
+	}
+	wantIR(t, translateRISCV64(t, src, sigs), "call i64 @syscall(i64 ")
+
+	file, err := Parse(ArchRISCV64, src)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	ll, err := translateIRText(file, Options{
+		TargetTriple: "riscv64-unknown-linux-gnu",
+		Goarch:       "riscv64",
+		ResolveSym:   testResolveSym("example"),
+		Sigs:         sigs,
+		Syscall:      RawSyscall{},
+	})
+	if err != nil {
+		t.Fatalf("translateIRText: %v", err)
+	}
+	wantIR(t, ll, `asm sideeffect "ecall", "={x10},={x11},{x17},{x10},{x11},{x12},{x13},{x14},{x15},~{memory}"`)
+}
+
+func TestTranslateRISCV64RejectsMixedRegisters(t *testing.T) {
+	for _, tc := range []struct{ insn, want string }{
+		{"ADD F1, X5", "expects a register or immediate source"},
+		{"FADDD X5, F1, F2", "expects F registers"},
+		{"BEQ F1, X5, 2(PC)", "expects 2 registers"},
+		{"MOVQ X5, X6", "unsupported"},
+	} {
+		file, err := Parse(ArchRISCV64, "TEXT ·f(SB),NOSPLIT,$0-0\n\t"+tc.insn+"\n\tRET\n")
+		if err != nil {
+			t.Fatalf("%s: Parse: %v", tc.insn, err)
+		}
+		_, err = translateIRText(file, Options{
+			Goarch:     "riscv64",
+			ResolveSym: testResolveSym("example"),
+			Sigs:       map[string]FuncSig{"example.f": {Name: "example.f", Ret: Void}},
+		})
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: err = %v, want %q", tc.insn, err, tc.want)
+		}
+	}
+}
+
+func TestTranslateRISCV64Compile(t *testing.T) {
+	llc, _, ok := findLlcAndClang(t)
+	if !ok {
+		t.Skip("llc not found")
+	}
+	ll := translateRISCV64(t, `TEXT ·sum(SB),NOSPLIT,$0-24
+	MOV p+0(FP), A0
+	MOV n+8(FP), A1
+	MOV ZERO, A2
+loop:
+	BEQZ A1, done
+	MOVWU (A0), A3
+	ADD A3, A2
+	ADDI $4, A0
+	ADDI $-1, A1
+	JMP loop
+done:
+	MOV A2, ret+16(FP)
+	RET
+
+TEXT ·hypot(SB),NOSPLIT,$0-24
+	MOVD a+0(FP), F0
+	MOVD b+8(FP), F1
+	FMULD F0, F0, F2
+	FMADDD F1, F1, F2, F2
+	FSQRTD F2, F0
+	MOVD F0, ret+16(FP)
+	RET
+`, map[string]FuncSig{
+		"example.sum":   sigWithClassicFrame("example.sum", []LLVMType{Ptr, I64}, I64),
+		"example.hypot": sigWithClassicFrame("example.hypot", []LLVMType{LLVMType("double"), LLVMType("double")}, LLVMType("double")),
+	})
+	dir := t.TempDir()
+	llPath := filepath.Join(dir, "sum.ll")
+	if err := os.WriteFile(llPath, []byte(ll), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out, err := exec.Command(llc, "-mtriple=riscv64-unknown-linux-gnu", "-mattr=+m,+a,+f,+d,+c", "-filetype=obj", llPath, "-o", filepath.Join(dir, "sum.o")).CombinedOutput()
+	if err != nil {
+		t.Fatalf("llc: %v\n%s", err, out)
+	}
+}
@@ -339,7 +339,9 @@ func compileAndRunRuntimeTest(t *testing.T, llc, clang, name, ll, mainC string)
 		t.Fatal(err)
 	}
 
-	llcCmd := exec.Command(llc, "-mtriple="+triple, "-filetype=obj", llPath, "-o", objPath)
+	// Position-independent code links into the PIE executables most
+	// toolchains build by default.
+	llcCmd := exec.Command(llc, "-mtriple="+triple, "-relocation-model=pic", "-filetype=obj", llPath, "-o", objPath)
 	if out, err := llcCmd.CombinedOutput(); err != nil {
 		s := string(out)
 		if llcUnsupportedTarget(s) {
@@ -0,0 +1,89 @@
+package plan9asm
+
+import (
+	"fmt"
+	"strings"
+)
+
+type s390xBlock struct {
+	name   string // source label (or "entry")
+	instrs []Instr
+}
+
+// s390xIsTerminator reports whether ins ends a basic block: RET, JMP, BR
+// or a conditional branch.
+func s390xIsTerminator(ins Instr) bool {
+	if ins.Op == OpRET {
+		return true
+	}
+	op := strings.ToUpper(string(ins.Op))
+	return op == "JMP" || op == "BR" || s390xIsCondBranch(op)
+}
+
+// s390xPCRelTarget returns the instruction offset of a branch to n(PC).
+func s390xPCRelTarget(ins Instr) (off int64, ok bool) {
+	if !s390xIsTerminator(ins) || len(ins.Args) == 0 {
+		return 0, false
+	}
+	last := ins.Args[len(ins.Args)-1]
+	if last.Kind != OpMem || last.Mem.Base != PC {
+		return 0, false
+	}
+	return last.Mem.Off, true
+}
+
+func s390xSplitBlocks(fn Func) []s390xBlock {
+	blocks := []s390xBlock{{name: "entry"}}
+	cur := 0
+	anon := 0
+
+	startAnon := func() {
+		anon++
+		blocks = append(blocks, s390xBlock{name: fmt.Sprintf("anon_%d", anon)})
+		cur = len(blocks) - 1
+	}
+
+	linear := make([]Instr, 0, len(fn.Instrs))
+	for _, ins := range fn.Instrs {
+		if ins.Op == OpLABEL {
+			continue
+		}
+		linear = append(linear, ins)
+	}
+	splitAt := map[int]bool{}
+	for i, ins := range linear {
+		if off, ok := s390xPCRelTarget(ins); ok {
+			t := i + int(off)
+			if 0 <= t && t < len(linear) {
+				splitAt[t] = true
+			}
+		}
+	}
+
+	li := 0
+	for _, ins := range fn.Instrs {
+		if ins.Op == OpLABEL && len(ins.Args) == 1 && ins.Args[0].Kind == OpLabel {
+			lbl := ins.Args[0].Sym
+			if len(blocks[cur].instrs) == 0 && strings.HasPrefix(blocks[cur].name, "anon_") {
+				blocks[cur].name = lbl
+				continue
+			}
+			blocks = append(blocks, s390xBlock{name: lbl})
+			cur = len(blocks) - 1
+			continue
+		}
+		if splitAt[li] && len(blocks[cur].instrs) != 0 {
+			startAnon()
+		}
+		blocks[cur].instrs = append(blocks[cur].instrs, ins)
+		li++
+		if s390xIsTerminator(ins) {
+			startAnon()
+		}
+	}
+
+	if len(blocks) > 1 && len(blocks[len(blocks)-1].instrs) == 0 && strings.HasPrefix(blocks[len(blocks)-1].name, "anon_") {
+		blocks = blocks[:len(blocks)-1]
+	}
+	return blocks
+}
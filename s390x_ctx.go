@@ -0,0 +1,454 @@
+package plan9asm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// s390xCtx lowers one s390x TEXT body. R0-R14 and R15 (SP) live in i64
+// slots; R0 is an ordinary register, and reads as zero only when it is the
+// base or index of a memory reference. The 128-bit vector registers
+// V16-V31 live in i128 slots; V0-V15 are split into the F register of the
+// same number, which is their first doubleword, and a VLO slot holding the
+// second. F registers hold double bits, or a single in their high word,
+// as the hardware does. Vector values use the ISA's element numbering:
+// element 0 is the most significant.
+//
+// The condition code lives in the CC slot as 0-3. A branch mask selects
+// the codes it branches on, 8 for CC 0 down to 1 for CC 3.
+type s390xCtx struct {
+	b       *strings.Builder
+	sig     FuncSig
+	resolve func(string) string
+	sigs    map[string]FuncSig
+	cfg     lowerConfig
+
+	tmp int
+
+	blocks     []s390xBlock
+	blockBase  []int
+	blockByIdx map[int]int
+
+	regSlot   map[Reg]string // reg -> alloca name
+	frameSize int64
+	// argFrameSize is the size of an in-memory copy of the argument frame,
+	// made when the body takes the address of an FP slot that is not a
+	// result, or 0.
+	argFrameSize int64
+
+	fpParams       map[int64]FrameSlot // off(FP) -> slot
+	fpResults      []FrameSlot         // result slots (Index is result index)
+	fpResAllocaOff map[int64]string    // off(FP) -> alloca
+	fpResAllocaIdx map[int]string      // result index -> alloca
+	fpResWritten   map[int]bool        // result index -> direct writes to fp slot
+	fpResAddrTaken map[int]bool        // result index -> fp result slot address escaped
+}
+
+// Branch masks of the extended mnemonics.
+const (
+	s390xCCEq    = 8 // CC 0: equal, zero
+	s390xCCLt    = 4 // CC 1: low, negative
+	s390xCCGt    = 2 // CC 2: high, positive
+	s390xCCOther = 1 // CC 3: overflow, unordered
+)
+
+func newS390XCtx(b *strings.Builder, fn Func, sig FuncSig, resolve func(string) string, sigs map[string]FuncSig, cfg lowerConfig) *s390xCtx {
+	c := &s390xCtx{
+		b:              b,
+		sig:            sig,
+		resolve:        resolve,
+		sigs:           sigs,
+		cfg:            cfg,
+		blocks:         s390xSplitBlocks(fn),
+		blockByIdx:     map[int]int{},
+		regSlot:        map[Reg]string{},
+		frameSize:      textFrameSize(fn),
+		fpParams:       map[int64]FrameSlot{},
+		fpResAllocaOff: map[int64]string{},
+		fpResAllocaIdx: map[int]string{},
+		fpResWritten:   map[int]bool{},
+		fpResAddrTaken: map[int]bool{},
+	}
+	for _, s := range sig.Frame.Params {
+		c.fpParams[s.Offset] = s
+	}
+	c.fpResults = append([]FrameSlot(nil), sig.Frame.Results...)
+	c.argFrameSize = riscv64ArgFrameSize(fn, sig)
+	base := 0
+	for i, blk := range c.blocks {
+		c.blockBase = append(c.blockBase, base)
+		c.blockByIdx[base] = i
+		base += len(blk.instrs)
+	}
+	return c
+}
+
+func (c *s390xCtx) emitSourceComment(ins Instr) {
+	if !c.cfg.annotateSource {
+		return
+	}
+	emitIRSourceComment(c.b, ins.Raw)
+}
+
+func (c *s390xCtx) newTmp() string {
+	c.tmp++
+	return fmt.Sprintf("t%d", c.tmp)
+}
+
+func (c *s390xCtx) emit(format string, args ...any) string {
+	t := c.newTmp()
+	fmt.Fprintf(c.b, "  %%%s = "+format+"\n", append([]any{t}, args...)...)
+	return "%" + t
+}
+
+func (c *s390xCtx) emitEntryAllocasAndArgInit() error {
+	c.b.WriteString("entry:\n")
+	regs := []Reg{SP, "CC"}
+	for i := 0; i <= 14; i++ {
+		regs = append(regs, Reg(fmt.Sprintf("R%d", i)))
+	}
+	for i := 0; i <= 15; i++ {
+		regs = append(regs, Reg(fmt.Sprintf("F%d", i)), Reg(fmt.Sprintf("VLO%d", i)))
+	}
+	for _, r := range regs {
+		name := "%" + arm64LLVMBlockName("reg_"+string(r))
+		c.regSlot[r] = name
+		fmt.Fprintf(c.b, "  %s = alloca i64\n", name)
+		fmt.Fprintf(c.b, "  store i64 0, ptr %s\n", name)
+	}
+	for i := 16; i <= 31; i++ {
+		r := Reg(fmt.Sprintf("V%d", i))
+		name := "%" + arm64LLVMBlockName("reg_"+string(r))
+		c.regSlot[r] = name
+		fmt.Fprintf(c.b, "  %s = alloca i128\n", name)
+		fmt.Fprintf(c.b, "  store i128 0, ptr %s\n", name)
+	}
+
+	// R15 points at the bottom of the TEXT frame, whose first word is the
+	// LR save slot. Leaf functions still get it, since bodies address
+	// off(R15) past it regardless.
+	fmt.Fprintf(c.b, "  %%frame = alloca [%d x i8], align 16\n", c.frameSize+s390xFixedFrame)
+	t := c.emit("ptrtoint ptr %%frame to i64")
+	if err := c.storeReg(SP, t); err != nil {
+		return err
+	}
+
+	// Scratch buffer for the variable-length vector accesses.
+	c.b.WriteString("  %vlbuf = alloca [16 x i8], align 16\n")
+
+	for _, r := range c.fpResults {
+		name := fmt.Sprintf("%%fp_ret_%d", r.Index)
+		c.fpResAllocaIdx[r.Index] = name
+		c.fpResAllocaOff[r.Offset] = name
+		fmt.Fprintf(c.b, "  %s = alloca %s\n", name, r.Type)
+		fmt.Fprintf(c.b, "  store %s %s, ptr %s\n", r.Type, llvmZeroValue(r.Type), name)
+	}
+
+	if c.argFrameSize > 0 {
+		if err := c.spillArgFrame(); err != nil {
+			return err
+		}
+	}
+
+	// Seed the argument registers too, for ABIInternal bodies and helper<>
+	// register assignments; ABI0 bodies read the FP slots instead.
+	if len(c.sig.ArgRegs) > 0 {
+		for i := 0; i < len(c.sig.Args) && i < len(c.sig.ArgRegs); i++ {
+			v, ok, err := c.valueAsReg(c.sig.Args[i], fmt.Sprintf("%%arg%d", i))
+			if err != nil {
+				return err
+			}
+			if !ok {
+				continue
+			}
+			if err := c.storeReg(c.sig.ArgRegs[i], v); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	var cur s390xArgCursor
+	for ai, argTy := range c.sig.Args {
+		arg := fmt.Sprintf("%%arg%d", ai)
+		fields, isAgg := parseLiteralStructFields(argTy)
+		if !isAgg {
+			fields = []LLVMType{argTy}
+		}
+		for fi, fTy := range fields {
+			r, ok := cur.next(fTy)
+			if !ok {
+				return nil
+			}
+			v := arg
+			if isAgg {
+				v = c.emit("extractvalue %s %s, %d", argTy, arg, fi)
+			}
+			v64, ok, err := c.valueAsReg(fTy, v)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				continue
+			}
+			if err := c.storeReg(r, v64); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// spillArgFrame stores the FP parameter slots into %argframe.
+func (c *s390xCtx) spillArgFrame() error {
+	fmt.Fprintf(c.b, "  %%argframe = alloca [%d x i8], align 8\n", c.argFrameSize)
+	for _, s := range c.sig.Frame.Params {
+		if s.Index < 0 || s.Index >= len(c.sig.Args) {
+			continue
+		}
+		v := fmt.Sprintf("%%arg%d", s.Index)
+		if s.Field >= 0 {
+			v = c.emit("extractvalue %s %s, %d", c.sig.Args[s.Index], v, s.Field)
+		}
+		p := c.emit("getelementptr i8, ptr %%argframe, i64 %d", s.Offset)
+		fmt.Fprintf(c.b, "  store %s %s, ptr %s\n", s.Type, v, p)
+	}
+	return nil
+}
+
+// s390xArgCursor hands out ABIInternal argument (or result) registers.
+type s390xArgCursor struct{ ints, floats int }
+
+func (a *s390xArgCursor) next(ty LLVMType) (Reg, bool) {
+	if riscv64IsFloatType(ty) {
+		if a.floats >= len(s390xFloatArgRegs) {
+			return "", false
+		}
+		a.floats++
+		return s390xFloatArgRegs[a.floats-1], true
+	}
+	if a.ints >= len(s390xIntArgRegs) {
+		return "", false
+	}
+	a.ints++
+	return s390xIntArgRegs[a.ints-1], true
+}
+
+// valueAsI64 converts a value of type ty to the bits it has in memory,
+// zero-extended: the form FP slots and integer registers hold it in.
+func (c *s390xCtx) valueAsI64(ty LLVMType, v string) (out string, ok bool, err error) {
+	switch ty {
+	case I64:
+		return v, true, nil
+	case Ptr:
+		return c.emit("ptrtoint ptr %s to i64", v), true, nil
+	case I1, I8, I16, I32:
+		return c.emit("zext %s %s to i64", ty, v), true, nil
+	case LLVMType("double"):
+		return c.emit("bitcast double %s to i64", v), true, nil
+	case LLVMType("float"):
+		s := c.emit("bitcast float %s to i32", v)
+		return c.emit("zext i32 %s to i64", s), true, nil
+	}
+	return "", false, nil
+}
+
+// i64ToValue converts memory bits back to ty.
+func (c *s390xCtx) i64ToValue(v string, ty LLVMType) (string, error) {
+	switch ty {
+	case I64:
+		return v, nil
+	case I32, I16, I8, I1:
+		return c.emit("trunc i64 %s to %s", v, ty), nil
+	case Ptr:
+		return c.emit("inttoptr i64 %s to ptr", v), nil
+	case LLVMType("double"):
+		return c.emit("bitcast i64 %s to double", v), nil
+	case LLVMType("float"):
+		s := c.emit("trunc i64 %s to i32", v)
+		return c.emit("bitcast i32 %s to float", s), nil
+	}
+	return "", fmt.Errorf("s390x: unsupported value type %s", ty)
+}
+
+// valueAsReg converts a value of type ty to the bits of the register
+// ABIInternal passes it in: singles sit in the high word of an F register.
+func (c *s390xCtx) valueAsReg(ty LLVMType, v string) (string, bool, error) {
+	bits, ok, err := c.valueAsI64(ty, v)
+	if err != nil || !ok || ty != LLVMType("float") {
+		return bits, ok, err
+	}
+	return c.emit("shl i64 %s, 32", bits), true, nil
+}
+
+// regToValue is the inverse of valueAsReg.
+func (c *s390xCtx) regToValue(v string, ty LLVMType) (string, error) {
+	if ty == LLVMType("float") {
+		v = c.emit("lshr i64 %s, 32", v)
+	}
+	return c.i64ToValue(v, ty)
+}
+
+func (c *s390xCtx) loadReg(r Reg) (string, error) {
+	slot, ok := c.regSlot[r]
+	if !ok || s390xIsVReg(r) {
+		return "", fmt.Errorf("s390x: unknown reg %s", r)
+	}
+	return c.emit("load i64, ptr %s", slot), nil
+}
+
+func (c *s390xCtx) storeReg(r Reg, v string) error {
+	slot, ok := c.regSlot[r]
+	if !ok || s390xIsVReg(r) {
+		return fmt.Errorf("s390x: unknown reg %s", r)
+	}
+	fmt.Fprintf(c.b, "  store i64 %s, ptr %s\n", v, slot)
+	return nil
+}
+
+// loadVec reads a vector register as an i128. V0-V15 combine the F
+// register and the VLO slot.
+func (c *s390xCtx) loadVec(r Reg) (string, error) {
+	if !s390xIsVReg(r) && !s390xIsFReg(r) {
+		return "", fmt.Errorf("s390x: %s is not a vector register", r)
+	}
+	n := s390xVRegNum(r)
+	if n >= 16 {
+		return c.emit("load i128, ptr %s", c.regSlot[r]), nil
+	}
+	hi, err := c.loadReg(Reg(fmt.Sprintf("F%d", n)))
+	if err != nil {
+		return "", err
+	}
+	lo, err := c.loadReg(Reg(fmt.Sprintf("VLO%d", n)))
+	if err != nil {
+		return "", err
+	}
+	return c.join128(hi, lo), nil
+}
+
+func (c *s390xCtx) storeVec(r Reg, v string) error {
+	if !s390xIsVReg(r) && !s390xIsFReg(r) {
+		return fmt.Errorf("s390x: %s is not a vector register", r)
+	}
+	n := s390xVRegNum(r)
+	if n >= 16 {
+		fmt.Fprintf(c.b, "  store i128 %s, ptr %s\n", v, c.regSlot[r])
+		return nil
+	}
+	hi, lo := c.split128(v)
+	if err := c.storeReg(Reg(fmt.Sprintf("F%d", n)), hi); err != nil {
+		return err
+	}
+	return c.storeReg(Reg(fmt.Sprintf("VLO%d", n)), lo)
+}
+
+// loadVecHi reads doubleword 0 of a vector register, the F register for
+// V0-V15.
+func (c *s390xCtx) loadVecHi(r Reg) (string, error) {
+	if n := s390xVRegNum(r); n < 16 {
+		return c.loadReg(Reg(fmt.Sprintf("F%d", n)))
+	}
+	v, err := c.loadVec(r)
+	if err != nil {
+		return "", err
+	}
+	hi, _ := c.split128(v)
+	return hi, nil
+}
+
+// join128 builds hi:lo, with hi as doubleword 0.
+func (c *s390xCtx) join128(hi, lo string) string {
+	h := c.emit("zext i64 %s to i128", hi)
+	h = c.emit("shl i128 %s, 64", h)
+	l := c.emit("zext i64 %s to i128", lo)
+	return c.emit("or i128 %s, %s", h, l)
+}
+
+// split128 returns doublewords 0 and 1 of v.
+func (c *s390xCtx) split128(v string) (hi, lo string) {
+	h := c.emit("lshr i128 %s, 64", v)
+	return c.emit("trunc i128 %s to i64", h), c.emit("trunc i128 %s to i64", v)
+}
+
+func (c *s390xCtx) loadCC() string {
+	return c.emit("load i64, ptr %s", c.regSlot["CC"])
+}
+
+func (c *s390xCtx) setCC(cc string) {
+	fmt.Fprintf(c.b, "  store i64 %s, ptr %s\n", cc, c.regSlot["CC"])
+}
+
+// ccCond returns an i1 that is set when the condition code is one the
+// branch mask selects.
+func (c *s390xCtx) ccCond(mask int64) string {
+	switch mask & 15 {
+	case 0:
+		return "false"
+	case 15:
+		return "true"
+	}
+	sh := c.emit("sub i64 3, %s", c.loadCC())
+	m := c.emit("lshr i64 %d, %s", mask&15, sh)
+	return c.emit("trunc i64 %s to i1", m)
+}
+
+// setCCSign sets the condition code of a signed result v of type ty: 0
+// for zero, 1 for negative, 2 for positive, or 3 when the i1 ovf is set.
+func (c *s390xCtx) setCCSign(ty, v, ovf string) {
+	neg := c.emit("icmp slt %s %s, 0", ty, v)
+	zero := c.emit("icmp eq %s %s, 0", ty, v)
+	cc := c.emit("select i1 %s, i64 1, i64 2", neg)
+	cc = c.emit("select i1 %s, i64 0, i64 %s", zero, cc)
+	if ovf != "" {
+		cc = c.emit("select i1 %s, i64 3, i64 %s", ovf, cc)
+	}
+	c.setCC(cc)
+}
+
+// setCCZero sets the condition code to 0 when v is zero and to 1
+// otherwise, as the bitwise operations do.
+func (c *s390xCtx) setCCZero(ty, v string) {
+	nz := c.emit("icmp ne %s %s, 0", ty, v)
+	c.setCC(c.emit("zext i1 %s to i64", nz))
+}
+
+// setCCLogical sets the condition code of the logical (unsigned)
+// arithmetic: 2 for a carry out, plus 1 for a nonzero result.
+func (c *s390xCtx) setCCLogical(ty, v, carry string) {
+	nz := c.emit("icmp ne %s %s, 0", ty, v)
+	nz = c.emit("zext i1 %s to i64", nz)
+	cy := c.emit("select i1 %s, i64 2, i64 0", carry)
+	c.setCC(c.emit("or i64 %s, %s", cy, nz))
+}
+
+// setCCCompare sets the condition code of comparing a with b: 0 for
+// equal, 1 for low, 2 for high.
+func (c *s390xCtx) setCCCompare(ty, a, b string, signed bool) {
+	lt := "ult"
+	if signed {
+		lt = "slt"
+	}
+	isLt := c.emit("icmp %s %s %s, %s", lt, ty, a, b)
+	eq := c.emit("icmp eq %s %s, %s", ty, a, b)
+	cc := c.emit("select i1 %s, i64 1, i64 2", isLt)
+	c.setCC(c.emit("select i1 %s, i64 0, i64 %s", eq, cc))
+}
+
+func (c *s390xCtx) ptrFromSB(sym string) (string, error) {
+	base, off, ok := parseSBRef(sym)
+	if !ok {
+		return "", fmt.Errorf("invalid (SB) sym ref: %q", sym)
+	}
+	base = strings.TrimPrefix(base, "$")
+	res := base
+	if strings.Contains(base, "·") || strings.Contains(base, "/") || strings.Contains(base, ".") {
+		res = c.resolve(base)
+	} else {
+		res = c.resolve("·" + base)
+	}
+	p := llvmGlobal(res)
+	if off == 0 {
+		return p, nil
+	}
+	return c.emit("getelementptr i8, ptr %s, i64 %d", p, off), nil
+}
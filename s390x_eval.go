@@ -0,0 +1,208 @@
+package plan9asm
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// addrI64 computes the i64 address of an off(base) or (base)(index)
+// reference. R0 there stands for zero, as in the RX and RXY instruction
+// forms.
+func (c *s390xCtx) addrI64(mem MemRef) (string, error) {
+	addrReg := func(r Reg) (string, error) {
+		if r == "R0" {
+			return "0", nil
+		}
+		return c.loadReg(r)
+	}
+	base, err := addrReg(mem.Base)
+	if err != nil {
+		return "", err
+	}
+	if mem.Index != "" {
+		idx, err := addrReg(mem.Index)
+		if err != nil {
+			return "", err
+		}
+		base = c.emit("add i64 %s, %s", base, idx)
+	}
+	if mem.Off == 0 {
+		return base, nil
+	}
+	return c.emit("add i64 %s, %d", base, mem.Off), nil
+}
+
+func (c *s390xCtx) memPtr(mem MemRef) (string, error) {
+	addr, err := c.addrI64(mem)
+	if err != nil {
+		return "", err
+	}
+	p := c.newTmp()
+	fmt.Fprintf(c.b, "  %%%s = inttoptr i64 %s to ptr\n", p, addr)
+	return "%" + p, nil
+}
+
+// loadMem loads bits from mem and sign- or zero-extends them to i64.
+func (c *s390xCtx) loadMem(mem MemRef, bits int, signed bool) (string, error) {
+	ptr, err := c.memPtr(mem)
+	if err != nil {
+		return "", err
+	}
+	t := c.newTmp()
+	fmt.Fprintf(c.b, "  %%%s = load i%d, ptr %s\n", t, bits, ptr)
+	if bits == 64 {
+		return "%" + t, nil
+	}
+	return c.extend("%"+t, bits, signed), nil
+}
+
+func (c *s390xCtx) storeMem(mem MemRef, bits int, v64 string) error {
+	ptr, err := c.memPtr(mem)
+	if err != nil {
+		return err
+	}
+	if bits == 64 {
+		fmt.Fprintf(c.b, "  store i64 %s, ptr %s\n", v64, ptr)
+		return nil
+	}
+	t := c.newTmp()
+	fmt.Fprintf(c.b, "  %%%s = trunc i64 %s to i%d\n", t, v64, bits)
+	fmt.Fprintf(c.b, "  store i%d %%%s, ptr %s\n", bits, t, ptr)
+	return nil
+}
+
+// extend sign- or zero-extends an i<bits> value to i64.
+func (c *s390xCtx) extend(v string, bits int, signed bool) string {
+	ext := "zext"
+	if signed {
+		ext = "sext"
+	}
+	t := c.newTmp()
+	fmt.Fprintf(c.b, "  %%%s = %s i%d %s to i64\n", t, ext, bits, v)
+	return "%" + t
+}
+
+// narrow truncates v64 to bits and extends it back, as the W, H and B
+// forms do with their results.
+func (c *s390xCtx) narrow(v64 string, bits int, signed bool) string {
+	if bits == 64 {
+		return v64
+	}
+	t := c.newTmp()
+	fmt.Fprintf(c.b, "  %%%s = trunc i64 %s to i%d\n", t, v64, bits)
+	return c.extend("%"+t, bits, signed)
+}
+
+// symAddr returns the address of a sym(SB) or $sym(SB) operand, of a
+// $name-off(FP) operand naming a word below the argument frame, or of an
+// $off(Rn) address constant.
+func (c *s390xCtx) symAddr(sym string) (string, error) {
+	if s := strings.TrimSpace(sym); strings.HasSuffix(s, "(FP)") {
+		return c.callerFrameAddr(s)
+	}
+	if m, ok := ppc64AddrConst(sym); ok {
+		return c.addrI64(m)
+	}
+	p, err := c.ptrFromSB(strings.TrimPrefix(strings.TrimSpace(sym), "$"))
+	if err != nil {
+		return "", err
+	}
+	t := c.newTmp()
+	fmt.Fprintf(c.b, "  %%%s = ptrtoint ptr %s to i64\n", t, p)
+	return "%" + t, nil
+}
+
+// callerFrameAddr evaluates $name-off(FP). The pseudo FP lies above the
+// TEXT frame and the caller's fixed frame header, as in Go's layout.
+func (c *s390xCtx) callerFrameAddr(s string) (string, error) {
+	inner := strings.TrimSuffix(strings.TrimPrefix(s, "$"), "(FP)")
+	i := strings.LastIndexAny(inner, "+-")
+	if i < 0 {
+		return "", fmt.Errorf("s390x: unsupported FP address %s", s)
+	}
+	off, err := strconv.ParseInt(inner[i:], 0, 64)
+	if err != nil {
+		return "", fmt.Errorf("s390x: unsupported FP address %s", s)
+	}
+	sp, err := c.loadReg(SP)
+	if err != nil {
+		return "", err
+	}
+	t := c.newTmp()
+	fmt.Fprintf(c.b, "  %%%s = add i64 %s, %d\n", t, sp, c.frameSize+2*s390xFixedFrame+off)
+	return "%" + t, nil
+}
+
+// eval64 evaluates a source operand of an integer operation: a register,
+// an immediate or an address constant.
+func (c *s390xCtx) eval64(op Operand) (string, error) {
+	switch op.Kind {
+	case OpImm:
+		return fmt.Sprintf("%d", op.Imm), nil
+	case OpReg:
+		return c.loadReg(op.Reg)
+	case OpFPAddr:
+		return c.evalFPAddr64(op)
+	case OpSym:
+		if s390xIsAddrSym(op) {
+			return c.symAddr(op.Sym)
+		}
+	}
+	return "", fmt.Errorf("s390x: unsupported source operand %s", op.String())
+}
+
+func (c *s390xCtx) evalFPValue64(op Operand) (string, error) {
+	slot, ok := c.fpParams[op.FPOffset]
+	if !ok {
+		return "", fmt.Errorf("s390x: unsupported FP param slot: %s", op.String())
+	}
+	idx := slot.Index
+	if idx < 0 || idx >= len(c.sig.Args) {
+		return "", fmt.Errorf("s390x: FP slot %s invalid arg index %d", op.String(), idx)
+	}
+	arg := fmt.Sprintf("%%arg%d", idx)
+	if slot.Field >= 0 {
+		t := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = extractvalue %s %s, %d\n", t, c.sig.Args[idx], arg, slot.Field)
+		arg = "%" + t
+	}
+	v, ok, err := c.valueAsI64(slot.Type, arg)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", fmt.Errorf("s390x: FP slot %s unsupported arg type %q", op.String(), slot.Type)
+	}
+	return v, nil
+}
+
+func (c *s390xCtx) evalFPAddr64(op Operand) (string, error) {
+	p, ok := c.fpResAllocaOff[op.FPOffset]
+	if !ok {
+		if c.argFrameSize == 0 {
+			return "", fmt.Errorf("s390x: unsupported FP address %s", op.String())
+		}
+		t := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = getelementptr i8, ptr %%argframe, i64 %d\n", t, op.FPOffset)
+		p = "%" + t
+	} else {
+		c.markFPResultAddrTaken(op.FPOffset)
+	}
+	t := c.newTmp()
+	fmt.Fprintf(c.b, "  %%%s = ptrtoint ptr %s to i64\n", t, p)
+	return "%" + t, nil
+}
+
+// s390xIsAddrSym reports whether op is an address constant: $sym(SB),
+// $name-off(FP) or $off(Rn).
+func s390xIsAddrSym(op Operand) bool {
+	if op.Kind != OpSym {
+		return false
+	}
+	s := strings.TrimSpace(op.Sym)
+	if _, ok := ppc64AddrConst(s); ok {
+		return true
+	}
+	return strings.HasPrefix(s, "$") && (strings.HasSuffix(s, "(SB)") || strings.HasSuffix(s, "(FP)"))
+}
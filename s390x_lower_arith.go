@@ -0,0 +1,506 @@
+package plan9asm
+
+import (
+	"fmt"
+	"math/bits"
+	"strings"
+)
+
+// s390xCCKind says how an integer operation sets the condition code.
+type s390xCCKind int
+
+const (
+	s390xCCNone    s390xCCKind = iota
+	s390xCCArith               // signed result: zero, negative, positive, overflow
+	s390xCCSign                // signed result without overflow (the algebraic shifts)
+	s390xCCNonzero             // zero or nonzero (the bitwise operations)
+)
+
+// s390xALUOp describes a two-source integer operation. w32 operations
+// compute on the low words and leave the high word of the destination
+// unchanged.
+type s390xALUOp struct {
+	kind string
+	w32  bool
+	cc   s390xCCKind
+}
+
+var s390xALUOps = map[string]s390xALUOp{
+	"ADD": {kind: "add", cc: s390xCCArith}, "ADDW": {kind: "add", w32: true, cc: s390xCCArith},
+	"SUB": {kind: "sub", cc: s390xCCArith}, "SUBW": {kind: "sub", w32: true, cc: s390xCCArith},
+	"AND": {kind: "and", cc: s390xCCNonzero}, "ANDW": {kind: "and", w32: true, cc: s390xCCNonzero},
+	"OR": {kind: "or", cc: s390xCCNonzero}, "ORW": {kind: "or", w32: true, cc: s390xCCNonzero},
+	"XOR": {kind: "xor", cc: s390xCCNonzero}, "XORW": {kind: "xor", w32: true, cc: s390xCCNonzero},
+	"MULLD": {kind: "mul"}, "MULHD": {kind: "mulh"}, "MULHDU": {kind: "mulhu"},
+	"DIVD": {kind: "div"}, "DIVDU": {kind: "divu"}, "MODD": {kind: "rem"}, "MODDU": {kind: "remu"},
+	"SLD": {kind: "shl"}, "SRD": {kind: "lshr"}, "SRAD": {kind: "ashr", cc: s390xCCSign},
+	"SLW": {kind: "shl", w32: true}, "SRW": {kind: "lshr", w32: true},
+	"SRAW": {kind: "ashr", w32: true, cc: s390xCCSign},
+	"RLLG": {kind: "rotl"}, "RLL": {kind: "rotl", w32: true},
+}
+
+// s390xCarryOps gives the operands of the logical arithmetic as x + y +
+// carry-in: "a" and "b" are the sources of "OP b, a, t", which computes
+// t = a OP b, "~" complements and "cc" is the carry (or not-borrow) in
+// the condition code.
+var s390xCarryOps = map[string][3]string{
+	"ADDC": {"a", "b", "0"},
+	"ADDE": {"a", "b", "cc"},
+	"SUBC": {"a", "~b", "1"},
+	"SUBE": {"a", "~b", "cc"},
+}
+
+// s390xCompares maps the compares "OP a, b|$imm" to their width and
+// signedness.
+var s390xCompares = map[string]struct {
+	ty     string
+	signed bool
+}{
+	"CMP": {"i64", true}, "CMPU": {"i64", false},
+	"CMPW": {"i32", true}, "CMPWU": {"i32", false},
+}
+
+// s390xTestMasks maps the test-under-mask instructions to the shift of
+// the halfword they test.
+var s390xTestMasks = map[string]int{"TMLL": 0, "TMLH": 16, "TMHL": 32, "TMHH": 48}
+
+func (c *s390xCtx) lowerArith(op string, ins Instr) (ok bool, terminated bool, err error) {
+	if alu, found := s390xALUOps[op]; found {
+		return true, false, c.lowerALU(op, alu, ins)
+	}
+	if _, found := s390xCarryOps[op]; found {
+		return true, false, c.lowerCarry(op, ins)
+	}
+	if cmp, found := s390xCompares[op]; found {
+		if len(ins.Args) != 2 || !s390xIsRReg(ins.Args[0]) {
+			return true, false, fmt.Errorf("s390x %s expects reg, reg|$imm: %q", op, ins.Raw)
+		}
+		a, b, err := c.cmpOperands(cmp.ty, ins.Args[0], ins.Args[1])
+		if err != nil {
+			return true, false, err
+		}
+		c.setCCCompare(cmp.ty, a, b, cmp.signed)
+		return true, false, nil
+	}
+	if sh, found := s390xTestMasks[op]; found {
+		return true, false, c.lowerTestMask(op, sh, ins)
+	}
+	switch op {
+	case "MULLW":
+		return true, false, c.lowerMULLW(ins)
+	case "DIVW", "DIVWU", "MODW", "MODWU":
+		return true, false, c.lowerDivW(op, ins)
+	case "NEG", "NEGW":
+		if len(ins.Args) != 1 && len(ins.Args) != 2 {
+			return true, false, fmt.Errorf("s390x %s expects reg[, reg]: %q", op, ins.Raw)
+		}
+		for _, a := range ins.Args {
+			if !s390xIsRReg(a) {
+				return true, false, fmt.Errorf("s390x %s expects R registers: %q", op, ins.Raw)
+			}
+		}
+		a, err := c.loadReg(ins.Args[0].Reg)
+		if err != nil {
+			return true, false, err
+		}
+		ovf := "false"
+		if op == "NEGW" {
+			a = c.narrow(a, 32, true)
+		} else {
+			ovf = c.emit("icmp eq i64 %s, %d", a, int64(-1)<<63)
+		}
+		v := c.emit("sub i64 0, %s", a)
+		c.setCCSign("i64", v, ovf)
+		return true, false, c.storeReg(ins.Args[len(ins.Args)-1].Reg, v)
+	case "FLOGR":
+		return true, false, c.lowerFLOGR(ins)
+	case "POPCNT":
+		// Each byte of the result counts the one bits of that byte.
+		if len(ins.Args) != 2 || !s390xIsRReg(ins.Args[0]) || !s390xIsRReg(ins.Args[1]) {
+			return true, false, fmt.Errorf("s390x POPCNT expects reg, reg: %q", ins.Raw)
+		}
+		a, err := c.loadReg(ins.Args[0].Reg)
+		if err != nil {
+			return true, false, err
+		}
+		vec := c.emit("bitcast i64 %s to <8 x i8>", a)
+		cnt := c.emit("call <8 x i8> @llvm.ctpop.v8i8(<8 x i8> %s)", vec)
+		v := c.emit("bitcast <8 x i8> %s to i64", cnt)
+		c.setCCZero("i64", v)
+		return true, false, c.storeReg(ins.Args[1].Reg, v)
+	case "MLGR":
+		return true, false, c.lowerMLGR(ins)
+	case "IPM":
+		// IPM R puts CC in bits 28-29 of R's low word, clearing the rest
+		// of its top byte (the program mask, which Go never sets).
+		if len(ins.Args) != 1 || !s390xIsRReg(ins.Args[0]) {
+			return true, false, fmt.Errorf("s390x IPM expects reg: %q", ins.Raw)
+		}
+		old, err := c.loadReg(ins.Args[0].Reg)
+		if err != nil {
+			return true, false, err
+		}
+		cc := c.emit("shl i64 %s, 28", c.loadCC())
+		keep := c.emit("and i64 %s, -4278190081", old)
+		return true, false, c.storeReg(ins.Args[0].Reg, c.emit("or i64 %s, %s", keep, cc))
+	case "RISBG", "RISBGZ", "RISBGN", "RISBGNZ", "RNSBG", "ROSBG", "RXSBG":
+		return true, false, c.lowerRotateSelect(op, ins)
+	}
+	return false, false, nil
+}
+
+// aluOperands checks "OP b|$imm, [a,] t" and evaluates a and b; the
+// two-operand form reads t as a.
+func (c *s390xCtx) aluOperands(op string, ins Instr) (a, b string, dst Reg, err error) {
+	if len(ins.Args) != 2 && len(ins.Args) != 3 {
+		return "", "", "", fmt.Errorf("s390x %s expects 2 or 3 operands: %q", op, ins.Raw)
+	}
+	for _, o := range ins.Args[:len(ins.Args)-1] {
+		if o.Kind != OpImm && !s390xIsRReg(o) {
+			return "", "", "", fmt.Errorf("s390x %s expects a register or immediate source: %q", op, ins.Raw)
+		}
+	}
+	last := ins.Args[len(ins.Args)-1]
+	if !s390xIsRReg(last) {
+		return "", "", "", fmt.Errorf("s390x %s expects an R register destination: %q", op, ins.Raw)
+	}
+	if b, err = c.eval64(ins.Args[0]); err != nil {
+		return "", "", "", err
+	}
+	if a, err = c.eval64(ins.Args[len(ins.Args)-2]); err != nil {
+		return "", "", "", err
+	}
+	if len(ins.Args) == 2 {
+		if a, err = c.loadReg(last.Reg); err != nil {
+			return "", "", "", err
+		}
+	}
+	return a, b, last.Reg, nil
+}
+
+// lowerALU lowers "OP b, a, t", which computes t = a OP b; b may be an
+// immediate.
+func (c *s390xCtx) lowerALU(op string, alu s390xALUOp, ins Instr) error {
+	a, b, dst, err := c.aluOperands(op, ins)
+	if err != nil {
+		return err
+	}
+	ty := "i64"
+	if alu.w32 {
+		ty = "i32"
+		a, b = c.trunc32(a), c.trunc32(b)
+	}
+	v := c.aluValue(alu.kind, ty, a, b)
+	switch alu.cc {
+	case s390xCCArith:
+		// Signed overflow: the operands agree in sign (for sub, differ)
+		// and the result does not.
+		x := c.emit("xor %s %s, %s", ty, a, v)
+		y := c.emit("xor %s %s, %s", ty, b, v)
+		if alu.kind == "sub" {
+			y = c.emit("xor %s %s, %s", ty, a, b)
+		}
+		o := c.emit("and %s %s, %s", ty, x, y)
+		c.setCCSign(ty, v, c.emit("icmp slt %s %s, 0", ty, o))
+	case s390xCCSign:
+		c.setCCSign(ty, v, "")
+	case s390xCCNonzero:
+		c.setCCZero(ty, v)
+	}
+	if alu.w32 {
+		return c.storeLow32(dst, v)
+	}
+	return c.storeReg(dst, v)
+}
+
+func (c *s390xCtx) trunc32(v string) string {
+	return c.emit("trunc i64 %s to i32", v)
+}
+
+// storeLow32 writes the i32 v to the low word of r, keeping the high word.
+func (c *s390xCtx) storeLow32(r Reg, v string) error {
+	old, err := c.loadReg(r)
+	if err != nil {
+		return err
+	}
+	hi := c.emit("and i64 %s, -4294967296", old)
+	lo := c.emit("zext i32 %s to i64", v)
+	return c.storeReg(r, c.emit("or i64 %s, %s", hi, lo))
+}
+
+// aluValue computes a OP b in ty. Shift amounts are the low six bits of
+// b: the 64-bit shifts take them modulo 64, while the 32-bit ones shift
+// out every bit (or fill with the sign) from 32 on. Division by zero,
+// which traps on the hardware, gives zero.
+func (c *s390xCtx) aluValue(kind, ty, a, b string) string {
+	bits := 64
+	if ty == "i32" {
+		bits = 32
+	}
+	switch kind {
+	case "add", "sub", "and", "or", "xor", "mul":
+		return c.emit("%s %s %s, %s", kind, ty, a, b)
+	case "shl", "lshr", "ashr":
+		n := c.emit("and %s %s, 63", ty, b)
+		if bits == 64 {
+			return c.emit("%s i64 %s, %s", kind, a, n)
+		}
+		big := c.emit("icmp uge i32 %s, 32", n)
+		safe := c.emit("and i32 %s, 31", n)
+		v := c.emit("%s i32 %s, %s", kind, a, safe)
+		fill := "0"
+		if kind == "ashr" {
+			fill = c.emit("ashr i32 %s, 31", a)
+		}
+		return c.emit("select i1 %s, i32 %s, i32 %s", big, fill, v)
+	case "rotl":
+		return c.emit("call %s @llvm.fshl.%s(%s %s, %s %s, %s %s)", ty, ty, ty, a, ty, a, ty, b)
+	case "mulh", "mulhu":
+		ext := "sext"
+		if kind == "mulhu" {
+			ext = "zext"
+		}
+		wide := fmt.Sprintf("i%d", 2*bits)
+		wa := c.emit("%s %s %s to %s", ext, ty, a, wide)
+		wb := c.emit("%s %s %s to %s", ext, ty, b, wide)
+		p := c.emit("mul %s %s, %s", wide, wa, wb)
+		hi := c.emit("lshr %s %s, %d", wide, p, bits)
+		return c.emit("trunc %s %s to %s", wide, hi, ty)
+	case "div", "divu", "rem", "remu":
+		zero := c.emit("icmp eq %s %s, 0", ty, b)
+		bad := zero
+		if kind == "div" || kind == "rem" {
+			isMin := c.emit("icmp eq %s %s, %d", ty, a, int64(-1)<<(bits-1))
+			isNeg1 := c.emit("icmp eq %s %s, -1", ty, b)
+			ov := c.emit("and i1 %s, %s", isMin, isNeg1)
+			bad = c.emit("or i1 %s, %s", zero, ov)
+		}
+		safe := c.emit("select i1 %s, %s 1, %s %s", bad, ty, ty, b)
+		insn := map[string]string{"div": "sdiv", "divu": "udiv", "rem": "srem", "remu": "urem"}[kind]
+		q := c.emit("%s %s %s, %s", insn, ty, a, safe)
+		return c.emit("select i1 %s, %s 0, %s %s", bad, ty, ty, q)
+	}
+	panic("s390x: unknown ALU kind " + kind)
+}
+
+// lowerMULLW lowers MULLW: the register form multiplies by the
+// sign-extended low word of the source (MSGFR), the immediate form
+// multiplies the low words (MSFI).
+func (c *s390xCtx) lowerMULLW(ins Instr) error {
+	a, b, dst, err := c.aluOperands("MULLW", ins)
+	if err != nil {
+		return err
+	}
+	if ins.Args[0].Kind == OpImm {
+		return c.storeLow32(dst, c.emit("mul i32 %s, %s", c.trunc32(a), c.trunc32(b)))
+	}
+	return c.storeReg(dst, c.emit("mul i64 %s, %s", a, c.narrow(b, 32, true)))
+}
+
+// lowerDivW lowers the word divisions. DIVW and MODW divide the 64-bit
+// dividend by the sign-extended divisor (DSGFR). DIVWU and MODWU divide
+// the low words (DLR): DIVWU keeps the high word of the dividend and
+// MODWU zero-extends the remainder.
+func (c *s390xCtx) lowerDivW(op string, ins Instr) error {
+	a, b, dst, err := c.aluOperands(op, ins)
+	if err != nil {
+		return err
+	}
+	switch op {
+	case "DIVW":
+		return c.storeReg(dst, c.aluValue("div", "i64", a, c.narrow(b, 32, true)))
+	case "MODW":
+		return c.storeReg(dst, c.aluValue("rem", "i64", a, c.narrow(b, 32, true)))
+	case "DIVWU":
+		q := c.aluValue("divu", "i32", c.trunc32(a), c.trunc32(b))
+		hi := c.emit("and i64 %s, -4294967296", a)
+		return c.storeReg(dst, c.emit("or i64 %s, %s", hi, c.extend(q, 32, false)))
+	}
+	r := c.aluValue("remu", "i32", c.trunc32(a), c.trunc32(b))
+	return c.storeReg(dst, c.extend(r, 32, false))
+}
+
+// lowerCarry lowers ADDC, ADDE, SUBC and SUBE "b, [a,] t". They set CC to
+// 2 for a carry out (for the subtractions, no borrow) plus 1 for a
+// nonzero result; ADDE and SUBE take that carry in.
+func (c *s390xCtx) lowerCarry(op string, ins Instr) error {
+	a, b, dst, err := c.aluOperands(op, ins)
+	if err != nil {
+		return err
+	}
+	vals := map[string]string{"a": a, "b": b}
+	var in [3]string
+	for i, s := range s390xCarryOps[op] {
+		switch {
+		case s == "cc":
+			in[i] = c.emit("lshr i64 %s, 1", c.loadCC())
+		case strings.HasPrefix(s, "~"):
+			in[i] = c.emit("xor i64 %s, -1", vals[s[1:]])
+		case vals[s] != "":
+			in[i] = vals[s]
+		default:
+			in[i] = s
+		}
+	}
+	// Add in 128 bits; bit 64 of the sum is the carry out.
+	sum := "0"
+	for _, v := range in {
+		w := c.emit("zext i64 %s to i128", v)
+		sum = c.emit("add i128 %s, %s", sum, w)
+	}
+	hi := c.emit("lshr i128 %s, 64", sum)
+	carry := c.emit("trunc i128 %s to i1", hi)
+	v := c.emit("trunc i128 %s to i64", sum)
+	c.setCCLogical("i64", v, carry)
+	return c.storeReg(dst, v)
+}
+
+// cmpOperands evaluates the operands of a compare at width ty.
+func (c *s390xCtx) cmpOperands(ty string, x, y Operand) (a, b string, err error) {
+	if a, err = c.eval64(x); err != nil {
+		return "", "", err
+	}
+	if b, err = c.eval64(y); err != nil {
+		return "", "", err
+	}
+	if ty == "i32" {
+		a, b = c.trunc32(a), c.trunc32(b)
+	}
+	return a, b, nil
+}
+
+// lowerTestMask lowers "TMLL R, $mask" and the other halfwords: CC is 0
+// when the selected bits are all zero, 3 when they are all one, and
+// otherwise 2 or 1 as the leftmost selected bit is one or zero.
+func (c *s390xCtx) lowerTestMask(op string, sh int, ins Instr) error {
+	if len(ins.Args) != 2 || !s390xIsRReg(ins.Args[0]) || ins.Args[1].Kind != OpImm {
+		return fmt.Errorf("s390x %s expects reg, $mask: %q", op, ins.Raw)
+	}
+	mask := ins.Args[1].Imm & 0xffff
+	if mask == 0 {
+		c.setCC("0")
+		return nil
+	}
+	v, err := c.loadReg(ins.Args[0].Reg)
+	if err != nil {
+		return err
+	}
+	v = c.emit("lshr i64 %s, %d", v, sh)
+	sel := c.emit("and i64 %s, %d", v, mask)
+	left := int64(1) << (bits.Len64(uint64(mask)) - 1)
+	l := c.emit("and i64 %s, %d", sel, left)
+	l = c.emit("icmp ne i64 %s, 0", l)
+	cc := c.emit("select i1 %s, i64 2, i64 1", l)
+	all := c.emit("icmp eq i64 %s, %d", sel, mask)
+	cc = c.emit("select i1 %s, i64 3, i64 %s", all, cc)
+	none := c.emit("icmp eq i64 %s, 0", sel)
+	c.setCC(c.emit("select i1 %s, i64 0, i64 %s", none, cc))
+	return nil
+}
+
+// lowerFLOGR lowers "FLOGR src, dst": dst gets the number of leading
+// zeros (64 for zero) and dst+1 gets src with its leftmost one bit
+// cleared. CC is 0 for zero and 2 otherwise.
+func (c *s390xCtx) lowerFLOGR(ins Instr) error {
+	if len(ins.Args) != 2 || !s390xIsRReg(ins.Args[0]) || !s390xIsRReg(ins.Args[1]) || s390xRegNum(ins.Args[1].Reg)%2 != 0 {
+		return fmt.Errorf("s390x FLOGR expects reg, even reg: %q", ins.Raw)
+	}
+	a, err := c.loadReg(ins.Args[0].Reg)
+	if err != nil {
+		return err
+	}
+	n := c.emit("call i64 @llvm.ctlz.i64(i64 %s, i1 false)", a)
+	bit := c.emit("lshr i64 %d, %s", int64(-1)<<63, n)
+	nb := c.emit("xor i64 %s, -1", bit)
+	rest := c.emit("and i64 %s, %s", a, nb)
+	zero := c.emit("icmp eq i64 %s, 0", a)
+	c.setCC(c.emit("select i1 %s, i64 0, i64 2", zero))
+	dst := ins.Args[1].Reg
+	if err := c.storeReg(dst, n); err != nil {
+		return err
+	}
+	return c.storeReg(s390xRegName(s390xRegNum(dst)+1), rest)
+}
+
+// lowerMLGR lowers "MLGR src, dst", the unsigned 128-bit product of src
+// and dst+1, whose high doubleword goes to dst and low one to dst+1.
+func (c *s390xCtx) lowerMLGR(ins Instr) error {
+	if len(ins.Args) != 2 || !s390xIsRReg(ins.Args[0]) || !s390xIsRReg(ins.Args[1]) || s390xRegNum(ins.Args[1].Reg)%2 != 0 {
+		return fmt.Errorf("s390x MLGR expects reg, even reg: %q", ins.Raw)
+	}
+	dst := ins.Args[1].Reg
+	lo := s390xRegName(s390xRegNum(dst) + 1)
+	a, err := c.loadReg(ins.Args[0].Reg)
+	if err != nil {
+		return err
+	}
+	b, err := c.loadReg(lo)
+	if err != nil {
+		return err
+	}
+	wa := c.emit("zext i64 %s to i128", a)
+	wb := c.emit("zext i64 %s to i128", b)
+	hi, low := c.split128(c.emit("mul i128 %s, %s", wa, wb))
+	if err := c.storeReg(dst, hi); err != nil {
+		return err
+	}
+	return c.storeReg(lo, low)
+}
+
+// s390xMask64 returns the IBM-numbered mask of bits start through end
+// (bit 0 is the most significant), wrapping around when start > end.
+func s390xMask64(start, end int64) uint64 {
+	ones := func(from, to int64) uint64 {
+		return (^uint64(0) >> from) & (^uint64(0) << (63 - to))
+	}
+	if start <= end {
+		return ones(start, end)
+	}
+	return ones(start, 63) | ones(0, end)
+}
+
+// lowerRotateSelect lowers "OP $start, $end, $rot, src, dst", which
+// rotates src left by rot and combines the bits start through end with
+// dst. RISBG inserts them, clearing the other bits in its Z forms and
+// setting CC from the result unless N; RNSBG, ROSBG and RXSBG and, or or
+// xor them into dst and set CC to whether the selected bits of the
+// result are nonzero.
+func (c *s390xCtx) lowerRotateSelect(op string, ins Instr) error {
+	if len(ins.Args) != 5 || ins.Args[0].Kind != OpImm || ins.Args[1].Kind != OpImm || ins.Args[2].Kind != OpImm ||
+		!s390xIsRReg(ins.Args[3]) || !s390xIsRReg(ins.Args[4]) {
+		return fmt.Errorf("s390x %s expects $start, $end, $rot, reg, reg: %q", op, ins.Raw)
+	}
+	mask := int64(s390xMask64(ins.Args[0].Imm&63, ins.Args[1].Imm&63))
+	src, err := c.loadReg(ins.Args[3].Reg)
+	if err != nil {
+		return err
+	}
+	dst := ins.Args[4].Reg
+	old, err := c.loadReg(dst)
+	if err != nil {
+		return err
+	}
+	rot := c.emit("call i64 @llvm.fshl.i64(i64 %s, i64 %s, i64 %d)", src, src, ins.Args[2].Imm&63)
+	sel := c.emit("and i64 %s, %d", rot, mask)
+	var v string
+	switch op {
+	case "RISBGZ", "RISBGNZ":
+		v = sel
+	case "RISBG", "RISBGN":
+		keep := c.emit("and i64 %s, %d", old, ^mask)
+		v = c.emit("or i64 %s, %s", keep, sel)
+	default:
+		kind := map[string]string{"RNSBG": "and", "ROSBG": "or", "RXSBG": "xor"}[op]
+		operand := sel
+		if kind == "and" {
+			// Bits outside the range are left alone.
+			operand = c.emit("or i64 %s, %d", rot, ^mask)
+		}
+		v = c.emit("%s i64 %s, %s", kind, old, operand)
+		c.setCCZero("i64", c.emit("and i64 %s, %d", v, mask))
+	}
+	if op == "RISBG" || op == "RISBGZ" {
+		c.setCCSign("i64", v, "")
+	}
+	return c.storeReg(dst, v)
+}
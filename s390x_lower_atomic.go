@@ -0,0 +1,108 @@
+package plan9asm
+
+import "fmt"
+
+// s390xInterlocked maps the interlocked-access instructions "OP src, dst,
+// mem" to the atomicrmw operation they perform and its width; dst gets
+// the old value and CC is set from the new one.
+var s390xInterlocked = map[string]struct {
+	rmw  string
+	bits int
+}{
+	"LAA": {"add", 32}, "LAAG": {"add", 64},
+	"LAAL": {"add", 32}, "LAALG": {"add", 64},
+	"LAN": {"and", 32}, "LANG": {"and", 64},
+	"LAO": {"or", 32}, "LAOG": {"or", 64},
+	"LAX": {"xor", 32}, "LAXG": {"xor", 64},
+}
+
+func (c *s390xCtx) lowerAtomic(op string, ins Instr) (ok bool, terminated bool, err error) {
+	switch op {
+	case "SYNC":
+		c.b.WriteString("  fence seq_cst\n")
+		return true, false, nil
+	case "CS", "CSY", "CSG":
+		return true, false, c.lowerCS(op, ins)
+	}
+	ia, found := s390xInterlocked[op]
+	if !found {
+		return false, false, nil
+	}
+	if len(ins.Args) != 3 || !s390xIsRReg(ins.Args[0]) || !s390xIsRReg(ins.Args[1]) || ins.Args[2].Kind != OpMem {
+		return true, false, fmt.Errorf("s390x %s expects R, R, mem: %q", op, ins.Raw)
+	}
+	ty := fmt.Sprintf("i%d", ia.bits)
+	src, err := c.loadReg(ins.Args[0].Reg)
+	if err != nil {
+		return true, false, err
+	}
+	if ia.bits == 32 {
+		src = c.trunc32(src)
+	}
+	p, err := c.memPtr(ins.Args[2].Mem)
+	if err != nil {
+		return true, false, err
+	}
+	old := c.emit("atomicrmw %s ptr %s, %s %s seq_cst, align %d", ia.rmw, p, ty, src, ia.bits/8)
+	nv := c.emit("%s %s %s, %s", ia.rmw, ty, old, src)
+	switch {
+	case ia.rmw != "add":
+		c.setCCZero(ty, nv)
+	case op == "LAAL" || op == "LAALG":
+		cy := c.emit("icmp ult %s %s, %s", ty, nv, old)
+		c.setCCLogical(ty, nv, cy)
+	default:
+		c.setCCSign(ty, nv, c.addOverflow(ty, old, src, nv))
+	}
+	if ia.bits == 32 {
+		return true, false, c.storeLow32(ins.Args[1].Reg, old)
+	}
+	return true, false, c.storeReg(ins.Args[1].Reg, old)
+}
+
+// addOverflow returns an i1 that is set when the signed sum a+b = s
+// overflowed.
+func (c *s390xCtx) addOverflow(ty, a, b, s string) string {
+	x := c.emit("xor %s %s, %s", ty, s, a)
+	y := c.emit("xor %s %s, %s", ty, s, b)
+	o := c.emit("and %s %s, %s", ty, x, y)
+	return c.emit("icmp slt %s %s, 0", ty, o)
+}
+
+// lowerCS lowers "CS old, new, mem", which stores new if mem holds old
+// and sets CC to 0, or otherwise loads the current value into old and
+// sets CC to 1. CS and CSY compare words, CSG doublewords.
+func (c *s390xCtx) lowerCS(op string, ins Instr) error {
+	if len(ins.Args) != 3 || !s390xIsRReg(ins.Args[0]) || !s390xIsRReg(ins.Args[1]) || ins.Args[2].Kind != OpMem {
+		return fmt.Errorf("s390x %s expects R, R, mem: %q", op, ins.Raw)
+	}
+	bits := 32
+	if op == "CSG" {
+		bits = 64
+	}
+	ty := fmt.Sprintf("i%d", bits)
+	old, err := c.loadReg(ins.Args[0].Reg)
+	if err != nil {
+		return err
+	}
+	nv, err := c.loadReg(ins.Args[1].Reg)
+	if err != nil {
+		return err
+	}
+	if bits == 32 {
+		old, nv = c.trunc32(old), c.trunc32(nv)
+	}
+	p, err := c.memPtr(ins.Args[2].Mem)
+	if err != nil {
+		return err
+	}
+	cx := c.emit("cmpxchg ptr %s, %s %s, %s %s seq_cst seq_cst, align %d", p, ty, old, ty, nv, bits/8)
+	cur := c.emit("extractvalue {%s, i1} %s, 0", ty, cx)
+	swapped := c.emit("extractvalue {%s, i1} %s, 1", ty, cx)
+	c.setCC(c.emit("select i1 %s, i64 0, i64 1", swapped))
+	// On success the current value is old, so the write is harmless.
+	if bits == 32 {
+		return c.storeLow32(ins.Args[0].Reg, cur)
+	}
+	return c.storeReg(ins.Args[0].Reg, cur)
+}
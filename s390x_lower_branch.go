@@ -0,0 +1,452 @@
+package plan9asm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// s390xBranchMasks maps the extended branch mnemonics to their branch
+// masks.
+var s390xBranchMasks = map[string]int64{
+	"BEQ": s390xCCEq, "BNE": 15 &^ s390xCCEq,
+	"BLT": s390xCCLt, "BLE": s390xCCLt | s390xCCEq,
+	"BGT": s390xCCGt, "BGE": s390xCCGt | s390xCCEq,
+	"BLTU": s390xCCLt | s390xCCOther, "BLEU": s390xCCLt | s390xCCEq | s390xCCOther,
+	"BVS": s390xCCOther, "BVC": 15 &^ s390xCCOther,
+}
+
+// s390xCmpBranchSuffixes maps the condition suffixes of the
+// compare-and-branch instructions to branch masks. CMPBNE does not
+// branch on CC 3, which a compare never sets.
+var s390xCmpBranchSuffixes = map[string]int64{
+	"EQ": s390xCCEq, "NE": s390xCCLt | s390xCCGt,
+	"LT": s390xCCLt, "LE": s390xCCLt | s390xCCEq,
+	"GT": s390xCCGt, "GE": s390xCCGt | s390xCCEq,
+}
+
+// s390xCmpBranch decodes "CMP[W][U]Bcc a, b, target": whether the
+// compare is 32-bit and signed, and its branch mask.
+func s390xCmpBranch(op string) (w32, signed bool, mask int64, ok bool) {
+	for _, p := range []struct {
+		prefix      string
+		w32, signed bool
+	}{
+		{"CMPWUB", true, false}, {"CMPWB", true, true},
+		{"CMPUB", false, false}, {"CMPB", false, true},
+	} {
+		if rest, found := strings.CutPrefix(op, p.prefix); found {
+			mask, ok = s390xCmpBranchSuffixes[rest]
+			return p.w32, p.signed, mask, ok
+		}
+	}
+	return false, false, 0, false
+}
+
+// s390xCmpJumps lists the compare-and-jump instructions "OP $mask, a, b,
+// target": whether they compare 32 bits and are signed.
+var s390xCmpJumps = map[string]struct{ w32, signed bool }{
+	"CRJ": {true, true}, "CGRJ": {false, true}, "CIJ": {true, true}, "CGIJ": {false, true},
+	"CLRJ": {true, false}, "CLGRJ": {false, false}, "CLIJ": {true, false}, "CLGIJ": {false, false},
+}
+
+// s390xIsCondBranch reports whether op is a conditional branch.
+func s390xIsCondBranch(op string) bool {
+	if _, ok := s390xBranchMasks[op]; ok {
+		return true
+	}
+	if _, ok := s390xCmpJumps[op]; ok {
+		return true
+	}
+	if _, _, _, ok := s390xCmpBranch(op); ok {
+		return true
+	}
+	switch op {
+	case "BRC", "BC", "BRCT", "BRCTG":
+		return true
+	}
+	return false
+}
+
+// branchTarget resolves a label, local symbol or n(PC) operand of the
+// instruction at index ii of block bi to a block name.
+func (c *s390xCtx) branchTarget(bi, ii int, op Operand) (string, bool) {
+	switch op.Kind {
+	case OpIdent:
+		return op.Ident, true
+	case OpSym:
+		s := strings.TrimSpace(op.Sym)
+		if strings.HasSuffix(s, "(SB)") {
+			return "", false
+		}
+		return strings.TrimSuffix(s, "<>"), s != ""
+	case OpMem:
+		if op.Mem.Base != PC {
+			return "", false
+		}
+		tbi, ok := c.blockByIdx[c.blockBase[bi]+ii+int(op.Mem.Off)]
+		if !ok {
+			return "", false
+		}
+		return c.blocks[tbi].name, true
+	}
+	return "", false
+}
+
+func (c *s390xCtx) condBr(bi int, cond, tgt string) {
+	fmt.Fprintf(c.b, "  br i1 %s, label %%%s, label %%%s\n", cond, arm64LLVMBlockName(tgt), arm64LLVMBlockName(c.blocks[bi+1].name))
+}
+
+// s390xIndirectReg returns the register of an R or (R) branch target.
+func s390xIndirectReg(o Operand) (Reg, bool) {
+	switch {
+	case s390xIsRReg(o):
+		return o.Reg, true
+	case o.Kind == OpMem && o.Mem.Index == "" && o.Mem.Off == 0 && s390xIsRName(string(o.Mem.Base)):
+		return o.Mem.Base, true
+	}
+	return "", false
+}
+
+// s390xIsLinkReg reports whether a branch target is R14 or (R14), a
+// return.
+func s390xIsLinkReg(o Operand) bool {
+	r, ok := s390xIndirectReg(o)
+	return ok && r == "R14"
+}
+
+func (c *s390xCtx) lowerBranch(bi, ii int, op string, ins Instr) (ok bool, terminated bool, err error) {
+	if mask, found := s390xBranchMasks[op]; found {
+		if len(ins.Args) != 1 {
+			return true, false, fmt.Errorf("s390x %s expects a target: %q", op, ins.Raw)
+		}
+		return true, true, c.condJump(bi, ii, c.ccCond(mask), ins.Args[0], ins)
+	}
+	if w32, signed, mask, found := s390xCmpBranch(op); found {
+		if len(ins.Args) != 3 {
+			return true, false, fmt.Errorf("s390x %s expects a, b, target: %q", op, ins.Raw)
+		}
+		cond, err := c.compareCond(w32, signed, mask, ins.Args[0], ins.Args[1])
+		if err != nil {
+			return true, false, fmt.Errorf("s390x %s %v: %q", op, err, ins.Raw)
+		}
+		return true, true, c.condJump(bi, ii, cond, ins.Args[2], ins)
+	}
+	if cj, found := s390xCmpJumps[op]; found {
+		if len(ins.Args) != 4 || ins.Args[0].Kind != OpImm {
+			return true, false, fmt.Errorf("s390x %s expects $mask, a, b, target: %q", op, ins.Raw)
+		}
+		cond, err := c.compareCond(cj.w32, cj.signed, ins.Args[0].Imm, ins.Args[1], ins.Args[2])
+		if err != nil {
+			return true, false, fmt.Errorf("s390x %s %v: %q", op, err, ins.Raw)
+		}
+		return true, true, c.condJump(bi, ii, cond, ins.Args[3], ins)
+	}
+
+	switch op {
+	case "BRC", "BC":
+		if len(ins.Args) != 2 || ins.Args[0].Kind != OpImm {
+			return true, false, fmt.Errorf("s390x %s expects $mask, target: %q", op, ins.Raw)
+		}
+		return true, true, c.condJump(bi, ii, c.ccCond(ins.Args[0].Imm), ins.Args[1], ins)
+
+	case "BRCT", "BRCTG":
+		// Decrement R (its low word for BRCT) and branch unless it is
+		// now zero.
+		if len(ins.Args) != 2 || !s390xIsRReg(ins.Args[0]) {
+			return true, false, fmt.Errorf("s390x %s expects R, target: %q", op, ins.Raw)
+		}
+		r := ins.Args[0].Reg
+		v, err := c.loadReg(r)
+		if err != nil {
+			return true, false, err
+		}
+		ty := "i64"
+		if op == "BRCT" {
+			v, ty = c.trunc32(v), "i32"
+		}
+		v = c.emit("sub %s %s, 1", ty, v)
+		if op == "BRCT" {
+			err = c.storeLow32(r, v)
+		} else {
+			err = c.storeReg(r, v)
+		}
+		if err != nil {
+			return true, false, err
+		}
+		cond := c.emit("icmp ne %s %s, 0", ty, v)
+		return true, true, c.condJump(bi, ii, cond, ins.Args[1], ins)
+
+	case "JMP", "BR":
+		if len(ins.Args) != 1 {
+			return true, false, fmt.Errorf("s390x %s expects 1 operand: %q", op, ins.Raw)
+		}
+		return true, true, c.jump(bi, ii, ins.Args[0], ins)
+
+	case "CALL", "BL":
+		if len(ins.Args) != 1 {
+			return true, false, fmt.Errorf("s390x %s expects 1 operand: %q", op, ins.Raw)
+		}
+		return true, false, c.call(ins.Args[0], ins)
+	}
+	return false, false, nil
+}
+
+// compareCond returns an i1 that is set when comparing a with b gives a
+// condition code the mask selects. The compare-and-branch instructions
+// leave CC alone.
+func (c *s390xCtx) compareCond(w32, signed bool, mask int64, x, y Operand) (string, error) {
+	ty := "i64"
+	if w32 {
+		ty = "i32"
+	}
+	a, b, err := c.cmpOperands(ty, x, y)
+	if err != nil {
+		return "", err
+	}
+	lt := "ult"
+	if signed {
+		lt = "slt"
+	}
+	var terms []string
+	if mask&s390xCCEq != 0 {
+		terms = append(terms, c.emit("icmp eq %s %s, %s", ty, a, b))
+	}
+	if mask&s390xCCLt != 0 {
+		terms = append(terms, c.emit("icmp %s %s %s, %s", lt, ty, a, b))
+	}
+	if mask&s390xCCGt != 0 {
+		terms = append(terms, c.emit("icmp %s %s %s, %s", strings.Replace(lt, "lt", "gt", 1), ty, a, b))
+	}
+	if len(terms) == 0 {
+		return "false", nil
+	}
+	cond := terms[0]
+	for _, t := range terms[1:] {
+		cond = c.emit("or i1 %s, %s", cond, t)
+	}
+	return cond, nil
+}
+
+// condJump branches to target when the i1 cond is set and falls through
+// otherwise. A target of (R14) is a conditional return.
+func (c *s390xCtx) condJump(bi, ii int, cond string, target Operand, ins Instr) error {
+	switch cond {
+	case "true":
+		return c.jump(bi, ii, target, ins)
+	}
+	if bi+1 >= len(c.blocks) {
+		return fmt.Errorf("s390x %s needs fallthrough block: %q", ins.Op, ins.Raw)
+	}
+	if s390xIsLinkReg(target) {
+		ret := "cond_ret_" + c.newTmp()
+		c.condBr(bi, cond, ret)
+		fmt.Fprintf(c.b, "\n%s:\n", ret)
+		return c.lowerRET()
+	}
+	tgt, ok := c.branchTarget(bi, ii, target)
+	if !ok {
+		return fmt.Errorf("s390x %s invalid target: %q", ins.Op, ins.Raw)
+	}
+	c.condBr(bi, cond, tgt)
+	return nil
+}
+
+// jump lowers an unconditional branch: to a label, a tail call to
+// sym(SB), a return through R14 or an indirect jump through another
+// register.
+func (c *s390xCtx) jump(bi, ii int, target Operand, ins Instr) error {
+	if riscv64IsSBSym(target) {
+		return c.tailCallAndRet(target)
+	}
+	if r, ok := s390xIndirectReg(target); ok {
+		if r == "R14" {
+			return c.lowerRET()
+		}
+		addr, err := c.loadReg(r)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(c.b, "  call void asm sideeffect \"br $0\", %q(i64 %s)\n", "r,~{memory}", addr)
+		c.lowerRetZero()
+		return nil
+	}
+	tgt, ok := c.branchTarget(bi, ii, target)
+	if !ok {
+		return fmt.Errorf("s390x %s invalid target: %q", ins.Op, ins.Raw)
+	}
+	c.br(tgt)
+	return nil
+}
+
+// call lowers "BL sym(SB)" and the indirect "BL (R)".
+func (c *s390xCtx) call(target Operand, ins Instr) error {
+	if riscv64IsSBSym(target) {
+		return c.callSym(target)
+	}
+	r, ok := s390xIndirectReg(target)
+	if !ok {
+		return fmt.Errorf("s390x %s expects symbol(SB) or (R): %q", ins.Op, ins.Raw)
+	}
+	addr, err := c.loadReg(r)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(c.b, "  call void asm sideeffect \"basr %%r14, $0\", %q(i64 %s)\n", "r,~{r14},~{memory}", addr)
+	return nil
+}
+
+// callArgs reads csig's arguments from the ABIInternal registers (or
+// csig.ArgRegs), assembling aggregates from consecutive registers.
+func (c *s390xCtx) callArgs(csig FuncSig) ([]string, error) {
+	args := make([]string, 0, len(csig.Args))
+	var cur s390xArgCursor
+	for i, argTy := range csig.Args {
+		if len(csig.ArgRegs) > 0 {
+			if i >= len(csig.ArgRegs) {
+				return nil, fmt.Errorf("no register for arg %d", i)
+			}
+			v, err := c.loadReg(csig.ArgRegs[i])
+			if err != nil {
+				return nil, err
+			}
+			val, err := c.regToValue(v, argTy)
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, fmt.Sprintf("%s %s", argTy, val))
+			continue
+		}
+		fields, isAgg := parseLiteralStructFields(argTy)
+		if !isAgg {
+			fields = []LLVMType{argTy}
+		}
+		agg := "undef"
+		val := ""
+		for fi, fTy := range fields {
+			r, ok := cur.next(fTy)
+			if !ok {
+				return nil, fmt.Errorf("too many register args")
+			}
+			v, err := c.loadReg(r)
+			if err != nil {
+				return nil, err
+			}
+			if val, err = c.regToValue(v, fTy); err != nil {
+				return nil, err
+			}
+			if isAgg {
+				agg = c.emit("insertvalue %s %s, %s %s, %d", argTy, agg, fTy, val, fi)
+				val = agg
+			}
+		}
+		args = append(args, fmt.Sprintf("%s %s", argTy, val))
+	}
+	return args, nil
+}
+
+// storeCallResult writes a call's result to the ABIInternal result
+// registers.
+func (c *s390xCtx) storeCallResult(ty LLVMType, v string) error {
+	fields, isAgg := parseLiteralStructFields(ty)
+	if !isAgg {
+		fields = []LLVMType{ty}
+	}
+	var cur s390xArgCursor
+	for fi, fTy := range fields {
+		r, ok := cur.next(fTy)
+		if !ok {
+			return fmt.Errorf("too many register results")
+		}
+		fv := v
+		if isAgg {
+			fv = c.emit("extractvalue %s %s, %d", ty, v, fi)
+		}
+		v64, ok, err := c.valueAsReg(fTy, fv)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("unsupported result type %s", fTy)
+		}
+		if err := c.storeReg(r, v64); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *s390xCtx) callSym(symOp Operand) error {
+	callee := c.resolve(strings.TrimSuffix(strings.TrimSpace(symOp.Sym), "(SB)"))
+	// Syscall stubs invoke runtime entersyscall/exitsyscall around SYSCALL.
+	// llgo runtime does not require these scheduler hooks at this layer.
+	if callee == "runtime.entersyscall" || callee == "runtime.exitsyscall" {
+		return nil
+	}
+	csig, ok := c.sigs[callee]
+	if !ok {
+		csig = FuncSig{Name: callee, Ret: Void}
+	}
+	args, err := c.callArgs(csig)
+	if err != nil {
+		return fmt.Errorf("s390x call %q: %v", callee, err)
+	}
+	if csig.Ret == Void {
+		fmt.Fprintf(c.b, "  call void %s(%s)\n", llvmGlobal(callee), strings.Join(args, ", "))
+		return nil
+	}
+	r := c.emit("call %s %s(%s)", csig.Ret, llvmGlobal(callee), strings.Join(args, ", "))
+	if err := c.storeCallResult(csig.Ret, r); err != nil {
+		return fmt.Errorf("s390x call %q: %v", callee, err)
+	}
+	return nil
+}
+
+func (c *s390xCtx) tailCallAndRet(symOp Operand) error {
+	callee := c.resolve(strings.TrimSuffix(strings.TrimSpace(symOp.Sym), "(SB)"))
+	csig, ok := c.sigs[callee]
+	if !ok {
+		// Cross-package trampoline (e.g. sync/atomic -> internal/runtime/atomic).
+		// Without an explicit signature, fall back to the caller's.
+		csig = c.sig
+		csig.Name = callee
+	}
+
+	// A JMP to a function with the caller's signature is an ABI0 tail call
+	// made before any register shuffling, so pass the caller's own args.
+	var args []string
+	if len(csig.ArgRegs) == 0 && sameLLVMTypes(csig.Args, c.sig.Args) && csig.Ret == c.sig.Ret {
+		for i, ty := range csig.Args {
+			args = append(args, fmt.Sprintf("%s %%arg%d", ty, i))
+		}
+	} else {
+		var err error
+		if args, err = c.callArgs(csig); err != nil {
+			return fmt.Errorf("s390x tailcall %q: %v", callee, err)
+		}
+	}
+
+	if csig.Ret == Void {
+		fmt.Fprintf(c.b, "  call void %s(%s)\n", llvmGlobal(callee), strings.Join(args, ", "))
+		if len(c.fpResults) > 0 {
+			return c.lowerRET()
+		}
+		c.lowerRetZero()
+		return nil
+	}
+	r := c.emit("call %s %s(%s)", csig.Ret, llvmGlobal(callee), strings.Join(args, ", "))
+	if c.sig.Ret == Void {
+		c.b.WriteString("  ret void\n")
+		return nil
+	}
+	if csig.Ret != c.sig.Ret {
+		v64, ok, err := c.valueAsI64(csig.Ret, r)
+		if err != nil || !ok {
+			return fmt.Errorf("s390x tailcall return mismatch: caller %s callee %s", c.sig.Ret, csig.Ret)
+		}
+		if r, err = c.i64ToValue(v64, c.sig.Ret); err != nil {
+			return fmt.Errorf("s390x tailcall return mismatch: caller %s callee %s", c.sig.Ret, csig.Ret)
+		}
+	}
+	fmt.Fprintf(c.b, "  ret %s %s\n", c.sig.Ret, r)
+	return nil
+}
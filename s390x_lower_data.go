@@ -0,0 +1,459 @@
+package plan9asm
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+type s390xMovKind int
+
+const (
+	s390xMovInt    s390xMovKind = iota
+	s390xMovRev                 // byte-reversed (LRVG, STRVG, ...)
+	s390xMovSingle              // FMOVS: a single in memory and in the high word of F
+)
+
+// s390xMovForm gives the access width and extension of a MOV form; float
+// forms move F registers.
+type s390xMovForm struct {
+	bits   int
+	signed bool
+	float  bool
+	kind   s390xMovKind
+}
+
+var s390xMovForms = map[string]s390xMovForm{
+	"MOVD":   {bits: 64, signed: true},
+	"MOVW":   {bits: 32, signed: true},
+	"MOVWZ":  {bits: 32},
+	"MOVH":   {bits: 16, signed: true},
+	"MOVHZ":  {bits: 16},
+	"MOVB":   {bits: 8, signed: true},
+	"MOVBZ":  {bits: 8},
+	"MOVDBR": {bits: 64, kind: s390xMovRev},
+	"MOVWBR": {bits: 32, kind: s390xMovRev},
+	"MOVHBR": {bits: 16, kind: s390xMovRev},
+	"FMOVD":  {bits: 64, float: true},
+	"FMOVS":  {bits: 32, float: true, kind: s390xMovSingle},
+}
+
+// s390xCondMoves maps the conditional moves "MOVDEQ src, dst" to the
+// branch mask they test.
+var s390xCondMoves = map[string]int64{
+	"MOVDEQ": 8, "MOVDNE": 7, "MOVDLT": 4, "MOVDLE": 12, "MOVDGT": 2, "MOVDGE": 10,
+}
+
+// s390xStorageOps maps the storage-to-storage logical operations to the
+// operation they apply to each destination byte.
+var s390xStorageOps = map[string]string{
+	"MVC": "", "XC": "xor", "NC": "and", "OC": "or",
+}
+
+func (c *s390xCtx) lowerData(op string, ins Instr) (ok bool, terminated bool, err error) {
+	if mask, found := s390xCondMoves[op]; found {
+		return true, false, c.lowerCondMove(op, mask, ins.Args, ins)
+	}
+	if kind, found := s390xStorageOps[op]; found {
+		return true, false, c.lowerStorageOp(op, kind, ins)
+	}
+	switch op {
+	case "LOCR", "LOCGR":
+		// "LOCGR $mask, src, dst".
+		if len(ins.Args) != 3 || ins.Args[0].Kind != OpImm {
+			return true, false, fmt.Errorf("s390x %s expects $mask, src, dst: %q", op, ins.Raw)
+		}
+		return true, false, c.lowerCondMove(op, ins.Args[0].Imm, ins.Args[1:], ins)
+	case "LA", "LAY":
+		if len(ins.Args) != 2 || ins.Args[0].Kind != OpMem || !s390xIsRReg(ins.Args[1]) {
+			return true, false, fmt.Errorf("s390x %s expects mem, Rd: %q", op, ins.Raw)
+		}
+		addr, err := c.addrI64(ins.Args[0].Mem)
+		if err != nil {
+			return true, false, err
+		}
+		return true, false, c.storeReg(ins.Args[1].Reg, addr)
+	case "LGDR", "LDGR":
+		// The bits move unchanged between an F and an R register.
+		if len(ins.Args) != 2 || ins.Args[0].Kind != OpReg || ins.Args[1].Kind != OpReg {
+			return true, false, fmt.Errorf("s390x %s expects 2 registers: %q", op, ins.Raw)
+		}
+		v, err := c.loadReg(ins.Args[0].Reg)
+		if err != nil {
+			return true, false, err
+		}
+		return true, false, c.storeReg(ins.Args[1].Reg, v)
+	case "LMG", "LMY", "STMG", "STMY":
+		return true, false, c.lowerMultiple(op, ins)
+	case "CLC":
+		return true, false, c.lowerCLC(ins)
+	}
+
+	f, found := s390xMovForms[op]
+	if !found {
+		return false, false, nil
+	}
+	if len(ins.Args) != 2 {
+		return true, false, fmt.Errorf("s390x %s expects 2 operands: %q", op, ins.Raw)
+	}
+	src, dst := ins.Args[0], ins.Args[1]
+	isF := s390xIsFRegOp
+	switch {
+	case s390xIsVecOp(src) || s390xIsVecOp(dst):
+		return true, false, fmt.Errorf("s390x %s cannot move vector registers: %q", op, ins.Raw)
+	case f.float:
+		if (!isF(src) && !isF(dst)) || s390xIsRReg(src) || s390xIsRReg(dst) {
+			return true, false, fmt.Errorf("s390x %s moves between F registers and memory: %q", op, ins.Raw)
+		}
+	case isF(src) || isF(dst):
+		return true, false, fmt.Errorf("s390x %s cannot move F registers (use LGDR/LDGR): %q", op, ins.Raw)
+	}
+	if src.Kind != OpReg && dst.Kind != OpReg && src.Kind != OpImm {
+		return true, false, fmt.Errorf("s390x %s needs a register operand: %q", op, ins.Raw)
+	}
+
+	v, err := c.movSrc(src, f)
+	if err != nil {
+		return true, false, fmt.Errorf("s390x %s: %v: %q", op, err, ins.Raw)
+	}
+	if err := c.movDst(dst, f, v); err != nil {
+		return true, false, fmt.Errorf("s390x %s: %v: %q", op, err, ins.Raw)
+	}
+	return true, false, nil
+}
+
+// movSrc reads a MOV source as register bits.
+func (c *s390xCtx) movSrc(src Operand, f s390xMovForm) (string, error) {
+	switch src.Kind {
+	case OpImm:
+		// Float immediates hold double bits.
+		v := src.Imm
+		switch {
+		case f.kind == s390xMovSingle:
+			v = int64(math.Float32bits(float32(math.Float64frombits(uint64(v))))) << 32
+		case f.bits == 64 || f.float:
+		case f.signed:
+			v = v << (64 - f.bits) >> (64 - f.bits)
+		default:
+			v = int64(uint64(v) << (64 - f.bits) >> (64 - f.bits))
+		}
+		return fmt.Sprintf("%d", v), nil
+	case OpReg:
+		v, err := c.loadReg(src.Reg)
+		if err != nil {
+			return "", err
+		}
+		if f.float {
+			return v, nil
+		}
+		if f.kind == s390xMovRev {
+			return c.byteRev(v, f.bits), nil
+		}
+		return c.narrow(v, f.bits, f.signed), nil
+	case OpMem:
+		addr, err := c.addrI64(src.Mem)
+		if err != nil {
+			return "", err
+		}
+		return c.loadAs(addr, f), nil
+	case OpFP:
+		v, err := c.evalFPValue64(src)
+		if err != nil {
+			return "", err
+		}
+		if f.kind == s390xMovSingle {
+			return c.emit("shl i64 %s, 32", v), nil
+		}
+		if f.float {
+			return v, nil
+		}
+		return c.narrow(v, f.bits, f.signed), nil
+	case OpFPAddr:
+		return c.evalFPAddr64(src)
+	case OpSym:
+		if s390xIsAddrSym(src) {
+			return c.symAddr(src.Sym)
+		}
+		s := strings.TrimSpace(src.Sym)
+		if !strings.HasSuffix(s, "(SB)") {
+			break
+		}
+		p, err := c.ptrFromSB(s)
+		if err != nil {
+			return "", err
+		}
+		return c.loadAs(c.emit("ptrtoint ptr %s to i64", p), f), nil
+	}
+	return "", fmt.Errorf("unsupported source %s", src.String())
+}
+
+// movDst writes v to a MOV destination.
+func (c *s390xCtx) movDst(dst Operand, f s390xMovForm, v string) error {
+	switch dst.Kind {
+	case OpReg:
+		return c.storeReg(dst.Reg, v)
+	case OpMem:
+		addr, err := c.addrI64(dst.Mem)
+		if err != nil {
+			return err
+		}
+		c.storeAs(addr, f, v)
+		return nil
+	case OpFP:
+		if f.kind == s390xMovSingle {
+			v = c.emit("lshr i64 %s, 32", v)
+		}
+		return c.storeFPResult64(dst.FPOffset, v)
+	case OpSym:
+		s := strings.TrimSpace(dst.Sym)
+		if strings.HasPrefix(s, "$") || !strings.HasSuffix(s, "(SB)") {
+			break
+		}
+		p, err := c.ptrFromSB(s)
+		if err != nil {
+			return err
+		}
+		c.storeAs(c.emit("ptrtoint ptr %s to i64", p), f, v)
+		return nil
+	}
+	return fmt.Errorf("unsupported destination %s", dst.String())
+}
+
+// byteRev reverses the low bits of v64 and zero-extends the result.
+func (c *s390xCtx) byteRev(v64 string, bits int) string {
+	if bits < 64 {
+		v64 = c.emit("trunc i64 %s to i%d", v64, bits)
+	}
+	v := c.emit("call i%d @llvm.bswap.i%d(i%d %s)", bits, bits, bits, v64)
+	if bits == 64 {
+		return v
+	}
+	return c.extend(v, bits, false)
+}
+
+// loadAs performs a MOV form's load from the i64 address addr.
+func (c *s390xCtx) loadAs(addr string, f s390xMovForm) string {
+	p := c.emit("inttoptr i64 %s to ptr", addr)
+	v := c.emit("load i%d, ptr %s, align 1", f.bits, p)
+	if f.kind == s390xMovRev {
+		v = c.emit("call i%d @llvm.bswap.i%d(i%d %s)", f.bits, f.bits, f.bits, v)
+	}
+	if f.bits == 64 {
+		return v
+	}
+	v = c.extend(v, f.bits, f.signed)
+	if f.kind == s390xMovSingle {
+		v = c.emit("shl i64 %s, 32", v)
+	}
+	return v
+}
+
+// storeAs performs a MOV form's store of the register bits v to addr.
+func (c *s390xCtx) storeAs(addr string, f s390xMovForm, v string) {
+	p := c.emit("inttoptr i64 %s to ptr", addr)
+	if f.kind == s390xMovSingle {
+		v = c.emit("lshr i64 %s, 32", v)
+	}
+	if f.bits < 64 {
+		v = c.emit("trunc i64 %s to i%d", v, f.bits)
+	}
+	if f.kind == s390xMovRev {
+		v = c.emit("call i%d @llvm.bswap.i%d(i%d %s)", f.bits, f.bits, f.bits, v)
+	}
+	fmt.Fprintf(c.b, "  store i%d %s, ptr %s, align 1\n", f.bits, v, p)
+}
+
+// lowerCondMove lowers "src, dst" moves made when the condition code is
+// one mask selects. LOCR moves the low word only.
+func (c *s390xCtx) lowerCondMove(op string, mask int64, args []Operand, ins Instr) error {
+	if len(args) != 2 || !s390xIsRReg(args[1]) {
+		return fmt.Errorf("s390x %s expects src, Rd: %q", op, ins.Raw)
+	}
+	src, err := c.eval64(args[0])
+	if err != nil {
+		return err
+	}
+	old, err := c.loadReg(args[1].Reg)
+	if err != nil {
+		return err
+	}
+	if op == "LOCR" {
+		hi := c.emit("and i64 %s, -4294967296", old)
+		lo := c.emit("and i64 %s, 4294967295", src)
+		src = c.emit("or i64 %s, %s", hi, lo)
+	}
+	v := c.emit("select i1 %s, i64 %s, i64 %s", c.ccCond(mask), src, old)
+	return c.storeReg(args[1].Reg, v)
+}
+
+// lowerMultiple lowers "LMG mem, Ra, Rb" and "STMG Ra, Rb, mem", which
+// load or store the registers Ra through Rb, wrapping from R15 to R0, at
+// consecutive words. LMY and STMY move the low words, leaving the high
+// words of the registers unchanged.
+func (c *s390xCtx) lowerMultiple(op string, ins Instr) error {
+	if len(ins.Args) != 3 {
+		return fmt.Errorf("s390x %s expects 3 operands: %q", op, ins.Raw)
+	}
+	load := strings.HasPrefix(op, "L")
+	mem, first, last := ins.Args[2], ins.Args[0], ins.Args[1]
+	if load {
+		mem, first, last = ins.Args[0], ins.Args[1], ins.Args[2]
+	}
+	if !s390xIsRReg(first) || !s390xIsRReg(last) {
+		return fmt.Errorf("s390x %s expects a register range: %q", op, ins.Raw)
+	}
+	size := int64(8)
+	if strings.HasSuffix(op, "Y") {
+		size = 4
+	}
+	n := (s390xRegNum(last.Reg)-s390xRegNum(first.Reg))&15 + 1
+	var base string
+	switch mem.Kind {
+	case OpMem:
+		a, err := c.addrI64(mem.Mem)
+		if err != nil {
+			return err
+		}
+		base = a
+	case OpFP:
+		if size != 8 {
+			return fmt.Errorf("s390x %s cannot address FP slots: %q", op, ins.Raw)
+		}
+	default:
+		return fmt.Errorf("s390x %s expects a memory operand: %q", op, ins.Raw)
+	}
+	for i := 0; i < n; i++ {
+		r := s390xRegName(s390xRegNum(first.Reg) + i)
+		off := int64(i) * size
+		if mem.Kind == OpFP {
+			slot := Operand{Kind: OpFP, FPOffset: mem.FPOffset + off}
+			if load {
+				v, err := c.evalFPValue64(slot)
+				if err != nil {
+					return err
+				}
+				if err := c.storeReg(r, v); err != nil {
+					return err
+				}
+				continue
+			}
+			v, err := c.loadReg(r)
+			if err != nil {
+				return err
+			}
+			if err := c.storeFPResult64(slot.FPOffset, v); err != nil {
+				return err
+			}
+			continue
+		}
+		p := c.emit("add i64 %s, %d", base, off)
+		p = c.emit("inttoptr i64 %s to ptr", p)
+		if load {
+			v := c.emit("load i%d, ptr %s, align 1", size*8, p)
+			if size == 4 {
+				old, err := c.loadReg(r)
+				if err != nil {
+					return err
+				}
+				hi := c.emit("and i64 %s, -4294967296", old)
+				v = c.emit("zext i32 %s to i64", v)
+				v = c.emit("or i64 %s, %s", hi, v)
+			}
+			if err := c.storeReg(r, v); err != nil {
+				return err
+			}
+			continue
+		}
+		v, err := c.loadReg(r)
+		if err != nil {
+			return err
+		}
+		if size == 4 {
+			v = c.emit("trunc i64 %s to i32", v)
+		}
+		fmt.Fprintf(c.b, "  store i%d %s, ptr %s, align 1\n", size*8, v, p)
+	}
+	return nil
+}
+
+// storageOperands decodes "$len, src, dst" of the storage-to-storage
+// instructions into the length and both addresses.
+func (c *s390xCtx) storageOperands(op string, ins Instr) (n int64, src, dst string, err error) {
+	if len(ins.Args) != 3 || ins.Args[0].Kind != OpImm || ins.Args[1].Kind != OpMem || ins.Args[2].Kind != OpMem {
+		return 0, "", "", fmt.Errorf("s390x %s expects $len, mem, mem: %q", op, ins.Raw)
+	}
+	n = ins.Args[0].Imm
+	if n < 1 || n > 256 {
+		return 0, "", "", fmt.Errorf("s390x %s length must be 1-256: %q", op, ins.Raw)
+	}
+	if src, err = c.addrI64(ins.Args[1].Mem); err != nil {
+		return 0, "", "", err
+	}
+	if dst, err = c.addrI64(ins.Args[2].Mem); err != nil {
+		return 0, "", "", err
+	}
+	return n, src, dst, nil
+}
+
+// lowerStorageOp lowers MVC, XC, NC and OC "$len, src, dst" as a byte
+// loop, since the operands may overlap: MVC then propagates bytes, and
+// "XC $n, x, x" clears x. The bitwise forms set CC to 0 when every result
+// byte is zero and to 1 otherwise.
+func (c *s390xCtx) lowerStorageOp(op, kind string, ins Instr) error {
+	n, src, dst, err := c.storageOperands(op, ins)
+	if err != nil {
+		return err
+	}
+	id := c.newTmp()
+	entry, loop, done := "ss_entry_"+id, "ss_loop_"+id, "ss_done_"+id
+	fmt.Fprintf(c.b, "  br label %%%s\n\n%s:\n  br label %%%s\n\n%s:\n", entry, entry, loop, loop)
+	i := c.emit("phi i64 [ 0, %%%s ], [ %%%s_next, %%%s ]", entry, id, loop)
+	acc := c.emit("phi i8 [ 0, %%%s ], [ %%%s_acc, %%%s ]", entry, id, loop)
+	sp := c.emit("add i64 %s, %s", src, i)
+	sp = c.emit("inttoptr i64 %s to ptr", sp)
+	dp := c.emit("add i64 %s, %s", dst, i)
+	dp = c.emit("inttoptr i64 %s to ptr", dp)
+	v := c.emit("load i8, ptr %s", sp)
+	if kind != "" {
+		d := c.emit("load i8, ptr %s", dp)
+		v = c.emit("%s i8 %s, %s", kind, d, v)
+	}
+	fmt.Fprintf(c.b, "  store i8 %s, ptr %s\n", v, dp)
+	fmt.Fprintf(c.b, "  %%%s_acc = or i8 %s, %s\n", id, acc, v)
+	fmt.Fprintf(c.b, "  %%%s_next = add i64 %s, 1\n", id, i)
+	more := c.emit("icmp ult i64 %%%s_next, %d", id, n)
+	fmt.Fprintf(c.b, "  br i1 %s, label %%%s, label %%%s\n\n%s:\n", more, loop, done, done)
+	if kind != "" {
+		c.setCCZero("i8", "%"+id+"_acc")
+	}
+	return nil
+}
+
+// lowerCLC lowers "CLC $len, a, b", an unsigned compare of the bytes at a
+// with those at b, setting CC like CMPU.
+func (c *s390xCtx) lowerCLC(ins Instr) error {
+	n, a, b, err := c.storageOperands("CLC", ins)
+	if err != nil {
+		return err
+	}
+	id := c.newTmp()
+	entry, loop, next, done := "clc_entry_"+id, "clc_loop_"+id, "clc_next_"+id, "clc_done_"+id
+	fmt.Fprintf(c.b, "  br label %%%s\n\n%s:\n  br label %%%s\n\n%s:\n", entry, entry, loop, loop)
+	i := c.emit("phi i64 [ 0, %%%s ], [ %%%s_i1, %%%s ]", entry, id, next)
+	ap := c.emit("add i64 %s, %s", a, i)
+	ap = c.emit("inttoptr i64 %s to ptr", ap)
+	bp := c.emit("add i64 %s, %s", b, i)
+	bp = c.emit("inttoptr i64 %s to ptr", bp)
+	av := c.emit("load i8, ptr %s", ap)
+	bv := c.emit("load i8, ptr %s", bp)
+	lt := c.emit("icmp ult i8 %s, %s", av, bv)
+	diffCC := c.emit("select i1 %s, i64 1, i64 2", lt)
+	eq := c.emit("icmp eq i8 %s, %s", av, bv)
+	fmt.Fprintf(c.b, "  br i1 %s, label %%%s, label %%%s\n\n%s:\n", eq, next, done, next)
+	fmt.Fprintf(c.b, "  %%%s_i1 = add i64 %s, 1\n", id, i)
+	more := c.emit("icmp ult i64 %%%s_i1, %d", id, n)
+	fmt.Fprintf(c.b, "  br i1 %s, label %%%s, label %%%s\n\n%s:\n", more, loop, done, done)
+	cc := c.emit("phi i64 [ %s, %%%s ], [ 0, %%%s ]", diffCC, loop, next)
+	c.setCC(cc)
+	return nil
+}
@@ -0,0 +1,314 @@
+package plan9asm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// loadFP reads an F register as a double, or as a float from its high
+// word.
+func (c *s390xCtx) loadFP(r Reg, single bool) (string, error) {
+	v, err := c.loadReg(r)
+	if err != nil {
+		return "", err
+	}
+	if single {
+		return c.regToValue(v, LLVMType("float"))
+	}
+	return c.emit("bitcast i64 %s to double", v), nil
+}
+
+func (c *s390xCtx) storeFP(r Reg, single bool, v string) error {
+	ty := LLVMType("double")
+	if single {
+		ty = LLVMType("float")
+	}
+	bits, _, err := c.valueAsReg(ty, v)
+	if err != nil {
+		return err
+	}
+	return c.storeReg(r, bits)
+}
+
+// setCCFloat sets the condition code of a floating-point result: 0 for
+// zero, 1 for negative, 2 for positive and 3 for NaN.
+func (c *s390xCtx) setCCFloat(ty, v string) {
+	c.setCCFloatCompare(ty, v, "0.0")
+}
+
+// setCCFloatCompare sets the condition code of comparing a with b: 0 for
+// equal, 1 for low, 2 for high and 3 for unordered.
+func (c *s390xCtx) setCCFloatCompare(ty, a, b string) {
+	lt := c.emit("fcmp olt %s %s, %s", ty, a, b)
+	gt := c.emit("fcmp ogt %s %s, %s", ty, a, b)
+	uno := c.emit("fcmp uno %s %s, %s", ty, a, b)
+	cc := c.emit("select i1 %s, i64 2, i64 0", gt)
+	cc = c.emit("select i1 %s, i64 1, i64 %s", lt, cc)
+	c.setCC(c.emit("select i1 %s, i64 3, i64 %s", uno, cc))
+}
+
+// s390xCheckFRegs reports whether ins has exactly n operands, all of them
+// F registers.
+func s390xCheckFRegs(ins Instr, n int) bool {
+	if len(ins.Args) != n {
+		return false
+	}
+	for _, a := range ins.Args {
+		if !s390xIsFRegOp(a) {
+			return false
+		}
+	}
+	return true
+}
+
+// s390xFPUnary describes the "OP [fs,] fd" operations: the intrinsic (or,
+// with a leading '-', instruction) computing them, whether they work on
+// singles and whether they set CC from the result.
+var s390xFPUnary = map[string]struct {
+	fn     string
+	single bool
+	cc     bool
+}{
+	"FABS": {"fabs", false, true}, "LPDFR": {"fabs", false, false},
+	"FNABS": {"-fnabs", false, true}, "LNDFR": {"-fnabs", false, false},
+	"FNEG": {"-fneg", false, false}, "LCDBR": {"-fneg", false, true},
+	"FNEGS": {"-fneg", true, true},
+	"LTDBR": {"-", false, true}, "LTEBR": {"-", true, true},
+	"FSQRT": {"sqrt", false, false}, "FSQRTS": {"sqrt", true, false},
+}
+
+// s390xFIRounding maps the rounding mode operand of FIDBR and FIEBR to the
+// intrinsic applying it; mode 0, the current mode, is round to nearest
+// even as Go leaves it.
+var s390xFIRounding = map[int64]string{
+	0: "roundeven", 1: "round", 3: "roundeven", 4: "roundeven",
+	5: "trunc", 6: "ceil", 7: "floor",
+}
+
+// s390xFPConvs describes the conversions between the general and F
+// registers: to floating point (single E or double D) from a signed or
+// logical word F or doubleword G, or back.
+var s390xFPConvs = map[string]struct {
+	toFloat, signed, single bool
+	bits                    int
+}{
+	"CEFBRA": {true, true, true, 32}, "CDFBRA": {true, true, false, 32},
+	"CEGBRA": {true, true, true, 64}, "CDGBRA": {true, true, false, 64},
+	"CELFBR": {true, false, true, 32}, "CDLFBR": {true, false, false, 32},
+	"CELGBR": {true, false, true, 64}, "CDLGBR": {true, false, false, 64},
+	"CFEBRA": {false, true, true, 32}, "CFDBRA": {false, true, false, 32},
+	"CGEBRA": {false, true, true, 64}, "CGDBRA": {false, true, false, 64},
+	"CLFEBR": {false, false, true, 32}, "CLFDBR": {false, false, false, 32},
+	"CLGEBR": {false, false, true, 64}, "CLGDBR": {false, false, false, 64},
+}
+
+func (c *s390xCtx) lowerFP(op string, ins Instr) (ok bool, terminated bool, err error) {
+	switch op {
+	case "FADD", "FSUB", "FMUL", "FDIV", "FADDS", "FSUBS", "FMULS", "FDIVS":
+		// "OP fs, fd" computes fd = fd OP fs; the additions and
+		// subtractions set CC.
+		if !s390xCheckFRegs(ins, 2) {
+			return true, false, fmt.Errorf("s390x %s expects F, F: %q", op, ins.Raw)
+		}
+		single := strings.HasSuffix(op, "S")
+		ty := "double"
+		if single {
+			ty = "float"
+		}
+		b, err := c.loadFP(ins.Args[0].Reg, single)
+		if err != nil {
+			return true, false, err
+		}
+		a, err := c.loadFP(ins.Args[1].Reg, single)
+		if err != nil {
+			return true, false, err
+		}
+		v := c.emit("f%s %s %s, %s", strings.ToLower(op[1:4]), ty, a, b)
+		if strings.HasPrefix(op, "FADD") || strings.HasPrefix(op, "FSUB") {
+			c.setCCFloat(ty, v)
+		}
+		return true, false, c.storeFP(ins.Args[1].Reg, single, v)
+
+	case "FMADD", "FMSUB", "FMADDS", "FMSUBS":
+		// "OP f1, f2, f3": f3 = f1*f2 ± f3.
+		if !s390xCheckFRegs(ins, 3) {
+			return true, false, fmt.Errorf("s390x %s expects three F registers: %q", op, ins.Raw)
+		}
+		single := strings.HasSuffix(op, "S")
+		ty, f := "double", "f64"
+		if single {
+			ty, f = "float", "f32"
+		}
+		var in [3]string
+		for i := range in {
+			if in[i], err = c.loadFP(ins.Args[i].Reg, single); err != nil {
+				return true, false, err
+			}
+		}
+		if strings.HasPrefix(op, "FMSUB") {
+			in[2] = c.emit("fneg %s %s", ty, in[2])
+		}
+		v := c.emit("call %s @llvm.fma.%s(%s %s, %s %s, %s %s)", ty, f, ty, in[0], ty, in[1], ty, in[2])
+		return true, false, c.storeFP(ins.Args[2].Reg, single, v)
+
+	case "FCMPU", "FCMPO", "CEBR", "KEBR":
+		// Both compares set CC the same way; FCMPO also signals on
+		// quiet NaNs.
+		if !s390xCheckFRegs(ins, 2) {
+			return true, false, fmt.Errorf("s390x %s expects F, F: %q", op, ins.Raw)
+		}
+		single := op == "CEBR" || op == "KEBR"
+		ty := "double"
+		if single {
+			ty = "float"
+		}
+		a, err := c.loadFP(ins.Args[0].Reg, single)
+		if err != nil {
+			return true, false, err
+		}
+		b, err := c.loadFP(ins.Args[1].Reg, single)
+		if err != nil {
+			return true, false, err
+		}
+		c.setCCFloatCompare(ty, a, b)
+		return true, false, nil
+
+	case "CPSDR":
+		// "CPSDR f1, f2, f3": f3 gets the magnitude of f2 and the sign
+		// of f1.
+		if !s390xCheckFRegs(ins, 3) {
+			return true, false, fmt.Errorf("s390x CPSDR expects three F registers: %q", ins.Raw)
+		}
+		sign, err := c.loadFP(ins.Args[0].Reg, false)
+		if err != nil {
+			return true, false, err
+		}
+		mag, err := c.loadFP(ins.Args[1].Reg, false)
+		if err != nil {
+			return true, false, err
+		}
+		v := c.emit("call double @llvm.copysign.f64(double %s, double %s)", mag, sign)
+		return true, false, c.storeFP(ins.Args[2].Reg, false, v)
+
+	case "LEDBR", "LDEBR":
+		// LEDBR rounds a double to single, LDEBR widens.
+		if !s390xCheckFRegs(ins, 2) {
+			return true, false, fmt.Errorf("s390x %s expects F, F: %q", op, ins.Raw)
+		}
+		toSingle := op == "LEDBR"
+		v, err := c.loadFP(ins.Args[0].Reg, !toSingle)
+		if err != nil {
+			return true, false, err
+		}
+		if toSingle {
+			v = c.emit("fptrunc double %s to float", v)
+		} else {
+			v = c.emit("fpext float %s to double", v)
+		}
+		return true, false, c.storeFP(ins.Args[1].Reg, toSingle, v)
+
+	case "FIDBR", "FIEBR":
+		// "FIDBR $mode, fs, fd" rounds to an integral value.
+		if len(ins.Args) != 3 || ins.Args[0].Kind != OpImm || !s390xIsFRegOp(ins.Args[1]) || !s390xIsFRegOp(ins.Args[2]) {
+			return true, false, fmt.Errorf("s390x %s expects $mode, F, F: %q", op, ins.Raw)
+		}
+		fn, found := s390xFIRounding[ins.Args[0].Imm]
+		if !found {
+			return true, false, fmt.Errorf("s390x %s unsupported rounding mode: %q", op, ins.Raw)
+		}
+		single := op == "FIEBR"
+		ty, f := "double", "f64"
+		if single {
+			ty, f = "float", "f32"
+		}
+		v, err := c.loadFP(ins.Args[1].Reg, single)
+		if err != nil {
+			return true, false, err
+		}
+		v = c.emit("call %s @llvm.%s.%s(%s %s)", ty, fn, f, ty, v)
+		return true, false, c.storeFP(ins.Args[2].Reg, single, v)
+	}
+
+	if conv, found := s390xFPConvs[op]; found {
+		return true, false, c.lowerFPConv(op, ins, conv.toFloat, conv.signed, conv.single, conv.bits)
+	}
+
+	u, found := s390xFPUnary[op]
+	if !found {
+		return false, false, nil
+	}
+	// "OP fs, fd" or "OP fd".
+	if !s390xCheckFRegs(ins, 2) && !s390xCheckFRegs(ins, 1) {
+		return true, false, fmt.Errorf("s390x %s expects [F,] F: %q", op, ins.Raw)
+	}
+	ty, f := "double", "f64"
+	if u.single {
+		ty, f = "float", "f32"
+	}
+	a, err := c.loadFP(ins.Args[0].Reg, u.single)
+	if err != nil {
+		return true, false, err
+	}
+	var v string
+	switch u.fn {
+	case "-":
+		v = a
+	case "-fneg":
+		v = c.emit("fneg %s %s", ty, a)
+	case "-fnabs":
+		v = c.emit("call %s @llvm.fabs.%s(%s %s)", ty, f, ty, a)
+		v = c.emit("fneg %s %s", ty, v)
+	default:
+		v = c.emit("call %s @llvm.%s.%s(%s %s)", ty, u.fn, f, ty, a)
+	}
+	if u.cc {
+		c.setCCFloat(ty, v)
+	}
+	return true, false, c.storeFP(ins.Args[len(ins.Args)-1].Reg, u.single, v)
+}
+
+// lowerFPConv lowers the integer conversions: "OP R, F" to floating
+// point, which rounds to nearest even, and "OP F, R" to integer, which
+// truncates and saturates. Word results go to the low word of R and set
+// CC from the source (3 for NaN).
+func (c *s390xCtx) lowerFPConv(op string, ins Instr, toFloat, signed, single bool, bits int) error {
+	ty, f := "double", "f64"
+	if single {
+		ty, f = "float", "f32"
+	}
+	ity := fmt.Sprintf("i%d", bits)
+	if toFloat {
+		if len(ins.Args) != 2 || !s390xIsRReg(ins.Args[0]) || !s390xIsFRegOp(ins.Args[1]) {
+			return fmt.Errorf("s390x %s expects R, F: %q", op, ins.Raw)
+		}
+		x, err := c.loadReg(ins.Args[0].Reg)
+		if err != nil {
+			return err
+		}
+		if bits == 32 {
+			x = c.trunc32(x)
+		}
+		conv := "uitofp"
+		if signed {
+			conv = "sitofp"
+		}
+		return c.storeFP(ins.Args[1].Reg, single, c.emit("%s %s %s to %s", conv, ity, x, ty))
+	}
+	if len(ins.Args) != 2 || !s390xIsFRegOp(ins.Args[0]) || !s390xIsRReg(ins.Args[1]) {
+		return fmt.Errorf("s390x %s expects F, R: %q", op, ins.Raw)
+	}
+	v, err := c.loadFP(ins.Args[0].Reg, single)
+	if err != nil {
+		return err
+	}
+	c.setCCFloat(ty, v)
+	fn := "fptoui"
+	if signed {
+		fn = "fptosi"
+	}
+	r := c.emit("call %s @llvm.%s.sat.%s.%s(%s %s)", ity, fn, ity, f, ty, v)
+	if bits == 32 {
+		return c.storeLow32(ins.Args[1].Reg, r)
+	}
+	return c.storeReg(ins.Args[1].Reg, r)
+}
@@ -0,0 +1,48 @@
+package plan9asm
+
+import "fmt"
+
+func (c *s390xCtx) lowerSyscall(op string, ins Instr) (ok bool, terminated bool, err error) {
+	switch op {
+	case "SYSCALL":
+		// Linux takes the trap number in R1 ("SYSCALL $n" encodes it
+		// instead) and the arguments in R2-R7, and returns the result,
+		// or -errno, in R2.
+		s := c.cfg.syscallSite(ArchS390X, c.b, c.newTmp)
+		switch {
+		case len(ins.Args) == 0:
+			s.Num, err = c.loadReg("R1")
+		case len(ins.Args) == 1 && ins.Args[0].Kind == OpImm:
+			s.Num = fmt.Sprintf("%d", ins.Args[0].Imm)
+		default:
+			return true, false, fmt.Errorf("s390x SYSCALL expects an optional $n: %q", ins.Raw)
+		}
+		if err != nil {
+			return true, false, err
+		}
+		for _, r := range []Reg{"R2", "R3", "R4", "R5", "R6", "R7"} {
+			v, err := c.loadReg(r)
+			if err != nil {
+				return true, false, err
+			}
+			s.Args = append(s.Args, v)
+		}
+		res, err := c.cfg.syscallStrategy().Lower(s)
+		if err != nil {
+			return true, false, err
+		}
+		if err := c.storeReg("R2", res.ret(s)); err != nil {
+			return true, false, err
+		}
+		return true, false, c.storeReg("R3", res.R2)
+
+	case "UNDEF":
+		c.b.WriteString("  call void @llvm.trap()\n  unreachable\n")
+		return true, true, nil
+
+	case "BRRK":
+		c.b.WriteString("  call void @llvm.debugtrap()\n")
+		return true, false, nil
+	}
+	return false, false, nil
+}
@@ -0,0 +1,1233 @@
+package plan9asm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// s390xVecLane is a vector shape: n lanes of bits each.
+type s390xVecLane struct{ bits, n int }
+
+func (l s390xVecLane) vecType() string {
+	return fmt.Sprintf("<%d x i%d>", l.n, l.bits)
+}
+
+func (l s390xVecLane) intrinsicSuffix() string {
+	return fmt.Sprintf("v%di%d", l.n, l.bits)
+}
+
+// wide is the shape with lanes twice as wide.
+func (l s390xVecLane) wide() s390xVecLane {
+	return s390xVecLane{2 * l.bits, l.n / 2}
+}
+
+// s390xVecLanes lists the element sizes of a vector register.
+var s390xVecLanes = []s390xVecLane{{8, 16}, {16, 8}, {32, 4}, {64, 2}}
+
+// s390xVecOp describes a vector operation that works element by element
+// (or on the whole quadword for Q). form is the asmz operand group:
+//
+//	"118"  OP a, [b,] t  t = a kind b
+//	"119"  OP a, [b,] t  t = b kind a (shifts, subtractions)
+//	"117"  OP a, b, t    t = a kind b, compares setting CC in S forms
+//	"120"  OP a, b, c, t t = kind(a, b, c)
+//	"114"  OP a, t       t = kind(a)
+//	"104"  OP $n|R, [b,] t  shift b by n
+//	"109"  VREPI $i, t and VLEI $idx, $i, t
+//	"110"  VGM $start, $end, t
+//	"111"  VREP $i, a, t
+//	"112"  VERIM $n, a, b, t
+//	"mem"  VLE, VSTE and VLREP with an element index or none
+//	"elem" VLGV and VLVG
+type s390xVecOp struct {
+	form string
+	kind string
+	lane s390xVecLane
+	cc   bool
+}
+
+var s390xVecOps = map[string]s390xVecOp{}
+
+func init() {
+	specs := []struct{ base, letters, form, kind string }{
+		{"VA", "B H F G Q", "118", "add"},
+		{"VACC", "B H F G Q", "118", "carry"},
+		{"VS", "B H F G Q", "119", "sub"},
+		{"VSCBI", "B H F G Q", "119", "borrow"},
+		{"VMX", "B H F G", "118", "smax"},
+		{"VMXL", "B H F G", "118", "umax"},
+		{"VMN", "B H F G", "118", "smin"},
+		{"VMNL", "B H F G", "118", "umin"},
+		{"VML", "B HW F", "118", "mul"},
+		{"VMLH", "B H F", "118", "mulhu"},
+		{"VMH", "B H F", "118", "mulh"},
+		{"VMLO", "B H F", "118", "mulo"},
+		{"VMO", "B H F", "118", "mulos"},
+		{"VMLE", "B H F", "118", "mule"},
+		{"VME", "B H F", "118", "mules"},
+		{"VMRH", "B H F G", "118", "mrgh"},
+		{"VMRL", "B H F G", "118", "mrgl"},
+		{"VSUM", "B H", "118", "sum"},
+		{"VSUMG", "H F", "118", "sumg"},
+		{"VSUMQ", "F G", "118", "sumq"},
+		{"VERLLV", "B H F G", "119", "rotl"},
+		{"VESLV", "B H F G", "119", "shl"},
+		{"VESRLV", "B H F G", "119", "lshr"},
+		{"VESRAV", "B H F G", "119", "ashr"},
+		{"VCEQ", "B H F G", "117", "eq"},
+		{"VCH", "B H F G", "117", "sgt"},
+		{"VCHL", "B H F G", "117", "ugt"},
+		{"VFEE", "B H F", "117", "fee"},
+		{"VMAL", "B HW F", "120", "mal"},
+		{"VMALH", "B H F", "120", "malh"},
+		{"VMAH", "B H F", "120", "mah"},
+		{"VMALO", "B H F", "120", "malo"},
+		{"VMAO", "B H F", "120", "mao"},
+		{"VMALE", "B H F", "120", "male"},
+		{"VMAE", "B H F", "120", "mae"},
+		{"VUPLL", "B H F", "114", "upll"},
+		{"VUPLH", "B H F", "114", "uplh"},
+		{"VUPL", "B HW F", "114", "upl"},
+		{"VUPH", "B H F", "114", "uph"},
+		{"VCLZ", "B H F G", "114", "ctlz"},
+		{"VCTZ", "B H F G", "114", "cttz"},
+		{"VLC", "B H F G", "114", "neg"},
+		{"VLP", "B H F G", "114", "abs"},
+		{"VESL", "B H F G", "104", "shl"},
+		{"VESRL", "B H F G", "104", "lshr"},
+		{"VESRA", "B H F G", "104", "ashr"},
+		{"VERLL", "B H F G", "104", "rotl"},
+		{"VLGV", "B H F G", "elem", "get"},
+		{"VLVG", "B H F G", "elem", "set"},
+		{"VREPI", "B H F G", "109", "repi"},
+		{"VLEI", "B H F G", "109", "lei"},
+		{"VGM", "B H F G", "110", "gm"},
+		{"VREP", "B H F G", "111", "rep"},
+		{"VERIM", "B H F G", "112", "erim"},
+		{"VLE", "B H F G", "mem", "le"},
+		{"VSTE", "B H F G", "mem", "ste"},
+		{"VLREP", "B H F G", "mem", "lrep"},
+	}
+	bits := map[string]int{"B": 8, "H": 16, "HW": 16, "F": 32, "G": 64, "Q": 128}
+	for _, s := range specs {
+		for _, l := range strings.Fields(s.letters) {
+			op := s390xVecOp{form: s.form, kind: s.kind, lane: s390xVecLane{bits[l], 128 / bits[l]}}
+			s390xVecOps[s.base+l] = op
+			if s.form == "117" {
+				op.cc = true
+				s390xVecOps[s.base+l+"S"] = op
+			}
+		}
+	}
+}
+
+// s390xVecLogic maps the bitwise operations "OP a, [b,] t" to the
+// function of (a, b) they compute.
+var s390xVecLogic = map[string]string{
+	"VN": "and", "VNC": "andc", "VO": "or", "VX": "xor", "VNO": "nor",
+	"VOC": "orc", "VNN": "nand", "VNX": "nxor",
+}
+
+// s390xVecFP describes the binary floating-point vector operations on
+// doubles. The W forms work on element 0 alone; rev marks the group 119
+// operations that compute b OP a, cc the compares setting CC.
+var s390xVecFP = map[string]struct {
+	kind string
+	rev  bool
+	cc   bool
+}{
+	"FADB": {kind: "fadd"}, "FSDB": {kind: "fsub", rev: true},
+	"FMDB": {kind: "fmul"}, "FDDB": {kind: "fdiv", rev: true},
+	"FCEDB": {kind: "oeq"}, "FCHDB": {kind: "ogt"}, "FCHEDB": {kind: "oge"},
+	"FCEDBS": {kind: "oeq", cc: true}, "FCHDBS": {kind: "ogt", cc: true}, "FCHEDBS": {kind: "oge", cc: true},
+}
+
+// lowerVec lowers the vector facility instructions Go's s390x assembly
+// uses outside the crypto packages: loads and stores, element moves,
+// bitwise, lane and quadword arithmetic, compares, shifts, permutes and
+// the double-precision operations. The Galois-field multiplies (VGFM*),
+// string searches other than VFEE and the packing operations are not
+// lowered.
+func (c *s390xCtx) lowerVec(op string, ins Instr) (ok bool, terminated bool, err error) {
+	if ok, err := c.lowerVecMem(op, ins); ok {
+		return true, false, err
+	}
+	if ok, err := c.lowerVecMove(op, ins); ok {
+		return true, false, err
+	}
+	if ok, err := c.lowerVecQuad(op, ins); ok {
+		return true, false, err
+	}
+	if kind, found := s390xVecLogic[op]; found {
+		return true, false, c.vecBinary(op, ins, false, func(a, b string) string {
+			switch kind {
+			case "andc", "orc":
+				nb := c.emit("xor i128 %s, -1", b)
+				return c.emit("%s i128 %s, %s", kind[:len(kind)-1], a, nb)
+			case "nor", "nand", "nxor":
+				v := c.emit("%s i128 %s, %s", kind[1:], a, b)
+				return c.emit("xor i128 %s, -1", v)
+			}
+			return c.emit("%s i128 %s, %s", kind, a, b)
+		})
+	}
+	if (strings.HasPrefix(op, "VF") || strings.HasPrefix(op, "WF")) && len(op) > 2 {
+		if ok, err := c.lowerVecFP(op, ins); ok {
+			return true, false, err
+		}
+	}
+	vop, found := s390xVecOps[op]
+	if !found {
+		return false, false, nil
+	}
+	switch vop.form {
+	case "118", "119", "117":
+		if vop.kind == "fee" {
+			return true, false, c.lowerVFEE(op, vop, ins)
+		}
+		return true, false, c.vecBinary(op, ins, vop.form == "119", func(a, b string) string {
+			v := c.laneBinary(vop.kind, vop.lane, a, b)
+			if vop.cc {
+				c.setCCVecMask(v)
+			}
+			return v
+		})
+	case "120":
+		regs, err := c.vecRegs(op, ins, 4)
+		if err != nil {
+			return true, false, err
+		}
+		in, err := c.loadVecs(regs[:3])
+		if err != nil {
+			return true, false, err
+		}
+		return true, false, c.storeVec(regs[3], c.laneTernary(vop.kind, vop.lane, in[0], in[1], in[2]))
+	case "114":
+		return true, false, c.vecUnary(op, ins, func(v string) string {
+			return c.laneUnary(vop.kind, vop.lane, v)
+		})
+	case "104":
+		return true, false, c.lowerVecShiftScalar(op, vop, ins)
+	case "elem":
+		return true, false, c.lowerVecElem(op, vop, ins)
+	case "mem":
+		return true, false, c.lowerVecElemMem(op, vop, ins)
+	}
+	return true, false, c.lowerVecImm(op, vop, ins)
+}
+
+// vecElemShift is the right shift of the i128 that brings element i of
+// a bits-wide lane down to bit 0; element 0 is the most significant.
+func s390xVecElemShift(bits, i int) int {
+	return 128 - bits*(i+1)
+}
+
+// elemGet extracts element i as an i<bits>.
+func (c *s390xCtx) elemGet(v string, bits, i int) string {
+	if sh := s390xVecElemShift(bits, i); sh != 0 {
+		v = c.emit("lshr i128 %s, %d", v, sh)
+	}
+	if bits == 128 {
+		return v
+	}
+	return c.emit("trunc i128 %s to i%d", v, bits)
+}
+
+// elemSet replaces element i of v with the i<bits> x.
+func (c *s390xCtx) elemSet(v string, bits, i int, x string) string {
+	sh := s390xVecElemShift(bits, i)
+	mask := c.emit("shl i128 %d, %d", int64(-1), bits)
+	mask = c.emit("xor i128 %s, -1", mask)
+	if sh != 0 {
+		mask = c.emit("shl i128 %s, %d", mask, sh)
+	}
+	keep := c.emit("xor i128 %s, -1", mask)
+	keep = c.emit("and i128 %s, %s", v, keep)
+	w := c.emit("zext i%d %s to i128", bits, x)
+	if sh != 0 {
+		w = c.emit("shl i128 %s, %d", w, sh)
+	}
+	return c.emit("or i128 %s, %s", keep, w)
+}
+
+// elemShiftVar returns the i128 shift of the element numbered by the low
+// bits of the i64 idx.
+func (c *s390xCtx) elemShiftVar(lane s390xVecLane, idx string) string {
+	i := c.emit("and i64 %s, %d", idx, lane.n-1)
+	r := c.emit("sub i64 %d, %s", lane.n-1, i)
+	r = c.emit("mul i64 %s, %d", r, lane.bits)
+	return c.emit("zext i64 %s to i128", r)
+}
+
+func (c *s390xCtx) elemGetVar(v string, lane s390xVecLane, idx string) string {
+	v = c.emit("lshr i128 %s, %s", v, c.elemShiftVar(lane, idx))
+	return c.emit("trunc i128 %s to i%d", v, lane.bits)
+}
+
+func (c *s390xCtx) elemSetVar(v string, lane s390xVecLane, idx, x string) string {
+	sh := c.elemShiftVar(lane, idx)
+	mask := c.emit("shl i128 %d, %d", int64(-1), lane.bits)
+	mask = c.emit("xor i128 %s, -1", mask)
+	mask = c.emit("shl i128 %s, %s", mask, sh)
+	keep := c.emit("xor i128 %s, -1", mask)
+	keep = c.emit("and i128 %s, %s", v, keep)
+	w := c.emit("zext i%d %s to i128", lane.bits, x)
+	w = c.emit("shl i128 %s, %s", w, sh)
+	return c.emit("or i128 %s, %s", keep, w)
+}
+
+// lanes reinterprets an i128 as a vector of lanes. The lane-wise
+// operations do not depend on which lane holds which element.
+func (c *s390xCtx) lanes(v string, lane s390xVecLane) string {
+	return c.emit("bitcast i128 %s to %s", v, lane.vecType())
+}
+
+func (c *s390xCtx) unlanes(v string, lane s390xVecLane) string {
+	return c.emit("bitcast %s %s to i128", lane.vecType(), v)
+}
+
+// splat builds a vector with every lane set to the scalar v.
+func (c *s390xCtx) splat(lane s390xVecLane, v string) string {
+	ty := lane.vecType()
+	one := c.emit("insertelement %s poison, i%d %s, i32 0", ty, lane.bits, v)
+	return c.emit("shufflevector %s %s, %s poison, <%d x i32> zeroinitializer", ty, one, ty, lane.n)
+}
+
+// splatConst returns the i128 with every element set to x.
+func s390xSplatConst(lane s390xVecLane, x uint64) string {
+	if lane.bits < 64 {
+		x &= 1<<lane.bits - 1
+	}
+	var hi, lo uint64
+	for i := 0; i < lane.n; i++ {
+		sh := s390xVecElemShift(lane.bits, i)
+		if sh >= 64 {
+			hi |= x << (sh - 64)
+		} else {
+			lo |= x << sh
+		}
+	}
+	return s390xI128Const(hi, lo)
+}
+
+// s390xI128Const formats hi:lo as an i128 constant.
+func s390xI128Const(hi, lo uint64) string {
+	if hi == 0 && lo>>63 == 0 {
+		return fmt.Sprintf("%d", lo)
+	}
+	if hi == ^uint64(0) && lo>>63 == 1 {
+		return fmt.Sprintf("%d", int64(lo))
+	}
+	return fmt.Sprintf("u0x%016X%016X", hi, lo)
+}
+
+// laneBinary computes x kind y lane by lane (or on the i128 for 128-bit
+// lanes). Compares give all-ones lanes where they hold.
+func (c *s390xCtx) laneBinary(kind string, lane s390xVecLane, x, y string) string {
+	switch kind {
+	case "mrgh", "mrgl":
+		// Interleave the high (low) halves of the elements of x and y.
+		base := 0
+		if kind == "mrgl" {
+			base = lane.n / 2
+		}
+		v := "0"
+		for i := 0; i < lane.n/2; i++ {
+			v = c.elemSet(v, lane.bits, 2*i, c.elemGet(x, lane.bits, base+i))
+			v = c.elemSet(v, lane.bits, 2*i+1, c.elemGet(y, lane.bits, base+i))
+		}
+		return v
+	case "sumq":
+		// The sum of the elements of x and the last element of y.
+		v := c.emit("zext i%d %s to i128", lane.bits, c.elemGet(y, lane.bits, lane.n-1))
+		for i := 0; i < lane.n; i++ {
+			e := c.emit("zext i%d %s to i128", lane.bits, c.elemGet(x, lane.bits, i))
+			v = c.emit("add i128 %s, %s", v, e)
+		}
+		return v
+	case "sum":
+		// Each word of the result sums the elements of the same word of
+		// x and the last element of that word of y.
+		return c.laneSum(lane, s390xVecLane{32, 4}, x, y)
+	case "sumg":
+		// The same by doublewords.
+		return c.laneSum(lane, s390xVecLane{64, 2}, x, y)
+	case "mulo", "mulos", "mule", "mules":
+		// The products of the odd (even) elements, widened.
+		wd := lane.wide()
+		part := func(v string) string {
+			v = c.lanes(v, wd)
+			odd := kind == "mulo" || kind == "mulos"
+			signed := kind == "mulos" || kind == "mules"
+			sh := c.splat(wd, fmt.Sprintf("%d", lane.bits))
+			switch {
+			case odd && signed:
+				v = c.emit("shl %s %s, %s", wd.vecType(), v, sh)
+				return c.emit("ashr %s %s, %s", wd.vecType(), v, sh)
+			case odd:
+				return c.emit("and %s %s, %s", wd.vecType(), v, c.splat(wd, fmt.Sprintf("%d", int64(1)<<lane.bits-1)))
+			case signed:
+				return c.emit("ashr %s %s, %s", wd.vecType(), v, sh)
+			}
+			return c.emit("lshr %s %s, %s", wd.vecType(), v, sh)
+		}
+		p := c.emit("mul %s %s, %s", wd.vecType(), part(x), part(y))
+		return c.unlanes(p, wd)
+	}
+	if lane.bits == 128 {
+		return c.quadBinary(kind, x, y)
+	}
+	ty := lane.vecType()
+	a, b := c.lanes(x, lane), c.lanes(y, lane)
+	var v string
+	switch kind {
+	case "add", "sub", "mul":
+		v = c.emit("%s %s %s, %s", kind, ty, a, b)
+	case "carry":
+		s := c.emit("add %s %s, %s", ty, a, b)
+		cy := c.emit("icmp ult %s %s, %s", ty, s, a)
+		v = c.emit("zext <%d x i1> %s to %s", lane.n, cy, ty)
+	case "borrow":
+		nb := c.emit("icmp uge %s %s, %s", ty, a, b)
+		v = c.emit("zext <%d x i1> %s to %s", lane.n, nb, ty)
+	case "smax", "umax", "smin", "umin":
+		pred := map[string]string{"smax": "sgt", "umax": "ugt", "smin": "slt", "umin": "ult"}[kind]
+		m := c.emit("icmp %s %s %s, %s", pred, ty, a, b)
+		v = c.emit("select <%d x i1> %s, %s %s, %s %s", lane.n, m, ty, a, ty, b)
+	case "mulh", "mulhu":
+		ext := "sext"
+		if kind == "mulhu" {
+			ext = "zext"
+		}
+		wty := fmt.Sprintf("<%d x i%d>", lane.n, 2*lane.bits)
+		wa := c.emit("%s %s %s to %s", ext, ty, a, wty)
+		wb := c.emit("%s %s %s to %s", ext, ty, b, wty)
+		p := c.emit("mul %s %s, %s", wty, wa, wb)
+		p = c.emit("lshr %s %s, %s", wty, p, c.splatWide(lane.n, 2*lane.bits, lane.bits))
+		v = c.emit("trunc %s %s to %s", wty, p, ty)
+	case "rotl":
+		v = c.emit("call %s @llvm.fshl.%s(%s %s, %s %s, %s %s)", ty, lane.intrinsicSuffix(), ty, a, ty, a, ty, b)
+	case "shl", "lshr", "ashr":
+		n := c.emit("and %s %s, %s", ty, b, c.splat(lane, fmt.Sprintf("%d", lane.bits-1)))
+		v = c.emit("%s %s %s, %s", kind, ty, a, n)
+	case "eq", "sgt", "ugt":
+		m := c.emit("icmp %s %s %s, %s", kind, ty, a, b)
+		v = c.emit("sext <%d x i1> %s to %s", lane.n, m, ty)
+	default:
+		panic("s390x: unknown vector kind " + kind)
+	}
+	return c.unlanes(v, lane)
+}
+
+// splatWide returns a constant vector of n lanes of i<bits> set to x.
+func (c *s390xCtx) splatWide(n, bits, x int) string {
+	parts := make([]string, n)
+	for i := range parts {
+		parts[i] = fmt.Sprintf("i%d %d", bits, x)
+	}
+	return "<" + strings.Join(parts, ", ") + ">"
+}
+
+// laneSum lowers VSUMB, VSUMH, VSUMGH and VSUMGF: each w lane of the
+// result is the sum of the lane-sized elements of that lane of x plus
+// the last element of that lane of y.
+func (c *s390xCtx) laneSum(lane, w s390xVecLane, x, y string) string {
+	ty := w.vecType()
+	a, b := c.lanes(x, w), c.lanes(y, w)
+	mask := c.splat(w, fmt.Sprintf("%d", int64(1)<<lane.bits-1))
+	v := c.emit("and %s %s, %s", ty, b, mask)
+	for sh := 0; sh < w.bits; sh += lane.bits {
+		e := a
+		if sh != 0 {
+			e = c.emit("lshr %s %s, %s", ty, a, c.splat(w, fmt.Sprintf("%d", sh)))
+		}
+		e = c.emit("and %s %s, %s", ty, e, mask)
+		v = c.emit("add %s %s, %s", ty, v, e)
+	}
+	return c.unlanes(v, w)
+}
+
+// quadBinary computes x kind y on whole quadwords.
+func (c *s390xCtx) quadBinary(kind, x, y string) string {
+	switch kind {
+	case "add", "sub":
+		return c.emit("%s i128 %s, %s", kind, x, y)
+	case "carry":
+		s := c.emit("add i128 %s, %s", x, y)
+		cy := c.emit("icmp ult i128 %s, %s", s, x)
+		return c.emit("zext i1 %s to i128", cy)
+	case "borrow":
+		nb := c.emit("icmp uge i128 %s, %s", x, y)
+		return c.emit("zext i1 %s to i128", nb)
+	}
+	panic("s390x: unknown quadword kind " + kind)
+}
+
+// laneTernary computes the group 120 multiply-and-add operations
+// kind(a, b, c).
+func (c *s390xCtx) laneTernary(kind string, lane s390xVecLane, a, b, cv string) string {
+	switch kind {
+	case "mal":
+		p := c.laneBinary("mul", lane, a, b)
+		return c.laneBinary("add", lane, p, cv)
+	case "malh", "mah":
+		// The high half of a*b + c in double-width lanes.
+		ext := "zext"
+		if kind == "mah" {
+			ext = "sext"
+		}
+		ty := lane.vecType()
+		wty := fmt.Sprintf("<%d x i%d>", lane.n, 2*lane.bits)
+		var w [3]string
+		for i, v := range []string{a, b, cv} {
+			w[i] = c.emit("%s %s %s to %s", ext, ty, c.lanes(v, lane), wty)
+		}
+		p := c.emit("mul %s %s, %s", wty, w[0], w[1])
+		p = c.emit("add %s %s, %s", wty, p, w[2])
+		p = c.emit("lshr %s %s, %s", wty, p, c.splatWide(lane.n, 2*lane.bits, lane.bits))
+		return c.unlanes(c.emit("trunc %s %s to %s", wty, p, ty), lane)
+	}
+	// malo, mao, male, mae: widened odd (even) products plus c.
+	mul := map[string]string{"malo": "mulo", "mao": "mulos", "male": "mule", "mae": "mules"}[kind]
+	p := c.laneBinary(mul, lane, a, b)
+	return c.laneBinary("add", lane.wide(), p, cv)
+}
+
+// laneUnary computes the group 114 operations.
+func (c *s390xCtx) laneUnary(kind string, lane s390xVecLane, v string) string {
+	switch kind {
+	case "upll", "uplh", "upl", "uph":
+		// Widen the low (high) half of the elements.
+		half := s390xVecLane{lane.bits, lane.n / 2}
+		var h string
+		if kind == "upll" || kind == "upl" {
+			h = c.emit("trunc i128 %s to i64", v)
+		} else {
+			h = c.emit("lshr i128 %s, 64", v)
+			h = c.emit("trunc i128 %s to i64", h)
+		}
+		ext := "sext"
+		if kind == "upll" || kind == "uplh" {
+			ext = "zext"
+		}
+		hv := c.emit("bitcast i64 %s to %s", h, half.vecType())
+		wd := lane.wide()
+		w := c.emit("%s %s %s to %s", ext, half.vecType(), hv, wd.vecType())
+		return c.unlanes(w, wd)
+	}
+	ty := lane.vecType()
+	a := c.lanes(v, lane)
+	var r string
+	switch kind {
+	case "ctlz", "cttz":
+		r = c.emit("call %s @llvm.%s.%s(%s %s, i1 false)", ty, kind, lane.intrinsicSuffix(), ty, a)
+	case "neg":
+		r = c.emit("sub %s zeroinitializer, %s", ty, a)
+	case "abs":
+		n := c.emit("sub %s zeroinitializer, %s", ty, a)
+		m := c.emit("icmp slt %s %s, zeroinitializer", ty, a)
+		r = c.emit("select <%d x i1> %s, %s %s, %s %s", lane.n, m, ty, n, ty, a)
+	default:
+		panic("s390x: unknown vector kind " + kind)
+	}
+	return c.unlanes(r, lane)
+}
+
+// setCCVecMask sets CC from a compare result: 0 when every lane holds,
+// 1 when some do and 3 when none does.
+func (c *s390xCtx) setCCVecMask(v string) {
+	all := c.emit("icmp eq i128 %s, -1", v)
+	none := c.emit("icmp eq i128 %s, 0", v)
+	cc := c.emit("select i1 %s, i64 3, i64 1", none)
+	c.setCC(c.emit("select i1 %s, i64 0, i64 %s", all, cc))
+}
+
+// vecRegOp reports whether o names a vector register, in its V or F
+// spelling.
+func s390xIsVecRegOp(o Operand) bool {
+	return s390xIsVecOp(o) || s390xIsFRegOp(o)
+}
+
+// vecRegs checks that ins has n vector register operands.
+func (c *s390xCtx) vecRegs(op string, ins Instr, n int) ([]Reg, error) {
+	if len(ins.Args) != n {
+		return nil, fmt.Errorf("s390x %s expects %d vector registers: %q", op, n, ins.Raw)
+	}
+	regs := make([]Reg, n)
+	for i, a := range ins.Args {
+		if !s390xIsVecRegOp(a) {
+			return nil, fmt.Errorf("s390x %s expects vector registers: %q", op, ins.Raw)
+		}
+		regs[i] = a.Reg
+	}
+	return regs, nil
+}
+
+func (c *s390xCtx) loadVecs(regs []Reg) ([]string, error) {
+	out := make([]string, len(regs))
+	for i, r := range regs {
+		v, err := c.loadVec(r)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = v
+	}
+	return out, nil
+}
+
+// vecBinary lowers "OP a, b, t" as t = f(a, b), or with rev as
+// t = f(b, a); "OP a, t" reads t as b.
+func (c *s390xCtx) vecBinary(op string, ins Instr, rev bool, f func(x, y string) string) error {
+	n := len(ins.Args)
+	if n != 2 {
+		n = 3
+	}
+	regs, err := c.vecRegs(op, ins, n)
+	if err != nil {
+		return err
+	}
+	if n == 2 {
+		regs = []Reg{regs[0], regs[1], regs[1]}
+	}
+	in, err := c.loadVecs(regs[:2])
+	if err != nil {
+		return err
+	}
+	if rev {
+		in[0], in[1] = in[1], in[0]
+	}
+	return c.storeVec(regs[2], f(in[0], in[1]))
+}
+
+// vecUnary lowers "OP a, t" as t = f(a).
+func (c *s390xCtx) vecUnary(op string, ins Instr, f func(v string) string) error {
+	regs, err := c.vecRegs(op, ins, 2)
+	if err != nil {
+		return err
+	}
+	v, err := c.loadVec(regs[0])
+	if err != nil {
+		return err
+	}
+	return c.storeVec(regs[1], f(v))
+}
+
+// vecAmount evaluates the $n or R operand giving a shift amount or an
+// element index.
+func (c *s390xCtx) vecAmount(o Operand) (string, error) {
+	if o.Kind == OpImm {
+		return fmt.Sprintf("%d", o.Imm), nil
+	}
+	if s390xIsRReg(o) {
+		return c.loadReg(o.Reg)
+	}
+	return "", fmt.Errorf("expects $n or a register")
+}
+
+// lowerVecShiftScalar lowers "VESLx $n|R, [b,] t", which shifts (or
+// rotates) every element of b by the same amount.
+func (c *s390xCtx) lowerVecShiftScalar(op string, vop s390xVecOp, ins Instr) error {
+	if len(ins.Args) != 2 && len(ins.Args) != 3 {
+		return fmt.Errorf("s390x %s expects $n|R, [v,] v: %q", op, ins.Raw)
+	}
+	n, err := c.vecAmount(ins.Args[0])
+	if err != nil {
+		return fmt.Errorf("s390x %s %v: %q", op, err, ins.Raw)
+	}
+	regs, err := c.vecRegs(op, Instr{Args: ins.Args[1:], Raw: ins.Raw}, len(ins.Args)-1)
+	if err != nil {
+		return err
+	}
+	v, err := c.loadVec(regs[0])
+	if err != nil {
+		return err
+	}
+	if vop.lane.bits < 64 {
+		n = c.emit("trunc i64 %s to i%d", n, vop.lane.bits)
+	}
+	amt := c.unlanes(c.splat(vop.lane, n), vop.lane)
+	return c.storeVec(regs[len(regs)-1], c.laneBinary(vop.kind, vop.lane, v, amt))
+}
+
+// lowerVecElem lowers "VLGVx $i|R, v, R", which extracts element i
+// zero-extended, and "VLVGx $i|R, R, v", which replaces it.
+func (c *s390xCtx) lowerVecElem(op string, vop s390xVecOp, ins Instr) error {
+	if len(ins.Args) != 3 {
+		return fmt.Errorf("s390x %s expects 3 operands: %q", op, ins.Raw)
+	}
+	lane := vop.lane
+	idx := ins.Args[0]
+	if vop.kind == "get" {
+		if !s390xIsVecRegOp(ins.Args[1]) || !s390xIsRReg(ins.Args[2]) {
+			return fmt.Errorf("s390x %s expects $i|R, V, R: %q", op, ins.Raw)
+		}
+		v, err := c.loadVec(ins.Args[1].Reg)
+		if err != nil {
+			return err
+		}
+		var e string
+		if idx.Kind == OpImm {
+			e = c.elemGet(v, lane.bits, int(idx.Imm)&(lane.n-1))
+		} else {
+			i, err := c.vecAmount(idx)
+			if err != nil {
+				return fmt.Errorf("s390x %s %v: %q", op, err, ins.Raw)
+			}
+			e = c.elemGetVar(v, lane, i)
+		}
+		if lane.bits < 64 {
+			e = c.extend(e, lane.bits, false)
+		}
+		return c.storeReg(ins.Args[2].Reg, e)
+	}
+	if !s390xIsRReg(ins.Args[1]) || !s390xIsVecRegOp(ins.Args[2]) {
+		return fmt.Errorf("s390x %s expects $i|R, R, V: %q", op, ins.Raw)
+	}
+	x, err := c.loadReg(ins.Args[1].Reg)
+	if err != nil {
+		return err
+	}
+	if lane.bits < 64 {
+		x = c.emit("trunc i64 %s to i%d", x, lane.bits)
+	}
+	v, err := c.loadVec(ins.Args[2].Reg)
+	if err != nil {
+		return err
+	}
+	if idx.Kind == OpImm {
+		v = c.elemSet(v, lane.bits, int(idx.Imm)&(lane.n-1), x)
+	} else {
+		i, err := c.vecAmount(idx)
+		if err != nil {
+			return fmt.Errorf("s390x %s %v: %q", op, err, ins.Raw)
+		}
+		v = c.elemSetVar(v, lane, i, x)
+	}
+	return c.storeVec(ins.Args[2].Reg, v)
+}
+
+// lowerVecElemMem lowers "VLEx $i, mem, t" and "VSTEx $i, v, mem", which
+// load or store element i, and "VLREPx mem, t", which loads an element
+// into every element.
+func (c *s390xCtx) lowerVecElemMem(op string, vop s390xVecOp, ins Instr) error {
+	lane := vop.lane
+	switch vop.kind {
+	case "lrep":
+		if len(ins.Args) != 2 || ins.Args[0].Kind != OpMem && ins.Args[0].Kind != OpFP || !s390xIsVecRegOp(ins.Args[1]) {
+			return fmt.Errorf("s390x %s expects mem, V: %q", op, ins.Raw)
+		}
+		var x string
+		if ins.Args[0].Kind == OpFP {
+			v, err := c.evalFPValue64(ins.Args[0])
+			if err != nil {
+				return err
+			}
+			x = v
+			if lane.bits < 64 {
+				x = c.emit("trunc i64 %s to i%d", v, lane.bits)
+			}
+		} else {
+			p, err := c.memPtr(ins.Args[0].Mem)
+			if err != nil {
+				return err
+			}
+			x = c.emit("load i%d, ptr %s, align 1", lane.bits, p)
+		}
+		return c.storeVec(ins.Args[1].Reg, c.unlanes(c.splat(lane, x), lane))
+	case "le":
+		if len(ins.Args) != 3 || ins.Args[0].Kind != OpImm || ins.Args[1].Kind != OpMem || !s390xIsVecRegOp(ins.Args[2]) {
+			return fmt.Errorf("s390x %s expects $i, mem, V: %q", op, ins.Raw)
+		}
+		p, err := c.memPtr(ins.Args[1].Mem)
+		if err != nil {
+			return err
+		}
+		x := c.emit("load i%d, ptr %s, align 1", lane.bits, p)
+		v, err := c.loadVec(ins.Args[2].Reg)
+		if err != nil {
+			return err
+		}
+		return c.storeVec(ins.Args[2].Reg, c.elemSet(v, lane.bits, int(ins.Args[0].Imm)&(lane.n-1), x))
+	}
+	if len(ins.Args) != 3 || ins.Args[0].Kind != OpImm || !s390xIsVecRegOp(ins.Args[1]) || ins.Args[2].Kind != OpMem {
+		return fmt.Errorf("s390x %s expects $i, V, mem: %q", op, ins.Raw)
+	}
+	v, err := c.loadVec(ins.Args[1].Reg)
+	if err != nil {
+		return err
+	}
+	x := c.elemGet(v, lane.bits, int(ins.Args[0].Imm)&(lane.n-1))
+	p, err := c.memPtr(ins.Args[2].Mem)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(c.b, "  store i%d %s, ptr %s, align 1\n", lane.bits, x, p)
+	return nil
+}
+
+// lowerVecImm lowers the immediate forms: VREPI, VLEI, VGM, VREP and
+// VERIM.
+func (c *s390xCtx) lowerVecImm(op string, vop s390xVecOp, ins Instr) error {
+	lane := vop.lane
+	imms := 0
+	for imms < len(ins.Args) && ins.Args[imms].Kind == OpImm {
+		imms++
+	}
+	want := map[string][2]int{"109": {1, 1}, "110": {2, 1}, "111": {1, 2}, "112": {1, 3}}[vop.form]
+	if vop.kind == "lei" {
+		want = [2]int{2, 1}
+	}
+	if imms != want[0] || len(ins.Args) != want[0]+want[1] {
+		return fmt.Errorf("s390x %s expects %d immediates and %d vector registers: %q", op, want[0], want[1], ins.Raw)
+	}
+	regs, err := c.vecRegs(op, Instr{Args: ins.Args[imms:], Raw: ins.Raw}, want[1])
+	if err != nil {
+		return err
+	}
+	imm := ins.Args[0].Imm
+	dst := regs[len(regs)-1]
+	switch vop.kind {
+	case "repi":
+		// The 16-bit immediate is sign-extended to the element.
+		return c.storeVec(dst, s390xSplatConst(lane, uint64(int64(int16(imm)))))
+	case "lei":
+		v, err := c.loadVec(dst)
+		if err != nil {
+			return err
+		}
+		x := fmt.Sprintf("%d", int64(int16(ins.Args[1].Imm)))
+		return c.storeVec(dst, c.elemSet(v, lane.bits, int(imm)&(lane.n-1), x))
+	case "gm":
+		// Bits start through end of each element, numbered from the
+		// left and wrapping around.
+		start, end := imm&int64(lane.bits-1), ins.Args[1].Imm&int64(lane.bits-1)
+		m := s390xMask64(start+int64(64-lane.bits), end+int64(64-lane.bits))
+		if start > end && lane.bits < 64 {
+			m &= 1<<lane.bits - 1
+		}
+		return c.storeVec(dst, s390xSplatConst(lane, m))
+	case "rep":
+		v, err := c.loadVec(regs[0])
+		if err != nil {
+			return err
+		}
+		x := c.elemGet(v, lane.bits, int(imm)&(lane.n-1))
+		return c.storeVec(dst, c.unlanes(c.splat(lane, x), lane))
+	}
+	// VERIM $n, a, b, t: rotate the elements of a and insert them into t
+	// under the mask b.
+	in, err := c.loadVecs(regs)
+	if err != nil {
+		return err
+	}
+	amt := s390xSplatConst(lane, uint64(imm)&uint64(lane.bits-1))
+	rot := c.laneBinary("rotl", lane, in[0], amt)
+	sel := c.emit("and i128 %s, %s", rot, in[1])
+	nm := c.emit("xor i128 %s, -1", in[1])
+	keep := c.emit("and i128 %s, %s", in[2], nm)
+	return c.storeVec(dst, c.emit("or i128 %s, %s", keep, sel))
+}
+
+// lowerVFEE lowers "VFEExS a, b, t": t gets the byte index of the
+// leftmost element of a equal to the same element of b, or 16, in
+// doubleword 0. The S forms set CC to 1 when an element matched and to 3
+// otherwise.
+func (c *s390xCtx) lowerVFEE(op string, vop s390xVecOp, ins Instr) error {
+	regs, err := c.vecRegs(op, ins, 3)
+	if err != nil {
+		return err
+	}
+	in, err := c.loadVecs(regs[:2])
+	if err != nil {
+		return err
+	}
+	m := c.laneBinary("eq", vop.lane, in[0], in[1])
+	n := c.emit("call i128 @llvm.ctlz.i128(i128 %s, i1 false)", m)
+	n = c.emit("lshr i128 %s, %d", n, 3)
+	// A lane's leading zero bits are a whole number of bytes.
+	n = c.emit("and i128 %s, %d", n, ^(vop.lane.bits/8-1)&31)
+	if vop.cc {
+		found := c.emit("icmp ne i128 %s, 0", m)
+		c.setCC(c.emit("select i1 %s, i64 1, i64 3", found))
+	}
+	return c.storeVec(regs[2], c.emit("shl i128 %s, 64", n))
+}
+
+// lowerVecMem lowers the whole-register loads and stores: VL, VST, the
+// multiple forms VLM and VSTM, and VLL and VSTL, which move the leftmost
+// min(R, 15)+1 bytes; VLL zeroes the rest of the register.
+func (c *s390xCtx) lowerVecMem(op string, ins Instr) (bool, error) {
+	switch op {
+	case "VL", "VST":
+		if len(ins.Args) != 2 {
+			return true, fmt.Errorf("s390x %s expects 2 operands: %q", op, ins.Raw)
+		}
+		if op == "VL" {
+			if ins.Args[0].Kind != OpMem || !s390xIsVecRegOp(ins.Args[1]) {
+				return true, fmt.Errorf("s390x VL expects mem, V: %q", ins.Raw)
+			}
+			p, err := c.memPtr(ins.Args[0].Mem)
+			if err != nil {
+				return true, err
+			}
+			return true, c.storeVec(ins.Args[1].Reg, c.emit("load i128, ptr %s, align 1", p))
+		}
+		if !s390xIsVecRegOp(ins.Args[0]) || ins.Args[1].Kind != OpMem {
+			return true, fmt.Errorf("s390x VST expects V, mem: %q", ins.Raw)
+		}
+		v, err := c.loadVec(ins.Args[0].Reg)
+		if err != nil {
+			return true, err
+		}
+		p, err := c.memPtr(ins.Args[1].Mem)
+		if err != nil {
+			return true, err
+		}
+		fmt.Fprintf(c.b, "  store i128 %s, ptr %s, align 1\n", v, p)
+		return true, nil
+	case "VLM", "VSTM":
+		if len(ins.Args) != 3 {
+			return true, fmt.Errorf("s390x %s expects 3 operands: %q", op, ins.Raw)
+		}
+		mem, first, last := ins.Args[2], ins.Args[0], ins.Args[1]
+		if op == "VLM" {
+			mem, first, last = ins.Args[0], ins.Args[1], ins.Args[2]
+		}
+		if mem.Kind != OpMem || !s390xIsVecOp(first) || !s390xIsVecOp(last) {
+			return true, fmt.Errorf("s390x %s expects a vector register range and mem: %q", op, ins.Raw)
+		}
+		lo, hi := s390xVRegNum(first.Reg), s390xVRegNum(last.Reg)
+		if hi < lo || hi-lo >= 16 {
+			return true, fmt.Errorf("s390x %s invalid register range: %q", op, ins.Raw)
+		}
+		base, err := c.addrI64(mem.Mem)
+		if err != nil {
+			return true, err
+		}
+		for n := lo; n <= hi; n++ {
+			r := Reg(fmt.Sprintf("V%d", n))
+			p := c.emit("add i64 %s, %d", base, 16*(n-lo))
+			p = c.emit("inttoptr i64 %s to ptr", p)
+			if op == "VLM" {
+				if err := c.storeVec(r, c.emit("load i128, ptr %s, align 1", p)); err != nil {
+					return true, err
+				}
+				continue
+			}
+			v, err := c.loadVec(r)
+			if err != nil {
+				return true, err
+			}
+			fmt.Fprintf(c.b, "  store i128 %s, ptr %s, align 1\n", v, p)
+		}
+		return true, nil
+	case "VLL", "VSTL":
+		if len(ins.Args) != 3 || !s390xIsRReg(ins.Args[0]) {
+			return true, fmt.Errorf("s390x %s expects R, ...: %q", op, ins.Raw)
+		}
+		mem, vr := ins.Args[1], ins.Args[2]
+		if op == "VSTL" {
+			mem, vr = ins.Args[2], ins.Args[1]
+		}
+		if mem.Kind != OpMem || !s390xIsVecRegOp(vr) {
+			return true, fmt.Errorf("s390x %s expects a memory operand and V: %q", op, ins.Raw)
+		}
+		l, err := c.loadReg(ins.Args[0].Reg)
+		if err != nil {
+			return true, err
+		}
+		l = c.emit("and i64 %s, 4294967295", l)
+		big := c.emit("icmp ugt i64 %s, 15", l)
+		l = c.emit("select i1 %s, i64 15, i64 %s", big, l)
+		n := c.emit("add i64 %s, 1", l)
+		p, err := c.memPtr(mem.Mem)
+		if err != nil {
+			return true, err
+		}
+		if op == "VLL" {
+			c.b.WriteString("  call void @llvm.memset.p0.i64(ptr %vlbuf, i8 0, i64 16, i1 false)\n")
+			fmt.Fprintf(c.b, "  call void @llvm.memcpy.p0.p0.i64(ptr %%vlbuf, ptr %s, i64 %s, i1 false)\n", p, n)
+			return true, c.storeVec(vr.Reg, c.emit("load i128, ptr %%vlbuf"))
+		}
+		v, err := c.loadVec(vr.Reg)
+		if err != nil {
+			return true, err
+		}
+		fmt.Fprintf(c.b, "  store i128 %s, ptr %%vlbuf\n", v)
+		fmt.Fprintf(c.b, "  call void @llvm.memcpy.p0.p0.i64(ptr %s, ptr %%vlbuf, i64 %s, i1 false)\n", p, n)
+		return true, nil
+	}
+	return false, nil
+}
+
+// lowerVecMove lowers the register moves and constants and the
+// positional permutes: VLR, VZERO, VONE, VGBM, VLVGP, VPDI, VSLDB, VSEL
+// and VPERM.
+func (c *s390xCtx) lowerVecMove(op string, ins Instr) (bool, error) {
+	switch op {
+	case "VLR":
+		return true, c.vecUnary(op, ins, func(v string) string { return v })
+	case "VNOT":
+		return true, c.vecUnary(op, ins, func(v string) string { return c.emit("xor i128 %s, -1", v) })
+	case "VZERO", "VONE":
+		regs, err := c.vecRegs(op, ins, 1)
+		if err != nil {
+			return true, err
+		}
+		v := "0"
+		if op == "VONE" {
+			v = "-1"
+		}
+		return true, c.storeVec(regs[0], v)
+	case "VGBM":
+		// Each bit of the mask, from the left, sets a byte.
+		if len(ins.Args) != 2 || ins.Args[0].Kind != OpImm || !s390xIsVecRegOp(ins.Args[1]) {
+			return true, fmt.Errorf("s390x VGBM expects $mask, V: %q", ins.Raw)
+		}
+		var hi, lo uint64
+		for i := 0; i < 16; i++ {
+			if ins.Args[0].Imm>>(15-i)&1 == 0 {
+				continue
+			}
+			if sh := s390xVecElemShift(8, i); sh >= 64 {
+				hi |= 0xff << (sh - 64)
+			} else {
+				lo |= 0xff << sh
+			}
+		}
+		return true, c.storeVec(ins.Args[1].Reg, s390xI128Const(hi, lo))
+	case "VLVGP":
+		if len(ins.Args) != 3 || !s390xIsRReg(ins.Args[0]) || !s390xIsRReg(ins.Args[1]) || !s390xIsVecRegOp(ins.Args[2]) {
+			return true, fmt.Errorf("s390x VLVGP expects R, R, V: %q", ins.Raw)
+		}
+		hi, err := c.loadReg(ins.Args[0].Reg)
+		if err != nil {
+			return true, err
+		}
+		lo, err := c.loadReg(ins.Args[1].Reg)
+		if err != nil {
+			return true, err
+		}
+		return true, c.storeVec(ins.Args[2].Reg, c.join128(hi, lo))
+	case "VPDI", "VSLDB":
+		if len(ins.Args) != 4 || ins.Args[0].Kind != OpImm {
+			return true, fmt.Errorf("s390x %s expects $imm, V, V, V: %q", op, ins.Raw)
+		}
+		regs, err := c.vecRegs(op, Instr{Args: ins.Args[1:], Raw: ins.Raw}, 3)
+		if err != nil {
+			return true, err
+		}
+		in, err := c.loadVecs(regs[:2])
+		if err != nil {
+			return true, err
+		}
+		m := ins.Args[0].Imm
+		var v string
+		if op == "VPDI" {
+			// [a.dw[m>>2&1], b.dw[m&1]]
+			a := c.elemGet(in[0], 64, int(m>>2&1))
+			b := c.elemGet(in[1], 64, int(m&1))
+			v = c.join128(a, b)
+		} else {
+			// Bytes n through n+15 of a:b.
+			n := m & 15
+			v = in[0]
+			if n != 0 {
+				hi := c.emit("shl i128 %s, %d", in[0], 8*n)
+				lo := c.emit("lshr i128 %s, %d", in[1], 128-8*n)
+				v = c.emit("or i128 %s, %s", hi, lo)
+			}
+		}
+		return true, c.storeVec(regs[2], v)
+	case "VSEL":
+		// t = a where c is set, else b.
+		regs, err := c.vecRegs(op, ins, 4)
+		if err != nil {
+			return true, err
+		}
+		in, err := c.loadVecs(regs[:3])
+		if err != nil {
+			return true, err
+		}
+		x := c.emit("and i128 %s, %s", in[0], in[2])
+		nm := c.emit("xor i128 %s, -1", in[2])
+		y := c.emit("and i128 %s, %s", in[1], nm)
+		return true, c.storeVec(regs[3], c.emit("or i128 %s, %s", x, y))
+	case "VPERM":
+		return true, c.lowerVPERM(ins)
+	}
+	return false, nil
+}
+
+// lowerVPERM lowers "VPERM a, b, c, t": byte i of t is the byte of a:b
+// numbered by the low five bits of byte i of c.
+func (c *s390xCtx) lowerVPERM(ins Instr) error {
+	regs, err := c.vecRegs("VPERM", ins, 4)
+	if err != nil {
+		return err
+	}
+	in, err := c.loadVecs(regs[:3])
+	if err != nil {
+		return err
+	}
+	// Build the 32 source bytes in element order.
+	src := "poison"
+	for k := 0; k < 32; k++ {
+		b := c.elemGet(in[k/16], 8, k%16)
+		src = c.emit("insertelement <32 x i8> %s, i8 %s, i32 %d", src, b, k)
+	}
+	v := "0"
+	for i := 0; i < 16; i++ {
+		sel := c.elemGet(in[2], 8, i)
+		sel = c.emit("and i8 %s, 31", sel)
+		b := c.emit("extractelement <32 x i8> %s, i8 %s", src, sel)
+		v = c.elemSet(v, 8, i, b)
+	}
+	return c.storeVec(regs[3], v)
+}
+
+// lowerVecQuad lowers the quadword arithmetic with a carry in, "OP a, b,
+// c, t": VACQ t = a + b + c&1 and VSBIQ t = a + ^b + c&1, with VACCCQ and
+// VSBCBIQ giving the carry out of the same sums; and the whole-register
+// shifts "OP a, b, t", which shift b by the bit count (VSL, VSRL, VSRA)
+// or byte count (the B forms) in the last byte of a.
+func (c *s390xCtx) lowerVecQuad(op string, ins Instr) (bool, error) {
+	switch op {
+	case "VACQ", "VACCCQ", "VSBIQ", "VSBCBIQ":
+		regs, err := c.vecRegs(op, ins, 4)
+		if err != nil {
+			return true, err
+		}
+		in, err := c.loadVecs(regs[:3])
+		if err != nil {
+			return true, err
+		}
+		a, b := in[0], in[1]
+		if strings.HasPrefix(op, "VSB") {
+			b = c.emit("xor i128 %s, -1", b)
+		}
+		cin := c.emit("and i128 %s, 1", in[2])
+		s1 := c.emit("add i128 %s, %s", a, b)
+		sum := c.emit("add i128 %s, %s", s1, cin)
+		if op == "VACQ" || op == "VSBIQ" {
+			return true, c.storeVec(regs[3], sum)
+		}
+		c1 := c.emit("icmp ult i128 %s, %s", s1, a)
+		c2 := c.emit("icmp ult i128 %s, %s", sum, s1)
+		cy := c.emit("or i1 %s, %s", c1, c2)
+		return true, c.storeVec(regs[3], c.emit("zext i1 %s to i128", cy))
+	case "VSL", "VSLB", "VSRL", "VSRLB", "VSRA", "VSRAB":
+		return true, c.vecBinary(op, ins, true, func(x, y string) string {
+			var n string
+			if strings.HasSuffix(op, "B") {
+				n = c.emit("lshr i128 %s, 3", y)
+				n = c.emit("and i128 %s, 15", n)
+				n = c.emit("shl i128 %s, 3", n)
+			} else {
+				n = c.emit("and i128 %s, 7", y)
+			}
+			kind := map[byte]string{'L': "shl", 'R': "lshr"}[op[2]]
+			if strings.HasPrefix(op, "VSRA") {
+				kind = "ashr"
+			}
+			return c.emit("%s i128 %s, %s", kind, x, n)
+		})
+	}
+	return false, nil
+}
+
+// lowerVecFP lowers the double-precision vector operations, the W forms
+// working on element 0 and zeroing the rest of the target: the binary
+// arithmetic and compares, VFMADB and VFMSDB "a, b, c, t" (t = a*b ± c),
+// and the unary VFLCDB, VFLPDB, VFLNDB and VFSQDB "a, t".
+func (c *s390xCtx) lowerVecFP(op string, ins Instr) (bool, error) {
+	single := op[0] == 'W'
+	name := op[1:]
+	// apply applies fn to the elements of the operands as doubles.
+	apply := func(vals []string, fn func(x []string, ty string) string) string {
+		if single {
+			x := make([]string, len(vals))
+			for i, v := range vals {
+				hi, _ := c.split128(v)
+				x[i] = c.emit("bitcast i64 %s to double", hi)
+			}
+			r := c.emit("bitcast double %s to i64", fn(x, "double"))
+			return c.join128(r, "0")
+		}
+		x := make([]string, len(vals))
+		for i, v := range vals {
+			x[i] = c.emit("bitcast i128 %s to <2 x double>", v)
+		}
+		return c.emit("bitcast <2 x double> %s to i128", fn(x, "<2 x double>"))
+	}
+	suffix := func(ty string) string {
+		if ty == "double" {
+			return "f64"
+		}
+		return "v2f64"
+	}
+	if spec, found := s390xVecFP[name]; found {
+		cmp := strings.HasPrefix(spec.kind, "o")
+		return true, c.vecBinary(op, ins, spec.rev, func(a, b string) string {
+			if !cmp {
+				return apply([]string{a, b}, func(x []string, ty string) string {
+					return c.emit("%s %s %s, %s", spec.kind, ty, x[0], x[1])
+				})
+			}
+			var m string
+			v := apply([]string{a, b}, func(x []string, ty string) string {
+				m = c.emit("fcmp %s %s %s, %s", spec.kind, ty, x[0], x[1])
+				if single {
+					s := c.emit("sext i1 %s to i64", m)
+					return c.emit("bitcast i64 %s to double", s)
+				}
+				s := c.emit("sext <2 x i1> %s to <2 x i64>", m)
+				return c.emit("bitcast <2 x i64> %s to <2 x double>", s)
+			})
+			if spec.cc {
+				if single {
+					c.setCC(c.emit("select i1 %s, i64 0, i64 3", m))
+				} else {
+					c.setCCVecMask(v)
+				}
+			}
+			return v
+		})
+	}
+	switch name {
+	case "FMADB", "FMSDB":
+		regs, err := c.vecRegs(op, ins, 4)
+		if err != nil {
+			return true, err
+		}
+		in, err := c.loadVecs(regs[:3])
+		if err != nil {
+			return true, err
+		}
+		v := apply(in, func(x []string, ty string) string {
+			z := x[2]
+			if name == "FMSDB" {
+				z = c.emit("fneg %s %s", ty, z)
+			}
+			return c.emit("call %s @llvm.fma.%s(%s %s, %s %s, %s %s)", ty, suffix(ty), ty, x[0], ty, x[1], ty, z)
+		})
+		return true, c.storeVec(regs[3], v)
+	case "FLCDB", "FLPDB", "FLNDB", "FSQDB":
+		return true, c.vecUnary(op, ins, func(a string) string {
+			return apply([]string{a}, func(x []string, ty string) string {
+				switch name {
+				case "FLCDB":
+					return c.emit("fneg %s %s", ty, x[0])
+				case "FSQDB":
+					return c.emit("call %s @llvm.sqrt.%s(%s %s)", ty, suffix(ty), ty, x[0])
+				}
+				v := c.emit("call %s @llvm.fabs.%s(%s %s)", ty, suffix(ty), ty, x[0])
+				if name == "FLNDB" {
+					v = c.emit("fneg %s %s", ty, v)
+				}
+				return v
+			})
+		})
+	}
+	return false, nil
+}
@@ -0,0 +1,116 @@
+package plan9asm
+
+import (
+	"strconv"
+	"strings"
+)
+
+// s390xRegAlias maps the register names the s390x assembler accepts
+// besides R0-R15, F0-F15 and V0-V31. R15 is the hardware stack pointer,
+// so it shares the SP slot. LR must be listed because parseReg reads it
+// as the arm64 alias of R30, and g as the arm64 alias of R28.
+var s390xRegAlias = map[string]string{
+	"R15": "SP",
+	"g":   "R13",
+	"LR":  "R14",
+}
+
+// s390xFixedFrame is FIXED_FRAME for s390x: the LR save word at 0(R15)
+// below the outgoing arguments of every frame.
+const s390xFixedFrame = 8
+
+// Go's ABIInternal assigns integer arguments and results to R2-R9 and
+// floating-point ones to F0-F15.
+var (
+	s390xIntArgRegs   = []Reg{"R2", "R3", "R4", "R5", "R6", "R7", "R8", "R9"}
+	s390xFloatArgRegs = []Reg{"F0", "F1", "F2", "F3", "F4", "F5", "F6", "F7",
+		"F8", "F9", "F10", "F11", "F12", "F13", "F14", "F15"}
+)
+
+// s390xParseOperands parses an operand list in the s390x dialect. Besides
+// the aliases it keeps $off(Rn) and $off(Rb)(Rx) address constants, which
+// the generic operand parser reads as memory references, as symbols for
+// the backend, and reads lower-case names as labels rather than registers.
+func s390xParseOperands(s string) ([]Operand, error) {
+	if s == "" {
+		return nil, nil
+	}
+	s = canonicalRegAliases(s, s390xRegAlias)
+	parts := splitTopLevelCSV(s)
+	out := make([]Operand, 0, len(parts))
+	for _, p := range parts {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		if _, ok := ppc64AddrConst(p); ok {
+			out = append(out, Operand{Kind: OpSym, Sym: p})
+			continue
+		}
+		op, err := parseOperand(p)
+		if err != nil {
+			return nil, err
+		}
+		if op.Kind == OpReg && p != strings.ToUpper(p) {
+			// Register names are upper case; math/big branches to v1.
+			op = Operand{Kind: OpIdent, Ident: p}
+		}
+		out = append(out, op)
+	}
+	return out, nil
+}
+
+func s390xIsRName(s string) bool {
+	if s == "SP" {
+		return true
+	}
+	n, err := strconv.Atoi(strings.TrimPrefix(s, "R"))
+	return strings.HasPrefix(s, "R") && err == nil && 0 <= n && n <= 14
+}
+
+func s390xIsFReg(r Reg) bool {
+	s := string(r)
+	n, err := strconv.Atoi(strings.TrimPrefix(s, "F"))
+	return strings.HasPrefix(s, "F") && err == nil && 0 <= n && n <= 15
+}
+
+func s390xIsVReg(r Reg) bool {
+	s := string(r)
+	n, err := strconv.Atoi(strings.TrimPrefix(s, "V"))
+	return strings.HasPrefix(s, "V") && err == nil && 0 <= n && n <= 31
+}
+
+func s390xIsRReg(o Operand) bool {
+	return o.Kind == OpReg && s390xIsRName(string(o.Reg))
+}
+
+func s390xIsFRegOp(o Operand) bool {
+	return o.Kind == OpReg && s390xIsFReg(o.Reg)
+}
+
+func s390xIsVecOp(o Operand) bool {
+	return o.Kind == OpReg && s390xIsVReg(o.Reg)
+}
+
+// s390xRegNum returns n for Rn (15 for SP).
+func s390xRegNum(r Reg) int {
+	if r == SP {
+		return 15
+	}
+	n, _ := strconv.Atoi(strings.TrimPrefix(string(r), "R"))
+	return n
+}
+
+// s390xRegName returns the slot name of general register n.
+func s390xRegName(n int) Reg {
+	if n&15 == 15 {
+		return SP
+	}
+	return Reg("R" + strconv.Itoa(n&15))
+}
+
+// s390xVRegNum returns n for Vn or Fn.
+func s390xVRegNum(r Reg) int {
+	n, _ := strconv.Atoi(string(r)[1:])
+	return n
+}
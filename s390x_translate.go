@@ -0,0 +1,214 @@
+package plan9asm
+
+import (
+	"fmt"
+	"strings"
+)
+
+func emitS390XPrelude(b *strings.Builder) {
+	for _, w := range []string{"i32", "i64"} {
+		fmt.Fprintf(b, "declare %s @llvm.fshl.%s(%s, %s, %s)\n", w, w, w, w, w)
+	}
+	for _, w := range []string{"i64", "i128"} {
+		fmt.Fprintf(b, "declare %s @llvm.ctlz.%s(%s, i1)\n", w, w, w)
+	}
+	for _, w := range []string{"i16", "i32", "i64"} {
+		fmt.Fprintf(b, "declare %s @llvm.bswap.%s(%s)\n", w, w, w)
+	}
+	b.WriteString("declare <8 x i8> @llvm.ctpop.v8i8(<8 x i8>)\n")
+	for _, v := range s390xVecLanes {
+		ty, suffix := v.vecType(), v.intrinsicSuffix()
+		fmt.Fprintf(b, "declare %s @llvm.fshl.%s(%s, %s, %s)\n", ty, suffix, ty, ty, ty)
+		for _, fn := range []string{"ctlz", "cttz"} {
+			fmt.Fprintf(b, "declare %s @llvm.%s.%s(%s, i1)\n", ty, fn, suffix, ty)
+		}
+	}
+	for _, f := range []string{"f32", "f64", "v2f64"} {
+		ty := riscv64FloatTypes[f]
+		if f == "v2f64" {
+			ty = "<2 x double>"
+		}
+		fmt.Fprintf(b, "declare %s @llvm.fma.%s(%s, %s, %s)\n", ty, f, ty, ty, ty)
+		for _, fn := range []string{"sqrt", "fabs"} {
+			fmt.Fprintf(b, "declare %s @llvm.%s.%s(%s)\n", ty, fn, f, ty)
+		}
+		if f == "v2f64" {
+			continue
+		}
+		fmt.Fprintf(b, "declare %s @llvm.copysign.%s(%s, %s)\n", ty, f, ty, ty)
+		for _, fn := range []string{"round", "roundeven", "floor", "ceil", "trunc"} {
+			fmt.Fprintf(b, "declare %s @llvm.%s.%s(%s)\n", ty, fn, f, ty)
+		}
+		for _, conv := range []string{"fptosi", "fptoui"} {
+			for _, w := range []string{"i32", "i64"} {
+				fmt.Fprintf(b, "declare %s @llvm.%s.sat.%s.%s(%s)\n", w, conv, w, f, ty)
+			}
+		}
+	}
+	b.WriteString("declare void @llvm.memset.p0.i64(ptr, i8, i64, i1)\n")
+	b.WriteString("declare void @llvm.memcpy.p0.p0.i64(ptr, ptr, i64, i1)\n")
+	b.WriteString("declare void @llvm.debugtrap()\n")
+	b.WriteString("declare void @llvm.trap()\n")
+	b.WriteString("\n")
+}
+
+func translateFuncS390X(b *strings.Builder, fn Func, sig FuncSig, resolve func(string) string, sigs map[string]FuncSig, cfg lowerConfig) error {
+	fmt.Fprintf(b, "define %s %s(", sig.Ret, llvmGlobal(sig.Name))
+	for i, t := range sig.Args {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		fmt.Fprintf(b, "%s %%arg%d", t, i)
+	}
+	b.WriteString(")")
+	if sig.Attrs != "" {
+		b.WriteString(" " + sig.Attrs)
+	}
+	b.WriteString(" {\n")
+
+	c := newS390XCtx(b, fn, sig, resolve, sigs, cfg)
+	if err := c.emitEntryAllocasAndArgInit(); err != nil {
+		return err
+	}
+	if err := c.lowerBlocks(); err != nil {
+		return err
+	}
+
+	b.WriteString("}\n")
+	return nil
+}
+
+func (c *s390xCtx) br(target string) {
+	fmt.Fprintf(c.b, "  br label %%%s\n", arm64LLVMBlockName(target))
+}
+
+func (c *s390xCtx) lowerBlocks() error {
+	// The allocas get a block of their own so that a branch back to the
+	// first instruction stays valid.
+	c.br(c.blocks[0].name)
+	for bi, blk := range c.blocks {
+		fmt.Fprintf(c.b, "\n%s:\n", arm64LLVMBlockName(blk.name))
+		terminated := false
+		for ii, ins := range blk.instrs {
+			c.emitSourceComment(ins)
+			term, err := c.lowerInstr(bi, ii, ins)
+			if err != nil {
+				return err
+			}
+			if term {
+				terminated = true
+				break
+			}
+		}
+		if terminated {
+			continue
+		}
+		if bi+1 < len(c.blocks) {
+			c.br(c.blocks[bi+1].name)
+			continue
+		}
+		c.lowerRetZero()
+	}
+	return nil
+}
+
+func (c *s390xCtx) lowerInstr(bi, ii int, ins Instr) (terminated bool, err error) {
+	op := strings.ToUpper(string(ins.Op))
+	switch Op(op) {
+	case OpTEXT:
+		return false, nil
+	case OpRET:
+		return true, c.lowerRET()
+	case OpBYTE:
+		// s390x code uses BYTE and WORD to encode instructions the
+		// assembler lacks, not data.
+		return false, fmt.Errorf("s390x: raw instruction encoding %s is not lowered", ins.Op)
+	}
+	switch op {
+	case "WORD":
+		return false, fmt.Errorf("s390x: raw instruction encoding %s is not lowered", ins.Op)
+	case "PCALIGN", "NO_LOCAL_POINTERS", "PCDATA", "FUNCDATA", "GO_ARGS", "NOP", "NOOP":
+		return false, nil
+	}
+
+	if ok, term, err := c.lowerData(op, ins); ok {
+		return term, err
+	}
+	if ok, term, err := c.lowerAtomic(op, ins); ok {
+		return term, err
+	}
+	if ok, term, err := c.lowerVec(op, ins); ok {
+		return term, err
+	}
+	if ok, term, err := c.lowerFP(op, ins); ok {
+		return term, err
+	}
+	if ok, term, err := c.lowerArith(op, ins); ok {
+		return term, err
+	}
+	if ok, term, err := c.lowerSyscall(op, ins); ok {
+		return term, err
+	}
+	if ok, term, err := c.lowerBranch(bi, ii, op, ins); ok {
+		return term, err
+	}
+	return false, fmt.Errorf("s390x: unsupported instruction %s", ins.Op)
+}
+
+func (c *s390xCtx) lowerRET() error {
+	if len(c.fpResults) == 0 {
+		if c.sig.Ret == Void {
+			c.b.WriteString("  ret void\n")
+			return nil
+		}
+		var cur s390xArgCursor
+		r, _ := cur.next(c.sig.Ret)
+		v64, err := c.loadReg(r)
+		if err != nil {
+			return err
+		}
+		v, err := c.regToValue(v64, c.sig.Ret)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(c.b, "  ret %s %s\n", c.sig.Ret, v)
+		return nil
+	}
+
+	// Results come from their FP slots when the body stores to them (or
+	// takes their address), otherwise from the ABIInternal registers.
+	load := func(slot FrameSlot) (string, error) {
+		if c.fpResWritten[slot.Index] || c.fpResAddrTaken[slot.Index] {
+			return c.loadFPResult(slot)
+		}
+		return c.loadRetSlotFallback(slot)
+	}
+	if len(c.fpResults) == 1 {
+		v, err := load(c.fpResults[0])
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(c.b, "  ret %s %s\n", c.sig.Ret, v)
+		return nil
+	}
+	cur := "undef"
+	for _, slot := range c.fpResults {
+		v, err := load(slot)
+		if err != nil {
+			return err
+		}
+		t := c.newTmp()
+		fmt.Fprintf(c.b, "  %%%s = insertvalue %s %s, %s %s, %d\n", t, c.sig.Ret, cur, slot.Type, v, slot.Index)
+		cur = "%" + t
+	}
+	fmt.Fprintf(c.b, "  ret %s %s\n", c.sig.Ret, cur)
+	return nil
+}
+
+func (c *s390xCtx) lowerRetZero() {
+	if c.sig.Ret == Void {
+		c.b.WriteString("  ret void\n")
+		return
+	}
+	fmt.Fprintf(c.b, "  ret %s %s\n", c.sig.Ret, llvmZeroValue(c.sig.Ret))
+}
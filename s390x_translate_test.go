@@ -0,0 +1,222 @@
+//go:build !llgo
+// +build !llgo
+
+package plan9asm
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func translateS390X(t *testing.T, src string, sigs map[string]FuncSig) string {
+	t.Helper()
+	file, err := Parse(ArchS390X, src)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	ll, err := translateIRText(file, Options{
+		TargetTriple: "s390x-unknown-linux-gnu",
+		Goarch:       "s390x",
+		ResolveSym:   testResolveSym("example"),
+		Sigs:         sigs,
+	})
+	if err != nil {
+		t.Fatalf("translateIRText: %v", err)
+	}
+	return ll
+}
+
+func TestTranslateS390XRegisters(t *testing.T) {
+	ll := translateS390X(t, `TEXT ·f(SB),NOSPLIT,$0-24
+	MOVD a+0(FP), R2
+	MOVD b+8(FP), R3
+	ADD R3, R2, R4
+	SUB R3, R2, R5
+	MULHDU R3, R2
+	RISBGZ $56, $63, $8, R4, R6
+	ADDC R2, R3, R7
+	ADDE R0, R0, R8
+	MOVWZ R5, R9
+	RLLG $3, R9, R9
+	ADD R6, R4
+	ADD R7, R4
+	ADD R8, R4
+	ADD R9, R4
+	MOVD R4, ret+16(FP)
+	RET
+`, map[string]FuncSig{
+		"example.f": sigWithClassicFrame("example.f", []LLVMType{I64, I64}, I64),
+	})
+	wantIR(t, ll,
+		`define i64 @"example.f"(i64 %arg0, i64 %arg1)`,
+		"%reg_R2 = alloca i64",
+		"%reg_CC = alloca i64",
+		"add i64",
+		"sub i64",
+		"mul i128",
+		"@llvm.fshl.i64",
+		"zext i32",
+		"ret i64",
+	)
+}
+
+func TestTranslateS390XBranches(t *testing.T) {
+	ll := translateS390X(t, `TEXT ·sum(SB),NOSPLIT,$0-16
+	MOVD n+0(FP), R2
+	MOVD $0, R3
+	CMPBLE R2, $0, done
+loop:
+	ADD R2, R3
+	BRCTG R2, loop
+done:
+	MOVD R3, ret+8(FP)
+	RET
+
+TEXT ·max(SB),NOSPLIT,$0-24
+	MOVD a+0(FP), R2
+	MOVD b+8(FP), R3
+	CMPU R2, R3
+	BLT less
+	CIJ $8, R2, $0, zero
+	MOVD R2, ret+16(FP)
+	RET
+less:
+	MOVD R3, ret+16(FP)
+	RET
+zero:
+	MOVD $-1, ret+16(FP)
+	RET
+`, map[string]FuncSig{
+		"example.sum": sigWithClassicFrame("example.sum", []LLVMType{I64}, I64),
+		"example.max": sigWithClassicFrame("example.max", []LLVMType{I64, I64}, I64),
+	})
+	wantIR(t, ll, "icmp slt i64", "icmp ult i64", "icmp eq i32", "%reg_CC", "label %loop", "label %done", "label %less", "label %zero")
+}
+
+func TestTranslateS390XFloat(t *testing.T) {
+	ll := translateS390X(t, `TEXT ·fma(SB),NOSPLIT,$0-32
+	FMOVD a+0(FP), F1
+	FMOVD b+8(FP), F2
+	FMOVD c+16(FP), F3
+	FMADD F1, F2, F3
+	CGDBRA F3, R2
+	MOVD R2, ret+24(FP)
+	RET
+
+TEXT ·floor(SB),NOSPLIT,$0-16
+	FMOVD x+0(FP), F0
+	FIDBR $7, F0, F0
+	WFMADB V0, V0, V0, V1
+	FMOVD F1, ret+8(FP)
+	RET
+`, map[string]FuncSig{
+		"example.fma":   sigWithClassicFrame("example.fma", []LLVMType{LLVMType("double"), LLVMType("double"), LLVMType("double")}, I64),
+		"example.floor": sigWithClassicFrame("example.floor", []LLVMType{LLVMType("double")}, LLVMType("double")),
+	})
+	wantIR(t, ll, "@llvm.fma.f64", "@llvm.fptosi.sat.i64.f64", "@llvm.floor.f64", "bitcast i64")
+}
+
+func TestTranslateS390XAtomics(t *testing.T) {
+	ll := translateS390X(t, `TEXT ·cas(SB),NOSPLIT,$0-25
+	MOVD ptr+0(FP), R3
+	MOVD old+8(FP), R4
+	MOVD new+16(FP), R5
+	CSG R4, R5, 0(R3)
+	BNE fail
+	MOVB $1, ret+24(FP)
+	RET
+fail:
+	MOVB $0, ret+24(FP)
+	RET
+
+TEXT ·xadd(SB),NOSPLIT,$0-24
+	MOVD ptr+0(FP), R4
+	MOVD delta+8(FP), R5
+	LAAG R5, R6, 0(R4)
+	ADD R5, R6
+	SYNC
+	MOVD R6, ret+16(FP)
+	RET
+`, map[string]FuncSig{
+		"example.cas":  sigWithClassicFrame("example.cas", []LLVMType{Ptr, I64, I64}, I1),
+		"example.xadd": sigWithClassicFrame("example.xadd", []LLVMType{Ptr, I64}, I64),
+	})
+	wantIR(t, ll, "cmpxchg ptr", "atomicrmw add ptr", "fence seq_cst")
+}
+
+func TestTranslateS390XVector(t *testing.T) {
+	ll := translateS390X(t, `TEXT ·eq16(SB),NOSPLIT,$0-17
+	MOVD a+0(FP), R3
+	MOVD b+8(FP), R4
+	VL 0(R3), V1
+	VL 0(R4), V2
+	VCEQBS V1, V2, V3
+	BEQ equal
+	MOVB $0, ret+16(FP)
+	RET
+equal:
+	MOVB $1, ret+16(FP)
+	RET
+
+TEXT ·add(SB),NOSPLIT,$0-24
+	MOVD a+0(FP), R3
+	MOVD b+8(FP), R4
+	MOVD n+16(FP), R5
+	VL 0(R3), V1
+	VLL R5, 0(R4), V2
+	VZERO V0
+	VACQ V1, V2, V0, V3
+	VACCCQ V1, V2, V0, V4
+	VFEEBS V1, V2, V5
+	VLGVG $0, V5, R6
+	VST V3, 0(R3)
+	RET
+`, map[string]FuncSig{
+		"example.eq16": sigWithClassicFrame("example.eq16", []LLVMType{Ptr, Ptr}, I1),
+		"example.add":  sigWithClassicFrame("example.add", []LLVMType{Ptr, Ptr, I64}, Void),
+	})
+	wantIR(t, ll, "%reg_V16 = alloca i128", "icmp eq <16 x i8>", "load i128", "add i128", "@llvm.memcpy.p0.p0.i64(ptr %vlbuf", "@llvm.ctlz.i128", "store i128")
+}
+
+func TestTranslateS390XDataBigEndian(t *testing.T) {
+	ll := translateS390X(t, `DATA ·tab<>+0(SB)/4, $0x01020304
+DATA ·tab<>+4(SB)/2, $0x0506
+GLOBL ·tab<>(SB), RODATA, $8
+
+TEXT ·f(SB),NOSPLIT,$0-0
+	RET
+`, map[string]FuncSig{
+		"example.f": {Name: "example.f", Ret: Void},
+	})
+	wantIR(t, ll, "[i8 1, i8 2, i8 3, i8 4, i8 5, i8 6, i8 0, i8 0]")
+
+	if got := encodeDATA(ArchAMD64, 0x0102, 4); !bytes.Equal(got, []byte{2, 1, 0, 0}) {
+		t.Fatalf("encodeDATA(amd64) = %v", got)
+	}
+	if got := encodeDATA(ArchS390X, 0x0102, 4); !bytes.Equal(got, []byte{0, 0, 1, 2}) {
+		t.Fatalf("encodeDATA(s390x) = %v", got)
+	}
+}
+
+func TestTranslateS390XRejectsUnsupported(t *testing.T) {
+	for _, tc := range []struct{ insn, want string }{
+		{"WORD $0xB9830012", "raw instruction encoding"},
+		{"EXRL $f<>(SB), R1", "unsupported instruction EXRL"},
+		{"VGFMG V1, V2, V3", "unsupported instruction VGFMG"},
+		{"FADD R3, F1", "expects F, F"},
+	} {
+		file, err := Parse(ArchS390X, "TEXT ·f(SB),NOSPLIT,$0-0\n\t"+tc.insn+"\n\tRET\n")
+		if err != nil {
+			t.Fatalf("%s: Parse: %v", tc.insn, err)
+		}
+		_, err = translateIRText(file, Options{
+			Goarch:     "s390x",
+			ResolveSym: testResolveSym("example"),
+			Sigs:       map[string]FuncSig{"example.f": {Name: "example.f", Ret: Void}},
+		})
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: err = %v, want %q", tc.insn, err, tc.want)
+		}
+	}
+}
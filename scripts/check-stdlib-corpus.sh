@@ -31,7 +31,7 @@ for target in "${targets[@]}"; do
 
   echo "==> scan $goos/$goarch"
   json="$tmp_root/$goos-$goarch.json"
-  go run ./cmd/plan9asmscan -goos="$goos" -goarch="$goarch" -repo-root . -format json -out "$json"
+  go run ./cmd/plan9asmscan -goos="$goos" -goarch="$goarch" -format json -out "$json"
   python3 - "$json" "$goos/$goarch" <<'PY'
 import json
 import sys
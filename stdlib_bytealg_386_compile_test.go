@@ -0,0 +1,113 @@
+//go:build !llgo
+// +build !llgo
+
+package plan9asm
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestStdlibInternalBytealg_386_Compile(t *testing.T) {
+	llc, _, ok := findLlcAndClang(t)
+	if !ok {
+		t.Skip("llc not found")
+	}
+	goroot := runtime.GOROOT()
+	if goroot == "" {
+		t.Skip("GOROOT not available")
+	}
+	sfiles := stdlibBytealg386Sigs(goroot)
+	if len(sfiles) == 0 {
+		t.Skip("internal/bytealg 386 asm files not present in this GOROOT")
+	}
+	resolve := func(sym string) string {
+		sym = goStripABISuffix(sym)
+		if strings.HasPrefix(sym, "runtime·") {
+			sym = strings.ReplaceAll(sym, "∕", "/")
+			return strings.ReplaceAll(sym, "·", ".")
+		}
+		if strings.HasPrefix(sym, "·") {
+			return "internal/bytealg." + strings.TrimPrefix(sym, "·")
+		}
+		if !strings.Contains(sym, "·") && !strings.Contains(sym, ".") && !strings.Contains(sym, "/") {
+			return "internal/bytealg." + sym
+		}
+		sym = strings.ReplaceAll(sym, "∕", "/")
+		return strings.ReplaceAll(sym, "·", ".")
+	}
+
+	triple := "i686-unknown-linux-gnu"
+	compiled := 0
+	for path, sigs := range sfiles {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("read %s: %v", path, err)
+		}
+		file, err := Parse(Arch386, string(src))
+		if err != nil {
+			t.Fatalf("parse %s: %v", path, err)
+		}
+		ll, err := Translate(file, Options{
+			TargetTriple: triple,
+			ResolveSym:   resolve,
+			Sigs:         sigs,
+			Goarch:       "386",
+		})
+		if err != nil {
+			t.Fatalf("translate %s: %v", path, err)
+		}
+		tmp := t.TempDir()
+		llPath := filepath.Join(tmp, filepath.Base(path)+".ll")
+		objPath := filepath.Join(tmp, filepath.Base(path)+".o")
+		if err := os.WriteFile(llPath, []byte(ll), 0644); err != nil {
+			t.Fatal(err)
+		}
+		cmd := exec.Command(llc, "-mtriple="+triple, "-mcpu=pentium4", "-filetype=obj", llPath, "-o", objPath)
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			s := string(out)
+			if strings.Contains(s, "No available targets") ||
+				strings.Contains(s, "no targets are registered") ||
+				strings.Contains(s, "unknown target triple") ||
+				strings.Contains(s, "unknown target") ||
+				strings.Contains(s, "is not a registered target") {
+				t.Skipf("llc does not support triple %q: %s", triple, strings.TrimSpace(s))
+			}
+			t.Fatalf("llc failed for %s: %v\n%s", path, err, s)
+		}
+		compiled++
+	}
+	if compiled == 0 {
+		t.Fatalf("expected at least one successful llc compilation")
+	}
+}
+
+func stdlibBytealg386Sigs(goroot string) map[string]map[string]FuncSig {
+	sfiles := map[string]map[string]FuncSig{
+		filepath.Join(goroot, "src", "internal", "bytealg", "compare_386.s"): {
+			"internal/bytealg.Compare": sigWithClassicFrame32("internal/bytealg.Compare", []LLVMType{"{ ptr, i32, i32 }", "{ ptr, i32, i32 }"}, I32),
+			"runtime.cmpstring":        sigWithClassicFrame32("runtime.cmpstring", []LLVMType{"{ ptr, i32 }", "{ ptr, i32 }"}, I32),
+			"internal/bytealg.cmpbody": withArgRegs(sigWithClassicFrame32("internal/bytealg.cmpbody", []LLVMType{Ptr, Ptr, I32, I32, Ptr}, Void), []Reg{SI, DI, BX, DX, AX}),
+		},
+		filepath.Join(goroot, "src", "internal", "bytealg", "equal_386.s"): {
+			"runtime.memequal":           sigWithClassicFrame32("runtime.memequal", []LLVMType{Ptr, Ptr, I32}, I1),
+			"runtime.memequal_varlen":    sigWithClassicFrame32("runtime.memequal_varlen", []LLVMType{Ptr, Ptr}, I1),
+			"internal/bytealg.memeqbody": withArgRegs(sigWithClassicFrame32("internal/bytealg.memeqbody", []LLVMType{Ptr, Ptr, I32, Ptr}, Void), []Reg{SI, DI, BX, AX}),
+		},
+		filepath.Join(goroot, "src", "internal", "bytealg", "indexbyte_386.s"): {
+			"internal/bytealg.IndexByte":       sigWithClassicFrame32("internal/bytealg.IndexByte", []LLVMType{"{ ptr, i32, i32 }", I8}, I32),
+			"internal/bytealg.IndexByteString": sigWithClassicFrame32("internal/bytealg.IndexByteString", []LLVMType{"{ ptr, i32 }", I8}, I32),
+		},
+	}
+	for path := range sfiles {
+		if _, err := os.Stat(path); err != nil {
+			delete(sfiles, path)
+		}
+	}
+	return sfiles
+}
@@ -0,0 +1,326 @@
+package plan9asm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SyscallStrategy selects how system call instructions (amd64 SYSCALL, 386
+// INT $0x80, arm64 SVC, arm SWI, riscv64 ECALL, loong64, ppc64, s390x and
+// mips SYSCALL) are lowered. The backends load the trap number and argument
+// registers, hand them to the strategy as i64 values, and write the results
+// back following the source ABI.
+//
+// The built-in strategies are LibcSyscall (the default), RawSyscall and
+// HookSyscall. Other packages can supply their own, such as a sandbox shim
+// that services some calls inline, by implementing both methods with
+// SyscallSite and SyscallResult.
+type SyscallStrategy interface {
+	// Decls writes the module-level declarations the strategy's calls
+	// reference, one per line.
+	Decls(b *strings.Builder)
+	// Lower writes IR performing the call described by s and returns its
+	// results.
+	Lower(s *SyscallSite) (SyscallResult, error)
+}
+
+// LibcSyscall calls the C library's syscall(2) and reads errno through a
+// helper function, since errno itself is not addressable from IR.
+type LibcSyscall struct {
+	// Func is the `i64 (i64 x7)` syscall function. Default "syscall".
+	Func string
+	// Errno is the `i32 ()` function returning the current errno. Default
+	// "cliteErrno".
+	Errno string
+}
+
+// RawSyscall emits the kernel trap instruction itself as inline asm, for
+// static builds without a C library. On Linux the registers are
+//
+//	amd64  SYSCALL    RAX = num, RDI RSI RDX R10 R8 R9 -> RAX, RDX
+//	386    INT $0x80  EAX = num, EBX ECX EDX ESI EDI EBP -> EAX, EDX
+//	arm64  SVC        X8 = num, X0-X5                  -> X0, X1
+//	arm    SWI        R7 = num, R0-R6                  -> R0, R1
+//	riscv64 ECALL     A7 = num, A0-A5                  -> A0, A1
+//	loong64 SYSCALL   R11 = num, R4-R9                 -> R4
+//	ppc64le SYSCALL   R0 = num, R3-R8                  -> R3, R4
+//	s390x  SYSCALL    R1 = num, R2-R7                  -> R2, R3
+//	mips64 SYSCALL    R2 = num, R4-R9                  -> R2, R3
+//	mips   SYSCALL    R2 = num, R4-R7, 16(SP), 20(SP)  -> R2, R3
+//
+// darwin and the BSDs are supported on amd64 (same registers) and arm64
+// (X16 and SVC #0x80 on darwin, X17 on netbsd), with the carry flag read
+// back as the error indication; ppc64le Linux reports failure the same way,
+// in CR0.SO, and the MIPS dialects in R7. Both result registers are written
+// back as the kernel leaves them. It needs Options.TargetTriple to name the
+// source architecture (or be empty).
+type RawSyscall struct{}
+
+// HookSyscall calls a runtime-provided function
+//
+//	{ i64, i64, i64 } Func(i64 num, i64 a1, ..., i64 a6)
+//
+// returning the two result registers and a positive errno, which is zero on
+// success. The aggregate is returned directly, as llgo returns a Go func's
+// (r1, r2, errno uintptr) results. It suits sandboxes and runtimes that
+// service syscalls themselves.
+type HookSyscall struct {
+	Func string
+}
+
+// syscallConv is how the target OS reports a failed system call.
+type syscallConv int
+
+const (
+	// syscallConvLinux returns -errno in the first result register.
+	syscallConvLinux syscallConv = iota
+	// syscallConvBSD returns errno in the first result register and sets the
+	// carry flag (darwin, dragonfly, freebsd, netbsd, openbsd).
+	syscallConvBSD
+)
+
+func syscallConvFor(goos string) syscallConv {
+	switch goos {
+	case "darwin", "ios", "dragonfly", "freebsd", "netbsd", "openbsd":
+		return syscallConvBSD
+	}
+	return syscallConvLinux
+}
+
+// tripleGOOS returns the GOOS an LLVM target triple names, or "" when it is
+// not recognized.
+func tripleGOOS(triple string) string {
+	parts := strings.Split(triple, "-")
+	if len(parts) < 3 {
+		return ""
+	}
+	if parts[1] == "apple" {
+		return "darwin"
+	}
+	for _, goos := range []string{"linux", "freebsd", "netbsd", "openbsd", "dragonfly", "windows"} {
+		if strings.HasPrefix(parts[2], goos) {
+			return goos
+		}
+	}
+	return ""
+}
+
+// SyscallSite is one system call instruction being lowered. Values are IR
+// operands: SSA names such as %t3 or integer constants.
+type SyscallSite struct {
+	// Arch is the source architecture and Goos the target OS.
+	Arch Arch
+	Goos string
+	// Native reports that the target executes Arch instructions, which
+	// RawSyscall requires.
+	Native bool
+	// Num is the trap number, an i64.
+	Num string
+	// Args holds the i64 argument registers in order. libc and hook calls
+	// pass the first six; arm's raw SWI also passes R6.
+	Args []string
+
+	conv   syscallConv
+	b      *strings.Builder
+	newTmp func() string
+}
+
+// Tmp returns a fresh SSA name, with its leading %.
+func (s *SyscallSite) Tmp() string { return "%" + s.newTmp() }
+
+// Emit writes one IR instruction, formatted as by fmt.Sprintf, into the
+// function being lowered.
+func (s *SyscallSite) Emit(format string, args ...any) {
+	fmt.Fprintf(s.b, "  "+format+"\n", args...)
+}
+
+// SyscallResult holds the i64 result registers of a system call, whether
+// it failed (i1) and the positive errno (i64, meaningful only on failure).
+// Direct is set when R1 already follows the target OS convention, as it
+// does for a raw kernel call; otherwise the backend derives it from IsErr
+// and Errno.
+type SyscallResult struct {
+	R1, R2 string
+	IsErr  string
+	Errno  string
+	Direct bool
+}
+
+// ret returns the first result register under the site's convention:
+// -errno (Linux) or errno (BSD) on failure, r1 otherwise.
+func (r SyscallResult) ret(s *SyscallSite) string {
+	if r.Direct {
+		return r.R1
+	}
+	errv := r.Errno
+	if s.conv == syscallConvLinux {
+		errv = s.Tmp()
+		fmt.Fprintf(s.b, "  %s = sub i64 0, %s\n", errv, r.Errno)
+	}
+	ret := s.Tmp()
+	fmt.Fprintf(s.b, "  %s = select i1 %s, i64 %s, i64 %s\n", ret, r.IsErr, errv, r.R1)
+	return ret
+}
+
+func (opt Options) syscallStrategy() SyscallStrategy {
+	if opt.Syscall == nil {
+		return LibcSyscall{}
+	}
+	return opt.Syscall
+}
+
+func (l LibcSyscall) names() (fn, errno string) {
+	fn, errno = l.Func, l.Errno
+	if fn == "" {
+		fn = "syscall"
+	}
+	if errno == "" {
+		errno = "cliteErrno"
+	}
+	return fn, errno
+}
+
+func (l LibcSyscall) Decls(b *strings.Builder) {
+	fn, errno := l.names()
+	fmt.Fprintf(b, "declare i64 %s(i64, i64, i64, i64, i64, i64, i64)\n", llvmGlobal(fn))
+	fmt.Fprintf(b, "declare i32 %s()\n", llvmGlobal(errno))
+}
+
+func (l LibcSyscall) Lower(s *SyscallSite) (SyscallResult, error) {
+	fn, errno := l.names()
+	r := s.Tmp()
+	fmt.Fprintf(s.b, "  %s = call i64 %s(i64 %s, i64 %s, i64 %s, i64 %s, i64 %s, i64 %s, i64 %s)\n",
+		r, llvmGlobal(fn), s.Num, s.Args[0], s.Args[1], s.Args[2], s.Args[3], s.Args[4], s.Args[5])
+	isErr := s.Tmp()
+	fmt.Fprintf(s.b, "  %s = icmp eq i64 %s, -1\n", isErr, r)
+	errno32 := s.Tmp()
+	fmt.Fprintf(s.b, "  %s = call i32 %s()\n", errno32, llvmGlobal(errno))
+	errno64 := s.Tmp()
+	fmt.Fprintf(s.b, "  %s = zext i32 %s to i64\n", errno64, errno32)
+	// syscall(2) has a single return value.
+	return SyscallResult{R1: r, R2: "0", IsErr: isErr, Errno: errno64}, nil
+}
+
+func (RawSyscall) Decls(b *strings.Builder) {}
+
+func (RawSyscall) Lower(s *SyscallSite) (SyscallResult, error) {
+	if !s.Native {
+		return SyscallResult{}, fmt.Errorf("raw syscalls need a %s target triple", s.Arch)
+	}
+	// BSD kernels report failure in the carry flag, read back as a third
+	// output.
+	bsd := s.conv == syscallConvBSD
+	var insn, cons, ty, carryTy string
+	switch {
+	case s.Arch == ArchAMD64:
+		insn, ty = "syscall", "i64"
+		cons = "={rax},={rdx},{rax},{rdi},{rsi},{rdx},{r10},{r8},{r9},~{rcx},~{r11},~{memory}"
+		if bsd {
+			carryTy = "i8"
+			cons = "={rax},={rdx},={@ccc},{rax},{rdi},{rsi},{rdx},{r10},{r8},{r9},~{rcx},~{r11},~{memory}"
+		}
+	case s.Arch == Arch386 && !bsd:
+		insn, ty = "int $$0x80", "i32"
+		cons = "={eax},={edx},{eax},{ebx},{ecx},{edx},{esi},{edi},{ebp},~{memory}"
+	case s.Arch == ArchARM64 && !bsd:
+		insn, ty = "svc #0", "i64"
+		cons = "={x0},={x1},{x8},{x0},{x1},{x2},{x3},{x4},{x5},~{memory}"
+	case s.Arch == ArchARM64:
+		insn, ty, carryTy = "svc #0", "i64", "i32"
+		num := "x8"
+		switch s.Goos {
+		case "darwin", "ios":
+			insn, num = "svc #0x80", "x16"
+		case "netbsd":
+			num = "x17"
+		}
+		insn += "\n\tcset ${2:w}, cs"
+		cons = "={x0},={x1},=r,{" + num + "},{x0},{x1},{x2},{x3},{x4},{x5},~{memory},~{cc}"
+	case s.Arch == ArchRISCV64 && !bsd:
+		insn, ty = "ecall", "i64"
+		cons = "={x10},={x11},{x17},{x10},{x11},{x12},{x13},{x14},{x15},~{memory}"
+	case s.Arch == ArchLOONG64 && !bsd:
+		insn, ty = "syscall 0", "i64"
+		cons = "={$r4},={$r5},{$r11},{$r4},{$r5},{$r6},{$r7},{$r8},{$r9},~{memory}"
+	case s.Arch == ArchPPC64:
+		// CR0.SO, the error indication, is extracted into the third output.
+		insn, ty, carryTy = "sc\n\tmfcr $2\n\trlwinm $2, $2, 4, 31, 31", "i64", "i64"
+		cons = "={r3},={r4},=r,{r0},{r3},{r4},{r5},{r6},{r7},{r8},~{r9},~{r10},~{r11},~{r12},~{ctr},~{xer},~{cr0},~{memory}"
+	case s.Arch == ArchS390X && !bsd:
+		insn, ty = "svc 0", "i64"
+		cons = "={r2},={r3},{r1},{r2},{r3},{r4},{r5},{r6},{r7},~{memory}"
+	case s.Arch == ArchMIPS64 || s.Arch == ArchMIPS64LE:
+		// R7 flags failure, leaving the positive errno in R2.
+		insn, ty, carryTy = "syscall", "i64", "i64"
+		cons = "={$2},={$3},={$7},{$2},{$4},{$5},{$6},{$7},{$8},{$9},~{$1},~{$10},~{$11},~{$12},~{$13},~{$14},~{$15},~{$24},~{$25},~{hi},~{lo},~{memory}"
+	case s.Arch == ArchMIPS || s.Arch == ArchMIPSLE:
+		// o32 passes the fifth and sixth arguments at 16(SP) and 20(SP).
+		insn, ty, carryTy = "addiu $$sp, $$sp, -32\n\tsw $$8, 16($$sp)\n\tsw $$9, 20($$sp)\n\tsyscall\n\taddiu $$sp, $$sp, 32", "i32", "i32"
+		cons = "={$2},={$3},={$7},{$2},{$4},{$5},{$6},{$7},{$8},{$9},~{$1},~{$10},~{$11},~{$12},~{$13},~{$14},~{$15},~{$24},~{$25},~{hi},~{lo},~{memory}"
+	case s.Arch == ArchARM && !bsd:
+		insn, ty = "swi #0", "i32"
+		cons = "={r0},={r1},{r7},{r0},{r1},{r2},{r3},{r4},{r5},{r6},~{memory}"
+	default:
+		return SyscallResult{}, fmt.Errorf("raw syscalls are not supported on %s/%s", s.Goos, s.Arch)
+	}
+	ops := append([]string{s.Num}, s.Args...)
+	for i, v := range ops {
+		if ty == "i32" {
+			t := s.Tmp()
+			fmt.Fprintf(s.b, "  %s = trunc i64 %s to i32\n", t, v)
+			v = t
+		}
+		ops[i] = ty + " " + v
+	}
+	retTy := "{ " + ty + ", " + ty + " }"
+	if carryTy != "" {
+		retTy = "{ " + ty + ", " + ty + ", " + carryTy + " }"
+	}
+	res := s.Tmp()
+	fmt.Fprintf(s.b, "  %s = call %s asm sideeffect %s, %q(%s)\n", res, retTy, llvmStringLit(insn), cons, strings.Join(ops, ", "))
+	var out [2]string
+	for i := range out {
+		out[i] = s.Tmp()
+		fmt.Fprintf(s.b, "  %s = extractvalue %s %s, %d\n", out[i], retTy, res, i)
+		if ty == "i32" {
+			// Sign-extend so -errno stays negative.
+			t := s.Tmp()
+			fmt.Fprintf(s.b, "  %s = sext i32 %s to i64\n", t, out[i])
+			out[i] = t
+		}
+	}
+	isErr := s.Tmp()
+	errno := out[0]
+	if carryTy != "" {
+		carry := s.Tmp()
+		fmt.Fprintf(s.b, "  %s = extractvalue %s %s, 2\n", carry, retTy, res)
+		fmt.Fprintf(s.b, "  %s = icmp ne %s %s, 0\n", isErr, carryTy, carry)
+	} else {
+		// The kernel returns -errno in [-4095, -1].
+		fmt.Fprintf(s.b, "  %s = icmp ugt i64 %s, -4096\n", isErr, out[0])
+		errno = s.Tmp()
+		fmt.Fprintf(s.b, "  %s = sub i64 0, %s\n", errno, out[0])
+	}
+	return SyscallResult{R1: out[0], R2: out[1], IsErr: isErr, Errno: errno, Direct: true}, nil
+}
+
+func (h HookSyscall) Decls(b *strings.Builder) {
+	fmt.Fprintf(b, "declare { i64, i64, i64 } %s(i64, i64, i64, i64, i64, i64, i64)\n", llvmGlobal(h.Func))
+}
+
+func (h HookSyscall) Lower(s *SyscallSite) (SyscallResult, error) {
+	if h.Func == "" {
+		return SyscallResult{}, fmt.Errorf("HookSyscall needs a function name")
+	}
+	res := s.Tmp()
+	fmt.Fprintf(s.b, "  %s = call { i64, i64, i64 } %s(i64 %s, i64 %s, i64 %s, i64 %s, i64 %s, i64 %s, i64 %s)\n",
+		res, llvmGlobal(h.Func), s.Num, s.Args[0], s.Args[1], s.Args[2], s.Args[3], s.Args[4], s.Args[5])
+	var out [3]string
+	for i := range out {
+		out[i] = s.Tmp()
+		fmt.Fprintf(s.b, "  %s = extractvalue { i64, i64, i64 } %s, %d\n", out[i], res, i)
+	}
+	isErr := s.Tmp()
+	fmt.Fprintf(s.b, "  %s = icmp ne i64 %s, 0\n", isErr, out[2])
+	return SyscallResult{R1: out[0], R2: out[1], IsErr: isErr, Errno: out[2]}, nil
+}
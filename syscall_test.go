@@ -0,0 +1,567 @@
+//go:build !llgo
+// +build !llgo
+
+package plan9asm
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestSyscallStrategies(t *testing.T) {
+	srcs := []struct {
+		arch   Arch
+		goarch string
+		triple string
+		word   LLVMType
+		src    string
+	}{
+		{ArchAMD64, "amd64", "x86_64-unknown-linux-gnu", I64, `TEXT ·f(SB),0,$0-16
+	MOVQ a+0(FP), DI
+	MOVQ $39, AX
+	SYSCALL
+	ADDQ DX, AX
+	MOVQ AX, ret+8(FP)
+	RET
+`},
+		{Arch386, "386", "i386-unknown-linux-gnu", I32, `TEXT ·f(SB),0,$0-8
+	MOVL a+0(FP), BX
+	MOVL $20, AX
+	INT $0x80
+	ADDL DX, AX
+	MOVL AX, ret+4(FP)
+	RET
+`},
+		{ArchARM64, "arm64", "aarch64-unknown-linux-gnu", I64, `TEXT ·f(SB),0,$0-16
+	MOVD a+0(FP), R0
+	MOVD $172, R8
+	SVC
+	ADD R1, R0, R0
+	MOVD R0, ret+8(FP)
+	RET
+`},
+		{ArchARM, "arm", "armv7-unknown-linux-gnueabihf", I32, `TEXT ·f(SB),0,$0-8
+	MOVW a+0(FP), R0
+	MOVW $20, R7
+	SWI $0
+	ADD R1, R0, R0
+	MOVW R0, ret+4(FP)
+	RET
+`},
+		{ArchRISCV64, "riscv64", "riscv64-unknown-linux-gnu", I64, `TEXT ·f(SB),0,$0-16
+	MOV a+0(FP), A0
+	MOV $172, A7
+	ECALL
+	ADD A1, A0, A0
+	MOV A0, ret+8(FP)
+	RET
+`},
+		{ArchLOONG64, "loong64", "loongarch64-unknown-linux-gnu", I64, `TEXT ·f(SB),0,$0-16
+	MOVV a+0(FP), R4
+	MOVV $172, R11
+	SYSCALL
+	MOVV R4, ret+8(FP)
+	RET
+`},
+		{ArchPPC64, "ppc64le", "powerpc64le-unknown-linux-gnu", I64, `TEXT ·f(SB),0,$0-16
+	MOVD a+0(FP), R3
+	MOVD $172, R0
+	SYSCALL
+	MOVD R3, ret+8(FP)
+	RET
+`},
+		{ArchS390X, "s390x", "s390x-unknown-linux-gnu", I64, `TEXT ·f(SB),0,$0-16
+	MOVD a+0(FP), R2
+	MOVD $20, R1
+	SYSCALL
+	MOVD R2, ret+8(FP)
+	RET
+`},
+		{ArchMIPS64LE, "mips64le", "mips64el-unknown-linux-gnuabi64", I64, `TEXT ·f(SB),0,$0-16
+	MOVV a+0(FP), R4
+	MOVV $5038, R2
+	SYSCALL
+	MOVV R2, ret+8(FP)
+	RET
+`},
+	}
+	strategies := []struct {
+		name    string
+		sys     SyscallStrategy
+		want    map[Arch][]string
+		wantAll []string
+		notWant []string
+	}{
+		{
+			name:    "default",
+			wantAll: []string{"declare i64 @syscall(", "declare i32 @cliteErrno()", "call i64 @syscall(i64 ", "call i32 @cliteErrno()"},
+		},
+		{
+			name:    "libc renamed",
+			sys:     LibcSyscall{Func: "my.syscall", Errno: "my_errno"},
+			wantAll: []string{`declare i64 @"my.syscall"(`, "declare i32 @my_errno()", `call i64 @"my.syscall"(i64 `},
+			notWant: []string{"@syscall", "@cliteErrno"},
+		},
+		{
+			name: "raw",
+			sys:  RawSyscall{},
+			want: map[Arch][]string{
+				ArchAMD64:   {`asm sideeffect "syscall", "={rax},={rdx},{rax},{rdi},{rsi},{rdx},{r10},{r8},{r9},~{rcx},~{r11},~{memory}"(i64 `, "store i64 %t", "icmp ugt i64 %", ", -4096"},
+				Arch386:     {`asm sideeffect "int $$0x80", "={eax},={edx},{eax},{ebx},{ecx},{edx},{esi},{edi},{ebp},~{memory}"(i32 `, "sext i32", "icmp ugt i64 %", ", -4096"},
+				ArchARM64:   {`asm sideeffect "svc #0", "={x0},={x1},{x8},{x0},{x1},{x2},{x3},{x4},{x5},~{memory}"(i64 `, "icmp ugt i64 %", ", -4096"},
+				ArchARM:     {`asm sideeffect "swi #0", "={r0},={r1},{r7},{r0},{r1},{r2},{r3},{r4},{r5},{r6},~{memory}"(i32 `, "sext i32", "icmp ugt i64 %", ", -4096"},
+				ArchRISCV64: {`asm sideeffect "ecall", "={x10},={x11},{x17},{x10},{x11},{x12},{x13},{x14},{x15},~{memory}"(i64 `, "icmp ugt i64 %", ", -4096"},
+				ArchLOONG64: {`asm sideeffect "syscall 0", "={$r4},={$r5},{$r11},{$r4},{$r5},{$r6},{$r7},{$r8},{$r9},~{memory}"(i64 `, "icmp ugt i64 %", ", -4096"},
+				// ppc64 Linux reports failure in CR0.SO with a positive errno.
+				ArchPPC64: {`asm sideeffect "sc\0A\09mfcr $2\0A\09rlwinm $2, $2, 4, 31, 31", "={r3},={r4},=r,{r0},{r3},{r4},{r5},{r6},{r7},{r8},`, "icmp ne i64 %"},
+				// MIPS flags failure in R7 with a positive errno in R2.
+				ArchMIPS64LE: {`asm sideeffect "syscall", "={$2},={$3},={$7},{$2},{$4},{$5},{$6},{$7},{$8},{$9},`, "icmp ne i64 %"},
+				ArchS390X:    {`asm sideeffect "svc 0", "={r2},={r3},{r1},{r2},{r3},{r4},{r5},{r6},{r7},~{memory}"(i64 `, "icmp ugt i64 %", ", -4096"},
+			},
+			notWant: []string{"@syscall", "@cliteErrno"},
+		},
+		{
+			name:    "custom",
+			sys:     sandboxShim{num: 39, val: 4242},
+			wantAll: []string{"; sandbox shim", ", 39\n", "select i1 %t", ", i64 4242, i64 0", "xor i1 %t"},
+			notWant: []string{"@syscall", "@cliteErrno", "asm sideeffect"},
+		},
+		{
+			name:    "hook",
+			sys:     HookSyscall{Func: "sandbox_syscall"},
+			wantAll: []string{"declare { i64, i64, i64 } @sandbox_syscall(", "call { i64, i64, i64 } @sandbox_syscall(i64 ", "extractvalue { i64, i64, i64 } %t"},
+			notWant: []string{"@syscall", "@cliteErrno"},
+		},
+	}
+	for _, src := range srcs {
+		for _, st := range strategies {
+			t.Run(string(src.arch)+"/"+st.name, func(t *testing.T) {
+				file, err := Parse(src.arch, src.src)
+				if err != nil {
+					t.Fatalf("Parse: %v", err)
+				}
+				resultOff := int64(8)
+				if src.word == I32 {
+					resultOff = 4
+				}
+				opt := Options{
+					TargetTriple: src.triple,
+					Goarch:       src.goarch,
+					ResolveSym:   testResolveSym("example"),
+					Sigs: map[string]FuncSig{
+						"example.f": {
+							Name: "example.f",
+							Args: []LLVMType{src.word},
+							Ret:  src.word,
+							Frame: FrameLayout{
+								Params:  []FrameSlot{{Offset: 0, Type: src.word, Index: 0, Field: -1}},
+								Results: []FrameSlot{{Offset: resultOff, Type: src.word, Index: 0, Field: -1}},
+							},
+						},
+					},
+					Syscall: st.sys,
+				}
+				ir, err := translateIRText(file, opt)
+				if err != nil {
+					t.Fatalf("translateIRText: %v", err)
+				}
+				for _, s := range append(st.wantAll, st.want[src.arch]...) {
+					if !strings.Contains(ir, s) {
+						t.Fatalf("missing %q in IR:\n%s", s, ir)
+					}
+				}
+				for _, s := range st.notWant {
+					if strings.Contains(ir, s) {
+						t.Fatalf("unexpected %q in IR:\n%s", s, ir)
+					}
+				}
+
+				if _, ok := st.sys.(RawSyscall); ok {
+					opt.TargetTriple = "sparc64-unknown-linux-gnu"
+					if _, err := translateIRText(file, opt); err == nil || !strings.Contains(err.Error(), "raw syscalls need") {
+						t.Fatalf("foreign triple: err = %v, want raw syscall error", err)
+					}
+				}
+			})
+		}
+	}
+}
+
+// sandboxShim is a SyscallStrategy written as another package would write
+// one, against the exported API only: it answers call num with val and
+// fails every other call with EPERM, without entering the kernel.
+type sandboxShim struct {
+	num, val int64
+}
+
+func (sandboxShim) Decls(b *strings.Builder) {
+	b.WriteString("; sandbox shim\n")
+}
+
+func (sh sandboxShim) Lower(s *SyscallSite) (SyscallResult, error) {
+	ok := s.Tmp()
+	s.Emit("%s = icmp eq i64 %s, %d", ok, s.Num, sh.num)
+	r1 := s.Tmp()
+	s.Emit("%s = select i1 %s, i64 %d, i64 0", r1, ok, sh.val)
+	isErr := s.Tmp()
+	s.Emit("%s = xor i1 %s, true", isErr, ok)
+	return SyscallResult{R1: r1, R2: "0", IsErr: isErr, Errno: "1"}, nil
+}
+
+func TestHookSyscallNeedsName(t *testing.T) {
+	file, err := Parse(ArchAMD64, "TEXT ·f(SB),0,$0-0\n\tSYSCALL\n\tRET\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = translateIRText(file, Options{
+		Goarch:     "amd64",
+		ResolveSym: testResolveSym("example"),
+		Sigs:       map[string]FuncSig{"example.f": {Name: "example.f", Ret: Void}},
+		Syscall:    HookSyscall{},
+	})
+	if err == nil || !strings.Contains(err.Error(), "HookSyscall needs a function name") {
+		t.Fatalf("err = %v, want missing hook name", err)
+	}
+}
+
+// TestRuntimeExecRawSyscall runs getpid and write through RawSyscall-lowered
+// SYSCALL instructions and checks them against libc.
+func TestRuntimeExecRawSyscall(t *testing.T) {
+	if runtime.GOOS != "linux" || runtime.GOARCH != "amd64" {
+		t.Skip("runtime execution test only runs on linux/amd64 host")
+	}
+
+	llc, clang, ok := findLlcAndClang(t)
+	if !ok {
+		t.Skip("llc/clang not found")
+	}
+
+	file, err := Parse(ArchAMD64, `TEXT rawgetpid(SB),NOSPLIT,$0-8
+	MOVQ $39, AX
+	SYSCALL
+	MOVQ AX, ret+0(FP)
+	RET
+
+TEXT rawwrite(SB),NOSPLIT,$0-32
+	MOVQ fd+0(FP), DI
+	MOVQ p+8(FP), SI
+	MOVQ n+16(FP), DX
+	MOVQ $1, AX
+	SYSCALL
+	MOVQ AX, ret+24(FP)
+	RET
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	slot := func(off int64, i int) FrameSlot { return FrameSlot{Offset: off, Type: I64, Index: i, Field: -1} }
+	ll, err := Translate(file, Options{
+		TargetTriple: testTargetTriple(runtime.GOOS, runtime.GOARCH),
+		Goarch:       "amd64",
+		Syscall:      RawSyscall{},
+		Sigs: map[string]FuncSig{
+			"rawgetpid": {
+				Name:  "rawgetpid",
+				Ret:   I64,
+				Frame: FrameLayout{Results: []FrameSlot{slot(0, 0)}},
+			},
+			"rawwrite": {
+				Name: "rawwrite",
+				Args: []LLVMType{I64, I64, I64},
+				Ret:  I64,
+				Frame: FrameLayout{
+					Params:  []FrameSlot{slot(0, 0), slot(8, 1), slot(16, 2)},
+					Results: []FrameSlot{slot(24, 0)},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(ll, "@syscall") || strings.Contains(ll, "@cliteErrno") {
+		t.Fatalf("raw syscalls must not reference libc:\n%s", ll)
+	}
+
+	mainC := `
+#include <stdint.h>
+#include <string.h>
+#include <unistd.h>
+extern int64_t rawgetpid(void);
+extern int64_t rawwrite(int64_t, int64_t, int64_t);
+int main(void) {
+	if (rawgetpid() != getpid()) return 1;
+	int fds[2];
+	if (pipe(fds) != 0) return 2;
+	const char msg[] = "plan9asm";
+	if (rawwrite(fds[1], (int64_t)(uintptr_t)msg, 8) != 8) return 3;
+	char buf[8];
+	if (read(fds[0], buf, 8) != 8 || memcmp(buf, msg, 8) != 0) return 4;
+	if (rawwrite(-1, (int64_t)(uintptr_t)msg, 8) != -9) return 5; /* -EBADF */
+	return 0;
+}
+`
+	compileAndRunRuntimeTest(t, llc, clang, "raw_syscall", ll, mainC)
+}
+
+// TestRawSyscallCompiles runs the RawSyscall output for each Linux trap
+// instruction through llc, whose integrated assembler checks the inline asm
+// and its register constraints.
+func TestRawSyscallCompiles(t *testing.T) {
+	llc, _, ok := findLlcAndClang(t)
+	if !ok {
+		t.Skip("llc not found")
+	}
+	tests := []struct {
+		arch   Arch
+		triple string
+		word   LLVMType
+		trap   string
+		src    string
+	}{
+		{ArchAMD64, "x86_64-unknown-linux-gnu", I64, "syscall", `TEXT ·rawwrite(SB),NOSPLIT,$0-32
+	MOVQ fd+0(FP), DI
+	MOVQ p+8(FP), SI
+	MOVQ n+16(FP), DX
+	MOVQ $1, AX
+	SYSCALL
+	MOVQ AX, ret+24(FP)
+	RET
+`},
+		{ArchARM64, "aarch64-unknown-linux-gnu", I64, "svc #0", `TEXT ·rawwrite(SB),NOSPLIT,$0-32
+	MOVD fd+0(FP), R0
+	MOVD p+8(FP), R1
+	MOVD n+16(FP), R2
+	MOVD $64, R8
+	SVC
+	MOVD R0, ret+24(FP)
+	RET
+`},
+		{ArchARM, "armv7-unknown-linux-gnueabihf", I32, "swi #0", `TEXT ·rawwrite(SB),NOSPLIT,$0-16
+	MOVW fd+0(FP), R0
+	MOVW p+4(FP), R1
+	MOVW n+8(FP), R2
+	MOVW $4, R7
+	SWI $0
+	MOVW R0, ret+12(FP)
+	RET
+`},
+	}
+	for _, tc := range tests {
+		t.Run(string(tc.arch), func(t *testing.T) {
+			file, err := Parse(tc.arch, tc.src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			size := int64(8)
+			if tc.word == I32 {
+				size = 4
+			}
+			slot := func(i int) FrameSlot { return FrameSlot{Offset: int64(i) * size, Type: tc.word, Index: i, Field: -1} }
+			ll, err := translateIRText(file, Options{
+				TargetTriple: tc.triple,
+				Goarch:       string(tc.arch),
+				Syscall:      RawSyscall{},
+				ResolveSym:   testResolveSym("example"),
+				Sigs: map[string]FuncSig{
+					"example.rawwrite": {
+						Name: "example.rawwrite",
+						Args: []LLVMType{tc.word, tc.word, tc.word},
+						Ret:  tc.word,
+						Frame: FrameLayout{
+							Params:  []FrameSlot{slot(0), slot(1), slot(2)},
+							Results: []FrameSlot{{Offset: 3 * size, Type: tc.word, Index: 0, Field: -1}},
+						},
+					},
+				},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(ll, `asm sideeffect "`+tc.trap+`"`) || strings.Contains(ll, "@syscall") {
+				t.Fatalf("want an inline %q and no libc call:\n%s", tc.trap, ll)
+			}
+			compileLLVMToObject(t, llc, tc.triple, "raw.ll", "raw.o", ll)
+		})
+	}
+}
+
+func TestSyscallConvention(t *testing.T) {
+	tests := []struct {
+		goos, triple string
+		want         syscallConv
+	}{
+		{"", "", syscallConvLinux},
+		{"linux", "", syscallConvLinux},
+		{"", "x86_64-unknown-linux-gnu", syscallConvLinux},
+		{"darwin", "", syscallConvBSD},
+		{"", "arm64-apple-macosx", syscallConvBSD},
+		{"", "x86_64-unknown-freebsd14.0", syscallConvBSD},
+		{"openbsd", "x86_64-unknown-linux-gnu", syscallConvBSD},
+		{"windows", "", syscallConvLinux},
+	}
+	for _, tc := range tests {
+		cfg := Options{Goos: tc.goos, TargetTriple: tc.triple}.lowerConfig(ArchAMD64)
+		if cfg.sysErr != tc.want {
+			t.Errorf("goos %q triple %q: convention %d, want %d", tc.goos, tc.triple, cfg.sysErr, tc.want)
+		}
+	}
+}
+
+func TestSyscallBSDConvention(t *testing.T) {
+	tests := []struct {
+		name   string
+		arch   Arch
+		goos   string
+		triple string
+		sys    SyscallStrategy
+		src    string
+		want   []string
+		err    string
+	}{
+		{
+			name: "amd64 libc",
+			arch: ArchAMD64, goos: "darwin",
+			src:  "TEXT ·f(SB),0,$0-0\n\tSYSCALL\n\tJCC ok\n\tMOVQ $0, AX\nok:\n\tRET\n",
+			want: []string{"= select i1 %t", "store i1 %t3, ptr %flags_cf"},
+		},
+		{
+			name: "amd64 raw",
+			arch: ArchAMD64, goos: "freebsd", triple: "x86_64-unknown-freebsd14.0", sys: RawSyscall{},
+			src:  "TEXT ·f(SB),0,$0-0\n\tSYSCALL\n\tJCC ok\n\tMOVQ $0, AX\nok:\n\tRET\n",
+			want: []string{`call { i64, i64, i8 } asm sideeffect "syscall", "={rax},={rdx},={@ccc},{rax},`, "ptr %flags_cf"},
+		},
+		{
+			name: "arm64 darwin raw",
+			arch: ArchARM64, triple: "arm64-apple-macosx", sys: RawSyscall{},
+			src:  "TEXT ·f(SB),0,$0-0\n\tSVC $0x80\n\tBCC ok\n\tMOVD $0, R0\nok:\n\tRET\n",
+			want: []string{`asm sideeffect "svc #0x80\0A\09cset ${2:w}, cs", "={x0},={x1},=r,{x16},`},
+		},
+		{
+			name: "arm64 netbsd raw",
+			arch: ArchARM64, goos: "netbsd", triple: "aarch64-unknown-netbsd", sys: RawSyscall{},
+			src:  "TEXT ·f(SB),0,$0-0\n\tSVC\n\tBCC ok\n\tMOVD $0, R0\nok:\n\tRET\n",
+			want: []string{`asm sideeffect "svc #0\0A\09cset ${2:w}, cs", "={x0},={x1},=r,{x17},`},
+		},
+		{
+			name: "arm freebsd libc",
+			arch: ArchARM, goos: "freebsd",
+			src:  "TEXT ·f(SB),0,$0-0\n\tSWI $0\n\tRET\n",
+			want: []string{"ptr %flags_c"},
+		},
+		{
+			name: "arm freebsd raw",
+			arch: ArchARM, goos: "freebsd", triple: "armv7-unknown-freebsd", sys: RawSyscall{},
+			src: "TEXT ·f(SB),0,$0-0\n\tSWI $0\n\tRET\n",
+			err: "raw syscalls are not supported on freebsd/arm",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			file, err := Parse(tc.arch, tc.src)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			ir, err := translateIRText(file, Options{
+				TargetTriple: tc.triple,
+				Goarch:       string(tc.arch),
+				Goos:         tc.goos,
+				ResolveSym:   testResolveSym("example"),
+				Sigs:         map[string]FuncSig{"example.f": {Name: "example.f", Ret: Void}},
+				Syscall:      tc.sys,
+			})
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("err = %v, want %q", err, tc.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("translateIRText: %v", err)
+			}
+			for _, s := range tc.want {
+				if !strings.Contains(ir, s) {
+					t.Fatalf("missing %q in IR:\n%s", s, ir)
+				}
+			}
+			if strings.Contains(ir, "sub i64 0,") {
+				t.Fatalf("BSD convention must not negate errno:\n%s", ir)
+			}
+		})
+	}
+}
+
+// TestRuntimeExecBSDSyscallConvention runs darwin-style syscall asm against a
+// hook that fails for trap 1, checking that the JCC error path sees errno in
+// AX with CF set.
+func TestRuntimeExecBSDSyscallConvention(t *testing.T) {
+	if runtime.GOARCH != "amd64" {
+		t.Skip("runtime execution test only runs on amd64 host")
+	}
+
+	llc, clang, ok := findLlcAndClang(t)
+	if !ok {
+		t.Skip("llc/clang not found")
+	}
+
+	file, err := Parse(ArchAMD64, `TEXT bsdcall(SB),NOSPLIT,$0-16
+	MOVQ num+0(FP), AX
+	SYSCALL
+	JCC ok
+	ADDQ $1000, AX
+ok:
+	MOVQ AX, ret+8(FP)
+	RET
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ll, err := Translate(file, Options{
+		TargetTriple: testTargetTriple(runtime.GOOS, runtime.GOARCH),
+		Goarch:       "amd64",
+		Goos:         "darwin",
+		Syscall:      HookSyscall{Func: "fake_syscall"},
+		Sigs: map[string]FuncSig{
+			"bsdcall": {
+				Name: "bsdcall",
+				Args: []LLVMType{I64},
+				Ret:  I64,
+				Frame: FrameLayout{
+					Params:  []FrameSlot{{Offset: 0, Type: I64, Index: 0, Field: -1}},
+					Results: []FrameSlot{{Offset: 8, Type: I64, Index: 0, Field: -1}},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The hook returns an LLVM aggregate, so it is written in IR in place of
+	// its declaration: trap 1 fails with EBADF, any other trap returns num*2.
+	decl := "declare { i64, i64, i64 } @fake_syscall(i64, i64, i64, i64, i64, i64, i64)\n"
+	if !strings.Contains(ll, decl) {
+		t.Fatalf("missing hook declaration in IR:\n%s", ll)
+	}
+	ll = strings.Replace(ll, decl, `define { i64, i64, i64 } @fake_syscall(i64 %num, i64 %a1, i64 %a2, i64 %a3, i64 %a4, i64 %a5, i64 %a6) {
+  %fail = icmp eq i64 %num, 1
+  %r1 = shl i64 %num, 1
+  %v1 = select i1 %fail, i64 0, i64 %r1
+  %e = select i1 %fail, i64 9, i64 0
+  %s0 = insertvalue { i64, i64, i64 } undef, i64 %v1, 0
+  %s1 = insertvalue { i64, i64, i64 } %s0, i64 0, 1
+  %s2 = insertvalue { i64, i64, i64 } %s1, i64 %e, 2
+  ret { i64, i64, i64 } %s2
+}
+`, 1)
+	mainC := `
+#include <stdint.h>
+extern int64_t bsdcall(int64_t);
+int main(void) {
+	if (bsdcall(5) != 10) return 1;
+	if (bsdcall(1) != 1009) return 2;
+	return 0;
+}
+`
+	compileAndRunRuntimeTest(t, llc, clang, "bsd_syscall", ll, mainC)
+}
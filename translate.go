@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 )
 
@@ -35,6 +36,12 @@ type FuncSig struct {
 	// where helper expects inputs in a custom register assignment.
 	ArgRegs []Reg
 
+	// RetRegs optionally lists the registers a file-local helper hands back
+	// to its callers. Ret is their value, or a literal struct of them in
+	// order. Together with ArgRegs this forms the helper's register
+	// contract; see applyRegContracts.
+	RetRegs []Reg
+
 	// Frame provides a minimal stack-frame model for resolving name+off(FP)
 	// references in Go/Plan9 assembly into LLVM function args/returns.
 	//
@@ -73,9 +80,152 @@ type Options struct {
 	// Goarch is used for a few arch-specific translations (e.g. x86 CPUID).
 	Goarch string
 
+	// Goos selects OS-specific conventions such as how system calls report
+	// errors (negative errno on Linux, errno plus carry flag on darwin and
+	// the BSDs). When empty it is taken from TargetTriple.
+	Goos string
+
 	// AnnotateSource emits source asm lines as IR comments before lowering each
 	// instruction, for translation debugging.
 	AnnotateSource bool
+
+	// Degraded keeps translating when a function fails to lower: the failing
+	// TEXT symbol is replaced by a stub and recorded in the report returned by
+	// TranslateWithReport / TranslateModuleWithReport. Missing or mismatched
+	// signatures are still fatal, since a stub needs the signature too.
+	Degraded bool
+
+	// FallbackSym names the symbol a degraded stub for the resolved function
+	// name should tail-call with its own arguments (e.g. a pure Go
+	// implementation). An empty result, or a nil FallbackSym, makes the stub
+	// trap instead.
+	FallbackSym func(name string) string
+
+	// InlineAsm lowers instructions that have no IR lowering as LLVM inline
+	// assembly (`asm sideeffect`) on the amd64, arm64, arm and riscv64 CFG
+	// backends.
+	// Register operands are bound to the current register slots through
+	// constraints, and flags and memory are clobbered; on amd64 the flags an
+	// opcode writes are read back into the modeled flags. On amd64, arm64 and
+	// arm only opcodes with a checked assembler spelling are passed through;
+	// the rest stay unsupported. It only takes effect when TargetTriple names the
+	// source architecture (or is empty).
+	InlineAsm bool
+
+	// Syscall selects how system call instructions are lowered. Nil means
+	// LibcSyscall with its default symbol names.
+	Syscall SyscallStrategy
+
+	// Dispatch emits each function whose instructions use optional target
+	// features (AVX2, AVX-512, BMI2, LSE, ...) in two versions, one with the
+	// features and a baseline one, plus a resolver that picks between them
+	// from CPUID (amd64) or AT_HWCAP (arm64 Linux) at run time. Features that
+	// the emitted target intrinsics cannot do without are kept in both
+	// versions. It requires TargetTriple to name the source architecture (or
+	// be empty).
+	Dispatch DispatchMode
+
+	// SoftFloat marks every function "+soft-float", so that LLVM lowers its
+	// floating-point operations to library calls, for targets without an
+	// FPU (GOMIPS=softfloat, GOARM=5).
+	SoftFloat bool
+
+	// Goarm is the ARM architecture level, 5, 6 or 7, as the GOARM variable
+	// sets it. Below 7 the TLS register read is lowered to the kernel helper
+	// that runtime.read_tls_fallback calls, and a nonzero level without
+	// SoftFloat marks functions with its VFP features. Zero lowers as level
+	// 7 and leaves the features to TargetTriple.
+	Goarm int
+
+	// InlineHelpers splices the bodies of file-local helper<> functions into
+	// their CALL sites, where they share the caller's register and flag
+	// slots, instead of calling them. Helpers that reach themselves, use the
+	// frame or leave other than by RET are still called. A helper left
+	// without references is not emitted and is reported as FuncInlined.
+	InlineHelpers bool
+}
+
+// lowerConfig is the subset of Options consulted while lowering individual
+// instructions in the CFG backends.
+type lowerConfig struct {
+	annotateSource bool
+	inlineAsm      bool
+	syscall        SyscallStrategy
+	// native reports that TargetTriple executes the source architecture.
+	native bool
+	goos   string
+	sysErr syscallConv
+	// goarm is Options.Goarm.
+	goarm int
+	// keepDeadFlags disables flag liveness so every modeled flag is stored;
+	// tests use it to measure what liveness saves.
+	keepDeadFlags bool
+}
+
+// syscallSite starts a system call lowering through the configured strategy.
+func (cfg lowerConfig) syscallSite(arch Arch, b *strings.Builder, newTmp func() string) *SyscallSite {
+	return &SyscallSite{Arch: arch, Goos: cfg.goos, conv: cfg.sysErr, b: b, newTmp: newTmp, Native: cfg.native}
+}
+
+func (cfg lowerConfig) syscallStrategy() SyscallStrategy {
+	if cfg.syscall == nil {
+		return LibcSyscall{}
+	}
+	return cfg.syscall
+}
+
+func (opt Options) lowerConfig(arch Arch) lowerConfig {
+	goos := opt.Goos
+	if goos == "" {
+		goos = tripleGOOS(opt.TargetTriple)
+	}
+	return lowerConfig{
+		annotateSource: opt.AnnotateSource,
+		inlineAsm:      opt.InlineAsm && tripleMatchesArch(opt.TargetTriple, arch),
+		syscall:        opt.syscallStrategy(),
+		native:         tripleMatchesArch(opt.TargetTriple, arch),
+		goos:           goos,
+		sysErr:         syscallConvFor(goos),
+		goarm:          opt.Goarm,
+	}
+}
+
+// tripleMatchesArch reports whether an LLVM target triple executes arch
+// instructions natively. An empty triple matches any arch.
+func tripleMatchesArch(triple string, arch Arch) bool {
+	if triple == "" {
+		return true
+	}
+	cpu, _, _ := strings.Cut(triple, "-")
+	switch arch {
+	case ArchAMD64:
+		return cpu == "x86_64" || cpu == "amd64"
+	case Arch386:
+		return cpu == "i386" || cpu == "i486" || cpu == "i586" || cpu == "i686"
+	case ArchARM64:
+		return cpu == "aarch64" || cpu == "arm64"
+	case ArchARM:
+		return strings.HasPrefix(cpu, "arm") && cpu != "arm64" || strings.HasPrefix(cpu, "thumb")
+	case ArchRISCV64:
+		return cpu == "riscv64"
+	case ArchLOONG64:
+		return cpu == "loongarch64"
+	case ArchPPC64:
+		return cpu == "powerpc64le"
+	case ArchS390X:
+		return cpu == "s390x"
+	case ArchMIPS:
+		return cpu == "mips"
+	case ArchMIPSLE:
+		return cpu == "mipsel"
+	case ArchMIPS64:
+		return cpu == "mips64"
+	case ArchMIPS64LE:
+		return cpu == "mips64el"
+	case ArchWasm:
+		return cpu == "wasm32"
+	}
+	return false
 }
 
 // Translate converts a parsed Plan 9 asm File into LLVM IR text (`.ll`).
@@ -93,17 +243,36 @@ func Translate(file *File, opt Options) (string, error) {
 
 // translateIRText builds textual LLVM IR prior to module parsing.
 func translateIRText(file *File, opt Options) (string, error) {
+	ir, _, err := translateIRTextReport(file, opt, nil)
+	return ir, err
+}
+
+// translateIRTextReport builds textual LLVM IR and reports the outcome per
+// function. Functions named in forceStub are stubbed without lowering, with
+// the mapped error recorded in their report.
+func translateIRTextReport(file *File, opt Options, forceStub map[string]error) (string, []FuncReport, error) {
 	if file == nil {
-		return "", fmt.Errorf("nil file")
+		return "", nil, fmt.Errorf("nil file")
 	}
 	if len(file.Funcs) == 0 {
-		return "", fmt.Errorf("empty file")
+		return "", nil, fmt.Errorf("empty file")
+	}
+	if err := opt.validateGoarm(file.Arch); err != nil {
+		return "", nil, err
 	}
 
 	resolve := opt.ResolveSym
 	if resolve == nil {
 		resolve = func(s string) string { return s }
 	}
+	if file.Arch == ArchWasm {
+		opt.Sigs = wasmWithABISigs(file, resolve, opt.Sigs)
+	}
+	var inlined map[string]bool
+	if opt.InlineHelpers {
+		file, inlined = inlineHelpers(file, resolve)
+	}
+	file, opt.Sigs, _ = applyRegContracts(file, resolve, opt.Sigs)
 
 	var b strings.Builder
 	b.WriteString("; Generated by llgo internal/plan9asm (prototype)\n")
@@ -112,13 +281,14 @@ func translateIRText(file *File, opt Options) (string, error) {
 	}
 	// Keep translate.go as the cross-platform pipeline entry.
 	// Architecture-specific declarations live in arch-specific files.
-	emitArchPrelude(&b, file.Arch, opt.Goarch)
+	emitArchPrelude(&b, file.Arch, opt.Goarch, opt.syscallStrategy())
+	emitDispatchDecls(&b, file.Arch, opt)
 
 	emitExternSBGlobals(&b, file, resolve, opt.Sigs)
 
 	if len(file.Data) != 0 || len(file.Globl) != 0 {
 		if err := emitDataGlobals(&b, file, resolve); err != nil {
-			return "", err
+			return "", nil, err
 		}
 		b.WriteString("\n")
 	}
@@ -126,56 +296,142 @@ func translateIRText(file *File, opt Options) (string, error) {
 	emitExternFuncDecls(&b, file, resolve, opt.Sigs)
 
 	attrRegistry := newFeatureAttrRegistry()
+	report := make([]FuncReport, 0, len(file.Funcs))
+	stubSigs := map[string]FuncSig{}
 	for i := range file.Funcs {
 		fn := &file.Funcs[i]
 		name := resolve(fn.Sym)
+		if inlined[name] {
+			report = append(report, FuncReport{Name: name, Status: FuncInlined})
+			continue
+		}
 		sig, ok := opt.Sigs[name]
 		if !ok {
-			return "", fmt.Errorf("missing signature for %q", name)
+			return "", nil, fmt.Errorf("missing signature for %q", name)
 		}
 		if sig.Name == "" {
 			sig.Name = name
 		}
 		if sig.Name != name {
-			return "", fmt.Errorf("signature name mismatch: %q vs %q", sig.Name, name)
+			return "", nil, fmt.Errorf("signature name mismatch: %q vs %q", sig.Name, name)
 		}
 		if sig.Ret == "" {
-			return "", fmt.Errorf("missing return type for %q", name)
+			return "", nil, fmt.Errorf("missing return type for %q", name)
 		}
-		if err := validateResolvedImmediates(file.Arch, *fn); err != nil {
-			return "", fmt.Errorf("%s: %v", name, err)
-		}
-		if sig.Attrs == "" {
-			sig.Attrs = attrRegistry.ref(inferFuncTargetFeatures(file.Arch, *fn))
-		}
-		if file.Arch == ArchARM && funcNeedsARMCFG(*fn) {
-			if err := translateFuncARM(&b, *fn, sig, resolve, opt.Sigs, opt.AnnotateSource); err != nil {
-				return "", fmt.Errorf("%s: %v", name, err)
+
+		var fb strings.Builder
+		err, forced := forceStub[name]
+		if !forced {
+			err = translateFuncIR(&fb, file.Arch, *fn, sig, resolve, opt, attrRegistry)
+			if err != nil && !opt.Degraded {
+				return "", nil, fmt.Errorf("%s: %v", name, err)
 			}
-			b.WriteString("\n")
-			continue
 		}
-		if file.Arch == ArchARM64 && funcNeedsARM64CFG(*fn) {
-			if err := translateFuncARM64(&b, *fn, sig, resolve, opt.Sigs, opt.AnnotateSource); err != nil {
-				return "", fmt.Errorf("%s: %v", name, err)
+		if forced || err != nil {
+			fb.Reset()
+			r := FuncReport{Name: name, Status: FuncTrap, Err: err}
+			if opt.FallbackSym != nil {
+				if fallback := opt.FallbackSym(name); fallback != "" {
+					r.Status = FuncFallback
+					r.Fallback = fallback
+				}
 			}
-			b.WriteString("\n")
-			continue
+			emitDegradedStub(&fb, sig, r.Fallback)
+			stubSigs[name] = sig
+			report = append(report, r)
+		} else {
+			report = append(report, FuncReport{Name: name, Status: FuncTranslated})
 		}
-		if file.Arch == ArchAMD64 && opt.Goarch == "amd64" && funcNeedsAMD64CFG(*fn) {
-			if err := translateFuncAMD64(&b, *fn, sig, resolve, opt.Sigs, opt.AnnotateSource); err != nil {
-				return "", fmt.Errorf("%s: %v", name, err)
+		b.WriteString(fb.String())
+		b.WriteString("\n")
+	}
+	emitDegradedDecls(&b, report, opt.Sigs, stubSigs)
+	attrRegistry.emit(&b)
+	return b.String(), report, nil
+}
+
+// translateFuncIR lowers one function through the backend that handles it.
+func translateFuncIR(b *strings.Builder, arch Arch, fn Func, sig FuncSig, resolve func(string) string, opt Options, attrRegistry *featureAttrRegistry) error {
+	if err := validateResolvedImmediates(arch, fn); err != nil {
+		return err
+	}
+	if sig.Attrs == "" {
+		if opt.Dispatch != DispatchNone {
+			if ok, err := translateDispatchedFunc(b, arch, fn, sig, resolve, opt, attrRegistry); ok {
+				return err
 			}
-			b.WriteString("\n")
-			continue
 		}
-		if err := translateFuncLinear(&b, file.Arch, *fn, sig, opt.AnnotateSource); err != nil {
-			return "", fmt.Errorf("%s: %v", name, err)
+		features := inferFuncTargetFeatures(arch, fn)
+		extra := ""
+		switch {
+		case opt.SoftFloat:
+			extra = "+soft-float"
+		case arch == ArchARM:
+			extra = opt.armFloatFeatures()
 		}
-		b.WriteString("\n")
+		if extra != "" {
+			if features != "" {
+				features += ","
+			}
+			features += extra
+		}
+		sig.Attrs = attrRegistry.ref(features)
 	}
-	attrRegistry.emit(&b)
-	return b.String(), nil
+	return lowerFuncIR(b, arch, fn, sig, resolve, opt)
+}
+
+// lowerFuncIR emits fn with sig's attributes as they are.
+func lowerFuncIR(b *strings.Builder, arch Arch, fn Func, sig FuncSig, resolve func(string) string, opt Options) error {
+	if arch == ArchARM && funcNeedsARMCFG(fn) {
+		return translateFuncARM(b, fn, sig, resolve, opt.Sigs, opt.lowerConfig(arch))
+	}
+	if arch == ArchARM64 && funcNeedsARM64CFG(fn) {
+		return translateFuncARM64(b, fn, sig, resolve, opt.Sigs, opt.lowerConfig(arch))
+	}
+	if arch == ArchAMD64 && opt.Goarch == "amd64" && funcNeedsAMD64CFG(fn) {
+		return translateFuncAMD64(b, fn, sig, resolve, opt.Sigs, opt.lowerConfig(arch))
+	}
+	if arch == Arch386 {
+		return translateFunc386(b, fn, sig, resolve, opt.Sigs, opt.lowerConfig(arch))
+	}
+	if arch == ArchRISCV64 {
+		return translateFuncRISCV64(b, fn, sig, resolve, opt.Sigs, opt.lowerConfig(arch))
+	}
+	if arch == ArchLOONG64 {
+		return translateFuncLOONG64(b, fn, sig, resolve, opt.Sigs, opt.lowerConfig(arch))
+	}
+	if arch == ArchPPC64 {
+		return translateFuncPPC64(b, fn, sig, resolve, opt.Sigs, opt.lowerConfig(arch))
+	}
+	if arch == ArchS390X {
+		return translateFuncS390X(b, fn, sig, resolve, opt.Sigs, opt.lowerConfig(arch))
+	}
+	if archIsMIPS(arch) {
+		return translateFuncMIPS(b, arch, fn, sig, resolve, opt.Sigs, opt.lowerConfig(arch))
+	}
+	if arch == ArchWasm {
+		return translateFuncWasm(b, fn, sig, resolve, opt.Sigs, opt.lowerConfig(arch))
+	}
+	return translateFuncLinear(b, arch, fn, sig, opt.AnnotateSource)
+}
+
+// textFrameSize returns the local frame size from the TEXT directive
+// ("TEXT sym(SB), flags, $frame-args"), or 0 when it has none.
+func textFrameSize(fn Func) int64 {
+	if len(fn.Instrs) == 0 || fn.Instrs[0].Op != OpTEXT {
+		return 0
+	}
+	raw := fn.Instrs[0].Raw
+	i := strings.LastIndexByte(raw, '$')
+	if i < 0 {
+		return 0
+	}
+	frame, _, _ := strings.Cut(raw[i+1:], "-")
+	n, err := strconv.ParseInt(strings.TrimSpace(frame), 0, 64)
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
 }
 
 func validateResolvedImmediates(arch Arch, fn Func) error {
@@ -304,6 +560,27 @@ func emitExternSBGlobals(b *strings.Builder, file *File, resolve func(string) st
 	b.WriteString("\n")
 }
 
+// archBigEndian reports whether arch stores multi-byte values most
+// significant byte first.
+func archBigEndian(arch Arch) bool {
+	return arch == ArchS390X || arch == ArchMIPS || arch == ArchMIPS64
+}
+
+// encodeDATA returns the width bytes a DATA immediate occupies in memory:
+// Plan 9 asm stores it in the target's byte order.
+func encodeDATA(arch Arch, v uint64, width int64) []byte {
+	payload := make([]byte, width)
+	for i := int64(0); i < width; i++ {
+		if archBigEndian(arch) {
+			payload[width-1-i] = byte(v)
+		} else {
+			payload[i] = byte(v)
+		}
+		v >>= 8
+	}
+	return payload
+}
+
 func emitDataGlobals(b *strings.Builder, file *File, resolve func(string) string) error {
 	// Merge DATA and GLOBL into resolved symbol -> bytes.
 	type symData struct {
@@ -344,14 +621,7 @@ func emitDataGlobals(b *strings.Builder, file *File, resolve func(string) string
 		if d.Width <= 0 {
 			return fmt.Errorf("DATA %s: invalid width %d", d.Sym, d.Width)
 		}
-		payload := make([]byte, d.Width)
-		// Plan 9 asm DATA encodes immediates little-endian on amd64/arm64.
-		v := d.Value
-		for i := int64(0); i < d.Width; i++ {
-			payload[i] = byte(v & 0xff)
-			v >>= 8
-		}
-		sd.bytes[d.Off] = payload
+		sd.bytes[d.Off] = encodeDATA(file.Arch, d.Value, d.Width)
 		if end := d.Off + d.Width; end > sd.size {
 			sd.size = end
 		}
@@ -1221,8 +1491,17 @@ func translateFuncLinear(b *strings.Builder, arch Arch, fn Func, sig FuncSig, an
 }
 
 func archReturnReg(arch Arch) Reg {
-	if arch == ArchARM || arch == ArchARM64 {
+	switch arch {
+	case ArchARM, ArchARM64:
 		return Reg("R0")
+	case ArchRISCV64:
+		return Reg("X10")
+	case ArchLOONG64:
+		return Reg("R4")
+	case ArchPPC64:
+		return Reg("R3")
+	case ArchS390X, ArchMIPS, ArchMIPSLE, ArchMIPS64, ArchMIPS64LE:
+		return Reg("R2")
 	}
 	return AX
 }
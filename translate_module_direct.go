@@ -29,11 +29,28 @@ func translateModuleDirect(file *File, opt Options) (llvm.Module, error) {
 	if opt.AnnotateSource {
 		return llvm.Module{}, directUnsupportedf("source annotation requires textual lowering")
 	}
+	if opt.Dispatch != DispatchNone {
+		return llvm.Module{}, directUnsupportedf("CPU dispatch requires textual lowering")
+	}
+	if opt.SoftFloat || opt.Goarm != 0 {
+		return llvm.Module{}, directUnsupportedf("float target features require textual lowering")
+	}
 
 	resolve := opt.ResolveSym
 	if resolve == nil {
 		resolve = func(s string) string { return s }
 	}
+	if file.Arch == ArchWasm {
+		opt.Sigs = wasmWithABISigs(file, resolve, opt.Sigs)
+	}
+	if opt.InlineHelpers {
+		if out, _ := inlineHelpers(file, resolve); out != file {
+			return llvm.Module{}, directUnsupportedf("helper inlining requires textual lowering")
+		}
+	}
+	if out, _, _ := applyRegContracts(file, resolve, opt.Sigs); out != file {
+		return llvm.Module{}, directUnsupportedf("register contracts require textual lowering")
+	}
 
 	ctx := llvm.GlobalContext()
 	mod := ctx.NewModule("plan9asm")
@@ -83,6 +100,34 @@ func translateModuleDirect(file *File, opt Options) (llvm.Module, error) {
 			mod.Dispose()
 			return llvm.Module{}, directUnsupportedf("amd64 CFG lowering required for %s", name)
 		}
+		if file.Arch == Arch386 {
+			mod.Dispose()
+			return llvm.Module{}, directUnsupportedf("386 lowering required for %s", name)
+		}
+		if file.Arch == ArchRISCV64 {
+			mod.Dispose()
+			return llvm.Module{}, directUnsupportedf("riscv64 lowering required for %s", name)
+		}
+		if file.Arch == ArchLOONG64 {
+			mod.Dispose()
+			return llvm.Module{}, directUnsupportedf("loong64 lowering required for %s", name)
+		}
+		if file.Arch == ArchPPC64 {
+			mod.Dispose()
+			return llvm.Module{}, directUnsupportedf("ppc64 lowering required for %s", name)
+		}
+		if file.Arch == ArchS390X {
+			mod.Dispose()
+			return llvm.Module{}, directUnsupportedf("s390x lowering required for %s", name)
+		}
+		if archIsMIPS(file.Arch) {
+			mod.Dispose()
+			return llvm.Module{}, directUnsupportedf("%s lowering required for %s", file.Arch, name)
+		}
+		if file.Arch == ArchWasm {
+			mod.Dispose()
+			return llvm.Module{}, directUnsupportedf("wasm lowering required for %s", name)
+		}
 		if err := translateFuncLinearModule(mod, file.Arch, *fn, sig); err != nil {
 			mod.Dispose()
 			if errors.Is(err, errDirectModuleUnsupported) {
@@ -577,13 +622,7 @@ func emitDataGlobalsModule(mod llvm.Module, file *File, resolve func(string) str
 		if d.Width <= 0 {
 			return fmt.Errorf("DATA %s: invalid width %d", d.Sym, d.Width)
 		}
-		payload := make([]byte, d.Width)
-		v := d.Value
-		for i := int64(0); i < d.Width; i++ {
-			payload[i] = byte(v & 0xff)
-			v >>= 8
-		}
-		sd.bytes[d.Off] = payload
+		sd.bytes[d.Off] = encodeDATA(file.Arch, d.Value, d.Width)
 		if end := d.Off + d.Width; end > sd.size {
 			sd.size = end
 		}
@@ -2,17 +2,40 @@ package plan9asm
 
 import "strings"
 
-func emitArchPrelude(b *strings.Builder, arch Arch, goarch string) {
+func emitArchPrelude(b *strings.Builder, arch Arch, goarch string, sys SyscallStrategy) {
 	switch arch {
 	case ArchARM:
+		sys.Decls(b)
 		emitARMPrelude(b)
 	case ArchARM64:
+		sys.Decls(b)
 		emitARM64Prelude(b)
 	case ArchAMD64:
 		// Keep historical gating to avoid injecting x86-only prelude when
 		// parsing amd64 syntax for non-amd64 targets in tests.
 		if goarch == "amd64" {
+			sys.Decls(b)
 			emitAMD64Prelude(b)
 		}
+	case Arch386:
+		sys.Decls(b)
+		emitAMD64Prelude(b)
+	case ArchRISCV64:
+		sys.Decls(b)
+		emitRISCV64Prelude(b)
+	case ArchLOONG64:
+		sys.Decls(b)
+		emitLOONG64Prelude(b)
+	case ArchPPC64:
+		sys.Decls(b)
+		emitPPC64Prelude(b)
+	case ArchS390X:
+		sys.Decls(b)
+		emitS390XPrelude(b)
+	case ArchMIPS, ArchMIPSLE, ArchMIPS64, ArchMIPS64LE:
+		sys.Decls(b)
+		emitMIPSPrelude(b)
+	case ArchWasm:
+		emitWasmPrelude(b)
 	}
 }
@@ -13,9 +13,21 @@ import (
 type Arch string
 
 const (
-	ArchAMD64 Arch = "amd64"
-	ArchARM   Arch = "arm"
-	ArchARM64 Arch = "arm64"
+	ArchAMD64   Arch = "amd64"
+	Arch386     Arch = "386"
+	ArchARM     Arch = "arm"
+	ArchARM64   Arch = "arm64"
+	ArchRISCV64 Arch = "riscv64"
+	ArchLOONG64 Arch = "loong64"
+	ArchPPC64   Arch = "ppc64"
+	ArchS390X   Arch = "s390x"
+	// The MIPS dialects share one backend; mips and mips64 are big-endian.
+	ArchMIPS     Arch = "mips"
+	ArchMIPSLE   Arch = "mipsle"
+	ArchMIPS64   Arch = "mips64"
+	ArchMIPS64LE Arch = "mips64le"
+	// ArchWasm is the stack-machine dialect of js/wasm and wasip1/wasm.
+	ArchWasm Arch = "wasm"
 )
 
 type Reg string
@@ -46,17 +58,17 @@ const (
 func parseReg(s string) (Reg, bool) {
 	ss := strings.ToUpper(strings.TrimSpace(s))
 	switch ss {
-	case "RAX":
+	case "RAX", "EAX":
 		return AX, true
-	case "RBX":
+	case "RBX", "EBX":
 		return BX, true
-	case "RCX":
+	case "RCX", "ECX":
 		return CX, true
-	case "RDX":
+	case "RDX", "EDX":
 		return DX, true
-	case "RSI":
+	case "RSI", "ESI":
 		return SI, true
-	case "RDI":
+	case "RDI", "EDI":
 		return DI, true
 	case "RSP", "ESP":
 		return SP, true
@@ -119,7 +131,7 @@ func parseReg(s string) (Reg, bool) {
 		}
 	}
 	// SIMD/FP registers:
-	// - x86: X0..X31, Y0..Y31, Z0..Z31, K0..K7
+	// - x86: X0..X31, Y0..Y31, Z0..Z31, K0..K7, M0..M7
 	// - arm64: V0..V31, with optional lane suffix (e.g. V0.B16, V8.D[0])
 	// - arm64 FP: F0..F31
 	if strings.HasPrefix(ss, "K") && len(ss) >= 2 {
@@ -127,6 +139,14 @@ func parseReg(s string) (Reg, bool) {
 			return Reg(ss), true
 		}
 	}
+	if strings.HasPrefix(ss, "M") && len(ss) == 2 && ss[1] >= '0' && ss[1] <= '7' {
+		// 386 MMX registers M0..M7.
+		return Reg(ss), true
+	}
+	if strings.HasPrefix(ss, "FCC") && len(ss) == 4 && ss[3] >= '0' && ss[3] <= '7' {
+		// loong64 condition flag registers FCC0..FCC7.
+		return Reg(ss), true
+	}
 	if (strings.HasPrefix(ss, "X") || strings.HasPrefix(ss, "Y") || strings.HasPrefix(ss, "Z") || strings.HasPrefix(ss, "V") || strings.HasPrefix(ss, "F")) && len(ss) >= 2 {
 		i := 1
 		for i < len(ss) && ss[i] >= '0' && ss[i] <= '9' {
@@ -303,21 +323,22 @@ func parseImm(s string) (int64, bool) {
 		if f, ferr := strconv.ParseFloat(v, 64); ferr == nil {
 			return int64(math.Float64bits(f)), true
 		}
+		// Integer expressions such as $(8+FIXED_FRAME) come first, so
+		// that only expressions with a float literal give float bits.
+		if u, ok := parseImmExpr(v); ok {
+			return int64(u), true
+		}
 		if f, ok := parseImmFloatExpr(v); ok {
 			return int64(math.Float64bits(f)), true
 		}
-		u, ok := parseImmExpr(v)
-		if !ok {
-			// Be permissive with symbolic immediates such as:
-			//   $(16 + callbackArgs__size)
-			// Parser/scan should accept them, but lowering must reject them
-			// explicitly via Operand.ImmRaw instead of silently materializing 0.
-			if isSymbolicImmPlaceholder(s) {
-				return 0, true
-			}
-			return 0, false
+		// Be permissive with symbolic immediates such as:
+		//   $(16 + callbackArgs__size)
+		// Parser/scan should accept them, but lowering must reject them
+		// explicitly via Operand.ImmRaw instead of silently materializing 0.
+		if isSymbolicImmPlaceholder(s) {
+			return 0, true
 		}
-		return int64(u), true
+		return 0, false
 	}
 	return int64(u), true
 }
@@ -0,0 +1,82 @@
+package plan9asm
+
+import "strings"
+
+// wasmABIFunc is the WebAssembly signature of a function that Go's linker
+// gives a calling convention of its own: its parameters arrive in R0,
+// R1, ... rather than in the Go frame, further locals follow them, and
+// Return hands back the result left on the value stack.
+type wasmABIFunc struct {
+	params []string
+	result string
+	locals []string
+}
+
+// wasmABIFuncs mirrors the table of cmd/link/internal/wasm and the locals
+// cmd/internal/obj/wasm declares for those functions. File-local helpers
+// are keyed with their <> suffix.
+var wasmABIFuncs = map[string]wasmABIFunc{
+	"runtime.wasmDiv":    {params: []string{"i64", "i64"}, result: "i64"},
+	"runtime.wasmTruncS": {params: []string{"double"}, result: "i64"},
+	"runtime.wasmTruncU": {params: []string{"double"}, result: "i64"},
+	"gcWriteBarrier<>":   {params: []string{"i64"}, result: "i64", locals: []string{"i64", "i64", "i64", "i64", "i64"}},
+	"cmpbody<>":          {params: []string{"i64", "i64", "i64", "i64"}, result: "i64", locals: []string{"i64", "i64"}},
+	"memeqbody<>":        {params: []string{"i64", "i64", "i64"}, result: "i64"},
+	"memcmp<>":           {params: []string{"i32", "i32", "i32"}, result: "i32", locals: []string{"i32", "i32"}},
+	"memchr<>":           {params: []string{"i32", "i32", "i32"}, result: "i32", locals: []string{"i32", "i32"}},
+}
+
+// wasmABIFuncFor looks up the TEXT or call symbol sym in wasmABIFuncs.
+func wasmABIFuncFor(sym string) (wasmABIFunc, bool) {
+	s := strings.TrimSuffix(strings.TrimSpace(sym), "(SB)")
+	s = goABISuffixRe.ReplaceAllString(s, "")
+	s = strings.ReplaceAll(s, "·", ".")
+	f, ok := wasmABIFuncs[s]
+	return f, ok
+}
+
+// wasmABISig returns the LLVM signature of the wasm-ABI function sym,
+// named resolved.
+func wasmABISig(sym, resolved string) (FuncSig, bool) {
+	f, ok := wasmABIFuncFor(sym)
+	if !ok {
+		return FuncSig{}, false
+	}
+	sig := FuncSig{Name: resolved, Ret: Void}
+	for _, t := range f.params {
+		sig.Args = append(sig.Args, LLVMType(t))
+	}
+	if f.result != "" {
+		sig.Ret = LLVMType(f.result)
+	}
+	return sig, true
+}
+
+// wasmLocals returns the types of R0, R1, ... in a wasm-ABI function.
+func (f wasmABIFunc) wasmLocals() []string {
+	return append(append([]string(nil), f.params...), f.locals...)
+}
+
+// wasmWithABISigs returns sigs with the wasm-ABI functions that file
+// defines or calls given their fixed signatures, which override whatever
+// a caller inferred from Go declarations.
+func wasmWithABISigs(file *File, resolve func(string) string, sigs map[string]FuncSig) map[string]FuncSig {
+	out := make(map[string]FuncSig, len(sigs))
+	for name, sig := range sigs {
+		out[name] = sig
+	}
+	add := func(sym string) {
+		if sig, ok := wasmABISig(sym, resolve(strings.TrimSuffix(strings.TrimSpace(sym), "(SB)"))); ok {
+			out[sig.Name] = sig
+		}
+	}
+	for _, fn := range file.Funcs {
+		add(fn.Sym)
+		for _, ins := range fn.Instrs {
+			if op := strings.ToUpper(string(ins.Op)); (op == "CALL" || op == "CALLNORESUME") && len(ins.Args) == 1 && ins.Args[0].Kind == OpSym {
+				add(ins.Args[0].Sym)
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,316 @@
+package plan9asm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// wasmValue is an entry of the WebAssembly value stack: an SSA value of
+// type i32, i64, float or double. sp marks the value Get SP pushed, the
+// address FP operands of stores are relative to.
+type wasmValue struct {
+	ty string
+	v  string
+	sp bool
+}
+
+// wasmFrame is an open Block, Loop or If, or the function body itself at
+// the bottom of the stack.
+type wasmFrame struct {
+	kind   string // "func", "block", "loop" or "if"
+	label  string // assembler label placed on the construct, if any
+	result string // value type the construct leaves, or ""
+	height int    // value stack height inside the construct
+	head   string // loop: the block a branch continues at
+	end    string // the block after End
+	els    string // if: the false arm, until Else or End places it
+	slot   string // alloca carrying the result to end
+}
+
+// wasmCtx lowers one TEXT body of the wasm dialect. The value stack and
+// the nesting of structured control flow are known statically, so the
+// stack lives in SSA values and each construct becomes LLVM blocks;
+// results cross block boundaries through allocas. Locals live in allocas
+// of their WebAssembly type, SP in an i32 slot pointing at the TEXT
+// frame. Functions in wasmABIFuncs take their parameters in R0, R1, ...
+// and return with Return; the others follow Go's convention, reading
+// their arguments from FP slots and returning with RET.
+type wasmCtx struct {
+	b       *strings.Builder
+	arch    Arch
+	fn      Func
+	sig     FuncSig
+	resolve func(string) string
+	sigs    map[string]FuncSig
+	cfg     lowerConfig
+
+	abi    wasmABIFunc
+	hasABI bool
+
+	tmp    int
+	blockN int
+	// allocas collects the result slots of constructs, which the entry
+	// block allocates.
+	allocas strings.Builder
+
+	regSlot   map[Reg]string
+	regType   map[Reg]string
+	frameSize int64
+
+	stack   []wasmValue
+	frames  []wasmFrame
+	dead    bool   // the current position is unreachable
+	pending string // label waiting for the next Block, Loop or If
+
+	fpResults      []FrameSlot
+	fpResAllocaOff map[int64]string
+	fpResAllocaIdx map[int]string
+}
+
+func newWasmCtx(b *strings.Builder, fn Func, sig FuncSig, resolve func(string) string, sigs map[string]FuncSig, cfg lowerConfig) *wasmCtx {
+	c := &wasmCtx{
+		b:              b,
+		arch:           ArchWasm,
+		fn:             fn,
+		sig:            sig,
+		resolve:        resolve,
+		sigs:           sigs,
+		cfg:            cfg,
+		regSlot:        map[Reg]string{},
+		regType:        map[Reg]string{},
+		frameSize:      textFrameSize(fn),
+		fpResAllocaOff: map[int64]string{},
+		fpResAllocaIdx: map[int]string{},
+	}
+	c.abi, c.hasABI = wasmABIFuncFor(fn.Sym)
+	if !c.hasABI {
+		c.fpResults = append([]FrameSlot(nil), sig.Frame.Results...)
+	}
+	return c
+}
+
+func (c *wasmCtx) emitSourceComment(ins Instr) {
+	if !c.cfg.annotateSource {
+		return
+	}
+	emitIRSourceComment(c.b, ins.Raw)
+}
+
+func (c *wasmCtx) newTmp() string {
+	c.tmp++
+	return fmt.Sprintf("t%d", c.tmp)
+}
+
+func (c *wasmCtx) emit(format string, args ...any) string {
+	t := c.newTmp()
+	fmt.Fprintf(c.b, "  %%%s = "+format+"\n", append([]any{t}, args...)...)
+	return "%" + t
+}
+
+// newBlock returns a fresh LLVM block name.
+func (c *wasmCtx) newBlock(kind string) string {
+	c.blockN++
+	return fmt.Sprintf("wasm_%s%d", kind, c.blockN)
+}
+
+func (c *wasmCtx) br(target string) {
+	fmt.Fprintf(c.b, "  br label %%%s\n", target)
+}
+
+func (c *wasmCtx) startBlock(name string) {
+	fmt.Fprintf(c.b, "\n%s:\n", name)
+}
+
+// usedRegs returns the locals the body names, in order of appearance.
+func (c *wasmCtx) usedRegs() []Reg {
+	var regs []Reg
+	seen := map[Reg]bool{}
+	add := func(r Reg) {
+		if _, ok := wasmLocalType(r); ok && !seen[r] {
+			seen[r] = true
+			regs = append(regs, r)
+		}
+	}
+	for _, ins := range c.fn.Instrs {
+		for _, a := range ins.Args {
+			switch a.Kind {
+			case OpReg:
+				add(a.Reg)
+			case OpMem:
+				add(a.Mem.Base)
+			case OpSym:
+				if m, ok := wasmAddrConst(a.Sym); ok {
+					add(m.Base)
+				}
+			}
+		}
+	}
+	return regs
+}
+
+func (c *wasmCtx) emitEntryAllocasAndArgInit() error {
+	var locals []string
+	if c.hasABI {
+		locals = c.abi.wasmLocals()
+	}
+	for _, r := range c.usedRegs() {
+		ty, _ := wasmLocalType(r)
+		if c.hasABI {
+			n, err := wasmRegIndex(r)
+			if err != nil || n >= len(locals) {
+				return fmt.Errorf("%s: %s is not a local of %s", c.arch, r, c.fn.Sym)
+			}
+			ty = locals[n]
+		}
+		name := "%" + arm64LLVMBlockName("reg_"+string(r))
+		c.regSlot[r] = name
+		c.regType[r] = ty
+		fmt.Fprintf(c.b, "  %s = alloca %s\n", name, ty)
+		fmt.Fprintf(c.b, "  store %s %s, ptr %s\n", ty, wasmZero(ty), name)
+	}
+
+	// SP points at the bottom of the TEXT frame; the word above the frame
+	// holds the return address in Go's layout, and the arguments follow.
+	c.regSlot[SP] = "%reg_SP"
+	c.regType[SP] = "i32"
+	c.b.WriteString("  %reg_SP = alloca i32\n")
+	sp := "0"
+	if c.frameSize > 0 {
+		fmt.Fprintf(c.b, "  %%frame = alloca [%d x i8], align 8\n", c.frameSize+8)
+		sp = c.emit("ptrtoint ptr %%frame to i32")
+	}
+	fmt.Fprintf(c.b, "  store i32 %s, ptr %%reg_SP\n", sp)
+
+	for _, r := range c.fpResults {
+		name := fmt.Sprintf("%%fp_ret_%d", r.Index)
+		c.fpResAllocaIdx[r.Index] = name
+		c.fpResAllocaOff[r.Offset] = name
+		fmt.Fprintf(c.b, "  %s = alloca %s\n", name, r.Type)
+		fmt.Fprintf(c.b, "  store %s %s, ptr %s\n", r.Type, llvmZeroValue(r.Type), name)
+	}
+
+	if !c.hasABI {
+		return nil
+	}
+	for i, ty := range c.abi.params {
+		r := Reg(fmt.Sprintf("R%d", i))
+		if slot, ok := c.regSlot[r]; ok {
+			fmt.Fprintf(c.b, "  store %s %%arg%d, ptr %s\n", ty, i, slot)
+		}
+	}
+	return nil
+}
+
+func wasmZero(ty string) string {
+	switch ty {
+	case "float", "double":
+		return "0.0"
+	}
+	return "0"
+}
+
+func (c *wasmCtx) loadReg(r Reg) (wasmValue, error) {
+	slot, ok := c.regSlot[r]
+	if !ok {
+		if wasmGlobals[string(r)] {
+			return wasmValue{}, fmt.Errorf("%s: global %s is not lowered", c.arch, r)
+		}
+		return wasmValue{}, fmt.Errorf("%s: unknown reg %s", c.arch, r)
+	}
+	ty := c.regType[r]
+	return wasmValue{ty: ty, v: c.emit("load %s, ptr %s", ty, slot), sp: r == SP}, nil
+}
+
+func (c *wasmCtx) storeReg(r Reg, v wasmValue) error {
+	slot, ok := c.regSlot[r]
+	if !ok {
+		if wasmGlobals[string(r)] {
+			return fmt.Errorf("%s: global %s is not lowered", c.arch, r)
+		}
+		return fmt.Errorf("%s: unknown reg %s", c.arch, r)
+	}
+	if ty := c.regType[r]; v.ty != ty {
+		return fmt.Errorf("%s: %s value for %s register %s", c.arch, v.ty, ty, r)
+	}
+	fmt.Fprintf(c.b, "  store %s %s, ptr %s\n", v.ty, v.v, slot)
+	return nil
+}
+
+func (c *wasmCtx) ptrFromSB(sym string) (string, error) {
+	base, off, ok := parseSBRef(sym)
+	if !ok {
+		return "", fmt.Errorf("invalid (SB) sym ref: %q", sym)
+	}
+	base = strings.TrimPrefix(base, "$")
+	res := base
+	if strings.Contains(base, "·") || strings.Contains(base, "/") || strings.Contains(base, ".") {
+		res = c.resolve(base)
+	} else {
+		res = c.resolve("·" + base)
+	}
+	p := llvmGlobal(res)
+	if off == 0 {
+		return p, nil
+	}
+	return c.emit("getelementptr i8, ptr %s, i64 %d", p, off), nil
+}
+
+// bits64 returns the bits of v, zero-extended to i64.
+func (c *wasmCtx) bits64(v wasmValue) string {
+	switch v.ty {
+	case "i32":
+		return c.emit("zext i32 %s to i64", v.v)
+	case "float":
+		return c.emit("zext i32 %s to i64", c.emit("bitcast float %s to i32", v.v))
+	case "double":
+		return c.emit("bitcast double %s to i64", v.v)
+	}
+	return v.v
+}
+
+// fromBits reinterprets the low bits of v64 as a value of type ty.
+func (c *wasmCtx) fromBits(v64, ty string) wasmValue {
+	switch ty {
+	case "i32":
+		return wasmValue{ty: ty, v: c.emit("trunc i64 %s to i32", v64)}
+	case "float":
+		return wasmValue{ty: ty, v: c.emit("bitcast i32 %s to float", c.emit("trunc i64 %s to i32", v64))}
+	case "double":
+		return wasmValue{ty: ty, v: c.emit("bitcast i64 %s to double", v64)}
+	}
+	return wasmValue{ty: "i64", v: v64}
+}
+
+// valueAsI64 converts an LLVM argument of type ty to register bits.
+func (c *wasmCtx) valueAsI64(ty LLVMType, v string) (string, bool) {
+	switch ty {
+	case I64:
+		return v, true
+	case Ptr:
+		return c.emit("ptrtoint ptr %s to i64", v), true
+	case I1, I8, I16, I32:
+		return c.emit("zext %s %s to i64", ty, v), true
+	case LLVMType("double"):
+		return c.emit("bitcast double %s to i64", v), true
+	case LLVMType("float"):
+		return c.emit("zext i32 %s to i64", c.emit("bitcast float %s to i32", v)), true
+	}
+	return "", false
+}
+
+// i64ToValue converts register bits back to ty.
+func (c *wasmCtx) i64ToValue(v string, ty LLVMType) (string, error) {
+	switch ty {
+	case I64:
+		return v, nil
+	case I32, I16, I8, I1:
+		return c.emit("trunc i64 %s to %s", v, ty), nil
+	case Ptr:
+		return c.emit("inttoptr i64 %s to ptr", v), nil
+	case LLVMType("double"):
+		return c.emit("bitcast i64 %s to double", v), nil
+	case LLVMType("float"):
+		return c.emit("bitcast i32 %s to float", c.emit("trunc i64 %s to i32", v)), nil
+	}
+	return "", fmt.Errorf("%s: unsupported value type %s", c.arch, ty)
+}
@@ -0,0 +1,94 @@
+package plan9asm
+
+import "fmt"
+
+// slotAt returns the slot among slots that holds the bytes+off(FP) being
+// accessed, and the shift of those bytes within the slot's bits. Go's
+// wasm frame has 8-byte pointers and stores values little-endian.
+func (c *wasmCtx) slotAt(slots []FrameSlot, off int64, bytes int64) (FrameSlot, int64, bool) {
+	for _, s := range slots {
+		n := riscv64SlotSize(s.Type)
+		if off == s.Offset && (bytes >= n || !mipsSplittable(s.Type)) {
+			return s, 0, true
+		}
+		if off < s.Offset || off+bytes > s.Offset+n || !mipsSplittable(s.Type) {
+			continue
+		}
+		return s, 8 * (off - s.Offset), true
+	}
+	return FrameSlot{}, 0, false
+}
+
+// evalFPLoad reads bits from the argument at off(FP), extended to i64.
+func (c *wasmCtx) evalFPLoad(op Operand, bits int, signed bool) (string, error) {
+	if c.hasABI {
+		return "", fmt.Errorf("%s: %s has no Go argument frame: %s", c.arch, c.fn.Sym, op.String())
+	}
+	slot, shift, ok := c.slotAt(c.sig.Frame.Params, op.FPOffset, int64(bits/8))
+	if !ok {
+		return "", fmt.Errorf("%s: unsupported FP param slot: %s", c.arch, op.String())
+	}
+	idx := slot.Index
+	if idx < 0 || idx >= len(c.sig.Args) {
+		return "", fmt.Errorf("%s: FP slot %s invalid arg index %d", c.arch, op.String(), idx)
+	}
+	arg := fmt.Sprintf("%%arg%d", idx)
+	if slot.Field >= 0 {
+		arg = c.emit("extractvalue %s %s, %d", c.sig.Args[idx], arg, slot.Field)
+	}
+	v, ok := c.valueAsI64(slot.Type, arg)
+	if !ok {
+		return "", fmt.Errorf("%s: FP slot %s unsupported arg type %q", c.arch, op.String(), slot.Type)
+	}
+	if shift != 0 {
+		v = c.emit("lshr i64 %s, %d", v, shift)
+	}
+	if bits == 64 {
+		return v, nil
+	}
+	ext := "zext"
+	if signed {
+		ext = "sext"
+	}
+	return c.emit("%s i%d %s to i64", ext, bits, c.emit("trunc i64 %s to i%d", v, bits)), nil
+}
+
+// storeFPResult stores the low bits of v64 to the result slot holding
+// off(FP). A narrower store replaces part of the slot.
+func (c *wasmCtx) storeFPResult(off int64, bits int, v64 string) error {
+	slot, shift, ok := c.slotAt(c.fpResults, off, int64(bits/8))
+	if !ok {
+		return fmt.Errorf("%s: unsupported FP result slot +%d(FP)", c.arch, off)
+	}
+	p := c.fpResAllocaIdx[slot.Index]
+	n := riscv64SlotSize(slot.Type)
+	if off == slot.Offset && int64(bits/8) >= n || !mipsSplittable(slot.Type) {
+		v, err := c.i64ToValue(v64, slot.Type)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(c.b, "  store %s %s, ptr %s\n", slot.Type, v, p)
+		return nil
+	}
+	ity := fmt.Sprintf("i%d", 8*n)
+	mask := (uint64(1)<<bits - 1) << shift
+	old := c.emit("load %s, ptr %s", ity, p)
+	part := c.emit("and i64 %s, %d", v64, int64(uint64(1)<<bits-1))
+	if shift != 0 {
+		part = c.emit("shl i64 %s, %d", part, shift)
+	}
+	if n < 8 {
+		part = c.emit("trunc i64 %s to %s", part, ity)
+	}
+	kept := c.emit("and %s %s, %d", ity, old, int64(^mask)<<(64-8*n)>>(64-8*n))
+	fmt.Fprintf(c.b, "  store %s %s, ptr %s\n", ity, c.emit("or %s %s, %s", ity, kept, part), p)
+	return nil
+}
+
+func (c *wasmCtx) loadFPResult(slot FrameSlot) (string, error) {
+	p, ok := c.fpResAllocaIdx[slot.Index]
+	if !ok {
+		return "", fmt.Errorf("%s: missing FP result alloca for index %d", c.arch, slot.Index)
+	}
+	return c.emit("load %s, ptr %s", slot.Type, p), nil
+}
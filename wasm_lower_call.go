@@ -0,0 +1,201 @@
+package plan9asm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// calleeSym returns the symbol of a call or jump target sym(SB).
+func (c *wasmCtx) calleeSym(ins Instr) (string, error) {
+	if len(ins.Args) != 1 || !riscv64IsSBSym(ins.Args[0]) {
+		return "", fmt.Errorf("%s %s expects symbol(SB): %q", c.arch, ins.Op, ins.Raw)
+	}
+	return strings.TrimSuffix(strings.TrimSpace(ins.Args[0].Sym), "(SB)"), nil
+}
+
+// lowerCall lowers Call and CALL, which the parser does not tell apart: a
+// call to a function in wasmABIFuncs passes its parameters and result on
+// the value stack, and any other callee follows Go's convention, taking
+// its arguments from the outgoing area at 0(SP) and leaving its results
+// after them.
+func (c *wasmCtx) lowerCall(ins Instr) error {
+	sym, err := c.calleeSym(ins)
+	if err != nil {
+		return err
+	}
+	callee := c.resolve(sym)
+	if abi, ok := wasmABIFuncFor(sym); ok {
+		args, err := c.popN(abi.params...)
+		if err != nil {
+			return err
+		}
+		list := make([]string, len(args))
+		for i, a := range args {
+			list[i] = abi.params[i] + " " + a
+		}
+		if abi.result == "" {
+			fmt.Fprintf(c.b, "  call void %s(%s)\n", llvmGlobal(callee), strings.Join(list, ", "))
+			return nil
+		}
+		c.push(abi.result, c.emit("call %s %s(%s)", abi.result, llvmGlobal(callee), strings.Join(list, ", ")))
+		return nil
+	}
+
+	csig, ok := c.sigs[callee]
+	if !ok {
+		csig = FuncSig{Name: callee, Ret: Void}
+	}
+	args := make([]string, 0, len(csig.Args))
+	for i, ty := range csig.Args {
+		slots := i386ArgSlots(csig, i)
+		if len(slots) == 0 || c.frameSize == 0 {
+			return fmt.Errorf("%s CALL %q: arg %d needs a frame slot", c.arch, callee, i)
+		}
+		agg := "undef"
+		for _, s := range slots {
+			v, err := c.loadOutSlot(s)
+			if err != nil {
+				return err
+			}
+			if s.Field < 0 {
+				agg = v
+				break
+			}
+			agg = c.emit("insertvalue %s %s, %s %s, %d", ty, agg, s.Type, v, s.Field)
+		}
+		args = append(args, fmt.Sprintf("%s %s", ty, agg))
+	}
+	if csig.Ret == Void {
+		fmt.Fprintf(c.b, "  call void %s(%s)\n", llvmGlobal(callee), strings.Join(args, ", "))
+		return nil
+	}
+	if c.frameSize == 0 || len(csig.Frame.Results) == 0 {
+		return fmt.Errorf("%s CALL %q: results need frame slots", c.arch, callee)
+	}
+	r := c.emit("call %s %s(%s)", csig.Ret, llvmGlobal(callee), strings.Join(args, ", "))
+	multi := len(csig.Frame.Results) > 1 && csig.Frame.Results[len(csig.Frame.Results)-1].Index > 0
+	for _, s := range csig.Frame.Results {
+		v, ty := r, csig.Ret
+		if multi {
+			fields, ok := parseLiteralStructFields(ty)
+			if !ok || s.Index >= len(fields) {
+				return fmt.Errorf("%s CALL %q: result %d of %s", c.arch, callee, s.Index, ty)
+			}
+			v, ty = c.emit("extractvalue %s %s, %d", ty, v, s.Index), fields[s.Index]
+		}
+		if s.Field >= 0 {
+			v = c.emit("extractvalue %s %s, %d", ty, v, s.Field)
+		}
+		if err := c.storeOutSlot(s, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// outSlotPtr returns a pointer to the outgoing slot s. The callee's
+// arguments start at the caller's 0(SP), as CALL pushes the return
+// address below it.
+func (c *wasmCtx) outSlotPtr(s FrameSlot) (string, error) {
+	sp, err := c.loadReg(SP)
+	if err != nil {
+		return "", err
+	}
+	return c.emit("inttoptr i32 %s to ptr", c.emit("add i32 %s, %d", sp.v, s.Offset)), nil
+}
+
+// loadOutSlot reads slot s of the outgoing area. Pointers take 8 bytes in
+// Go's frame.
+func (c *wasmCtx) loadOutSlot(s FrameSlot) (string, error) {
+	p, err := c.outSlotPtr(s)
+	if err != nil {
+		return "", err
+	}
+	if s.Type == Ptr {
+		return c.emit("inttoptr i64 %s to ptr", c.emit("load i64, ptr %s, align 1", p)), nil
+	}
+	return c.emit("load %s, ptr %s, align 1", s.Type, p), nil
+}
+
+func (c *wasmCtx) storeOutSlot(s FrameSlot, v string) error {
+	p, err := c.outSlotPtr(s)
+	if err != nil {
+		return err
+	}
+	if s.Type == Ptr {
+		fmt.Fprintf(c.b, "  store i64 %s, ptr %s, align 1\n", c.emit("ptrtoint ptr %s to i64", v), p)
+		return nil
+	}
+	fmt.Fprintf(c.b, "  store %s %s, ptr %s, align 1\n", s.Type, v, p)
+	return nil
+}
+
+// lowerJMP lowers JMP sym(SB), a tail call that hands the callee the
+// caller's argument frame. Jumps between basic blocks go through Go's
+// resume loop on PC_B, which is not lowered.
+func (c *wasmCtx) lowerJMP(ins Instr) error {
+	if len(ins.Args) == 1 && !riscv64IsSBSym(ins.Args[0]) {
+		return fmt.Errorf("%s: JMP within a function is not lowered: %q", c.arch, ins.Raw)
+	}
+	sym, err := c.calleeSym(ins)
+	if err != nil {
+		return err
+	}
+	if c.hasABI {
+		return fmt.Errorf("%s: JMP in %s, which has no Go argument frame: %q", c.arch, c.fn.Sym, ins.Raw)
+	}
+	callee := c.resolve(sym)
+	csig, ok := c.sigs[callee]
+	if !ok {
+		// Without an explicit signature, fall back to the caller's.
+		csig = c.sig
+		csig.Name = callee
+	}
+
+	var args []string
+	switch {
+	case sameLLVMTypes(csig.Args, c.sig.Args):
+		for i, ty := range csig.Args {
+			args = append(args, fmt.Sprintf("%s %%arg%d", ty, i))
+		}
+	default:
+		for i, ty := range csig.Args {
+			slots := i386ArgSlots(csig, i)
+			if len(slots) == 0 {
+				return fmt.Errorf("%s tailcall %q: no frame slot for arg %d", c.arch, callee, i)
+			}
+			agg := "undef"
+			for _, s := range slots {
+				v64, err := c.evalFPLoad(Operand{Kind: OpFP, FPOffset: s.Offset}, int(8*riscv64SlotSize(s.Type)), false)
+				if err != nil {
+					return fmt.Errorf("%s tailcall %q: %v", c.arch, callee, err)
+				}
+				v, err := c.i64ToValue(v64, s.Type)
+				if err != nil {
+					return err
+				}
+				if s.Field < 0 {
+					agg = v
+					break
+				}
+				agg = c.emit("insertvalue %s %s, %s %s, %d", ty, agg, s.Type, v, s.Field)
+			}
+			args = append(args, fmt.Sprintf("%s %s", ty, agg))
+		}
+	}
+
+	switch {
+	case csig.Ret == Void:
+		fmt.Fprintf(c.b, "  call void %s(%s)\n", llvmGlobal(callee), strings.Join(args, ", "))
+		if err := c.lowerRET(); err != nil {
+			return err
+		}
+	case csig.Ret == c.sig.Ret:
+		r := c.emit("call %s %s(%s)", csig.Ret, llvmGlobal(callee), strings.Join(args, ", "))
+		fmt.Fprintf(c.b, "  ret %s %s\n", c.sig.Ret, r)
+	default:
+		return fmt.Errorf("%s tailcall return mismatch: caller %s callee %s", c.arch, c.sig.Ret, csig.Ret)
+	}
+	c.terminate()
+	return nil
+}
@@ -0,0 +1,312 @@
+package plan9asm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// wasmMemOps gives the value type, access width and signedness of the
+// loads and stores.
+var wasmMemOps = map[string]struct {
+	ty     string
+	bits   int
+	signed bool
+}{
+	"I32LOAD":    {"i32", 32, false},
+	"I64LOAD":    {"i64", 64, false},
+	"F32LOAD":    {"float", 32, false},
+	"F64LOAD":    {"double", 64, false},
+	"I32LOAD8S":  {"i32", 8, true},
+	"I32LOAD8U":  {"i32", 8, false},
+	"I32LOAD16S": {"i32", 16, true},
+	"I32LOAD16U": {"i32", 16, false},
+	"I64LOAD8S":  {"i64", 8, true},
+	"I64LOAD8U":  {"i64", 8, false},
+	"I64LOAD16S": {"i64", 16, true},
+	"I64LOAD16U": {"i64", 16, false},
+	"I64LOAD32S": {"i64", 32, true},
+	"I64LOAD32U": {"i64", 32, false},
+	"I32STORE":   {"i32", 32, false},
+	"I64STORE":   {"i64", 64, false},
+	"F32STORE":   {"float", 32, false},
+	"F64STORE":   {"double", 64, false},
+	"I32STORE8":  {"i32", 8, false},
+	"I32STORE16": {"i32", 16, false},
+	"I64STORE8":  {"i64", 8, false},
+	"I64STORE16": {"i64", 16, false},
+	"I64STORE32": {"i64", 32, false},
+}
+
+// wasmMovBits gives the width of the MOV pseudo-instructions, which load
+// zero-extended.
+var wasmMovBits = map[string]int{
+	"MOVB": 8,
+	"MOVH": 16,
+	"MOVW": 32,
+	"MOVD": 64,
+}
+
+func (c *wasmCtx) lowerMemory(op string, ins Instr) (ok bool, err error) {
+	if m, found := wasmMemOps[op]; found {
+		if strings.Contains(op, "STORE") {
+			return true, c.lowerStore(m.ty, m.bits, ins)
+		}
+		return true, c.lowerLoad(m.ty, m.bits, m.signed, ins)
+	}
+	if bits, found := wasmMovBits[op]; found {
+		return true, c.lowerMOV(bits, ins)
+	}
+	switch op {
+	case "MEMORYFILL":
+		v, err := c.popN("i32", "i32", "i32")
+		if err != nil {
+			return true, err
+		}
+		p := c.emit("inttoptr i32 %s to ptr", v[0])
+		b := c.emit("trunc i32 %s to i8", v[1])
+		fmt.Fprintf(c.b, "  call void @llvm.memset.p0.i32(ptr %s, i8 %s, i32 %s, i1 false)\n", p, b, v[2])
+		return true, nil
+	case "MEMORYCOPY":
+		v, err := c.popN("i32", "i32", "i32")
+		if err != nil {
+			return true, err
+		}
+		dst := c.emit("inttoptr i32 %s to ptr", v[0])
+		src := c.emit("inttoptr i32 %s to ptr", v[1])
+		fmt.Fprintf(c.b, "  call void @llvm.memmove.p0.p0.i32(ptr %s, ptr %s, i32 %s, i1 false)\n", dst, src, v[2])
+		return true, nil
+	case "CURRENTMEMORY":
+		c.push("i32", c.emit("call i32 @llvm.wasm.memory.size.i32(i32 0)"))
+		return true, nil
+	case "GROWMEMORY":
+		n, err := c.pop("i32")
+		if err != nil {
+			return true, err
+		}
+		c.push("i32", c.emit("call i32 @llvm.wasm.memory.grow.i32(i32 0, i32 %s)", n))
+		return true, nil
+	}
+	return false, nil
+}
+
+// memAddr returns a pointer to the memory operand of a load: $off adds to
+// the address on the stack, off(Rn) to the register.
+func (c *wasmCtx) memAddr(op Operand, ins Instr) (string, error) {
+	var addr string
+	var off int64
+	switch op.Kind {
+	case OpImm:
+		v, err := c.pop("i32")
+		if err != nil {
+			return "", err
+		}
+		addr, off = v, op.Imm
+	case OpMem:
+		v, err := c.regAddr(op.Mem.Base)
+		if err != nil {
+			return "", err
+		}
+		addr, off = v, op.Mem.Off
+	default:
+		return "", fmt.Errorf("%s %s: unsupported memory operand: %q", c.arch, ins.Op, ins.Raw)
+	}
+	if off != 0 {
+		addr = c.emit("add i32 %s, %d", addr, off)
+	}
+	return c.emit("inttoptr i32 %s to ptr", addr), nil
+}
+
+// regAddr reads a register as an i32 address.
+func (c *wasmCtx) regAddr(r Reg) (string, error) {
+	v, err := c.loadReg(r)
+	if err != nil {
+		return "", err
+	}
+	switch v.ty {
+	case "i32":
+		return v.v, nil
+	case "i64":
+		return c.emit("trunc i64 %s to i32", v.v), nil
+	}
+	return "", fmt.Errorf("%s: %s register %s as an address", c.arch, v.ty, r)
+}
+
+// loadValue loads bits from p and extends them to a value of type ty.
+func (c *wasmCtx) loadValue(p, ty string, bits int, signed bool) string {
+	switch {
+	case ty == "float" || ty == "double":
+		return c.emit("load %s, ptr %s, align 1", ty, p)
+	case fmt.Sprintf("i%d", bits) == ty:
+		return c.emit("load %s, ptr %s, align 1", ty, p)
+	}
+	ext := "zext"
+	if signed {
+		ext = "sext"
+	}
+	return c.emit("%s i%d %s to %s", ext, bits, c.emit("load i%d, ptr %s, align 1", bits, p), ty)
+}
+
+func (c *wasmCtx) lowerLoad(ty string, bits int, signed bool, ins Instr) error {
+	if len(ins.Args) != 1 {
+		return fmt.Errorf("%s %s expects 1 operand: %q", c.arch, ins.Op, ins.Raw)
+	}
+	if ins.Args[0].Kind == OpFP {
+		v64, err := c.evalFPLoad(ins.Args[0], bits, signed)
+		if err != nil {
+			return err
+		}
+		v := c.fromBits(v64, ty)
+		c.push(v.ty, v.v)
+		return nil
+	}
+	p, err := c.memAddr(ins.Args[0], ins)
+	if err != nil {
+		return err
+	}
+	c.push(ty, c.loadValue(p, ty, bits, signed))
+	return nil
+}
+
+// lowerStore pops the value and then the address. A store to an FP slot
+// takes its address from Get SP and writes the result.
+func (c *wasmCtx) lowerStore(ty string, bits int, ins Instr) error {
+	if len(ins.Args) != 1 {
+		return fmt.Errorf("%s %s expects 1 operand: %q", c.arch, ins.Op, ins.Raw)
+	}
+	v, err := c.pop(ty)
+	if err != nil {
+		return err
+	}
+	a := ins.Args[0]
+	switch a.Kind {
+	case OpFP:
+		addr, err := c.popAny("i32")
+		if err != nil {
+			return err
+		}
+		if !addr.sp && !c.dead {
+			return fmt.Errorf("%s %s: an FP store needs the address of Get SP: %q", c.arch, ins.Op, ins.Raw)
+		}
+		return c.storeFPResult(a.FPOffset, bits, c.bits64(wasmValue{ty: ty, v: v}))
+	case OpImm:
+	default:
+		return fmt.Errorf("%s %s: unsupported memory operand: %q", c.arch, ins.Op, ins.Raw)
+	}
+	p, err := c.memAddr(a, ins)
+	if err != nil {
+		return err
+	}
+	c.storeValue(p, ty, bits, v)
+	return nil
+}
+
+func (c *wasmCtx) storeValue(p, ty string, bits int, v string) {
+	if ty != "float" && ty != "double" && fmt.Sprintf("i%d", bits) != ty {
+		v = c.emit("trunc %s %s to i%d", ty, v, bits)
+		ty = fmt.Sprintf("i%d", bits)
+	}
+	fmt.Fprintf(c.b, "  store %s %s, ptr %s, align 1\n", ty, v, p)
+}
+
+// lowerMOV lowers the MOVB, MOVH, MOVW and MOVD pseudo-instructions,
+// which move zero-extended bits between registers, memory and FP slots
+// without touching the value stack.
+func (c *wasmCtx) lowerMOV(bits int, ins Instr) error {
+	if len(ins.Args) != 2 {
+		return fmt.Errorf("%s %s expects 2 operands: %q", c.arch, ins.Op, ins.Raw)
+	}
+	src, dst := ins.Args[0], ins.Args[1]
+	var v string
+	switch src.Kind {
+	case OpImm:
+		v = fmt.Sprintf("%d", src.Imm)
+	case OpReg:
+		r, err := c.loadReg(src.Reg)
+		if err != nil {
+			return err
+		}
+		switch r.ty {
+		case "i32":
+			v = c.emit("zext i32 %s to i64", r.v)
+		case "i64":
+			v = r.v
+		default:
+			return fmt.Errorf("%s %s: %s register %s: %q", c.arch, ins.Op, r.ty, src.Reg, ins.Raw)
+		}
+	case OpMem:
+		p, err := c.memAddr(src, ins)
+		if err != nil {
+			return err
+		}
+		v = c.loadValue(p, "i64", bits, false)
+	case OpFP:
+		var err error
+		if v, err = c.evalFPLoad(src, bits, false); err != nil {
+			return err
+		}
+	case OpSym:
+		var err error
+		if v, err = c.addrConst(src.Sym); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("%s %s: unsupported source: %q", c.arch, ins.Op, ins.Raw)
+	}
+	if bits < 64 && src.Kind != OpMem && src.Kind != OpFP {
+		v = c.emit("and i64 %s, %d", v, int64(uint64(1)<<bits-1))
+	}
+
+	switch dst.Kind {
+	case OpReg:
+		r := wasmValue{ty: "i64", v: v}
+		if c.regType[dst.Reg] == "i32" {
+			r = wasmValue{ty: "i32", v: c.emit("trunc i64 %s to i32", v)}
+		}
+		return c.storeReg(dst.Reg, r)
+	case OpMem:
+		p, err := c.memAddr(dst, ins)
+		if err != nil {
+			return err
+		}
+		c.storeValue(p, "i64", bits, v)
+		return nil
+	case OpFP:
+		return c.storeFPResult(dst.FPOffset, bits, v)
+	}
+	return fmt.Errorf("%s %s: unsupported destination: %q", c.arch, ins.Op, ins.Raw)
+}
+
+// addrConst evaluates a $sym(SB) or $off(Rn) address constant as an i64.
+func (c *wasmCtx) addrConst(sym string) (string, error) {
+	s := strings.TrimSpace(sym)
+	if m, ok := wasmAddrConst(s); ok {
+		r, err := c.loadReg(m.Base)
+		if err != nil {
+			return "", err
+		}
+		v := r.v
+		if r.ty == "i32" {
+			v = c.emit("zext i32 %s to i64", v)
+		}
+		if m.Off == 0 {
+			return v, nil
+		}
+		return c.emit("add i64 %s, %d", v, m.Off), nil
+	}
+	if strings.HasPrefix(s, "$") && strings.HasSuffix(s, "(SB)") {
+		p, err := c.ptrFromSB(s)
+		if err != nil {
+			return "", err
+		}
+		return c.emit("ptrtoint ptr %s to i64", p), nil
+	}
+	return "", fmt.Errorf("%s: unsupported address %s", c.arch, sym)
+}
+
+// emitWasmMemoryDecls declares the intrinsics lowerMemory calls.
+func emitWasmMemoryDecls(b *strings.Builder) {
+	b.WriteString("declare void @llvm.memset.p0.i32(ptr, i8, i32, i1)\n")
+	b.WriteString("declare void @llvm.memmove.p0.p0.i32(ptr, ptr, i32, i1)\n")
+	b.WriteString("declare i32 @llvm.wasm.memory.size.i32(i32)\n")
+	b.WriteString("declare i32 @llvm.wasm.memory.grow.i32(i32, i32)\n")
+}
@@ -0,0 +1,424 @@
+package plan9asm
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// wasmValueTypes maps the type prefix of an opcode to its value type.
+var wasmValueTypes = map[string]string{
+	"I32": "i32",
+	"I64": "i64",
+	"F32": "float",
+	"F64": "double",
+}
+
+// wasmIntrinsicSuffix returns the overload suffix of an LLVM intrinsic on
+// value type ty.
+func wasmIntrinsicSuffix(ty string) string {
+	switch ty {
+	case "float":
+		return "f32"
+	case "double":
+		return "f64"
+	}
+	return ty
+}
+
+var wasmIntBinOps = map[string]string{
+	"ADD": "add",
+	"SUB": "sub",
+	"MUL": "mul",
+	"AND": "and",
+	"OR":  "or",
+	"XOR": "xor",
+}
+
+var wasmIntShifts = map[string]string{
+	"SHL":  "shl",
+	"SHRS": "ashr",
+	"SHRU": "lshr",
+	"ROTL": "fshl",
+	"ROTR": "fshr",
+}
+
+var wasmIntCompares = map[string]string{
+	"EQ":  "eq",
+	"NE":  "ne",
+	"LTS": "slt",
+	"LTU": "ult",
+	"GTS": "sgt",
+	"GTU": "ugt",
+	"LES": "sle",
+	"LEU": "ule",
+	"GES": "sge",
+	"GEU": "uge",
+}
+
+var wasmFloatBinOps = map[string]string{
+	"ADD":      "fadd",
+	"SUB":      "fsub",
+	"MUL":      "fmul",
+	"DIV":      "fdiv",
+	"MIN":      "minimum",
+	"MAX":      "maximum",
+	"COPYSIGN": "copysign",
+}
+
+var wasmFloatCompares = map[string]string{
+	"EQ": "oeq",
+	"NE": "une",
+	"LT": "olt",
+	"GT": "ogt",
+	"LE": "ole",
+	"GE": "oge",
+}
+
+var wasmFloatUnary = map[string]string{
+	"ABS":     "fabs",
+	"CEIL":    "ceil",
+	"FLOOR":   "floor",
+	"TRUNC":   "trunc",
+	"NEAREST": "roundeven",
+	"SQRT":    "sqrt",
+}
+
+// lowerNumeric lowers the constants, arithmetic, comparisons and
+// conversions, whose opcodes start with the value type they produce.
+func (c *wasmCtx) lowerNumeric(op string, ins Instr) (ok bool, err error) {
+	if len(op) < 4 {
+		return false, nil
+	}
+	ty, found := wasmValueTypes[op[:3]]
+	if !found {
+		return false, nil
+	}
+	name := op[3:]
+	if name == "CONST" {
+		return true, c.lowerConst(ty, ins)
+	}
+	if ty == "i32" || ty == "i64" {
+		return c.lowerIntOp(ty, name, ins)
+	}
+	return c.lowerFloatOp(ty, name, ins)
+}
+
+func (c *wasmCtx) lowerConst(ty string, ins Instr) error {
+	if len(ins.Args) != 1 {
+		return fmt.Errorf("%s %s expects 1 operand: %q", c.arch, ins.Op, ins.Raw)
+	}
+	a := ins.Args[0]
+	switch ty {
+	case "float", "double":
+		f, ok := wasmFloatLiteral(ins)
+		if !ok {
+			return fmt.Errorf("%s %s expects a $float constant: %q", c.arch, ins.Op, ins.Raw)
+		}
+		if ty == "float" {
+			f = float64(float32(f))
+		}
+		c.push(ty, formatLLVMFloat64Literal(f))
+		return nil
+	}
+	switch {
+	case a.Kind == OpImm && a.ImmRaw == "":
+		v := a.Imm
+		if ty == "i32" {
+			v = int64(int32(v))
+		}
+		c.push(ty, strconv.FormatInt(v, 10))
+		return nil
+	case a.Kind == OpSym && strings.HasPrefix(a.Sym, "$") && strings.HasSuffix(strings.TrimSpace(a.Sym), "(SB)"):
+		p, err := c.ptrFromSB(strings.TrimSpace(a.Sym))
+		if err != nil {
+			return err
+		}
+		c.push(ty, c.emit("ptrtoint ptr %s to %s", p, ty))
+		return nil
+	}
+	return fmt.Errorf("%s %s: unsupported constant: %q", c.arch, ins.Op, ins.Raw)
+}
+
+// wasmFloatLiteral reads the float operand of F32Const and F64Const from
+// the source text, as the parser keeps $1 an integer and $1.0 float bits.
+func wasmFloatLiteral(ins Instr) (float64, bool) {
+	fields := strings.Fields(ins.Raw)
+	if len(fields) != 2 || !strings.HasPrefix(fields[1], "$") {
+		return 0, false
+	}
+	s := strings.TrimPrefix(fields[1], "$")
+	if strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")") {
+		s = s[1 : len(s)-1]
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		if n, ierr := strconv.ParseInt(s, 0, 64); ierr == nil {
+			return float64(n), true
+		}
+		return 0, false
+	}
+	return f, true
+}
+
+func (c *wasmCtx) lowerIntOp(ty, name string, ins Instr) (bool, error) {
+	bits := 32
+	if ty == "i64" {
+		bits = 64
+	}
+	binary := func(f func(a, b string) string) (bool, error) {
+		v, err := c.popN(ty, ty)
+		if err != nil {
+			return true, err
+		}
+		c.push(ty, f(v[0], v[1]))
+		return true, nil
+	}
+	if llop, ok := wasmIntBinOps[name]; ok {
+		return binary(func(a, b string) string { return c.emit("%s %s %s, %s", llop, ty, a, b) })
+	}
+	if llop, ok := wasmIntShifts[name]; ok {
+		// The shift count is taken modulo the width.
+		return binary(func(a, b string) string {
+			n := c.emit("and %s %s, %d", ty, b, bits-1)
+			if llop == "fshl" || llop == "fshr" {
+				return c.emit("call %s @llvm.%s.%s(%s %s, %s %s, %s %s)", ty, llop, ty, ty, a, ty, a, ty, n)
+			}
+			return c.emit("%s %s %s, %s", llop, ty, a, n)
+		})
+	}
+	if pred, ok := wasmIntCompares[name]; ok {
+		v, err := c.popN(ty, ty)
+		if err != nil {
+			return true, err
+		}
+		c.push("i32", c.emit("zext i1 %s to i32", c.emit("icmp %s %s %s, %s", pred, ty, v[0], v[1])))
+		return true, nil
+	}
+	switch name {
+	case "DIVS", "DIVU", "REMS", "REMU":
+		return binary(func(a, b string) string { return c.divValue(name, ty, bits, a, b) })
+	case "EQZ":
+		// I64Eqz $0 carries an operand the assembler ignores.
+		v, err := c.pop(ty)
+		if err != nil {
+			return true, err
+		}
+		c.push("i32", c.emit("zext i1 %s to i32", c.emit("icmp eq %s %s, 0", ty, v)))
+		return true, nil
+	case "CLZ", "CTZ", "POPCNT":
+		v, err := c.pop(ty)
+		if err != nil {
+			return true, err
+		}
+		fn := map[string]string{"CLZ": "ctlz", "CTZ": "cttz", "POPCNT": "ctpop"}[name]
+		if fn == "ctpop" {
+			c.push(ty, c.emit("call %s @llvm.ctpop.%s(%s %s)", ty, ty, ty, v))
+		} else {
+			c.push(ty, c.emit("call %s @llvm.%s.%s(%s %s, i1 false)", ty, fn, ty, ty, v))
+		}
+		return true, nil
+	case "EXTEND8S", "EXTEND16S", "EXTEND32S":
+		n, _ := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(name, "EXTEND"), "S"))
+		if n >= bits {
+			return true, fmt.Errorf("%s: %s on %s: %q", c.arch, ins.Op, ty, ins.Raw)
+		}
+		v, err := c.pop(ty)
+		if err != nil {
+			return true, err
+		}
+		c.push(ty, c.emit("sext i%d %s to %s", n, c.emit("trunc %s %s to i%d", ty, v, n), ty))
+		return true, nil
+	case "WRAPI64":
+		if ty != "i32" {
+			break
+		}
+		v, err := c.pop("i64")
+		if err != nil {
+			return true, err
+		}
+		c.push("i32", c.emit("trunc i64 %s to i32", v))
+		return true, nil
+	case "EXTENDI32S", "EXTENDI32U":
+		if ty != "i64" {
+			break
+		}
+		v, err := c.pop("i32")
+		if err != nil {
+			return true, err
+		}
+		ext := "sext"
+		if name == "EXTENDI32U" {
+			ext = "zext"
+		}
+		c.push("i64", c.emit("%s i32 %s to i64", ext, v))
+		return true, nil
+	case "REINTERPRETF32", "REINTERPRETF64":
+		from := wasmValueTypes[name[len(name)-3:]]
+		if (from == "float") != (ty == "i32") {
+			break
+		}
+		v, err := c.pop(from)
+		if err != nil {
+			return true, err
+		}
+		c.push(ty, c.emit("bitcast %s %s to %s", from, v, ty))
+		return true, nil
+	}
+	// I32TruncF64S, I64TruncSatF32U and the like. WebAssembly traps where
+	// the value does not fit in the non-saturating forms; both lower to
+	// the saturating conversions.
+	if rest, ok := strings.CutPrefix(name, "TRUNC"); ok {
+		rest = strings.TrimPrefix(rest, "SAT")
+		if len(rest) == 4 && (rest[3] == 'S' || rest[3] == 'U') {
+			if from, ok := wasmValueTypes[rest[:3]]; ok && (from == "float" || from == "double") {
+				v, err := c.pop(from)
+				if err != nil {
+					return true, err
+				}
+				fn := "fptosi"
+				if rest[3] == 'U' {
+					fn = "fptoui"
+				}
+				c.push(ty, c.emit("call %s @llvm.%s.sat.%s.%s(%s %s)", ty, fn, ty, wasmIntrinsicSuffix(from), from, v))
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// divValue returns a / b or a % b, trapping as WebAssembly does on a zero
+// divisor and on the signed division of the minimum by -1. The signed
+// remainder of those operands is 0, which a divisor of 1 gives as well.
+func (c *wasmCtx) divValue(name, ty string, bits int, a, b string) string {
+	c.trapIf(c.emit("icmp eq %s %s, 0", ty, b))
+	minInt := strconv.FormatInt(-1<<(bits-1), 10)
+	switch name {
+	case "DIVS":
+		isMin := c.emit("icmp eq %s %s, %s", ty, a, minInt)
+		isNeg1 := c.emit("icmp eq %s %s, -1", ty, b)
+		c.trapIf(c.emit("and i1 %s, %s", isMin, isNeg1))
+		return c.emit("sdiv %s %s, %s", ty, a, b)
+	case "DIVU":
+		return c.emit("udiv %s %s, %s", ty, a, b)
+	case "REMS":
+		isNeg1 := c.emit("icmp eq %s %s, -1", ty, b)
+		return c.emit("srem %s %s, %s", ty, a, c.emit("select i1 %s, %s 1, %s %s", isNeg1, ty, ty, b))
+	}
+	return c.emit("urem %s %s, %s", ty, a, b)
+}
+
+// trapIf traps when cond holds.
+func (c *wasmCtx) trapIf(cond string) {
+	trap, next := c.newBlock("trap"), c.newBlock("cont")
+	fmt.Fprintf(c.b, "  br i1 %s, label %%%s, label %%%s\n", cond, trap, next)
+	c.startBlock(trap)
+	c.b.WriteString("  call void @llvm.trap()\n  unreachable\n")
+	c.startBlock(next)
+}
+
+func (c *wasmCtx) lowerFloatOp(ty, name string, ins Instr) (bool, error) {
+	sfx := wasmIntrinsicSuffix(ty)
+	if llop, ok := wasmFloatBinOps[name]; ok {
+		v, err := c.popN(ty, ty)
+		if err != nil {
+			return true, err
+		}
+		if strings.HasPrefix(llop, "f") {
+			c.push(ty, c.emit("%s %s %s, %s", llop, ty, v[0], v[1]))
+		} else {
+			c.push(ty, c.emit("call %s @llvm.%s.%s(%s %s, %s %s)", ty, llop, sfx, ty, v[0], ty, v[1]))
+		}
+		return true, nil
+	}
+	if pred, ok := wasmFloatCompares[name]; ok {
+		v, err := c.popN(ty, ty)
+		if err != nil {
+			return true, err
+		}
+		c.push("i32", c.emit("zext i1 %s to i32", c.emit("fcmp %s %s %s, %s", pred, ty, v[0], v[1])))
+		return true, nil
+	}
+	if fn, ok := wasmFloatUnary[name]; ok {
+		v, err := c.pop(ty)
+		if err != nil {
+			return true, err
+		}
+		c.push(ty, c.emit("call %s @llvm.%s.%s(%s %s)", ty, fn, sfx, ty, v))
+		return true, nil
+	}
+	switch name {
+	case "NEG":
+		v, err := c.pop(ty)
+		if err != nil {
+			return true, err
+		}
+		c.push(ty, c.emit("fneg %s %s", ty, v))
+		return true, nil
+	case "DEMOTEF64", "PROMOTEF32":
+		from, conv := "double", "fptrunc"
+		if name == "PROMOTEF32" {
+			from, conv = "float", "fpext"
+		}
+		if from == ty {
+			break
+		}
+		v, err := c.pop(from)
+		if err != nil {
+			return true, err
+		}
+		c.push(ty, c.emit("%s %s %s to %s", conv, from, v, ty))
+		return true, nil
+	case "REINTERPRETI32", "REINTERPRETI64":
+		from := wasmValueTypes[name[len(name)-3:]]
+		if (from == "i32") != (ty == "float") {
+			break
+		}
+		v, err := c.pop(from)
+		if err != nil {
+			return true, err
+		}
+		c.push(ty, c.emit("bitcast %s %s to %s", from, v, ty))
+		return true, nil
+	case "CONVERTI32S", "CONVERTI32U", "CONVERTI64S", "CONVERTI64U":
+		from := wasmValueTypes[name[7:10]]
+		v, err := c.pop(from)
+		if err != nil {
+			return true, err
+		}
+		conv := "sitofp"
+		if name[10] == 'U' {
+			conv = "uitofp"
+		}
+		c.push(ty, c.emit("%s %s %s to %s", conv, from, v, ty))
+		return true, nil
+	}
+	return false, nil
+}
+
+// emitWasmNumericDecls declares the intrinsics lowerNumeric calls.
+func emitWasmNumericDecls(b *strings.Builder) {
+	for _, ty := range []string{"i32", "i64"} {
+		fmt.Fprintf(b, "declare %s @llvm.ctlz.%s(%s, i1)\n", ty, ty, ty)
+		fmt.Fprintf(b, "declare %s @llvm.cttz.%s(%s, i1)\n", ty, ty, ty)
+		fmt.Fprintf(b, "declare %s @llvm.ctpop.%s(%s)\n", ty, ty, ty)
+		for _, fn := range []string{"fshl", "fshr"} {
+			fmt.Fprintf(b, "declare %s @llvm.%s.%s(%s, %s, %s)\n", ty, fn, ty, ty, ty, ty)
+		}
+	}
+	for _, ty := range []string{"float", "double"} {
+		sfx := wasmIntrinsicSuffix(ty)
+		for _, fn := range []string{"fabs", "ceil", "floor", "trunc", "roundeven", "sqrt"} {
+			fmt.Fprintf(b, "declare %s @llvm.%s.%s(%s)\n", ty, fn, sfx, ty)
+		}
+		for _, fn := range []string{"minimum", "maximum", "copysign"} {
+			fmt.Fprintf(b, "declare %s @llvm.%s.%s(%s, %s)\n", ty, fn, sfx, ty, ty)
+		}
+		for _, ity := range []string{"i32", "i64"} {
+			for _, fn := range []string{"fptosi", "fptoui"} {
+				fmt.Fprintf(b, "declare %s @llvm.%s.sat.%s.%s(%s)\n", ity, fn, ity, sfx, ty)
+			}
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package plan9asm
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// wasmGlobals are the registers the wasm port keeps in WebAssembly
+// globals rather than function locals. Only SP is lowered; the others
+// belong to the runtime's goroutine and unwinding machinery.
+var wasmGlobals = map[string]bool{
+	"SP":    true,
+	"CTXT":  true,
+	"g":     true,
+	"RET0":  true,
+	"RET1":  true,
+	"RET2":  true,
+	"RET3":  true,
+	"PAUSE": true,
+	"PC_F":  true,
+	"PC_B":  true,
+}
+
+// wasmParseOperands parses an operand list in the wasm dialect. The
+// globals are registers of their own (parseReg would read g as the arm64
+// alias of R28), off(CTXT) and off(g) are memory operands, and $off(Rn)
+// address constants are kept as symbols.
+func wasmParseOperands(s string) ([]Operand, error) {
+	if s == "" {
+		return nil, nil
+	}
+	parts := splitTopLevelCSV(s)
+	out := make([]Operand, 0, len(parts))
+	for _, p := range parts {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		if wasmGlobals[p] {
+			out = append(out, Operand{Kind: OpReg, Reg: Reg(p)})
+			continue
+		}
+		if m, ok := wasmGlobalMem(p); ok {
+			out = append(out, Operand{Kind: OpMem, Mem: m})
+			continue
+		}
+		if _, ok := wasmAddrConst(p); ok {
+			out = append(out, Operand{Kind: OpSym, Sym: p})
+			continue
+		}
+		op, err := parseOperand(p)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, op)
+	}
+	return out, nil
+}
+
+// wasmGlobalMem parses off(CTXT) and off(g).
+func wasmGlobalMem(s string) (MemRef, bool) {
+	open := strings.LastIndex(s, "(")
+	if open < 0 || !strings.HasSuffix(s, ")") {
+		return MemRef{}, false
+	}
+	base := s[open+1 : len(s)-1]
+	if base != "CTXT" && base != "g" {
+		return MemRef{}, false
+	}
+	m := MemRef{Base: Reg(base)}
+	if off := strings.TrimSpace(s[:open]); off != "" {
+		v, err := strconv.ParseInt(off, 0, 64)
+		if err != nil {
+			return MemRef{}, false
+		}
+		m.Off = v
+	}
+	return m, true
+}
+
+// wasmAddrConst parses an $off(Rn) or $off(SP) address constant.
+func wasmAddrConst(s string) (MemRef, bool) {
+	if !strings.HasPrefix(s, "$") || !strings.HasSuffix(s, ")") {
+		return MemRef{}, false
+	}
+	op, err := parseOperand(s[1:])
+	if err != nil || op.Kind != OpMem || op.Mem.Index != "" {
+		return MemRef{}, false
+	}
+	if _, ok := wasmLocalType(op.Mem.Base); !ok && op.Mem.Base != SP {
+		return MemRef{}, false
+	}
+	return op.Mem, true
+}
+
+// wasmLocalType returns the value type of a register held in a local
+// under Go's calling convention: R0-R15 are i64, F0-F15 f32 and F16-F31
+// f64.
+func wasmLocalType(r Reg) (string, bool) {
+	s := string(r)
+	switch {
+	case strings.HasPrefix(s, "R"):
+		if n, err := strconv.Atoi(s[1:]); err == nil && 0 <= n && n <= 15 {
+			return "i64", true
+		}
+	case strings.HasPrefix(s, "F"):
+		n, err := strconv.Atoi(s[1:])
+		if err != nil || n < 0 || n > 31 {
+			break
+		}
+		if n < 16 {
+			return "float", true
+		}
+		return "double", true
+	}
+	return "", false
+}
+
+// wasmRegIndex returns n for Rn.
+func wasmRegIndex(r Reg) (int, error) {
+	s := string(r)
+	if strings.HasPrefix(s, "R") {
+		if n, err := strconv.Atoi(s[1:]); err == nil && 0 <= n && n <= 15 {
+			return n, nil
+		}
+	}
+	return 0, fmt.Errorf("%s is not a local of this function", r)
+}
@@ -0,0 +1,289 @@
+package plan9asm
+
+import "fmt"
+
+func (c *wasmCtx) push(ty, v string) {
+	c.stack = append(c.stack, wasmValue{ty: ty, v: v})
+}
+
+func (c *wasmCtx) top() *wasmFrame {
+	return &c.frames[len(c.frames)-1]
+}
+
+// popAny pops the top value. Below the innermost frame's entries the
+// stack of unreachable code is polymorphic; want gives the type of the
+// poison value read there, or "" to report underflow anyway.
+func (c *wasmCtx) popAny(want string) (wasmValue, error) {
+	if len(c.stack) > c.top().height {
+		v := c.stack[len(c.stack)-1]
+		c.stack = c.stack[:len(c.stack)-1]
+		return v, nil
+	}
+	if c.dead && want != "" {
+		return wasmValue{ty: want, v: "poison"}, nil
+	}
+	return wasmValue{}, fmt.Errorf("%s: value stack underflow", c.arch)
+}
+
+// pop pops a value of type ty.
+func (c *wasmCtx) pop(ty string) (string, error) {
+	v, err := c.popAny(ty)
+	if err != nil {
+		return "", err
+	}
+	if v.ty != ty {
+		return "", fmt.Errorf("%s: value stack has %s, want %s", c.arch, v.ty, ty)
+	}
+	return v.v, nil
+}
+
+// popN pops len(tys) values, the last of tys from the top.
+func (c *wasmCtx) popN(tys ...string) ([]string, error) {
+	out := make([]string, len(tys))
+	for i := len(tys) - 1; i >= 0; i-- {
+		v, err := c.pop(tys[i])
+		if err != nil {
+			return nil, err
+		}
+		out[i] = v
+	}
+	return out, nil
+}
+
+// popCond pops an i32 condition as an i1.
+func (c *wasmCtx) popCond() (string, error) {
+	v, err := c.pop("i32")
+	if err != nil {
+		return "", err
+	}
+	return c.emit("icmp ne i32 %s, 0", v), nil
+}
+
+// terminate ends the current LLVM block and continues in a fresh one
+// that nothing reaches, as WebAssembly validates code after br, return
+// and unreachable against a polymorphic stack.
+func (c *wasmCtx) terminate() {
+	c.stack = c.stack[:c.top().height]
+	c.dead = true
+	c.startBlock(c.newBlock("dead"))
+}
+
+// wasmBlockType decodes the block type operand of Block, Loop and If.
+func (c *wasmCtx) wasmBlockType(ins Instr) (string, error) {
+	if len(ins.Args) == 0 {
+		return "", nil
+	}
+	if len(ins.Args) != 1 || ins.Args[0].Kind != OpImm {
+		return "", fmt.Errorf("%s %s expects an optional $type: %q", c.arch, ins.Op, ins.Raw)
+	}
+	switch ins.Args[0].Imm {
+	case 0:
+		return "", nil
+	case 1:
+		return "i32", nil
+	case 2:
+		return "i64", nil
+	case 3:
+		return "float", nil
+	case 4:
+		return "double", nil
+	}
+	return "", fmt.Errorf("%s %s: unsupported block type %d: %q", c.arch, ins.Op, ins.Args[0].Imm, ins.Raw)
+}
+
+// open pushes a construct frame whose End continues at a new block.
+func (c *wasmCtx) open(kind string, ins Instr) (*wasmFrame, error) {
+	result, err := c.wasmBlockType(ins)
+	if err != nil {
+		return nil, err
+	}
+	f := wasmFrame{
+		kind:   kind,
+		label:  c.pending,
+		result: result,
+		height: len(c.stack),
+		end:    c.newBlock(kind + "_end"),
+	}
+	c.pending = ""
+	if result != "" {
+		f.slot = "%" + f.end + "_result"
+		fmt.Fprintf(&c.allocas, "  %s = alloca %s\n", f.slot, result)
+	}
+	c.frames = append(c.frames, f)
+	return c.top(), nil
+}
+
+func (c *wasmCtx) lowerBlock(ins Instr) error {
+	_, err := c.open("block", ins)
+	return err
+}
+
+func (c *wasmCtx) lowerLoop(ins Instr) error {
+	f, err := c.open("loop", ins)
+	if err != nil {
+		return err
+	}
+	f.head = c.newBlock("loop")
+	c.br(f.head)
+	c.startBlock(f.head)
+	return nil
+}
+
+func (c *wasmCtx) lowerIf(ins Instr) error {
+	cond, err := c.popCond()
+	if err != nil {
+		return err
+	}
+	f, err := c.open("if", ins)
+	if err != nil {
+		return err
+	}
+	then := c.newBlock("then")
+	f.els = c.newBlock("else")
+	fmt.Fprintf(c.b, "  br i1 %s, label %%%s, label %%%s\n", cond, then, f.els)
+	c.startBlock(then)
+	return nil
+}
+
+// leave finishes the reachable end of a construct's arm: its result goes
+// to the result slot and control to the block after End.
+func (c *wasmCtx) leave(f *wasmFrame) error {
+	if !c.dead {
+		if f.result != "" {
+			v, err := c.pop(f.result)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(c.b, "  store %s %s, ptr %s\n", f.result, v, f.slot)
+		}
+		if len(c.stack) != f.height {
+			return fmt.Errorf("%s: %d values left at the end of %s", c.arch, len(c.stack)-f.height, f.kind)
+		}
+		c.br(f.end)
+	} else {
+		// Nothing branches to the block after Br, Return or Unreachable.
+		c.b.WriteString("  unreachable\n")
+	}
+	c.stack = c.stack[:f.height]
+	return nil
+}
+
+func (c *wasmCtx) lowerElse(ins Instr) error {
+	f := c.top()
+	if f.kind != "if" || f.els == "" {
+		return fmt.Errorf("%s: Else outside If: %q", c.arch, ins.Raw)
+	}
+	if err := c.leave(f); err != nil {
+		return err
+	}
+	c.startBlock(f.els)
+	f.els = ""
+	c.dead = false
+	return nil
+}
+
+func (c *wasmCtx) lowerEnd(ins Instr) error {
+	if len(c.frames) == 1 {
+		return fmt.Errorf("%s: End without Block, Loop or If: %q", c.arch, ins.Raw)
+	}
+	f := *c.top()
+	if err := c.leave(&f); err != nil {
+		return err
+	}
+	if f.els != "" {
+		// An If without Else falls through when the condition is false.
+		c.startBlock(f.els)
+		c.br(f.end)
+	}
+	c.frames = c.frames[:len(c.frames)-1]
+	c.startBlock(f.end)
+	c.dead = false
+	if f.result != "" {
+		c.push(f.result, c.emit("load %s, ptr %s", f.result, f.slot))
+	}
+	return nil
+}
+
+// branchFrame returns the index in c.frames of the target of Br or BrIf:
+// a relative depth, or a label placed on an enclosing construct.
+func (c *wasmCtx) branchFrame(ins Instr) (int, error) {
+	if len(ins.Args) != 1 {
+		return 0, fmt.Errorf("%s %s expects 1 operand: %q", c.arch, ins.Op, ins.Raw)
+	}
+	a := ins.Args[0]
+	switch a.Kind {
+	case OpImm:
+		if a.Imm < 0 || a.Imm >= int64(len(c.frames)) {
+			return 0, fmt.Errorf("%s %s: depth %d out of range: %q", c.arch, ins.Op, a.Imm, ins.Raw)
+		}
+		return len(c.frames) - 1 - int(a.Imm), nil
+	case OpIdent, OpLabel:
+		name := a.Ident
+		if a.Kind == OpLabel {
+			name = a.Sym
+		}
+		for i := len(c.frames) - 1; i > 0; i-- {
+			if c.frames[i].label == name {
+				return i, nil
+			}
+		}
+		return 0, fmt.Errorf("%s %s: label %s is not on an enclosing Block, Loop or If: %q", c.arch, ins.Op, name, ins.Raw)
+	}
+	return 0, fmt.Errorf("%s %s: invalid target: %q", c.arch, ins.Op, ins.Raw)
+}
+
+// branchTo transfers control to frame i. A branch to a loop continues it;
+// one to the function body returns.
+func (c *wasmCtx) branchTo(i int) error {
+	if i == 0 {
+		return c.lowerReturn()
+	}
+	f := &c.frames[i]
+	if f.kind == "loop" {
+		c.br(f.head)
+		return nil
+	}
+	if f.result != "" {
+		v, err := c.pop(f.result)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(c.b, "  store %s %s, ptr %s\n", f.result, v, f.slot)
+	}
+	c.br(f.end)
+	return nil
+}
+
+func (c *wasmCtx) lowerBr(ins Instr) error {
+	i, err := c.branchFrame(ins)
+	if err != nil {
+		return err
+	}
+	if err := c.branchTo(i); err != nil {
+		return err
+	}
+	c.terminate()
+	return nil
+}
+
+func (c *wasmCtx) lowerBrIf(ins Instr) error {
+	i, err := c.branchFrame(ins)
+	if err != nil {
+		return err
+	}
+	cond, err := c.popCond()
+	if err != nil {
+		return err
+	}
+	taken, next := c.newBlock("br"), c.newBlock("cont")
+	fmt.Fprintf(c.b, "  br i1 %s, label %%%s, label %%%s\n", cond, taken, next)
+	c.startBlock(taken)
+	// The branch consumes its values only on the taken path.
+	stack := append([]wasmValue(nil), c.stack...)
+	if err := c.branchTo(i); err != nil {
+		return err
+	}
+	c.stack = stack
+	c.startBlock(next)
+	return nil
+}
@@ -0,0 +1,250 @@
+package plan9asm
+
+import (
+	"fmt"
+	"strings"
+)
+
+func emitWasmPrelude(b *strings.Builder) {
+	emitWasmNumericDecls(b)
+	emitWasmMemoryDecls(b)
+	b.WriteString("declare void @llvm.trap()\n")
+	b.WriteString("\n")
+}
+
+func translateFuncWasm(b *strings.Builder, fn Func, sig FuncSig, resolve func(string) string, sigs map[string]FuncSig, cfg lowerConfig) error {
+	var entry, body strings.Builder
+	c := newWasmCtx(&entry, fn, sig, resolve, sigs, cfg)
+	if err := c.emitEntryAllocasAndArgInit(); err != nil {
+		return err
+	}
+	c.b = &body
+	if err := c.lowerBody(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(b, "define %s %s(", sig.Ret, llvmGlobal(sig.Name))
+	for i, t := range sig.Args {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		fmt.Fprintf(b, "%s %%arg%d", t, i)
+	}
+	b.WriteString(")")
+	if sig.Attrs != "" {
+		b.WriteString(" " + sig.Attrs)
+	}
+	b.WriteString(" {\nentry:\n")
+	b.WriteString(c.allocas.String())
+	b.WriteString(entry.String())
+	b.WriteString("  br label %wasm_body\n\nwasm_body:\n")
+	b.WriteString(body.String())
+	b.WriteString("}\n")
+	return nil
+}
+
+// lowerBody lowers the instructions in order. The end of the body returns
+// like Return in a wasm-ABI function; a Go-ABI body that runs off its end
+// returns zero, as the other backends do.
+func (c *wasmCtx) lowerBody() error {
+	c.frames = []wasmFrame{{kind: "func", result: c.abi.result}}
+	for _, ins := range c.fn.Instrs {
+		c.emitSourceComment(ins)
+		if err := c.lowerInstr(ins); err != nil {
+			return err
+		}
+	}
+	if len(c.frames) != 1 {
+		return fmt.Errorf("%s: %d Block, Loop or If left without End", c.arch, len(c.frames)-1)
+	}
+	if c.dead {
+		c.b.WriteString("  unreachable\n")
+		return nil
+	}
+	if c.hasABI {
+		return c.lowerReturn()
+	}
+	c.lowerRetZero()
+	return nil
+}
+
+func (c *wasmCtx) lowerInstr(ins Instr) error {
+	op := strings.ToUpper(string(ins.Op))
+	switch op {
+	case string(OpLABEL), "BLOCK", "LOOP", "IF":
+	default:
+		// A label names the construct that immediately follows it.
+		c.pending = ""
+	}
+	switch Op(op) {
+	case OpTEXT:
+		return nil
+	case OpLABEL:
+		if len(ins.Args) == 1 && ins.Args[0].Kind == OpLabel {
+			c.pending = ins.Args[0].Sym
+		}
+		return nil
+	case OpRET:
+		if c.hasABI {
+			return fmt.Errorf("%s: RET in %s, which returns with Return", c.arch, c.fn.Sym)
+		}
+		if err := c.lowerRET(); err != nil {
+			return err
+		}
+		c.terminate()
+		return nil
+	case OpBYTE:
+		return fmt.Errorf("%s: raw instruction encoding %s is not lowered", c.arch, ins.Op)
+	}
+	switch op {
+	case "WORD":
+		return fmt.Errorf("%s: raw instruction encoding %s is not lowered", c.arch, ins.Op)
+	case "NO_LOCAL_POINTERS", "PCDATA", "FUNCDATA", "GO_ARGS", "NOP":
+		return nil
+	case "BLOCK":
+		return c.lowerBlock(ins)
+	case "LOOP":
+		return c.lowerLoop(ins)
+	case "IF":
+		return c.lowerIf(ins)
+	case "ELSE":
+		return c.lowerElse(ins)
+	case "END":
+		return c.lowerEnd(ins)
+	case "BR":
+		return c.lowerBr(ins)
+	case "BRIF":
+		return c.lowerBrIf(ins)
+	case "RETURN":
+		if !c.hasABI {
+			return fmt.Errorf("%s: Return in %s, which follows Go's convention and returns with RET", c.arch, c.fn.Sym)
+		}
+		if err := c.lowerReturn(); err != nil {
+			return err
+		}
+		c.terminate()
+		return nil
+	case "UNDEF", "UNREACHABLE":
+		c.b.WriteString("  call void @llvm.trap()\n  unreachable\n")
+		c.terminate()
+		return nil
+	case "GET", "SET", "TEE":
+		return c.lowerLocal(op, ins)
+	case "DROP":
+		_, err := c.popAny("i32")
+		return err
+	case "SELECT":
+		return c.lowerSelect()
+	case "CALL", "CALLNORESUME":
+		return c.lowerCall(ins)
+	case "JMP":
+		return c.lowerJMP(ins)
+	}
+	if ok, err := c.lowerNumeric(op, ins); ok {
+		return err
+	}
+	if ok, err := c.lowerMemory(op, ins); ok {
+		return err
+	}
+	return fmt.Errorf("%s: unsupported instruction %s", c.arch, ins.Op)
+}
+
+func (c *wasmCtx) lowerLocal(op string, ins Instr) error {
+	if len(ins.Args) != 1 || ins.Args[0].Kind != OpReg {
+		return fmt.Errorf("%s %s expects a register: %q", c.arch, ins.Op, ins.Raw)
+	}
+	r := ins.Args[0].Reg
+	if op == "GET" {
+		v, err := c.loadReg(r)
+		if err != nil {
+			return err
+		}
+		c.stack = append(c.stack, v)
+		return nil
+	}
+	ty, ok := c.regType[r]
+	if !ok {
+		_, err := c.loadReg(r)
+		return err
+	}
+	v, err := c.popAny(ty)
+	if err != nil {
+		return err
+	}
+	if err := c.storeReg(r, v); err != nil {
+		return err
+	}
+	if op == "TEE" {
+		c.push(v.ty, v.v)
+	}
+	return nil
+}
+
+func (c *wasmCtx) lowerSelect() error {
+	cond, err := c.popCond()
+	if err != nil {
+		return err
+	}
+	b, err := c.popAny("i32")
+	if err != nil {
+		return err
+	}
+	a, err := c.pop(b.ty)
+	if err != nil {
+		return err
+	}
+	c.push(b.ty, c.emit("select i1 %s, %s %s, %s %s", cond, b.ty, a, b.ty, b.v))
+	return nil
+}
+
+// lowerReturn returns from a wasm-ABI function with the result on the
+// stack, and from a Go-ABI one as RET does.
+func (c *wasmCtx) lowerReturn() error {
+	if !c.hasABI {
+		return c.lowerRET()
+	}
+	if c.abi.result == "" {
+		c.b.WriteString("  ret void\n")
+		return nil
+	}
+	v, err := c.pop(c.abi.result)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(c.b, "  ret %s %s\n", c.abi.result, v)
+	return nil
+}
+
+// lowerRET returns the results the body stored to their FP slots.
+func (c *wasmCtx) lowerRET() error {
+	if len(c.fpResults) == 0 {
+		c.lowerRetZero()
+		return nil
+	}
+	if len(c.fpResults) == 1 {
+		v, err := c.loadFPResult(c.fpResults[0])
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(c.b, "  ret %s %s\n", c.sig.Ret, v)
+		return nil
+	}
+	cur := "undef"
+	for _, slot := range c.fpResults {
+		v, err := c.loadFPResult(slot)
+		if err != nil {
+			return err
+		}
+		cur = c.emit("insertvalue %s %s, %s %s, %d", c.sig.Ret, cur, slot.Type, v, slot.Index)
+	}
+	fmt.Fprintf(c.b, "  ret %s %s\n", c.sig.Ret, cur)
+	return nil
+}
+
+func (c *wasmCtx) lowerRetZero() {
+	if c.sig.Ret == Void {
+		c.b.WriteString("  ret void\n")
+		return
+	}
+	fmt.Fprintf(c.b, "  ret %s %s\n", c.sig.Ret, llvmZeroValue(c.sig.Ret))
+}
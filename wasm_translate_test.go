@@ -0,0 +1,290 @@
+//go:build !llgo
+// +build !llgo
+
+package plan9asm
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func translateWasm(t *testing.T, src string, sigs map[string]FuncSig) string {
+	t.Helper()
+	file, err := Parse(ArchWasm, src)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	ll, err := translateIRText(file, Options{
+		TargetTriple: "wasm32-unknown-wasi",
+		Goarch:       "wasm",
+		ResolveSym:   testResolveSym("example"),
+		Sigs:         sigs,
+	})
+	if err != nil {
+		t.Fatalf("translateIRText: %v", err)
+	}
+	return ll
+}
+
+// wasmMemchr is memchr<> of internal/bytealg, trimmed to its byte loop.
+const wasmMemchr = `TEXT memchr<>(SB), NOSPLIT, $0
+	Get R1
+	Set R4
+	Block
+		Block
+			Get R2
+			I32Eqz
+			BrIf $0
+			Loop
+				Get R0
+				I32Load8U $0
+				Get R4
+				I32Eq
+				BrIf $2
+				Get R0
+				I32Const $1
+				I32Add
+				Set R0
+				Get R2
+				I32Const $-1
+				I32Add
+				Tee R2
+				BrIf $0
+			End
+		End
+		I32Const $0
+		Return
+	End
+	Get R0
+	Return
+`
+
+func TestTranslateWasmStructured(t *testing.T) {
+	ll := translateWasm(t, `TEXT ·IndexByte(SB), NOSPLIT, $0-40
+	I64Load b_base+0(FP)
+	I32WrapI64
+	I32Load8U c+24(FP)
+	I64Load b_len+8(FP)
+	I32WrapI64
+	Call memchr<>(SB)
+	I64ExtendI32U
+	Set R0
+
+	Get SP
+	I64Const $-1
+	Get R0
+	I64Load b_base+0(FP)
+	I64Sub
+	Get R0
+	I64Eqz $0
+	Select
+	I64Store ret+32(FP)
+
+	RET
+
+`+wasmMemchr, map[string]FuncSig{
+		"example.IndexByte": sigWithClassicFrame("example.IndexByte", []LLVMType{Ptr, I64, I64, I8}, I64),
+	})
+	wantIR(t, ll,
+		`define i32 @"example.memchr"(i32 %arg0, i32 %arg1, i32 %arg2)`,
+		"%reg_R0 = alloca i32",
+		"store i32 %arg0, ptr %reg_R0",
+		"load i8, ptr",
+		"br label %wasm_loop",
+		`call i32 @"example.memchr"(i32`,
+		"zext i32",
+		"select i1",
+		"define i64 @\"example.IndexByte\"",
+		"ret i32",
+	)
+	if strings.Contains(ll, "declare i64 @\"example.memchr\"") {
+		t.Fatalf("memchr<> declared with a Go signature:\n%s", ll)
+	}
+}
+
+func TestTranslateWasmABIOverridesSig(t *testing.T) {
+	// Go's linker fixes the signature of wasmDiv whatever a caller infers.
+	ll := translateWasm(t, `TEXT runtime·wasmDiv(SB), NOSPLIT, $0-0
+	Get R0
+	I64Const $-0x8000000000000000
+	I64Eq
+	If
+		Get R1
+		I64Const $-1
+		I64Eq
+		If
+			I64Const $-0x8000000000000000
+			Return
+		End
+	End
+	Get R0
+	Get R1
+	I64DivS
+	Return
+`, map[string]FuncSig{
+		"runtime.wasmDiv": {Name: "runtime.wasmDiv", Ret: I64},
+	})
+	wantIR(t, ll,
+		`define i64 @"runtime.wasmDiv"(i64 %arg0, i64 %arg1)`,
+		"icmp eq i64",
+		"sdiv i64",
+		"call void @llvm.trap()",
+		"ret i64 -9223372036854775808",
+	)
+}
+
+func TestTranslateWasmGoFrame(t *testing.T) {
+	ll := translateWasm(t, `TEXT ·Floor(SB),NOSPLIT,$0
+	Get SP
+	F64Load x+0(FP)
+	F64Floor
+	F64Store ret+8(FP)
+	RET
+
+TEXT ·Store64(SB), NOSPLIT, $0-16
+	MOVD ptr+0(FP), R0
+	MOVD val+8(FP), 0(R0)
+	RET
+
+TEXT ·Clear(SB), NOSPLIT, $0-16
+	MOVD ptr+0(FP), R0
+	MOVD n+8(FP), R1
+	Get R0
+	I32WrapI64
+	I32Const $0
+	Get R1
+	I32WrapI64
+	MemoryFill
+	RET
+
+TEXT ·Grow(SB), NOSPLIT, $0-8
+	Get SP
+	I32Load n+0(FP)
+	GrowMemory
+	I32Store ret+8(FP)
+	RET
+`, map[string]FuncSig{
+		"example.Floor":   sigWithClassicFrame("example.Floor", []LLVMType{LLVMType("double")}, LLVMType("double")),
+		"example.Store64": sigWithClassicFrame("example.Store64", []LLVMType{Ptr, I64}, Void),
+		"example.Clear":   sigWithClassicFrame("example.Clear", []LLVMType{Ptr, I64}, Void),
+		"example.Grow": {Name: "example.Grow", Args: []LLVMType{I32}, Ret: I32, Frame: FrameLayout{
+			Params:  []FrameSlot{{Offset: 0, Type: I32, Index: 0, Field: -1}},
+			Results: []FrameSlot{{Offset: 8, Type: I32, Index: 0, Field: -1}},
+		}},
+	})
+	wantIR(t, ll,
+		`define double @"example.Floor"(double %arg0)`,
+		"call double @llvm.floor.f64(double",
+		"ret double",
+		"ptrtoint ptr %arg0 to i64",
+		"store i64 %arg1, ptr",
+		"call void @llvm.memset.p0.i32(ptr",
+		"call i32 @llvm.wasm.memory.grow.i32(i32 0, i32",
+	)
+}
+
+func TestTranslateWasmTailCall(t *testing.T) {
+	ll := translateWasm(t, `TEXT ·addVV(SB),NOSPLIT,$0
+	JMP ·addVV_g(SB)
+`, map[string]FuncSig{
+		"example.addVV":   sigWithClassicFrame("example.addVV", []LLVMType{Ptr, Ptr, I64}, I64),
+		"example.addVV_g": sigWithClassicFrame("example.addVV_g", []LLVMType{Ptr, Ptr, I64}, I64),
+	})
+	wantIR(t, ll,
+		`call i64 @"example.addVV_g"(ptr %arg0, ptr %arg1, i64 %arg2)`,
+	)
+}
+
+func TestTranslateWasmCompile(t *testing.T) {
+	llc, _, ok := findLlcAndClang(t)
+	if !ok {
+		t.Skip("llc not found")
+	}
+	ll := translateWasm(t, `TEXT ·Count(SB), NOSPLIT, $0-40
+	I64Load b_base+0(FP)
+	I32WrapI64
+	I32Load8U c+24(FP)
+	I64Load b_len+8(FP)
+	I32WrapI64
+	Call memchr<>(SB)
+	I64ExtendI32U
+	Set R0
+	Get SP
+	Get R0
+	I64Store ret+32(FP)
+	RET
+
+`+wasmMemchr, map[string]FuncSig{
+		"example.Count": sigWithClassicFrame("example.Count", []LLVMType{Ptr, I64, I64, I8}, I64),
+	})
+	dir := t.TempDir()
+	llPath := filepath.Join(dir, "memchr.ll")
+	if err := os.WriteFile(llPath, []byte(ll), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out, err := exec.Command(llc, "-mtriple=wasm32-unknown-wasi", "-filetype=obj", llPath, "-o", filepath.Join(dir, "memchr.o")).CombinedOutput()
+	if err != nil {
+		t.Fatalf("llc: %v\n%s", err, out)
+	}
+}
+
+func TestTranslateWasmRejectsUnsupported(t *testing.T) {
+	for _, tc := range []struct{ src, want string }{
+		{"TEXT ·f(SB),NOSPLIT,$0-0\n\tMOVD 8(CTXT), R0\n\tRET\n", "CTXT is not lowered"},
+		{"TEXT ·f(SB),NOSPLIT,$0-0\nloop:\n\tJMP loop\n", "JMP within a function"},
+		{"TEXT ·f(SB),NOSPLIT,$0-0\n\tI32Const $0\n\tReturn\n", "returns with RET"},
+		{"TEXT ·f(SB),NOSPLIT,$0-0\n\tBlock\n\tRET\n", "without End"},
+		{"TEXT ·f(SB),NOSPLIT,$0-0\n\tI32Add\n\tRET\n", "stack"},
+	} {
+		file, err := Parse(ArchWasm, tc.src)
+		if err != nil {
+			t.Fatalf("%q: Parse: %v", tc.src, err)
+		}
+		_, err = translateIRText(file, Options{
+			Goarch:     "wasm",
+			ResolveSym: testResolveSym("example"),
+			Sigs:       map[string]FuncSig{"example.f": {Name: "example.f", Ret: Void}},
+		})
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%q: err = %v, want %q", tc.src, err, tc.want)
+		}
+	}
+}
+
+func TestGoSigsWasmHelpers(t *testing.T) {
+	pkg := mustGoPackage(t, "test/pkg", `package testpkg
+func Count(b []byte, c byte) int
+`)
+	file, err := Parse(ArchWasm, `TEXT ·Count(SB), NOSPLIT, $0-40
+	I64Load b_base+0(FP)
+	I32WrapI64
+	I32Load8U c+24(FP)
+	I64Load b_len+8(FP)
+	I32WrapI64
+	Call memchr<>(SB)
+	I64ExtendI32U
+	Set R0
+	Get SP
+	Get R0
+	I64Store ret+32(FP)
+	RET
+
+`+wasmMemchr)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	sigs, err := goSigsForAsmFile(pkg, file, testResolveSym("test/pkg"), "wasm", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, ok := sigs["test/pkg.memchr"]
+	if !ok || sig.Ret != I32 || !sameLLVMTypes(sig.Args, []LLVMType{I32, I32, I32}) {
+		t.Fatalf("memchr<> signature = %+v, %v", sig, ok)
+	}
+	if sig := sigs["test/pkg.Count"]; len(sig.Frame.Results) != 1 || sig.Frame.Results[0].Offset != 32 {
+		t.Fatalf("Count frame = %+v", sig.Frame)
+	}
+}